@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/clientconfig"
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	"github.com/gardarr/gardarr/internal/interfaces"
+	instanceRepository "github.com/gardarr/gardarr/internal/repository/instance/agent"
+	delugeInstance "github.com/gardarr/gardarr/internal/repository/instance/deluge"
+	transmissionInstance "github.com/gardarr/gardarr/internal/repository/instance/transmission"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	delugeTask "github.com/gardarr/gardarr/internal/repository/task/deluge"
+	transmissionTask "github.com/gardarr/gardarr/internal/repository/task/transmission"
+	instanceService "github.com/gardarr/gardarr/internal/services/instance/agent"
+	taskService "github.com/gardarr/gardarr/internal/services/task/agent"
+	tracker "github.com/gardarr/gardarr/internal/services/tracker/agent"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/jfxdev/go-qbt"
+)
+
+// torrentClient is a torrent client managed by the agent, with its services
+type torrentClient struct {
+	definition clientconfig.Definition
+	tasks      interfaces.TaskService
+	instance   interfaces.InstanceService
+	tracker    *tracker.Tracker
+}
+
+// newClients creates the services of the torrent clients defined in
+// AGENT_CLIENTS_FILE, or of the one set in AGENT_CLIENT
+func newClients() ([]*torrentClient, error) {
+	definitions, err := clientconfig.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	interval := env.Get("AGENT_SYNC_INTERVAL").Default("2s").ValuePositiveDuration()
+
+	clients := make([]*torrentClient, len(definitions))
+	for i, definition := range definitions {
+		taskRepo, instanceRepo, err := newRepositories(definition)
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", definition.Name, err)
+		}
+
+		taskTracker := tracker.NewWithClient(tracker.NewSyncer(definition), interval)
+
+		clients[i] = &torrentClient{
+			definition: definition,
+			tasks:      taskService.NewWithRepository(taskTracker.WrapRepository(taskRepo)),
+			instance:   instanceService.NewWithRepository(instanceRepo),
+			tracker:    taskTracker,
+		}
+	}
+
+	if len(clients) > 1 {
+		log.Printf("agent: managing %d torrent clients, %s being the default one", len(clients), definitions[0].Name)
+	}
+
+	return clients, nil
+}
+
+// newRepositories creates the task and instance repositories of the torrent client of the definition
+func newRepositories(definition clientconfig.Definition) (taskRepository.RepositoryInterface, instanceRepository.RepositoryInterface, error) {
+	switch definition.Type {
+	case entities.ClientQBittorrent:
+		config := qbt.Config{
+			BaseURL:  definition.URL,
+			Username: definition.Username,
+			Password: definition.Password,
+		}
+		taskRepo, err := taskRepository.NewWithConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		instanceRepo, err := instanceRepository.NewWithConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return taskRepo, instanceRepo, nil
+	case entities.ClientTransmission:
+		client := transmission.New(definition.URL, definition.Username, definition.Password)
+		return transmissionTask.NewWithClient(client), transmissionInstance.NewWithClient(client), nil
+	case entities.ClientDeluge:
+		client := deluge.New(definition.URL, definition.Password, definition.HostID)
+		return delugeTask.NewWithClient(client), delugeInstance.NewWithClient(client), nil
+	default:
+		return nil, nil, fmt.Errorf("type must be one of %v, got %q", entities.Clients, definition.Type)
+	}
+}
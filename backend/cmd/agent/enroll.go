@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agentidentity"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+var (
+	enrollURL        string
+	enrollName       string
+	advertiseAddress string
+	enrollAllowHTTP  bool
+	useTunnel        bool
+
+	// enrolled is the identity of the agent, when it enrolled
+	enrolled *agentidentity.Identity
+)
+
+func init() {
+	cmd.Flags().StringVar(&enrollURL, "enroll", "", "enroll with the manager at this URL, the enrollment token being the argument")
+	cmd.Flags().StringVar(&enrollName, "name", "", "name to enroll with, defaults to the hostname")
+	cmd.Flags().StringVar(&advertiseAddress, "advertise-address", "", "address the manager reaches the agent at, defaults to AGENT_ADVERTISE_ADDRESS then the hostname and AGENT_PORT")
+	cmd.Flags().BoolVar(&enrollAllowHTTP, "enroll-allow-http", false, "allow enrolling with a manager served over plain HTTP")
+	cmd.Flags().BoolVar(&useTunnel, "tunnel", false, "connect to the manager through a tunnel, for agents it cannot reach")
+}
+
+// loadIdentity enrolls the agent when --enroll is given, or else loads the
+// identity of an enrolled agent when AGENT_SECRET is not set. The secret is
+// set as AGENT_SECRET, where the routes read it from.
+func loadIdentity(ctx context.Context, args []string) error {
+	path := env.Get(constants.AgentIdentityFileEnv).Default("agent.json").Value()
+
+	if enrollURL == "" {
+		if len(args) > 0 {
+			return errors.New("an enrollment token is only expected with --enroll")
+		}
+		if env.Get(constants.AgentSecretEnv).Value() != "" {
+			return nil
+		}
+
+		identity, err := agentidentity.Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("agent: enrolled as %s with %s", identity.Name, identity.ManagerURL)
+		enrolled = identity
+		return os.Setenv(constants.AgentSecretEnv, identity.Secret)
+	}
+
+	if len(args) != 1 {
+		return errors.New("usage: agent --enroll <manager-url> <enrollment-token>")
+	}
+
+	// Checked before the token is used, which would be lost otherwise
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%w: remove %s to enroll again", agentidentity.ErrAlreadyEnrolled, path)
+	}
+
+	request, err := enrollRequest(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	identity, err := agentidentity.Enroll(ctx, client, enrollURL, *request, enrollAllowHTTP)
+	if err != nil {
+		return err
+	}
+	identity.Tunnel = useTunnel
+
+	if err := identity.Save(path); err != nil {
+		return fmt.Errorf("enrolled as %s but failed to save the identity: %w", identity.Name, err)
+	}
+
+	log.Printf("agent: enrolled as %s with %s, identity saved to %s", identity.Name, identity.ManagerURL, path)
+	enrolled = identity
+	return os.Setenv(constants.AgentSecretEnv, identity.Secret)
+}
+
+// enrollRequest describes the agent to the manager: its name, torrent client,
+// address and the fingerprint of the certificate it serves
+func enrollRequest(token string) (*schemas.AgentEnrollRequest, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "agent"
+	}
+
+	name := enrollName
+	if name == "" {
+		name = hostname
+	}
+
+	certFile := env.Get(constants.AgentTLSCertFileEnv).Value()
+
+	address := advertiseAddress
+	if address == "" {
+		address = env.Get(constants.AgentAdvertiseAddressEnv).Value()
+	}
+	if address == "" && useTunnel {
+		address = agenttunnel.Address(name)
+	}
+	if address == "" {
+		scheme := "http"
+		if certFile != "" {
+			scheme = "https"
+		}
+		address = fmt.Sprintf("%s://%s:%s", scheme, hostname, env.Get(constants.AgentPortEnv).Default("3100").Value())
+	}
+
+	var fingerprint string
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, env.Get(constants.AgentTLSKeyFileEnv).Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the agent certificate: %w", err)
+		}
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the agent certificate: %w", err)
+		}
+		fingerprint = agentauth.Fingerprint(leaf)
+	}
+
+	return &schemas.AgentEnrollRequest{
+		Token:                  token,
+		Name:                   name,
+		Type:                   env.Get(constants.AgentClientEnv).Default(entities.ClientQBittorrent).Value(),
+		Address:                address,
+		CertificateFingerprint: fingerprint,
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,13 +12,13 @@ import (
 	"time"
 
 	"github.com/gardarr/gardarr/internal/constants"
-	"github.com/gardarr/gardarr/internal/interfaces"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	clientRoutes "github.com/gardarr/gardarr/internal/routes/agent/v1/clients"
+	"github.com/gardarr/gardarr/internal/routes/agent/v1/events"
 	"github.com/gardarr/gardarr/internal/routes/agent/v1/health"
 	"github.com/gardarr/gardarr/internal/routes/agent/v1/instance"
 	"github.com/gardarr/gardarr/internal/routes/agent/v1/tasks"
 	"github.com/gardarr/gardarr/internal/schemas"
-	instanceService "github.com/gardarr/gardarr/internal/services/instance/agent"
-	taskService "github.com/gardarr/gardarr/internal/services/task/agent"
 	"github.com/gardarr/gardarr/pkg/env"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -28,8 +29,9 @@ import (
 
 var (
 	cmd = &cobra.Command{
-		Use:          "agent",
+		Use:          "agent [enrollment-token]",
 		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
 		RunE:         run,
 	}
 	router *gin.Engine
@@ -40,21 +42,37 @@ func Command() *cobra.Command {
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	if err := loadIdentity(cmd.Context(), args); err != nil {
+		return err
+	}
+
 	if err := setRouter(); err != nil {
 		return err
 	}
 
-	taskSvc, err := taskService.New()
+	clients, err := newClients()
 	if err != nil {
 		return err
 	}
 
-	instanceSvc, err := instanceService.New()
+	// Keep an in-memory torrent table in sync with each torrent client for reads and live events
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+
+	for _, client := range clients {
+		go client.tracker.Run(syncCtx)
+	}
+
+	setRoutes(clients)
+
+	// Behind NAT, the agent connects to the manager instead of waiting for it
+	connector, err := tunnelConnector()
 	if err != nil {
 		return err
 	}
-
-	setRoutes(taskSvc, instanceSvc)
+	if connector != nil {
+		go connector.Run(syncCtx)
+	}
 
 	// Create server with timeout
 	port := env.Get(constants.AgentPortEnv).Default("3100").Value()
@@ -64,12 +82,30 @@ func run(cmd *cobra.Command, args []string) error {
 		Handler: router,
 		// set timeout due CWE-400 - Potential Slowloris Attack
 		ReadHeaderTimeout: 5 * time.Second,
+		// event streams end when the background context is canceled on shutdown
+		BaseContext: func(net.Listener) context.Context { return syncCtx },
+	}
+
+	// Serve TLS when a certificate is set, requiring a client certificate
+	// issued by AGENT_TLS_CLIENT_CA_FILE when set
+	if certFile := env.Get(constants.AgentTLSCertFileEnv).Value(); certFile != "" {
+		tlsConfig, err := agentauth.ServerTLSConfig(certFile, env.Get(constants.AgentTLSKeyFileEnv).Value(), env.Get(constants.AgentTLSClientCAFileEnv).Value())
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
 	}
 
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
@@ -83,6 +119,7 @@ func run(cmd *cobra.Command, args []string) error {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopSync()
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -105,7 +142,7 @@ func setRouter() error {
 	}
 
 	if env.Get(constants.AgentSecretEnv).Value() == "" {
-		return errors.New("AGENT_SECRET is not set")
+		return errors.New("AGENT_SECRET is not set and the agent is not enrolled, see --enroll")
 	}
 
 	// Setup Security Headers
@@ -123,10 +160,31 @@ func setRouter() error {
 	return nil
 }
 
-func setRoutes(t interfaces.TaskService, i interfaces.InstanceService) {
+func setRoutes(clients []*torrentClient) {
 	// API routes
 	v1 := router.Group("/v1")
-	health.NewModule(v1, i).Register()
-	tasks.NewModule(v1, t).Register()
-	instance.NewModule(v1, i).Register()
+
+	// The first client also answers the routes of the agents managing a single one
+	registerClient(v1, clients[0])
+
+	listed := make([]clientRoutes.Client, len(clients))
+	for i, client := range clients {
+		registerClient(v1.Group("/clients/"+client.definition.Name), client)
+
+		listed[i] = clientRoutes.Client{
+			Name:     client.definition.Name,
+			Type:     client.definition.Type,
+			Default:  i == 0,
+			Instance: client.instance,
+		}
+	}
+	clientRoutes.NewModule(v1, listed).Register()
+}
+
+// registerClient registers the routes of a torrent client under the group
+func registerClient(group *gin.RouterGroup, client *torrentClient) {
+	health.NewModule(group, client.instance).Register()
+	tasks.NewModule(group, client.tasks).Register()
+	instance.NewModule(group, client.instance).Register()
+	events.NewModule(group, client.tracker).Register()
 }
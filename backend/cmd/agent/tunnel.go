@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// tunnelConnector returns the connector keeping the tunnel to the manager
+// open, or nil when the agent waits for the manager to connect. The manager is
+// AGENT_TUNNEL_URL, or the one the agent enrolled with when told to use a tunnel.
+func tunnelConnector() (*agenttunnel.Connector, error) {
+	managerURL := env.Get(constants.AgentTunnelURLEnv).Value()
+	agentID := env.Get(constants.AgentIDEnv).Value()
+
+	if enrolled != nil {
+		if managerURL == "" && (enrolled.Tunnel || useTunnel) {
+			managerURL = enrolled.ManagerURL
+		}
+		if agentID == "" {
+			agentID = enrolled.AgentUUID
+		}
+	}
+
+	if managerURL == "" {
+		if useTunnel {
+			return nil, errors.New("--tunnel needs AGENT_TUNNEL_URL or an enrolled agent")
+		}
+		return nil, nil
+	}
+	if agentID == "" {
+		return nil, errors.New("AGENT_ID must be set to the agent UUID to open a tunnel")
+	}
+
+	var tlsConfig *tls.Config
+	if caFile := env.Get(constants.AgentTunnelCAFileEnv).Value(); caFile != "" {
+		var err error
+		if tlsConfig, err = agentauth.ClientTLSConfig(caFile, "", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return &agenttunnel.Connector{
+		ManagerURL: managerURL,
+		AgentID:    agentID,
+		Secret:     env.Get(constants.AgentSecretEnv).Value(),
+		TLS:        tlsConfig,
+		Handler:    router,
+		Config:     agenttunnel.LoadConfigFromEnv(),
+	}, nil
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/gardarr/gardarr/cmd/agent"
 	"github.com/gardarr/gardarr/cmd/generate"
 	"github.com/gardarr/gardarr/cmd/service"
+	"github.com/gardarr/gardarr/cmd/torrent"
+	"github.com/gardarr/gardarr/cmd/user"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +18,8 @@ var cmd = &cobra.Command{
 func init() {
 	cmd.AddCommand(generate.Command())
 	cmd.AddCommand(agent.Command())
+	cmd.AddCommand(user.Command())
+	cmd.AddCommand(torrent.Command())
 }
 
 func Command() *cobra.Command {
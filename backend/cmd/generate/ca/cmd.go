@@ -0,0 +1,62 @@
+package ca
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	out      string
+	validity time.Duration
+)
+
+var cmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Generate the CA of the agent certificates",
+	Long: `Generate the certificate authority issuing the certificates of the agents
+and of the manager, see "generate cert". The CA key stays with the manager:
+the agents only get the CA certificate, to verify the manager certificate.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certFile := filepath.Join(out, "ca.crt")
+		keyFile := filepath.Join(out, "ca.key")
+		if _, err := os.Stat(keyFile); err == nil {
+			return fmt.Errorf("%s already exists", keyFile)
+		}
+
+		_, issued, err := agentauth.NewCA("Gardarr agents CA", validity)
+		if err != nil {
+			return fmt.Errorf("failed to generate the CA: %w", err)
+		}
+
+		if err := os.MkdirAll(out, 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(certFile, issued.CertPEM, 0o644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(keyFile, issued.KeyPEM, 0o600); err != nil {
+			return err
+		}
+
+		fmt.Printf("CA certificate: %s\n", certFile)
+		fmt.Printf("CA key:         %s (keep it private)\n", keyFile)
+		fmt.Println()
+		fmt.Println("Manager: AGENT_CLIENT_TLS_CA_FILE=" + certFile)
+		fmt.Println("Agents:  AGENT_TLS_CLIENT_CA_FILE=" + certFile)
+		return nil
+	},
+}
+
+func init() {
+	cmd.Flags().StringVar(&out, "out", "certs", "directory the CA is written to")
+	cmd.Flags().DurationVar(&validity, "validity", 10*365*24*time.Hour, "validity of the CA")
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -0,0 +1,90 @@
+package cert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	caDir    string
+	out      string
+	role     string
+	name     string
+	hosts    []string
+	validity time.Duration
+)
+
+var cmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Issue an agent or manager certificate with the CA",
+	Long: `Issue a certificate with the CA made by "generate ca": an agent certificate,
+valid for the host names and addresses the manager reaches the agent at, or
+the manager certificate presented to the agents. The fingerprint printed for
+an agent certificate can be pinned on the agent in the manager.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certPEM, err := os.ReadFile(filepath.Join(caDir, "ca.crt"))
+		if err != nil {
+			return fmt.Errorf("failed to read the CA certificate: %w", err)
+		}
+		keyPEM, err := os.ReadFile(filepath.Join(caDir, "ca.key"))
+		if err != nil {
+			return fmt.Errorf("failed to read the CA key: %w", err)
+		}
+
+		ca, err := agentauth.LoadCA(certPEM, keyPEM)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			name = role
+		}
+		issued, err := ca.Issue(role, name, hosts, validity)
+		if err != nil {
+			return err
+		}
+
+		certFile := filepath.Join(out, name+".crt")
+		keyFile := filepath.Join(out, name+".key")
+		if err := os.MkdirAll(out, 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(certFile, issued.CertPEM, 0o644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(keyFile, issued.KeyPEM, 0o600); err != nil {
+			return err
+		}
+
+		fmt.Printf("Certificate: %s\n", certFile)
+		fmt.Printf("Key:         %s\n", keyFile)
+		fmt.Printf("SHA-256 fingerprint: %s\n", issued.Fingerprint)
+		fmt.Println()
+
+		if role == agentauth.RoleAgent {
+			fmt.Println("Agent: AGENT_TLS_CERT_FILE=" + certFile + " AGENT_TLS_KEY_FILE=" + keyFile)
+			fmt.Println("Pin the fingerprint in the certificate_fingerprint of the agent.")
+		} else {
+			fmt.Println("Manager: AGENT_CLIENT_TLS_CERT_FILE=" + certFile + " AGENT_CLIENT_TLS_KEY_FILE=" + keyFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	cmd.Flags().StringVar(&caDir, "ca-dir", "certs", "directory of the CA made by generate ca")
+	cmd.Flags().StringVar(&out, "out", "certs", "directory the certificate is written to")
+	cmd.Flags().StringVar(&role, "role", agentauth.RoleAgent, "agent or manager")
+	cmd.Flags().StringVar(&name, "name", "", "name of the certificate and its files, the role by default")
+	cmd.Flags().StringSliceVar(&hosts, "host", nil, "host name or address of the agent, repeatable")
+	cmd.Flags().DurationVar(&validity, "validity", 2*365*24*time.Hour, "validity of the certificate")
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -1,6 +1,9 @@
 package generate
 
 import (
+	"github.com/gardarr/gardarr/cmd/generate/ca"
+	"github.com/gardarr/gardarr/cmd/generate/cert"
+	"github.com/gardarr/gardarr/cmd/generate/enrollment"
 	"github.com/gardarr/gardarr/cmd/generate/key"
 	"github.com/spf13/cobra"
 )
@@ -8,11 +11,14 @@ import (
 var cmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate utilities for the application",
-	Long:  "Generate various utilities like encryption keys, configs, etc.",
+	Long:  "Generate various utilities like encryption keys, agent certificates, enrollment tokens, configs, etc.",
 }
 
 func init() {
 	cmd.AddCommand(key.Command())
+	cmd.AddCommand(ca.Command())
+	cmd.AddCommand(cert.Command())
+	cmd.AddCommand(enrollment.Command())
 }
 
 func Command() *cobra.Command {
@@ -0,0 +1,64 @@
+package enrollment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	enrollmentService "github.com/gardarr/gardarr/internal/services/enrollment"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	name       string
+	ttl        time.Duration
+	managerURL string
+)
+
+var cmd = &cobra.Command{
+	Use:   "enrollment-token",
+	Short: "Issue a single use token an agent enrolls with",
+	Long: `Issue a short-lived enrollment token. The agent registers itself with it and
+receives a secret of its own, so no secret is copied by hand:
+
+  seedbox agent --enroll <manager-url> <token>
+
+The token is valid once. It is shown only now, the database keeping its hash.
+When --name is given, the agent is registered with this name.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.NewDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the database: %w", err)
+		}
+
+		if err := database.RunMigrations(db); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+
+		// Issuing a token encrypts nothing, the crypto service is not needed
+		service := enrollmentService.NewService(db, nil)
+		token, err := service.CreateToken(context.Background(), uuid.Nil, name, ttl)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Enrollment token, valid until %s:\n", token.ExpiresAt.Format(time.RFC3339))
+		fmt.Printf("\033[1;32m%s\033[0m\n", token.Token) // Green color
+		fmt.Println()
+		fmt.Println("Run on the agent:")
+		fmt.Printf("seedbox agent --enroll %s %s\n", managerURL, token.Token)
+		return nil
+	},
+}
+
+func init() {
+	cmd.Flags().StringVar(&name, "name", "", "name the agent is registered with, defaults to the one it sends")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "lifetime of the token, defaults to AGENT_ENROLLMENT_TOKEN_TTL")
+	cmd.Flags().StringVar(&managerURL, "manager-url", "<manager-url>", "manager URL shown in the agent command")
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -1,29 +1,77 @@
 package key
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"log"
 
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/services/crypto"
 	"github.com/spf13/cobra"
 )
 
+var rotate bool
+
 var cmd = &cobra.Command{
 	Use:   "key",
 	Short: "Generate a new AES-256 encryption key",
 	Long: `Generate a secure 256-bit encryption key for encrypting sensitive data.
-This key is used to encrypt torrent client credentials in the database.`,
-	Run: func(cmd *cobra.Command, args []string) {
+This key is used to encrypt torrent client credentials in the database.
+
+With --rotate, no key is generated: the secrets stored in the database are
+encrypted again with ENCRYPTION_KEY, the previous keys being read from
+ENCRYPTION_PREVIOUS_KEYS. They can be removed once it succeeds.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rotate {
+			return rotateSecrets()
+		}
+
 		encryptionKey := generateEncryptionKey()
 		printKeyUsageInstructions(encryptionKey)
+		return nil
 	},
 }
 
+func init() {
+	cmd.Flags().BoolVar(&rotate, "rotate", false, "re-encrypt the stored secrets with the active key")
+}
+
 func Command() *cobra.Command {
 	return cmd
 }
 
+// rotateSecrets encrypts again the stored secrets with the active key
+func rotateSecrets() error {
+	cryptoService, err := crypto.NewCryptoService()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the database: %w", err)
+	}
+
+	if err := database.RunMigrations(db); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	result, err := cryptoService.Rotate(context.Background(), db)
+	if err != nil {
+		return fmt.Errorf("rotation failed, nothing was changed: %w", err)
+	}
+
+	fmt.Printf("Secrets encrypted with key %s:\n", cryptoService.ActiveKeyID())
+	fmt.Printf("- %d agent tokens\n", result.AgentTokens)
+	fmt.Printf("- %d TOTP secrets\n", result.TOTPSecrets)
+	fmt.Printf("- %d already encrypted with it\n", result.AlreadyActive)
+	fmt.Println()
+	fmt.Println("ENCRYPTION_PREVIOUS_KEYS can now be removed.")
+	return nil
+}
+
 // generateEncryptionKey generates a secure 32-byte key for AES-256
 func generateEncryptionKey() string {
 	key := make([]byte, 32) // 32 bytes = 256 bits
@@ -68,4 +116,12 @@ func printKeyUsageInstructions(encryptionKey string) {
 	fmt.Println("- Never commit this key to version control")
 	fmt.Println("- Backup this key - losing it means losing encrypted data")
 	fmt.Println("- Generate different keys for dev/staging/production")
+	fmt.Println()
+
+	fmt.Println("=== ROTATING THE KEY ===")
+	fmt.Println()
+	fmt.Println("1. Move the current key to ENCRYPTION_PREVIOUS_KEYS and set this one as ENCRYPTION_KEY")
+	fmt.Println("2. Restart the service, the stored secrets stay readable")
+	fmt.Println("3. Run: seedbox generate key --rotate")
+	fmt.Println("4. Remove ENCRYPTION_PREVIOUS_KEYS")
 }
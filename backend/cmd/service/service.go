@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,13 +15,28 @@ import (
 
 	"github.com/gardarr/gardarr/internal/constants"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/middlewares"
 	"github.com/gardarr/gardarr/internal/routes/api/v1/agents"
+	auditRoutes "github.com/gardarr/gardarr/internal/routes/api/v1/audit"
 	"github.com/gardarr/gardarr/internal/routes/api/v1/auth"
 	"github.com/gardarr/gardarr/internal/routes/api/v1/category"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/enrollment"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/events"
 	"github.com/gardarr/gardarr/internal/routes/api/v1/health"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/ratelimits"
+	ruleRoutes "github.com/gardarr/gardarr/internal/routes/api/v1/rules"
+	statsRoutes "github.com/gardarr/gardarr/internal/routes/api/v1/stats"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/torrents"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/tunnel"
+	"github.com/gardarr/gardarr/internal/routes/api/v1/users"
 	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/internal/services/agentmanager"
+	"github.com/gardarr/gardarr/internal/services/audit"
 	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gardarr/gardarr/internal/services/rule"
+	"github.com/gardarr/gardarr/internal/services/session"
+	"github.com/gardarr/gardarr/internal/services/stats"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -52,17 +68,55 @@ func Run(cmd *cobra.Command, args []string) error {
 		panic(fmt.Sprintf("erro ao rodar migrations: %v", err))
 	}
 
-	setRouter()
+	upgraded, err := cryptoSvc.UpgradeLegacy(context.Background(), db)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade the stored secrets: %w", err)
+	}
+	if n := upgraded.AgentTokens + upgraded.TOTPSecrets; n > 0 {
+		log.Printf("Bound %d stored secrets to their owner", n)
+	}
+
+	httpConfig := middlewares.LoadConfigFromEnv()
+	if err := setRouter(httpConfig); err != nil {
+		return err
+	}
+
+	agentSvc, err := agentmanager.NewService(db, cryptoSvc)
+	if err != nil {
+		return err
+	}
+
+	statsSvc := stats.NewService(db, agentSvc.Repository())
+
+	ruleSvc := rule.NewService(db, agentSvc.Repository())
+
+	sessionSvc := session.NewService(db)
 
-	agentSvc := agentmanager.NewService(db, cryptoSvc)
+	limits := ratelimit.NewLimiters(db)
 
-	setRoutes(db, agentSvc)
+	auditSvc := audit.NewService(db)
+
+	// Background jobs: agent health probes, live events, torrent clients discovery, transfer statistics sampling, automation rules, expired sessions, rate limits and audit log cleanup
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go agentSvc.HealthMonitor().Run(jobsCtx)
+	go agentSvc.EventHub().Run(jobsCtx)
+	go agentSvc.RunClientDiscovery(jobsCtx)
+	go statsSvc.Run(jobsCtx)
+	go ruleSvc.Run(jobsCtx)
+	go sessionSvc.Run(jobsCtx)
+	go limits.Run(jobsCtx)
+	go auditSvc.Run(jobsCtx)
+
+	setRoutes(httpConfig, db, cryptoSvc, agentSvc, statsSvc, ruleSvc, limits)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", env.Get(constants.AppPortEnv).Default("3000").Value()),
 		Handler: router,
 		// set timeout due CWE-400 - Potential Slowloris Attack
 		ReadHeaderTimeout: 5 * time.Second,
+		// event streams end when the background context is canceled on shutdown
+		BaseContext: func(net.Listener) context.Context { return jobsCtx },
 	}
 
 	// Initializing the server in a goroutine so that
@@ -82,6 +136,7 @@ func Run(cmd *cobra.Command, args []string) error {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -91,6 +146,9 @@ func Run(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "Server forced to shutdown: ")
 	}
 
+	// The tunnels were taken over from the server, which does not close them
+	agentSvc.Tunnels().Close()
+
 	log.Println("Server exiting")
 
 	return nil
@@ -165,9 +223,16 @@ func securityHeadersMiddleware() gin.HandlerFunc {
 	}
 }
 
-func setRouter() {
+func setRouter(httpConfig middlewares.Config) error {
 	router = gin.Default()
 
+	// Client addresses come from the real IP header only behind a trusted proxy,
+	// otherwise anyone could pick the address rate limits are keyed on
+	if err := router.SetTrustedProxies(httpConfig.TrustedProxies); err != nil {
+		return errors.Wrap(err, "invalid trusted proxies: ")
+	}
+	router.RemoteIPHeaders = []string{httpConfig.RealIPHeader}
+
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		schemas.RegisterCustomValidators(v)
 	}
@@ -176,7 +241,7 @@ func setRouter() {
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middlewares.CSRFHeaderName},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -190,34 +255,64 @@ func setRouter() {
 
 	// Setup Security Headers
 	router.Use(securityHeadersMiddleware())
+
+	return nil
 }
 
-func setRoutes(db *database.Database, a *agentmanager.Service) {
+// setRoutes registers the API and frontend routes under the base path of
+// httpConfig, the path prefix the manager is served under behind a reverse
+// proxy ("" for the root), their cookies using its settings
+func setRoutes(httpConfig middlewares.Config, db *database.Database, c *crypto.CryptoService, a *agentmanager.Service, st *stats.Service, r *rule.Service, l *ratelimit.Limiters) {
+	basePath := httpConfig.BasePath
+
 	// Get current working directory
 	wd, _ := os.Getwd()
 	webPath := filepath.Join(wd, "web")
 	assetsPath := filepath.Join(webPath, "assets")
 
-	// Serve static files from the web directory FIRST
-	router.Static("/assets", assetsPath)
-	router.StaticFile("/favicon.ico", filepath.Join(webPath, "favicon.ico"))
-	router.StaticFile("/vite.svg", filepath.Join(webPath, "vite.svg"))
+	base := router.Group(basePath)
 
-	// API routes
-	v1 := router.Group("/v1")
+	// Serve static files from the web directory FIRST
+	base.Static("/assets", assetsPath)
+	base.StaticFile("/favicon.ico", filepath.Join(webPath, "favicon.ico"))
+	base.StaticFile("/vite.svg", filepath.Join(webPath, "vite.svg"))
+
+	// API routes, every request being throttled per client
+	v1 := base.Group("/v1")
+	v1.Use(middlewares.Throttle(l.API, middlewares.ClientKey))
+	authn := middlewares.NewAuth(db, httpConfig, l)
 	health.NewModule(v1, db).Register()
-	auth.NewModule(v1, db).Register()
-	agents.NewModule(v1, a).Register()
-	category.NewModule(v1, db).Register()
+	auth.NewModule(v1, db, c, authn, l).Register()
+	users.NewModule(v1, db, c, authn).Register()
+	agents.NewModule(v1, db, authn, l, a).Register()
+	enrollment.NewModule(v1, db, c, authn, l).Register()
+	tunnel.NewModule(v1, a).Register()
+	category.NewModule(v1, db, authn).Register()
+	statsRoutes.NewModule(v1, db, authn, st).Register()
+	events.NewModule(v1, db, authn, a.EventHub()).Register()
+	ruleRoutes.NewModule(v1, authn, r).Register()
+	ratelimits.NewModule(v1, db, authn, l).Register()
+	auditRoutes.NewModule(v1, db, authn).Register()
+	torrents.NewModule(v1, authn).Register()
 
 	// Serve the main index.html for all non-API routes (SPA fallback)
 	router.NoRoute(func(c *gin.Context) {
 		// Check if the request is for an API route
-		if strings.HasPrefix(c.Request.URL.Path, "/v1/") {
+		if strings.HasPrefix(c.Request.URL.Path, basePath+"/v1/") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "API endpoint not found"})
 			return
 		}
 
+		// Outside of the base path only the root leads to the frontend
+		if !strings.HasPrefix(c.Request.URL.Path, basePath+"/") {
+			if c.Request.URL.Path == "/" || c.Request.URL.Path == basePath {
+				c.Redirect(http.StatusMovedPermanently, basePath+"/")
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+
 		// For all other routes, serve the index.html (SPA fallback)
 		indexPath := filepath.Join("./web", "index.html")
 		if _, err := os.Stat(indexPath); err == nil {
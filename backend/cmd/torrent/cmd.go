@@ -0,0 +1,20 @@
+package torrent
+
+import (
+	"github.com/gardarr/gardarr/cmd/torrent/inspect"
+	"github.com/spf13/cobra"
+)
+
+var cmd = &cobra.Command{
+	Use:   "torrent",
+	Short: "Work with .torrent files",
+	Long:  "Work with .torrent files locally, without a manager or an agent.",
+}
+
+func init() {
+	cmd.AddCommand(inspect.Command())
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -0,0 +1,118 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/torrent"
+	"github.com/spf13/cobra"
+)
+
+var asJSON bool
+
+var cmd = &cobra.Command{
+	Use:   "inspect <file>",
+	Short: "Print the metainfo of a .torrent file",
+	Long: `Print the name, infohashes, trackers, web seeds, pieces and files of a
+.torrent file, along with its magnet link. v1, v2 and hybrid torrents are read.
+
+With --json, the metainfo is printed as POST /v1/torrents/inspect answers it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		meta, err := torrent.ParseMetaInfo(data)
+		if err != nil {
+			return fmt.Errorf("invalid torrent file: %w", err)
+		}
+
+		response := mappers.ToTorrentInspectResponse(meta)
+		if asJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(response)
+		}
+
+		return printMetaInfo(cmd.OutOrStdout(), response)
+	},
+}
+
+func init() {
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the metainfo as JSON")
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
+
+// readFile reads a .torrent file, bounded as the uploaded ones are
+func readFile(name string) ([]byte, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, schemas.MaxTorrentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > schemas.MaxTorrentSize {
+		return nil, fmt.Errorf("torrent file larger than %d bytes", schemas.MaxTorrentSize)
+	}
+
+	return data, nil
+}
+
+func printMetaInfo(out io.Writer, r models.TorrentInspectResponse) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", r.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", r.Version)
+	if r.InfoHashV1 != "" {
+		fmt.Fprintf(w, "Infohash v1:\t%s\n", r.InfoHashV1)
+	}
+	if r.InfoHashV2 != "" {
+		fmt.Fprintf(w, "Infohash v2:\t%s\n", r.InfoHashV2)
+	}
+	fmt.Fprintf(w, "Size:\t%d bytes\n", r.Size)
+	fmt.Fprintf(w, "Pieces:\t%d of %d bytes\n", r.PieceCount, r.PieceLength)
+	fmt.Fprintf(w, "Private:\t%t\n", r.Private)
+	if r.Comment != "" {
+		fmt.Fprintf(w, "Comment:\t%s\n", r.Comment)
+	}
+	if r.CreatedBy != "" {
+		fmt.Fprintf(w, "Created by:\t%s\n", r.CreatedBy)
+	}
+	if r.CreationDate != nil {
+		fmt.Fprintf(w, "Created on:\t%s\n", r.CreationDate.Format(time.RFC3339))
+	}
+	for i, tier := range r.Trackers {
+		fmt.Fprintf(w, "Tier %d:\t%s\n", i+1, strings.Join(tier, " "))
+	}
+	for _, seed := range r.WebSeeds {
+		fmt.Fprintf(w, "Web seed:\t%s\n", seed)
+	}
+	fmt.Fprintf(w, "Magnet:\t%s\n", r.MagnetURI)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nFiles (%d):\n", len(r.Files))
+	for _, file := range r.Files {
+		fmt.Fprintf(out, "  %12d  %s\n", file.Size, file.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package user
+
+import (
+	"github.com/gardarr/gardarr/cmd/user/reset"
+	"github.com/spf13/cobra"
+)
+
+var cmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users of the manager",
+	Long:  "Manage the users of the manager from the server, for instance when no admin can log in.",
+}
+
+func init() {
+	cmd.AddCommand(reset.Command())
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -0,0 +1,47 @@
+package reset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/spf13/cobra"
+)
+
+var email string
+
+var cmd = &cobra.Command{
+	Use:   "reset-password",
+	Short: "Issue a single use password reset token for a user",
+	Long: `Issue a single use token to set a new password for a user, replacing the
+previous tokens of the user. The token is used with POST /v1/auth/password/reset.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.NewDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the database: %w", err)
+		}
+
+		if err := database.RunMigrations(db); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+
+		reset, err := user.NewService(db).IssuePasswordResetByEmail(context.Background(), email)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Password reset token for %s (expires %s):\n", email, reset.ExpiresAt.Format("2006-01-02 15:04:05 MST"))
+		fmt.Println(reset.Token)
+		return nil
+	},
+}
+
+func init() {
+	cmd.Flags().StringVar(&email, "email", "", "email of the user")
+	_ = cmd.MarkFlagRequired("email")
+}
+
+func Command() *cobra.Command {
+	return cmd
+}
@@ -3,6 +3,27 @@ package constants
 const (
 	AppDomainsEnv  = "APP_DOMAINS"
 	AppPortEnv     = "APP_PORT"
+	AppBasePathEnv = "APP_BASE_PATH"
 	AgentPortEnv   = "AGENT_PORT"
 	AgentSecretEnv = "AGENT_SECRET"
+	AgentClientEnv = "AGENT_CLIENT"
+
+	AgentClientsFileEnv = "AGENT_CLIENTS_FILE"
+
+	AgentTLSCertFileEnv      = "AGENT_TLS_CERT_FILE"
+	AgentTLSKeyFileEnv       = "AGENT_TLS_KEY_FILE"
+	AgentTLSClientCAFileEnv  = "AGENT_TLS_CLIENT_CA_FILE"
+	AgentRequireSignedEnv    = "AGENT_REQUIRE_SIGNED_REQUESTS"
+	AgentSignatureMaxSkewEnv = "AGENT_SIGNATURE_MAX_SKEW"
+
+	AgentIdentityFileEnv     = "AGENT_IDENTITY_FILE"
+	AgentAdvertiseAddressEnv = "AGENT_ADVERTISE_ADDRESS"
+	AgentTunnelURLEnv        = "AGENT_TUNNEL_URL"
+	AgentTunnelCAFileEnv     = "AGENT_TUNNEL_CA_FILE"
+	AgentIDEnv               = "AGENT_ID"
+
+	AgentTorrentURLAllowedNetworksEnv = "AGENT_TORRENT_URL_ALLOWED_NETWORKS"
+
+	AppTrustedProxiesEnv = "APP_TRUSTED_PROXIES"
+	AppRealIPHeaderEnv   = "APP_REAL_IP_HEADER"
 )
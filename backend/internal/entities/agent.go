@@ -9,13 +9,43 @@ const (
 )
 
 type Agent struct {
-	UUID     uuid.UUID
-	Name     string
-	Token    string
-	Address  string
-	Status   string
-	Error    string
-	Icon     string // Optional icon for frontend display
-	Color    string // Optional color for frontend display
-	Instance *Instance
+	UUID    uuid.UUID
+	Name    string
+	Token   string
+	Type    string // torrent client driven by the agent, see Clients
+	Address string
+	Status  string
+	Error   string
+	Icon    string // Optional icon for frontend display
+	Color   string // Optional color for frontend display
+	// CertificateFingerprint pins the TLS certificate of the agent, SHA-256 in hex
+	CertificateFingerprint string
+	Instance               *Instance
+	Health                 *AgentHealth
+	// Tunnel tells whether the agent is connected through a tunnel it opened
+	Tunnel bool
+	// Parent is the agent managing the torrent client of a child agent, which
+	// is reached through it; nil for an agent running its own torrent client
+	Parent *Agent
+	// Client is the name of the torrent client of a child agent on its parent
+	Client string
+}
+
+// Host returns the agent the requests of a are sent to: the parent of a child
+// agent, a itself otherwise
+func (a *Agent) Host() *Agent {
+	if a.Parent != nil {
+		return a.Parent
+	}
+	return a
+}
+
+// AgentClient is a torrent client listed by an agent managing several
+type AgentClient struct {
+	Name string
+	Type string
+	// Default tells whether the client also answers the routes outside /v1/clients
+	Default bool
+	Status  string
+	Error   string
 }
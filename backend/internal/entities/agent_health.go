@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentHealth is the last known health state of an agent, as observed by the health monitor
+type AgentHealth struct {
+	AgentUUID            uuid.UUID
+	Status               string
+	LastSeenAt           *time.Time // last successful probe
+	LastCheckAt          time.Time
+	Latency              time.Duration
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	LastError            string
+}
+
+// AgentHealthEvent records a status transition of an agent
+type AgentHealthEvent struct {
+	ID             uuid.UUID
+	AgentUUID      uuid.UUID
+	PreviousStatus string
+	Status         string
+	Latency        time.Duration
+	Error          string
+	CreatedAt      time.Time
+}
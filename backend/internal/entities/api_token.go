@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API token scopes
+const (
+	ScopeTasksRead   = "tasks:read"   // read agents and their tasks
+	ScopeTasksWrite  = "tasks:write"  // add tasks and act on them
+	ScopeAgentsAdmin = "agents:admin" // create, update and delete agents
+)
+
+// Scopes lists the API token scopes
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeAgentsAdmin}
+
+// APIToken authenticates scripts as its user, limited to its scopes
+type APIToken struct {
+	ID         uuid.UUID
+	UserUUID   uuid.UUID
+	Name       string
+	Prefix     string // first characters of the token, to recognize it
+	Token      string // only set on creation, the hash is stored
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope tells whether the token was granted the scope
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
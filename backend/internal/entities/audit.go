@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Security audit actions
+const (
+	AuditRegister          = "auth.register"
+	AuditLogin             = "auth.login"
+	AuditLoginFailed       = "auth.login_failed"
+	AuditLockout           = "auth.lockout"
+	AuditLogout            = "auth.logout"
+	AuditLogoutAll         = "auth.logout_all"
+	AuditPasswordChange    = "auth.password_change"
+	AuditPasswordReset     = "auth.password_reset"
+	AuditTwoFactorEnable   = "auth.2fa_enable"
+	AuditTwoFactorDisable  = "auth.2fa_disable"
+	AuditSessionRevoke     = "session.revoke"
+	AuditTokenCreate       = "token.create"
+	AuditTokenRevoke       = "token.revoke"
+	AuditAgentCreate       = "agent.create"
+	AuditAgentUpdate       = "agent.update"
+	AuditAgentTokenChange  = "agent.token_change"
+	AuditAgentDelete       = "agent.delete"
+	AuditAgentEnroll       = "agent.enroll"
+	AuditEnrollmentCreate  = "enrollment_token.create"
+	AuditEnrollmentRevoke  = "enrollment_token.revoke"
+	AuditCategoryCreate    = "category.create"
+	AuditCategoryUpdate    = "category.update"
+	AuditCategoryDelete    = "category.delete"
+	AuditRateLimitUnblock  = "ratelimit.unblock"
+	AuditTaskDelete        = "task.delete"
+	AuditUserInvite        = "user.invite"
+	AuditUserRoleChange    = "user.role_change"
+	AuditUserDisable       = "user.disable"
+	AuditUserEnable        = "user.enable"
+	AuditUserAgents        = "user.agents_change"
+	AuditUserTwoFactor     = "user.2fa_reset"
+	AuditUserPasswordReset = "user.password_reset"
+)
+
+// Kinds of the objects an audit event targets
+const (
+	AuditTargetUser            = "user"
+	AuditTargetSession         = "session"
+	AuditTargetAPIToken        = "api_token"
+	AuditTargetAgent           = "agent"
+	AuditTargetCategory        = "category"
+	AuditTargetTask            = "task"
+	AuditTargetClient          = "client"
+	AuditTargetEnrollmentToken = "enrollment_token"
+)
+
+// AuditEvent records a security relevant action. The actor is unset for
+// anonymous requests, e.g. a failed login, ActorEmail then holds the email tried.
+type AuditEvent struct {
+	ID         uuid.UUID
+	Action     string
+	ActorUUID  uuid.UUID
+	ActorEmail string
+	IPAddress  string
+	UserAgent  string
+	TargetType string
+	TargetID   string
+	Detail     string
+	CreatedAt  time.Time
+}
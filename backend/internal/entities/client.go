@@ -0,0 +1,62 @@
+package entities
+
+// Torrent clients an agent can drive
+const (
+	ClientQBittorrent  = "qbittorrent"
+	ClientTransmission = "transmission"
+	ClientDeluge       = "deluge"
+)
+
+// Clients lists the supported torrent clients
+var Clients = []string{ClientQBittorrent, ClientTransmission, ClientDeluge}
+
+// ClientCapabilities tells which optional task actions a torrent client supports
+type ClientCapabilities struct {
+	Categories   bool
+	Tags         bool
+	SuperSeeding bool
+	ForceStart   bool
+	Rename       bool
+	ShareLimits  bool
+	// ContentLayout, SkipChecking and SequentialDownload are the options of
+	// schemas.TaskCreateSchema supported when adding a task
+	ContentLayout      bool
+	SkipChecking       bool
+	SequentialDownload bool
+}
+
+// CapabilitiesOf returns the capabilities of a torrent client. Agents registered
+// before the client was recorded have no client and run qBittorrent.
+func CapabilitiesOf(client string) ClientCapabilities {
+	switch client {
+	case ClientTransmission:
+		return ClientCapabilities{
+			Tags:        true,
+			ForceStart:  true,
+			Rename:      true,
+			ShareLimits: true,
+		}
+	case ClientDeluge:
+		// categories are the labels of the Label plugin
+		return ClientCapabilities{
+			Categories:         true,
+			SuperSeeding:       true,
+			Rename:             true,
+			ShareLimits:        true,
+			SkipChecking:       true,
+			SequentialDownload: true,
+		}
+	default:
+		return ClientCapabilities{
+			Categories:         true,
+			Tags:               true,
+			SuperSeeding:       true,
+			ForceStart:         true,
+			Rename:             true,
+			ShareLimits:        true,
+			ContentLayout:      true,
+			SkipChecking:       true,
+			SequentialDownload: true,
+		}
+	}
+}
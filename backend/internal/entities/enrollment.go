@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnrollmentToken lets an agent register itself once, see the agent --enroll flag
+type EnrollmentToken struct {
+	ID        uuid.UUID
+	Name      string // name given to the agent, else the one it sends
+	Token     string // only set on creation, the hash is stored
+	CreatedBy uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	AgentUUID *uuid.UUID // agent registered with the token
+	CreatedAt time.Time
+}
+
+// Enrollment is an agent registered with an enrollment token and the secret
+// generated for it, only known by the agent afterwards
+type Enrollment struct {
+	Agent  *Agent
+	Secret string
+	Token  *EnrollmentToken // token the agent enrolled with
+}
@@ -0,0 +1,24 @@
+package entities
+
+import "github.com/google/uuid"
+
+// Live event types published by the agents and re-exposed by the manager
+const (
+	EventSnapshot          = "snapshot"
+	EventTaskAdded         = "task.added"
+	EventTaskUpdated       = "task.updated"
+	EventTaskRemoved       = "task.removed"
+	EventInstanceUpdated   = "instance.updated"
+	EventAgentDisconnected = "agent.disconnected"
+)
+
+// Event is a live change of an agent state. Only the fields relevant to the
+// event type are set.
+type Event struct {
+	Type      string
+	AgentUUID uuid.UUID // set by the manager
+	Task      *Task     // task.added, task.updated
+	TaskID    string    // task.removed
+	Tasks     []*Task   // snapshot
+	Instance  *Instance // snapshot, instance.updated
+}
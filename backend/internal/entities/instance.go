@@ -18,6 +18,7 @@ type InstancePreferencesGlobalRateLimits struct {
 }
 
 type InstanceApplication struct {
+	Client     string // torrent client driven by the agent, see Clients
 	Version    string
 	APIVersion string
 }
@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// OIDCLogin is a single sign-on started, the browser being sent to URL. State
+// binds the flow to the browser.
+type OIDCLogin struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// RateLimitEntry is the rate limiting state of one identifier under one policy
+type RateLimitEntry struct {
+	Key          string    // policy name and identifier, "login_ip:203.0.113.7"
+	Count        int       // failed attempts in the current window (lockout)
+	WindowStart  time.Time // start of the attempts window, or last refill of the bucket
+	BlockedUntil time.Time // zero when not blocked (lockout)
+	Tokens       float64   // requests left in the bucket (token bucket)
+	ExpiresAt    time.Time // after which the entry no longer matters
+}
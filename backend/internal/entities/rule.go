@@ -0,0 +1,99 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Task fields a rule condition can test
+const (
+	RuleFieldName         = "name"
+	RuleFieldRatio        = "ratio"
+	RuleFieldSeedingTime  = "seeding_time"
+	RuleFieldState        = "state"
+	RuleFieldCategory     = "category"
+	RuleFieldTags         = "tags"
+	RuleFieldSize         = "size"
+	RuleFieldProgress     = "progress"
+	RuleFieldTracker      = "tracker"
+	RuleFieldSwarmSeeders = "swarm_seeders"
+	RuleFieldAgent        = "agent"
+)
+
+// Rule condition operators
+const (
+	RuleOperatorEqual       = "eq"
+	RuleOperatorNotEqual    = "neq"
+	RuleOperatorGreater     = "gt"
+	RuleOperatorGreaterOrEq = "gte"
+	RuleOperatorLess        = "lt"
+	RuleOperatorLessOrEq    = "lte"
+	RuleOperatorContains    = "contains"
+	RuleOperatorNotContains = "not_contains"
+	RuleOperatorIn          = "in"
+	RuleOperatorNotIn       = "not_in"
+	RuleOperatorMatches     = "matches"
+)
+
+// Rule actions
+const (
+	RuleActionStop             = "stop"
+	RuleActionDelete           = "delete"
+	RuleActionMove             = "move"
+	RuleActionRetag            = "retag"
+	RuleActionRecategorize     = "recategorize"
+	RuleActionSetShareLimit    = "set_share_limit"
+	RuleActionSetDownloadLimit = "set_download_limit"
+	RuleActionSetUploadLimit   = "set_upload_limit"
+	RuleActionReannounce       = "reannounce"
+)
+
+// Rule is a user-defined automation: every task matching all the conditions
+// gets the actions applied, in order
+type Rule struct {
+	UUID       uuid.UUID
+	Name       string
+	Enabled    bool
+	DryRun     bool          // only record what the actions would do
+	Interval   time.Duration // time between two evaluations
+	Conditions []RuleCondition
+	Actions    []RuleAction
+	LastRunAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type RuleCondition struct {
+	Field    string
+	Operator string
+	Value    string
+	Values   []string // for the in and not_in operators
+}
+
+type RuleAction struct {
+	Type             string
+	DeleteFiles      bool     // delete
+	Location         string   // move
+	Tags             []string // retag
+	Category         string   // recategorize
+	RatioLimit       float64  // set_share_limit
+	SeedingTimeLimit int      // set_share_limit, in minutes
+	Limit            int      // set_download_limit and set_upload_limit, in bytes per second
+}
+
+// RuleExecution is an action a rule applied, or would apply in dry-run, to a task
+type RuleExecution struct {
+	ID        uuid.UUID
+	RuleUUID  uuid.UUID
+	RuleName  string
+	AgentUUID uuid.UUID
+	AgentName string
+	TaskHash  string
+	TaskName  string
+	Action    string
+	Detail    string
+	DryRun    bool
+	Error     string
+	CreatedAt time.Time
+}
@@ -7,11 +7,12 @@ import (
 )
 
 type Session struct {
-	ID        uuid.UUID
-	UserUUID  uuid.UUID
-	Token     string
-	UserAgent string
-	IPAddress string
-	ExpiresAt time.Time
-	CreatedAt time.Time
+	ID         uuid.UUID
+	UserUUID   uuid.UUID
+	Token      string // only set when the session is created, the database keeps its hash
+	UserAgent  string
+	IPAddress  string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
 }
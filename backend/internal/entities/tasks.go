@@ -19,6 +19,10 @@ type Task struct {
 	Pairs      TaskPairs
 	NumSeeds   int
 	Network    TaskNetwork
+	// SeedingTime is the time spent seeding, in seconds
+	SeedingTime int
+	// Tracker is the tracker currently announced to
+	Tracker string
 }
 
 type TaskMagnetLink struct {
@@ -58,6 +62,24 @@ type TaskCreationConfig struct {
 	Directory string
 }
 
+// TaskPreview describes the task a source would add, before adding it
+type TaskPreview struct {
+	Name string
+	Size int64
+	// InfoHashV1 and InfoHashV2 are the infohashes known from the source, a
+	// magnet link giving a single one
+	InfoHashV1 string
+	InfoHashV2 string
+	Files      []TaskPreviewFile
+	// Existing is the task already added from the same torrent, if any
+	Existing *Task
+}
+
+type TaskPreviewFile struct {
+	Path string
+	Size int64
+}
+
 type TaskFile struct {
 	Name         string
 	Size         int64
@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferScopeInstance = "instance"
+	TransferScopeTask     = "task"
+)
+
+// Transfer sample resolutions, from the finest to the coarsest retention tier
+const (
+	TransferResolutionRaw    = "raw"
+	TransferResolutionMinute = "1m"
+	TransferResolutionHour   = "1h"
+	TransferResolutionDay    = "1d"
+)
+
+// TransferSample is a point of a transfer series. Raw samples hold the counters read
+// from the agent; downsampled ones cover a whole bucket starting at Timestamp.
+type TransferSample struct {
+	AgentUUID  uuid.UUID
+	Scope      string
+	TaskHash   string // empty for instance samples
+	Category   string
+	Resolution string
+	Timestamp  time.Time
+
+	// Cumulative counters at the end of the sample, as reported by the client
+	Downloaded int64
+	Uploaded   int64
+	Ratio      float64
+
+	// Volume transferred since the previous sample of the series
+	DownloadedDelta int64
+	UploadedDelta   int64
+}
+
+// TransferSeries is the aggregated transfer volume of a range, split in points of Step
+type TransferSeries struct {
+	Category     string
+	From         time.Time
+	To           time.Time
+	Step         time.Duration
+	Downloaded   int64
+	Uploaded     int64
+	Ratio        float64 // uploaded / downloaded within the range
+	CurrentRatio float64 // last ratio reported by the client, when available
+	Points       []TransferPoint
+}
+
+type TransferPoint struct {
+	Timestamp  time.Time
+	Downloaded int64
+	Uploaded   int64
+	Ratio      float64
+}
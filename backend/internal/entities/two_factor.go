@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// TwoFactorStatus describes the second factor of a user
+type TwoFactorStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+// TOTPEnrollment is a TOTP secret waiting to be confirmed with a first code
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// LoginChallenge is issued when the password of a user with a second factor
+// is verified, the session is created once the challenge is answered
+type LoginChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
@@ -1,15 +1,61 @@
 package entities
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// User roles, from the most to the least privileged
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// Roles lists the user roles, from the least to the most privileged
+var Roles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
 type User struct {
 	UUID      uuid.UUID
 	Username  string
 	Email     string
+	Role      string
+	Disabled  bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// HasRole tells whether the user is active and has at least the given role
+func (u *User) HasRole(role string) bool {
+	if u == nil || u.Disabled {
+		return false
+	}
+
+	required := slices.Index(Roles, role)
+	return required >= 0 && slices.Index(Roles, u.Role) >= required
+}
+
+// IsAdmin tells whether the user is an active admin, admins have access to every agent
+func (u *User) IsAdmin() bool {
+	return u.HasRole(RoleAdmin)
+}
+
+// UserInvite lets someone register with a role while registration is closed
+type UserInvite struct {
+	ID        uuid.UUID
+	Email     string
+	Role      string
+	Token     string // only set on creation, the hash is stored
+	InvitedBy uuid.UUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// PasswordReset lets a user set a new password once, without the current one
+type PasswordReset struct {
+	UserUUID  uuid.UUID
+	Token     string // only set on creation, the hash is stored
+	ExpiresAt time.Time
+}
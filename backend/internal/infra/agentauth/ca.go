@@ -0,0 +1,164 @@
+package agentauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Roles of the certificates issued by the CA
+const (
+	RoleAgent   = "agent"   // served by an agent
+	RoleManager = "manager" // presented by the manager to the agents
+)
+
+// CA issues the certificates of the agents and of the manager
+type CA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// Issued is a certificate and its private key, PEM encoded
+type Issued struct {
+	CertPEM     []byte
+	KeyPEM      []byte
+	Fingerprint string
+}
+
+// NewCA generates a self-signed CA
+func NewCA(commonName string, validity time.Duration) (*CA, *Issued, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"Gardarr"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	issued, cert, err := encode(template, template, &key.PublicKey, key, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &CA{cert: cert, key: key}, issued, nil
+}
+
+// LoadCA loads a CA from its PEM certificate and key
+func LoadCA(certPEM, keyPEM []byte) (*CA, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the CA: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("the certificate is not a CA")
+	}
+
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the CA key must be an ECDSA key")
+	}
+
+	return &CA{cert: cert, key: key}, nil
+}
+
+// Issue issues a certificate for an agent, valid for its host names and
+// addresses, or for the manager
+func (ca *CA) Issue(role, commonName string, hosts []string, validity time.Duration) (*Issued, error) {
+	var usage []x509.ExtKeyUsage
+	switch role {
+	case RoleAgent:
+		usage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		if len(hosts) == 0 {
+			return nil, errors.New("an agent certificate needs at least one host")
+		}
+	case RoleManager:
+		usage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		return nil, fmt.Errorf("role must be %s or %s, got %q", RoleAgent, RoleManager, role)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Gardarr"}, OrganizationalUnit: []string{role}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	issued, _, err := encode(template, ca.cert, &key.PublicKey, ca.key, key)
+	return issued, err
+}
+
+func encode(template, parent *x509.Certificate, public *ecdsa.PublicKey, signer, key *ecdsa.PrivateKey) (*Issued, *x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, public, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Issued{
+		CertPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		Fingerprint: Fingerprint(cert),
+	}, cert, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,178 @@
+package agentauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of a signed request
+const (
+	TimestampHeader = "X-Gardarr-Timestamp"
+	NonceHeader     = "X-Gardarr-Nonce"
+	SignatureHeader = "X-Gardarr-Signature"
+)
+
+var (
+	ErrUnsigned         = errors.New("request is not signed")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrStaleRequest     = errors.New("request timestamp outside the allowed skew")
+	ErrReplayedRequest  = errors.New("request nonce already used")
+)
+
+// nonceSize is the number of random bytes of a nonce
+const nonceSize = 16
+
+// signingLabel separates the signing key from the agent secret it is derived from
+const signingLabel = "gardarr agent request signature"
+
+// Sign signs a request with a key derived from the agent secret: an HMAC-SHA256
+// of its method, URI, timestamp, a random nonce and the SHA-256 of its body. The
+// secret itself is not sent. The agent refuses a signature made too long ago or
+// seen before, see Verifier.
+func Sign(req *http.Request, secret string, body []byte, now time.Time) error {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	encodedNonce := hex.EncodeToString(nonce)
+
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, encodedNonce)
+	req.Header.Set(SignatureHeader, signature(secret, req.Method, req.URL.RequestURI(), timestamp, encodedNonce, body))
+	return nil
+}
+
+// Signed tells whether a request carries a signature
+func Signed(r *http.Request) bool {
+	return r.Header.Get(SignatureHeader) != ""
+}
+
+// signingKey derives the key the requests are signed with from the agent secret
+func signingKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingLabel))
+	return mac.Sum(nil)
+}
+
+func signature(secret, method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, signingKey(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, uri, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks the request signatures, remembering their nonces while
+// their timestamp is within the allowed skew so a request cannot be replayed
+type Verifier struct {
+	maxSkew time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time // nonce -> when it can be forgotten
+	lastPrune time.Time
+}
+
+// NewVerifier creates a verifier accepting the requests signed at most maxSkew
+// before or after its clock
+func NewVerifier(maxSkew time.Duration) *Verifier {
+	return &Verifier{
+		maxSkew: maxSkew,
+		now:     time.Now,
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+// CheckHeaders checks the timestamp and nonce of a signed request, so that a
+// request signed too long ago or replayed is refused before its body is read
+func (v *Verifier) CheckHeaders(r *http.Request) error {
+	_, err := v.checkHeaders(r)
+	return err
+}
+
+// Verify checks the signature of a request with the agent secret. The body is
+// the one the request was read with.
+func (v *Verifier) Verify(r *http.Request, secret string, body []byte) error {
+	signedAt, err := v.checkHeaders(r)
+	if err != nil {
+		return err
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	nonce := r.Header.Get(NonceHeader)
+	expected := signature(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(r.Header.Get(SignatureHeader)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Checked again, the same request may have been verified meanwhile
+	if _, seen := v.nonces[nonce]; seen {
+		return ErrReplayedRequest
+	}
+	// Past this, the timestamp is refused anyway
+	v.nonces[nonce] = signedAt.Add(v.maxSkew)
+
+	return nil
+}
+
+// checkHeaders checks everything but the signature itself and returns when
+// the request was signed
+func (v *Verifier) checkHeaders(r *http.Request) (time.Time, error) {
+	timestamp := r.Header.Get(TimestampHeader)
+	nonce := r.Header.Get(NonceHeader)
+	if r.Header.Get(SignatureHeader) == "" || timestamp == "" || nonce == "" {
+		return time.Time{}, ErrUnsigned
+	}
+
+	if decoded, err := hex.DecodeString(nonce); err != nil || len(decoded) != nonceSize {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidSignature
+	}
+	signedAt := time.Unix(seconds, 0)
+
+	now := v.now()
+	if signedAt.Before(now.Add(-v.maxSkew)) || signedAt.After(now.Add(v.maxSkew)) {
+		return time.Time{}, ErrStaleRequest
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.prune(now)
+	if _, seen := v.nonces[nonce]; seen {
+		return time.Time{}, ErrReplayedRequest
+	}
+
+	return signedAt, nil
+}
+
+// prune forgets the expired nonces, at most once per skew
+func (v *Verifier) prune(now time.Time) {
+	if now.Sub(v.lastPrune) < v.maxSkew {
+		return
+	}
+	v.lastPrune = now
+
+	for nonce, expiresAt := range v.nonces {
+		if now.After(expiresAt) {
+			delete(v.nonces, nonce)
+		}
+	}
+}
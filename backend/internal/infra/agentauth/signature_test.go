@@ -0,0 +1,104 @@
+package agentauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	verifier := NewVerifier(5 * time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	body := []byte(`{"urls":["magnet:?xt=urn:btih:abc"]}`)
+	signed := func(signedAt time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/?category=tv", strings.NewReader(string(body)))
+		if err := Sign(req, "secret", body, signedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return req
+	}
+
+	req := signed(now)
+	if err := verifier.Verify(req, "secret", body); err != nil {
+		t.Fatalf("Expected the signature to be valid, got %v", err)
+	}
+
+	// The same request cannot be sent twice
+	if err := verifier.Verify(req, "secret", body); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Expected ErrReplayedRequest, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		secret   string
+		body     []byte
+		expected error
+	}{
+		{"wrong secret", signed(now), "other", body, ErrInvalidSignature},
+		{"tampered body", signed(now), "secret", []byte(`{"urls":[]}`), ErrInvalidSignature},
+		{"too old", signed(now.Add(-6 * time.Minute)), "secret", body, ErrStaleRequest},
+		{"in the future", signed(now.Add(6 * time.Minute)), "secret", body, ErrStaleRequest},
+		{"unsigned", httptest.NewRequest(http.MethodGet, "/v1/tasks/", nil), "secret", nil, ErrUnsigned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifier.Verify(tt.req, tt.secret, tt.body); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// The headers are checked alone before the body is read
+	if err := verifier.CheckHeaders(signed(now.Add(-6 * time.Minute))); !errors.Is(err, ErrStaleRequest) {
+		t.Errorf("Expected ErrStaleRequest, got %v", err)
+	}
+	if err := verifier.CheckHeaders(req); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Expected ErrReplayedRequest, got %v", err)
+	}
+	req = signed(now)
+	req.Header.Set(NonceHeader, "not-hex")
+	if err := verifier.CheckHeaders(req); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for a malformed nonce, got %v", err)
+	}
+
+	// A tampered URI is refused
+	req = signed(now)
+	req.URL.RawQuery = "category=movies"
+	if err := verifier.Verify(req, "secret", body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestVerify_ForgetsExpiredNonces(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	verifier := NewVerifier(time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/instance/", nil)
+	if err := Sign(req, "secret", nil, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := verifier.Verify(req, "secret", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(3 * time.Minute)
+	next := httptest.NewRequest(http.MethodGet, "/v1/instance/", nil)
+	if err := Sign(next, "secret", nil, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := verifier.Verify(next, "secret", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(verifier.nonces) != 1 {
+		t.Errorf("Expected the expired nonce to be forgotten, got %d nonces", len(verifier.nonces))
+	}
+}
@@ -0,0 +1,140 @@
+package agentauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidFingerprint  = errors.New("certificate fingerprint must be a SHA-256 in hex")
+	ErrFingerprintMismatch = errors.New("agent certificate does not match its pinned fingerprint")
+)
+
+// Fingerprint returns the SHA-256 of a certificate, in lowercase hex
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint accepts a SHA-256 fingerprint in hex, with or without
+// colons as printed by openssl, and returns it in lowercase without them
+func NormalizeFingerprint(value string) (string, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), ":", ""))
+	if decoded, err := hex.DecodeString(normalized); err != nil || len(decoded) != sha256.Size {
+		return "", ErrInvalidFingerprint
+	}
+	return normalized, nil
+}
+
+// ServerTLSConfig loads the certificate an agent serves. With a client CA, the
+// agent only accepts the connections of a client certificate it issued.
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the agent certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// ClientTLSConfig loads the CA the agent certificates are verified with,
+// instead of the system ones, and the client certificate presented to them.
+// Every file is optional.
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the manager certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Pin returns a copy of the TLS configuration only accepting the agent
+// certificate of the fingerprint. When the configuration has a CA, the
+// certificate must also be issued by it; otherwise the pin alone is trusted,
+// so self-signed agent certificates can be used.
+func Pin(config *tls.Config, fingerprint string) *tls.Config {
+	pinned := config.Clone()
+	if pinned == nil {
+		pinned = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	roots := pinned.RootCAs
+
+	// The chain is verified below, only when a CA is configured
+	pinned.InsecureSkipVerify = true
+	pinned.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrFingerprintMismatch
+		}
+		leaf := state.PeerCertificates[0]
+
+		if subtle.ConstantTimeCompare([]byte(Fingerprint(leaf)), []byte(fingerprint)) != 1 {
+			return ErrFingerprintMismatch
+		}
+
+		if roots == nil {
+			return nil
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			DNSName:       state.ServerName,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+
+	return pinned
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+
+	return pool, nil
+}
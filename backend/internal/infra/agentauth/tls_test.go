@@ -0,0 +1,184 @@
+package agentauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeFingerprint(t *testing.T) {
+	const hex = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      bool
+	}{
+		{"plain", hex, hex, false},
+		{"openssl format", "9F:86:D0:81:88:4C:7D:65:9A:2F:EA:A0:C5:5A:D0:15:A3:BF:4F:1B:2B:0B:82:2C:D1:5D:6C:15:B0:F0:0A:08", hex, false},
+		{"too short", "9f86d081", "", true},
+		{"not hex", "zz86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeFingerprint(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// startAgent serves a TLS agent with a certificate of the CA, requiring a
+// client certificate of it
+func startAgent(t *testing.T, ca *CA, caFile string) (*httptest.Server, *Issued) {
+	issued, err := ca.Issue(RoleAgent, "agent", []string{"127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue agent certificate: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "agent.crt"), filepath.Join(dir, "agent.key")
+	os.WriteFile(certFile, issued.CertPEM, 0o600)
+	os.WriteFile(keyFile, issued.KeyPEM, 0o600)
+
+	config, err := ServerTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, issued
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, caIssued, err := NewCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	os.WriteFile(caFile, caIssued.CertPEM, 0o600)
+
+	server, agentCert := startAgent(t, ca, caFile)
+
+	manager, err := ca.Issue(RoleManager, "manager", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue manager certificate: %v", err)
+	}
+	managerCert, managerKey := filepath.Join(dir, "manager.crt"), filepath.Join(dir, "manager.key")
+	os.WriteFile(managerCert, manager.CertPEM, 0o600)
+	os.WriteFile(managerKey, manager.KeyPEM, 0o600)
+
+	get := func(config *tls.Config) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		defer client.CloseIdleConnections()
+
+		response, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		return nil
+	}
+
+	withCert, err := ClientTLSConfig(caFile, managerCert, managerKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := get(withCert); err != nil {
+		t.Errorf("Expected the manager to connect, got %v", err)
+	}
+
+	withoutCert, err := ClientTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := get(withoutCert); err == nil {
+		t.Error("Expected a client without certificate to be refused")
+	}
+
+	if err := get(Pin(withCert, agentCert.Fingerprint)); err != nil {
+		t.Errorf("Expected the pinned certificate to be accepted, got %v", err)
+	}
+
+	other, _ := ca.Issue(RoleAgent, "other", []string{"127.0.0.1"}, time.Hour)
+	if err := get(Pin(withCert, other.Fingerprint)); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("Expected ErrFingerprintMismatch, got %v", err)
+	}
+}
+
+func TestPin_SelfSigned(t *testing.T) {
+	// An agent certificate of another CA, only trusted by its pin
+	ca, _, err := NewCA("agent CA", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	server, agentCert := startAgent(t, ca, "")
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: Pin(nil, agentCert.Fingerprint)}}
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the pinned certificate to be accepted, got %v", err)
+	}
+	response.Body.Close()
+}
+
+func TestIssue(t *testing.T) {
+	ca, caIssued, err := NewCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	loaded, err := LoadCA(caIssued.CertPEM, caIssued.KeyPEM)
+	if err != nil {
+		t.Fatalf("Expected the CA to load, got %v", err)
+	}
+
+	issued, err := loaded.Issue(RoleAgent, "seedbox", []string{"seedbox.example.com", "10.0.0.5"}, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pair, err := tls.X509KeyPair(issued.CertPEM, issued.KeyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(pair.Certificate[0])
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	for _, host := range []string{"seedbox.example.com", "10.0.0.5"} {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("Expected the certificate to be valid for %s, got %v", host, err)
+		}
+	}
+
+	if _, err := loaded.Issue(RoleAgent, "seedbox", nil, time.Hour); err == nil {
+		t.Error("Expected an agent certificate without host to be refused")
+	}
+	if _, err := loaded.Issue("admin", "seedbox", nil, time.Hour); err == nil {
+		t.Error("Expected an unknown role to be refused")
+	}
+	if _, err := LoadCA(issued.CertPEM, issued.KeyPEM); err == nil {
+		t.Error("Expected a leaf certificate to be refused as CA")
+	}
+}
@@ -0,0 +1,218 @@
+package agentclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// Client is a typed HTTP client for a single agent API.
+// It is safe for concurrent use and reuses its connections between requests.
+type Client struct {
+	baseURL         string
+	token           string
+	http            *http.Client
+	stream          *http.Client // long-lived requests, without overall timeout
+	maxResponseSize int64
+	// client is the torrent client of the agent the requests are for, see ForClient
+	client string
+}
+
+// New creates a client for the agent listening on address, authenticated with the
+// plain (already decrypted) bearer token
+func New(address, token string, config Config) *Client {
+	return NewPinned(address, token, "", config)
+}
+
+// NewPinned creates a client only accepting the agent certificate of the
+// fingerprint, when not empty, see agentauth.Pin
+func NewPinned(address, token, fingerprint string, config Config) *Client {
+	tlsConfig := config.TLS
+	if fingerprint != "" {
+		tlsConfig = agentauth.Pin(tlsConfig, fingerprint)
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   config.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.DialTimeout,
+		TLSClientConfig:       tlsConfig,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &Client{
+		baseURL:         strings.TrimRight(address, "/"),
+		token:           token,
+		maxResponseSize: config.MaxResponseSize,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+		stream: &http.Client{
+			Transport: transport,
+		},
+	}
+}
+
+// Address returns the base URL of the agent
+func (c *Client) Address() string {
+	return c.baseURL
+}
+
+// ForClient returns a client sending the requests to the torrent client of the
+// name, among the ones managed by the agent, sharing the connections of c
+func (c *Client) ForClient(name string) *Client {
+	client := *c
+	client.client = name
+	return &client
+}
+
+// url returns the URL of an API path, routed to the torrent client of the
+// client when it has one
+func (c *Client) url(path string) string {
+	if c.client != "" {
+		path = "/v1/clients/" + url.PathEscape(c.client) + strings.TrimPrefix(path, "/v1")
+	}
+	return c.baseURL + path
+}
+
+// throughTunnel sends the requests through the tunnel of the agent whenever
+// it has one open, and to its address otherwise
+func (c *Client) throughTunnel(uid uuid.UUID, tunnels Tunnels) {
+	transport := &selectingTransport{uid: uid, tunnels: tunnels, direct: c.http.Transport}
+	c.http.Transport = transport
+	c.stream.Transport = transport
+}
+
+// selectingTransport picks the tunnel of an agent over its address
+type selectingTransport struct {
+	uid     uuid.UUID
+	tunnels Tunnels
+	direct  http.RoundTripper
+}
+
+func (t *selectingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tunnel, ok := t.tunnels.RoundTripper(t.uid); ok {
+		return tunnel.RoundTrip(req)
+	}
+
+	if req.URL.Scheme == agenttunnel.AddressScheme {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, agenttunnel.ErrNotConnected
+	}
+
+	return t.direct.RoundTrip(req)
+}
+
+func (t *selectingTransport) CloseIdleConnections() {
+	if closer, ok := t.direct.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Close releases the idle connections held by the client
+func (c *Client) Close() {
+	c.http.CloseIdleConnections()
+}
+
+// authenticate signs a request with the token, see agentauth.Sign. The token
+// is not sent, so a captured request cannot be signed again.
+func (c *Client) authenticate(req *http.Request, body []byte) error {
+	return agentauth.Sign(req, c.token, body, time.Now())
+}
+
+// Do sends an authenticated request to the agent API. The body, when not nil, is
+// encoded as JSON and a successful response is decoded into out, when not nil.
+// Transport failures wrap errors.ErrAgentUnavailable and non-2xx answers are
+// returned as *errors.AgentError.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	var encoded []byte
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		encoded = payload
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path), reader)
+	if err != nil {
+		return err
+	}
+
+	if err := c.authenticate(req, encoded); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrAgentUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	payload, err := c.readBody(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &errors.AgentError{
+			StatusCode: response.StatusCode,
+			Message:    errors.DecodeMessage(payload),
+		}
+	}
+
+	if out == nil || len(payload) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("failed to decode agent response: %w", err)
+	}
+
+	return nil
+}
+
+// readBody reads the response body up to the configured size limit
+func (c *Client) readBody(body io.Reader) ([]byte, error) {
+	if c.maxResponseSize <= 0 {
+		return io.ReadAll(body)
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(body, c.maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAgentUnavailable, err)
+	}
+
+	if int64(len(payload)) > c.maxResponseSize {
+		return nil, fmt.Errorf("agent response exceeds %d bytes", c.maxResponseSize)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,312 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// countingDecrypter returns the ciphertext unchanged and counts the calls
+type countingDecrypter struct {
+	calls atomic.Int32
+}
+
+func (d *countingDecrypter) DecryptWithAD(ciphertext string, associatedData []byte) (string, error) {
+	d.calls.Add(1)
+	return ciphertext, nil
+}
+
+// signedWith tells whether a request without body is signed with the secret
+func signedWith(r *http.Request, secret string) bool {
+	return agentauth.NewVerifier(time.Minute).Verify(r, secret, nil) == nil
+}
+
+func TestClient_Do_SignsRequests(t *testing.T) {
+	var header string
+	var signed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		signed = signedWith(r, "secret")
+		w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "secret", DefaultConfig())
+
+	var out map[string]string
+	if err := client.Do(context.Background(), http.MethodGet, "/v1/health/", nil, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if header != "" {
+		t.Errorf("Expected the token not to be sent, got Authorization header '%s'", header)
+	}
+	if !signed {
+		t.Error("Expected the request to be signed with the token")
+	}
+	if out["message"] != "pong" {
+		t.Errorf("Expected message 'pong', got '%s'", out["message"])
+	}
+}
+
+func TestClient_Do_AgentError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+	}{
+		{"error object", http.StatusNotFound, `{"error":"task not found"}`, "task not found"},
+		{"message object", http.StatusBadRequest, `{"message":"invalid hash"}`, "invalid hash"},
+		{"json string", http.StatusInternalServerError, `"boom"`, "boom"},
+		{"plain text", http.StatusBadGateway, "bad gateway\n", "bad gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := New(server.URL, "secret", DefaultConfig()).Do(context.Background(), http.MethodGet, "/", nil, nil)
+
+			var agentErr *errors.AgentError
+			if !errors.As(err, &agentErr) {
+				t.Fatalf("Expected *errors.AgentError, got %v", err)
+			}
+			if agentErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, agentErr.StatusCode)
+			}
+			if agentErr.Message != tt.message {
+				t.Errorf("Expected message '%s', got '%s'", tt.message, agentErr.Message)
+			}
+		})
+	}
+}
+
+func TestClient_Do_ResponseSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + strings.Repeat("a", 64) + `"`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.MaxResponseSize = 16
+
+	var out string
+	err := New(server.URL, "secret", config).Do(context.Background(), http.MethodGet, "/", nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected size limit error, got %v", err)
+	}
+}
+
+func TestClient_Do_Unavailable(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := DefaultConfig()
+	config.Timeout = 50 * time.Millisecond
+
+	err := New(server.URL, "secret", config).Do(context.Background(), http.MethodGet, "/", nil, nil)
+	if !errors.Is(err, errors.ErrAgentUnavailable) {
+		t.Errorf("Expected ErrAgentUnavailable on timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = New(server.URL, "secret", DefaultConfig()).Do(ctx, http.MethodGet, "/", nil, nil)
+	if !errors.Is(err, errors.ErrAgentUnavailable) {
+		t.Errorf("Expected ErrAgentUnavailable on canceled context, got %v", err)
+	}
+}
+
+func TestPool_CachesClientPerAgent(t *testing.T) {
+	decrypter := &countingDecrypter{}
+	pool := NewPool(DefaultConfig(), decrypter)
+
+	agent := &entities.Agent{UUID: uuid.New(), Address: "http://agent:8080", Token: "token"}
+
+	first, err := pool.Get(agent)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, _ := pool.Get(agent)
+
+	if first != second {
+		t.Error("Expected the same client to be reused")
+	}
+	if decrypter.calls.Load() != 1 {
+		t.Errorf("Expected token to be decrypted once, got %d", decrypter.calls.Load())
+	}
+
+	agent.Address = "http://other:8080"
+	third, _ := pool.Get(agent)
+	if third == first {
+		t.Error("Expected a new client after the address changed")
+	}
+	if third.Address() != "http://other:8080" {
+		t.Errorf("Expected address 'http://other:8080', got '%s'", third.Address())
+	}
+
+	pool.Invalidate(agent.UUID)
+	fourth, _ := pool.Get(agent)
+	if fourth == third {
+		t.Error("Expected a new client after invalidation")
+	}
+	if decrypter.calls.Load() != 3 {
+		t.Errorf("Expected 3 decryptions, got %d", decrypter.calls.Load())
+	}
+}
+
+func TestPool_RoutesChildAgentsThroughParent(t *testing.T) {
+	var paths []string
+	var signed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		signed = signedWith(r, "parent-token")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	decrypter := &countingDecrypter{}
+	pool := NewPool(DefaultConfig(), decrypter)
+
+	parent := &entities.Agent{UUID: uuid.New(), Address: server.URL, Token: "parent-token"}
+	child := &entities.Agent{UUID: uuid.New(), Parent: parent, Client: "movies"}
+
+	client, err := pool.Get(child)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.GetInstance(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := pool.Get(parent); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(paths) != 1 || paths[0] != "/v1/clients/movies/instance/" {
+		t.Errorf("Expected the request on '/v1/clients/movies/instance/', got %v", paths)
+	}
+	if !signed {
+		t.Error("Expected the request to be signed with the token of the parent")
+	}
+	if decrypter.calls.Load() != 1 {
+		t.Errorf("Expected the parent token to be decrypted once, got %d", decrypter.calls.Load())
+	}
+}
+
+func TestPool_ProbesWithThePlaintextToken(t *testing.T) {
+	var signed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed = signedWith(r, "token")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	decrypter := &countingDecrypter{}
+	pool := NewPool(DefaultConfig(), decrypter)
+
+	agent := &entities.Agent{Address: server.URL, Token: "token"}
+
+	first := pool.Probe(agent)
+	defer first.Close()
+	if _, err := first.GetInstance(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	second := pool.Probe(agent)
+	defer second.Close()
+	if first == second {
+		t.Error("Expected a new client for every probe")
+	}
+	if !signed {
+		t.Error("Expected the request to be signed with the plaintext token")
+	}
+	if decrypter.calls.Load() != 0 {
+		t.Errorf("Expected no decryption, got %d", decrypter.calls.Load())
+	}
+}
+
+// staticTunnels sends the requests of the connected agents to a test server
+type staticTunnels struct {
+	connected map[uuid.UUID]string
+}
+
+func (s *staticTunnels) RoundTripper(uid uuid.UUID) (http.RoundTripper, bool) {
+	address, ok := s.connected[uid]
+	if !ok {
+		return nil, false
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		out := req.Clone(req.Context())
+		out.URL.Scheme = "http"
+		out.URL.Host = strings.TrimPrefix(address, "http://")
+		return http.DefaultTransport.RoundTrip(out)
+	}), true
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestPool_SelectsTunnel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer server.Close()
+
+	tunnels := &staticTunnels{connected: map[uuid.UUID]string{}}
+	config := DefaultConfig()
+	config.Tunnels = tunnels
+	pool := NewPool(config, &countingDecrypter{})
+
+	agent := &entities.Agent{UUID: uuid.New(), Address: agenttunnel.Address("seedbox"), Token: "token"}
+	client, err := pool.Get(agent)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = client.Do(context.Background(), http.MethodGet, "/v1/health/", nil, nil)
+	if !errors.Is(err, errors.ErrAgentUnavailable) || !strings.Contains(err.Error(), agenttunnel.ErrNotConnected.Error()) {
+		t.Errorf("Expected ErrAgentUnavailable while the tunnel is closed, got %v", err)
+	}
+
+	tunnels.connected[agent.UUID] = server.URL
+	if err := client.Do(context.Background(), http.MethodGet, "/v1/health/", nil, nil); err != nil {
+		t.Errorf("Expected the request to go through the tunnel, got %v", err)
+	}
+}
+
+func TestLoadConfigFromEnv_RefusesInvalidTLS(t *testing.T) {
+	t.Setenv("AGENT_CLIENT_TLS_CA_FILE", t.TempDir()+"/missing.crt")
+
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Error("Expected an error for an unreadable CA, the connections falling back to no client TLS")
+	}
+
+	t.Setenv("AGENT_CLIENT_TLS_CA_FILE", "")
+	config, err := LoadConfigFromEnv()
+	if err != nil || config.TLS != nil {
+		t.Errorf("Expected no TLS settings without the variables, got %v, %v", config.TLS, err)
+	}
+}
@@ -0,0 +1,80 @@
+package agentclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/google/uuid"
+)
+
+// Config holds the HTTP settings shared by every agent client
+type Config struct {
+	Timeout             time.Duration // overall request timeout
+	DialTimeout         time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConnsPerHost int
+	MaxResponseSize     int64 // in bytes
+	// TLS verifies the agent certificates with a private CA and presents the
+	// manager certificate to the agents requiring one; nil uses the defaults
+	TLS *tls.Config
+	// Tunnels carries the requests of the agents connected through a tunnel,
+	// the others being reached at their address; nil disables the tunnels
+	Tunnels Tunnels
+}
+
+// Tunnels gives the transport of the agents connected through a tunnel, see agenttunnel
+type Tunnels interface {
+	RoundTripper(uid uuid.UUID) (http.RoundTripper, bool)
+}
+
+// LoadConfigFromEnv loads the agent client configuration from environment variables.
+// Invalid TLS settings are an error, the agents requiring them refusing the connections.
+func LoadConfigFromEnv() (Config, error) {
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		Timeout:             env.Get("AGENT_CLIENT_TIMEOUT").Default("15s").ValueDuration(),
+		DialTimeout:         env.Get("AGENT_CLIENT_DIAL_TIMEOUT").Default("5s").ValueDuration(),
+		IdleConnTimeout:     env.Get("AGENT_CLIENT_IDLE_CONN_TIMEOUT").Default("90s").ValueDuration(),
+		MaxIdleConnsPerHost: env.Get("AGENT_CLIENT_MAX_IDLE_CONNS").Default("4").ValueInt(),
+		MaxResponseSize:     int64(env.Get("AGENT_CLIENT_MAX_RESPONSE_SIZE").Default("33554432").ValueInt()),
+		TLS:                 tlsConfig,
+		Tunnels:             agenttunnel.Shared(),
+	}, nil
+}
+
+// loadTLSConfig loads the CA and the manager certificate of the agent connections
+func loadTLSConfig() (*tls.Config, error) {
+	caFile := env.Get("AGENT_CLIENT_TLS_CA_FILE").Value()
+	certFile := env.Get("AGENT_CLIENT_TLS_CERT_FILE").Value()
+	keyFile := env.Get("AGENT_CLIENT_TLS_KEY_FILE").Value()
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config, err := agentauth.ClientTLSConfig(caFile, certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AGENT_CLIENT_TLS settings: %w", err)
+	}
+
+	return config, nil
+}
+
+// DefaultConfig returns the configuration used when no environment override is set
+func DefaultConfig() Config {
+	return Config{
+		Timeout:             15 * time.Second,
+		DialTimeout:         5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConnsPerHost: 4,
+		MaxResponseSize:     32 << 20, // 32MB
+	}
+}
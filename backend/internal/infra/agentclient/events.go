@@ -0,0 +1,52 @@
+package agentclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/events"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+// StreamEvents follows the live event stream of the agent and calls fn for every
+// event, until ctx is canceled, the stream ends or fn returns an error. The
+// request is not bound by the client timeout.
+func (c *Client) StreamEvents(ctx context.Context, fn func(event *entities.Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/v1/events/"), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.authenticate(req, nil); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	response, err := c.stream.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrAgentUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		payload, _ := c.readBody(response.Body)
+		return &errors.AgentError{
+			StatusCode: response.StatusCode,
+			Message:    errors.DecodeMessage(payload),
+		}
+	}
+
+	return events.Read(response.Body, func(name string, data []byte) error {
+		var body models.EventResponse
+		if err := json.Unmarshal(data, &body); err != nil {
+			return fmt.Errorf("failed to decode agent event: %w", err)
+		}
+
+		return fn(mappers.ToEvent(name, body))
+	})
+}
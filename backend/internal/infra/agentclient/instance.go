@@ -0,0 +1,60 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+)
+
+// GetInstance retrieves the torrent client instance information
+func (c *Client) GetInstance(ctx context.Context) (*entities.Instance, error) {
+	var handler models.InstanceResponse
+	if err := c.Do(ctx, http.MethodGet, "/v1/instance/", nil, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToInstance(handler), nil
+}
+
+// GetPreferences retrieves the torrent client preferences
+func (c *Client) GetPreferences(ctx context.Context) (*entities.InstancePreferences, error) {
+	var handler models.InstancePreferencesResponse
+	if err := c.Do(ctx, http.MethodGet, "/v1/instance/preferences", nil, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToInstancePreferences(handler), nil
+}
+
+func (c *Client) SetDownloadSpeedLimit(ctx context.Context, schema schemas.InstanceSetDownloadSpeedLimitSchema) error {
+	return c.Do(ctx, http.MethodPost, "/v1/instance/download_speed_limit", schema, nil)
+}
+
+func (c *Client) SetUploadSpeedLimit(ctx context.Context, schema schemas.InstanceSetUploadSpeedLimitSchema) error {
+	return c.Do(ctx, http.MethodPost, "/v1/instance/upload_speed_limit", schema, nil)
+}
+
+// Ping checks that the agent and its torrent client are reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Do(ctx, http.MethodGet, "/v1/health/", nil, nil)
+}
+
+// ListClients retrieves the torrent clients managed by the agent. Agents
+// managing a single one answer with a 404 *errors.AgentError.
+func (c *Client) ListClients(ctx context.Context) ([]*entities.AgentClient, error) {
+	var handler []models.AgentClientResponse
+	if err := c.Do(ctx, http.MethodGet, "/v1/clients/", nil, &handler); err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.AgentClient, len(handler))
+	for i, item := range handler {
+		result[i] = mappers.ToAgentClient(item)
+	}
+
+	return result, nil
+}
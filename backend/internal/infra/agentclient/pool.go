@@ -0,0 +1,118 @@
+package agentclient
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/google/uuid"
+)
+
+// Decrypter decrypts the agent tokens stored in the database, bound to the
+// agent UUID as associated data
+type Decrypter interface {
+	DecryptWithAD(ciphertext string, associatedData []byte) (string, error)
+}
+
+// Pool keeps one configured client per agent, so connections are reused and
+// tokens are decrypted once instead of on every request
+type Pool struct {
+	config    Config
+	decrypter Decrypter
+
+	mu      sync.Mutex
+	clients map[uuid.UUID]*pooledClient
+}
+
+type pooledClient struct {
+	client *Client
+	// address, encrypted token and pinned certificate the client was built from,
+	// used to detect agent updates
+	address     string
+	token       string
+	fingerprint string
+}
+
+// NewPool creates a pool of agent clients sharing the given configuration
+func NewPool(config Config, decrypter Decrypter) *Pool {
+	return &Pool{
+		config:    config,
+		decrypter: decrypter,
+		clients:   make(map[uuid.UUID]*pooledClient),
+	}
+}
+
+// Get returns the client for a saved agent, creating it when the agent is new or
+// its address or token changed. A child agent is reached through the client of
+// its parent.
+func (p *Pool) Get(agent *entities.Agent) (*Client, error) {
+	if agent.Parent != nil {
+		client, err := p.Get(agent.Parent)
+		if err != nil {
+			return nil, err
+		}
+		return client.ForClient(agent.Client), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[agent.UUID]; ok {
+		if cached.address == agent.Address && cached.token == agent.Token && cached.fingerprint == agent.CertificateFingerprint {
+			return cached.client, nil
+		}
+
+		cached.client.Close()
+		delete(p.clients, agent.UUID)
+	}
+
+	client, err := p.build(agent)
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients[agent.UUID] = &pooledClient{
+		client:      client,
+		address:     agent.Address,
+		token:       agent.Token,
+		fingerprint: agent.CertificateFingerprint,
+	}
+
+	return client, nil
+}
+
+// Invalidate drops the cached client of an agent
+func (p *Pool) Invalidate(uid uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[uid]; ok {
+		cached.client.Close()
+		delete(p.clients, uid)
+	}
+}
+
+// Probe returns an uncached client for an agent being created or updated, whose
+// token is still in plaintext. The caller closes it once done.
+func (p *Pool) Probe(agent *entities.Agent) *Client {
+	return p.newClient(agent, agent.Token)
+}
+
+func (p *Pool) build(agent *entities.Agent) (*Client, error) {
+	token, err := p.decrypter.DecryptWithAD(agent.Token, crypto.AssociatedData(agent.UUID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt agent token: %w", err)
+	}
+
+	return p.newClient(agent, token), nil
+}
+
+func (p *Pool) newClient(agent *entities.Agent, token string) *Client {
+	client := NewPinned(agent.Address, token, agent.CertificateFingerprint, p.config)
+	if p.config.Tunnels != nil && agent.UUID != uuid.Nil {
+		client.throughTunnel(agent.UUID, p.config.Tunnels)
+	}
+
+	return client
+}
@@ -0,0 +1,142 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+)
+
+// ListTasks lists every task of the agent torrent client
+func (c *Client) ListTasks(ctx context.Context) ([]*entities.Task, error) {
+	var handler []models.TaskResponseModel
+	if err := c.Do(ctx, http.MethodGet, "/v1/tasks/", nil, &handler); err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.Task, len(handler))
+	for i, item := range handler {
+		result[i] = mappers.ToTask(item)
+	}
+
+	return result, nil
+}
+
+// GetTask retrieves a single task by its hash
+func (c *Client) GetTask(ctx context.Context, id string) (*entities.Task, error) {
+	var handler models.TaskResponseModel
+	if err := c.Do(ctx, http.MethodGet, taskPath(id, ""), nil, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToTask(handler), nil
+}
+
+// CreateTask adds a new task to the agent torrent client
+func (c *Client) CreateTask(ctx context.Context, schema schemas.TaskCreateSchema) (*entities.Task, error) {
+	var handler models.TaskResponseModel
+	if err := c.Do(ctx, http.MethodPost, "/v1/task/", schema, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToTask(handler), nil
+}
+
+// PreviewTask reads the torrent a task would be added from, without adding it
+func (c *Client) PreviewTask(ctx context.Context, schema schemas.TaskCreateSchema) (*entities.TaskPreview, error) {
+	var handler models.TaskPreviewResponse
+	if err := c.Do(ctx, http.MethodPost, "/v1/task/preview", schema, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToTaskPreview(handler), nil
+}
+
+// DeleteTask removes a task, deleting its files when purge is set
+func (c *Client) DeleteTask(ctx context.Context, id string, purge bool) error {
+	path := taskPath(id, "")
+	if purge {
+		path += "?purge=true"
+	}
+
+	return c.Do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func (c *Client) StopTask(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "stop"), nil, nil)
+}
+
+func (c *Client) StartTask(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "start"), nil, nil)
+}
+
+func (c *Client) ForceResumeTask(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "force_resume"), nil, nil)
+}
+
+func (c *Client) SetTaskShareLimit(ctx context.Context, id string, schema schemas.TaskSetShareLimitSchema) error {
+	schema.Hash = id
+	return c.Do(ctx, http.MethodPost, taskPath(id, "share_limit"), schema, nil)
+}
+
+func (c *Client) SetTaskLocation(ctx context.Context, id string, schema schemas.TaskSetLocationSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "location"), schema, nil)
+}
+
+func (c *Client) RenameTask(ctx context.Context, id string, schema schemas.TaskRenameSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "rename"), schema, nil)
+}
+
+func (c *Client) SetTaskSuperSeeding(ctx context.Context, id string, schema schemas.TaskSuperSeedingSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "super_seeding"), schema, nil)
+}
+
+func (c *Client) ForceRecheckTask(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "force_recheck"), nil, nil)
+}
+
+func (c *Client) ForceReannounceTask(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "force_reannounce"), nil, nil)
+}
+
+func (c *Client) SetTaskDownloadLimit(ctx context.Context, id string, schema schemas.TaskSetDownloadLimitSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "limit_download_rate"), schema, nil)
+}
+
+func (c *Client) SetTaskUploadLimit(ctx context.Context, id string, schema schemas.TaskSetUploadLimitSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "limit_upload_rate"), schema, nil)
+}
+
+// SetTaskCategory moves a task to a category, none when the category is empty
+func (c *Client) SetTaskCategory(ctx context.Context, id string, schema schemas.TaskSetCategorySchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "category"), schema, nil)
+}
+
+// SetTaskTags replaces the tags of a task
+func (c *Client) SetTaskTags(ctx context.Context, id string, schema schemas.TaskSetTagsSchema) error {
+	return c.Do(ctx, http.MethodPost, taskPath(id, "tags"), schema, nil)
+}
+
+// ListTaskFiles lists the files of a task
+func (c *Client) ListTaskFiles(ctx context.Context, id string) ([]*entities.TaskFile, error) {
+	var handler []models.TaskFileResponse
+	if err := c.Do(ctx, http.MethodGet, taskPath(id, "files"), nil, &handler); err != nil {
+		return nil, err
+	}
+
+	return mappers.ToTaskFiles(handler), nil
+}
+
+// taskPath builds the agent route for a single task and optional action
+func taskPath(id, action string) string {
+	path := "/v1/task/" + url.PathEscape(id)
+	if action != "" {
+		path += "/" + action
+	}
+
+	return path
+}
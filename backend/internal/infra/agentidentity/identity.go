@@ -0,0 +1,146 @@
+// Package agentidentity enrolls an agent with a manager and keeps the identity
+// it receives, so the agent restarts without AGENT_SECRET being set.
+package agentidentity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+)
+
+const maxResponseSize = 1 << 20 // 1MB
+
+var (
+	ErrAlreadyEnrolled = errors.New("agent already enrolled")
+	ErrInsecureManager = errors.New("the manager URL must use https")
+)
+
+// Identity is what an enrolled agent keeps about itself
+type Identity struct {
+	ManagerURL string    `json:"manager_url"`
+	AgentUUID  string    `json:"agent_uuid"`
+	Name       string    `json:"name"`
+	Secret     string    `json:"secret"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+	// Tunnel tells the agent to connect to the manager through a tunnel
+	Tunnel bool `json:"tunnel,omitempty"`
+}
+
+// Load reads the identity stored at path. The error wraps os.ErrNotExist when
+// the agent is not enrolled.
+func Load(path string) (*Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("invalid agent identity %s: %w", path, err)
+	}
+	if identity.Secret == "" {
+		return nil, fmt.Errorf("invalid agent identity %s: no secret", path)
+	}
+
+	return &identity, nil
+}
+
+// Save stores the identity at path, readable by its owner only. An existing
+// identity is never replaced, it has to be removed first.
+func (i *Identity) Save(path string) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%w: %s exists", ErrAlreadyEnrolled, path)
+		}
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return file.Close()
+}
+
+// Enroll registers the agent with the manager at managerURL, which includes
+// its base path, and returns the identity it is given. Plain HTTP is refused
+// unless allowHTTP is set, the answer holding the agent secret.
+func Enroll(ctx context.Context, client *http.Client, managerURL string, request schemas.AgentEnrollRequest, allowHTTP bool) (*Identity, error) {
+	base, err := url.Parse(strings.TrimRight(managerURL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid manager URL %q", managerURL)
+	}
+	if base.Scheme != "https" && !(allowHTTP && base.Scheme == "http") {
+		return nil, ErrInsecureManager
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base.String()+"/v1/enrollment/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the manager: %w", err)
+	}
+	defer response.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manager response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("enrollment refused (%d): %s", response.StatusCode, pkgerrors.DecodeMessage(payload))
+	}
+
+	var enrolled models.AgentEnrollResponse
+	if err := json.Unmarshal(payload, &enrolled); err != nil {
+		return nil, fmt.Errorf("failed to decode the manager response: %w", err)
+	}
+	if enrolled.Secret == "" {
+		return nil, errors.New("the manager answered no secret")
+	}
+
+	return &Identity{
+		ManagerURL: base.String(),
+		AgentUUID:  enrolled.AgentUUID,
+		Name:       enrolled.Name,
+		Secret:     enrolled.Secret,
+		EnrolledAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,101 @@
+package agentidentity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+)
+
+func TestEnroll(t *testing.T) {
+	var received schemas.AgentEnrollRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/gardarr/v1/enrollment/" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if received.Token != "gde_valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"status_code": 401, "message": "Invalid or expired enrollment token"})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.AgentEnrollResponse{AgentUUID: "uuid", Name: received.Name, Secret: "secret"})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	request := schemas.AgentEnrollRequest{Token: "gde_valid", Name: "seedbox-1", Type: "qbittorrent", Address: "https://seedbox-1:3100"}
+
+	identity, err := Enroll(ctx, server.Client(), server.URL+"/gardarr/", request, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if identity.Secret != "secret" || identity.Name != "seedbox-1" || identity.ManagerURL != server.URL+"/gardarr" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	if received.Address != request.Address {
+		t.Errorf("Expected address %s sent, got %s", request.Address, received.Address)
+	}
+
+	request.Token = "gde_used"
+	if _, err := Enroll(ctx, server.Client(), server.URL+"/gardarr", request, false); err == nil || !strings.Contains(err.Error(), "Invalid or expired enrollment token") {
+		t.Errorf("Expected the manager error, got %v", err)
+	}
+}
+
+func TestEnroll_RefusesPlainHTTP(t *testing.T) {
+	request := schemas.AgentEnrollRequest{Token: "gde_valid"}
+
+	if _, err := Enroll(context.Background(), http.DefaultClient, "http://manager:3000", request, false); !errors.Is(err, ErrInsecureManager) {
+		t.Errorf("Expected ErrInsecureManager, got %v", err)
+	}
+	if _, err := Enroll(context.Background(), http.DefaultClient, "manager", request, true); err == nil {
+		t.Error("Expected an invalid manager URL to be refused")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "agent.json")
+
+	if _, err := Load(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist before enrolling, got %v", err)
+	}
+
+	identity := &Identity{ManagerURL: "https://manager", AgentUUID: "uuid", Name: "seedbox-1", Secret: "secret"}
+	if err := identity.Save(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the identity to be saved, got %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *loaded != *identity {
+		t.Errorf("Expected %+v, got %+v", identity, loaded)
+	}
+
+	if err := identity.Save(path); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("Expected ErrAlreadyEnrolled, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package agenttunnel
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the tunnel settings
+type Config struct {
+	// PingInterval is how long a tunnel may stay silent before it is pinged,
+	// a tunnel not answering being closed
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	// MaxSkew is how far the timestamp of a tunnel request may be from the clock
+	MaxSkew time.Duration
+	// MinBackoff and MaxBackoff bound the delay before an agent reconnects
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// LoadConfigFromEnv loads the tunnel configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		PingInterval: env.Get("AGENT_TUNNEL_PING_INTERVAL").Default("30s").ValueDuration(),
+		PingTimeout:  env.Get("AGENT_TUNNEL_PING_TIMEOUT").Default("15s").ValueDuration(),
+		MaxSkew:      env.Get("AGENT_SIGNATURE_MAX_SKEW").Default("5m").ValueDuration(),
+		MinBackoff:   env.Get("AGENT_TUNNEL_MIN_BACKOFF").Default("1s").ValueDuration(),
+		MaxBackoff:   env.Get("AGENT_TUNNEL_MAX_BACKOFF").Default("1m").ValueDuration(),
+	}
+}
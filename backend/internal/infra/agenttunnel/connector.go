@@ -0,0 +1,169 @@
+package agenttunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"golang.org/x/net/http2"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Connector keeps the tunnel of an agent open, on the agent side
+type Connector struct {
+	ManagerURL string // including its base path
+	AgentID    string // UUID the agent is registered with
+	Secret     string
+	TLS        *tls.Config // verifies the manager certificate, nil uses the system CAs
+	Handler    http.Handler
+	Config     Config
+}
+
+// Run connects to the manager and serves the agent API over the tunnel,
+// reconnecting with an exponential backoff, until ctx is canceled
+func (c *Connector) Run(ctx context.Context) {
+	if c.Config.MinBackoff <= 0 {
+		c.Config.MinBackoff = time.Second
+	}
+	c.Config.MaxBackoff = max(c.Config.MaxBackoff, c.Config.MinBackoff)
+	backoff := c.Config.MinBackoff
+
+	for {
+		conn, err := c.Dial(ctx)
+		if err == nil {
+			log.Printf("agent tunnel: connected to %s", c.ManagerURL)
+			backoff = c.Config.MinBackoff
+			c.Serve(ctx, conn)
+			if ctx.Err() == nil {
+				log.Printf("agent tunnel: disconnected from %s", c.ManagerURL)
+			}
+		} else if ctx.Err() == nil {
+			log.Printf("agent tunnel: failed to connect to %s: %v", c.ManagerURL, err)
+		}
+
+		// A random part keeps the agents from reconnecting all at once
+		delay := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		backoff = min(backoff*2, c.Config.MaxBackoff)
+	}
+}
+
+// Serve serves the agent API over a tunnel until it closes or ctx is canceled
+func (c *Connector) Serve(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	server := &http2.Server{
+		ReadIdleTimeout: c.Config.PingInterval,
+		PingTimeout:     c.Config.PingTimeout,
+	}
+	server.ServeConn(conn, &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: c.Handler,
+	})
+}
+
+// Dial opens a tunnel to the manager, authenticated by a request signed with
+// the agent secret
+func (c *Connector) Dial(ctx context.Context) (net.Conn, error) {
+	target, err := url.Parse(strings.TrimRight(c.ManagerURL, "/"))
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid manager URL %q", c.ManagerURL)
+	}
+
+	address := target.Host
+	if target.Port() == "" {
+		switch target.Scheme {
+		case "https":
+			address = net.JoinHostPort(target.Hostname(), "443")
+		case "http":
+			address = net.JoinHostPort(target.Hostname(), "80")
+		default:
+			return nil, fmt.Errorf("invalid manager URL %q", c.ManagerURL)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if target.Scheme == "https" {
+		config := &tls.Config{}
+		if c.TLS != nil {
+			config = c.TLS.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = target.Hostname()
+		}
+		// The upgrade only exists in HTTP/1.1
+		config.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	tunnel, err := c.upgrade(ctx, conn, target.String()+Path)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// upgrade sends the tunnel request over conn and reads the answer
+func (c *Connector) upgrade(ctx context.Context, conn net.Conn, target string) (net.Conn, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", Protocol)
+	req.Header.Set(AgentHeader, c.AgentID)
+	if err := agentauth.Sign(req, c.Secret, nil, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		defer response.Body.Close()
+		payload, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return nil, fmt.Errorf("tunnel refused (%d): %s", response.StatusCode, errors.DecodeMessage(payload))
+	}
+
+	return withBuffer(conn, reader), nil
+}
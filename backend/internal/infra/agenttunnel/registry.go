@@ -0,0 +1,209 @@
+package agenttunnel
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/google/uuid"
+	"golang.org/x/net/http2"
+)
+
+// shared is the registry of the manager, used by every agent client pool
+var shared = sync.OnceValue(func() *Registry {
+	return NewRegistry(LoadConfigFromEnv())
+})
+
+// Shared returns the registry of the manager process
+func Shared() *Registry {
+	return shared()
+}
+
+// Registry keeps the tunnel opened by each agent, on the manager side
+type Registry struct {
+	transport *http2.Transport
+	verifier  *agentauth.Verifier
+
+	mu        sync.Mutex
+	sessions  map[uuid.UUID]*session
+	listeners []func(uid uuid.UUID, connected bool)
+}
+
+// session is the tunnel of an agent, the manager being its HTTP/2 client
+type session struct {
+	client      *http2.ClientConn
+	conn        *watchedConn
+	connectedAt time.Time
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry(config Config) *Registry {
+	return &Registry{
+		transport: &http2.Transport{
+			AllowHTTP:       true,
+			ReadIdleTimeout: config.PingInterval,
+			PingTimeout:     config.PingTimeout,
+		},
+		verifier: agentauth.NewVerifier(config.MaxSkew),
+		sessions: make(map[uuid.UUID]*session),
+	}
+}
+
+// Notify registers fn to be called, in its own goroutine, when an agent
+// connects or its tunnel closes
+func (r *Registry) Notify(fn func(uid uuid.UUID, connected bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, fn)
+}
+
+// Verify checks the signature of a tunnel request with the agent secret
+func (r *Registry) Verify(req *http.Request, secret string) error {
+	return r.verifier.Verify(req, secret, nil)
+}
+
+// Open starts the HTTP/2 client of a tunnel an agent opened, replacing its
+// previous tunnel
+func (r *Registry) Open(uid uuid.UUID, conn net.Conn) error {
+	watched := &watchedConn{Conn: conn, closed: make(chan struct{})}
+
+	client, err := r.transport.NewClientConn(watched)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	current := &session{client: client, conn: watched, connectedAt: time.Now()}
+
+	r.mu.Lock()
+	previous := r.sessions[uid]
+	r.sessions[uid] = current
+	r.mu.Unlock()
+
+	if previous != nil {
+		previous.client.Close()
+	}
+
+	go r.watch(uid, current)
+	r.notify(uid, true)
+
+	return nil
+}
+
+// watch forgets a tunnel once its connection is closed, unless it was replaced
+func (r *Registry) watch(uid uuid.UUID, s *session) {
+	<-s.conn.closed
+	s.client.Close()
+
+	r.mu.Lock()
+	removed := r.sessions[uid] == s
+	if removed {
+		delete(r.sessions, uid)
+	}
+	r.mu.Unlock()
+
+	if removed {
+		log.Printf("agent tunnel: agent %s disconnected after %s", uid, time.Since(s.connectedAt).Round(time.Second))
+		r.notify(uid, false)
+	}
+}
+
+func (r *Registry) notify(uid uuid.UUID, connected bool) {
+	r.mu.Lock()
+	listeners := append([]func(uuid.UUID, bool){}, r.listeners...)
+	r.mu.Unlock()
+
+	for _, fn := range listeners {
+		go fn(uid, connected)
+	}
+}
+
+// Connected tells whether an agent has a tunnel open
+func (r *Registry) Connected(uid uuid.UUID) bool {
+	_, ok := r.RoundTripper(uid)
+	return ok
+}
+
+// RoundTripper returns the transport sending requests through the tunnel of
+// an agent, when it has one open
+func (r *Registry) RoundTripper(uid uuid.UUID) (http.RoundTripper, bool) {
+	r.mu.Lock()
+	s, ok := r.sessions[uid]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	if state := s.client.State(); state.Closed || state.Closing {
+		return nil, false
+	}
+	return tunnelTransport{client: s.client}, true
+}
+
+// Disconnect closes the tunnel of an agent, when it has one open
+func (r *Registry) Disconnect(uid uuid.UUID) {
+	r.mu.Lock()
+	s, ok := r.sessions[uid]
+	r.mu.Unlock()
+
+	if ok {
+		s.conn.Close()
+	}
+}
+
+// Close closes every tunnel
+func (r *Registry) Close() {
+	r.mu.Lock()
+	sessions := make([]*session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		s.conn.Close()
+	}
+}
+
+// tunnelTransport sends the requests over a tunnel whatever their address
+type tunnelTransport struct {
+	client *http2.ClientConn
+}
+
+func (t tunnelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The signature covers the request URI only, the address can be rewritten
+	out := req.Clone(req.Context())
+	out.URL.Scheme = "http"
+	out.URL.Host = "agent"
+	out.Host = ""
+
+	return t.client.RoundTrip(out)
+}
+
+// watchedConn signals when the connection closes or fails
+type watchedConn struct {
+	net.Conn
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (c *watchedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err != nil {
+		c.signal()
+	}
+	return n, err
+}
+
+func (c *watchedConn) Close() error {
+	c.signal()
+	return c.Conn.Close()
+}
+
+func (c *watchedConn) signal() {
+	c.once.Do(func() { close(c.closed) })
+}
@@ -0,0 +1,110 @@
+// Package agenttunnel lets the manager reach the agents it cannot connect to,
+// behind NAT for instance. The agent dials out to the manager and upgrades the
+// connection into a tunnel, then serves its API over it with HTTP/2 while the
+// manager is the client, so the requests share the single connection.
+package agenttunnel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Protocol is the Upgrade header value of the tunnel requests
+	Protocol = "gardarr-tunnel"
+	// Path is where the manager accepts the tunnels, under its base path
+	Path = "/v1/tunnel"
+	// AgentHeader identifies the agent opening a tunnel, by its UUID
+	AgentHeader = "X-Gardarr-Agent"
+	// AddressScheme marks the agent addresses only reachable through a tunnel
+	AddressScheme = "tunnel"
+)
+
+var (
+	ErrNotConnected    = errors.New("agent not connected through its tunnel")
+	ErrUpgradeRequired = errors.New("tunnel upgrade required")
+	ErrUnknownAgent    = errors.New("missing or invalid agent identifier")
+)
+
+// Address returns the address of an agent only reachable through a tunnel
+func Address(name string) string {
+	return AddressScheme + "://" + name
+}
+
+// IsTunnelAddress tells whether an agent is only reachable through a tunnel
+func IsTunnelAddress(address string) bool {
+	return strings.HasPrefix(address, AddressScheme+"://")
+}
+
+// AgentID returns the UUID of the agent opening a tunnel
+func AgentID(r *http.Request) (uuid.UUID, error) {
+	uid, err := uuid.Parse(r.Header.Get(AgentHeader))
+	if err != nil || uid == uuid.Nil {
+		return uuid.Nil, ErrUnknownAgent
+	}
+	return uid, nil
+}
+
+// Upgrade takes over the connection of a tunnel request, answering 101. The
+// request must be authenticated first.
+func Upgrade(w http.ResponseWriter, r *http.Request) (net.Conn, error) {
+	if !upgradeRequested(r.Header) {
+		return nil, ErrUpgradeRequired
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be taken over")
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", Protocol); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return withBuffer(conn, buffered.Reader), nil
+}
+
+// upgradeRequested tells whether the headers ask for a tunnel
+func upgradeRequested(header http.Header) bool {
+	if !strings.EqualFold(header.Get("Upgrade"), Protocol) {
+		return false
+	}
+
+	for _, value := range header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bufferedConn reads first what was buffered while reading the upgrade
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func withBuffer(conn net.Conn, reader *bufio.Reader) net.Conn {
+	if reader == nil || reader.Buffered() == 0 {
+		return conn
+	}
+	return &bufferedConn{Conn: conn, reader: reader}
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,190 @@
+package agenttunnel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const testSecret = "secret"
+
+func testConfig() Config {
+	return Config{
+		PingInterval: time.Minute,
+		PingTimeout:  time.Second,
+		MaxSkew:      time.Minute,
+		MinBackoff:   10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+	}
+}
+
+// newManager serves the tunnel route of a manager knowing one agent
+func newManager(t *testing.T, uid uuid.UUID) (*Registry, *httptest.Server) {
+	t.Helper()
+
+	registry := NewRegistry(testConfig())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gardarr"+Path {
+			http.NotFound(w, r)
+			return
+		}
+		if id, err := AgentID(r); err != nil || id != uid || registry.Verify(r, testSecret) != nil {
+			http.Error(w, `{"error":"Invalid agent credentials"}`, http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUpgradeRequired)
+			return
+		}
+		if err := registry.Open(uid, conn); err != nil {
+			t.Errorf("Expected the tunnel to open, got %v", err)
+		}
+	}))
+	t.Cleanup(func() {
+		registry.Close()
+		server.Close()
+	})
+
+	return registry, server
+}
+
+func waitFor(t *testing.T, changes <-chan bool, expected bool) {
+	t.Helper()
+
+	select {
+	case connected := <-changes:
+		if connected != expected {
+			t.Fatalf("Expected connected %v, got %v", expected, connected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected connected %v, nothing happened", expected)
+	}
+}
+
+func TestTunnel_CarriesRequests(t *testing.T) {
+	uid := uuid.New()
+	registry, server := newManager(t, uid)
+
+	changes := make(chan bool, 4)
+	registry.Notify(func(id uuid.UUID, connected bool) {
+		if id == uid {
+			changes <- connected
+		}
+	})
+
+	release := make(chan struct{})
+	handler := http.NewServeMux()
+	handler.HandleFunc("/v1/instance", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization"))
+	})
+	handler.HandleFunc("/v1/events", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connector := &Connector{
+		ManagerURL: server.URL + "/gardarr/",
+		AgentID:    uid.String(),
+		Secret:     testSecret,
+		Handler:    handler,
+		Config:     testConfig(),
+	}
+	go connector.Run(ctx)
+
+	waitFor(t, changes, true)
+
+	transport, ok := registry.RoundTripper(uid)
+	if !ok {
+		t.Fatal("Expected the tunnel to be registered")
+	}
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	// A long-lived request does not hold the tunnel
+	stream, err := http.NewRequest(http.MethodGet, Address("seedbox")+"/v1/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamResponse, err := client.Do(stream)
+	if err != nil {
+		t.Fatalf("Expected the stream to open, got %v", err)
+	}
+	defer streamResponse.Body.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, Address("seedbox")+"/v1/instance", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(body) != "Bearer token" {
+		t.Errorf("Expected the request to reach the agent, got %q", body)
+	}
+	close(release)
+
+	// The manager closing the tunnel, the agent reconnects
+	registry.Disconnect(uid)
+	waitFor(t, changes, false)
+	waitFor(t, changes, true)
+
+	// The agent stopping, the tunnel is forgotten
+	cancel()
+	waitFor(t, changes, false)
+	if registry.Connected(uid) {
+		t.Error("Expected the agent to be disconnected")
+	}
+}
+
+func TestConnector_Dial_Refused(t *testing.T) {
+	uid := uuid.New()
+	_, server := newManager(t, uid)
+
+	tests := []struct {
+		name    string
+		agentID string
+		secret  string
+	}{
+		{name: "Wrong secret", agentID: uid.String(), secret: "other"},
+		{name: "Unknown agent", agentID: uuid.NewString(), secret: testSecret},
+		{name: "No agent", agentID: "", secret: testSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &Connector{ManagerURL: server.URL + "/gardarr", AgentID: tt.agentID, Secret: tt.secret, Config: testConfig()}
+
+			_, err := connector.Dial(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "Invalid agent credentials") {
+				t.Errorf("Expected the tunnel to be refused, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpgrade_RequiresUpgradeHeaders(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, Path, nil)
+	if _, err := Upgrade(httptest.NewRecorder(), request); err != ErrUpgradeRequired {
+		t.Errorf("Expected ErrUpgradeRequired, got %v", err)
+	}
+}
+
+func TestIsTunnelAddress(t *testing.T) {
+	if !IsTunnelAddress(Address("seedbox")) {
+		t.Error("Expected a tunnel address")
+	}
+	if IsTunnelAddress("https://seedbox:3100") {
+		t.Error("Expected a direct address")
+	}
+}
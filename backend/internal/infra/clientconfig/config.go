@@ -0,0 +1,153 @@
+package clientconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/spf13/viper"
+)
+
+// DefaultName is the name of the client defined by the environment variables
+const DefaultName = "default"
+
+var (
+	ErrNoClients   = errors.New("no torrent client defined")
+	ErrInvalidName = errors.New("invalid torrent client name")
+)
+
+// namePattern keeps the names usable as a path segment of the agent routes
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Definition describes a torrent client managed by the agent
+type Definition struct {
+	Name     string `mapstructure:"name"`
+	Type     string `mapstructure:"type"`
+	URL      string `mapstructure:"url"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// PasswordFile is read into Password, keeping the secret out of the file
+	PasswordFile string `mapstructure:"password_file"`
+	// HostID selects the daemon of a Deluge Web UI managing several
+	HostID string `mapstructure:"host_id"`
+}
+
+// Load returns the torrent clients defined in AGENT_CLIENTS_FILE, or the one
+// defined by AGENT_CLIENT and its environment variables when it is not set
+func Load() ([]Definition, error) {
+	if path := env.Get(constants.AgentClientsFileEnv).Value(); path != "" {
+		return LoadFile(path)
+	}
+
+	definitions := []Definition{FromEnv()}
+	if err := Validate(definitions); err != nil {
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+// LoadFile reads the torrent clients listed under the "clients" key of a
+// YAML, JSON or TOML file, the format following the file extension
+func LoadFile(path string) ([]Definition, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var definitions []Definition
+	if err := v.UnmarshalKey("clients", &definitions); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i := range definitions {
+		definition := &definitions[i]
+		if definition.Type == "" {
+			definition.Type = entities.ClientQBittorrent
+		}
+		if definition.URL == "" {
+			definition.URL = defaultURL(definition.Type)
+		}
+		if definition.PasswordFile != "" {
+			password, err := os.ReadFile(definition.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("client %s: %w", definition.Name, err)
+			}
+			definition.Password = strings.TrimSpace(string(password))
+		}
+	}
+
+	if err := Validate(definitions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return definitions, nil
+}
+
+// FromEnv returns the torrent client set in AGENT_CLIENT, configured by the
+// environment variables of its type
+func FromEnv() Definition {
+	definition := Definition{
+		Name: DefaultName,
+		Type: env.Get(constants.AgentClientEnv).Default(entities.ClientQBittorrent).Value(),
+	}
+
+	switch definition.Type {
+	case entities.ClientTransmission:
+		definition.URL = env.Get("TRANSMISSION_URL").Default(defaultURL(definition.Type)).Value()
+		definition.Username = env.Get("TRANSMISSION_USERNAME").Value()
+		definition.Password = env.Get("TRANSMISSION_PASSWORD").Value()
+	case entities.ClientDeluge:
+		definition.URL = env.Get("DELUGE_URL").Default(defaultURL(definition.Type)).Value()
+		definition.Password = env.Get("DELUGE_PASSWORD").Default("deluge").Value()
+		definition.HostID = env.Get("DELUGE_HOST_ID").Value()
+	default:
+		definition.URL = env.Get("QBITTORRENT_BASEURL").Value()
+		definition.Username = env.Get("QBITTORRENT_USERNAME").Value()
+		definition.Password = env.Get("QBITTORRENT_PASSWORD").Value()
+	}
+
+	return definition
+}
+
+// Validate checks the definitions have a known type and unique names usable in a path
+func Validate(definitions []Definition) error {
+	if len(definitions) == 0 {
+		return ErrNoClients
+	}
+
+	names := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		if !namePattern.MatchString(definition.Name) {
+			return fmt.Errorf("%w: %q must be lowercase letters, digits, '-' or '_'", ErrInvalidName, definition.Name)
+		}
+		if names[definition.Name] {
+			return fmt.Errorf("%w: %q is defined twice", ErrInvalidName, definition.Name)
+		}
+		names[definition.Name] = true
+
+		if !slices.Contains(entities.Clients, definition.Type) {
+			return fmt.Errorf("client %s: type must be one of %v, got %q", definition.Name, entities.Clients, definition.Type)
+		}
+	}
+
+	return nil
+}
+
+// defaultURL returns the address of a local client of the given type
+func defaultURL(clientType string) string {
+	switch clientType {
+	case entities.ClientTransmission:
+		return "http://localhost:9091/transmission/rpc"
+	case entities.ClientDeluge:
+		return "http://localhost:8112/json"
+	}
+	return ""
+}
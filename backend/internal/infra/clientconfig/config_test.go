@@ -0,0 +1,96 @@
+package clientconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	passwordFile := writeFile(t, "password", "s3cret\n")
+	path := writeFile(t, "clients.yaml", `
+clients:
+  - name: movies
+    url: http://qbittorrent-movies:8080
+    username: admin
+    password_file: `+passwordFile+`
+  - name: tv
+    type: transmission
+  - name: music
+    type: deluge
+    password: deluge
+    host_id: abc
+`)
+
+	definitions, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(definitions) != 3 {
+		t.Fatalf("Expected 3 clients, got %d", len(definitions))
+	}
+	if movies := definitions[0]; movies.Type != entities.ClientQBittorrent || movies.Username != "admin" || movies.Password != "s3cret" {
+		t.Errorf("Expected a qBittorrent client with the password of the file, got %+v", movies)
+	}
+	if tv := definitions[1]; tv.URL != "http://localhost:9091/transmission/rpc" {
+		t.Errorf("Expected the default Transmission URL, got '%s'", tv.URL)
+	}
+	if music := definitions[2]; music.HostID != "abc" || music.URL != "http://localhost:8112/json" {
+		t.Errorf("Expected the Deluge host and default URL, got %+v", music)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"no clients", `{"clients": []}`, ErrNoClients},
+		{"duplicated name", `{"clients": [{"name": "tv"}, {"name": "tv"}]}`, ErrInvalidName},
+		{"name unusable in a path", `{"clients": [{"name": "TV Shows"}]}`, ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFile(writeFile(t, "clients.json", tt.content))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := LoadFile(writeFile(t, "clients.json", `{"clients": [{"name": "tv", "type": "utorrent"}]}`)); err == nil {
+		t.Error("Expected an error for an unknown client type, got nil")
+	}
+}
+
+func TestLoad_FromEnv(t *testing.T) {
+	t.Setenv(constants.AgentClientsFileEnv, "")
+	t.Setenv(constants.AgentClientEnv, entities.ClientTransmission)
+	t.Setenv("TRANSMISSION_URL", "http://transmission:9091/transmission/rpc")
+
+	definitions, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(definitions) != 1 || definitions[0].Name != DefaultName || definitions[0].URL != "http://transmission:9091/transmission/rpc" {
+		t.Errorf("Expected the client of the environment, got %+v", definitions)
+	}
+}
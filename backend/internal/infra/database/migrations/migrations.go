@@ -1,8 +1,13 @@
 package migrations
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
 	"github.com/gardarr/gardarr/internal/infra/migration"
 	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
 
 	"gorm.io/gorm"
 )
@@ -37,6 +42,10 @@ func Register(m *migration.Migrator) {
 				type User struct {
 					Role string `gorm:"size:100"`
 				}
+				// A database created after the model got its role already has it
+				if db.Migrator().HasColumn(&User{}, "Role") {
+					return nil
+				}
 				return db.Migrator().AddColumn(&User{}, "Role")
 			},
 			Down: func(db *gorm.DB) error {
@@ -140,5 +149,305 @@ func Register(m *migration.Migrator) {
 				return db.Migrator().DropColumn(&Agent{}, "Icon")
 			},
 		},
+		{
+			Version:     "009_create_agent_health_tables",
+			Description: "Cria as tabelas de estado e histórico de saúde dos agentes",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.AgentHealth{}, &models.AgentHealthEvent{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.AgentHealthEvent{}, &models.AgentHealth{})
+			},
+		},
+		{
+			Version:     "010_create_transfer_samples_table",
+			Description: "Cria a tabela de séries temporais de transferência",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.TransferSample{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.TransferSample{})
+			},
+		},
+		{
+			Version:     "011_create_rules_tables",
+			Description: "Cria as tabelas de regras de automação e de auditoria das execuções",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.Rule{}, &models.RuleExecution{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.RuleExecution{}, &models.Rule{})
+			},
+		},
+		{
+			Version:     "012_set_agents_type",
+			Description: "Define o tipo qbittorrent nos agentes cadastrados antes do suporte ao Transmission",
+			Up: func(db *gorm.DB) error {
+				type Agent struct {
+					Type string `gorm:"size:25"`
+				}
+				return db.Model(&Agent{}).Where("type IS NULL OR type = ''").Update("type", "qbittorrent").Error
+			},
+			Down: func(db *gorm.DB) error {
+				return nil
+			},
+		},
+		{
+			Version:     "013_add_user_access_control",
+			Description: "Adiciona usuários desativados, permissões por agente e convites, e atribui papéis aos usuários existentes",
+			Up: func(db *gorm.DB) error {
+				type User struct {
+					UUID      uuid.UUID
+					Role      string `gorm:"size:100"`
+					Disabled  bool   `gorm:"not null;default:false"`
+					CreatedAt time.Time
+				}
+				if !db.Migrator().HasColumn(&User{}, "Disabled") {
+					if err := db.Migrator().AddColumn(&User{}, "Disabled"); err != nil {
+						return err
+					}
+				}
+				if err := db.AutoMigrate(&models.AgentPermission{}, &models.UserInvite{}); err != nil {
+					return err
+				}
+
+				return db.Transaction(func(tx *gorm.DB) error {
+					// The oldest user becomes admin when there is none yet
+					var admins int64
+					if err := tx.Model(&User{}).Where("role = ?", "admin").Count(&admins).Error; err != nil {
+						return err
+					}
+					if admins == 0 {
+						var first User
+						err := tx.Where("role IS NULL OR role = ''").Order("created_at ASC").Limit(1).Find(&first).Error
+						if err != nil {
+							return err
+						}
+						if first.UUID != uuid.Nil {
+							if err := tx.Model(&User{}).Where("uuid = ?", first.UUID).Update("role", "admin").Error; err != nil {
+								return err
+							}
+						}
+					}
+
+					// The other users keep acting on the tasks of every existing agent
+					var users []User
+					if err := tx.Where("role IS NULL OR role = ''").Find(&users).Error; err != nil {
+						return err
+					}
+					if len(users) == 0 {
+						return nil
+					}
+
+					var agentUUIDs []uuid.UUID
+					if err := tx.Model(&models.Agent{}).Pluck("uuid", &agentUUIDs).Error; err != nil {
+						return err
+					}
+
+					var grants []models.AgentPermission
+					for _, user := range users {
+						for _, agentUUID := range agentUUIDs {
+							grants = append(grants, models.AgentPermission{UserUUID: user.UUID, AgentUUID: agentUUID})
+						}
+					}
+					if len(grants) > 0 {
+						if err := tx.Create(&grants).Error; err != nil {
+							return err
+						}
+					}
+
+					return tx.Model(&User{}).Where("role IS NULL OR role = ''").Update("role", "operator").Error
+				})
+			},
+			Down: func(db *gorm.DB) error {
+				type User struct{}
+				if err := db.Migrator().DropTable(&models.UserInvite{}, &models.AgentPermission{}); err != nil {
+					return err
+				}
+				return db.Migrator().DropColumn(&User{}, "Disabled")
+			},
+		},
+		{
+			Version:     "014_create_api_tokens_table",
+			Description: "Cria a tabela de tokens de API com escopos",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.APIToken{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.APIToken{})
+			},
+		},
+		{
+			Version:     "015_create_two_factor_tables",
+			Description: "Cria as tabelas de autenticação em dois fatores: segredos TOTP, códigos de recuperação e desafios de login",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.UserTOTP{}, &models.RecoveryCode{}, &models.LoginChallenge{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.LoginChallenge{}, &models.RecoveryCode{}, &models.UserTOTP{})
+			},
+		},
+		{
+			Version:     "016_create_password_resets_table",
+			Description: "Cria a tabela de tokens de redefinição de senha",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.PasswordReset{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.PasswordReset{})
+			},
+		},
+		{
+			Version:     "017_hash_session_tokens",
+			Description: "Substitui os tokens de sessão pelo seu hash SHA-256 e adiciona a coluna last_seen_at",
+			Up: func(db *gorm.DB) error {
+				migrator := db.Migrator()
+
+				// Sessions opened before store the token itself, it is replaced by
+				// its hash so that they stay valid
+				if migrator.HasColumn(&models.Session{}, "token") {
+					var sessions []struct {
+						ID    uuid.UUID
+						Token string
+					}
+					if err := db.Table("sessions").Select("id", "token").Find(&sessions).Error; err != nil {
+						return err
+					}
+
+					if migrator.HasIndex(&models.Session{}, "idx_sessions_token") {
+						if err := migrator.DropIndex(&models.Session{}, "idx_sessions_token"); err != nil {
+							return err
+						}
+					}
+					if err := migrator.RenameColumn(&models.Session{}, "token", "token_hash"); err != nil {
+						return err
+					}
+
+					for _, session := range sessions {
+						sum := sha256.Sum256([]byte(session.Token))
+						if err := db.Table("sessions").Where("id = ?", session.ID).Update("token_hash", hex.EncodeToString(sum[:])).Error; err != nil {
+							return err
+						}
+					}
+				}
+
+				if err := db.AutoMigrate(&models.Session{}); err != nil {
+					return err
+				}
+
+				return db.Table("sessions").Where("last_seen_at IS NULL").Update("last_seen_at", gorm.Expr("updated_at")).Error
+			},
+			Down: func(db *gorm.DB) error {
+				// Hashed tokens cannot be restored, the sessions are ended
+				if err := db.Where("1 = 1").Delete(&models.Session{}).Error; err != nil {
+					return err
+				}
+
+				migrator := db.Migrator()
+				if err := migrator.DropColumn(&models.Session{}, "LastSeenAt"); err != nil {
+					return err
+				}
+				if migrator.HasIndex(&models.Session{}, "idx_sessions_token_hash") {
+					if err := migrator.DropIndex(&models.Session{}, "idx_sessions_token_hash"); err != nil {
+						return err
+					}
+				}
+				return migrator.RenameColumn(&models.Session{}, "token_hash", "token")
+			},
+		},
+		{
+			Version:     "018_create_rate_limits_table",
+			Description: "Cria a tabela de estado do rate limiting, compartilhada entre as réplicas",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.RateLimit{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.RateLimit{})
+			},
+		},
+		{
+			Version:     "019_create_audit_events_table",
+			Description: "Cria a tabela do log de auditoria de segurança",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.AuditEvent{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.AuditEvent{})
+			},
+		},
+		{
+			Version:     "020_create_oidc_tables",
+			Description: "Cria as tabelas do single sign-on OIDC: logins em andamento e identidades vinculadas aos usuários",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.OIDCLoginState{}, &models.UserIdentity{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.UserIdentity{}, &models.OIDCLoginState{})
+			},
+		},
+		{
+			Version:     "021_add_certificate_fingerprint_to_agents",
+			Description: "Adiciona a coluna certificate_fingerprint na tabela agents, para fixar o certificado TLS de cada agente",
+			Up: func(db *gorm.DB) error {
+				type Agent struct {
+					CertificateFingerprint string `gorm:"size:64"`
+				}
+				// A database created after the model got its fingerprint already has it
+				if db.Migrator().HasColumn(&Agent{}, "CertificateFingerprint") {
+					return nil
+				}
+				return db.Migrator().AddColumn(&Agent{}, "CertificateFingerprint")
+			},
+			Down: func(db *gorm.DB) error {
+				type Agent struct{}
+				return db.Migrator().DropColumn(&Agent{}, "CertificateFingerprint")
+			},
+		},
+		{
+			Version:     "022_create_agent_enrollment_tokens_table",
+			Description: "Cria a tabela dos tokens de uso único com que os agentes se registram no gerenciador",
+			Up: func(db *gorm.DB) error {
+				return db.AutoMigrate(&models.AgentEnrollmentToken{})
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Migrator().DropTable(&models.AgentEnrollmentToken{})
+			},
+		},
+		{
+			Version:     "023_add_parent_to_agents",
+			Description: "Adiciona as colunas parent_uuid e client na tabela agents, para os clientes torrent de um agente que gerencia vários",
+			Up: func(db *gorm.DB) error {
+				type Agent struct {
+					ParentUUID *uuid.UUID `gorm:"type:uuid;index"`
+					Client     string     `gorm:"size:50"`
+				}
+				// A database created after the model got its parent already has them
+				for _, column := range []string{"ParentUUID", "Client"} {
+					if db.Migrator().HasColumn(&Agent{}, column) {
+						continue
+					}
+					if err := db.Migrator().AddColumn(&Agent{}, column); err != nil {
+						return err
+					}
+				}
+				if db.Migrator().HasIndex(&Agent{}, "ParentUUID") {
+					return nil
+				}
+				return db.Migrator().CreateIndex(&Agent{}, "ParentUUID")
+			},
+			Down: func(db *gorm.DB) error {
+				type Agent struct {
+					ParentUUID *uuid.UUID `gorm:"type:uuid;index"`
+					Client     string     `gorm:"size:50"`
+				}
+				if err := db.Migrator().DropIndex(&Agent{}, "ParentUUID"); err != nil {
+					return err
+				}
+				if err := db.Migrator().DropColumn(&Agent{}, "Client"); err != nil {
+					return err
+				}
+				return db.Migrator().DropColumn(&Agent{}, "ParentUUID")
+			},
+		},
 	})
 }
@@ -0,0 +1,190 @@
+// Package deluge is a minimal client of the Deluge Web JSON-RPC API, covering
+// the calls the agent needs to manage torrents and the daemon
+package deluge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorCodeNotAuthenticated is the error code of the calls made without a valid session cookie
+const ErrorCodeNotAuthenticated = 1
+
+// Client calls a Deluge Web JSON-RPC endpoint. The Web UI authenticates with a
+// session cookie and forwards the core.* calls to the daemon it is connected to.
+type Client struct {
+	url      string
+	password string
+	hostID   string
+	http     *http.Client
+
+	nextID atomic.Int64
+
+	mu    sync.Mutex
+	ready bool
+}
+
+// New creates a client for the JSON endpoint, e.g. http://localhost:8112/json.
+// hostID selects the daemon of the Web UI host list, the first one when empty.
+func New(url, password, hostID string) *Client {
+	jar, _ := cookiejar.New(nil)
+
+	return &Client{
+		url:      strings.TrimRight(url, "/"),
+		password: password,
+		hostID:   hostID,
+		http:     &http.Client{Timeout: 30 * time.Second, Jar: jar},
+	}
+}
+
+type request struct {
+	ID     int64  `json:"id"`
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+}
+
+type response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Call runs an RPC method and decodes its result into result when not nil. The
+// session is opened on the first call and again when it expires.
+func (c *Client) Call(ctx context.Context, method string, result any, params ...any) error {
+	if err := c.session(ctx); err != nil {
+		return err
+	}
+
+	err := c.call(ctx, method, result, params...)
+	if apiErr := (*Error)(nil); errors.As(err, &apiErr) && apiErr.Code == ErrorCodeNotAuthenticated {
+		c.mu.Lock()
+		c.ready = false
+		c.mu.Unlock()
+
+		if err := c.session(ctx); err != nil {
+			return err
+		}
+		return c.call(ctx, method, result, params...)
+	}
+
+	return err
+}
+
+// session logs in and connects the Web UI to the daemon unless it is done already
+func (c *Client) session(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ready {
+		return nil
+	}
+
+	var loggedIn bool
+	if err := c.call(ctx, "auth.login", &loggedIn, c.password); err != nil {
+		return err
+	}
+	if !loggedIn {
+		return errors.New("deluge refused the password")
+	}
+
+	var connected bool
+	if err := c.call(ctx, "web.connected", &connected); err != nil {
+		return err
+	}
+	if !connected {
+		if err := c.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.ready = true
+	return nil
+}
+
+// connect connects the Web UI to the configured daemon host
+func (c *Client) connect(ctx context.Context) error {
+	// every host is [id, address, port, status or user]
+	var hosts [][]any
+	if err := c.call(ctx, "web.get_hosts", &hosts); err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		if len(host) == 0 {
+			continue
+		}
+		id, _ := host[0].(string)
+		if c.hostID == "" || c.hostID == id {
+			return c.call(ctx, "web.connect", nil, id)
+		}
+	}
+
+	if c.hostID != "" {
+		return fmt.Errorf("deluge has no daemon host %s", c.hostID)
+	}
+	return errors.New("deluge has no daemon host")
+}
+
+func (c *Client) call(ctx context.Context, method string, result any, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+
+	body, err := json.Marshal(request{ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("deluge %s answered %d", method, resp.StatusCode)
+	}
+
+	var decoded response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&decoded); err != nil {
+		return fmt.Errorf("failed to decode deluge %s answer: %w", method, err)
+	}
+	if decoded.Error != nil {
+		decoded.Error.Method = method
+		return decoded.Error
+	}
+
+	if result == nil || len(decoded.Result) == 0 || string(decoded.Result) == "null" {
+		return nil
+	}
+	return json.Unmarshal(decoded.Result, result)
+}
+
+// Error is a call Deluge answered with an error
+type Error struct {
+	Method  string `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("deluge %s failed: %s", e.Method, e.Message)
+}
@@ -0,0 +1,282 @@
+package deluge
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+)
+
+// Torrent states reported in the state key
+const (
+	StateAllocating  = "Allocating"
+	StateChecking    = "Checking"
+	StateDownloading = "Downloading"
+	StateSeeding     = "Seeding"
+	StatePaused      = "Paused"
+	StateError       = "Error"
+	StateQueued      = "Queued"
+	StateMoving      = "Moving"
+)
+
+// TorrentFields are the status keys requested by TorrentsStatus
+var TorrentFields = []string{
+	"hash", "name", "state", "message", "progress", "is_finished",
+	"total_size", "total_wanted", "ratio", "download_location", "label", "queue",
+	"tracker", "total_seeds", "total_peers", "num_seeds", "num_peers",
+	"download_payload_rate", "upload_payload_rate", "all_time_download", "total_uploaded",
+	"seeding_time",
+}
+
+// Torrent holds the status keys of a torrent. The label is only set when the
+// Label plugin is enabled.
+type Torrent struct {
+	Hash                string  `json:"hash"`
+	Name                string  `json:"name"`
+	Status              string  `json:"state"`
+	Message             string  `json:"message"`
+	Progress            float64 `json:"progress"` // percent
+	IsFinished          bool    `json:"is_finished"`
+	TotalSize           int     `json:"total_size"`
+	TotalWanted         int     `json:"total_wanted"`
+	Ratio               float64 `json:"ratio"`
+	DownloadLocation    string  `json:"download_location"`
+	Label               string  `json:"label"`
+	Queue               int     `json:"queue"`
+	Tracker             string  `json:"tracker"`
+	TotalSeeds          int     `json:"total_seeds"`
+	TotalPeers          int     `json:"total_peers"`
+	NumSeeds            int     `json:"num_seeds"`
+	NumPeers            int     `json:"num_peers"`
+	DownloadPayloadRate int     `json:"download_payload_rate"`
+	UploadPayloadRate   int     `json:"upload_payload_rate"`
+	AllTimeDownload     int     `json:"all_time_download"`
+	TotalUploaded       int     `json:"total_uploaded"`
+	SeedingTime         int     `json:"seeding_time"`
+}
+
+// State maps the torrent state to the qBittorrent state names used as keys
+// of entities.TaskStatuses
+func (t Torrent) State() string {
+	switch t.Status {
+	case StateError:
+		return "error"
+	case StatePaused:
+		if t.IsFinished {
+			return "pausedUP"
+		}
+		return "pausedDL"
+	case StateQueued:
+		if t.IsFinished {
+			return "queuedUP"
+		}
+		return "queuedDL"
+	case StateChecking:
+		if t.IsFinished {
+			return "checkingUP"
+		}
+		return "checkingDL"
+	case StateAllocating:
+		return "allocating"
+	case StateMoving:
+		return "moving"
+	case StateDownloading:
+		if t.NumSeeds == 0 && t.DownloadPayloadRate == 0 {
+			return "stalledDL"
+		}
+		return "downloading"
+	case StateSeeding:
+		if t.NumPeers == 0 {
+			return "stalledUP"
+		}
+		return "uploading"
+	}
+	return "unknown"
+}
+
+// MagnetURI builds the magnet link of the torrent, Deluge does not report it
+func (t Torrent) MagnetURI() string {
+	if t.Hash == "" {
+		return ""
+	}
+
+	uri := "magnet:?xt=urn:btih:" + t.Hash
+	if t.Name != "" {
+		uri += "&dn=" + url.QueryEscape(t.Name)
+	}
+	if t.Tracker != "" {
+		uri += "&tr=" + url.QueryEscape(t.Tracker)
+	}
+	return uri
+}
+
+// TorrentsStatus returns the torrents with the given hashes, or every torrent when none is given, by hash
+func (c *Client) TorrentsStatus(ctx context.Context, hashes ...string) (map[string]Torrent, error) {
+	filter := map[string]any{}
+	if len(hashes) > 0 {
+		filter["id"] = hashes
+	}
+
+	result := make(map[string]Torrent)
+	if err := c.Call(ctx, "core.get_torrents_status", &result, filter, TorrentFields); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AddMagnet adds a magnet link and returns the hash of the torrent. Deluge
+// answers no hash for a torrent it already has.
+func (c *Client) AddMagnet(ctx context.Context, uri string, options map[string]any) (string, error) {
+	if options == nil {
+		options = map[string]any{}
+	}
+
+	var hash string
+	if err := c.Call(ctx, "core.add_torrent_magnet", &hash, uri, options); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// AddTorrentFile adds a .torrent file and returns the hash of the torrent.
+// Deluge answers no hash for a torrent it already has.
+func (c *Client) AddTorrentFile(ctx context.Context, filename string, data []byte, options map[string]any) (string, error) {
+	if options == nil {
+		options = map[string]any{}
+	}
+
+	var hash string
+	if err := c.Call(ctx, "core.add_torrent_file", &hash, filename, base64.StdEncoding.EncodeToString(data), options); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// TorrentsAction runs an action taking the torrent hashes such as core.pause_torrents,
+// core.resume_torrents, core.force_recheck or core.force_reannounce
+func (c *Client) TorrentsAction(ctx context.Context, method string, hashes ...string) error {
+	return c.Call(ctx, method, nil, hashes)
+}
+
+// RemoveTorrent removes a torrent, and its data when removeData is set
+func (c *Client) RemoveTorrent(ctx context.Context, hash string, removeData bool) error {
+	return c.Call(ctx, "core.remove_torrent", nil, hash, removeData)
+}
+
+// MoveStorage moves the data of torrents
+func (c *Client) MoveStorage(ctx context.Context, location string, hashes ...string) error {
+	return c.Call(ctx, "core.move_storage", nil, hashes, location)
+}
+
+// SetTorrentOptions changes the given options of torrents. Speed limits are in KiB/s, -1 removes them.
+func (c *Client) SetTorrentOptions(ctx context.Context, options map[string]any, hashes ...string) error {
+	return c.Call(ctx, "core.set_torrent_options", nil, hashes, options)
+}
+
+// Labels returns the labels of the Label plugin
+func (c *Client) Labels(ctx context.Context) ([]string, error) {
+	var labels []string
+	if err := c.Call(ctx, "label.get_labels", &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// AddLabel creates a label of the Label plugin
+func (c *Client) AddLabel(ctx context.Context, label string) error {
+	return c.Call(ctx, "label.add", nil, label)
+}
+
+// SetTorrentLabel sets the label of a torrent, an empty label removes it
+func (c *Client) SetTorrentLabel(ctx context.Context, hash, label string) error {
+	return c.Call(ctx, "label.set_torrent", nil, hash, label)
+}
+
+// File is a file of a torrent
+type File struct {
+	Index int    `json:"index"`
+	Path  string `json:"path"`
+	Size  int    `json:"size"`
+}
+
+// TorrentFiles returns the files of a torrent with their progress, between 0 and 1,
+// and priority, in the same order
+func (c *Client) TorrentFiles(ctx context.Context, hash string) ([]File, []float64, []int, error) {
+	var result struct {
+		Files          []File    `json:"files"`
+		FileProgress   []float64 `json:"file_progress"`
+		FilePriorities []int     `json:"file_priorities"`
+	}
+	if err := c.Call(ctx, "core.get_torrent_status", &result, hash, []string{"files", "file_progress", "file_priorities"}); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return result.Files, result.FileProgress, result.FilePriorities, nil
+}
+
+// Version returns the version of the daemon
+func (c *Client) Version(ctx context.Context) (string, error) {
+	var version string
+	if err := c.Call(ctx, "daemon.get_version", &version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// SpeedUnit is the size of the KiB of the speed limits
+const SpeedUnit = 1024
+
+// SpeedLimit converts a limit in B/s to KiB/s, -1 when there is none
+func SpeedLimit(limit int) float64 {
+	if limit <= 0 {
+		return -1
+	}
+	return float64(limit) / SpeedUnit
+}
+
+// Config holds the daemon settings used by the agent. Speed limits are in KiB/s, -1 when unlimited.
+type Config struct {
+	DownloadLocation string  `json:"download_location"`
+	MaxDownloadSpeed float64 `json:"max_download_speed"`
+	MaxUploadSpeed   float64 `json:"max_upload_speed"`
+}
+
+// GetConfig returns the daemon settings
+func (c *Client) GetConfig(ctx context.Context) (*Config, error) {
+	var config Config
+	if err := c.Call(ctx, "core.get_config_values", &config, []string{"download_location", "max_download_speed", "max_upload_speed"}); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SetConfig changes the given daemon settings
+func (c *Client) SetConfig(ctx context.Context, values map[string]any) error {
+	return c.Call(ctx, "core.set_config", nil, values)
+}
+
+// SessionStatus holds the payload totals of the running daemon session
+type SessionStatus struct {
+	TotalPayloadDownload int `json:"total_payload_download"`
+	TotalPayloadUpload   int `json:"total_payload_upload"`
+}
+
+// GetSessionStatus returns the transfer totals since the daemon started
+func (c *Client) GetSessionStatus(ctx context.Context) (*SessionStatus, error) {
+	var status SessionStatus
+	if err := c.Call(ctx, "core.get_session_status", &status, []string{"total_payload_download", "total_payload_upload"}); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// FreeSpace returns the free space, in bytes, of the disk holding the default download location
+func (c *Client) FreeSpace(ctx context.Context) (int, error) {
+	var size int
+	if err := c.Call(ctx, "core.get_free_space", &size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/gardarr/gardarr/internal/entities"
+)
+
+// Broker fans events out to its subscribers. Publishing never blocks: a
+// subscriber that does not keep up is dropped and has to subscribe again,
+// which gives it a fresh snapshot instead of a gap in the stream.
+type Broker struct {
+	buffer int
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	C <-chan *entities.Event
+
+	ch     chan *entities.Event
+	broker *Broker
+}
+
+// NewBroker creates a broker whose subscribers buffer up to buffer events
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		buffer:      buffer,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber
+func (b *Broker) Subscribe() *Subscription {
+	ch := make(chan *entities.Event, b.buffer)
+	sub := &Subscription{C: ch, ch: ch, broker: b}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish sends the event to every subscriber
+func (b *Broker) Publish(event *entities.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Len returns the number of subscribers
+func (b *Broker) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Close unregisters the subscription. Its channel is closed.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if _, ok := s.broker.subscribers[s]; ok {
+		delete(s.broker.subscribers, s)
+		close(s.ch)
+	}
+}
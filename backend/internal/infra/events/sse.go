@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle streams open through proxies
+const heartbeatInterval = 15 * time.Second
+
+// maxLineSize bounds a single SSE line (a snapshot of a large instance is one line)
+const maxLineSize = 64 << 20
+
+// Stream writes the initial events and then the subscription to the client as
+// Server-Sent Events, until the client goes away or the subscription is closed
+func Stream(c *gin.Context, initial []*entities.Event, sub <-chan *entities.Event) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range initial {
+		if err := writeEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+			if err := writeEvent(c.Writer, event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, event *entities.Event) error {
+	data, err := json.Marshal(mappers.ToEventResponse(event))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// Read parses a Server-Sent Events stream and calls fn for every event, until
+// the stream ends or fn returns an error
+func Read(r io.Reader, fn func(name string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var name string
+	var data bytes.Buffer
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		switch {
+		case len(line) == 0:
+			if data.Len() > 0 {
+				if name == "" {
+					name = "message"
+				}
+				if err := fn(name, bytes.Clone(data.Bytes())); err != nil {
+					return err
+				}
+			}
+			name = ""
+			data.Reset()
+		case line[0] == ':':
+			// comment, used as heartbeat
+		case bytes.HasPrefix(line, []byte("event:")):
+			name = string(bytes.TrimSpace(line[len("event:"):]))
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(line[len("data:"):], []byte(" ")))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
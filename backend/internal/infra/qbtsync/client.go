@@ -0,0 +1,153 @@
+// Package qbtsync reads the incremental state of a qBittorrent instance through
+// the sync/maindata endpoint of its Web API, and applies the torrent edits that
+// go-qbt does not cover
+package qbtsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errForbidden = errors.New("qbittorrent session rejected")
+
+// MainData is a sync/maindata answer. When FullUpdate is false, Torrents and
+// ServerState only hold the fields that changed since the previous rid.
+type MainData struct {
+	Rid             int64                                 `json:"rid"`
+	FullUpdate      bool                                  `json:"full_update"`
+	Torrents        map[string]map[string]json.RawMessage `json:"torrents"`
+	TorrentsRemoved []string                              `json:"torrents_removed"`
+	ServerState     map[string]json.RawMessage            `json:"server_state"`
+}
+
+// Client is a minimal qBittorrent Web API client
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+// New creates a client. The session is opened on the first request.
+func New(baseURL, username, password string) *Client {
+	jar, _ := cookiejar.New(nil)
+
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		http: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// MainData returns the changes since rid, or the full state when rid is 0
+func (c *Client) MainData(ctx context.Context, rid int64) (*MainData, error) {
+	var data *MainData
+	err := c.withSession(ctx, func() (err error) {
+		data, err = c.mainData(ctx, rid)
+		return err
+	})
+
+	return data, err
+}
+
+// withSession runs fn with an open session, logging in again once when it was rejected
+func (c *Client) withSession(ctx context.Context, fn func() error) error {
+	if err := c.ensureLogin(ctx); err != nil {
+		return err
+	}
+
+	err := fn()
+	if errors.Is(err, errForbidden) {
+		// the session expired, open a new one and retry once
+		c.mu.Lock()
+		c.loggedIn = false
+		c.mu.Unlock()
+
+		if err := c.ensureLogin(ctx); err != nil {
+			return err
+		}
+		err = fn()
+	}
+
+	return err
+}
+
+func (c *Client) mainData(ctx context.Context, rid int64) (*MainData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v2/sync/maindata?rid="+strconv.FormatInt(rid, 10), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, errForbidden
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("qbittorrent sync failed with status %d", resp.StatusCode)
+	}
+
+	var data MainData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode qbittorrent sync data: %w", err)
+	}
+
+	return &data, nil
+}
+
+func (c *Client) ensureLogin(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loggedIn {
+		return nil
+	}
+
+	form := url.Values{
+		"username": {c.username},
+		"password": {c.password},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v2/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// qBittorrent rejects logins whose Referer/Origin does not match its host
+	req.Header.Set("Referer", c.baseURL)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "Ok." {
+		return fmt.Errorf("qbittorrent login failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	c.loggedIn = true
+	return nil
+}
@@ -0,0 +1,236 @@
+package qbtsync
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	hashB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// fakeQBittorrent serves scripted sync/maindata answers, one per rid, to the
+// requests carrying the cookie of the current session
+type fakeQBittorrent struct {
+	mu         sync.Mutex
+	responses  map[int64]string
+	password   string
+	session    string // value of the current SID, the older ones being rejected
+	logins     int
+	rids       []int64
+	posts      []string
+	categories map[string]bool
+	status     map[string]int // answer of a path instead of 200
+	answer     map[string]string
+}
+
+func newFake() *fakeQBittorrent {
+	return &fakeQBittorrent{
+		responses:  map[int64]string{},
+		password:   "secret",
+		categories: map[string]bool{},
+		status:     map[string]int{},
+		answer:     map[string]string{},
+	}
+}
+
+func (f *fakeQBittorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/api/v2/auth/login" {
+		f.logins++
+		if r.FormValue("password") != f.password {
+			w.Write([]byte("Fails."))
+			return
+		}
+		f.session = "session" + strconv.Itoa(f.logins)
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: f.session, Path: "/"})
+		w.Write([]byte("Ok."))
+		return
+	}
+
+	if cookie, err := r.Cookie("SID"); err != nil || cookie.Value != f.session {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if status, ok := f.status[r.URL.Path]; ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/v2/sync/maindata":
+		rid, _ := strconv.ParseInt(r.URL.Query().Get("rid"), 10, 64)
+		f.rids = append(f.rids, rid)
+		w.Write([]byte(f.responses[rid]))
+	case "/api/v2/torrents/setCategory":
+		if !f.categories[r.FormValue("category")] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.posts = append(f.posts, r.URL.Path)
+	case "/api/v2/torrents/createCategory":
+		f.categories[r.FormValue("category")] = true
+		f.posts = append(f.posts, r.URL.Path)
+	default:
+		f.posts = append(f.posts, r.URL.Path)
+		w.Write([]byte(f.answer[r.URL.Path]))
+	}
+}
+
+// expire drops the current session, as qBittorrent does after its timeout
+func (f *fakeQBittorrent) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = "expired"
+}
+
+func setupClient(t *testing.T, fake *fakeQBittorrent) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return New(server.URL+"/", "admin", "secret")
+}
+
+func TestClient_MainData(t *testing.T) {
+	fake := newFake()
+	fake.responses[0] = `{"rid":1,"full_update":true,
+		"torrents":{
+			"` + hashA + `":{"name":"Movie","state":"uploading","uploaded":100},
+			"` + hashB + `":{"name":"Show","state":"downloading"}
+		},
+		"server_state":{"alltime_ul":2000}}`
+	fake.responses[1] = `{"rid":2,
+		"torrents":{"` + hashA + `":{"uploaded":150}},
+		"torrents_removed":["` + hashB + `"]}`
+	client := setupClient(t, fake)
+	ctx := context.Background()
+
+	full, err := client.MainData(ctx, 0)
+	if err != nil {
+		t.Fatalf("Failed to get the full state: %v", err)
+	}
+	if !full.FullUpdate || full.Rid != 1 || len(full.Torrents) != 2 || string(full.ServerState["alltime_ul"]) != "2000" {
+		t.Errorf("Expected the full state at rid 1, got %+v", full)
+	}
+
+	// A partial update only holds the changed fields
+	partial, err := client.MainData(ctx, full.Rid)
+	if err != nil {
+		t.Fatalf("Failed to get the changes: %v", err)
+	}
+	if partial.FullUpdate || partial.Rid != 2 {
+		t.Errorf("Expected a partial update at rid 2, got %+v", partial)
+	}
+	if torrent := partial.Torrents[hashA]; len(torrent) != 1 || string(torrent["uploaded"]) != "150" {
+		t.Errorf("Expected only the uploaded field of the torrent, got %v", torrent)
+	}
+	if len(partial.TorrentsRemoved) != 1 || partial.TorrentsRemoved[0] != hashB {
+		t.Errorf("Expected the removed torrent, got %v", partial.TorrentsRemoved)
+	}
+
+	if fake.logins != 1 || len(fake.rids) != 2 || fake.rids[1] != 1 {
+		t.Errorf("Expected a single login and the rids 0 and 1, got %d logins and %v", fake.logins, fake.rids)
+	}
+}
+
+func TestClient_LogsInAgainWhenSessionExpires(t *testing.T) {
+	fake := newFake()
+	fake.responses[0] = `{"rid":1,"full_update":true}`
+	client := setupClient(t, fake)
+	ctx := context.Background()
+
+	if _, err := client.MainData(ctx, 0); err != nil {
+		t.Fatalf("Failed to get the state: %v", err)
+	}
+
+	fake.expire()
+	if _, err := client.MainData(ctx, 0); err != nil {
+		t.Fatalf("Expected the request to be retried with a new session, got %v", err)
+	}
+	if err := client.SetCategory(ctx, hashA, "tv"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fake.logins != 2 {
+		t.Errorf("Expected 2 logins, got %d", fake.logins)
+	}
+
+	// A session rejected again is not retried in a loop
+	fake.status["/api/v2/sync/maindata"] = http.StatusForbidden
+	if _, err := client.MainData(ctx, 0); !errors.Is(err, errForbidden) {
+		t.Errorf("Expected errForbidden, got %v", err)
+	}
+	if fake.logins != 3 {
+		t.Errorf("Expected a single new login, got %d logins", fake.logins)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("login refused", func(t *testing.T) {
+		fake := newFake()
+		fake.password = "other"
+		client := setupClient(t, fake)
+
+		if _, err := client.MainData(ctx, 0); err == nil || !strings.Contains(err.Error(), "login failed") {
+			t.Errorf("Expected the login to fail, got %v", err)
+		}
+		if len(fake.rids) != 0 {
+			t.Errorf("Expected no sync request without a session, got %v", fake.rids)
+		}
+	})
+
+	t.Run("sync status", func(t *testing.T) {
+		fake := newFake()
+		fake.status["/api/v2/sync/maindata"] = http.StatusInternalServerError
+		client := setupClient(t, fake)
+
+		if _, err := client.MainData(ctx, 0); err == nil || !strings.Contains(err.Error(), "status 500") {
+			t.Errorf("Expected the status in the error, got %v", err)
+		}
+	})
+
+	t.Run("malformed sync data", func(t *testing.T) {
+		fake := newFake()
+		fake.responses[0] = `{"rid":`
+		client := setupClient(t, fake)
+
+		if _, err := client.MainData(ctx, 0); err == nil || !strings.Contains(err.Error(), "decode") {
+			t.Errorf("Expected a decoding error, got %v", err)
+		}
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		fake := newFake()
+		client := setupClient(t, fake)
+
+		// The category is created, the torrent being moved again
+		if err := client.SetCategory(ctx, hashA, "tv"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !fake.categories["tv"] || len(fake.posts) != 2 || fake.posts[1] != "/api/v2/torrents/setCategory" {
+			t.Errorf("Expected the category to be created before moving the torrent, got %v", fake.posts)
+		}
+	})
+
+	t.Run("torrent refused", func(t *testing.T) {
+		fake := newFake()
+		fake.answer["/api/v2/torrents/add"] = "Fails."
+		client := setupClient(t, fake)
+
+		if err := client.AddTorrent(ctx, AddOptions{MagnetURI: "magnet:?xt=urn:btih:" + hashA}); err == nil {
+			t.Error("Expected an error for a torrent qBittorrent failed to add")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package qbtsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var errConflict = errors.New("qbittorrent conflict")
+
+// SetCategory moves a torrent to a category, creating the category when it does
+// not exist yet. An empty category removes the torrent from its category.
+func (c *Client) SetCategory(ctx context.Context, hash, category string) error {
+	form := url.Values{"hashes": {hash}, "category": {category}}
+
+	err := c.post(ctx, "/api/v2/torrents/setCategory", form)
+	if errors.Is(err, errConflict) {
+		// qBittorrent answers 409 for unknown categories
+		if err := c.post(ctx, "/api/v2/torrents/createCategory", url.Values{"category": {category}}); err != nil {
+			return err
+		}
+		err = c.post(ctx, "/api/v2/torrents/setCategory", form)
+	}
+
+	return err
+}
+
+// ReplaceTags sets the tags of a torrent to exactly the given ones
+func (c *Client) ReplaceTags(ctx context.Context, hash string, tags []string) error {
+	// without a tags field every tag of the torrent is removed
+	if err := c.post(ctx, "/api/v2/torrents/removeTags", url.Values{"hashes": {hash}}); err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return c.post(ctx, "/api/v2/torrents/addTags", url.Values{"hashes": {hash}, "tags": {strings.Join(tags, ",")}})
+}
+
+// AddOptions are the fields of a torrents/add request, adding either the
+// magnet link or the .torrent file
+type AddOptions struct {
+	MagnetURI string
+	Torrent   []byte
+	SavePath  string
+	Category  string
+	Tags      []string
+	Paused    bool
+	// SkipChecking trusts the data already in the save path
+	SkipChecking       bool
+	SequentialDownload bool
+	// ContentLayout is Original, Subfolder or NoSubfolder, the default when empty
+	ContentLayout string
+}
+
+// AddTorrent adds a torrent. qBittorrent adds it asynchronously, it may not be
+// listed yet when AddTorrent returns.
+func (c *Client) AddTorrent(ctx context.Context, options AddOptions) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	if len(options.Torrent) > 0 {
+		part, err := form.CreateFormFile("torrents", "task.torrent")
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(options.Torrent); err != nil {
+			return err
+		}
+	}
+
+	fields := map[string]string{
+		"urls":     options.MagnetURI,
+		"savepath": options.SavePath,
+		"category": options.Category,
+		"tags":     strings.Join(options.Tags, ","),
+	}
+	if options.Paused {
+		// qBittorrent 5 renamed paused to stopped
+		fields["paused"], fields["stopped"] = "true", "true"
+	}
+	if options.SkipChecking {
+		fields["skip_checking"] = "true"
+	}
+	if options.SequentialDownload {
+		fields["sequentialDownload"] = "true"
+	}
+	if options.ContentLayout != "" {
+		fields["contentLayout"] = options.ContentLayout
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	return c.send(ctx, "/api/v2/torrents/add", form.FormDataContentType(), body.Bytes())
+}
+
+// SetFilePriorities sets the priority of the files of a torrent by index, on
+// the qBittorrent scale: 0 skipped, 1 normal, 6 high, 7 maximal
+func (c *Client) SetFilePriorities(ctx context.Context, hash string, priorities []int) error {
+	indexes := make(map[int][]string)
+	for i, priority := range priorities {
+		indexes[priority] = append(indexes[priority], strconv.Itoa(i))
+	}
+
+	for priority, ids := range indexes {
+		// files are added with the normal priority
+		if priority == 1 {
+			continue
+		}
+
+		form := url.Values{"hash": {hash}, "id": {strings.Join(ids, "|")}, "priority": {strconv.Itoa(priority)}}
+		if err := c.post(ctx, "/api/v2/torrents/filePrio", form); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values) error {
+	return c.send(ctx, path, "application/x-www-form-urlencoded", []byte(form.Encode()))
+}
+
+func (c *Client) send(ctx context.Context, path, contentType string, body []byte) error {
+	return c.withSession(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// qBittorrent 4 answers 200 to the torrents it failed to add
+			answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			if strings.TrimSpace(string(answer)) == "Fails." {
+				return fmt.Errorf("qbittorrent request %s failed", path)
+			}
+			return nil
+		case http.StatusForbidden:
+			return errForbidden
+		case http.StatusConflict:
+			return errConflict
+		default:
+			return fmt.Errorf("qbittorrent request %s failed with status %d", path, resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Package transmission is a minimal client of the Transmission RPC protocol,
+// covering the calls the agent needs to manage torrents and the session
+package transmission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionHeader carries the CSRF token Transmission requires on every call
+const sessionHeader = "X-Transmission-Session-Id"
+
+// Client calls a Transmission RPC endpoint
+type Client struct {
+	url      string
+	username string
+	password string
+	http     *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// New creates a client for the RPC endpoint, e.g. http://localhost:9091/transmission/rpc
+func New(url, username, password string) *Client {
+	return &Client{
+		url:      strings.TrimRight(url, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type request struct {
+	Method    string `json:"method"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Call runs an RPC method and decodes its arguments into result when not nil
+func (c *Client) Call(ctx context.Context, method string, args any, result any) error {
+	body, err := json.Marshal(request{Method: method, Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		// the session id is missing or expired, the answer carries a new one
+		resp.Body.Close()
+		c.mu.Lock()
+		c.sessionID = resp.Header.Get(sessionHeader)
+		c.mu.Unlock()
+
+		if resp, err = c.do(ctx, body); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("transmission %s answered %d", method, resp.StatusCode)
+	}
+
+	var decoded response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&decoded); err != nil {
+		return fmt.Errorf("failed to decode transmission %s answer: %w", method, err)
+	}
+	if decoded.Result != "success" {
+		return &Error{Method: method, Result: decoded.Result}
+	}
+
+	if result == nil || len(decoded.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(decoded.Arguments, result)
+}
+
+func (c *Client) do(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c.mu.Lock()
+	if c.sessionID != "" {
+		req.Header.Set(sessionHeader, c.sessionID)
+	}
+	c.mu.Unlock()
+
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.http.Do(req)
+}
+
+// Error is a call Transmission answered with a result other than success
+type Error struct {
+	Method string
+	Result string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("transmission %s failed: %s", e.Method, e.Result)
+}
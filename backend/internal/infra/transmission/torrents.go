@@ -0,0 +1,352 @@
+package transmission
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+// Torrent activity reported in the status field
+const (
+	StatusStopped = iota
+	StatusCheckWait
+	StatusCheck
+	StatusDownloadWait
+	StatusDownload
+	StatusSeedWait
+	StatusSeed
+)
+
+// TorrentFields are the fields requested by TorrentGet and RecentlyActive
+var TorrentFields = []string{
+	"id", "hashString", "name", "status", "error", "errorString",
+	"totalSize", "sizeWhenDone", "leftUntilDone", "percentDone", "metadataPercentComplete",
+	"uploadRatio", "downloadDir", "labels", "queuePosition", "magnetLink",
+	"rateDownload", "rateUpload", "downloadedEver", "uploadedEver", "secondsSeeding",
+	"peersSendingToUs", "peersGettingFromUs", "trackerStats",
+}
+
+// Torrent holds the torrent-get fields of a torrent
+type Torrent struct {
+	ID                      int            `json:"id"`
+	HashString              string         `json:"hashString"`
+	Name                    string         `json:"name"`
+	Status                  int            `json:"status"`
+	Error                   int            `json:"error"`
+	ErrorString             string         `json:"errorString"`
+	TotalSize               int            `json:"totalSize"`
+	SizeWhenDone            int            `json:"sizeWhenDone"`
+	LeftUntilDone           int            `json:"leftUntilDone"`
+	PercentDone             float64        `json:"percentDone"`
+	MetadataPercentComplete float64        `json:"metadataPercentComplete"`
+	UploadRatio             float64        `json:"uploadRatio"`
+	DownloadDir             string         `json:"downloadDir"`
+	Labels                  []string       `json:"labels"`
+	QueuePosition           int            `json:"queuePosition"`
+	MagnetLink              string         `json:"magnetLink"`
+	RateDownload            int            `json:"rateDownload"`
+	RateUpload              int            `json:"rateUpload"`
+	DownloadedEver          int            `json:"downloadedEver"`
+	UploadedEver            int            `json:"uploadedEver"`
+	SecondsSeeding          int            `json:"secondsSeeding"`
+	PeersSendingToUs        int            `json:"peersSendingToUs"`
+	PeersGettingFromUs      int            `json:"peersGettingFromUs"`
+	TrackerStats            []TrackerStats `json:"trackerStats"`
+}
+
+// TrackerStats holds the announce state of a tracker of a torrent
+type TrackerStats struct {
+	Announce              string `json:"announce"`
+	Host                  string `json:"host"`
+	Tier                  int    `json:"tier"`
+	SeederCount           int    `json:"seederCount"`
+	LeecherCount          int    `json:"leecherCount"`
+	LastAnnounceSucceeded bool   `json:"lastAnnounceSucceeded"`
+}
+
+// State maps the torrent activity to the qBittorrent state names used as keys
+// of entities.TaskStatuses
+func (t Torrent) State() string {
+	complete := t.LeftUntilDone == 0 && t.MetadataPercentComplete >= 1
+
+	switch {
+	case t.Error != 0:
+		return "error"
+	case t.Status == StatusStopped && complete:
+		return "stoppedUP"
+	case t.Status == StatusStopped:
+		return "stoppedDL"
+	case t.Status == StatusCheckWait, t.Status == StatusCheck:
+		if complete {
+			return "checkingUP"
+		}
+		return "checkingDL"
+	case t.Status == StatusDownloadWait:
+		return "queuedDL"
+	case t.Status == StatusDownload && t.MetadataPercentComplete < 1:
+		return "metaDL"
+	case t.Status == StatusDownload && t.PeersSendingToUs == 0:
+		return "stalledDL"
+	case t.Status == StatusDownload:
+		return "downloading"
+	case t.Status == StatusSeedWait:
+		return "queuedUP"
+	case t.Status == StatusSeed && t.PeersGettingFromUs == 0:
+		return "stalledUP"
+	case t.Status == StatusSeed:
+		return "uploading"
+	}
+	return "unknown"
+}
+
+// Tracker returns the announce URL of the first tracker that answered, or of the first one
+func (t Torrent) Tracker() string {
+	for _, stats := range t.TrackerStats {
+		if stats.LastAnnounceSucceeded {
+			return stats.Announce
+		}
+	}
+	if len(t.TrackerStats) > 0 {
+		return t.TrackerStats[0].Announce
+	}
+	return ""
+}
+
+// Swarm returns the seeders and leechers reported by the best answering tracker
+func (t Torrent) Swarm() (seeders, leechers int) {
+	for _, stats := range t.TrackerStats {
+		seeders = max(seeders, stats.SeederCount)
+		leechers = max(leechers, stats.LeecherCount)
+	}
+	return seeders, leechers
+}
+
+// TorrentGet returns the torrents with the given hashes, or every torrent when none is given
+func (c *Client) TorrentGet(ctx context.Context, hashes ...string) ([]Torrent, error) {
+	args := map[string]any{"fields": TorrentFields}
+	if len(hashes) > 0 {
+		args["ids"] = hashes
+	}
+
+	var result struct {
+		Torrents []Torrent `json:"torrents"`
+	}
+	if err := c.Call(ctx, "torrent-get", args, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Torrents, nil
+}
+
+// RecentlyActive returns the torrents changed since the previous call and the ids of the removed ones
+func (c *Client) RecentlyActive(ctx context.Context) ([]Torrent, []int, error) {
+	var result struct {
+		Torrents []Torrent `json:"torrents"`
+		Removed  []int     `json:"removed"`
+	}
+	if err := c.Call(ctx, "torrent-get", map[string]any{
+		"ids":    "recently-active",
+		"fields": TorrentFields,
+	}, &result); err != nil {
+		return nil, nil, err
+	}
+
+	return result.Torrents, result.Removed, nil
+}
+
+// AddedTorrent identifies a torrent answered by torrent-add
+type AddedTorrent struct {
+	ID         int    `json:"id"`
+	HashString string `json:"hashString"`
+	Name       string `json:"name"`
+}
+
+// AddArgs are the torrent-add arguments, adding either the magnet link or
+// torrent URL of Filename or the .torrent file of MetaInfo. The files are
+// given by index.
+type AddArgs struct {
+	Filename      string
+	MetaInfo      []byte
+	DownloadDir   string
+	Labels        []string
+	Paused        bool
+	FilesUnwanted []int
+	PriorityHigh  []int
+}
+
+// TorrentAdd adds a torrent, an already known torrent is returned as is
+func (c *Client) TorrentAdd(ctx context.Context, add AddArgs) (*AddedTorrent, error) {
+	args := map[string]any{}
+	if len(add.MetaInfo) > 0 {
+		args["metainfo"] = base64.StdEncoding.EncodeToString(add.MetaInfo)
+	} else {
+		args["filename"] = add.Filename
+	}
+	if add.DownloadDir != "" {
+		args["download-dir"] = add.DownloadDir
+	}
+	if len(add.Labels) > 0 {
+		args["labels"] = add.Labels
+	}
+	if add.Paused {
+		args["paused"] = true
+	}
+	if len(add.FilesUnwanted) > 0 {
+		args["files-unwanted"] = add.FilesUnwanted
+	}
+	if len(add.PriorityHigh) > 0 {
+		args["priority-high"] = add.PriorityHigh
+	}
+
+	var result struct {
+		Added     *AddedTorrent `json:"torrent-added"`
+		Duplicate *AddedTorrent `json:"torrent-duplicate"`
+	}
+	if err := c.Call(ctx, "torrent-add", args, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Added != nil {
+		return result.Added, nil
+	}
+	return result.Duplicate, nil
+}
+
+// TorrentAction runs an action without arguments such as torrent-start, torrent-stop,
+// torrent-start-now, torrent-verify or torrent-reannounce
+func (c *Client) TorrentAction(ctx context.Context, method string, hashes ...string) error {
+	return c.Call(ctx, method, map[string]any{"ids": hashes}, nil)
+}
+
+// TorrentRemove removes torrents, and their data when deleteData is set
+func (c *Client) TorrentRemove(ctx context.Context, deleteData bool, hashes ...string) error {
+	return c.Call(ctx, "torrent-remove", map[string]any{
+		"ids":               hashes,
+		"delete-local-data": deleteData,
+	}, nil)
+}
+
+// TorrentSet changes the given torrent-set fields of a torrent
+func (c *Client) TorrentSet(ctx context.Context, hash string, fields map[string]any) error {
+	args := map[string]any{"ids": []string{hash}}
+	for key, value := range fields {
+		args[key] = value
+	}
+	return c.Call(ctx, "torrent-set", args, nil)
+}
+
+// TorrentSetLocation moves the data of a torrent
+func (c *Client) TorrentSetLocation(ctx context.Context, hash, location string) error {
+	return c.Call(ctx, "torrent-set-location", map[string]any{
+		"ids":      []string{hash},
+		"location": location,
+		"move":     true,
+	}, nil)
+}
+
+// TorrentRenamePath renames a file or folder of a torrent, the torrent itself when path is its name
+func (c *Client) TorrentRenamePath(ctx context.Context, hash, path, name string) error {
+	return c.Call(ctx, "torrent-rename-path", map[string]any{
+		"ids":  []string{hash},
+		"path": path,
+		"name": name,
+	}, nil)
+}
+
+// File is a file of a torrent
+type File struct {
+	Name           string `json:"name"`
+	Length         int    `json:"length"`
+	BytesCompleted int    `json:"bytesCompleted"`
+}
+
+// FileStats holds the download settings of a file
+type FileStats struct {
+	Wanted   bool `json:"wanted"`
+	Priority int  `json:"priority"`
+}
+
+// TorrentFiles returns the files of a torrent and their settings, in the same order
+func (c *Client) TorrentFiles(ctx context.Context, hash string) ([]File, []FileStats, error) {
+	var result struct {
+		Torrents []struct {
+			Files     []File      `json:"files"`
+			FileStats []FileStats `json:"fileStats"`
+		} `json:"torrents"`
+	}
+	if err := c.Call(ctx, "torrent-get", map[string]any{
+		"ids":    []string{hash},
+		"fields": []string{"files", "fileStats"},
+	}, &result); err != nil {
+		return nil, nil, err
+	}
+
+	if len(result.Torrents) == 0 {
+		return nil, nil, nil
+	}
+	return result.Torrents[0].Files, result.Torrents[0].FileStats, nil
+}
+
+// SpeedUnit is the size of the kB of the speed limits
+const SpeedUnit = 1000
+
+// SpeedLimit converts a limit in B/s to kB/s, 0 when there is none. It rounds
+// up, a limit below 1 kB/s becoming 0 would stop the transfers instead.
+func SpeedLimit(limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	return (limit + SpeedUnit - 1) / SpeedUnit
+}
+
+// Session holds the session-get fields used by the agent. Speed limits are in kB/s.
+type Session struct {
+	Version               string `json:"version"`
+	RPCVersion            int    `json:"rpc-version"`
+	DownloadDir           string `json:"download-dir"`
+	SpeedLimitDown        int    `json:"speed-limit-down"`
+	SpeedLimitDownEnabled bool   `json:"speed-limit-down-enabled"`
+	SpeedLimitUp          int    `json:"speed-limit-up"`
+	SpeedLimitUpEnabled   bool   `json:"speed-limit-up-enabled"`
+}
+
+// SessionGet returns the session settings
+func (c *Client) SessionGet(ctx context.Context) (*Session, error) {
+	var session Session
+	if err := c.Call(ctx, "session-get", nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SessionSet changes the given session-set fields
+func (c *Client) SessionSet(ctx context.Context, fields map[string]any) error {
+	return c.Call(ctx, "session-set", fields, nil)
+}
+
+// Stats holds the transfer totals of session-stats
+type Stats struct {
+	DownloadedBytes int `json:"downloadedBytes"`
+	UploadedBytes   int `json:"uploadedBytes"`
+}
+
+// SessionStats returns the all-time transfer totals
+func (c *Client) SessionStats(ctx context.Context) (*Stats, error) {
+	var result struct {
+		Cumulative Stats `json:"cumulative-stats"`
+	}
+	if err := c.Call(ctx, "session-stats", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result.Cumulative, nil
+}
+
+// FreeSpace returns the free space, in bytes, of the disk holding path
+func (c *Client) FreeSpace(ctx context.Context, path string) (int, error) {
+	var result struct {
+		SizeBytes int `json:"size-bytes"`
+	}
+	if err := c.Call(ctx, "free-space", map[string]any{"path": path}, &result); err != nil {
+		return 0, err
+	}
+	return result.SizeBytes, nil
+}
@@ -11,6 +11,7 @@ type TaskService interface {
 	ListTasks(context.Context) ([]*entities.Task, error)
 	GetTask(context.Context, string) (*entities.Task, error)
 	CreateTask(context.Context, schemas.TaskCreateSchema) (*entities.Task, error)
+	PreviewTask(context.Context, schemas.TaskCreateSchema) (*entities.TaskPreview, error)
 	DeleteTask(context.Context, string, bool) error
 	StopTask(context.Context, string) error
 	StartTask(context.Context, string) error
@@ -24,6 +25,8 @@ type TaskService interface {
 	SetTaskDownloadLimit(context.Context, string, schemas.TaskSetDownloadLimitSchema) error
 	SetTaskUploadLimit(context.Context, string, schemas.TaskSetUploadLimitSchema) error
 	ListTaskFiles(context.Context, string) ([]*entities.TaskFile, error)
+	SetTaskCategory(context.Context, string, schemas.TaskSetCategorySchema) error
+	SetTaskTags(context.Context, string, schemas.TaskSetTagsSchema) error
 }
 
 type InstanceService interface {
@@ -10,14 +10,83 @@ func ToAgentResponse(e *entities.Agent) *models.AgentResponse {
 		return &models.AgentResponse{}
 	}
 
-	return &models.AgentResponse{
-		UUID:     e.UUID.String(),
-		Name:     e.Name,
-		Address:  e.Address,
-		Status:   e.Status,
-		Error:    e.Error,
-		Icon:     e.Icon,
-		Color:    e.Color,
+	capabilities := entities.CapabilitiesOf(e.Type)
+
+	response := &models.AgentResponse{
+		UUID:    e.UUID.String(),
+		Name:    e.Name,
+		Type:    e.Type,
+		Address: e.Address,
+		Status:  e.Status,
+		Error:   e.Error,
+		Icon:    e.Icon,
+		Color:   e.Color,
+		Capabilities: models.ClientCapabilitiesResponse{
+			Categories:         capabilities.Categories,
+			Tags:               capabilities.Tags,
+			SuperSeeding:       capabilities.SuperSeeding,
+			ForceStart:         capabilities.ForceStart,
+			Rename:             capabilities.Rename,
+			ShareLimits:        capabilities.ShareLimits,
+			ContentLayout:      capabilities.ContentLayout,
+			SkipChecking:       capabilities.SkipChecking,
+			SequentialDownload: capabilities.SequentialDownload,
+		},
 		Instance: ToInstanceResponse(e.Instance),
+		Health:   ToAgentHealthResponse(e.Health),
+
+		CertificateFingerprint: e.CertificateFingerprint,
+		Tunnel:                 e.Tunnel,
+		Client:                 e.Client,
+	}
+
+	if e.Parent != nil {
+		response.ParentUUID = e.Parent.UUID.String()
+	}
+
+	return response
+}
+
+func ToAgentHealthResponse(e *entities.AgentHealth) *models.AgentHealthResponse {
+	if e == nil {
+		return nil
+	}
+
+	return &models.AgentHealthResponse{
+		LastSeenAt:          e.LastSeenAt,
+		LastCheckAt:         e.LastCheckAt,
+		LatencyMs:           e.Latency.Milliseconds(),
+		ConsecutiveFailures: e.ConsecutiveFailures,
+		LastError:           e.LastError,
+	}
+}
+
+func ToAgentHealthEventResponse(e *entities.AgentHealthEvent) models.AgentHealthEventResponse {
+	return models.AgentHealthEventResponse{
+		PreviousStatus: e.PreviousStatus,
+		Status:         e.Status,
+		LatencyMs:      e.Latency.Milliseconds(),
+		Error:          e.Error,
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
+func ToAgentClientResponse(e *entities.AgentClient) models.AgentClientResponse {
+	return models.AgentClientResponse{
+		Name:    e.Name,
+		Type:    e.Type,
+		Default: e.Default,
+		Status:  e.Status,
+		Error:   e.Error,
+	}
+}
+
+func ToAgentClient(m models.AgentClientResponse) *entities.AgentClient {
+	return &entities.AgentClient{
+		Name:    m.Name,
+		Type:    m.Type,
+		Default: m.Default,
+		Status:  m.Status,
+		Error:   m.Error,
 	}
 }
@@ -0,0 +1,19 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+)
+
+func ToAPITokenResponse(e *entities.APIToken) models.APITokenResponse {
+	return models.APITokenResponse{
+		ID:         e.ID.String(),
+		Name:       e.Name,
+		Prefix:     e.Prefix,
+		Token:      e.Token,
+		Scopes:     e.Scopes,
+		ExpiresAt:  e.ExpiresAt,
+		LastUsedAt: e.LastUsedAt,
+		CreatedAt:  e.CreatedAt,
+	}
+}
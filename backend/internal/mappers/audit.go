@@ -0,0 +1,28 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+// ToAuditEventResponse converts an audit event entity to its API response
+func ToAuditEventResponse(event *entities.AuditEvent) models.AuditEventResponse {
+	resp := models.AuditEventResponse{
+		ID:         event.ID.String(),
+		Action:     event.Action,
+		ActorEmail: event.ActorEmail,
+		IPAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Detail:     event.Detail,
+		CreatedAt:  event.CreatedAt,
+	}
+
+	if event.ActorUUID != uuid.Nil {
+		resp.ActorUUID = event.ActorUUID.String()
+	}
+
+	return resp
+}
@@ -0,0 +1,22 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+)
+
+func ToEnrollmentTokenResponse(e *entities.EnrollmentToken) models.AgentEnrollmentTokenResponse {
+	response := models.AgentEnrollmentTokenResponse{
+		ID:        e.ID.String(),
+		Name:      e.Name,
+		Token:     e.Token,
+		ExpiresAt: e.ExpiresAt,
+		UsedAt:    e.UsedAt,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.AgentUUID != nil {
+		response.AgentUUID = e.AgentUUID.String()
+	}
+
+	return response
+}
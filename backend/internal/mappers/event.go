@@ -0,0 +1,60 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+func ToEventResponse(e *entities.Event) models.EventResponse {
+	resp := models.EventResponse{
+		TaskID: e.TaskID,
+	}
+
+	if e.AgentUUID != uuid.Nil {
+		resp.Agent = e.AgentUUID.String()
+	}
+
+	if e.Task != nil {
+		task := ToTaskResponse(e.Task)
+		resp.Task = &task
+	}
+
+	if e.Tasks != nil {
+		resp.Tasks = make([]models.TaskResponseModel, len(e.Tasks))
+		for i, item := range e.Tasks {
+			resp.Tasks[i] = ToTaskResponse(item)
+		}
+	}
+
+	if e.Instance != nil {
+		instance := ToInstanceResponse(e.Instance)
+		resp.Instance = &instance
+	}
+
+	return resp
+}
+
+func ToEvent(eventType string, body models.EventResponse) *entities.Event {
+	e := &entities.Event{
+		Type:   eventType,
+		TaskID: body.TaskID,
+	}
+
+	if body.Task != nil {
+		e.Task = ToTask(*body.Task)
+	}
+
+	if body.Tasks != nil {
+		e.Tasks = make([]*entities.Task, len(body.Tasks))
+		for i, item := range body.Tasks {
+			e.Tasks[i] = ToTask(item)
+		}
+	}
+
+	if body.Instance != nil {
+		e.Instance = ToInstance(*body.Instance)
+	}
+
+	return e
+}
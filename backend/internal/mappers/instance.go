@@ -15,6 +15,7 @@ func ToInstanceResponse(e *entities.Instance) models.InstanceResponse {
 			FreeSpaceOnDisk: e.Server.FreeSpaceOnDisk,
 		},
 		Application: models.InstanceApplicationResponse{
+			Client:     e.Application.Client,
 			Version:    e.Application.Version,
 			APIVersion: e.Application.APIVersion,
 		},
@@ -49,6 +50,7 @@ func ToInstance(body models.InstanceResponse) *entities.Instance {
 			FreeSpaceOnDisk: body.Server.FreeSpaceOnDisk,
 		},
 		Application: entities.InstanceApplication{
+			Client:     body.Application.Client,
 			Version:    body.Application.Version,
 			APIVersion: body.Application.APIVersion,
 		},
@@ -0,0 +1,26 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+)
+
+func ToRateLimitPolicyResponse(policy ratelimit.Policy, stats map[string]interface{}, blocked []*entities.RateLimitEntry) models.RateLimitPolicyResponse {
+	response := models.RateLimitPolicyResponse{
+		Name:    policy.Name,
+		Mode:    policy.Mode,
+		Stats:   stats,
+		Blocked: make([]models.RateLimitBlockedResponse, len(blocked)),
+	}
+
+	for i, entry := range blocked {
+		response.Blocked[i] = models.RateLimitBlockedResponse{
+			Identifier:   entry.Key,
+			Attempts:     entry.Count,
+			BlockedUntil: entry.BlockedUntil,
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,69 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+func ToRuleResponse(e *entities.Rule) models.RuleResponse {
+	conditions := make([]models.RuleCondition, len(e.Conditions))
+	for i, item := range e.Conditions {
+		conditions[i] = models.RuleCondition{
+			Field:    item.Field,
+			Operator: item.Operator,
+			Value:    item.Value,
+			Values:   item.Values,
+		}
+	}
+
+	actions := make([]models.RuleAction, len(e.Actions))
+	for i, item := range e.Actions {
+		actions[i] = models.RuleAction{
+			Type:             item.Type,
+			DeleteFiles:      item.DeleteFiles,
+			Location:         item.Location,
+			Tags:             item.Tags,
+			Category:         item.Category,
+			RatioLimit:       item.RatioLimit,
+			SeedingTimeLimit: item.SeedingTimeLimit,
+			Limit:            item.Limit,
+		}
+	}
+
+	return models.RuleResponse{
+		UUID:       e.UUID.String(),
+		Name:       e.Name,
+		Enabled:    e.Enabled,
+		DryRun:     e.DryRun,
+		Interval:   formatStep(e.Interval),
+		Conditions: conditions,
+		Actions:    actions,
+		LastRunAt:  e.LastRunAt,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+func ToRuleExecutionResponse(e *entities.RuleExecution) models.RuleExecutionResponse {
+	resp := models.RuleExecutionResponse{
+		Rule:      e.RuleUUID.String(),
+		RuleName:  e.RuleName,
+		Agent:     e.AgentUUID.String(),
+		AgentName: e.AgentName,
+		TaskID:    e.TaskHash,
+		TaskName:  e.TaskName,
+		Action:    e.Action,
+		Detail:    e.Detail,
+		DryRun:    e.DryRun,
+		Error:     e.Error,
+		CreatedAt: e.CreatedAt,
+	}
+
+	// previews are not stored and have no ID
+	if e.ID != uuid.Nil {
+		resp.ID = e.ID.String()
+	}
+
+	return resp
+}
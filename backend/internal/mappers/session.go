@@ -0,0 +1,21 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+// ToSessionResponse converts a session entity to its API response, marking the
+// session of the request
+func ToSessionResponse(session *entities.Session, currentID uuid.UUID) models.SessionResponse {
+	return models.SessionResponse{
+		ID:         session.ID.String(),
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		Current:    session.ID == currentID,
+		LastSeenAt: session.LastSeenAt,
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
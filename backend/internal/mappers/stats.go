@@ -0,0 +1,46 @@
+package mappers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+)
+
+func ToTransferSeriesResponse(e *entities.TransferSeries) models.TransferSeriesResponse {
+	points := make([]models.TransferPointResponse, len(e.Points))
+	for i, item := range e.Points {
+		points[i] = models.TransferPointResponse{
+			Timestamp:  item.Timestamp,
+			Downloaded: item.Downloaded,
+			Uploaded:   item.Uploaded,
+			Ratio:      item.Ratio,
+		}
+	}
+
+	return models.TransferSeriesResponse{
+		Category:     e.Category,
+		From:         e.From,
+		To:           e.To,
+		Step:         formatStep(e.Step),
+		Downloaded:   e.Downloaded,
+		Uploaded:     e.Uploaded,
+		Ratio:        e.Ratio,
+		CurrentRatio: e.CurrentRatio,
+		Points:       points,
+	}
+}
+
+// formatStep prints whole days as "Nd" and other steps as a Go duration without zero units
+func formatStep(step time.Duration) string {
+	day := 24 * time.Hour
+	if step >= day && step%day == 0 {
+		return strconv.Itoa(int(step/day)) + "d"
+	}
+
+	value := step.String()
+	value = strings.TrimSuffix(value, "0s")
+	return strings.TrimSuffix(value, "0m")
+}
@@ -37,8 +37,10 @@ func ToTask(e models.TaskResponseModel) *entities.Task {
 			Seeders:       e.Pairs.Seeders,
 			Leechers:      e.Pairs.Leechers,
 		},
-		NumSeeds: e.Pairs.Seeders,
-		Tags:     e.Tags,
+		NumSeeds:    e.Pairs.Seeders,
+		Tags:        e.Tags,
+		SeedingTime: e.SeedingTime,
+		Tracker:     e.Tracker,
 		Network: entities.TaskNetwork{
 			Download: entities.TaskDownload{
 				Speed:  e.Network.Download.Speed,
@@ -84,7 +86,9 @@ func ToTaskResponse(e *entities.Task) models.TaskResponseModel {
 			Seeders:       e.Pairs.Seeders,
 			Leechers:      e.Pairs.Leechers,
 		},
-		Tags: e.Tags,
+		Tags:        e.Tags,
+		SeedingTime: e.SeedingTime,
+		Tracker:     e.Tracker,
 		Network: models.TaskNetworkResponseModel{
 			Download: models.TaskDownloadResponseModel{
 				Speed:  e.Network.Download.Speed,
@@ -122,3 +126,57 @@ func ToTaskFilesResponse(files []*entities.TaskFile) []models.TaskFileResponse {
 	}
 	return response
 }
+
+func ToTaskFiles(files []models.TaskFileResponse) []*entities.TaskFile {
+	result := make([]*entities.TaskFile, len(files))
+	for i, file := range files {
+		result[i] = &entities.TaskFile{
+			Name:         file.Name,
+			Size:         file.Size,
+			Progress:     file.Progress,
+			Priority:     file.Priority,
+			IsSeed:       file.IsSeed,
+			PieceRange:   file.PieceRange,
+			Availability: file.Availability,
+		}
+	}
+
+	return result
+}
+
+func ToTaskPreviewResponse(e *entities.TaskPreview) models.TaskPreviewResponse {
+	response := models.TaskPreviewResponse{
+		Name:       e.Name,
+		Size:       e.Size,
+		InfoHashV1: e.InfoHashV1,
+		InfoHashV2: e.InfoHashV2,
+		Files:      make([]models.TaskPreviewFileResponse, len(e.Files)),
+	}
+	for i, file := range e.Files {
+		response.Files[i] = models.TaskPreviewFileResponse{Path: file.Path, Size: file.Size}
+	}
+	if e.Existing != nil {
+		existing := ToTaskResponse(e.Existing)
+		response.Existing = &existing
+	}
+
+	return response
+}
+
+func ToTaskPreview(r models.TaskPreviewResponse) *entities.TaskPreview {
+	preview := &entities.TaskPreview{
+		Name:       r.Name,
+		Size:       r.Size,
+		InfoHashV1: r.InfoHashV1,
+		InfoHashV2: r.InfoHashV2,
+		Files:      make([]entities.TaskPreviewFile, len(r.Files)),
+	}
+	for i, file := range r.Files {
+		preview.Files[i] = entities.TaskPreviewFile{Path: file.Path, Size: file.Size}
+	}
+	if r.Existing != nil {
+		preview.Existing = ToTask(*r.Existing)
+	}
+
+	return preview
+}
@@ -0,0 +1,46 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/pkg/torrent"
+)
+
+// ToTorrentInspectResponse describes a metainfo, the trackers being listed
+// tier by tier
+func ToTorrentInspectResponse(m *torrent.MetaInfo) models.TorrentInspectResponse {
+	response := models.TorrentInspectResponse{
+		Name:        m.Name,
+		Version:     m.Version(),
+		InfoHashV1:  m.InfoHash.V1,
+		InfoHashV2:  m.InfoHash.V2,
+		Size:        m.Length,
+		PieceLength: m.PieceLength,
+		PieceCount:  m.PieceCount,
+		Private:     m.Private,
+		Trackers:    [][]string{},
+		WebSeeds:    []string{},
+		Comment:     m.Comment,
+		CreatedBy:   m.CreatedBy,
+		Files:       make([]models.TorrentFileResponse, len(m.Files)),
+		MagnetURI:   m.MagnetURI(),
+	}
+
+	switch {
+	case len(m.AnnounceList) > 0:
+		response.Trackers = m.AnnounceList
+	case m.Announce != "":
+		response.Trackers = [][]string{{m.Announce}}
+	}
+	if len(m.WebSeeds) > 0 {
+		response.WebSeeds = m.WebSeeds
+	}
+	if !m.CreationDate.IsZero() {
+		date := m.CreationDate
+		response.CreationDate = &date
+	}
+	for i, file := range m.Files {
+		response.Files[i] = models.TorrentFileResponse{Path: file.Path, Size: file.Length}
+	}
+
+	return response
+}
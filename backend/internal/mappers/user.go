@@ -0,0 +1,36 @@
+package mappers
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+func ToUserResponse(e *entities.User) models.UserResponse {
+	return models.UserResponse{
+		UUID:      e.UUID.String(),
+		Email:     e.Email,
+		Role:      e.Role,
+		Disabled:  e.Disabled,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func ToUserInviteResponse(e *entities.UserInvite) models.UserInviteResponse {
+	return models.UserInviteResponse{
+		ID:        e.ID.String(),
+		Email:     e.Email,
+		Role:      e.Role,
+		Token:     e.Token,
+		ExpiresAt: e.ExpiresAt,
+	}
+}
+
+func ToUserAgentsResponse(agents []uuid.UUID) models.UserAgentsResponse {
+	resp := models.UserAgentsResponse{Agents: make([]string, len(agents))}
+	for i, item := range agents {
+		resp.Agents[i] = item.String()
+	}
+
+	return resp
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// NewAuditEvent starts the audit event of an action of the request, made by
+// the current user when authenticated
+func NewAuditEvent(c *gin.Context, action, targetType, targetID string) *entities.AuditEvent {
+	event := &entities.AuditEvent{
+		Action:     action,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	if currentUser, ok := CurrentUser(c); ok {
+		event.ActorUUID = currentUser.UUID
+		event.ActorEmail = currentUser.Email
+	}
+
+	return event
+}
+
+// RecordFailure records a failed authentication attempt of identifier in
+// limiter and audits the lockout it leads to. Locked out identifiers being
+// refused before their attempts are checked, a lockout is audited once.
+func RecordFailure(c *gin.Context, auditService *audit.Service, limiter *ratelimit.Service, identifier string) {
+	limiter.RecordAttempt(identifier)
+
+	if blocked, blockedFor := limiter.IsBlocked(identifier); blocked {
+		event := NewAuditEvent(c, entities.AuditLockout, "", "")
+		event.Detail = fmt.Sprintf("%s locked out by the %s policy for %s", identifier, limiter.Policy().Name, blockedFor.Round(time.Second))
+		auditService.Record(c.Request.Context(), event)
+	}
+}
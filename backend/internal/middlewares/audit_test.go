@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNewAuditEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("DELETE", "/v1/agent/1", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+	c.Request.Header.Set("User-Agent", "curl/8.0")
+
+	event := NewAuditEvent(c, entities.AuditAgentDelete, entities.AuditTargetAgent, "1")
+	if event.ActorUUID != uuid.Nil || event.IPAddress != "10.0.0.1" || event.UserAgent != "curl/8.0" {
+		t.Errorf("Expected an anonymous event from the client, got %+v", event)
+	}
+
+	currentUser := &entities.User{UUID: uuid.New(), Email: "admin@example.com"}
+	c.Set(UserContextKey, currentUser)
+
+	event = NewAuditEvent(c, entities.AuditAgentDelete, entities.AuditTargetAgent, "1")
+	if event.ActorUUID != currentUser.UUID || event.ActorEmail != currentUser.Email {
+		t.Errorf("Expected the event to be made by the current user, got %+v", event)
+	}
+}
+
+func TestRecordFailure_AuditsLockoutOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AuditEvent{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	auditService := audit.NewService(&database.Database{DB: db})
+	limiter := ratelimit.NewService(3, time.Minute, time.Hour)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/auth/me", nil)
+
+	for i := 0; i < 3; i++ {
+		RecordFailure(c, auditService, limiter, "192.0.2.1")
+	}
+
+	events, total, err := auditService.ListEvents(c.Request.Context(), schemas.AuditEventQuery{Action: entities.AuditLockout})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 1 {
+		t.Fatalf("Expected 1 lockout event, got %d", total)
+	}
+	if events[0].IPAddress != "192.0.2.1" {
+		t.Errorf("Expected the lockout of 192.0.2.1, got %+v", events[0])
+	}
+}
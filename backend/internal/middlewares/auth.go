@@ -1,33 +1,80 @@
 package middlewares
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/pkg/env"
 	"github.com/gin-gonic/gin"
 )
 
-// RequireBearerToken validates the Authorization Bearer token against the value of the provided envKey.
-// Returns 403 when the token is missing or does not match the expected value.
+// maxAgentRequestSize bounds the body of a signed request, read before its
+// signature is checked. The largest is a task create request, whose .torrent
+// file is base64 encoded in JSON, with room for the other fields or for the
+// overhead of a multipart form.
+const maxAgentRequestSize = (schemas.MaxTorrentSize+2)/3*4 + 1<<20
+
+// agentVerifier remembers the nonces of the signed requests, shared by every route
+var agentVerifier = sync.OnceValue(func() *agentauth.Verifier {
+	return agentauth.NewVerifier(env.Get(constants.AgentSignatureMaxSkewEnv).Default("5m").ValueDuration())
+})
+
+// RequireAgentBearerToken authenticates the requests of the manager against
+// AGENT_SECRET. Signed requests are checked with their signature, see
+// agentauth.Verifier. Unsigned ones, authenticated by the Authorization Bearer
+// token alone, are refused unless AGENT_REQUIRE_SIGNED_REQUESTS is false.
+// Returns 403 when the request is not authenticated.
 func RequireAgentBearerToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		expected := strings.TrimSpace(env.Get(constants.AgentSecretEnv).Value())
-		authHeader := c.GetHeader("Authorization")
-
 		if expected == "" {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
 
-		if !strings.HasPrefix(authHeader, "Bearer ") {
+		if !agentauth.Signed(c.Request) {
+			if env.Get(constants.AgentRequireSignedEnv).Default("true").ValueBool() || !validBearer(c, expected) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// Stale and replayed requests are refused before their body is read
+		if err := agentVerifier().CheckHeaders(c.Request); err != nil {
+			log.Printf("agent: refused request from %s: %v", c.ClientIP(), err)
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
 
-		provided := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
-		if provided != expected {
+		// The body is signed, it is read then restored for the handlers
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxAgentRequestSize)); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if err := agentVerifier().Verify(c.Request, expected, body); err != nil {
+			log.Printf("agent: refused request from %s: %v", c.ClientIP(), err)
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
@@ -35,3 +82,14 @@ func RequireAgentBearerToken() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// validBearer tells whether the Authorization Bearer token is the agent secret
+func validBearer(c *gin.Context, expected string) bool {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return false
+	}
+
+	provided := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agentclient"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAgentBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AGENT_SECRET", "agent-secret")
+
+	router := gin.New()
+	router.POST("/v1/tasks/", RequireAgentBearerToken(), func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	})
+
+	send := func(token string, sign bool, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if sign {
+			if err := agentauth.Sign(req, token, []byte(body), time.Now()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Unsigned requests are refused unless allowed
+	if recorder := send("agent-secret", false, "plain"); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected unsigned requests to be refused, got %d", recorder.Code)
+	}
+
+	// The handlers read the signed body
+	recorder := send("agent-secret", true, "signed")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "signed" {
+		t.Errorf("Expected the signed body, got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	// A forged signature is refused even with the right token
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks/", strings.NewReader("body"))
+	req.Header.Set("Authorization", "Bearer agent-secret")
+	_ = agentauth.Sign(req, "agent-secret", []byte("other body"), time.Now())
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+
+	// An oversized body is refused before its signature is checked
+	large := strings.Repeat("a", maxAgentRequestSize+1)
+	if recorder := send("agent-secret", true, large); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, recorder.Code)
+	}
+
+	// A stale request is refused without its body being read
+	req = httptest.NewRequest(http.MethodPost, "/v1/tasks/", &unreadBody{t: t})
+	_ = agentauth.Sign(req, "agent-secret", nil, time.Now().Add(-time.Hour))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+
+	t.Setenv("AGENT_REQUIRE_SIGNED_REQUESTS", "false")
+	if recorder := send("agent-secret", false, "plain"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if recorder := send("wrong", false, "plain"); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+}
+
+func TestRequireAgentBearerToken_CapturedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AGENT_SECRET", "agent-secret")
+
+	router := gin.New()
+	router.POST("/v1/tasks/", RequireAgentBearerToken(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// a request of the manager, as seen on the wire
+	var captured *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, body = r, []byte(`{"magnet_uri":"magnet:?xt=urn:btih:abc"}`)
+		router.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := agentclient.New(server.URL, "agent-secret", agentclient.DefaultConfig())
+	if err := client.Do(context.Background(), http.MethodPost, "/v1/tasks/", map[string]string{"magnet_uri": "magnet:?xt=urn:btih:abc"}, nil); err != nil {
+		t.Fatalf("Expected the request of the manager to be accepted, got %v", err)
+	}
+	if captured.Header.Get("Authorization") != "" {
+		t.Errorf("Expected the secret not to be sent, got '%s'", captured.Header.Get("Authorization"))
+	}
+
+	replay := func(mutate func(req *http.Request)) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/", bytes.NewReader(body))
+		req.Header = captured.Header.Clone()
+		mutate(req)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := replay(func(req *http.Request) {}); code != http.StatusForbidden {
+		t.Errorf("Expected the replayed request to be refused, got %d", code)
+	}
+	if code := replay(func(req *http.Request) {
+		req.Header.Set(agentauth.NonceHeader, strings.Repeat("0", 32))
+	}); code != http.StatusForbidden {
+		t.Errorf("Expected the request replayed with a new nonce to be refused, got %d", code)
+	}
+	if code := replay(func(req *http.Request) {
+		// signed again with the credential seen on the wire, if any
+		seen := strings.TrimPrefix(captured.Header.Get("Authorization"), "Bearer ")
+		_ = agentauth.Sign(req, seen, body, time.Now())
+	}); code != http.StatusForbidden {
+		t.Errorf("Expected the request signed again with a new nonce to be refused, got %d", code)
+	}
+}
+
+// unreadBody fails the test when it is read
+type unreadBody struct {
+	t *testing.T
+}
+
+func (b *unreadBody) Read(p []byte) (int, error) {
+	b.t.Error("Expected the body of a refused request not to be read")
+	return 0, io.EOF
+}
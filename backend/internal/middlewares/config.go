@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the settings of the manager behind a reverse proxy and of its
+// session cookie
+type Config struct {
+	// BasePath is the path prefix the manager is served under, e.g. "/gardarr",
+	// empty when served at the root
+	BasePath string
+	// TrustedProxies lists the addresses and CIDRs of the reverse proxies whose
+	// RealIPHeader is trusted. The client address is the connection one otherwise.
+	TrustedProxies []string
+	RealIPHeader   string
+
+	CookieName     string
+	CookieDomain   string
+	CookiePath     string // the base path when empty
+	CookieSecure   bool
+	CookieSameSite http.SameSite
+	CookieMaxAge   time.Duration // the longest session lifetime when zero
+
+	// CSRFEnabled requires the cookie-authenticated state-changing requests to
+	// repeat the CSRF cookie in the CSRF header (double-submit)
+	CSRFEnabled bool
+}
+
+// LoadConfigFromEnv loads the proxy and cookie configuration from environment variables
+func LoadConfigFromEnv() Config {
+	config := Config{
+		BasePath:       normalizeBasePath(env.Get(constants.AppBasePathEnv).Value()),
+		TrustedProxies: env.Get(constants.AppTrustedProxiesEnv).ValueList(),
+		RealIPHeader:   env.Get(constants.AppRealIPHeaderEnv).Default("X-Forwarded-For").Value(),
+		CookieName:     env.Get("AUTH_COOKIE_NAME").Default("session_token").Value(),
+		CookieDomain:   env.Get("AUTH_COOKIE_DOMAIN").Value(),
+		CookiePath:     env.Get("AUTH_COOKIE_PATH").Value(),
+		CookieSecure:   env.Get("AUTH_COOKIE_SECURE").Default("false").ValueBool(),
+		CookieSameSite: parseSameSite(env.Get("AUTH_COOKIE_SAMESITE").Default("lax").Value()),
+		CookieMaxAge:   env.Get("AUTH_COOKIE_MAX_AGE").Default("0s").ValueDuration(),
+		CSRFEnabled:    env.Get("AUTH_CSRF_ENABLED").Default("true").ValueBool(),
+	}
+
+	if config.CookiePath == "" {
+		config.CookiePath = config.BasePath + "/"
+	}
+
+	return config
+}
+
+// normalizeBasePath turns a base path into "/prefix" form, the root being empty
+func normalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// parseSameSite maps a SameSite setting to its cookie mode, Lax when unknown
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
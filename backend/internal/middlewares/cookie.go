@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+
+	csrfTokenLength = 32
+)
+
+// SessionToken returns the token of the session cookie, empty when missing
+func (a *Auth) SessionToken(c *gin.Context) string {
+	token, err := c.Cookie(a.config.CookieName)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// SetSessionCookie sets the HTTP-only session cookie along with a new CSRF
+// cookie. maxAge is their lifetime unless one is configured.
+func (a *Auth) SetSessionCookie(c *gin.Context, token string, maxAge time.Duration) {
+	if a.config.CookieMaxAge > 0 {
+		maxAge = a.config.CookieMaxAge
+	}
+
+	a.setCookie(c, a.config.CookieName, token, int(maxAge.Seconds()), true)
+	a.issueCSRFCookie(c, int(maxAge.Seconds()))
+}
+
+// ClearSessionCookie removes the session and CSRF cookies
+func (a *Auth) ClearSessionCookie(c *gin.Context) {
+	a.setCookie(c, a.config.CookieName, "", -1, true)
+	a.setCookie(c, CSRFCookieName, "", -1, false)
+}
+
+// BasePath returns the path prefix the manager is served under, empty at the root
+func (a *Auth) BasePath() string {
+	return a.config.BasePath
+}
+
+// SetFlowCookie sets a short-lived HTTP-only cookie binding a login flow to the
+// browser, e.g. the state of a single sign-on. A strict SameSite is relaxed,
+// the identity provider sending the browser back with a cross-site navigation.
+func (a *Auth) SetFlowCookie(c *gin.Context, name, value string, maxAge time.Duration) {
+	sameSite := a.config.CookieSameSite
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	c.SetSameSite(sameSite)
+	c.SetCookie(name, value, int(maxAge.Seconds()), a.config.CookiePath, a.config.CookieDomain, a.config.CookieSecure, true)
+}
+
+// FlowCookie returns the value of a login flow cookie and removes it, a flow
+// being completed once
+func (a *Auth) FlowCookie(c *gin.Context, name string) string {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+
+	a.setCookie(c, name, "", -1, true)
+	return value
+}
+
+// setCookie sets a cookie with the configured domain, path and security attributes
+func (a *Auth) setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	c.SetSameSite(a.config.CookieSameSite)
+	c.SetCookie(name, value, maxAge, a.config.CookiePath, a.config.CookieDomain, a.config.CookieSecure, httpOnly)
+}
+
+// issueCSRFCookie sets a new random CSRF token, readable by the frontend which
+// repeats it in the CSRF header
+func (a *Auth) issueCSRFCookie(c *gin.Context, maxAge int) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return
+	}
+	a.setCookie(c, CSRFCookieName, base64.RawURLEncoding.EncodeToString(token), maxAge, false)
+}
+
+// checkCSRF reports whether a cookie-authenticated request may proceed: safe
+// methods always can, the others must send the CSRF cookie value in the CSRF
+// header. Sessions missing the CSRF cookie, opened before it existed, get one
+// on their next safe request.
+func (a *Auth) checkCSRF(c *gin.Context) bool {
+	if !a.config.CSRFEnabled {
+		return true
+	}
+
+	cookie, err := c.Cookie(CSRFCookieName)
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if err != nil || cookie == "" {
+			a.issueCSRFCookie(c, int(a.config.CookieMaxAge.Seconds()))
+		}
+		return true
+	}
+
+	header := c.GetHeader(CSRFHeaderName)
+	return err == nil && cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
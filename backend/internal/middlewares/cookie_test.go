@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"/":         "",
+		"gardarr":   "/gardarr",
+		"/gardarr/": "/gardarr",
+		" /a/b/ ":   "/a/b",
+	}
+
+	for input, expected := range tests {
+		if got := normalizeBasePath(input); got != expected {
+			t.Errorf("Expected base path '%s' for '%s', got '%s'", expected, input, got)
+		}
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	tests := map[string]http.SameSite{
+		"strict":  http.SameSiteStrictMode,
+		"None":    http.SameSiteNoneMode,
+		"lax":     http.SameSiteLaxMode,
+		"unknown": http.SameSiteLaxMode,
+	}
+
+	for input, expected := range tests {
+		if got := parseSameSite(input); got != expected {
+			t.Errorf("Expected SameSite %d for '%s', got %d", expected, input, got)
+		}
+	}
+}
+
+// testAuth sets the cookies with the default settings, CSRF check included
+func testAuth() *Auth {
+	return &Auth{config: Config{
+		CookieName:     "session_token",
+		CookiePath:     "/",
+		CookieSameSite: http.SameSiteLaxMode,
+		CSRFEnabled:    true,
+	}}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := testAuth()
+
+	tests := []struct {
+		name     string
+		method   string
+		cookie   string
+		header   string
+		expected bool
+	}{
+		{"safe method without token", http.MethodGet, "", "", true},
+		{"matching token", http.MethodPost, "token", "token", true},
+		{"missing header", http.MethodPost, "token", "", false},
+		{"mismatching header", http.MethodDelete, "token", "other", false},
+		{"missing cookie", http.MethodPut, "", "token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(tt.method, "/v1/auth/logout", nil)
+			if tt.cookie != "" {
+				c.Request.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				c.Request.Header.Set(CSRFHeaderName, tt.header)
+			}
+
+			if got := auth.checkCSRF(c); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckCSRF_IssuesMissingCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+
+	testAuth().checkCSRF(c)
+
+	if cookie := recorder.Header().Get("Set-Cookie"); !strings.HasPrefix(cookie, CSRFCookieName+"=") || strings.Contains(cookie, "HttpOnly") {
+		t.Errorf("Expected a CSRF cookie readable by scripts, got '%s'", cookie)
+	}
+}
+
+func TestCheckCSRF_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := testAuth()
+	auth.config.CSRFEnabled = false
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+
+	if !auth.checkCSRF(c) {
+		t.Error("Expected the request to pass with the CSRF check disabled")
+	}
+}
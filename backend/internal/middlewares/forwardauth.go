@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/forwardauth"
+	"github.com/gardarr/gardarr/internal/services/session"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// authenticateForwarded authenticates a request by the user headers of a
+// trusted proxy. The user gets a session as with a login, reused by the next
+// requests while the proxy names the same user. It aborts the request and
+// returns false on failure.
+func (a *Auth) authenticateForwarded(c *gin.Context, forwardAuth *forwardauth.Service, sessionService *session.Service, auditService *audit.Service) (*entities.User, *entities.Session, bool) {
+	ctx := c.Request.Context()
+
+	user, err := forwardAuth.Authenticate(ctx, c.Request.Header)
+	if err != nil {
+		log.Printf("forwardauth: refused %s: %v", c.ClientIP(), err)
+		respErr := errors.ToResponseError(err)
+		c.AbortWithStatusJSON(respErr.StatusCode, respErr)
+		return nil, nil, false
+	}
+
+	if token := a.SessionToken(c); token != "" {
+		sessionUser, sessionEntity, err := sessionService.ValidateSession(ctx, token, c.ClientIP())
+		if err == nil && sessionUser.UUID == user.UUID {
+			if !a.checkCSRF(c) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+				return nil, nil, false
+			}
+			return user, sessionEntity, true
+		}
+
+		// The proxy now names another user
+		if err == nil {
+			_ = sessionService.DeleteSession(ctx, token)
+		}
+	}
+
+	// A state-changing request cannot carry the CSRF token of a session not
+	// opened yet: it is refused, the client first loading a page
+	if a.config.CSRFEnabled {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+			return nil, nil, false
+		}
+	}
+
+	sessionEntity, err := sessionService.CreateSession(ctx, user.UUID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return nil, nil, false
+	}
+	a.SetSessionCookie(c, sessionEntity.Token, sessionService.MaxAge())
+
+	event := NewAuditEvent(c, entities.AuditLogin, entities.AuditTargetUser, user.UUID.String())
+	event.ActorUUID = user.UUID
+	event.ActorEmail = user.Email
+	event.Detail = "forward authentication"
+	auditService.Record(ctx, event)
+
+	return user, sessionEntity, true
+}
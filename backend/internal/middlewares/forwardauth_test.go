@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/forwardauth"
+	"github.com/gardarr/gardarr/internal/services/session"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuthenticateForwarded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.User{}, &models.UserIdentity{}, &models.Session{}, &models.AuditEvent{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	forwardAuth := forwardauth.NewWithConfig(db, forwardauth.Config{
+		Enabled:        true,
+		TrustedProxies: []string{"192.0.2.1"},
+		UserHeader:     "Remote-User",
+		EmailHeader:    "Remote-Email",
+		DefaultRole:    entities.RoleViewer,
+		AutoProvision:  true,
+	})
+	sessionService := session.NewService(db)
+	auditService := audit.NewService(db)
+	auth := testAuth()
+
+	authenticate := func(method, user string, cookies []*http.Cookie) (*httptest.ResponseRecorder, *entities.User, *entities.Session, bool) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(method, "/v1/auth/me", nil)
+		c.Request.RemoteAddr = "192.0.2.1:4567"
+		c.Request.Header.Set("Remote-User", user)
+		c.Request.Header.Set("Remote-Email", user+"@example.com")
+		for _, cookie := range cookies {
+			c.Request.AddCookie(cookie)
+		}
+
+		u, s, ok := auth.authenticateForwarded(c, forwardAuth, sessionService, auditService)
+		return recorder, u, s, ok
+	}
+
+	// The first request opens a session
+	recorder, u, first, ok := authenticate(http.MethodGet, "alice", nil)
+	if !ok {
+		t.Fatalf("Expected the request to be authenticated, got status %d", recorder.Code)
+	}
+	if u.Email != "alice@example.com" {
+		t.Errorf("Expected alice@example.com, got %s", u.Email)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("Expected the session cookies to be set")
+	}
+
+	// The next ones reuse it
+	_, _, again, ok := authenticate(http.MethodGet, "alice", cookies)
+	if !ok || again.ID != first.ID {
+		t.Errorf("Expected session %s to be reused, got %+v", first.ID, again)
+	}
+
+	// A state-changing request cannot open a session, lacking its CSRF token
+	recorder, _, _, ok = authenticate(http.MethodPost, "alice", nil)
+	if ok || recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+
+	// Another user named by the proxy replaces the session
+	_, other, replaced, ok := authenticate(http.MethodGet, "bob", cookies)
+	if !ok || other.Email != "bob@example.com" || replaced.ID == first.ID {
+		t.Errorf("Expected a new session for bob, got %+v", replaced)
+	}
+
+	var count int64
+	gdb.Model(&models.Session{}).Where("user_uuid = ?", u.UUID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the session of alice to be removed, got %d", count)
+	}
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// Throttle limits the requests of each identifier returned by key with a token
+// bucket policy, answering 429 once its bucket is empty
+func Throttle(limiter *ratelimit.Service, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limiter.Policy().Enabled() {
+			c.Next()
+			return
+		}
+
+		decision := limiter.Allow(key(c))
+		SetRateLimitHeaders(c, limiter.Policy(), decision)
+
+		if !decision.Allowed {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":               "Too many requests",
+				"retry_after_seconds": seconds(decision.RetryAfter),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ClientKey identifies a request by its client address
+func ClientKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// UserKey identifies a request by its authenticated user, or else its client address
+func UserKey(c *gin.Context) string {
+	if currentUser, ok := CurrentUser(c); ok {
+		return "user:" + currentUser.UUID.String()
+	}
+	return c.ClientIP()
+}
+
+// SetRateLimitHeaders reports a rate limit decision in the RateLimit-* headers,
+// and in Retry-After when the request is refused
+func SetRateLimitHeaders(c *gin.Context, policy ratelimit.Policy, decision ratelimit.Decision) {
+	if !policy.Enabled() {
+		return
+	}
+
+	window := policy.Period
+	if policy.Mode == ratelimit.ModeLockout {
+		window = policy.Window
+	}
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", decision.Limit, seconds(window)))
+	c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+
+	if !decision.Allowed {
+		c.Header("Retry-After", strconv.Itoa(seconds(decision.RetryAfter)))
+	}
+}
+
+// AbortLockedOut answers 429 to a request from an identifier locked out by limiter
+func AbortLockedOut(c *gin.Context, limiter *ratelimit.Service, identifier, message string) {
+	decision := limiter.Status(identifier)
+	SetRateLimitHeaders(c, limiter.Policy(), decision)
+
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":               message,
+		"retry_after_seconds": seconds(decision.RetryAfter),
+	})
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
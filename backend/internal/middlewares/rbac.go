@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentUser returns the user authenticated by SessionMiddleware
+func CurrentUser(c *gin.Context) (*entities.User, bool) {
+	value, exists := c.Get(UserContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	currentUser, ok := value.(*entities.User)
+	return currentUser, ok && currentUser != nil
+}
+
+// RequireRole lets through the users having at least the given role. It must
+// run after SessionMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if !currentUser.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Authorize lets through the users having at least the given role. A request
+// authenticated with an API token also needs the token to hold the scope. It
+// must run after AuthMiddleware.
+func Authorize(role, scope string) gin.HandlerFunc {
+	requireRole := RequireRole(role)
+
+	return func(c *gin.Context) {
+		if apiToken, ok := CurrentAPIToken(c); ok && !apiToken.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API token lacks the %s scope", scope)})
+			return
+		}
+
+		requireRole(c)
+	}
+}
+
+// RequireAgentAccess lets through the users granted the agent identified by the
+// given route parameter, and every admin. It must run after SessionMiddleware.
+func RequireAgentAccess(db *database.Database, param string) gin.HandlerFunc {
+	userService := user.NewService(db)
+
+	return func(c *gin.Context) {
+		currentUser, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		allowed, err := userService.CanAccessAgent(c.Request.Context(), currentUser, c.Param(param))
+		if err != nil {
+			errors.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "No access to this agent"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,68 +1,86 @@
 package middlewares
 
 import (
-	"fmt"
 	"net/http"
 
+	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/forwardauth"
 	"github.com/gardarr/gardarr/internal/services/ratelimit"
 	"github.com/gardarr/gardarr/internal/services/session"
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	SessionCookieName = "session_token"
 	UserContextKey    = "user"
 	SessionContextKey = "session"
 )
 
-var (
-	// Global rate limiter instance
-	rateLimiter = ratelimit.NewDefaultService()
-)
+// Auth builds the authentication middlewares of the API routes and sets their
+// cookies, rate limited by the limiters of the manager
+type Auth struct {
+	db     *database.Database
+	config Config
+	limits *ratelimit.Limiters
+}
+
+// NewAuth creates the authentication middlewares with the cookie settings of
+// config, sharing limits
+func NewAuth(db *database.Database, config Config, limits *ratelimit.Limiters) *Auth {
+	return &Auth{db: db, config: config, limits: limits}
+}
 
-// SessionMiddleware validates the session token from cookies with rate limiting
-func SessionMiddleware(db *database.Database) gin.HandlerFunc {
-	sessionService := session.NewService(db)
+// SessionMiddleware validates the session token from cookies with rate limiting.
+// State-changing requests must also pass the CSRF check, see checkCSRF.
+// Behind a trusted authenticating proxy, the user headers it sends open the
+// session instead, see authenticateForwarded.
+func (a *Auth) SessionMiddleware() gin.HandlerFunc {
+	sessionService := session.NewService(a.db)
+	rateLimiter := a.limits.Session
+	auditService := audit.NewService(a.db)
+	forwardAuth := forwardauth.NewService(a.db)
 
 	return func(c *gin.Context) {
-		// Create identifier for rate limiting
+		// Rate limiting is keyed on the client address only, other request
+		// attributes being chosen by the client
 		ip := c.ClientIP()
-		userAgent := c.Request.UserAgent()
-		identifier := ratelimit.GetIdentifier(ip, userAgent)
+		identifier := ip
 
 		// Check if blocked
-		if blocked, remaining := rateLimiter.IsBlocked(identifier); blocked {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":               "Too many failed authentication attempts",
-				"retry_after_seconds": int(remaining.Seconds()),
-			})
-			c.Abort()
+		if blocked, _ := rateLimiter.IsBlocked(identifier); blocked {
+			AbortLockedOut(c, rateLimiter, identifier, "Too many failed authentication attempts")
+			return
+		}
+
+		if forwardAuth.Trusted(c.Request) {
+			user, sessionEntity, ok := a.authenticateForwarded(c, forwardAuth, sessionService, auditService)
+			if !ok {
+				return
+			}
+
+			c.Set(UserContextKey, user)
+			c.Set(SessionContextKey, sessionEntity)
+			c.Next()
 			return
 		}
 
 		// Get session token from cookie
-		token, err := c.Cookie(SessionCookieName)
-		if err != nil || token == "" {
+		token := a.SessionToken(c)
+		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 			c.Abort()
 			return
 		}
 
 		// Validate session
-		user, sessionEntity, err := sessionService.ValidateSession(c.Request.Context(), token)
+		user, sessionEntity, err := sessionService.ValidateSession(c.Request.Context(), token, ip)
 		if err != nil {
 			// Record failed attempt
-			rateLimiter.RecordAttempt(identifier)
+			RecordFailure(c, auditService, rateLimiter, identifier)
 
 			// Clear invalid cookie
-			c.SetCookie(SessionCookieName, "", -1, "/", "", false, true)
-
-			// Log suspicious activity
-			attemptCount := rateLimiter.GetAttemptCount(identifier)
-			if attemptCount > 3 {
-				fmt.Printf("[SECURITY] Multiple failed auth attempts from %s (count: %d)\n", ip, attemptCount)
-			}
+			a.ClearSessionCookie(c)
 
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
 			c.Abort()
@@ -72,6 +90,11 @@ func SessionMiddleware(db *database.Database) gin.HandlerFunc {
 		// Successful authentication - reset rate limit
 		rateLimiter.Reset(identifier)
 
+		if !a.checkCSRF(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+			return
+		}
+
 		// Store user and session in context
 		c.Set(UserContextKey, user)
 		c.Set(SessionContextKey, sessionEntity)
@@ -81,22 +104,28 @@ func SessionMiddleware(db *database.Database) gin.HandlerFunc {
 }
 
 // OptionalSessionMiddleware validates the session but doesn't abort if missing
-func OptionalSessionMiddleware(db *database.Database) gin.HandlerFunc {
-	sessionService := session.NewService(db)
+func (a *Auth) OptionalSessionMiddleware() gin.HandlerFunc {
+	sessionService := session.NewService(a.db)
 
 	return func(c *gin.Context) {
 		// Get session token from cookie
-		token, err := c.Cookie(SessionCookieName)
-		if err != nil || token == "" {
+		token := a.SessionToken(c)
+		if token == "" {
 			c.Next()
 			return
 		}
 
 		// Validate session
-		user, sessionEntity, err := sessionService.ValidateSession(c.Request.Context(), token)
+		user, sessionEntity, err := sessionService.ValidateSession(c.Request.Context(), token, c.ClientIP())
 		if err != nil {
 			// Clear invalid cookie
-			c.SetCookie(SessionCookieName, "", -1, "/", "", false, true)
+			a.ClearSessionCookie(c)
+			c.Next()
+			return
+		}
+
+		// A state-changing request failing the CSRF check stays anonymous
+		if !a.checkCSRF(c) {
 			c.Next()
 			return
 		}
@@ -108,3 +137,14 @@ func OptionalSessionMiddleware(db *database.Database) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// CurrentSession returns the session authenticated by SessionMiddleware
+func CurrentSession(c *gin.Context) (*entities.Session, bool) {
+	value, exists := c.Get(SessionContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	currentSession, ok := value.(*entities.Session)
+	return currentSession, ok && currentSession != nil
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/apitoken"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gin-gonic/gin"
+)
+
+const APITokenContextKey = "api_token"
+
+// AuthMiddleware authenticates the request with an API token sent as
+// "Authorization: Bearer <token>", or else with the session cookie. Routes
+// using it must check the scopes of the token, see Authorize.
+func (a *Auth) AuthMiddleware() gin.HandlerFunc {
+	sessionMiddleware := a.SessionMiddleware()
+	tokenService := apitoken.NewService(a.db)
+	rateLimiter := a.limits.APIToken
+	auditService := audit.NewService(a.db)
+
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			sessionMiddleware(c)
+			return
+		}
+
+		// Create identifier for rate limiting
+		ip := c.ClientIP()
+		identifier := ip
+
+		// Check if blocked
+		if blocked, _ := rateLimiter.IsBlocked(identifier); blocked {
+			AbortLockedOut(c, rateLimiter, identifier, "Too many failed authentication attempts")
+			return
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+		user, apiToken, err := tokenService.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			RecordFailure(c, auditService, rateLimiter, identifier)
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API token"})
+			return
+		}
+
+		rateLimiter.Reset(identifier)
+
+		// Store user and token in context
+		c.Set(UserContextKey, user)
+		c.Set(APITokenContextKey, apiToken)
+
+		c.Next()
+	}
+}
+
+// CurrentAPIToken returns the API token the request was authenticated with, if any
+func CurrentAPIToken(c *gin.Context) (*entities.APIToken, bool) {
+	value, exists := c.Get(APITokenContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	apiToken, ok := value.(*entities.APIToken)
+	return apiToken, ok && apiToken != nil
+}
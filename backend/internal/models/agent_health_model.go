@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AgentHealth struct {
+	AgentUUID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Status               string    `gorm:"size:25;not null"`
+	LastSeenAt           *time.Time
+	LastCheckAt          time.Time
+	LatencyMs            int64
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	LastError            string `gorm:"size:1000"`
+	UpdatedAt            time.Time
+}
+
+type AgentHealthEvent struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AgentUUID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	PreviousStatus string    `gorm:"size:25"`
+	Status         string    `gorm:"size:25;not null"`
+	LatencyMs      int64
+	Error          string    `gorm:"size:1000"`
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+func (e *AgentHealthEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
+
+type AgentHealthResponse struct {
+	LastSeenAt          *time.Time `json:"last_seen_at,omitempty"`
+	LastCheckAt         time.Time  `json:"last_check_at"`
+	LatencyMs           int64      `json:"latency_ms"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type AgentHealthEventResponse struct {
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	LatencyMs      int64     `json:"latency_ms"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIToken struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primaryKey"`
+	UserUUID   uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Name       string      `gorm:"size:100;not null"`
+	Prefix     string      `gorm:"size:16;not null"`
+	TokenHash  string      `gorm:"size:64;uniqueIndex;not null"`
+	Scopes     StringArray `gorm:"type:text"`
+	ExpiresAt  time.Time   `gorm:"not null;index"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+
+	// Relations
+	User User `gorm:"foreignKey:UserUUID;references:UUID"`
+}
+
+func (t *APIToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// APITokenResponse represents an API token, the token itself is only returned
+// on creation
+type APITokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Action     string    `gorm:"size:50;not null;index"`
+	ActorUUID  uuid.UUID `gorm:"type:uuid;index"`
+	ActorEmail string    `gorm:"size:255"`
+	IPAddress  string    `gorm:"size:45;index"`
+	UserAgent  string    `gorm:"size:500"`
+	TargetType string    `gorm:"size:50;index:idx_audit_events_target"`
+	TargetID   string    `gorm:"size:255;index:idx_audit_events_target"`
+	Detail     string    `gorm:"size:1000"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
+
+type AuditEventResponse struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	ActorUUID  string    `json:"actor_uuid,omitempty"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	TargetType string    `json:"target_type,omitempty"`
+	TargetID   string    `json:"target_id,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditEventPageResponse is a page of the audit log, Total counting every
+// event matching the filters
+type AuditEventPageResponse struct {
+	Items  []AuditEventResponse `json:"items"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AgentEnrollmentToken is a single use token for an agent to register itself,
+// stored hashed
+type AgentEnrollmentToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name      string    `gorm:"size:100"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	CreatedBy uuid.UUID `gorm:"type:uuid"` // unset for the tokens issued from the shell
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	AgentUUID *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time
+}
+
+func (t *AgentEnrollmentToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// AgentEnrollmentTokenResponse represents an enrollment token, the token
+// itself is only returned on creation
+type AgentEnrollmentTokenResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	AgentUUID string     `json:"agent_uuid,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AgentEnrollResponse is answered to an enrolled agent, the secret it
+// authenticates the manager with is only sent once
+type AgentEnrollResponse struct {
+	AgentUUID string `json:"agent_uuid"`
+	Name      string `json:"name"`
+	Secret    string `json:"secret"`
+}
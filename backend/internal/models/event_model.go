@@ -0,0 +1,9 @@
+package models
+
+type EventResponse struct {
+	Agent    string              `json:"agent,omitempty"`
+	Task     *TaskResponseModel  `json:"task,omitempty"`
+	TaskID   string              `json:"task_id,omitempty"`
+	Tasks    []TaskResponseModel `json:"tasks,omitempty"`
+	Instance *InstanceResponse   `json:"instance,omitempty"`
+}
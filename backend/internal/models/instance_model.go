@@ -15,8 +15,15 @@ type Agent struct {
 	EncrypetedToken string    `gorm:"size:600;not null"`
 	Icon            string    `gorm:"size:100"`
 	Color           string    `gorm:"size:50"`
-	CreatedAt       time.Time `gorm:"autoCreateTime"`
-	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+	// SHA-256 of the pinned agent certificate, in hex
+	CertificateFingerprint string `gorm:"size:64"`
+	// ParentUUID is the agent managing the torrent client of a child agent,
+	// which has no address nor token of its own
+	ParentUUID *uuid.UUID `gorm:"type:uuid;index"`
+	// Client is the name of the torrent client of a child agent on its parent
+	Client    string    `gorm:"size:50"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
 func (a *Agent) BeforeCreate(tx *gorm.DB) (err error) {
@@ -34,14 +41,46 @@ func (a *Agent) BeforeUpdate(tx *gorm.DB) (err error) {
 }
 
 type AgentResponse struct {
-	UUID     string           `json:"uuid"`
-	Name     string           `json:"name"`
-	Address  string           `json:"address"`
-	Status   string           `json:"status"`
-	Error    string           `json:"error,omitempty"`
-	Icon     string           `json:"icon,omitempty"`
-	Color    string           `json:"color,omitempty"`
-	Instance InstanceResponse `json:"instance"`
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Icon    string `json:"icon,omitempty"`
+	Color   string `json:"color,omitempty"`
+	// CertificateFingerprint is the SHA-256 of the pinned agent certificate
+	CertificateFingerprint string                     `json:"certificate_fingerprint,omitempty"`
+	Capabilities           ClientCapabilitiesResponse `json:"capabilities"`
+	Instance               InstanceResponse           `json:"instance"`
+	Health                 *AgentHealthResponse       `json:"health,omitempty"`
+	// Tunnel tells whether the agent is connected through a tunnel it opened
+	Tunnel bool `json:"tunnel"`
+	// ParentUUID and Client identify the torrent client of a child agent on its parent
+	ParentUUID string `json:"parent_uuid,omitempty"`
+	Client     string `json:"client,omitempty"`
+}
+
+// AgentClientResponse is a torrent client of an agent, listed by GET /v1/clients
+type AgentClientResponse struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default bool   `json:"default"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ClientCapabilitiesResponse tells which optional task actions the torrent client of an agent supports
+type ClientCapabilitiesResponse struct {
+	Categories         bool `json:"categories"`
+	Tags               bool `json:"tags"`
+	SuperSeeding       bool `json:"super_seeding"`
+	ForceStart         bool `json:"force_start"`
+	Rename             bool `json:"rename"`
+	ShareLimits        bool `json:"share_limits"`
+	ContentLayout      bool `json:"content_layout"`
+	SkipChecking       bool `json:"skip_checking"`
+	SequentialDownload bool `json:"sequential_download"`
 }
 
 type InstanceResponse struct {
@@ -51,6 +90,7 @@ type InstanceResponse struct {
 }
 
 type InstanceApplicationResponse struct {
+	Client     string `json:"client,omitempty"`
 	Version    string `json:"version"`
 	APIVersion string `json:"api_version"`
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginState is a single sign-on waiting for the identity provider to send
+// the user back. The state is stored hashed.
+type OIDCLoginState struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null"`
+	Nonce        string    `gorm:"size:100;not null"`
+	CodeVerifier string    `gorm:"size:100;not null"` // PKCE
+	RedirectPath string    `gorm:"size:500"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+// TableName overrides the name gorm derives from the acronym
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+func (s *OIDCLoginState) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// UserIdentity links a user to the subject of an identity provider
+type UserIdentity struct {
+	Issuer      string    `gorm:"size:255;primaryKey"`
+	Subject     string    `gorm:"size:255;primaryKey"`
+	UserUUID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Email       string    `gorm:"size:255"`
+	LastLoginAt time.Time
+	CreatedAt   time.Time
+
+	// Relations
+	User User `gorm:"foreignKey:UserUUID;references:UUID"`
+}
+
+// AuthProvidersResponse tells the login page how users can log in
+type AuthProvidersResponse struct {
+	PasswordLogin bool                  `json:"password_login"`
+	ForwardAuth   bool                  `json:"forward_auth"` // users are logged in by an authenticating proxy
+	OIDC          *OIDCProviderResponse `json:"oidc,omitempty"`
+}
+
+// OIDCProviderResponse represents the single sign-on offered on the login page
+type OIDCProviderResponse struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"login_url"`
+}
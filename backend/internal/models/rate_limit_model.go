@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type RateLimit struct {
+	Key          string `gorm:"primaryKey;size:255"`
+	Count        int    `gorm:"not null;default:0"`
+	WindowStart  time.Time
+	BlockedUntil time.Time
+	Tokens       float64
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	UpdatedAt    time.Time
+}
+
+// RateLimitPolicyResponse represents the state of a rate limiting policy
+type RateLimitPolicyResponse struct {
+	Name    string                     `json:"name"`
+	Mode    string                     `json:"mode"`
+	Stats   map[string]interface{}     `json:"stats"`
+	Blocked []RateLimitBlockedResponse `json:"blocked"`
+}
+
+// RateLimitBlockedResponse represents an identifier locked out by a policy
+type RateLimitBlockedResponse struct {
+	Identifier   string    `json:"identifier"`
+	Attempts     int       `json:"attempts"`
+	BlockedUntil time.Time `json:"blocked_until"`
+}
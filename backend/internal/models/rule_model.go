@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Rule struct {
+	UUID            uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Name            string          `gorm:"size:100;not null;uniqueIndex"`
+	Enabled         bool            `gorm:"not null"`
+	DryRun          bool            `gorm:"not null"`
+	IntervalSeconds int64           `gorm:"not null"`
+	Conditions      []RuleCondition `gorm:"type:text;serializer:json"`
+	Actions         []RuleAction    `gorm:"type:text;serializer:json"`
+	LastRunAt       *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (r *Rule) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.UUID == uuid.Nil {
+		r.UUID = uuid.New()
+	}
+	return
+}
+
+// RuleCondition is stored as JSON within the rule and returned as is
+type RuleCondition struct {
+	Field    string   `json:"field"`
+	Operator string   `json:"operator"`
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// RuleAction is stored as JSON within the rule and returned as is
+type RuleAction struct {
+	Type             string   `json:"type"`
+	DeleteFiles      bool     `json:"delete_files,omitempty"`
+	Location         string   `json:"location,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	Category         string   `json:"category,omitempty"`
+	RatioLimit       float64  `json:"ratio_limit,omitempty"`
+	SeedingTimeLimit int      `json:"seeding_time_limit,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+}
+
+type RuleExecution struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RuleUUID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	RuleName  string    `gorm:"size:100;not null"`
+	AgentUUID uuid.UUID `gorm:"type:uuid;not null;index"`
+	AgentName string    `gorm:"size:100"`
+	TaskHash  string    `gorm:"size:64;not null"`
+	TaskName  string    `gorm:"size:1000"`
+	Action    string    `gorm:"size:50;not null"`
+	Detail    string    `gorm:"size:1000"`
+	DryRun    bool      `gorm:"not null"`
+	Error     string    `gorm:"size:1000"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (e *RuleExecution) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
+
+type RuleResponse struct {
+	UUID       string          `json:"uuid"`
+	Name       string          `json:"name"`
+	Enabled    bool            `json:"enabled"`
+	DryRun     bool            `json:"dry_run"`
+	Interval   string          `json:"interval"`
+	Conditions []RuleCondition `json:"conditions"`
+	Actions    []RuleAction    `json:"actions"`
+	LastRunAt  *time.Time      `json:"last_run_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type RuleExecutionResponse struct {
+	ID        string    `json:"id,omitempty"`
+	Rule      string    `json:"rule"`
+	RuleName  string    `json:"rule_name"`
+	Agent     string    `json:"agent"`
+	AgentName string    `json:"agent_name"`
+	TaskID    string    `json:"task_id"`
+	TaskName  string    `json:"task_name"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail,omitempty"`
+	DryRun    bool      `json:"dry_run"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
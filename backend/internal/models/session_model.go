@@ -8,14 +8,15 @@ import (
 )
 
 type Session struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;uniqueIndex"`
-	UserUUID  uuid.UUID `gorm:"type:uuid;not null;index"`
-	Token     string    `gorm:"size:255;uniqueIndex;not null"`
-	UserAgent string    `gorm:"size:500"`
-	IPAddress string    `gorm:"size:45"`
-	ExpiresAt time.Time `gorm:"not null;index"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;uniqueIndex"`
+	UserUUID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash  string    `gorm:"size:255;uniqueIndex;not null"` // SHA-256 of the cookie token
+	UserAgent  string    `gorm:"size:500"`
+	IPAddress  string    `gorm:"size:45"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 
 	// Relations
 	User User `gorm:"foreignKey:UserUUID;references:UUID"`
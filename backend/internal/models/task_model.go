@@ -18,6 +18,9 @@ type TaskResponseModel struct {
 	Network    TaskNetworkResponseModel `json:"network"`
 	Tags       []string                 `json:"tags,omitempty"`
 	Agent      *AgentResponse           `json:"agent,omitempty"`
+	// SeedingTime is in seconds
+	SeedingTime int    `json:"seeding_time"`
+	Tracker     string `json:"tracker,omitempty"`
 }
 
 type TaskMagnetLinkResponse struct {
@@ -59,3 +62,18 @@ type TaskFileResponse struct {
 	PieceRange   [2]int  `json:"piece_range"`
 	Availability float64 `json:"availability"`
 }
+
+// TaskPreviewResponse describes the task a source would add
+type TaskPreviewResponse struct {
+	Name       string                    `json:"name"`
+	Size       int64                     `json:"size"`
+	InfoHashV1 string                    `json:"info_hash_v1,omitempty"`
+	InfoHashV2 string                    `json:"info_hash_v2,omitempty"`
+	Files      []TaskPreviewFileResponse `json:"files"`
+	Existing   *TaskResponseModel        `json:"existing,omitempty"`
+}
+
+type TaskPreviewFileResponse struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
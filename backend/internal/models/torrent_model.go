@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TorrentInspectResponse describes the metainfo of a .torrent file
+type TorrentInspectResponse struct {
+	Name         string                `json:"name"`
+	Version      string                `json:"version"`
+	InfoHashV1   string                `json:"info_hash_v1,omitempty"`
+	InfoHashV2   string                `json:"info_hash_v2,omitempty"`
+	Size         int64                 `json:"size"`
+	PieceLength  int64                 `json:"piece_length"`
+	PieceCount   int                   `json:"piece_count"`
+	Private      bool                  `json:"private"`
+	Trackers     [][]string            `json:"trackers"`
+	WebSeeds     []string              `json:"web_seeds"`
+	Comment      string                `json:"comment,omitempty"`
+	CreatedBy    string                `json:"created_by,omitempty"`
+	CreationDate *time.Time            `json:"creation_date,omitempty"`
+	Files        []TorrentFileResponse `json:"files"`
+	MagnetURI    string                `json:"magnet_uri"`
+}
+
+type TorrentFileResponse struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TransferSample struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement"`
+	AgentUUID       uuid.UUID `gorm:"type:uuid;not null;index:idx_transfer_samples_series,priority:2"`
+	Scope           string    `gorm:"size:20;not null;index:idx_transfer_samples_series,priority:3"`
+	TaskHash        string    `gorm:"size:64;index:idx_transfer_samples_series,priority:4"`
+	Category        string    `gorm:"size:100"`
+	Resolution      string    `gorm:"size:10;not null;index:idx_transfer_samples_series,priority:1;index:idx_transfer_samples_resolution_time,priority:1"`
+	Timestamp       time.Time `gorm:"not null;index:idx_transfer_samples_series,priority:5;index:idx_transfer_samples_resolution_time,priority:2"`
+	Downloaded      int64
+	Uploaded        int64
+	Ratio           float64
+	DownloadedDelta int64
+	UploadedDelta   int64
+}
+
+type TransferSeriesResponse struct {
+	Category     string                  `json:"category,omitempty"`
+	From         time.Time               `json:"from"`
+	To           time.Time               `json:"to"`
+	Step         string                  `json:"step"`
+	Downloaded   int64                   `json:"downloaded"`
+	Uploaded     int64                   `json:"uploaded"`
+	Ratio        float64                 `json:"ratio"`
+	CurrentRatio float64                 `json:"current_ratio,omitempty"`
+	Points       []TransferPointResponse `json:"points"`
+}
+
+type TransferPointResponse struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Downloaded int64     `json:"downloaded"`
+	Uploaded   int64     `json:"uploaded"`
+	Ratio      float64   `json:"ratio"`
+}
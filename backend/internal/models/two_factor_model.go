@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserTOTP holds the TOTP secret of a user, encrypted. The second factor is
+// enabled once the enrollment is confirmed.
+type UserTOTP struct {
+	UserUUID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	EncryptedSecret string    `gorm:"size:600;not null"`
+	LastStep        int64     `gorm:"not null;default:0"` // last accepted time step, refused afterwards
+	ConfirmedAt     *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// RecoveryCode is a single use code replacing a TOTP code, stored hashed
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserUUID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeHash  string    `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+// LoginChallenge is a login waiting for its second factor, stored hashed
+type LoginChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserUUID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+
+	// Relations
+	User User `gorm:"foreignKey:UserUUID;references:UUID"`
+}
+
+func (c *LoginChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+// TwoFactorStatusResponse represents the second factor of the current user
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollmentResponse represents a TOTP secret to add to an authenticator app
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse represents recovery codes, only returned when generated
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginChallengeResponse represents a login waiting for its second factor
+type LoginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
@@ -13,6 +13,8 @@ type User struct {
 	Email        string    `gorm:"size:255;uniqueIndex;not null"`
 	PasswordHash string    `gorm:"size:255;not null"`
 	Salt         string    `gorm:"size:255;not null"`
+	Role         string    `gorm:"size:100"`
+	Disabled     bool      `gorm:"not null;default:false"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -32,13 +34,80 @@ func (u *User) BeforeUpdate(tx *gorm.DB) (err error) {
 	return
 }
 
+// AgentPermission grants a user access to an agent. Admins have access to every agent.
+type AgentPermission struct {
+	UserUUID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AgentUUID uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// UserInvite lets someone register with a role while registration is closed
+type UserInvite struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Email     string    `gorm:"size:255;not null;index"`
+	Role      string    `gorm:"size:100;not null"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	InvitedBy uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (i *UserInvite) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// PasswordReset is a single use token to set a new password, stored hashed
+type PasswordReset struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserUUID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (r *PasswordReset) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+// PasswordResetResponse represents the response body of a created password
+// reset token, the token is only returned once
+type PasswordResetResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // UserResponse represents the response body for user operations
 type UserResponse struct {
 	UUID      string    `json:"uuid"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Disabled  bool      `json:"disabled"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// UserInviteResponse represents the response body of a created invite, the
+// token is only returned once
+type UserInviteResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserAgentsResponse represents the agents a user has access to
+type UserAgentsResponse struct {
+	Agents []string `json:"agents"`
+}
+
 // AuthResponse represents the response body for authentication operations
 type AuthResponse struct {
 	User UserResponse `json:"user"`
@@ -46,9 +115,11 @@ type AuthResponse struct {
 
 // SessionResponse represents the response body for session information
 type SessionResponse struct {
-	ID        string    `json:"id"`
-	UserAgent string    `json:"user_agent"`
-	IPAddress string    `json:"ip_address"`
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
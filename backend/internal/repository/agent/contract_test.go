@@ -0,0 +1,501 @@
+package agent_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/repository/agent"
+	instanceRepository "github.com/gardarr/gardarr/internal/repository/instance/agent"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	"github.com/gardarr/gardarr/internal/routes/agent/v1/clients"
+	"github.com/gardarr/gardarr/internal/routes/agent/v1/instance"
+	"github.com/gardarr/gardarr/internal/routes/agent/v1/tasks"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	instanceService "github.com/gardarr/gardarr/internal/services/instance/agent"
+	taskService "github.com/gardarr/gardarr/internal/services/task/agent"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contractSecret = "contract-secret"
+	contractHash   = "0123456789abcdef0123456789abcdef01234567"
+)
+
+// call records a single invocation received by the fake repositories
+type call struct {
+	method string
+	args   []any
+}
+
+// fakeTaskRepository implements taskRepository.RepositoryInterface and records every call
+type fakeTaskRepository struct {
+	mu    sync.Mutex
+	calls []call
+}
+
+var _ taskRepository.RepositoryInterface = (*fakeTaskRepository)(nil)
+
+func (f *fakeTaskRepository) record(method string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call{method: method, args: args})
+}
+
+func (f *fakeTaskRepository) last() call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return call{}
+	}
+	return f.calls[len(f.calls)-1]
+}
+
+func (f *fakeTaskRepository) task() *entities.Task {
+	return &entities.Task{
+		ID:       contractHash,
+		Hash:     contractHash,
+		Name:     "Contract Task",
+		State:    "UPLOADING",
+		Category: "movies",
+		Tags:     []string{"hd"},
+		MagnetLink: entities.TaskMagnetLink{
+			Hash: contractHash,
+		},
+	}
+}
+
+func (f *fakeTaskRepository) List() ([]*entities.Task, error) {
+	f.record("List")
+	return []*entities.Task{f.task()}, nil
+}
+
+func (f *fakeTaskRepository) Get(hash string) (*entities.Task, error) {
+	f.record("Get", hash)
+	return f.task(), nil
+}
+
+func (f *fakeTaskRepository) Add(schema schemas.TaskCreateSchema) (*entities.Task, error) {
+	f.record("Add", schema.MagnetURI, schema.Category)
+	return f.task(), nil
+}
+
+func (f *fakeTaskRepository) Stop(hash string) error {
+	f.record("Stop", hash)
+	return nil
+}
+
+func (f *fakeTaskRepository) Start(hash string) error {
+	f.record("Start", hash)
+	return nil
+}
+
+func (f *fakeTaskRepository) ForceResume(hash string) error {
+	f.record("ForceResume", hash)
+	return nil
+}
+
+func (f *fakeTaskRepository) Delete(id string, deleteFiles bool) error {
+	f.record("Delete", id, deleteFiles)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetTags(hash string, tags []string) error {
+	f.record("SetTags", hash, tags)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetShareLimit(schema schemas.TaskSetShareLimitSchema) error {
+	f.record("SetShareLimit", schema.Hash, schema.RatioLimit, schema.SeedingTimeLimit)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetLocation(hash string, schema schemas.TaskSetLocationSchema) error {
+	f.record("SetLocation", hash, schema.Location)
+	return nil
+}
+
+func (f *fakeTaskRepository) Rename(hash string, schema schemas.TaskRenameSchema) error {
+	f.record("Rename", hash, schema.NewName)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetSuperSeeding(hash string, schema schemas.TaskSuperSeedingSchema) error {
+	f.record("SetSuperSeeding", hash, schema.Enabled)
+	return nil
+}
+
+func (f *fakeTaskRepository) ForceRecheck(hash string) error {
+	f.record("ForceRecheck", hash)
+	return nil
+}
+
+func (f *fakeTaskRepository) ForceReannounce(hash string) error {
+	f.record("ForceReannounce", hash)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetDownloadLimit(hash string, schema schemas.TaskSetDownloadLimitSchema) error {
+	f.record("SetDownloadLimit", hash, schema.Limit)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetUploadLimit(hash string, schema schemas.TaskSetUploadLimitSchema) error {
+	f.record("SetUploadLimit", hash, schema.Limit)
+	return nil
+}
+
+func (f *fakeTaskRepository) SetCategory(hash string, schema schemas.TaskSetCategorySchema) error {
+	f.record("SetCategory", hash, schema.Category)
+	return nil
+}
+
+func (f *fakeTaskRepository) ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error {
+	f.record("ReplaceTags", hash, schema.Tags)
+	return nil
+}
+
+func (f *fakeTaskRepository) ListFiles(hash string) ([]*entities.TaskFile, error) {
+	f.record("ListFiles", hash)
+	return []*entities.TaskFile{
+		{Name: "movie.mkv", Size: 1024, Progress: 1, Priority: 1, IsSeed: true, PieceRange: [2]int{0, 3}},
+	}, nil
+}
+
+// fakeInstanceRepository implements instanceRepository.RepositoryInterface
+type fakeInstanceRepository struct{}
+
+var _ instanceRepository.RepositoryInterface = fakeInstanceRepository{}
+
+func (fakeInstanceRepository) GetInstance() (*entities.Instance, error) {
+	return &entities.Instance{
+		Application: entities.InstanceApplication{Version: "v5.0.0", APIVersion: "2.11.0"},
+		Transfer:    entities.InstanceTransfer{AllTimeUploaded: 2048, GlobalRatio: 2},
+	}, nil
+}
+
+func (fakeInstanceRepository) GetPreferences(ctx context.Context) (*entities.InstancePreferences, error) {
+	return &entities.InstancePreferences{
+		GlobalRateLimits: entities.InstancePreferencesGlobalRateLimits{UploadSpeedLimit: 512, UploadSpeedLimitEnabled: true},
+	}, nil
+}
+
+func (fakeInstanceRepository) Ping() error { return nil }
+
+func (fakeInstanceRepository) SetDownloadSpeedLimit(limit int) error { return nil }
+
+func (fakeInstanceRepository) SetUploadSpeedLimit(limit int) error { return nil }
+
+// setupContract starts the real agent router on top of the fakes and returns
+// a manager repository plus an agent entity pointing at it
+func setupContract(t *testing.T) (*agent.Repository, *entities.Agent, *fakeTaskRepository) {
+	t.Helper()
+
+	t.Setenv(constants.AgentSecretEnv, contractSecret)
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	fake := &fakeTaskRepository{}
+	v1 := router.Group("/v1")
+	tasks.NewModule(v1, taskService.NewWithRepository(fake)).Register()
+	instance.NewModule(v1, instanceService.NewWithRepository(fakeInstanceRepository{})).Register()
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	token, err := cryptoSvc.Encrypt(contractSecret)
+	if err != nil {
+		t.Fatalf("Failed to encrypt token: %v", err)
+	}
+
+	repo, err := agent.NewRepository(nil, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	return repo, &entities.Agent{Name: "contract", Address: server.URL, Token: token}, fake
+}
+
+func TestContract_TaskActions(t *testing.T) {
+	repo, a, fake := setupContract(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() error
+		want call
+	}{
+		{
+			name: "stop",
+			run:  func() error { return repo.StopAgentTask(ctx, a, contractHash) },
+			want: call{"Stop", []any{contractHash}},
+		},
+		{
+			name: "start",
+			run:  func() error { return repo.StartAgentTask(ctx, a, contractHash) },
+			want: call{"Start", []any{contractHash}},
+		},
+		{
+			name: "force resume",
+			run:  func() error { return repo.ForceResumeAgentTask(ctx, a, contractHash) },
+			want: call{"ForceResume", []any{contractHash}},
+		},
+		{
+			name: "delete",
+			run:  func() error { return repo.DeleteAgentTask(ctx, a, contractHash, false) },
+			want: call{"Delete", []any{contractHash, false}},
+		},
+		{
+			name: "delete with purge",
+			run:  func() error { return repo.DeleteAgentTask(ctx, a, contractHash, true) },
+			want: call{"Delete", []any{contractHash, true}},
+		},
+		{
+			name: "share limit",
+			run: func() error {
+				return repo.SetAgentTaskShareLimit(ctx, a, contractHash, schemas.TaskSetShareLimitSchema{RatioLimit: 2.5, SeedingTimeLimit: 120})
+			},
+			want: call{"SetShareLimit", []any{contractHash, 2.5, 120}},
+		},
+		{
+			name: "location",
+			run: func() error {
+				return repo.SetAgentTaskLocation(ctx, a, contractHash, schemas.TaskSetLocationSchema{Location: "/data/movies"})
+			},
+			want: call{"SetLocation", []any{contractHash, "/data/movies"}},
+		},
+		{
+			name: "rename",
+			run: func() error {
+				return repo.RenameAgentTask(ctx, a, contractHash, schemas.TaskRenameSchema{NewName: "Renamed"})
+			},
+			want: call{"Rename", []any{contractHash, "Renamed"}},
+		},
+		{
+			name: "super seeding",
+			run: func() error {
+				return repo.SetAgentTaskSuperSeeding(ctx, a, contractHash, schemas.TaskSuperSeedingSchema{Enabled: true})
+			},
+			want: call{"SetSuperSeeding", []any{contractHash, true}},
+		},
+		{
+			name: "recheck",
+			run:  func() error { return repo.ForceRecheckAgentTask(ctx, a, contractHash) },
+			want: call{"ForceRecheck", []any{contractHash}},
+		},
+		{
+			name: "reannounce",
+			run:  func() error { return repo.ForceReannounceAgentTask(ctx, a, contractHash) },
+			want: call{"ForceReannounce", []any{contractHash}},
+		},
+		{
+			name: "download limit",
+			run: func() error {
+				return repo.SetAgentTaskDownloadLimit(ctx, a, contractHash, schemas.TaskSetDownloadLimitSchema{Limit: 1024})
+			},
+			want: call{"SetDownloadLimit", []any{contractHash, 1024}},
+		},
+		{
+			name: "upload limit",
+			run: func() error {
+				return repo.SetAgentTaskUploadLimit(ctx, a, contractHash, schemas.TaskSetUploadLimitSchema{Limit: 2048})
+			},
+			want: call{"SetUploadLimit", []any{contractHash, 2048}},
+		},
+		{
+			name: "category",
+			run: func() error {
+				return repo.SetAgentTaskCategory(ctx, a, contractHash, schemas.TaskSetCategorySchema{Category: "archive"})
+			},
+			want: call{"SetCategory", []any{contractHash, "archive"}},
+		},
+		{
+			name: "tags",
+			run: func() error {
+				return repo.SetAgentTaskTags(ctx, a, contractHash, schemas.TaskSetTagsSchema{Tags: []string{"seeded", "done"}})
+			},
+			want: call{"ReplaceTags", []any{contractHash, []string{"seeded", "done"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if got := fake.last(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected agent to receive %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestContract_TaskQueries(t *testing.T) {
+	repo, a, fake := setupContract(t)
+	ctx := context.Background()
+
+	list, err := repo.ListAgentTasks(ctx, a)
+	if err != nil {
+		t.Fatalf("ListAgentTasks: expected no error, got %v", err)
+	}
+	if len(list) != 1 || list[0].Hash != contractHash || list[0].Agent != a {
+		t.Errorf("ListAgentTasks: unexpected result %+v", list)
+	}
+
+	task, err := repo.GetAgentTask(ctx, a, contractHash)
+	if err != nil {
+		t.Fatalf("GetAgentTask: expected no error, got %v", err)
+	}
+	if task.Hash != contractHash || task.Category != "movies" {
+		t.Errorf("GetAgentTask: unexpected result %+v", task)
+	}
+
+	created, err := repo.CreateAgentTask(ctx, a, schemas.TaskCreateSchema{
+		MagnetURI: "magnet:?xt=urn:btih:ffffffffffffffffffffffffffffffffffffffff",
+		Category:  "movies",
+		Tags:      []string{"hd"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAgentTask: expected no error, got %v", err)
+	}
+	if created.Hash != contractHash {
+		t.Errorf("CreateAgentTask: unexpected result %+v", created)
+	}
+	if got := fake.last(); got.method != "Add" {
+		t.Errorf("CreateAgentTask: expected Add to be called, got %+v", got)
+	}
+
+	preview, err := repo.PreviewAgentTask(ctx, a, schemas.TaskCreateSchema{
+		Torrent:  []byte("d4:infod6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"),
+		Category: "movies",
+	})
+	if err != nil {
+		t.Fatalf("PreviewAgentTask: expected no error, got %v", err)
+	}
+	if preview.Name != "file.iso" || preview.Size != 1024 || len(preview.InfoHashV1) != 40 || preview.Existing != nil {
+		t.Errorf("PreviewAgentTask: unexpected result %+v", preview)
+	}
+
+	preview, err = repo.PreviewAgentTask(ctx, a, schemas.TaskCreateSchema{
+		MagnetURI: "magnet:?xt=urn:btih:" + contractHash,
+		Category:  "movies",
+	})
+	if err != nil {
+		t.Fatalf("PreviewAgentTask: expected no error, got %v", err)
+	}
+	if preview.Existing == nil || preview.Existing.Hash != contractHash || preview.Existing.Agent != a {
+		t.Errorf("PreviewAgentTask: expected the existing task, got %+v", preview)
+	}
+
+	files, err := repo.ListAgentTaskFiles(ctx, a, contractHash)
+	if err != nil {
+		t.Fatalf("ListAgentTaskFiles: expected no error, got %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "movie.mkv" || files[0].PieceRange != [2]int{0, 3} {
+		t.Errorf("ListAgentTaskFiles: unexpected result %+v", files)
+	}
+}
+
+func TestContract_Instance(t *testing.T) {
+	repo, a, _ := setupContract(t)
+	ctx := context.Background()
+
+	inst, err := repo.GetInstance(ctx, a)
+	if err != nil {
+		t.Fatalf("GetInstance: expected no error, got %v", err)
+	}
+	if inst.Application.Version != "v5.0.0" || inst.Transfer.AllTimeUploaded != 2048 {
+		t.Errorf("GetInstance: unexpected result %+v", inst)
+	}
+
+	prefs, err := repo.GetAgentPreferences(ctx, a)
+	if err != nil {
+		t.Fatalf("GetAgentPreferences: expected no error, got %v", err)
+	}
+	if prefs.GlobalRateLimits.UploadSpeedLimit != 512 || !prefs.GlobalRateLimits.UploadSpeedLimitEnabled {
+		t.Errorf("GetAgentPreferences: unexpected result %+v", prefs)
+	}
+}
+
+func TestContract_RejectsInvalidToken(t *testing.T) {
+	repo, a, fake := setupContract(t)
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	a.Token, err = cryptoSvc.Encrypt("wrong-secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt token: %v", err)
+	}
+
+	if err := repo.StopAgentTask(context.Background(), a, contractHash); err == nil {
+		t.Error("Expected error for invalid agent token, got nil")
+	}
+
+	if got := fake.last(); got.method != "" {
+		t.Errorf("Expected agent repository to be untouched, got %+v", got)
+	}
+}
+
+func TestContract_ClientRoutes(t *testing.T) {
+	repo, a, defaultFake := setupContract(t)
+	ctx := context.Background()
+
+	// a second agent router serving the default client and a "movies" one
+	router := gin.New()
+	v1 := router.Group("/v1")
+	tasks.NewModule(v1, taskService.NewWithRepository(defaultFake)).Register()
+
+	moviesFake := &fakeTaskRepository{}
+	movies := v1.Group("/clients/movies")
+	tasks.NewModule(movies, taskService.NewWithRepository(moviesFake)).Register()
+	instance.NewModule(movies, instanceService.NewWithRepository(fakeInstanceRepository{})).Register()
+
+	instances := instanceService.NewWithRepository(fakeInstanceRepository{})
+	clients.NewModule(v1, []clients.Client{
+		{Name: "default", Type: entities.ClientQBittorrent, Default: true, Instance: instances},
+		{Name: "movies", Type: entities.ClientQBittorrent, Instance: instances},
+	}).Register()
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	a.Address = server.URL
+
+	listed, err := repo.ListAgentClients(ctx, a)
+	if err != nil {
+		t.Fatalf("ListAgentClients: expected no error, got %v", err)
+	}
+	if len(listed) != 2 || !listed[0].Default || listed[1].Name != "movies" || listed[1].Status != entities.AgentStatusActive {
+		t.Errorf("ListAgentClients: unexpected result %+v", listed)
+	}
+
+	child := &entities.Agent{Name: "contract/movies", Parent: a, Client: "movies"}
+	if err := repo.StopAgentTask(ctx, child, contractHash); err != nil {
+		t.Fatalf("StopAgentTask: expected no error, got %v", err)
+	}
+	if got := moviesFake.last(); got.method != "Stop" {
+		t.Errorf("Expected the movies client to stop the task, got %+v", got)
+	}
+	if got := defaultFake.last(); got.method != "" {
+		t.Errorf("Expected the default client to be untouched, got %+v", got)
+	}
+	if _, err := repo.GetInstance(ctx, child); err != nil {
+		t.Errorf("GetInstance: expected no error, got %v", err)
+	}
+}
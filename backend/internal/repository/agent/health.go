@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetAgentHealth retrieves the last known health state of an agent.
+// It returns nil without error when the agent was never checked.
+func (r *Repository) GetAgentHealth(ctx context.Context, uid uuid.UUID) (*entities.AgentHealth, error) {
+	var handler models.AgentHealth
+	if err := r.db.DB.WithContext(ctx).Where("agent_uuid = ?", uid).First(&handler).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return toAgentHealth(handler), nil
+}
+
+// ListAgentHealth retrieves the health state of every checked agent, indexed by agent UUID
+func (r *Repository) ListAgentHealth(ctx context.Context) (map[uuid.UUID]*entities.AgentHealth, error) {
+	var handler []models.AgentHealth
+	if err := r.db.DB.WithContext(ctx).Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]*entities.AgentHealth, len(handler))
+	for _, item := range handler {
+		result[item.AgentUUID] = toAgentHealth(item)
+	}
+
+	return result, nil
+}
+
+// SaveAgentHealth creates or replaces the health state of an agent
+func (r *Repository) SaveAgentHealth(ctx context.Context, health *entities.AgentHealth) error {
+	handler := models.AgentHealth{
+		AgentUUID:            health.AgentUUID,
+		Status:               health.Status,
+		LastSeenAt:           health.LastSeenAt,
+		LastCheckAt:          health.LastCheckAt,
+		LatencyMs:            health.Latency.Milliseconds(),
+		ConsecutiveFailures:  health.ConsecutiveFailures,
+		ConsecutiveSuccesses: health.ConsecutiveSuccesses,
+		LastError:            truncate(health.LastError, 1000),
+	}
+
+	return r.db.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&handler).Error
+}
+
+// CreateAgentHealthEvent stores a status transition of an agent
+func (r *Repository) CreateAgentHealthEvent(ctx context.Context, event *entities.AgentHealthEvent) error {
+	handler := models.AgentHealthEvent{
+		AgentUUID:      event.AgentUUID,
+		PreviousStatus: event.PreviousStatus,
+		Status:         event.Status,
+		LatencyMs:      event.Latency.Milliseconds(),
+		Error:          truncate(event.Error, 1000),
+		CreatedAt:      event.CreatedAt,
+	}
+
+	return r.db.DB.WithContext(ctx).Create(&handler).Error
+}
+
+// ListAgentHealthEvents retrieves the most recent status transitions of an agent, newest first
+func (r *Repository) ListAgentHealthEvents(ctx context.Context, uid uuid.UUID, limit int) ([]*entities.AgentHealthEvent, error) {
+	var handler []models.AgentHealthEvent
+	if err := r.db.DB.WithContext(ctx).
+		Where("agent_uuid = ?", uid).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.AgentHealthEvent, len(handler))
+	for i, item := range handler {
+		result[i] = &entities.AgentHealthEvent{
+			ID:             item.ID,
+			AgentUUID:      item.AgentUUID,
+			PreviousStatus: item.PreviousStatus,
+			Status:         item.Status,
+			Latency:        time.Duration(item.LatencyMs) * time.Millisecond,
+			Error:          item.Error,
+			CreatedAt:      item.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteAgentHealthEventsBefore removes the status transitions older than the given time (cleanup job)
+func (r *Repository) DeleteAgentHealthEventsBefore(ctx context.Context, before time.Time) error {
+	return r.db.DB.WithContext(ctx).Where("created_at < ?", before).Delete(&models.AgentHealthEvent{}).Error
+}
+
+func toAgentHealth(item models.AgentHealth) *entities.AgentHealth {
+	return &entities.AgentHealth{
+		AgentUUID:            item.AgentUUID,
+		Status:               item.Status,
+		LastSeenAt:           item.LastSeenAt,
+		LastCheckAt:          item.LastCheckAt,
+		Latency:              time.Duration(item.LatencyMs) * time.Millisecond,
+		ConsecutiveFailures:  item.ConsecutiveFailures,
+		ConsecutiveSuccesses: item.ConsecutiveSuccesses,
+		LastError:            item.LastError,
+	}
+}
+
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	return value[:size]
+}
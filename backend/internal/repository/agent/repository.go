@@ -1,18 +1,14 @@
 package agent
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
 	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentclient"
 	"github.com/gardarr/gardarr/internal/infra/database"
-	"github.com/gardarr/gardarr/internal/mappers"
 	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/internal/services/crypto"
@@ -21,32 +17,45 @@ import (
 )
 
 type Repository struct {
-	db     *database.Database
-	crypto *crypto.CryptoService
-	http   *http.Client
+	db      *database.Database
+	crypto  *crypto.CryptoService
+	clients *agentclient.Pool
 }
 
-func NewRepository(db *database.Database, crypto *crypto.CryptoService) *Repository {
-	return &Repository{
-		db:     db,
-		crypto: crypto,
-		http:   http.DefaultClient,
+// NewRepository creates the repository and the pool of the agent clients, failing
+// when their configuration is invalid
+func NewRepository(db *database.Database, crypto *crypto.CryptoService) (*Repository, error) {
+	config, err := agentclient.LoadConfigFromEnv()
+	if err != nil {
+		return nil, err
 	}
+
+	return &Repository{
+		db:      db,
+		crypto:  crypto,
+		clients: agentclient.NewPool(config, crypto),
+	}, nil
 }
 
 // Create inserts a new instance into the database
 func (r *Repository) CreateAgent(ctx context.Context, agent entities.Agent) (*entities.Agent, error) {
-	encToken, err := r.crypto.Encrypt(agent.Token)
+	// The UUID is assigned first, the token being bound to it
+	uid := uuid.New()
+	encToken, err := r.crypto.EncryptWithAD(agent.Token, crypto.AssociatedData(uid))
 	if err != nil {
 		return nil, err
 	}
 
 	handler := &models.Agent{
+		UUID:            uid,
 		Name:            agent.Name,
+		Type:            agent.Type,
 		Address:         agent.Address,
 		EncrypetedToken: encToken,
 		Icon:            agent.Icon,
 		Color:           agent.Color,
+
+		CertificateFingerprint: agent.CertificateFingerprint,
 	}
 
 	if err := r.db.DB.Create(handler).Error; err != nil {
@@ -68,8 +77,16 @@ func (r *Repository) ListAgents() ([]*entities.Agent, error) {
 	}
 
 	result := make([]*entities.Agent, len(handler))
+	byUUID := make(map[uuid.UUID]*entities.Agent, len(handler))
 	for i, item := range handler {
 		result[i] = toAgent(item)
+		byUUID[item.UUID] = result[i]
+	}
+
+	for i, item := range handler {
+		if item.ParentUUID != nil {
+			linkParent(result[i], byUUID[*item.ParentUUID])
+		}
 	}
 
 	return result, nil
@@ -82,84 +99,161 @@ func (r *Repository) GetAgentByUUID(uid uuid.UUID) (*entities.Agent, error) {
 		return nil, err
 	}
 
-	return toAgent(handler), nil
+	return r.withParent(handler)
 }
 
-// GetByUUID retrieves a single instance by its UUID
-func (r *Repository) GetInstance(agent *entities.Agent) (*entities.Instance, error) {
-	url := fmt.Sprintf("%s/v1/instance", agent.Address)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// withParent converts an agent, loading its parent when it is a child agent
+func (r *Repository) withParent(item models.Agent) (*entities.Agent, error) {
+	agent := toAgent(item)
+	if item.ParentUUID == nil {
+		return agent, nil
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
-	if err != nil {
+	var parent models.Agent
+	if err := r.db.DB.Where("uuid = ?", *item.ParentUUID).First(&parent).Error; err != nil {
 		return nil, err
 	}
+	linkParent(agent, toAgent(parent))
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	return agent, nil
+}
 
-	response, err := r.http.Do(req)
-	if err != nil {
-		return nil, err
+// linkParent sets the parent of a child agent, which is reached at its address
+func linkParent(agent, parent *entities.Agent) {
+	if parent == nil {
+		return
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to get instance")
-	}
+	agent.Parent = parent
+	agent.Address = parent.Address
+}
+
+// SyncAgentClients creates a child agent for every torrent client of the
+// parent but the default one, which the parent stands for, and deletes the
+// children of the clients it does not manage anymore. It returns the children.
+func (r *Repository) SyncAgentClients(ctx context.Context, parent *entities.Agent, clients []*entities.AgentClient) ([]*entities.Agent, error) {
+	var children []*entities.Agent
+
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.Agent
+		if err := tx.Where("parent_uuid = ?", parent.UUID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		byClient := make(map[string]models.Agent, len(existing))
+		for _, item := range existing {
+			byClient[item.Client] = item
+		}
+
+		for _, client := range clients {
+			if client.Default {
+				continue
+			}
+
+			item, ok := byClient[client.Name]
+			delete(byClient, client.Name)
+
+			switch {
+			case !ok:
+				parentUUID := parent.UUID
+				item = models.Agent{
+					Name:       parent.Name + "/" + client.Name,
+					Type:       client.Type,
+					ParentUUID: &parentUUID,
+					Client:     client.Name,
+					Icon:       parent.Icon,
+					Color:      parent.Color,
+				}
+				if err := tx.Create(&item).Error; err != nil {
+					return fmt.Errorf("failed to create agent of client %s: %w", client.Name, err)
+				}
+			case item.Type != client.Type:
+				item.Type = client.Type
+				if err := tx.Model(&models.Agent{}).Where("uuid = ?", item.UUID).Update("type", client.Type).Error; err != nil {
+					return err
+				}
+			}
+
+			child := toAgent(item)
+			linkParent(child, parent)
+			children = append(children, child)
+		}
+
+		stale := make([]uuid.UUID, 0, len(byClient))
+		for _, item := range byClient {
+			stale = append(stale, item.UUID)
+		}
 
-	var handler models.InstanceResponse
-	body, err := io.ReadAll(response.Body)
-	defer response.Body.Close()
+		return deleteAgents(tx, stale)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&handler); err != nil {
+	return children, nil
+}
+
+// ListAgentClients retrieves the torrent clients managed by the agent
+func (r *Repository) ListAgentClients(ctx context.Context, agent *entities.Agent) ([]*entities.AgentClient, error) {
+	client, err := r.clients.Get(agent)
+	if err != nil {
 		return nil, err
 	}
 
-	return mappers.ToInstance(handler), nil
+	return client.ListClients(ctx)
 }
 
-func (r *Repository) GetAgentPreferences(agent *entities.Agent) (*entities.InstancePreferences, error) {
-	url := fmt.Sprintf("%s/v1/instance/preferences", agent.Address)
+// AgentSecret decrypts the token of an agent, the secret it signs its requests with
+func (r *Repository) AgentSecret(agent *entities.Agent) (string, error) {
+	return r.crypto.DecryptWithAD(agent.Token, crypto.AssociatedData(agent.UUID))
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// GetInstance retrieves the torrent client instance information from the agent
+func (r *Repository) GetInstance(ctx context.Context, agent *entities.Agent) (*entities.Instance, error) {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	instance, err := client.GetInstance(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get instance: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	return instance, nil
+}
 
-	response, err := r.http.Do(req)
-	if err != nil {
-		return nil, err
+// ProbeInstance retrieves the torrent client instance information from an
+// agent being created or updated, whose token is in plaintext. A child agent
+// is reached through its saved parent.
+func (r *Repository) ProbeInstance(ctx context.Context, agent *entities.Agent) (*entities.Instance, error) {
+	if agent.Parent != nil {
+		return r.GetInstance(ctx, agent)
 	}
 
-	body, err := io.ReadAll(response.Body)
+	client := r.clients.Probe(agent)
+	defer client.Close()
+
+	instance, err := client.GetInstance(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get instance: %w", err)
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to get preferences")
-	}
+	return instance, nil
+}
 
-	var handler models.InstancePreferencesResponse
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&handler); err != nil {
+func (r *Repository) GetAgentPreferences(ctx context.Context, agent *entities.Agent) (*entities.InstancePreferences, error) {
+	client, err := r.clients.Get(agent)
+	if err != nil {
 		return nil, err
 	}
 
-	return mappers.ToInstancePreferences(handler), nil
+	preferences, err := client.GetPreferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	return preferences, nil
 }
 
 // UpdateAgent updates an existing agent in the database
@@ -174,7 +268,7 @@ func (r *Repository) UpdateAgent(ctx context.Context, uid uuid.UUID, updates map
 	// If token is being updated, encrypt it
 	if token, exists := updates["token"]; exists {
 		if tokenStr, ok := token.(string); ok {
-			encToken, err := r.crypto.Encrypt(tokenStr)
+			encToken, err := r.crypto.EncryptWithAD(tokenStr, crypto.AssociatedData(uid))
 			if err != nil {
 				return nil, err
 			}
@@ -193,221 +287,328 @@ func (r *Repository) UpdateAgent(ctx context.Context, uid uuid.UUID, updates map
 		return nil, err
 	}
 
-	return toAgent(agent), nil
+	r.clients.Invalidate(uid)
+
+	return r.withParent(agent)
 }
 
-// Delete removes an agent from the database by UUID
+// Delete removes an agent from the database by UUID, along with its child agents
 func (r *Repository) DeleteAgent(uid uuid.UUID) error {
-	if err := r.db.DB.Where("uuid = ?", uid).Delete(&models.Agent{}).Error; err != nil {
+	err := r.db.DB.Transaction(func(tx *gorm.DB) error {
+		var children []uuid.UUID
+		if err := tx.Model(&models.Agent{}).Where("parent_uuid = ?", uid).Pluck("uuid", &children).Error; err != nil {
+			return err
+		}
+
+		return deleteAgents(tx, append(children, uid))
+	})
+	if err != nil {
 		return err
 	}
 
+	r.clients.Invalidate(uid)
+
 	return nil
 }
 
-func (r *Repository) ListAgentTasks(agent *entities.Agent) ([]*entities.Task, error) {
-	url := fmt.Sprintf("%s/v1/tasks", agent.Address)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// deleteAgents removes the agents and the records bound to them
+func deleteAgents(tx *gorm.DB, uids []uuid.UUID) error {
+	if len(uids) == 0 {
+		return nil
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
-	if err != nil {
-		return nil, err
+	if err := tx.Where("agent_uuid IN ?", uids).Delete(&models.AgentHealthEvent{}).Error; err != nil {
+		return err
 	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
-
-	response, err := r.http.Do(req)
-	if err != nil {
-		return nil, err
+	if err := tx.Where("agent_uuid IN ?", uids).Delete(&models.AgentHealth{}).Error; err != nil {
+		return err
 	}
-
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to list tasks")
+	if err := tx.Where("agent_uuid IN ?", uids).Delete(&models.AgentPermission{}).Error; err != nil {
+		return err
 	}
+	if err := tx.Where("agent_uuid IN ?", uids).Delete(&models.TransferSample{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("uuid IN ?", uids).Delete(&models.Agent{}).Error
+}
 
-	var handler []models.TaskResponseModel
-	body, err := io.ReadAll(response.Body)
-	defer response.Body.Close()
+func (r *Repository) ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error) {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&handler); err != nil {
+	result, err := client.ListTasks(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	result := make([]*entities.Task, len(handler))
-	for i, item := range handler {
-		task := mappers.ToTask(item)
+	for _, task := range result {
 		task.Agent = agent
-		result[i] = task
 	}
 
 	return result, nil
 }
 
-func (r *Repository) CreateAgentTask(agent *entities.Agent, schema schemas.TaskCreateSchema) (*entities.Task, error) {
-	url := fmt.Sprintf("%s/v1/task", agent.Address)
-
-	payload, err := json.Marshal(schema)
+func (r *Repository) CreateAgentTask(ctx context.Context, agent *entities.Agent, schema schemas.TaskCreateSchema) (*entities.Task, error) {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	return client.CreateTask(ctx, schema)
+}
+
+func (r *Repository) PreviewAgentTask(ctx context.Context, agent *entities.Agent, schema schemas.TaskCreateSchema) (*entities.TaskPreview, error) {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	preview, err := client.PreviewTask(ctx, schema)
 	if err != nil {
 		return nil, err
 	}
+	if preview.Existing != nil {
+		preview.Existing.Agent = agent
+	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	return preview, nil
+}
 
-	response, err := r.http.Do(req)
+func (r *Repository) GetAgentTask(ctx context.Context, agent *entities.Agent, taskID string) (*entities.Task, error) {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to create task")
+	task, err := client.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	var handler models.TaskResponseModel
-	body, err := io.ReadAll(response.Body)
-	defer response.Body.Close()
+	task.Agent = agent
+
+	return task, nil
+}
+
+func (r *Repository) DeleteAgentTask(ctx context.Context, agent *entities.Agent, taskID string, purge bool) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&handler); err != nil {
-		return nil, err
+	if err := client.DeleteTask(ctx, taskID, purge); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	return mappers.ToTask(handler), nil
+	return nil
 }
 
-func (r *Repository) DeleteAgentTask(agent *entities.Agent, id string) error {
-	url := fmt.Sprintf("%s/v1/task/%s", agent.Address, id)
-
-	req, err := http.NewRequest("DELETE", url, nil)
+func (r *Repository) StopAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	if err := client.StopTask(ctx, taskID); err != nil {
+		return fmt.Errorf("failed to stop task: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) StartAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	if err := client.StartTask(ctx, taskID); err != nil {
+		return fmt.Errorf("failed to start task: %w", err)
+	}
 
-	response, err := r.http.Do(req)
+	return nil
+}
+
+func (r *Repository) ForceResumeAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return errors.New("failed to delete task")
+	if err := client.ForceResumeTask(ctx, taskID); err != nil {
+		return fmt.Errorf("failed to force resume task: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) PauseAgentTask(agent *entities.Agent, taskID string) error {
-	url := fmt.Sprintf("%s/v1/task/%s/pause", agent.Address, taskID)
-
-	req, err := http.NewRequest("POST", url, nil)
+func (r *Repository) SetAgentTaskShareLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetShareLimitSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	if err := client.SetTaskShareLimit(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task share limit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetAgentTaskLocation(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetLocationSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	if err := client.SetTaskLocation(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task location: %w", err)
+	}
+
+	return nil
+}
 
-	response, err := r.http.Do(req)
+func (r *Repository) RenameAgentTask(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskRenameSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return errors.New("failed to pause task")
+	if err := client.RenameTask(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to rename task: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) ResumeAgentTask(agent *entities.Agent, taskID string) error {
-	url := fmt.Sprintf("%s/v1/task/%s/resume", agent.Address, taskID)
-
-	req, err := http.NewRequest("POST", url, nil)
+func (r *Repository) SetAgentTaskSuperSeeding(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSuperSeedingSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	if err := client.SetTaskSuperSeeding(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task super seeding: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) ForceRecheckAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	if err := client.ForceRecheckTask(ctx, taskID); err != nil {
+		return fmt.Errorf("failed to force recheck task: %w", err)
+	}
 
-	response, err := r.http.Do(req)
+	return nil
+}
+
+func (r *Repository) ForceReannounceAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return errors.New("failed to resume task")
+	if err := client.ForceReannounceTask(ctx, taskID); err != nil {
+		return fmt.Errorf("failed to force reannounce task: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) ForceDownloadAgentTask(agent *entities.Agent, taskID string) error {
-	url := fmt.Sprintf("%s/v1/task/%s/force_download", agent.Address, taskID)
+func (r *Repository) SetAgentTaskDownloadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetDownloadLimitSchema) error {
+	client, err := r.clients.Get(agent)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SetTaskDownloadLimit(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task download limit: %w", err)
+	}
 
-	req, err := http.NewRequest("POST", url, nil)
+	return nil
+}
+
+func (r *Repository) SetAgentTaskUploadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetUploadLimitSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	decryptedToken, err := r.crypto.Decrypt(agent.Token)
+	if err := client.SetTaskUploadLimit(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task upload limit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetAgentTaskCategory(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetCategorySchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", decryptedToken))
+	if err := client.SetTaskCategory(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task category: %w", err)
+	}
 
-	response, err := r.http.Do(req)
+	return nil
+}
+
+func (r *Repository) SetAgentTaskTags(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetTagsSchema) error {
+	client, err := r.clients.Get(agent)
 	if err != nil {
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return errors.New("failed to force download task")
+	if err := client.SetTaskTags(ctx, taskID, schema); err != nil {
+		return fmt.Errorf("failed to set task tags: %w", err)
 	}
 
 	return nil
 }
 
+func (r *Repository) ListAgentTaskFiles(ctx context.Context, agent *entities.Agent, taskID string) ([]*entities.TaskFile, error) {
+	client, err := r.clients.Get(agent)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := client.ListTaskFiles(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task files: %w", err)
+	}
+
+	return files, nil
+}
+
+// StreamAgentEvents follows the live event stream of an agent until ctx is canceled or the stream fails
+func (r *Repository) StreamAgentEvents(ctx context.Context, agent *entities.Agent, fn func(event *entities.Event) error) error {
+	client, err := r.clients.Get(agent)
+	if err != nil {
+		return err
+	}
+
+	return client.StreamEvents(ctx, fn)
+}
+
 func toAgent(item models.Agent) *entities.Agent {
 	return &entities.Agent{
 		UUID:    item.UUID,
 		Name:    item.Name,
+		Type:    item.Type,
 		Address: item.Address,
 		Token:   item.EncrypetedToken,
 		Icon:    item.Icon,
 		Color:   item.Color,
+
+		CertificateFingerprint: item.CertificateFingerprint,
+		Client:                 item.Client,
 	}
 }
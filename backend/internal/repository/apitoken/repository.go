@@ -0,0 +1,116 @@
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateToken inserts a new API token, stored as the hash of the token
+func (r *Repository) CreateToken(ctx context.Context, token *entities.APIToken, tokenHash string) error {
+	model := &models.APIToken{
+		UserUUID:  token.UserUUID,
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		TokenHash: tokenHash,
+		Scopes:    models.StringArray(token.Scopes),
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+
+	token.ID = model.ID
+	token.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// GetTokenByHash retrieves an API token and its user by the hash of the token
+func (r *Repository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	var model models.APIToken
+	if err := r.db.DB.WithContext(ctx).
+		Preload("User").
+		Where("token_hash = ?", tokenHash).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgerrors.ErrAPITokenNotFound
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// ListUserTokens retrieves the API tokens of a user, expired ones included
+func (r *Repository) ListUserTokens(ctx context.Context, userUUID uuid.UUID) ([]*entities.APIToken, error) {
+	var items []models.APIToken
+	if err := r.db.DB.WithContext(ctx).
+		Where("user_uuid = ?", userUUID).
+		Order("created_at DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.APIToken, len(items))
+	for i, item := range items {
+		result[i] = ToEntity(item)
+	}
+
+	return result, nil
+}
+
+// DeleteToken removes an API token of a user
+func (r *Repository) DeleteToken(ctx context.Context, userUUID, id uuid.UUID) error {
+	result := r.db.DB.WithContext(ctx).
+		Where("id = ? AND user_uuid = ?", id, userUUID).
+		Delete(&models.APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return pkgerrors.ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+// TouchToken records the last use of an API token
+func (r *Repository) TouchToken(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.APIToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+// ToEntity converts a models.APIToken to entities.APIToken
+func ToEntity(model models.APIToken) *entities.APIToken {
+	return &entities.APIToken{
+		ID:         model.ID,
+		UserUUID:   model.UserUUID,
+		Name:       model.Name,
+		Prefix:     model.Prefix,
+		Scopes:     []string(model.Scopes),
+		ExpiresAt:  model.ExpiresAt,
+		LastUsedAt: model.LastUsedAt,
+		CreatedAt:  model.CreatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Filter selects audit events, zero values match everything
+type Filter struct {
+	Actions    []string
+	ActorUUID  uuid.UUID
+	IPAddress  string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// CreateEvent appends an event to the audit log
+func (r *Repository) CreateEvent(ctx context.Context, event *entities.AuditEvent) error {
+	model := &models.AuditEvent{
+		ID:         event.ID,
+		Action:     event.Action,
+		ActorUUID:  event.ActorUUID,
+		ActorEmail: event.ActorEmail,
+		IPAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Detail:     event.Detail,
+		CreatedAt:  event.CreatedAt,
+	}
+
+	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+
+	event.ID = model.ID
+	return nil
+}
+
+// ListEvents retrieves a page of the audit events matching the filter, newest
+// first, and the number of matching events
+func (r *Repository) ListEvents(ctx context.Context, filter Filter) ([]*entities.AuditEvent, int64, error) {
+	query := r.db.DB.WithContext(ctx).Model(&models.AuditEvent{})
+
+	if len(filter.Actions) > 0 {
+		query = query.Where("action IN ?", filter.Actions)
+	}
+	if filter.ActorUUID != uuid.Nil {
+		query = query.Where("actor_uuid = ?", filter.ActorUUID)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var handler []models.AuditEvent
+	if err := query.Find(&handler).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]*entities.AuditEvent, len(handler))
+	for i, item := range handler {
+		result[i] = &entities.AuditEvent{
+			ID:         item.ID,
+			Action:     item.Action,
+			ActorUUID:  item.ActorUUID,
+			ActorEmail: item.ActorEmail,
+			IPAddress:  item.IPAddress,
+			UserAgent:  item.UserAgent,
+			TargetType: item.TargetType,
+			TargetID:   item.TargetID,
+			Detail:     item.Detail,
+			CreatedAt:  item.CreatedAt,
+		}
+	}
+
+	return result, total, nil
+}
+
+// DeleteEventsBefore removes the audit events older than the given time and
+// returns how many were removed
+func (r *Repository) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.DB.WithContext(ctx).Where("created_at < ?", before.UTC()).Delete(&models.AuditEvent{})
+	return result.RowsAffected, result.Error
+}
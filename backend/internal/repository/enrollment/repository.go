@@ -0,0 +1,134 @@
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateToken stores an enrollment token, identified by the hash of its token
+func (r *Repository) CreateToken(ctx context.Context, token *entities.EnrollmentToken, tokenHash string) error {
+	model := &models.AgentEnrollmentToken{
+		Name:      token.Name,
+		TokenHash: tokenHash,
+		CreatedBy: token.CreatedBy,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+
+	token.ID = model.ID
+	token.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// ListTokens retrieves the enrollment tokens, used and expired ones included
+func (r *Repository) ListTokens(ctx context.Context) ([]*entities.EnrollmentToken, error) {
+	var items []models.AgentEnrollmentToken
+	if err := r.db.DB.WithContext(ctx).Order("created_at DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.EnrollmentToken, len(items))
+	for i, item := range items {
+		result[i] = ToEntity(item)
+	}
+
+	return result, nil
+}
+
+// DeleteToken removes an enrollment token not used yet
+func (r *Repository) DeleteToken(ctx context.Context, id uuid.UUID) error {
+	result := r.db.DB.WithContext(ctx).
+		Where("id = ? AND used_at IS NULL", id).
+		Delete(&models.AgentEnrollmentToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return pkgerrors.ErrEnrollmentTokenNotFound
+	}
+
+	return nil
+}
+
+// Enroll marks the unused and unexpired token with the given hash as used by
+// the agent and creates the agent, in one transaction: the token stays usable
+// when the agent cannot be created. A name given to the token replaces the
+// one of the agent. It returns the token.
+func (r *Repository) Enroll(ctx context.Context, tokenHash string, now time.Time, agent *models.Agent) (*entities.EnrollmentToken, error) {
+	var model models.AgentEnrollmentToken
+
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).First(&model).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return pkgerrors.ErrEnrollmentTokenInvalid
+			}
+			return err
+		}
+
+		// the condition on used_at keeps a concurrent enrollment from using it twice
+		result := tx.Model(&models.AgentEnrollmentToken{}).
+			Where("id = ? AND used_at IS NULL", model.ID).
+			Updates(map[string]any{"used_at": now, "agent_uuid": agent.UUID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return pkgerrors.ErrEnrollmentTokenInvalid
+		}
+
+		if model.Name != "" {
+			agent.Name = model.Name
+		}
+		if err := tx.Create(agent).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return fmt.Errorf("%w: agent %s already exists", pkgerrors.ErrInvalidInput, agent.Name)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	model.UsedAt = &now
+	model.AgentUUID = &agent.UUID
+	return ToEntity(model), nil
+}
+
+// ToEntity converts a models.AgentEnrollmentToken to entities.EnrollmentToken
+func ToEntity(model models.AgentEnrollmentToken) *entities.EnrollmentToken {
+	return &entities.EnrollmentToken{
+		ID:        model.ID,
+		Name:      model.Name,
+		CreatedBy: model.CreatedBy,
+		ExpiresAt: model.ExpiresAt,
+		UsedAt:    model.UsedAt,
+		AgentUUID: model.AgentUUID,
+		CreatedAt: model.CreatedAt,
+	}
+}
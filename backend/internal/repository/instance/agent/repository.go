@@ -15,11 +15,16 @@ type Repository struct {
 }
 
 func New() (*Repository, error) {
-	client, err := qbt.New(qbt.Config{
+	return NewWithConfig(qbt.Config{
 		BaseURL:  env.Get("QBITTORRENT_BASEURL").Value(),
 		Username: env.Get("QBITTORRENT_USERNAME").Value(),
 		Password: env.Get("QBITTORRENT_PASSWORD").Value(),
 	})
+}
+
+// NewWithConfig creates a repository of the qBittorrent instance set in the configuration
+func NewWithConfig(config qbt.Config) (*Repository, error) {
+	client, err := qbt.New(config)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +62,7 @@ func (s *Repository) GetInstance() (*entities.Instance, error) {
 
 	return &entities.Instance{
 		Application: entities.InstanceApplication{
+			Client:     entities.ClientQBittorrent,
 			Version:    version,
 			APIVersion: apiVersion,
 		},
@@ -0,0 +1,126 @@
+package instance
+
+import (
+	"context"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/pkg/errors"
+)
+
+// Repository reads and configures a Deluge daemon through the JSON-RPC API of its Web UI
+type Repository struct {
+	client *deluge.Client
+}
+
+func New() (*Repository, error) {
+	return NewWithClient(deluge.New(
+		env.Get("DELUGE_URL").Default("http://localhost:8112/json").Value(),
+		env.Get("DELUGE_PASSWORD").Default("deluge").Value(),
+		env.Get("DELUGE_HOST_ID").Value(),
+	)), nil
+}
+
+// NewWithClient creates the repository on top of an existing RPC client
+func NewWithClient(client *deluge.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+func (s *Repository) GetInstance() (*entities.Instance, error) {
+	ctx := context.Background()
+
+	version, err := s.client.Version(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get app version")
+	}
+
+	status, err := s.client.GetSessionStatus(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session status")
+	}
+
+	freeSpace, err := s.client.FreeSpace(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get free space")
+	}
+
+	return ToInstance(version, status, freeSpace), nil
+}
+
+func (s *Repository) GetPreferences(ctx context.Context) (*entities.InstancePreferences, error) {
+	config, err := s.client.GetConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get config")
+	}
+
+	return &entities.InstancePreferences{
+		GlobalRateLimits: entities.InstancePreferencesGlobalRateLimits{
+			DownloadSpeedLimit:        max(int(config.MaxDownloadSpeed*deluge.SpeedUnit), 0),
+			DownloadSpeedLimitEnabled: config.MaxDownloadSpeed > 0,
+			UploadSpeedLimit:          max(int(config.MaxUploadSpeed*deluge.SpeedUnit), 0),
+			UploadSpeedLimitEnabled:   config.MaxUploadSpeed > 0,
+		},
+	}, nil
+}
+
+func (s *Repository) Ping() error {
+	version, err := s.client.Version(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "failed to get app version")
+	}
+
+	if version == "" {
+		return errors.New("failed to get app version")
+	}
+
+	return nil
+}
+
+// SetDownloadSpeedLimit sets the global download limit in B/s, 0 removes it
+func (s *Repository) SetDownloadSpeedLimit(limit int) error {
+	if err := s.client.SetConfig(context.Background(), map[string]any{
+		"max_download_speed": deluge.SpeedLimit(limit),
+	}); err != nil {
+		return errors.Wrap(err, "failed to set download speed limit")
+	}
+
+	return nil
+}
+
+// SetUploadSpeedLimit sets the global upload limit in B/s, 0 removes it
+func (s *Repository) SetUploadSpeedLimit(limit int) error {
+	if err := s.client.SetConfig(context.Background(), map[string]any{
+		"max_upload_speed": deluge.SpeedLimit(limit),
+	}); err != nil {
+		return errors.Wrap(err, "failed to set upload speed limit")
+	}
+
+	return nil
+}
+
+// ToInstance maps the Deluge daemon status to an instance. Deluge keeps no
+// all-time totals, the transfer totals are the ones of the running daemon.
+func ToInstance(version string, status *deluge.SessionStatus, freeSpace int) *entities.Instance {
+	var ratio float64
+	if status.TotalPayloadDownload > 0 {
+		ratio = float64(status.TotalPayloadUpload) / float64(status.TotalPayloadDownload)
+	}
+
+	return &entities.Instance{
+		Application: entities.InstanceApplication{
+			Client:  entities.ClientDeluge,
+			Version: version,
+		},
+		Server: entities.InstanceServer{
+			FreeSpaceOnDisk: freeSpace,
+		},
+		Transfer: entities.InstanceTransfer{
+			AllTimeDownloaded: status.TotalPayloadDownload,
+			AllTimeUploaded:   status.TotalPayloadUpload,
+			GlobalRatio:       ratio,
+		},
+	}
+}
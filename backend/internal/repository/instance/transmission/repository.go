@@ -0,0 +1,129 @@
+package instance
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/pkg/errors"
+)
+
+// Repository reads and configures a Transmission daemon through its RPC API
+type Repository struct {
+	client *transmission.Client
+}
+
+func New() (*Repository, error) {
+	return NewWithClient(transmission.New(
+		env.Get("TRANSMISSION_URL").Default("http://localhost:9091/transmission/rpc").Value(),
+		env.Get("TRANSMISSION_USERNAME").Value(),
+		env.Get("TRANSMISSION_PASSWORD").Value(),
+	)), nil
+}
+
+// NewWithClient creates the repository on top of an existing RPC client
+func NewWithClient(client *transmission.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+func (s *Repository) GetInstance() (*entities.Instance, error) {
+	ctx := context.Background()
+
+	session, err := s.client.SessionGet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session")
+	}
+
+	stats, err := s.client.SessionStats(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session stats")
+	}
+
+	freeSpace, err := s.client.FreeSpace(ctx, session.DownloadDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get free space")
+	}
+
+	return ToInstance(session, stats, freeSpace), nil
+}
+
+func (s *Repository) GetPreferences(ctx context.Context) (*entities.InstancePreferences, error) {
+	session, err := s.client.SessionGet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session")
+	}
+
+	return &entities.InstancePreferences{
+		GlobalRateLimits: entities.InstancePreferencesGlobalRateLimits{
+			DownloadSpeedLimit:        session.SpeedLimitDown * transmission.SpeedUnit,
+			DownloadSpeedLimitEnabled: session.SpeedLimitDownEnabled,
+			UploadSpeedLimit:          session.SpeedLimitUp * transmission.SpeedUnit,
+			UploadSpeedLimitEnabled:   session.SpeedLimitUpEnabled,
+		},
+	}, nil
+}
+
+func (s *Repository) Ping() error {
+	session, err := s.client.SessionGet(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "failed to get session")
+	}
+
+	if session.Version == "" {
+		return errors.New("failed to get app version")
+	}
+
+	return nil
+}
+
+// SetDownloadSpeedLimit sets the global download limit in B/s, 0 removes it
+func (s *Repository) SetDownloadSpeedLimit(limit int) error {
+	if err := s.client.SessionSet(context.Background(), map[string]any{
+		"speed-limit-down":         transmission.SpeedLimit(limit),
+		"speed-limit-down-enabled": limit > 0,
+	}); err != nil {
+		return errors.Wrap(err, "failed to set download speed limit")
+	}
+
+	return nil
+}
+
+// SetUploadSpeedLimit sets the global upload limit in B/s, 0 removes it
+func (s *Repository) SetUploadSpeedLimit(limit int) error {
+	if err := s.client.SessionSet(context.Background(), map[string]any{
+		"speed-limit-up":         transmission.SpeedLimit(limit),
+		"speed-limit-up-enabled": limit > 0,
+	}); err != nil {
+		return errors.Wrap(err, "failed to set upload speed limit")
+	}
+
+	return nil
+}
+
+// ToInstance maps the Transmission session to an instance
+func ToInstance(session *transmission.Session, stats *transmission.Stats, freeSpace int) *entities.Instance {
+	var ratio float64
+	if stats.DownloadedBytes > 0 {
+		ratio = float64(stats.UploadedBytes) / float64(stats.DownloadedBytes)
+	}
+
+	return &entities.Instance{
+		Application: entities.InstanceApplication{
+			Client:     entities.ClientTransmission,
+			Version:    session.Version,
+			APIVersion: strconv.Itoa(session.RPCVersion),
+		},
+		Server: entities.InstanceServer{
+			FreeSpaceOnDisk: freeSpace,
+		},
+		Transfer: entities.InstanceTransfer{
+			AllTimeDownloaded: stats.DownloadedBytes,
+			AllTimeUploaded:   stats.UploadedBytes,
+			GlobalRatio:       ratio,
+		},
+	}
+}
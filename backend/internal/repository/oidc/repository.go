@@ -0,0 +1,85 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateState stores a single sign-on waiting for the identity provider
+func (r *Repository) CreateState(ctx context.Context, state *models.OIDCLoginState) error {
+	return r.db.DB.WithContext(ctx).Create(state).Error
+}
+
+// TakeState removes and returns a single sign-on by the hash of its state, nil
+// when missing. A state is only used once.
+func (r *Repository) TakeState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var model models.OIDCLoginState
+
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ?", stateHash).
+			First(&model).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.OIDCLoginState{}, "id = ?", model.ID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// DeleteExpiredStates removes the single sign-ons never completed
+func (r *Repository) DeleteExpiredStates(ctx context.Context, now time.Time) error {
+	return r.db.DB.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.OIDCLoginState{}).Error
+}
+
+// GetIdentity retrieves the link of a subject of an identity provider to a
+// user, nil when the subject is unknown
+func (r *Repository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var model models.UserIdentity
+	if err := r.db.DB.WithContext(ctx).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// CreateIdentity links a user to a subject of an identity provider
+func (r *Repository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.DB.WithContext(ctx).Create(identity).Error
+}
+
+// TouchIdentity records a login through an identity
+func (r *Repository) TouchIdentity(ctx context.Context, issuer, subject, email string, at time.Time) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.UserIdentity{}).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		Updates(map[string]any{"email": email, "last_login_at": at}).Error
+}
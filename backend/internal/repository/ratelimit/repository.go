@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository stores the rate limiting state in the database, shared by every
+// manager replica and kept across restarts
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Get retrieves the entry of a key, nil when missing
+func (r *Repository) Get(ctx context.Context, key string) (*entities.RateLimitEntry, error) {
+	var model models.RateLimit
+	if err := r.db.DB.WithContext(ctx).Where("key = ?", key).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return toEntity(model), nil
+}
+
+// Update loads the entry of a key and stores the entry fn returns in a single
+// transaction, the row being locked meanwhile. fn receives nil when the key is
+// missing and removes it by returning nil.
+func (r *Repository) Update(ctx context.Context, key string, fn func(entry *entities.RateLimitEntry) *entities.RateLimitEntry) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current *entities.RateLimitEntry
+
+		var model models.RateLimit
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&model).Error
+		switch {
+		case err == nil:
+			current = toEntity(model)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		next := fn(current)
+		if next == nil {
+			if current == nil {
+				return nil
+			}
+			return tx.Where("key = ?", key).Delete(&models.RateLimit{}).Error
+		}
+
+		// Two replicas may insert the same new key, the last write wins
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.RateLimit{
+			Key:          key,
+			Count:        next.Count,
+			WindowStart:  next.WindowStart,
+			BlockedUntil: next.BlockedUntil,
+			Tokens:       next.Tokens,
+			ExpiresAt:    next.ExpiresAt,
+		}).Error
+	})
+}
+
+// Delete removes the entry of a key
+func (r *Repository) Delete(ctx context.Context, key string) error {
+	return r.db.DB.WithContext(ctx).Where("key = ?", key).Delete(&models.RateLimit{}).Error
+}
+
+// List retrieves the entries whose key starts with prefix
+func (r *Repository) List(ctx context.Context, prefix string) ([]*entities.RateLimitEntry, error) {
+	var items []models.RateLimit
+	if err := r.db.DB.WithContext(ctx).
+		Where("key LIKE ? ESCAPE '\\'", escapeLike(prefix)+"%").
+		Order("key").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.RateLimitEntry, len(items))
+	for i, item := range items {
+		result[i] = toEntity(item)
+	}
+
+	return result, nil
+}
+
+// DeleteExpired removes the entries expired before the given time and returns
+// how many were removed
+func (r *Repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.DB.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RateLimit{})
+	return result.RowsAffected, result.Error
+}
+
+// escapeLike escapes the LIKE wildcards of a literal prefix
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// toEntity converts a models.RateLimit to entities.RateLimitEntry
+func toEntity(model models.RateLimit) *entities.RateLimitEntry {
+	return &entities.RateLimitEntry{
+		Key:          model.Key,
+		Count:        model.Count,
+		WindowStart:  model.WindowStart,
+		BlockedUntil: model.BlockedUntil,
+		Tokens:       model.Tokens,
+		ExpiresAt:    model.ExpiresAt,
+	}
+}
@@ -0,0 +1,283 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// ExecutionFilter selects audit entries, zero values match everything
+type ExecutionFilter struct {
+	RuleUUID  uuid.UUID
+	AgentUUID uuid.UUID
+	Limit     int
+}
+
+// CreateRule inserts a new rule
+func (r *Repository) CreateRule(ctx context.Context, rule entities.Rule) (*entities.Rule, error) {
+	model := toModel(rule)
+
+	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
+		if isDuplicate(err) {
+			return nil, errors.ErrRuleExists
+		}
+		return nil, err
+	}
+
+	return toRule(*model), nil
+}
+
+// ListRules retrieves every rule ordered by name
+func (r *Repository) ListRules(ctx context.Context) ([]*entities.Rule, error) {
+	var handler []models.Rule
+	if err := r.db.DB.WithContext(ctx).Order("name").Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.Rule, len(handler))
+	for i, item := range handler {
+		result[i] = toRule(item)
+	}
+
+	return result, nil
+}
+
+// GetRule retrieves a rule by its UUID
+func (r *Repository) GetRule(ctx context.Context, uid uuid.UUID) (*entities.Rule, error) {
+	var model models.Rule
+	if err := r.db.DB.WithContext(ctx).Where("uuid = ?", uid).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrRuleNotFound
+		}
+		return nil, err
+	}
+
+	return toRule(model), nil
+}
+
+// UpdateRule saves every field of an existing rule but its run time
+func (r *Repository) UpdateRule(ctx context.Context, rule entities.Rule) (*entities.Rule, error) {
+	model := toModel(rule)
+
+	result := r.db.DB.WithContext(ctx).Model(&models.Rule{UUID: rule.UUID}).
+		Select("name", "enabled", "dry_run", "interval_seconds", "conditions", "actions", "updated_at").
+		Updates(model)
+	if result.Error != nil {
+		if isDuplicate(result.Error) {
+			return nil, errors.ErrRuleExists
+		}
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.ErrRuleNotFound
+	}
+
+	return r.GetRule(ctx, rule.UUID)
+}
+
+// SetRuleLastRun records when a rule was last evaluated
+func (r *Repository) SetRuleLastRun(ctx context.Context, uid uuid.UUID, at time.Time) error {
+	return r.db.DB.WithContext(ctx).Model(&models.Rule{}).
+		Where("uuid = ?", uid).
+		UpdateColumn("last_run_at", at.UTC()).Error
+}
+
+// DeleteRule removes a rule. Its audit entries are kept.
+func (r *Repository) DeleteRule(ctx context.Context, uid uuid.UUID) error {
+	result := r.db.DB.WithContext(ctx).Where("uuid = ?", uid).Delete(&models.Rule{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.ErrRuleNotFound
+	}
+
+	return nil
+}
+
+// CreateExecutions appends entries to the rule audit log
+func (r *Repository) CreateExecutions(ctx context.Context, executions []*entities.RuleExecution) error {
+	if len(executions) == 0 {
+		return nil
+	}
+
+	handler := make([]models.RuleExecution, len(executions))
+	for i, item := range executions {
+		handler[i] = models.RuleExecution{
+			ID:        item.ID,
+			RuleUUID:  item.RuleUUID,
+			RuleName:  item.RuleName,
+			AgentUUID: item.AgentUUID,
+			AgentName: item.AgentName,
+			TaskHash:  item.TaskHash,
+			TaskName:  truncate(item.TaskName, 1000),
+			Action:    item.Action,
+			Detail:    truncate(item.Detail, 1000),
+			DryRun:    item.DryRun,
+			Error:     truncate(item.Error, 1000),
+			CreatedAt: item.CreatedAt.UTC(),
+		}
+	}
+
+	if err := r.db.DB.WithContext(ctx).CreateInBatches(handler, 500).Error; err != nil {
+		return err
+	}
+
+	for i, item := range handler {
+		executions[i].ID = item.ID
+	}
+
+	return nil
+}
+
+// ListExecutions retrieves audit entries, newest first
+func (r *Repository) ListExecutions(ctx context.Context, filter ExecutionFilter) ([]*entities.RuleExecution, error) {
+	query := r.db.DB.WithContext(ctx).Order("created_at DESC")
+
+	if filter.RuleUUID != uuid.Nil {
+		query = query.Where("rule_uuid = ?", filter.RuleUUID)
+	}
+	if filter.AgentUUID != uuid.Nil {
+		query = query.Where("agent_uuid = ?", filter.AgentUUID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var handler []models.RuleExecution
+	if err := query.Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.RuleExecution, len(handler))
+	for i, item := range handler {
+		result[i] = &entities.RuleExecution{
+			ID:        item.ID,
+			RuleUUID:  item.RuleUUID,
+			RuleName:  item.RuleName,
+			AgentUUID: item.AgentUUID,
+			AgentName: item.AgentName,
+			TaskHash:  item.TaskHash,
+			TaskName:  item.TaskName,
+			Action:    item.Action,
+			Detail:    item.Detail,
+			DryRun:    item.DryRun,
+			Error:     item.Error,
+			CreatedAt: item.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteExecutionsBefore removes the audit entries older than the given time
+func (r *Repository) DeleteExecutionsBefore(ctx context.Context, before time.Time) error {
+	return r.db.DB.WithContext(ctx).Where("created_at < ?", before.UTC()).Delete(&models.RuleExecution{}).Error
+}
+
+func toModel(rule entities.Rule) *models.Rule {
+	conditions := make([]models.RuleCondition, len(rule.Conditions))
+	for i, item := range rule.Conditions {
+		conditions[i] = models.RuleCondition{
+			Field:    item.Field,
+			Operator: item.Operator,
+			Value:    item.Value,
+			Values:   item.Values,
+		}
+	}
+
+	actions := make([]models.RuleAction, len(rule.Actions))
+	for i, item := range rule.Actions {
+		actions[i] = models.RuleAction{
+			Type:             item.Type,
+			DeleteFiles:      item.DeleteFiles,
+			Location:         item.Location,
+			Tags:             item.Tags,
+			Category:         item.Category,
+			RatioLimit:       item.RatioLimit,
+			SeedingTimeLimit: item.SeedingTimeLimit,
+			Limit:            item.Limit,
+		}
+	}
+
+	return &models.Rule{
+		UUID:            rule.UUID,
+		Name:            rule.Name,
+		Enabled:         rule.Enabled,
+		DryRun:          rule.DryRun,
+		IntervalSeconds: int64(rule.Interval / time.Second),
+		Conditions:      conditions,
+		Actions:         actions,
+		LastRunAt:       rule.LastRunAt,
+	}
+}
+
+func toRule(model models.Rule) *entities.Rule {
+	conditions := make([]entities.RuleCondition, len(model.Conditions))
+	for i, item := range model.Conditions {
+		conditions[i] = entities.RuleCondition{
+			Field:    item.Field,
+			Operator: item.Operator,
+			Value:    item.Value,
+			Values:   item.Values,
+		}
+	}
+
+	actions := make([]entities.RuleAction, len(model.Actions))
+	for i, item := range model.Actions {
+		actions[i] = entities.RuleAction{
+			Type:             item.Type,
+			DeleteFiles:      item.DeleteFiles,
+			Location:         item.Location,
+			Tags:             item.Tags,
+			Category:         item.Category,
+			RatioLimit:       item.RatioLimit,
+			SeedingTimeLimit: item.SeedingTimeLimit,
+			Limit:            item.Limit,
+		}
+	}
+
+	return &entities.Rule{
+		UUID:       model.UUID,
+		Name:       model.Name,
+		Enabled:    model.Enabled,
+		DryRun:     model.DryRun,
+		Interval:   time.Duration(model.IntervalSeconds) * time.Second,
+		Conditions: conditions,
+		Actions:    actions,
+		LastRunAt:  model.LastRunAt,
+		CreatedAt:  model.CreatedAt,
+		UpdatedAt:  model.UpdatedAt,
+	}
+}
+
+func isDuplicate(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed") ||
+		strings.Contains(err.Error(), "duplicate key")
+}
+
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	return value[:size]
+}
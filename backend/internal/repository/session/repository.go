@@ -8,6 +8,7 @@ import (
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/models"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -23,13 +24,15 @@ func NewRepository(db *database.Database) *Repository {
 }
 
 // CreateSession inserts a new session into the database
-func (r *Repository) CreateSession(ctx context.Context, userUUID uuid.UUID, token, userAgent, ipAddress string, expiresAt time.Time) (*entities.Session, error) {
+func (r *Repository) CreateSession(ctx context.Context, userUUID uuid.UUID, tokenHash, userAgent, ipAddress string, createdAt, expiresAt time.Time) (*entities.Session, error) {
 	model := &models.Session{
-		UserUUID:  userUUID,
-		Token:     token,
-		UserAgent: userAgent,
-		IPAddress: ipAddress,
-		ExpiresAt: expiresAt,
+		UserUUID:   userUUID,
+		TokenHash:  tokenHash,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastSeenAt: createdAt,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  createdAt,
 	}
 
 	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
@@ -39,15 +42,15 @@ func (r *Repository) CreateSession(ctx context.Context, userUUID uuid.UUID, toke
 	return toEntity(*model), nil
 }
 
-// GetSessionByToken retrieves a session by token
-func (r *Repository) GetSessionByToken(ctx context.Context, token string) (*models.Session, error) {
+// GetSessionByTokenHash retrieves a session by the hash of its token
+func (r *Repository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
 	var model models.Session
 	if err := r.db.DB.WithContext(ctx).
 		Preload("User").
-		Where("token = ?", token).
+		Where("token_hash = ?", tokenHash).
 		First(&model).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("session not found")
+			return nil, pkgerrors.ErrSessionNotFound
 		}
 		return nil, err
 	}
@@ -55,12 +58,28 @@ func (r *Repository) GetSessionByToken(ctx context.Context, token string) (*mode
 	return &model, nil
 }
 
-// GetUserSessions retrieves all sessions for a user
-func (r *Repository) GetUserSessions(ctx context.Context, userUUID uuid.UUID) ([]*entities.Session, error) {
+// GetSessionByID retrieves a session by its ID
+func (r *Repository) GetSessionByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	var model models.Session
+	if err := r.db.DB.WithContext(ctx).
+		Preload("User").
+		Where("id = ?", id).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgerrors.ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// GetUserSessions retrieves the sessions of a user still valid at the given time
+func (r *Repository) GetUserSessions(ctx context.Context, userUUID uuid.UUID, now time.Time) ([]*entities.Session, error) {
 	var models []models.Session
 	if err := r.db.DB.WithContext(ctx).
 		Where("user_uuid = ?", userUUID).
-		Where("expires_at > ?", time.Now()).
+		Where("expires_at > ?", now).
 		Order("created_at DESC").
 		Find(&models).Error; err != nil {
 		return nil, err
@@ -74,15 +93,43 @@ func (r *Repository) GetUserSessions(ctx context.Context, userUUID uuid.UUID) ([
 	return result, nil
 }
 
-// DeleteSession removes a session by token
-func (r *Repository) DeleteSession(ctx context.Context, token string) error {
-	result := r.db.DB.WithContext(ctx).Where("token = ?", token).Delete(&models.Session{})
+// TouchSession records the last use of a session and moves its expiry
+func (r *Repository) TouchSession(ctx context.Context, id uuid.UUID, lastSeenAt time.Time, ipAddress string, expiresAt time.Time) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"last_seen_at": lastSeenAt,
+			"ip_address":   ipAddress,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// DeleteSession removes a session by the hash of its token
+func (r *Repository) DeleteSession(ctx context.Context, tokenHash string) error {
+	result := r.db.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).Delete(&models.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return pkgerrors.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteSessionByID removes a session of a user by its ID
+func (r *Repository) DeleteSessionByID(ctx context.Context, userUUID, id uuid.UUID) error {
+	result := r.db.DB.WithContext(ctx).
+		Where("id = ? AND user_uuid = ?", id, userUUID).
+		Delete(&models.Session{})
 	if result.Error != nil {
 		return result.Error
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("session not found")
+		return pkgerrors.ErrSessionNotFound
 	}
 
 	return nil
@@ -93,20 +140,22 @@ func (r *Repository) DeleteUserSessions(ctx context.Context, userUUID uuid.UUID)
 	return r.db.DB.WithContext(ctx).Where("user_uuid = ?", userUUID).Delete(&models.Session{}).Error
 }
 
-// DeleteExpiredSessions removes all expired sessions (cleanup job)
-func (r *Repository) DeleteExpiredSessions(ctx context.Context) error {
-	return r.db.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.Session{}).Error
+// DeleteExpiredSessions removes the sessions expired before the given time
+// (cleanup job) and returns how many were removed
+func (r *Repository) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.DB.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
 }
 
 // toEntity converts a models.Session to entities.Session
 func toEntity(model models.Session) *entities.Session {
 	return &entities.Session{
-		ID:        model.ID,
-		UserUUID:  model.UserUUID,
-		Token:     model.Token,
-		UserAgent: model.UserAgent,
-		IPAddress: model.IPAddress,
-		ExpiresAt: model.ExpiresAt,
-		CreatedAt: model.CreatedAt,
+		ID:         model.ID,
+		UserUUID:   model.UserUUID,
+		UserAgent:  model.UserAgent,
+		IPAddress:  model.IPAddress,
+		LastSeenAt: model.LastSeenAt,
+		ExpiresAt:  model.ExpiresAt,
+		CreatedAt:  model.CreatedAt,
 	}
 }
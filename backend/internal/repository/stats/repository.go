@@ -0,0 +1,150 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// SampleFilter selects the samples of one resolution within [From, To)
+type SampleFilter struct {
+	Resolution string
+	AgentUUID  uuid.UUID // uuid.Nil matches every agent
+	Scope      string
+	TaskHash   string // ignored when empty
+	From       time.Time
+	To         time.Time
+}
+
+// CreateSamples inserts transfer samples in batches
+func (r *Repository) CreateSamples(ctx context.Context, samples []*entities.TransferSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	handler := make([]models.TransferSample, len(samples))
+	for i, item := range samples {
+		handler[i] = models.TransferSample{
+			AgentUUID:       item.AgentUUID,
+			Scope:           item.Scope,
+			TaskHash:        item.TaskHash,
+			Category:        item.Category,
+			Resolution:      item.Resolution,
+			Timestamp:       item.Timestamp.UTC(),
+			Downloaded:      item.Downloaded,
+			Uploaded:        item.Uploaded,
+			Ratio:           item.Ratio,
+			DownloadedDelta: item.DownloadedDelta,
+			UploadedDelta:   item.UploadedDelta,
+		}
+	}
+
+	return r.db.DB.WithContext(ctx).CreateInBatches(handler, 500).Error
+}
+
+// ListSamples retrieves the samples matching the filter, oldest first
+func (r *Repository) ListSamples(ctx context.Context, filter SampleFilter) ([]*entities.TransferSample, error) {
+	query := r.db.DB.WithContext(ctx).
+		Where("resolution = ?", filter.Resolution).
+		Where("timestamp >= ? AND timestamp < ?", filter.From.UTC(), filter.To.UTC())
+
+	if filter.AgentUUID != uuid.Nil {
+		query = query.Where("agent_uuid = ?", filter.AgentUUID)
+	}
+	if filter.Scope != "" {
+		query = query.Where("scope = ?", filter.Scope)
+	}
+	if filter.TaskHash != "" {
+		query = query.Where("task_hash = ?", filter.TaskHash)
+	}
+
+	var handler []models.TransferSample
+	if err := query.Order("timestamp ASC").Order("id ASC").Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	return toSamples(handler), nil
+}
+
+// ListLatestSamples retrieves the raw samples of the last collection round of an agent
+func (r *Repository) ListLatestSamples(ctx context.Context, agentUUID uuid.UUID) ([]*entities.TransferSample, error) {
+	var last models.TransferSample
+	result := r.db.DB.WithContext(ctx).
+		Where("resolution = ? AND agent_uuid = ?", entities.TransferResolutionRaw, agentUUID).
+		Order("timestamp DESC").
+		Limit(1).
+		Find(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var handler []models.TransferSample
+	if err := r.db.DB.WithContext(ctx).
+		Where("resolution = ? AND agent_uuid = ? AND timestamp = ?", entities.TransferResolutionRaw, agentUUID, last.Timestamp).
+		Find(&handler).Error; err != nil {
+		return nil, err
+	}
+
+	return toSamples(handler), nil
+}
+
+// LastTimestamp returns the timestamp of the most recent sample of a resolution.
+// The boolean is false when the resolution has no sample yet.
+func (r *Repository) LastTimestamp(ctx context.Context, resolution string) (time.Time, bool, error) {
+	var last models.TransferSample
+	result := r.db.DB.WithContext(ctx).
+		Where("resolution = ?", resolution).
+		Order("timestamp DESC").
+		Limit(1).
+		Find(&last)
+	if result.Error != nil {
+		return time.Time{}, false, result.Error
+	}
+
+	return last.Timestamp, result.RowsAffected > 0, nil
+}
+
+// DeleteSamplesBefore removes the samples of a resolution older than the given time
+func (r *Repository) DeleteSamplesBefore(ctx context.Context, resolution string, before time.Time) error {
+	return r.db.DB.WithContext(ctx).
+		Where("resolution = ? AND timestamp < ?", resolution, before.UTC()).
+		Delete(&models.TransferSample{}).Error
+}
+
+func toSamples(handler []models.TransferSample) []*entities.TransferSample {
+	result := make([]*entities.TransferSample, len(handler))
+	for i, item := range handler {
+		result[i] = &entities.TransferSample{
+			AgentUUID:       item.AgentUUID,
+			Scope:           item.Scope,
+			TaskHash:        item.TaskHash,
+			Category:        item.Category,
+			Resolution:      item.Resolution,
+			Timestamp:       item.Timestamp.UTC(),
+			Downloaded:      item.Downloaded,
+			Uploaded:        item.Uploaded,
+			Ratio:           item.Ratio,
+			DownloadedDelta: item.DownloadedDelta,
+			UploadedDelta:   item.UploadedDelta,
+		}
+	}
+
+	return result
+}
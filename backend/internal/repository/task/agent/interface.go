@@ -24,4 +24,6 @@ type RepositoryInterface interface {
 	SetDownloadLimit(hash string, schema schemas.TaskSetDownloadLimitSchema) error
 	SetUploadLimit(hash string, schema schemas.TaskSetUploadLimitSchema) error
 	ListFiles(hash string) ([]*entities.TaskFile, error)
+	SetCategory(hash string, schema schemas.TaskSetCategorySchema) error
+	ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error
 }
@@ -1,33 +1,51 @@
 package task
 
 import (
+	"context"
 	"strings"
+	"time"
 
 	"github.com/gardarr/gardarr/cmd/constants"
 	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/qbtsync"
 	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/gardarr/gardarr/pkg/torrent"
 	"github.com/jfxdev/go-qbt"
 
 	"github.com/gardarr/gardarr/pkg/errors"
 )
 
+// addAttempts and addRetryDelay bound the wait for an added task to be listed
+const (
+	addAttempts   = 10
+	addRetryDelay = 200 * time.Millisecond
+)
+
 type Repository struct {
 	client *qbt.Client
+	// web covers the Web API calls missing from go-qbt
+	web *qbtsync.Client
 }
 
 func New() (*Repository, error) {
-	client, err := qbt.New(qbt.Config{
+	return NewWithConfig(qbt.Config{
 		BaseURL:  env.Get("QBITTORRENT_BASEURL").Value(),
 		Username: env.Get("QBITTORRENT_USERNAME").Value(),
 		Password: env.Get("QBITTORRENT_PASSWORD").Value(),
 	})
+}
+
+// NewWithConfig creates a repository of the qBittorrent instance set in the configuration
+func NewWithConfig(config qbt.Config) (*Repository, error) {
+	client, err := qbt.New(config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Repository{
 		client: client,
+		web:    qbtsync.New(config.BaseURL, config.Username, config.Password),
 	}, nil
 }
 
@@ -60,39 +78,66 @@ func (s *Repository) Get(hash string) (*entities.Task, error) {
 	return nil, errors.ErrTaskNotFound
 }
 
+// Add adds a magnet link or a .torrent file, then waits for qBittorrent to
+// list the task, which it adds asynchronously
 func (s *Repository) Add(schema schemas.TaskCreateSchema) (*entities.Task, error) {
-	if err := s.client.AddTorrentLink(qbt.TorrentConfig{
-		MagnetURI: schema.MagnetURI,
-		Category:  schema.Category,
-		Directory: schema.Directory,
+	var matches func(item *qbt.TorrentResponse) bool
+	if len(schema.Torrent) > 0 {
+		meta, err := torrent.ParseMetaInfo(schema.Torrent)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse torrent")
+		}
+		matches = func(item *qbt.TorrentResponse) bool { return meta.InfoHash.Matches(item.Hash) }
+	} else {
+		uri, err := qbt.ParseMagnetLink(schema.MagnetURI)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse magnet link")
+		}
+		matches = func(item *qbt.TorrentResponse) bool { return strings.EqualFold(item.MagnetLink.Hash, uri.Hash) }
+	}
+
+	ctx := context.Background()
+
+	if err := s.web.AddTorrent(ctx, qbtsync.AddOptions{
+		MagnetURI:          schema.MagnetURI,
+		Torrent:            schema.Torrent,
+		SavePath:           schema.Directory,
+		Category:           schema.Category,
+		Tags:               schema.Tags,
+		Paused:             schema.Paused,
+		SkipChecking:       schema.SkipChecking,
+		SequentialDownload: schema.SequentialDownload,
+		ContentLayout:      schema.ContentLayout,
 	}); err != nil {
 		return nil, errors.Wrap(err, "failed to add task")
 	}
 
-	list, err := s.client.ListTorrents(qbt.ListOptions{
-		Category: schema.Category,
-	})
-	if err != nil {
-		return nil, err
-	}
+	for attempt := 0; attempt < addAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(addRetryDelay)
+		}
 
-	uri, err := qbt.ParseMagnetLink(schema.MagnetURI)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse magnet link")
-	}
+		list, err := s.client.ListTorrents(qbt.ListOptions{
+			Category: schema.Category,
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	var task *qbt.TorrentResponse
-	for _, item := range list {
-		if strings.EqualFold(item.MagnetLink.Hash, uri.Hash) {
-			task = item
-			if err := s.client.AddTorrentTags(task.Hash, schema.Tags); err != nil {
-				return nil, err
+		for _, item := range list {
+			if !matches(item) {
+				continue
 			}
-			break
+			if len(schema.FilePriorities) > 0 {
+				if err := s.web.SetFilePriorities(ctx, item.Hash, schema.FilePriorities); err != nil {
+					return nil, errors.Wrap(err, "failed to set file priorities")
+				}
+			}
+			return toTask(item), nil
 		}
 	}
 
-	return toTask(task), nil
+	return nil, errors.Wrap(errors.ErrTaskNotFound, "failed to add task")
 }
 
 func (s *Repository) Stop(hash string) error {
@@ -166,6 +211,22 @@ func (s *Repository) Rename(hash string, schema schemas.TaskRenameSchema) error
 	return nil
 }
 
+func (s *Repository) SetCategory(hash string, schema schemas.TaskSetCategorySchema) error {
+	if err := s.web.SetCategory(context.Background(), hash, schema.Category); err != nil {
+		return errors.Wrap(err, "failed to set torrent category")
+	}
+
+	return nil
+}
+
+func (s *Repository) ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error {
+	if err := s.web.ReplaceTags(context.Background(), hash, schema.Tags); err != nil {
+		return errors.Wrap(err, "failed to set torrent tags")
+	}
+
+	return nil
+}
+
 func ParseMagnetLink(magnetURI string) (*entities.TaskMagnetLink, error) {
 	uri, err := qbt.ParseMagnetLink(magnetURI)
 	if err != nil {
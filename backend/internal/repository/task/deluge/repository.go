@@ -0,0 +1,365 @@
+package task
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/gardarr/gardarr/cmd/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/gardarr/gardarr/pkg/torrent"
+
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+// Repository drives the tasks of a Deluge daemon through the JSON-RPC API of its Web UI
+type Repository struct {
+	client *deluge.Client
+}
+
+func New() (*Repository, error) {
+	return NewWithClient(deluge.New(
+		env.Get("DELUGE_URL").Default("http://localhost:8112/json").Value(),
+		env.Get("DELUGE_PASSWORD").Default("deluge").Value(),
+		env.Get("DELUGE_HOST_ID").Value(),
+	)), nil
+}
+
+// NewWithClient creates the repository on top of an existing RPC client
+func NewWithClient(client *deluge.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+func (s *Repository) List() ([]*entities.Task, error) {
+	items, err := s.client.TorrentsStatus(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.Task, 0, len(items))
+	for hash, item := range items {
+		item.Hash = hash
+		result = append(result, ToTask(item))
+	}
+
+	return result, nil
+}
+
+func (s *Repository) Get(hash string) (*entities.Task, error) {
+	item, err := s.get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToTask(*item), nil
+}
+
+// Add adds a magnet link or a .torrent file. Deluge has no tags, the tags of
+// the task are not kept.
+func (s *Repository) Add(schema schemas.TaskCreateSchema) (*entities.Task, error) {
+	if schema.ContentLayout != "" {
+		return nil, errors.Wrap(errors.ErrNotSupported, "deluge has no content layouts")
+	}
+
+	options := map[string]any{}
+	if schema.Directory != "" {
+		options["download_location"] = schema.Directory
+	}
+	if schema.Paused {
+		options["add_paused"] = true
+	}
+	if schema.SkipChecking {
+		options["seed_mode"] = true
+	}
+	if schema.SequentialDownload {
+		options["sequential_download"] = true
+	}
+	if len(schema.FilePriorities) > 0 {
+		// Deluge goes from 0 skipped to 7 high, 4 being normal
+		priorities := make([]int, len(schema.FilePriorities))
+		for i, priority := range schema.FilePriorities {
+			if priority == 1 {
+				priority = 4
+			}
+			priorities[i] = priority
+		}
+		options["file_priorities"] = priorities
+	}
+
+	ctx := context.Background()
+
+	var hash, known string
+	if len(schema.Torrent) > 0 {
+		meta, err := torrent.ParseMetaInfo(schema.Torrent)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse torrent")
+		}
+		known = meta.InfoHash.ID()
+
+		hash, err = s.client.AddTorrentFile(ctx, meta.Name+".torrent", schema.Torrent, options)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to add task")
+		}
+	} else {
+		link, err := taskRepository.ParseMagnetLink(schema.MagnetURI)
+		if err != nil {
+			return nil, err
+		}
+		known = strings.ToLower(link.Hash)
+
+		hash, err = s.client.AddMagnet(ctx, schema.MagnetURI, options)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to add task")
+		}
+	}
+	if hash == "" {
+		// already known to Deluge
+		hash = known
+	}
+
+	if schema.Category != "" {
+		if err := s.SetCategory(hash, schemas.TaskSetCategorySchema{Category: schema.Category}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Get(hash)
+}
+
+func (s *Repository) Stop(hash string) error {
+	if err := s.client.TorrentsAction(context.Background(), "core.pause_torrents", hash); err != nil {
+		return errors.Wrap(err, "failed to stop torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) Start(hash string) error {
+	if err := s.client.TorrentsAction(context.Background(), "core.resume_torrents", hash); err != nil {
+		return errors.Wrap(err, "failed to start torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) ForceResume(hash string) error {
+	return errors.Wrap(errors.ErrNotSupported, "deluge has no forced start")
+}
+
+func (s *Repository) Delete(id string, deleteFiles bool) error {
+	if err := s.client.RemoveTorrent(context.Background(), id, deleteFiles); err != nil {
+		return errors.Wrap(err, "failed to delete torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetTags(hash string, tags []string) error {
+	return errors.Wrap(errors.ErrNotSupported, "deluge has no tags")
+}
+
+func (s *Repository) ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error {
+	return errors.Wrap(errors.ErrNotSupported, "deluge has no tags")
+}
+
+// SetShareLimit sets the ratio at which seeding stops. Deluge has no seeding
+// time limit per torrent, the seeding time limit is ignored.
+func (s *Repository) SetShareLimit(schema schemas.TaskSetShareLimitSchema) error {
+	if err := s.client.SetTorrentOptions(context.Background(), map[string]any{
+		"stop_at_ratio": schema.RatioLimit > 0,
+		"stop_ratio":    schema.RatioLimit,
+	}, schema.Hash); err != nil {
+		return errors.Wrap(err, "failed to set torrent share limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetLocation(hash string, schema schemas.TaskSetLocationSchema) error {
+	if err := s.client.MoveStorage(context.Background(), schema.Location, hash); err != nil {
+		return errors.Wrap(err, "failed to set torrent location")
+	}
+
+	return nil
+}
+
+// Rename changes the name Deluge displays for the torrent, the files keep their names
+func (s *Repository) Rename(hash string, schema schemas.TaskRenameSchema) error {
+	if err := s.client.SetTorrentOptions(context.Background(), map[string]any{"name": schema.NewName}, hash); err != nil {
+		return errors.Wrap(err, "failed to rename torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetSuperSeeding(hash string, schema schemas.TaskSuperSeedingSchema) error {
+	if err := s.client.SetTorrentOptions(context.Background(), map[string]any{"super_seeding": schema.Enabled}, hash); err != nil {
+		return errors.Wrap(err, "failed to set super seeding")
+	}
+
+	return nil
+}
+
+// SetCategory sets the label of the torrent, creating the label when it does not exist.
+// The Label plugin only accepts lowercase labels.
+func (s *Repository) SetCategory(hash string, schema schemas.TaskSetCategorySchema) error {
+	ctx := context.Background()
+	label := strings.ToLower(strings.TrimSpace(schema.Category))
+
+	if label != "" {
+		labels, err := s.client.Labels(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list labels")
+		}
+		if !slices.Contains(labels, label) {
+			if err := s.client.AddLabel(ctx, label); err != nil {
+				return errors.Wrap(err, "failed to create label")
+			}
+		}
+	}
+
+	if err := s.client.SetTorrentLabel(ctx, hash, label); err != nil {
+		return errors.Wrap(err, "failed to set torrent category")
+	}
+
+	return nil
+}
+
+func (s *Repository) ForceRecheck(hash string) error {
+	if err := s.client.TorrentsAction(context.Background(), "core.force_recheck", hash); err != nil {
+		return errors.Wrap(err, "failed to force recheck torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) ForceReannounce(hash string) error {
+	if err := s.client.TorrentsAction(context.Background(), "core.force_reannounce", hash); err != nil {
+		return errors.Wrap(err, "failed to force reannounce torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetDownloadLimit(hash string, schema schemas.TaskSetDownloadLimitSchema) error {
+	if err := s.client.SetTorrentOptions(context.Background(), map[string]any{
+		"max_download_speed": deluge.SpeedLimit(schema.Limit),
+	}, hash); err != nil {
+		return errors.Wrap(err, "failed to set torrent download limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetUploadLimit(hash string, schema schemas.TaskSetUploadLimitSchema) error {
+	if err := s.client.SetTorrentOptions(context.Background(), map[string]any{
+		"max_upload_speed": deluge.SpeedLimit(schema.Limit),
+	}, hash); err != nil {
+		return errors.Wrap(err, "failed to set torrent upload limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) ListFiles(hash string) ([]*entities.TaskFile, error) {
+	files, progress, priorities, err := s.client.TorrentFiles(context.Background(), hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list torrent files")
+	}
+
+	result := make([]*entities.TaskFile, len(files))
+	for i, file := range files {
+		item := &entities.TaskFile{
+			Name: file.Path,
+			Size: int64(file.Size),
+			// the qBittorrent scale: 0 skipped, 1 normal, 6 high
+			Priority: 1,
+		}
+		if i < len(progress) {
+			item.Progress = progress[i]
+			item.IsSeed = progress[i] >= 1
+		}
+		if i < len(priorities) {
+			// Deluge goes from 0 skipped to 7 high, 4 being normal
+			switch {
+			case priorities[i] == 0:
+				item.Priority = 0
+			case priorities[i] > 4:
+				item.Priority = 6
+			}
+		}
+		result[i] = item
+	}
+
+	return result, nil
+}
+
+func (s *Repository) get(hash string) (*deluge.Torrent, error) {
+	items, err := s.client.TorrentsStatus(context.Background(), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	item, ok := items[hash]
+	if !ok {
+		return nil, errors.ErrTaskNotFound
+	}
+	item.Hash = hash
+
+	return &item, nil
+}
+
+// ToTask maps a Deluge torrent to a task
+func ToTask(item deluge.Torrent) *entities.Task {
+	status := entities.TaskStatuses[constants.UnknownStatus]
+	if value, ok := entities.TaskStatuses[item.State()]; ok {
+		status = value
+	}
+
+	magnetURI := item.MagnetURI()
+
+	var magnetLink entities.TaskMagnetLink
+	if link, err := taskRepository.ParseMagnetLink(magnetURI); err == nil {
+		magnetLink = *link
+	}
+
+	return &entities.Task{
+		ID:         item.Hash,
+		Name:       item.Name,
+		Hash:       item.Hash,
+		Category:   item.Label,
+		Path:       item.DownloadLocation,
+		State:      status,
+		Size:       item.TotalWanted,
+		Priority:   item.Queue,
+		Ratio:      max(item.Ratio, 0),
+		Progress:   item.Progress,
+		MagnetURI:  magnetURI,
+		MagnetLink: magnetLink,
+		Pairs: entities.TaskPairs{
+			SwarmSeeders:  item.TotalSeeds,
+			SwarmLeechers: item.TotalPeers,
+			Seeders:       item.NumSeeds,
+			Leechers:      item.NumPeers,
+		},
+		NumSeeds:    item.NumSeeds,
+		SeedingTime: item.SeedingTime,
+		Tracker:     item.Tracker,
+		Network: entities.TaskNetwork{
+			Download: entities.TaskDownload{
+				Speed:  item.DownloadPayloadRate,
+				Amount: item.AllTimeDownload,
+			},
+			Upload: entities.TaskUpload{
+				Speed:  item.UploadPayloadRate,
+				Amount: item.TotalUploaded,
+			},
+		},
+	}
+}
@@ -0,0 +1,299 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+const hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// fakeDeluge answers the JSON-RPC calls of a Web UI with scripted torrents and
+// records the calls made to the daemon
+type fakeDeluge struct {
+	mu        sync.Mutex
+	torrents  map[string]map[string]any
+	labels    []string
+	session   string
+	connected bool
+	logins    int
+	calls     []string
+	params    [][]any
+}
+
+func (f *fakeDeluge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		ID     int    `json:"id"`
+		Method string `json:"method"`
+		Params []any  `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	answer := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"id": req.ID, "result": result, "error": nil})
+	}
+
+	if req.Method == "auth.login" {
+		if req.Params[0] != "secret" {
+			answer(false)
+			return
+		}
+		f.logins++
+		http.SetCookie(w, &http.Cookie{Name: "_session_id", Value: f.session})
+		answer(true)
+		return
+	}
+
+	if cookie, err := r.Cookie("_session_id"); err != nil || cookie.Value != f.session {
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":     req.ID,
+			"result": nil,
+			"error":  map[string]any{"message": "Not authenticated", "code": deluge.ErrorCodeNotAuthenticated},
+		})
+		return
+	}
+
+	switch req.Method {
+	case "web.connected":
+		answer(f.connected)
+	case "web.get_hosts":
+		answer([][]any{{"host1", "127.0.0.1", 58846, "localclient"}})
+	case "web.connect":
+		f.connected = req.Params[0] == "host1"
+		answer([]string{})
+	case "core.get_torrents_status":
+		answer(f.torrents)
+	case "label.get_labels":
+		answer(f.labels)
+	case "core.add_torrent_magnet", "core.add_torrent_file":
+		f.calls = append(f.calls, req.Method)
+		f.params = append(f.params, req.Params)
+		answer(hashA)
+	default:
+		f.calls = append(f.calls, req.Method)
+		f.params = append(f.params, req.Params)
+		answer(nil)
+	}
+}
+
+func setupRepository(t *testing.T) (*Repository, *fakeDeluge) {
+	t.Helper()
+
+	fake := &fakeDeluge{
+		torrents: map[string]map[string]any{
+			hashA: {
+				"name":                  "Some.Linux.ISO",
+				"state":                 "Seeding",
+				"progress":              100,
+				"is_finished":           true,
+				"total_wanted":          2048,
+				"ratio":                 1.5,
+				"download_location":     "/downloads",
+				"label":                 "linux",
+				"tracker":               "https://tracker.example.org/announce",
+				"total_seeds":           40,
+				"num_peers":             2,
+				"seeding_time":          3600,
+				"upload_payload_rate":   512,
+				"total_uploaded":        3072,
+				"download_payload_rate": 0,
+			},
+		},
+		labels:  []string{"linux"},
+		session: "session",
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return NewWithClient(deluge.New(server.URL+"/json", "secret", "")), fake
+}
+
+func TestRepository_List(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	tasks, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+
+	if fake.logins != 1 || !fake.connected {
+		t.Errorf("Expected one login and the daemon connected, got %d logins, connected %v", fake.logins, fake.connected)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tasks))
+	}
+
+	task := tasks[0]
+	if task.ID != hashA || task.State != "UPLOADING" || task.Progress != 100 {
+		t.Errorf("Unexpected task %+v", task)
+	}
+	if task.Category != "linux" || task.Path != "/downloads" || task.SeedingTime != 3600 {
+		t.Errorf("Expected label, location and seeding time, got %+v", task)
+	}
+	if task.Pairs.SwarmSeeders != 40 || task.Pairs.Leechers != 2 || task.Network.Upload.Amount != 3072 {
+		t.Errorf("Expected swarm and transfer, got %+v", task)
+	}
+	if task.MagnetLink.Hash == "" {
+		t.Errorf("Expected a magnet link built from the hash, got %q", task.MagnetURI)
+	}
+
+	if _, err := repo.List(); err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if fake.logins != 1 {
+		t.Errorf("Expected the session to be kept, got %d logins", fake.logins)
+	}
+
+	// the Web UI was restarted, the cookie is no longer valid
+	fake.mu.Lock()
+	fake.session = "restarted"
+	fake.mu.Unlock()
+
+	if _, err := repo.List(); err != nil {
+		t.Fatalf("Expected the session to be opened again, got %v", err)
+	}
+	if fake.logins != 2 {
+		t.Errorf("Expected a second login, got %d", fake.logins)
+	}
+}
+
+func TestRepository_State(t *testing.T) {
+	tests := []struct {
+		torrent  deluge.Torrent
+		expected string
+	}{
+		{deluge.Torrent{Status: deluge.StatePaused, IsFinished: true}, "pausedUP"},
+		{deluge.Torrent{Status: deluge.StatePaused}, "pausedDL"},
+		{deluge.Torrent{Status: deluge.StateDownloading, NumSeeds: 3}, "downloading"},
+		{deluge.Torrent{Status: deluge.StateDownloading}, "stalledDL"},
+		{deluge.Torrent{Status: deluge.StateSeeding, NumPeers: 1}, "uploading"},
+		{deluge.Torrent{Status: deluge.StateSeeding}, "stalledUP"},
+		{deluge.Torrent{Status: deluge.StateQueued}, "queuedDL"},
+		{deluge.Torrent{Status: deluge.StateChecking, IsFinished: true}, "checkingUP"},
+		{deluge.Torrent{Status: deluge.StateMoving}, "moving"},
+		{deluge.Torrent{Status: deluge.StateError}, "error"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.torrent.State(); got != tt.expected {
+			t.Errorf("State(%+v): expected %s, got %s", tt.torrent, tt.expected, got)
+		}
+	}
+}
+
+func TestRepository_Edits(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	if err := repo.Stop(hashA); err != nil {
+		t.Fatalf("Failed to stop task: %v", err)
+	}
+	if err := repo.SetLocation(hashA, schemas.TaskSetLocationSchema{Location: "/archive"}); err != nil {
+		t.Fatalf("Failed to move task: %v", err)
+	}
+	if err := repo.SetUploadLimit(hashA, schemas.TaskSetUploadLimitSchema{Limit: 51200}); err != nil {
+		t.Fatalf("Failed to set upload limit: %v", err)
+	}
+	if err := repo.SetCategory(hashA, schemas.TaskSetCategorySchema{Category: "TV"}); err != nil {
+		t.Fatalf("Failed to set category: %v", err)
+	}
+
+	expected := []string{"core.pause_torrents", "core.move_storage", "core.set_torrent_options", "label.add", "label.set_torrent"}
+	if len(fake.calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, fake.calls)
+	}
+	for i, method := range expected {
+		if fake.calls[i] != method {
+			t.Errorf("Expected call %d to be %s, got %s", i, method, fake.calls[i])
+		}
+	}
+
+	if fake.params[1][1] != "/archive" {
+		t.Errorf("Expected the move to /archive, got %v", fake.params[1])
+	}
+	if options, _ := json.Marshal(fake.params[2][1]); string(options) != `{"max_upload_speed":50}` {
+		t.Errorf("Expected a 50 KiB/s upload limit, got %s", options)
+	}
+	if fake.params[3][0] != "tv" || fake.params[4][1] != "tv" {
+		t.Errorf("Expected the lowercase label created and set, got %v and %v", fake.params[3], fake.params[4])
+	}
+}
+
+func TestRepository_Add(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	task, err := repo.Add(schemas.TaskCreateSchema{
+		MagnetURI: "magnet:?xt=urn:btih:" + hashA + "&dn=Some.Linux.ISO",
+		Directory: "/downloads",
+		Category:  "linux",
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if task.ID != hashA {
+		t.Errorf("Expected task %s, got %+v", hashA, task)
+	}
+
+	if len(fake.calls) != 2 || fake.calls[0] != "core.add_torrent_magnet" || fake.calls[1] != "label.set_torrent" {
+		t.Fatalf("Expected the magnet added then labeled, got %v", fake.calls)
+	}
+	if options, _ := json.Marshal(fake.params[0][1]); string(options) != `{"download_location":"/downloads"}` {
+		t.Errorf("Expected the download location, got %s", options)
+	}
+}
+
+func TestRepository_AddTorrentFile(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	data := []byte("d4:infod6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee")
+	if _, err := repo.Add(schemas.TaskCreateSchema{
+		Torrent:        data,
+		Paused:         true,
+		SkipChecking:   true,
+		FilePriorities: []int{1},
+	}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	if len(fake.calls) != 1 || fake.calls[0] != "core.add_torrent_file" {
+		t.Fatalf("Expected the torrent file added, got %v", fake.calls)
+	}
+	if fake.params[0][0] != "file.iso.torrent" || fake.params[0][1] != base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("Expected the base64 torrent file, got %v", fake.params[0][:2])
+	}
+	if options, _ := json.Marshal(fake.params[0][2]); string(options) != `{"add_paused":true,"file_priorities":[4],"seed_mode":true}` {
+		t.Errorf("Expected the add options on the Deluge scale, got %s", options)
+	}
+
+	if _, err := repo.Add(schemas.TaskCreateSchema{Torrent: data, ContentLayout: "NoSubfolder"}); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for a content layout, got %v", err)
+	}
+}
+
+func TestRepository_UnsupportedActions(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	if err := repo.SetTags(hashA, []string{"hd"}); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for tags, got %v", err)
+	}
+	if err := repo.ForceResume(hashA); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for forced start, got %v", err)
+	}
+
+	if len(fake.calls) != 0 {
+		t.Errorf("Expected no call to Deluge, got %v", fake.calls)
+	}
+}
@@ -0,0 +1,339 @@
+package task
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gardarr/gardarr/cmd/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/env"
+
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+// Repository drives the tasks of a Transmission daemon through its RPC API
+type Repository struct {
+	client *transmission.Client
+}
+
+func New() (*Repository, error) {
+	return NewWithClient(transmission.New(
+		env.Get("TRANSMISSION_URL").Default("http://localhost:9091/transmission/rpc").Value(),
+		env.Get("TRANSMISSION_USERNAME").Value(),
+		env.Get("TRANSMISSION_PASSWORD").Value(),
+	)), nil
+}
+
+// NewWithClient creates the repository on top of an existing RPC client
+func NewWithClient(client *transmission.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+func (s *Repository) List() ([]*entities.Task, error) {
+	items, err := s.client.TorrentGet(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.Task, len(items))
+	for i, item := range items {
+		result[i] = ToTask(item)
+	}
+
+	return result, nil
+}
+
+func (s *Repository) Get(hash string) (*entities.Task, error) {
+	item, err := s.get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToTask(*item), nil
+}
+
+// Add adds a magnet link or a .torrent file. Transmission has no categories,
+// the category of the task is not kept.
+func (s *Repository) Add(schema schemas.TaskCreateSchema) (*entities.Task, error) {
+	if schema.SkipChecking || schema.SequentialDownload || schema.ContentLayout != "" {
+		return nil, errors.Wrap(errors.ErrNotSupported, "transmission cannot skip checking, download sequentially or change the content layout")
+	}
+
+	args := transmission.AddArgs{
+		Filename:    schema.MagnetURI,
+		MetaInfo:    schema.Torrent,
+		DownloadDir: schema.Directory,
+		Labels:      schema.Tags,
+		Paused:      schema.Paused,
+	}
+	// Transmission only has low, normal and high priorities
+	for i, priority := range schema.FilePriorities {
+		switch {
+		case priority == 0:
+			args.FilesUnwanted = append(args.FilesUnwanted, i)
+		case priority > 1:
+			args.PriorityHigh = append(args.PriorityHigh, i)
+		}
+	}
+
+	added, err := s.client.TorrentAdd(context.Background(), args)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add task")
+	}
+	if added == nil {
+		return nil, errors.Wrap(errors.ErrTaskNotFound, "failed to add task")
+	}
+
+	return s.Get(added.HashString)
+}
+
+func (s *Repository) Stop(hash string) error {
+	if err := s.client.TorrentAction(context.Background(), "torrent-stop", hash); err != nil {
+		return errors.Wrap(err, "failed to stop torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) Start(hash string) error {
+	if err := s.client.TorrentAction(context.Background(), "torrent-start", hash); err != nil {
+		return errors.Wrap(err, "failed to start torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) ForceResume(hash string) error {
+	if err := s.client.TorrentAction(context.Background(), "torrent-start-now", hash); err != nil {
+		return errors.Wrap(err, "failed to force resume torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) Delete(id string, deleteFiles bool) error {
+	if err := s.client.TorrentRemove(context.Background(), deleteFiles, id); err != nil {
+		return errors.Wrap(err, "failed to delete torrent")
+	}
+
+	return nil
+}
+
+// SetTags adds tags to the labels of the torrent
+func (s *Repository) SetTags(hash string, tags []string) error {
+	item, err := s.get(hash)
+	if err != nil {
+		return err
+	}
+
+	labels := item.Labels
+	for _, tag := range tags {
+		if !slices.Contains(labels, tag) {
+			labels = append(labels, tag)
+		}
+	}
+
+	return s.setLabels(hash, labels)
+}
+
+func (s *Repository) ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error {
+	return s.setLabels(hash, schema.Tags)
+}
+
+func (s *Repository) setLabels(hash string, labels []string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+
+	if err := s.client.TorrentSet(context.Background(), hash, map[string]any{"labels": labels}); err != nil {
+		return errors.Wrap(err, "failed to set torrent tags")
+	}
+
+	return nil
+}
+
+// SetShareLimit sets the seed ratio limit. Transmission has no seeding time limit,
+// the closest setting is the idle time after which seeding stops.
+func (s *Repository) SetShareLimit(schema schemas.TaskSetShareLimitSchema) error {
+	fields := map[string]any{
+		"seedRatioLimit": schema.RatioLimit,
+		"seedRatioMode":  1,
+		"seedIdleMode":   0,
+	}
+	if schema.SeedingTimeLimit > 0 {
+		fields["seedIdleLimit"] = schema.SeedingTimeLimit
+		fields["seedIdleMode"] = 1
+	}
+
+	if err := s.client.TorrentSet(context.Background(), schema.Hash, fields); err != nil {
+		return errors.Wrap(err, "failed to set torrent share limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetLocation(hash string, schema schemas.TaskSetLocationSchema) error {
+	if err := s.client.TorrentSetLocation(context.Background(), hash, schema.Location); err != nil {
+		return errors.Wrap(err, "failed to set torrent location")
+	}
+
+	return nil
+}
+
+func (s *Repository) Rename(hash string, schema schemas.TaskRenameSchema) error {
+	item, err := s.get(hash)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.TorrentRenamePath(context.Background(), hash, item.Name, schema.NewName); err != nil {
+		return errors.Wrap(err, "failed to rename torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetSuperSeeding(hash string, schema schemas.TaskSuperSeedingSchema) error {
+	return errors.Wrap(errors.ErrNotSupported, "transmission has no super seeding mode")
+}
+
+func (s *Repository) SetCategory(hash string, schema schemas.TaskSetCategorySchema) error {
+	return errors.Wrap(errors.ErrNotSupported, "transmission has no categories")
+}
+
+func (s *Repository) ForceRecheck(hash string) error {
+	if err := s.client.TorrentAction(context.Background(), "torrent-verify", hash); err != nil {
+		return errors.Wrap(err, "failed to force recheck torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) ForceReannounce(hash string) error {
+	if err := s.client.TorrentAction(context.Background(), "torrent-reannounce", hash); err != nil {
+		return errors.Wrap(err, "failed to force reannounce torrent")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetDownloadLimit(hash string, schema schemas.TaskSetDownloadLimitSchema) error {
+	if err := s.client.TorrentSet(context.Background(), hash, map[string]any{
+		"downloadLimit":   transmission.SpeedLimit(schema.Limit),
+		"downloadLimited": schema.Limit > 0,
+	}); err != nil {
+		return errors.Wrap(err, "failed to set torrent download limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) SetUploadLimit(hash string, schema schemas.TaskSetUploadLimitSchema) error {
+	if err := s.client.TorrentSet(context.Background(), hash, map[string]any{
+		"uploadLimit":   transmission.SpeedLimit(schema.Limit),
+		"uploadLimited": schema.Limit > 0,
+	}); err != nil {
+		return errors.Wrap(err, "failed to set torrent upload limit")
+	}
+
+	return nil
+}
+
+func (s *Repository) ListFiles(hash string) ([]*entities.TaskFile, error) {
+	files, stats, err := s.client.TorrentFiles(context.Background(), hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list torrent files")
+	}
+
+	result := make([]*entities.TaskFile, len(files))
+	for i, file := range files {
+		item := &entities.TaskFile{
+			Name:   file.Name,
+			Size:   int64(file.Length),
+			IsSeed: file.BytesCompleted == file.Length,
+			// the qBittorrent scale: 0 skipped, 1 normal, 6 high
+			Priority: 1,
+		}
+		if file.Length > 0 {
+			item.Progress = float64(file.BytesCompleted) / float64(file.Length)
+		}
+		if i < len(stats) {
+			switch {
+			case !stats[i].Wanted:
+				item.Priority = 0
+			case stats[i].Priority > 0:
+				item.Priority = 6
+			}
+		}
+		result[i] = item
+	}
+
+	return result, nil
+}
+
+func (s *Repository) get(hash string) (*transmission.Torrent, error) {
+	items, err := s.client.TorrentGet(context.Background(), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, errors.ErrTaskNotFound
+	}
+
+	return &items[0], nil
+}
+
+// ToTask maps a Transmission torrent to a task
+func ToTask(item transmission.Torrent) *entities.Task {
+	status := entities.TaskStatuses[constants.UnknownStatus]
+	if value, ok := entities.TaskStatuses[item.State()]; ok {
+		status = value
+	}
+
+	var magnetLink entities.TaskMagnetLink
+	if link, err := taskRepository.ParseMagnetLink(item.MagnetLink); err == nil {
+		magnetLink = *link
+	}
+
+	seeders, leechers := item.Swarm()
+
+	return &entities.Task{
+		ID:         item.HashString,
+		Name:       item.Name,
+		Hash:       item.HashString,
+		Path:       item.DownloadDir,
+		State:      status,
+		Size:       item.SizeWhenDone,
+		Priority:   item.QueuePosition,
+		Ratio:      max(item.UploadRatio, 0),
+		Progress:   item.PercentDone * 100,
+		MagnetURI:  item.MagnetLink,
+		MagnetLink: magnetLink,
+		Pairs: entities.TaskPairs{
+			SwarmSeeders:  seeders,
+			SwarmLeechers: leechers,
+			Seeders:       item.PeersSendingToUs,
+			Leechers:      item.PeersGettingFromUs,
+		},
+		NumSeeds:    item.PeersSendingToUs,
+		Tags:        item.Labels,
+		SeedingTime: item.SecondsSeeding,
+		Tracker:     item.Tracker(),
+		Network: entities.TaskNetwork{
+			Download: entities.TaskDownload{
+				Speed:  item.RateDownload,
+				Amount: item.DownloadedEver,
+			},
+			Upload: entities.TaskUpload{
+				Speed:  item.RateUpload,
+				Amount: item.UploadedEver,
+			},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+const hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// fakeTransmission answers the RPC calls with scripted torrents and records the other calls
+type fakeTransmission struct {
+	mu       sync.Mutex
+	torrents []map[string]any
+	calls    []map[string]any
+	rejected int
+}
+
+func (f *fakeTransmission) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Transmission-Session-Id") != "session" {
+		f.rejected++
+		w.Header().Set("X-Transmission-Session-Id", "session")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var req struct {
+		Method    string         `json:"method"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	args := map[string]any{}
+	switch req.Method {
+	case "torrent-get":
+		args["torrents"] = f.torrents
+	case "torrent-add":
+		req.Arguments["method"] = req.Method
+		f.calls = append(f.calls, req.Arguments)
+		args["torrent-added"] = map[string]any{"id": 1, "hashString": hashA}
+	default:
+		req.Arguments["method"] = req.Method
+		f.calls = append(f.calls, req.Arguments)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"result": "success", "arguments": args})
+}
+
+func setupRepository(t *testing.T) (*Repository, *fakeTransmission) {
+	t.Helper()
+
+	fake := &fakeTransmission{
+		torrents: []map[string]any{{
+			"id":                      1,
+			"hashString":              hashA,
+			"name":                    "Some.Linux.ISO",
+			"status":                  transmission.StatusSeed,
+			"leftUntilDone":           0,
+			"percentDone":             1,
+			"metadataPercentComplete": 1,
+			"sizeWhenDone":            2048,
+			"uploadRatio":             1.5,
+			"downloadDir":             "/downloads",
+			"labels":                  []string{"linux"},
+			"peersGettingFromUs":      2,
+			"secondsSeeding":          3600,
+			"trackerStats": []map[string]any{
+				{"announce": "https://tracker.example.org/announce", "seederCount": 40, "leecherCount": 3, "lastAnnounceSucceeded": true},
+			},
+		}},
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return NewWithClient(transmission.New(server.URL+"/transmission/rpc", "", "")), fake
+}
+
+func TestRepository_List(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	tasks, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+
+	if fake.rejected != 1 {
+		t.Errorf("Expected the session id to be requested once, got %d", fake.rejected)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tasks))
+	}
+
+	task := tasks[0]
+	if task.ID != hashA || task.State != "UPLOADING" || task.Progress != 100 {
+		t.Errorf("Unexpected task %+v", task)
+	}
+	if task.Pairs.SwarmSeeders != 40 || task.Tracker != "https://tracker.example.org/announce" {
+		t.Errorf("Expected swarm and tracker from the tracker stats, got %+v", task)
+	}
+	if task.SeedingTime != 3600 || len(task.Tags) != 1 || task.Tags[0] != "linux" {
+		t.Errorf("Expected seeding time and labels, got %+v", task)
+	}
+}
+
+func TestRepository_State(t *testing.T) {
+	tests := []struct {
+		torrent  transmission.Torrent
+		expected string
+	}{
+		{transmission.Torrent{Status: transmission.StatusStopped, MetadataPercentComplete: 1}, "stoppedUP"},
+		{transmission.Torrent{Status: transmission.StatusStopped, LeftUntilDone: 10, MetadataPercentComplete: 1}, "stoppedDL"},
+		{transmission.Torrent{Status: transmission.StatusDownload, LeftUntilDone: 10, PeersSendingToUs: 3, MetadataPercentComplete: 1}, "downloading"},
+		{transmission.Torrent{Status: transmission.StatusDownload, LeftUntilDone: 10, MetadataPercentComplete: 1}, "stalledDL"},
+		{transmission.Torrent{Status: transmission.StatusDownload}, "metaDL"},
+		{transmission.Torrent{Status: transmission.StatusCheck, LeftUntilDone: 10, MetadataPercentComplete: 1}, "checkingDL"},
+		{transmission.Torrent{Status: transmission.StatusSeedWait, MetadataPercentComplete: 1}, "queuedUP"},
+		{transmission.Torrent{Status: transmission.StatusSeed, Error: 2, MetadataPercentComplete: 1}, "error"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.torrent.State(); got != tt.expected {
+			t.Errorf("State(%+v): expected %s, got %s", tt.torrent, tt.expected, got)
+		}
+	}
+}
+
+func TestRepository_Edits(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	if err := repo.SetTags(hashA, []string{"linux", "iso"}); err != nil {
+		t.Fatalf("Failed to add tags: %v", err)
+	}
+	if err := repo.ReplaceTags(hashA, schemas.TaskSetTagsSchema{}); err != nil {
+		t.Fatalf("Failed to replace tags: %v", err)
+	}
+	if err := repo.SetUploadLimit(hashA, schemas.TaskSetUploadLimitSchema{Limit: 50000}); err != nil {
+		t.Fatalf("Failed to set upload limit: %v", err)
+	}
+
+	if len(fake.calls) != 3 {
+		t.Fatalf("Expected 3 calls, got %v", fake.calls)
+	}
+
+	if labels, _ := json.Marshal(fake.calls[0]["labels"]); string(labels) != `["linux","iso"]` {
+		t.Errorf("Expected the new tag added to the labels, got %s", labels)
+	}
+	if labels, _ := json.Marshal(fake.calls[1]["labels"]); string(labels) != `[]` {
+		t.Errorf("Expected the labels cleared, got %s", labels)
+	}
+	if fake.calls[2]["uploadLimit"] != float64(50) || fake.calls[2]["uploadLimited"] != true {
+		t.Errorf("Expected a 50 kB/s upload limit, got %v", fake.calls[2])
+	}
+}
+
+func TestRepository_SpeedLimits(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	tests := []struct {
+		limit    int
+		expected float64
+	}{
+		{0, 0},
+		{1, 1},
+		{999, 1},
+		{1000, 1},
+		{1001, 2},
+	}
+
+	for i, tt := range tests {
+		if err := repo.SetDownloadLimit(hashA, schemas.TaskSetDownloadLimitSchema{Limit: tt.limit}); err != nil {
+			t.Fatalf("Failed to set download limit: %v", err)
+		}
+
+		// A limit below 1 kB/s throttles the torrent instead of stopping it
+		call := fake.calls[i]
+		if call["downloadLimit"] != tt.expected || call["downloadLimited"] != (tt.limit > 0) {
+			t.Errorf("Expected a %v kB/s download limit for %d B/s, got %v", tt.expected, tt.limit, call)
+		}
+	}
+}
+
+func TestRepository_AddTorrentFile(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	data := []byte("d4:infod6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee")
+	task, err := repo.Add(schemas.TaskCreateSchema{
+		Torrent:        data,
+		Paused:         true,
+		FilePriorities: []int{0, 1, 6, 7},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if task.Hash != hashA {
+		t.Errorf("Expected task %s, got %+v", hashA, task)
+	}
+
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected a single torrent-add call, got %v", fake.calls)
+	}
+	add := fake.calls[0]
+	if add["metainfo"] != base64.StdEncoding.EncodeToString(data) || add["filename"] != nil || add["paused"] != true {
+		t.Errorf("Expected the paused base64 torrent file, got %v", add)
+	}
+	if unwanted, _ := json.Marshal(add["files-unwanted"]); string(unwanted) != `[0]` {
+		t.Errorf("Expected the skipped file unwanted, got %s", unwanted)
+	}
+	if high, _ := json.Marshal(add["priority-high"]); string(high) != `[2,3]` {
+		t.Errorf("Expected the high and maximal files in high priority, got %s", high)
+	}
+
+	if _, err := repo.Add(schemas.TaskCreateSchema{Torrent: data, SequentialDownload: true}); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for a sequential download, got %v", err)
+	}
+}
+
+func TestRepository_UnsupportedActions(t *testing.T) {
+	repo, fake := setupRepository(t)
+
+	if err := repo.SetSuperSeeding(hashA, schemas.TaskSuperSeedingSchema{Enabled: true}); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for super seeding, got %v", err)
+	}
+	if err := repo.SetCategory(hashA, schemas.TaskSetCategorySchema{Category: "tv"}); !errors.Is(err, errors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for categories, got %v", err)
+	}
+
+	if len(fake.calls) != 0 {
+		t.Errorf("Expected no call to Transmission, got %v", fake.calls)
+	}
+}
@@ -0,0 +1,187 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// GetTOTP retrieves the TOTP secret of a user, nil when the user has none
+func (r *Repository) GetTOTP(ctx context.Context, userUUID uuid.UUID) (*models.UserTOTP, error) {
+	var model models.UserTOTP
+	if err := r.db.DB.WithContext(ctx).Where("user_uuid = ?", userUUID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// SaveTOTP stores a TOTP secret waiting for confirmation, replacing the
+// previous one of the user
+func (r *Repository) SaveTOTP(ctx context.Context, userUUID uuid.UUID, encryptedSecret string) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.UserTOTP{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserTOTP{
+			UserUUID:        userUUID,
+			EncryptedSecret: encryptedSecret,
+		}).Error
+	})
+}
+
+// ConfirmTOTP enables the TOTP secret of a user, recording the time step of
+// the confirming code, and replaces its recovery codes
+func (r *Repository) ConfirmTOTP(ctx context.Context, userUUID uuid.UUID, step int64, at time.Time, codeHashes []string) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserTOTP{}).
+			Where("user_uuid = ? AND confirmed_at IS NULL", userUUID).
+			Updates(map[string]interface{}{"confirmed_at": at, "last_step": step, "updated_at": at})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("no pending two-factor enrollment")
+		}
+
+		return replaceRecoveryCodes(tx, userUUID, codeHashes)
+	})
+}
+
+// UseStep records the time step of an accepted TOTP code. It returns false when
+// this step or a later one was already used, the code is then a replay.
+func (r *Repository) UseStep(ctx context.Context, userUUID uuid.UUID, step int64) (bool, error) {
+	result := r.db.DB.WithContext(ctx).
+		Model(&models.UserTOTP{}).
+		Where("user_uuid = ? AND last_step < ?", userUUID, step).
+		Update("last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces the recovery codes of a user
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userUUID uuid.UUID, codeHashes []string) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userUUID, codeHashes)
+	})
+}
+
+// UseRecoveryCode marks a recovery code of a user as used. It returns false
+// when the code is unknown or was already used.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userUUID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	result := r.db.DB.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_uuid = ? AND code_hash = ? AND used_at IS NULL", userUUID, codeHash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.DB.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_uuid = ? AND used_at IS NULL", userUUID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteTwoFactor removes the second factor of a user: its TOTP secret,
+// recovery codes and pending login challenges
+func (r *Repository) DeleteTwoFactor(ctx context.Context, userUUID uuid.UUID) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_uuid = ?", userUUID).Delete(&models.LoginChallenge{}).Error
+	})
+}
+
+// CreateChallenge inserts a login challenge, stored as the hash of its token.
+// Expired challenges are cleaned up on the way.
+func (r *Repository) CreateChallenge(ctx context.Context, userUUID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	db := r.db.DB.WithContext(ctx)
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.LoginChallenge{}).Error; err != nil {
+		return err
+	}
+
+	return db.Create(&models.LoginChallenge{
+		UserUUID:  userUUID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// GetChallenge retrieves a login challenge and its user by the hash of its token
+func (r *Repository) GetChallenge(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	var model models.LoginChallenge
+	if err := r.db.DB.WithContext(ctx).
+		Preload("User").
+		Where("token_hash = ?", tokenHash).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login challenge not found")
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// RecordChallengeFailure counts a wrong answer to a login challenge
+func (r *Repository) RecordChallengeFailure(ctx context.Context, id uuid.UUID) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.LoginChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeleteChallenge removes a login challenge
+func (r *Repository) DeleteChallenge(ctx context.Context, id uuid.UUID) error {
+	return r.db.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.LoginChallenge{}).Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userUUID uuid.UUID, codeHashes []string) error {
+	if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserUUID: userUUID, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	return tx.Create(&codes).Error
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/models"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -21,12 +24,38 @@ func NewRepository(db *database.Database) *Repository {
 	}
 }
 
-// CreateUser inserts a new user into the database
+// Transaction runs fn with a repository bound to a single transaction, which
+// is rolled back when fn fails
+func (r *Repository) Transaction(ctx context.Context, fn func(repo *Repository) error) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: &database.Database{DB: tx}})
+	})
+}
+
+// LockUsers keeps the other transactions from inserting users until the
+// current one ends, so that a count of the users stays true. SQLite already
+// refuses to commit a transaction that read what another one changed since.
+func (r *Repository) LockUsers(ctx context.Context) error {
+	if r.db.DB.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return r.db.DB.WithContext(ctx).Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE").Error
+}
+
+// CreateUser inserts a new viewer into the database
 func (r *Repository) CreateUser(ctx context.Context, email, passwordHash, salt string) (*entities.User, error) {
+	return r.CreateUserWithRole(ctx, email, passwordHash, salt, entities.RoleViewer)
+}
+
+// CreateUserWithRole inserts a new user with the given role into the database
+func (r *Repository) CreateUserWithRole(ctx context.Context, email, passwordHash, salt, role string) (*entities.User, error) {
 	model := &models.User{
+		// The username is unique, users log in by email and share no other name
+		Username:     email,
 		Email:        email,
 		PasswordHash: passwordHash,
 		Salt:         salt,
+		Role:         role,
 	}
 
 	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
@@ -69,7 +98,7 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.
 	var model models.User
 	if err := r.db.DB.WithContext(ctx).Where("email = ?", email).First(&model).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, pkgerrors.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -82,7 +111,7 @@ func (r *Repository) GetUserByUUID(ctx context.Context, uuid string) (*entities.
 	var model models.User
 	if err := r.db.DB.WithContext(ctx).Where("uuid = ?", uuid).First(&model).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, pkgerrors.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -90,12 +119,262 @@ func (r *Repository) GetUserByUUID(ctx context.Context, uuid string) (*entities.
 	return toEntity(model), nil
 }
 
+// CountUsers returns the number of registered users
+func (r *Repository) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountActiveAdmins returns the number of admins that are not disabled
+func (r *Repository) CountActiveAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("role = ? AND disabled = ?", entities.RoleAdmin, false).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ListUsers returns every user, oldest first
+func (r *Repository) ListUsers(ctx context.Context) ([]*entities.User, error) {
+	var items []models.User
+	if err := r.db.DB.WithContext(ctx).Order("created_at ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.User, len(items))
+	for i, item := range items {
+		result[i] = toEntity(item)
+	}
+
+	return result, nil
+}
+
+// UpdateRole changes the role of a user
+func (r *Repository) UpdateRole(ctx context.Context, userUUID uuid.UUID, role string) error {
+	return r.update(ctx, userUUID, map[string]any{"role": role})
+}
+
+// SetDisabled disables or enables a user
+func (r *Repository) SetDisabled(ctx context.Context, userUUID uuid.UUID, disabled bool) error {
+	return r.update(ctx, userUUID, map[string]any{"disabled": disabled})
+}
+
+func (r *Repository) update(ctx context.Context, userUUID uuid.UUID, updates map[string]any) error {
+	updates["updated_at"] = time.Now()
+
+	result := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("uuid = ?", userUUID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return pkgerrors.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetAgentGrants returns the agents a user was granted
+func (r *Repository) GetAgentGrants(ctx context.Context, userUUID uuid.UUID) ([]uuid.UUID, error) {
+	var items []models.AgentPermission
+	if err := r.db.DB.WithContext(ctx).Where("user_uuid = ?", userUUID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		result[i] = item.AgentUUID
+	}
+
+	return result, nil
+}
+
+// GetAccessibleAgents returns the agents a user was granted along with their
+// child agents, which the grant of an agent covers
+func (r *Repository) GetAccessibleAgents(ctx context.Context, userUUID uuid.UUID) ([]uuid.UUID, error) {
+	grants, err := r.GetAgentGrants(ctx, userUUID)
+	if err != nil || len(grants) == 0 {
+		return grants, err
+	}
+
+	var children []uuid.UUID
+	if err := r.db.DB.WithContext(ctx).Model(&models.Agent{}).Where("parent_uuid IN ?", grants).Pluck("uuid", &children).Error; err != nil {
+		return nil, err
+	}
+
+	return append(grants, children...), nil
+}
+
+// HasAgentGrant tells whether a user was granted an agent, or the parent of a child agent
+func (r *Repository) HasAgentGrant(ctx context.Context, userUUID, agentUUID uuid.UUID) (bool, error) {
+	parent := r.db.DB.Model(&models.Agent{}).Select("parent_uuid").Where("uuid = ? AND parent_uuid IS NOT NULL", agentUUID)
+
+	var count int64
+	if err := r.db.DB.WithContext(ctx).
+		Model(&models.AgentPermission{}).
+		Where("user_uuid = ? AND (agent_uuid = ? OR agent_uuid IN (?))", userUUID, agentUUID, parent).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// SetAgentGrants replaces the agents a user was granted
+func (r *Repository) SetAgentGrants(ctx context.Context, userUUID uuid.UUID, agentUUIDs []uuid.UUID) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ?", userUUID).Delete(&models.AgentPermission{}).Error; err != nil {
+			return err
+		}
+
+		if len(agentUUIDs) == 0 {
+			return nil
+		}
+
+		items := make([]models.AgentPermission, len(agentUUIDs))
+		for i, agentUUID := range agentUUIDs {
+			items[i] = models.AgentPermission{UserUUID: userUUID, AgentUUID: agentUUID}
+		}
+
+		return tx.Create(&items).Error
+	})
+}
+
+// CreateInvite stores an invite, identified by the hash of its token
+func (r *Repository) CreateInvite(ctx context.Context, invite *entities.UserInvite, tokenHash string) error {
+	model := &models.UserInvite{
+		Email:     invite.Email,
+		Role:      invite.Role,
+		TokenHash: tokenHash,
+		InvitedBy: invite.InvitedBy,
+		ExpiresAt: invite.ExpiresAt,
+	}
+
+	if err := r.db.DB.WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+
+	invite.ID = model.ID
+	invite.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// UseInvite marks the unused and unexpired invite with the given token hash as
+// used and returns it. It fails when no such invite exists.
+func (r *Repository) UseInvite(ctx context.Context, tokenHash string, now time.Time) (*entities.UserInvite, error) {
+	var model models.UserInvite
+
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).First(&model).Error; err != nil {
+			return err
+		}
+
+		// the condition on used_at keeps a concurrent registration from using it twice
+		result := tx.Model(&models.UserInvite{}).Where("id = ? AND used_at IS NULL", model.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired invite")
+		}
+		return nil, err
+	}
+
+	return &entities.UserInvite{
+		ID:        model.ID,
+		Email:     model.Email,
+		Role:      model.Role,
+		InvitedBy: model.InvitedBy,
+		ExpiresAt: model.ExpiresAt,
+		CreatedAt: model.CreatedAt,
+	}, nil
+}
+
+// GetUserModel retrieves a user with its password hash by UUID
+func (r *Repository) GetUserModel(ctx context.Context, userUUID uuid.UUID) (*models.User, error) {
+	var model models.User
+	if err := r.db.DB.WithContext(ctx).Where("uuid = ?", userUUID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgerrors.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// UpdatePassword replaces the password hash of a user. The hash embeds its
+// salt, the legacy salt column is cleared.
+func (r *Repository) UpdatePassword(ctx context.Context, userUUID uuid.UUID, passwordHash string) error {
+	return r.update(ctx, userUUID, map[string]any{"password_hash": passwordHash, "salt": ""})
+}
+
+// CreatePasswordReset inserts a password reset token, stored as its hash. The
+// previous unused tokens of the user are deleted.
+func (r *Repository) CreatePasswordReset(ctx context.Context, userUUID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_uuid = ? AND used_at IS NULL", userUUID).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordReset{
+			UserUUID:  userUUID,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+}
+
+// UsePasswordReset marks a valid password reset token as used and returns its user
+func (r *Repository) UsePasswordReset(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	var model models.PasswordReset
+
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).First(&model).Error; err != nil {
+			return err
+		}
+
+		// the condition on used_at keeps a concurrent reset from using it twice
+		result := tx.Model(&models.PasswordReset{}).Where("id = ? AND used_at IS NULL", model.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, errors.New("invalid or expired reset token")
+		}
+		return uuid.Nil, err
+	}
+
+	return model.UserUUID, nil
+}
+
 // toEntity converts a models.User to entities.User
 func toEntity(model models.User) *entities.User {
 	return &entities.User{
 		UUID:      model.UUID,
 		Username:  model.Username,
 		Email:     model.Email,
+		Role:      model.Role,
+		Disabled:  model.Disabled,
 		CreatedAt: model.CreatedAt,
 		UpdatedAt: model.UpdatedAt,
 	}
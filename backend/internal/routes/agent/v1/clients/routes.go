@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/interfaces"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Client is a torrent client managed by the agent, whose routes are under /v1/clients/:name
+type Client struct {
+	Name     string
+	Type     string
+	Default  bool
+	Instance interfaces.InstanceService
+}
+
+type Module struct {
+	group   *gin.RouterGroup
+	clients []Client
+}
+
+func NewModule(router *gin.RouterGroup, clients []Client) *Module {
+	return &Module{
+		group:   router.Group("/clients"),
+		clients: clients,
+	}
+}
+
+func (m Module) Register() {
+	m.group.Use(middlewares.RequireAgentBearerToken())
+
+	m.group.GET("/", m.list)
+}
+
+// list returns the torrent clients, pinging them concurrently for their status
+func (m Module) list(c *gin.Context) {
+	result := make([]models.AgentClientResponse, len(m.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			item := &entities.AgentClient{
+				Name:    client.Name,
+				Type:    client.Type,
+				Default: client.Default,
+				Status:  entities.AgentStatusActive,
+			}
+			if err := client.Instance.Ping(c.Request.Context()); err != nil {
+				item.Status = entities.AgentStatusErrored
+				item.Error = err.Error()
+			}
+			result[i] = mappers.ToAgentClientResponse(item)
+		}()
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"github.com/gardarr/gardarr/internal/infra/events"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	tracker "github.com/gardarr/gardarr/internal/services/tracker/agent"
+	"github.com/gin-gonic/gin"
+)
+
+type Module struct {
+	tracker *tracker.Tracker
+	group   *gin.RouterGroup
+}
+
+func NewModule(router *gin.RouterGroup, t *tracker.Tracker) *Module {
+	return &Module{
+		tracker: t,
+		group:   router.Group("/events"),
+	}
+}
+
+func (m Module) Register() {
+	m.group.Use(middlewares.RequireAgentBearerToken())
+
+	m.group.GET("/", m.stream)
+}
+
+// stream sends a snapshot of the tasks and instance, followed by their changes
+func (m *Module) stream(c *gin.Context) {
+	initial, sub := m.tracker.Subscribe()
+	defer sub.Close()
+
+	events.Stream(c, initial, sub.C)
+}
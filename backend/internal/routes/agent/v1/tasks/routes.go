@@ -33,6 +33,7 @@ func (m Module) Register() {
 	m.tasksRouter.GET("/", m.listTasks)
 
 	m.taskRouter.POST("/", m.createTask)
+	m.taskRouter.POST("/preview", m.previewTask)
 	m.taskRouter.DELETE("/:id", m.deleteTask)
 
 	m.taskRouter.GET("/:id", m.getTask)
@@ -48,12 +49,14 @@ func (m Module) Register() {
 	m.taskRouter.POST("/:id/limit_download_rate", m.setTaskDownloadLimit)
 	m.taskRouter.POST("/:id/limit_upload_rate", m.setTaskUploadLimit)
 	m.taskRouter.GET("/:id/files", m.listTaskFiles)
+	m.taskRouter.POST("/:id/category", m.setTaskCategory)
+	m.taskRouter.POST("/:id/tags", m.setTaskTags)
 }
 
 func (m *Module) listTasks(c *gin.Context) {
 	result, err := m.controller.ListTasks(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.JSON(errorStatus(err), err.Error())
 		return
 	}
 
@@ -69,7 +72,7 @@ func (m *Module) listTasks(c *gin.Context) {
 func (m *Module) getTask(c *gin.Context) {
 	result, err := m.controller.GetTask(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.JSON(errorStatus(err), err.Error())
 		return
 	}
 
@@ -82,21 +85,53 @@ func (m *Module) getTask(c *gin.Context) {
 }
 
 func (m *Module) createTask(c *gin.Context) {
-	var body schemas.TaskCreateSchema
-	if err := c.ShouldBindJSON(&body); err != nil {
+	body, err := bindTaskCreate(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
 	result, err := m.controller.CreateTask(c.Request.Context(), body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.JSON(errorStatus(err), err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, mappers.ToTaskResponse(result))
 }
 
+func (m *Module) previewTask(c *gin.Context) {
+	body, err := bindTaskCreate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := m.controller.PreviewTask(c.Request.Context(), body)
+	if err != nil {
+		c.JSON(errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTaskPreviewResponse(result))
+}
+
+// bindTaskCreate binds a JSON body, or a multipart form whose torrent part is the .torrent file
+func bindTaskCreate(c *gin.Context) (schemas.TaskCreateSchema, error) {
+	var body schemas.TaskCreateSchema
+	if err := c.ShouldBind(&body); err != nil {
+		return body, err
+	}
+
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		if file, err := c.FormFile("torrent"); err == nil {
+			return body, body.ReadTorrent(file)
+		}
+	}
+
+	return body, nil
+}
+
 func (m *Module) deleteTask(c *gin.Context) {
 	var schema schemas.TaskDeleteSchema
 	if err := c.ShouldBindUri(&schema); err != nil {
@@ -111,7 +146,7 @@ func (m *Module) deleteTask(c *gin.Context) {
 	}
 
 	if err := m.controller.DeleteTask(c.Request.Context(), schema.ID, options.Purge); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.JSON(errorStatus(err), err.Error())
 		return
 	}
 
@@ -126,7 +161,7 @@ func (m *Module) stopTask(c *gin.Context) {
 	}
 
 	if err := m.controller.StopTask(c.Request.Context(), taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -141,7 +176,7 @@ func (m *Module) startTask(c *gin.Context) {
 	}
 
 	if err := m.controller.StartTask(c.Request.Context(), taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -156,7 +191,7 @@ func (m *Module) forceResumeTask(c *gin.Context) {
 	}
 
 	if err := m.controller.ForceResumeTask(c.Request.Context(), taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -180,7 +215,7 @@ func (m *Module) setTaskShareLimit(c *gin.Context) {
 	body.Hash = taskID
 
 	if err := m.controller.SetTaskShareLimit(c.Request.Context(), body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -201,7 +236,7 @@ func (m *Module) setTaskLocation(c *gin.Context) {
 	}
 
 	if err := m.controller.SetTaskLocation(c.Request.Context(), taskID, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -222,7 +257,7 @@ func (m *Module) renameTask(c *gin.Context) {
 	}
 
 	if err := m.controller.RenameTask(c.Request.Context(), taskID, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -243,7 +278,7 @@ func (m *Module) setTaskSuperSeeding(c *gin.Context) {
 	}
 
 	if err := m.controller.SetTaskSuperSeeding(c.Request.Context(), taskID, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -258,7 +293,7 @@ func (m *Module) forceRecheckTask(c *gin.Context) {
 	}
 
 	if err := m.controller.ForceRecheckTask(c.Request.Context(), taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -273,7 +308,7 @@ func (m *Module) forceReannounceTask(c *gin.Context) {
 	}
 
 	if err := m.controller.ForceReannounceTask(c.Request.Context(), taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -294,7 +329,7 @@ func (m *Module) setTaskDownloadLimit(c *gin.Context) {
 	}
 
 	if err := m.controller.SetTaskDownloadLimit(c.Request.Context(), taskID, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -315,7 +350,7 @@ func (m *Module) setTaskUploadLimit(c *gin.Context) {
 	}
 
 	if err := m.controller.SetTaskUploadLimit(c.Request.Context(), taskID, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -331,9 +366,64 @@ func (m *Module) listTaskFiles(c *gin.Context) {
 
 	files, err := m.controller.ListTaskFiles(c.Request.Context(), taskID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, mappers.ToTaskFilesResponse(files))
 }
+
+func (m *Module) setTaskCategory(c *gin.Context) {
+	taskID := c.Param("id")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task ID is required"})
+		return
+	}
+
+	var body schemas.TaskSetCategorySchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := m.controller.SetTaskCategory(c.Request.Context(), taskID, body); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "task category set successfully"})
+}
+
+func (m *Module) setTaskTags(c *gin.Context) {
+	taskID := c.Param("id")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task ID is required"})
+		return
+	}
+
+	var body schemas.TaskSetTagsSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := m.controller.SetTaskTags(c.Request.Context(), taskID, body); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "task tags set successfully"})
+}
+
+// errorStatus answers 501 for the actions the torrent client does not support
+// and 400 for the invalid requests
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errors.ErrNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, errors.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,11 +2,18 @@ package agents
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
 	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/internal/services/agentmanager"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gardarr/gardarr/internal/services/user"
 	"github.com/gardarr/gardarr/pkg/errors"
 	"github.com/gin-gonic/gin"
 )
@@ -14,32 +21,94 @@ import (
 // Module holds agent routes configuration
 type Module struct {
 	service      *agentmanager.Service
+	users        *user.Service
+	auditService *audit.Service
+	auth         *middlewares.Auth
+	limits       *ratelimit.Limiters
+	db           *database.Database
 	agentsRouter *gin.RouterGroup
 	agentRouter  *gin.RouterGroup
 }
 
-func NewModule(router *gin.RouterGroup, svc *agentmanager.Service) *Module {
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth, limits *ratelimit.Limiters, svc *agentmanager.Service) *Module {
 	return &Module{
 		service:      svc,
+		users:        user.NewService(db),
+		auditService: audit.NewService(db),
+		auth:         auth,
+		limits:       limits,
+		db:           db,
 		agentsRouter: router.Group("/agents"),
 		agentRouter:  router.Group("/agent"),
 	}
 }
 
 func (m Module) Register() {
-	m.agentsRouter.GET("/", m.listAgents)
-	m.agentsRouter.GET("/tasks", m.listAgentsTasks)
+	// Scripts authenticate with an API token instead of the session cookie. The
+	// calls proxied to the agents are throttled per user.
+	auth := m.auth.AuthMiddleware()
+	throttle := middlewares.Throttle(m.limits.Agents, middlewares.UserKey)
+	m.agentsRouter.Use(auth, throttle)
+	m.agentRouter.Use(auth, throttle)
+
+	// Viewers read, operators act on tasks and admins manage the agents. Apart
+	// from admins, users only reach the agents they were granted.
+	viewer := middlewares.Authorize(entities.RoleViewer, entities.ScopeTasksRead)
+	operator := middlewares.Authorize(entities.RoleOperator, entities.ScopeTasksWrite)
+	admin := middlewares.Authorize(entities.RoleAdmin, entities.ScopeAgentsAdmin)
+	access := middlewares.RequireAgentAccess(m.db, "id")
+
+	m.agentsRouter.GET("/", viewer, m.listAgents)
+	m.agentsRouter.GET("/tasks", viewer, m.listAgentsTasks)
+
+	m.agentRouter.POST("/", admin, m.createAgent)
+	m.agentRouter.GET("/:id", viewer, access, m.getAgent)
+	m.agentRouter.PUT("/:id", admin, m.updateAgent)
+	m.agentRouter.GET("/:id/tasks", viewer, access, m.listAgentTasks)
+	m.agentRouter.GET("/:id/preferences", viewer, access, m.getAgentPreferences)
+	m.agentRouter.GET("/:id/health-history", viewer, access, m.getAgentHealthHistory)
+	m.agentRouter.DELETE("/:id", admin, m.deleteAgent)
+	m.agentRouter.POST("/:id/task", operator, access, m.createAgentTask)
+	m.agentRouter.POST("/:id/task/preview", operator, access, m.previewAgentTask)
+	m.agentRouter.GET("/:id/tasks/:task_id", viewer, access, m.getAgentTask)
+	m.agentRouter.DELETE("/:id/tasks/:task_id", operator, access, m.deleteAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/pause", operator, access, m.pauseAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/resume", operator, access, m.resumeAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/force-download", operator, access, m.forceDownloadAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/share-limit", operator, access, m.setAgentTaskShareLimit)
+	m.agentRouter.POST("/:id/tasks/:task_id/location", operator, access, m.setAgentTaskLocation)
+	m.agentRouter.POST("/:id/tasks/:task_id/rename", operator, access, m.renameAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/super-seeding", operator, access, m.setAgentTaskSuperSeeding)
+	m.agentRouter.POST("/:id/tasks/:task_id/recheck", operator, access, m.forceRecheckAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/reannounce", operator, access, m.forceReannounceAgentTask)
+	m.agentRouter.POST("/:id/tasks/:task_id/download-limit", operator, access, m.setAgentTaskDownloadLimit)
+	m.agentRouter.POST("/:id/tasks/:task_id/upload-limit", operator, access, m.setAgentTaskUploadLimit)
+	m.agentRouter.POST("/:id/tasks/:task_id/category", operator, access, m.setAgentTaskCategory)
+	m.agentRouter.POST("/:id/tasks/:task_id/tags", operator, access, m.setAgentTaskTags)
+	m.agentRouter.GET("/:id/tasks/:task_id/files", viewer, access, m.listAgentTaskFiles)
+}
+
+// visibleAgents returns the agents the current user was granted
+func (m *Module) visibleAgents(c *gin.Context) ([]*entities.Agent, error) {
+	agents, err := m.service.ListAgents(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
 
-	m.agentRouter.POST("/", m.createAgent)
-	m.agentRouter.GET("/:id", m.getAgent)
-	m.agentRouter.PUT("/:id", m.updateAgent)
-	m.agentRouter.GET("/:id/tasks", m.listAgentTasks)
-	m.agentRouter.GET("/:id/preferences", m.getAgentPreferences)
-	m.agentRouter.DELETE("/:id", m.deleteAgent)
-	m.agentRouter.POST("/:id/task", m.createAgentTask)
-	m.agentRouter.POST("/:id/tasks/:task_id/pause", m.pauseAgentTask)
-	m.agentRouter.POST("/:id/tasks/:task_id/resume", m.resumeAgentTask)
-	m.agentRouter.POST("/:id/tasks/:task_id/force-download", m.forceDownloadAgentTask)
+	currentUser, _ := middlewares.CurrentUser(c)
+	allowed, err := m.users.AgentFilter(c.Request.Context(), currentUser)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*entities.Agent, 0, len(agents))
+	for _, agent := range agents {
+		if allowed(agent.UUID) {
+			visible = append(visible, agent)
+		}
+	}
+
+	return visible, nil
 }
 
 func (m *Module) createAgent(c *gin.Context) {
@@ -56,11 +125,15 @@ func (m *Module) createAgent(c *gin.Context) {
 		return
 	}
 
+	event := middlewares.NewAuditEvent(c, entities.AuditAgentCreate, entities.AuditTargetAgent, result.UUID.String())
+	event.Detail = result.Name
+	m.auditService.Record(c.Request.Context(), event)
+
 	c.JSON(http.StatusOK, mappers.ToAgentResponse(result))
 }
 
 func (m *Module) listAgents(c *gin.Context) {
-	result, err := m.service.ListAgents()
+	result, err := m.visibleAgents(c)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
@@ -106,6 +179,30 @@ func (m *Module) getAgentPreferences(c *gin.Context) {
 	c.JSON(http.StatusOK, mappers.ToInstancePreferencesResponse(preferences))
 }
 
+func (m *Module) getAgentHealthHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		respErr := errors.NewBadRequestError("Invalid limit, expected a number between 1 and 1000", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.GetAgentHealthHistory(c.Request.Context(), id, limit)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := make([]models.AgentHealthEventResponse, len(result))
+	for i, item := range result {
+		resp[i] = mappers.ToAgentHealthEventResponse(item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (m *Module) updateAgent(c *gin.Context) {
 	id := c.Param("id")
 
@@ -122,6 +219,15 @@ func (m *Module) updateAgent(c *gin.Context) {
 		return
 	}
 
+	// A new token is audited apart, it changes what the manager is trusted with
+	action := entities.AuditAgentUpdate
+	if body.Token != "" {
+		action = entities.AuditAgentTokenChange
+	}
+	event := middlewares.NewAuditEvent(c, action, entities.AuditTargetAgent, result.UUID.String())
+	event.Detail = result.Name
+	m.auditService.Record(c.Request.Context(), event)
+
 	c.JSON(http.StatusOK, mappers.ToAgentResponse(result))
 }
 
@@ -133,11 +239,19 @@ func (m *Module) deleteAgent(c *gin.Context) {
 		return
 	}
 
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditAgentDelete, entities.AuditTargetAgent, id))
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
 func (m *Module) listAgentsTasks(c *gin.Context) {
-	result, err := m.service.ListAgentsTasks()
+	agents, err := m.visibleAgents(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	result, err := m.service.ListTasks(c.Request.Context(), agents)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
@@ -171,8 +285,8 @@ func (m *Module) listAgentTasks(c *gin.Context) {
 func (m *Module) createAgentTask(c *gin.Context) {
 	id := c.Param("id")
 
-	var body schemas.TaskCreateSchema
-	if err := c.ShouldBindJSON(&body); err != nil {
+	body, err := bindTaskCreate(c)
+	if err != nil {
 		respErr := errors.NewBadRequestError("Invalid request body", err)
 		c.JSON(respErr.StatusCode, respErr)
 		return
@@ -187,11 +301,86 @@ func (m *Module) createAgentTask(c *gin.Context) {
 	c.JSON(http.StatusOK, mappers.ToTaskResponse(result))
 }
 
+func (m *Module) previewAgentTask(c *gin.Context) {
+	id := c.Param("id")
+
+	body, err := bindTaskCreate(c)
+	if err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.PreviewAgentTask(c.Request.Context(), id, body)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTaskPreviewResponse(result))
+}
+
+// bindTaskCreate binds a JSON body, or a multipart form whose torrent part is
+// the .torrent file, forwarded to the agent base64 encoded
+func bindTaskCreate(c *gin.Context) (schemas.TaskCreateSchema, error) {
+	var body schemas.TaskCreateSchema
+	if err := c.ShouldBind(&body); err != nil {
+		return body, err
+	}
+
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		if file, err := c.FormFile("torrent"); err == nil {
+			return body, body.ReadTorrent(file)
+		}
+	}
+
+	return body, nil
+}
+
+func (m *Module) getAgentTask(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	result, err := m.service.GetAgentTask(c.Request.Context(), agentID, taskID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTaskResponse(result))
+}
+
+func (m *Module) deleteAgentTask(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var options schemas.TaskDeleteOptionsSchema
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respErr := errors.NewBadRequestError("Invalid query parameters", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.DeleteAgentTask(c.Request.Context(), agentID, taskID, options.Purge); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditTaskDelete, entities.AuditTargetTask, taskID)
+	event.Detail = "agent " + agentID
+	if options.Purge {
+		event.Detail += ", files deleted"
+	}
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+}
+
 func (m *Module) pauseAgentTask(c *gin.Context) {
 	agentID := c.Param("id")
 	taskID := c.Param("task_id")
 
-	if err := m.service.PauseAgentTask(c.Request.Context(), agentID, taskID); err != nil {
+	if err := m.service.StopAgentTask(c.Request.Context(), agentID, taskID); err != nil {
 		errors.HandleError(c, err)
 		return
 	}
@@ -203,7 +392,7 @@ func (m *Module) resumeAgentTask(c *gin.Context) {
 	agentID := c.Param("id")
 	taskID := c.Param("task_id")
 
-	if err := m.service.ResumeAgentTask(c.Request.Context(), agentID, taskID); err != nil {
+	if err := m.service.StartAgentTask(c.Request.Context(), agentID, taskID); err != nil {
 		errors.HandleError(c, err)
 		return
 	}
@@ -215,10 +404,199 @@ func (m *Module) forceDownloadAgentTask(c *gin.Context) {
 	agentID := c.Param("id")
 	taskID := c.Param("task_id")
 
-	if err := m.service.ForceDownloadAgentTask(c.Request.Context(), agentID, taskID); err != nil {
+	if err := m.service.ForceResumeAgentTask(c.Request.Context(), agentID, taskID); err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task force download initiated successfully"})
 }
+
+func (m *Module) setAgentTaskShareLimit(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetShareLimitSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskShareLimit(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task share limit set successfully"})
+}
+
+func (m *Module) setAgentTaskLocation(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetLocationSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskLocation(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task location set successfully"})
+}
+
+func (m *Module) renameAgentTask(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskRenameSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.RenameAgentTask(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task renamed successfully"})
+}
+
+func (m *Module) setAgentTaskSuperSeeding(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSuperSeedingSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskSuperSeeding(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task super seeding mode set successfully"})
+}
+
+func (m *Module) forceRecheckAgentTask(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	if err := m.service.ForceRecheckAgentTask(c.Request.Context(), agentID, taskID); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task recheck initiated successfully"})
+}
+
+func (m *Module) forceReannounceAgentTask(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	if err := m.service.ForceReannounceAgentTask(c.Request.Context(), agentID, taskID); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task reannounce initiated successfully"})
+}
+
+func (m *Module) setAgentTaskDownloadLimit(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetDownloadLimitSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskDownloadLimit(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task download limit set successfully"})
+}
+
+func (m *Module) setAgentTaskUploadLimit(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetUploadLimitSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskUploadLimit(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task upload limit set successfully"})
+}
+
+func (m *Module) setAgentTaskCategory(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetCategorySchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskCategory(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task category set successfully"})
+}
+
+func (m *Module) setAgentTaskTags(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	var body schemas.TaskSetTagsSchema
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := m.service.SetAgentTaskTags(c.Request.Context(), agentID, taskID, body); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task tags set successfully"})
+}
+
+func (m *Module) listAgentTaskFiles(c *gin.Context) {
+	agentID := c.Param("id")
+	taskID := c.Param("task_id")
+
+	files, err := m.service.ListAgentTaskFiles(c.Request.Context(), agentID, taskID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTaskFilesResponse(files))
+}
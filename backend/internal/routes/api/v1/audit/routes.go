@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultEventLimit is the page size of the listings made without one
+const defaultEventLimit = 100
+
+// Module holds the security audit log routes
+type Module struct {
+	group   *gin.RouterGroup
+	service *audit.Service
+	auth    *middlewares.Auth
+}
+
+// NewModule creates a new security audit log module
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth) *Module {
+	return &Module{
+		group:   router.Group("/audit"),
+		service: audit.NewService(db),
+		auth:    auth,
+	}
+}
+
+// Register registers the security audit log routes, reserved to admins
+func (m *Module) Register() {
+	m.group.Use(m.auth.SessionMiddleware(), middlewares.RequireRole(entities.RoleAdmin))
+
+	m.group.GET("/events", m.listEvents)
+}
+
+// listEvents returns a page of the audit log, newest first. The events can be
+// filtered by action, actor, client address, target and time range.
+func (m *Module) listEvents(c *gin.Context) {
+	var query schemas.AuditEventQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respErr := errors.NewBadRequestError("Invalid query parameters", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultEventLimit
+	}
+
+	result, total, err := m.service.ListEvents(c.Request.Context(), query)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := models.AuditEventPageResponse{
+		Items:  make([]models.AuditEventResponse, len(result)),
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	for i, item := range result {
+		resp.Items[i] = mappers.ToAuditEventResponse(item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/oidc"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie binds a single sign-on to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// getProviders tells the login page whether to offer the password form and
+// the single sign-on
+func (m *Module) getProviders(c *gin.Context) {
+	response := models.AuthProvidersResponse{
+		PasswordLogin: m.passwordLogin,
+		ForwardAuth:   m.forwardAuth.Enabled(),
+	}
+	if m.oidcService.Enabled() {
+		response.OIDC = &models.OIDCProviderResponse{
+			Name:     m.oidcService.ProviderName(),
+			LoginURL: m.auth.BasePath() + "/v1/auth/oidc/login",
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// oidcLogin sends the browser to the identity provider
+func (m *Module) oidcLogin(c *gin.Context) {
+	login, err := m.oidcService.Begin(c.Request.Context(), c.Query("redirect"))
+	if err != nil {
+		if errors.Is(err, errors.ErrSSODisabled) {
+			errors.HandleError(c, err)
+			return
+		}
+
+		log.Printf("oidc: failed to start a single sign-on: %v", err)
+		m.redirectToLogin(c, "sso")
+		return
+	}
+
+	m.auth.SetFlowCookie(c, oidcStateCookie, login.State, m.oidcService.StateTTL())
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// oidcCallback completes a single sign-on when the identity provider sends
+// the browser back, and starts a session as a password login does
+func (m *Module) oidcCallback(c *gin.Context) {
+	state := c.Query("state")
+	expected := m.auth.FlowCookie(c, oidcStateCookie)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		m.recordSSOFailure(c, "single sign-on: "+providerErr)
+		m.redirectToLogin(c, "sso")
+		return
+	}
+
+	// The state must come back to the browser it was given to
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expected)) != 1 {
+		m.recordSSOFailure(c, "single sign-on: state mismatch")
+		m.redirectToLogin(c, "sso")
+		return
+	}
+
+	authenticatedUser, redirectPath, err := m.oidcService.Complete(c.Request.Context(), state, c.Query("code"))
+	if err != nil {
+		log.Printf("oidc: single sign-on failed: %v", err)
+		m.recordSSOFailure(c, err.Error())
+
+		reason := "sso"
+		if errors.Is(err, errors.ErrSSODenied) {
+			reason = "sso_denied"
+		}
+		m.redirectToLogin(c, reason)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditLogin, entities.AuditTargetUser, authenticatedUser.UUID.String())
+	event.ActorUUID = authenticatedUser.UUID
+	event.ActorEmail = authenticatedUser.Email
+	event.Detail = "single sign-on"
+	m.auditService.Record(c.Request.Context(), event)
+
+	sessionEntity, err := m.sessionService.CreateSession(c.Request.Context(), authenticatedUser.UUID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		log.Printf("oidc: failed to create session: %v", err)
+		m.redirectToLogin(c, "sso")
+		return
+	}
+
+	m.auth.SetSessionCookie(c, sessionEntity.Token, m.sessionService.MaxAge())
+	c.Redirect(http.StatusFound, m.auth.BasePath()+oidc.SafeRedirectPath(redirectPath))
+}
+
+// recordSSOFailure audits a failed single sign-on
+func (m *Module) recordSSOFailure(c *gin.Context, detail string) {
+	event := middlewares.NewAuditEvent(c, entities.AuditLoginFailed, "", "")
+	event.Detail = detail
+	m.auditService.Record(c.Request.Context(), event)
+}
+
+// redirectToLogin sends the browser back to the login page with an error
+func (m *Module) redirectToLogin(c *gin.Context, reason string) {
+	c.Redirect(http.StatusFound, m.auth.BasePath()+"/login?"+url.Values{"error": {reason}}.Encode())
+}
+
+// requirePasswordLogin refuses the password routes when single sign-on is the
+// only way to log in
+func (m *Module) requirePasswordLogin(c *gin.Context) {
+	if !m.passwordLogin {
+		respErr := errors.ToResponseError(errors.ErrPasswordLoginOff)
+		c.AbortWithStatusJSON(respErr.StatusCode, respErr)
+		return
+	}
+	c.Next()
+}
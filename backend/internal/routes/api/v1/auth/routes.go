@@ -1,56 +1,96 @@
 package auth
 
 import (
-	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
 	"github.com/gardarr/gardarr/internal/middlewares"
 	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/apitoken"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/forwardauth"
+	"github.com/gardarr/gardarr/internal/services/oidc"
 	"github.com/gardarr/gardarr/internal/services/ratelimit"
 	"github.com/gardarr/gardarr/internal/services/session"
+	"github.com/gardarr/gardarr/internal/services/twofactor"
 	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/gardarr/gardarr/pkg/errors"
 	"github.com/gin-gonic/gin"
-)
-
-const (
-	sessionCookieName = "session_token"
-	sessionMaxAge     = 7 * 24 * 60 * 60 // 7 days in seconds
+	"github.com/google/uuid"
 )
 
 type Module struct {
 	group          *gin.RouterGroup
 	userService    *user.Service
 	sessionService *session.Service
-	rateLimiter    *ratelimit.Service
-	db             *database.Database
+	tokenService   *apitoken.Service
+	twoFactor      *twofactor.Service
+	limits         *ratelimit.Limiters
+	auditService   *audit.Service
+	oidcService    *oidc.Service
+	forwardAuth    *forwardauth.Service
+	passwordLogin  bool
+	auth           *middlewares.Auth
 }
 
-func NewModule(router *gin.RouterGroup, db *database.Database) *Module {
-	return &Module{
+func NewModule(router *gin.RouterGroup, db *database.Database, c *crypto.CryptoService, auth *middlewares.Auth, limits *ratelimit.Limiters) *Module {
+	m := &Module{
 		group:          router.Group("/auth"),
 		userService:    user.NewService(db),
 		sessionService: session.NewService(db),
-		rateLimiter:    ratelimit.NewDefaultService(),
-		db:             db,
+		tokenService:   apitoken.NewService(db),
+		twoFactor:      twofactor.NewService(db, c),
+		limits:         limits,
+		auditService:   audit.NewService(db),
+		oidcService:    oidc.NewService(db),
+		forwardAuth:    forwardauth.NewService(db),
+		auth:           auth,
+	}
+
+	// Password login can only be turned off when single sign-on lets users in
+	m.passwordLogin = m.userService.PasswordLoginEnabled()
+	if !m.passwordLogin && !m.oidcService.Enabled() && !m.forwardAuth.Enabled() {
+		log.Println("auth: AUTH_PASSWORD_LOGIN_ENABLED=false ignored, single sign-on is not configured")
+		m.passwordLogin = true
 	}
+
+	return m
 }
 
 func (m *Module) Register() {
 	// Public routes
-	m.group.POST("/register", m.register)
-	m.group.POST("/login", m.login)
+	m.group.GET("/providers", m.getProviders)
+	m.group.POST("/register", m.requirePasswordLogin, middlewares.Throttle(m.limits.Register, middlewares.ClientKey), m.register)
+	m.group.POST("/login", m.requirePasswordLogin, m.login)
+	m.group.POST("/login/2fa", m.requirePasswordLogin, m.loginTwoFactor)
+	m.group.POST("/password/reset", m.requirePasswordLogin, m.resetPassword)
+	m.group.GET("/oidc/login", m.oidcLogin)
+	m.group.GET("/oidc/callback", m.oidcCallback)
 
 	// Protected routes
 	protected := m.group.Group("")
-	protected.Use(middlewares.SessionMiddleware(m.db))
+	protected.Use(m.auth.SessionMiddleware())
 	protected.GET("/me", m.getCurrentUser)
 	protected.POST("/logout", m.logout)
 	protected.POST("/logout-all", m.logoutAll)
 	protected.GET("/sessions", m.listSessions)
+	protected.DELETE("/sessions/:id", m.revokeSession)
+	protected.POST("/password", m.changePassword)
+	protected.GET("/tokens", m.listTokens)
+	protected.POST("/tokens", m.createToken)
+	protected.DELETE("/tokens/:id", m.revokeToken)
+	protected.GET("/2fa", m.getTwoFactor)
+	protected.POST("/2fa/totp", m.enrollTOTP)
+	protected.POST("/2fa/totp/confirm", m.confirmTOTP)
+	protected.POST("/2fa/totp/disable", m.disableTOTP)
+	protected.POST("/2fa/recovery-codes", m.regenerateRecoveryCodes)
 }
 
 // register handles user registration
@@ -64,8 +104,13 @@ func (m *Module) register(c *gin.Context) {
 		return
 	}
 
-	newUser, err := m.userService.CreateUser(c.Request.Context(), body.Email, body.Password)
+	newUser, err := m.userService.Register(c.Request.Context(), body.Email, body.Password, body.InviteToken)
 	if err != nil {
+		if errors.Is(err, errors.ErrRegistrationOff) {
+			errors.HandleError(c, err)
+			return
+		}
+
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "already exists") {
 			statusCode = http.StatusConflict
@@ -77,6 +122,8 @@ func (m *Module) register(c *gin.Context) {
 		return
 	}
 
+	m.record(c, newUser, entities.AuditRegister, entities.AuditTargetUser, newUser.UUID.String())
+
 	// Create session
 	userAgent := c.Request.UserAgent()
 	ipAddress := c.ClientIP()
@@ -87,30 +134,21 @@ func (m *Module) register(c *gin.Context) {
 	}
 
 	// Set secure cookie
-	m.setSessionCookie(c, sessionEntity.Token, sessionEntity.ExpiresAt.Unix())
+	m.auth.SetSessionCookie(c, sessionEntity.Token, m.sessionService.MaxAge())
 
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		User: models.UserResponse{
-			UUID:      newUser.UUID.String(),
-			Email:     newUser.Email,
-			CreatedAt: newUser.CreatedAt,
-		},
+		User: mappers.ToUserResponse(newUser),
 	})
 }
 
 // login handles user authentication with rate limiting
 func (m *Module) login(c *gin.Context) {
-	// Create identifier for rate limiting
+	// Failed logins are limited by client address and by account
 	ip := c.ClientIP()
-	userAgent := c.Request.UserAgent()
-	identifier := ratelimit.GetIdentifier(ip, userAgent)
 
 	// Check if blocked
-	if blocked, remaining := m.rateLimiter.IsBlocked(identifier); blocked {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error":               "Too many login attempts",
-			"retry_after_seconds": int(remaining.Seconds()),
-		})
+	if blocked, _ := m.limits.LoginIP.IsBlocked(ip); blocked {
+		middlewares.AbortLockedOut(c, m.limits.LoginIP, ip, "Too many login attempts")
 		return
 	}
 
@@ -123,40 +161,146 @@ func (m *Module) login(c *gin.Context) {
 		return
 	}
 
+	account := strings.ToLower(strings.TrimSpace(body.Email))
+	if blocked, _ := m.limits.LoginAccount.IsBlocked(account); blocked {
+		middlewares.AbortLockedOut(c, m.limits.LoginAccount, account, "Too many login attempts")
+		return
+	}
+
 	authenticatedUser, err := m.userService.VerifyPassword(c.Request.Context(), body.Email, body.Password)
 	if err != nil {
 		// Record failed attempt
-		m.rateLimiter.RecordAttempt(identifier)
+		event := middlewares.NewAuditEvent(c, entities.AuditLoginFailed, "", "")
+		event.ActorEmail = account
+		event.Detail = "invalid credentials"
+		m.auditService.Record(c.Request.Context(), event)
 
-		// Log suspicious activity
-		attemptCount := m.rateLimiter.GetAttemptCount(identifier)
-		if attemptCount > 3 {
-			fmt.Printf("[SECURITY] Multiple failed login attempts for %s from %s (count: %d)\n", body.Email, ip, attemptCount)
-		}
+		middlewares.RecordFailure(c, m.auditService, m.limits.LoginIP, ip)
+		middlewares.RecordFailure(c, m.auditService, m.limits.LoginAccount, account)
+		middlewares.SetRateLimitHeaders(c, m.limits.LoginIP.Policy(), m.limits.LoginIP.Status(ip))
 
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Users with a second factor get a challenge instead of a session, the
+	// rate limit is only reset once the challenge is answered
+	enabled, err := m.twoFactor.Enabled(c.Request.Context(), authenticatedUser.UUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if enabled {
+		// The wrong codes are counted against the account, which gets no new
+		// challenge while it is locked out
+		account := strings.ToLower(authenticatedUser.Email)
+		if blocked, _ := m.limits.LoginAccount.IsBlocked(account); blocked {
+			middlewares.AbortLockedOut(c, m.limits.LoginAccount, account, "Too many login attempts")
+			return
+		}
+
+		challenge, err := m.twoFactor.Challenge(c.Request.Context(), authenticatedUser.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create login challenge"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.LoginChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge.Token,
+			ExpiresAt:         challenge.ExpiresAt,
+		})
+		return
+	}
+
 	// Successful login - reset rate limit
-	m.rateLimiter.Reset(identifier)
+	m.limits.LoginIP.Reset(ip)
+	m.limits.LoginAccount.Reset(account)
 
-	// Create session
-	sessionEntity, err := m.sessionService.CreateSession(c.Request.Context(), authenticatedUser.UUID, userAgent, ip)
+	m.record(c, authenticatedUser, entities.AuditLogin, entities.AuditTargetUser, authenticatedUser.UUID.String())
+	m.startSession(c, authenticatedUser)
+}
+
+// loginTwoFactor answers the challenge of a login with a TOTP or recovery code
+func (m *Module) loginTwoFactor(c *gin.Context) {
+	identifier := c.ClientIP()
+
+	// Check if blocked
+	if blocked, _ := m.limits.LoginIP.IsBlocked(identifier); blocked {
+		middlewares.AbortLockedOut(c, m.limits.LoginIP, identifier, "Too many login attempts")
+		return
+	}
+
+	var body schemas.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Wrong codes are also limited by account, the address being easy to change
+	account := ""
+	if challengeUser, err := m.twoFactor.ChallengeUser(c.Request.Context(), body.ChallengeToken); err == nil {
+		account = strings.ToLower(challengeUser.Email)
+		if blocked, _ := m.limits.LoginAccount.IsBlocked(account); blocked {
+			middlewares.AbortLockedOut(c, m.limits.LoginAccount, account, "Too many login attempts")
+			return
+		}
+	}
+
+	authenticatedUser, err := m.twoFactor.CompleteChallenge(c.Request.Context(), body.ChallengeToken, body.Code, body.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, errors.ErrInvalidCode) {
+			event := middlewares.NewAuditEvent(c, entities.AuditLoginFailed, "", "")
+			event.ActorEmail = account
+			event.Detail = "invalid two-factor code"
+			m.auditService.Record(c.Request.Context(), event)
+
+			middlewares.RecordFailure(c, m.auditService, m.limits.LoginIP, identifier)
+			if account != "" {
+				middlewares.RecordFailure(c, m.auditService, m.limits.LoginAccount, account)
+			}
+		}
+		errors.HandleError(c, err)
+		return
+	}
+
+	// Successful login - reset rate limit
+	m.limits.LoginIP.Reset(identifier)
+	m.limits.LoginAccount.Reset(strings.ToLower(authenticatedUser.Email))
+
+	event := middlewares.NewAuditEvent(c, entities.AuditLogin, entities.AuditTargetUser, authenticatedUser.UUID.String())
+	event.ActorUUID = authenticatedUser.UUID
+	event.ActorEmail = authenticatedUser.Email
+	event.Detail = "two-factor authentication"
+	m.auditService.Record(c.Request.Context(), event)
+
+	m.startSession(c, authenticatedUser)
+}
+
+// record audits an action of an unauthenticated request, made by actor
+func (m *Module) record(c *gin.Context, actor *entities.User, action, targetType, targetID string) {
+	event := middlewares.NewAuditEvent(c, action, targetType, targetID)
+	event.ActorUUID = actor.UUID
+	event.ActorEmail = actor.Email
+	m.auditService.Record(c.Request.Context(), event)
+}
+
+// startSession creates a session for an authenticated user and sets its cookie
+func (m *Module) startSession(c *gin.Context, authenticatedUser *entities.User) {
+	sessionEntity, err := m.sessionService.CreateSession(c.Request.Context(), authenticatedUser.UUID, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
 
 	// Set secure cookie
-	m.setSessionCookie(c, sessionEntity.Token, sessionEntity.ExpiresAt.Unix())
+	m.auth.SetSessionCookie(c, sessionEntity.Token, m.sessionService.MaxAge())
 
 	c.JSON(http.StatusOK, models.AuthResponse{
-		User: models.UserResponse{
-			UUID:      authenticatedUser.UUID.String(),
-			Email:     authenticatedUser.Email,
-			CreatedAt: authenticatedUser.CreatedAt,
-		},
+		User: mappers.ToUserResponse(authenticatedUser),
 	})
 }
 
@@ -169,22 +313,29 @@ func (m *Module) getCurrentUser(c *gin.Context) {
 	}
 
 	currentUser := user.(*entities.User)
-	c.JSON(http.StatusOK, models.UserResponse{
-		UUID:      currentUser.UUID.String(),
-		Email:     currentUser.Email,
-		CreatedAt: currentUser.CreatedAt,
-	})
+	c.JSON(http.StatusOK, mappers.ToUserResponse(currentUser))
 }
 
 // logout invalidates the current session
 func (m *Module) logout(c *gin.Context) {
-	token, err := c.Cookie(sessionCookieName)
-	if err == nil && token != "" {
+	if token := m.auth.SessionToken(c); token != "" {
 		_ = m.sessionService.DeleteSession(c.Request.Context(), token)
 	}
 
+	targetID := ""
+	if currentSession, ok := middlewares.CurrentSession(c); ok {
+		targetID = currentSession.ID.String()
+	}
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditLogout, entities.AuditTargetSession, targetID))
+
 	// Clear cookie
-	c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+	m.auth.ClearSessionCookie(c)
+
+	// Behind an authenticating proxy, the browser must also log out from it
+	if logoutURL := m.forwardAuth.LogoutURL(); logoutURL != "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully", "logout_url": logoutURL})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
@@ -202,8 +353,10 @@ func (m *Module) logoutAll(c *gin.Context) {
 		return
 	}
 
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditLogoutAll, entities.AuditTargetUser, currentUser.UUID.String()))
+
 	// Clear cookie
-	c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+	m.auth.ClearSessionCookie(c)
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
 }
 
@@ -222,34 +375,270 @@ func (m *Module) listSessions(c *gin.Context) {
 		return
 	}
 
+	var currentID uuid.UUID
+	if currentSession, ok := middlewares.CurrentSession(c); ok {
+		currentID = currentSession.ID
+	}
+
 	response := make([]models.SessionResponse, len(sessions))
 	for i, s := range sessions {
-		response[i] = models.SessionResponse{
-			ID:        s.ID.String(),
-			UserAgent: s.UserAgent,
-			IPAddress: s.IPAddress,
-			CreatedAt: s.CreatedAt,
-			ExpiresAt: s.ExpiresAt,
-		}
+		response[i] = mappers.ToSessionResponse(s, currentID)
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// setSessionCookie sets a secure HTTP-only cookie with the session token
-func (m *Module) setSessionCookie(c *gin.Context, token string, expiresAt int64) {
-	// Use session max age
-	maxAge := sessionMaxAge
+// revokeSession invalidates one of the sessions of the current user. Revoking
+// the current session also clears its cookie.
+func (m *Module) revokeSession(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
 
-	// Set secure cookie
-	// In production, set Secure to true when using HTTPS
-	c.SetCookie(
-		sessionCookieName,
-		token,
-		maxAge,
-		"/",
-		"",    // domain
-		false, // secure - set to true in production with HTTPS
-		true,  // httpOnly
-	)
+	if err := m.sessionService.RevokeSession(c.Request.Context(), currentUser.UUID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditSessionRevoke, entities.AuditTargetSession, c.Param("id")))
+
+	if currentSession, ok := middlewares.CurrentSession(c); ok && currentSession.ID.String() == c.Param("id") {
+		m.auth.ClearSessionCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// changePassword replaces the password of the current user. Its other sessions
+// are ended and a new session replaces the current one.
+func (m *Module) changePassword(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var body schemas.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := m.userService.ChangePassword(c.Request.Context(), currentUser.UUID, body.CurrentPassword, body.NewPassword); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditPasswordChange, entities.AuditTargetUser, currentUser.UUID.String()))
+
+	m.startSession(c, currentUser)
+}
+
+// resetPassword sets a new password with a reset token issued by an admin or the CLI
+func (m *Module) resetPassword(c *gin.Context) {
+	identifier := c.ClientIP()
+
+	// Check if blocked
+	if blocked, _ := m.limits.LoginIP.IsBlocked(identifier); blocked {
+		middlewares.AbortLockedOut(c, m.limits.LoginIP, identifier, "Too many attempts")
+		return
+	}
+
+	var body schemas.PasswordResetRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := m.userService.ResetPassword(c.Request.Context(), body.Token, body.NewPassword); err != nil {
+		middlewares.RecordFailure(c, m.auditService, m.limits.LoginIP, identifier)
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.limits.LoginIP.Reset(identifier)
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditPasswordReset, "", ""))
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset, log in with the new password"})
+}
+
+// listTokens returns the API tokens of the current user
+func (m *Module) listTokens(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	tokens, err := m.tokenService.ListTokens(c.Request.Context(), currentUser.UUID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	response := make([]models.APITokenResponse, len(tokens))
+	for i, item := range tokens {
+		response[i] = mappers.ToAPITokenResponse(item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// createToken creates an API token for the current user, the token is only
+// returned in this response
+func (m *Module) createToken(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var body schemas.APITokenCreateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ttl := time.Duration(body.ExpiresInDays) * 24 * time.Hour
+	token, err := m.tokenService.CreateToken(c.Request.Context(), currentUser, body.Name, body.Scopes, ttl)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditTokenCreate, entities.AuditTargetAPIToken, token.ID.String())
+	event.Detail = token.Name
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, mappers.ToAPITokenResponse(token))
+}
+
+// revokeToken deletes an API token of the current user
+func (m *Module) revokeToken(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := m.tokenService.RevokeToken(c.Request.Context(), currentUser.UUID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditTokenRevoke, entities.AuditTargetAPIToken, c.Param("id")))
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
+
+// getTwoFactor returns the second factor status of the current user
+func (m *Module) getTwoFactor(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	status, err := m.twoFactor.Status(c.Request.Context(), currentUser.UUID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorStatusResponse{
+		Enabled:           status.Enabled,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// enrollTOTP generates a TOTP secret for the current user, to be confirmed
+func (m *Module) enrollTOTP(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	enrollment, err := m.twoFactor.Enroll(c.Request.Context(), currentUser)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+// confirmTOTP enables the second factor of the current user with a first code
+func (m *Module) confirmTOTP(c *gin.Context) {
+	m.withTwoFactorCode(c, func(currentUser *entities.User, code string) {
+		codes, err := m.twoFactor.Confirm(c.Request.Context(), currentUser.UUID, code)
+		if err != nil {
+			errors.HandleError(c, err)
+			return
+		}
+
+		m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditTwoFactorEnable, entities.AuditTargetUser, currentUser.UUID.String()))
+
+		c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// disableTOTP removes the second factor of the current user
+func (m *Module) disableTOTP(c *gin.Context) {
+	m.withTwoFactorCode(c, func(currentUser *entities.User, code string) {
+		if err := m.twoFactor.Disable(c.Request.Context(), currentUser.UUID, code); err != nil {
+			errors.HandleError(c, err)
+			return
+		}
+
+		m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditTwoFactorDisable, entities.AuditTargetUser, currentUser.UUID.String()))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	})
+}
+
+// regenerateRecoveryCodes replaces the recovery codes of the current user
+func (m *Module) regenerateRecoveryCodes(c *gin.Context) {
+	m.withTwoFactorCode(c, func(currentUser *entities.User, code string) {
+		codes, err := m.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), currentUser.UUID, code)
+		if err != nil {
+			errors.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// withTwoFactorCode binds the code of a request confirmed by a second factor
+func (m *Module) withTwoFactorCode(c *gin.Context, fn func(currentUser *entities.User, code string)) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var body schemas.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fn(currentUser, body.Code)
 }
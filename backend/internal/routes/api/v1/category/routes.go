@@ -8,36 +8,42 @@ import (
 	"github.com/gardarr/gardarr/internal/middlewares"
 	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
 	"github.com/gardarr/gardarr/internal/services/category"
 	"github.com/gin-gonic/gin"
 )
 
 // Module holds category routes configuration
 type Module struct {
-	group   *gin.RouterGroup
-	service *category.Service
-	db      *database.Database
+	group        *gin.RouterGroup
+	service      *category.Service
+	auditService *audit.Service
+	auth         *middlewares.Auth
 }
 
 // NewModule creates a new category module
-func NewModule(router *gin.RouterGroup, db *database.Database) *Module {
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth) *Module {
 	return &Module{
-		group:   router.Group("/categories"),
-		service: category.NewService(db),
-		db:      db,
+		group:        router.Group("/categories"),
+		service:      category.NewService(db),
+		auditService: audit.NewService(db),
+		auth:         auth,
 	}
 }
 
 // Register registers all category routes
 func (m *Module) Register() {
 	// Apply authentication middleware to all routes
-	m.group.Use(middlewares.SessionMiddleware(m.db))
+	m.group.Use(m.auth.SessionMiddleware())
 
-	m.group.POST("", m.createCategory)
-	m.group.GET("", m.listCategories)
-	m.group.GET("/:id", m.getCategoryByID)
-	m.group.PUT("/:id", m.updateCategory)
-	m.group.DELETE("/:id", m.deleteCategory)
+	// Every user can read categories, only admins manage them
+	admin := middlewares.RequireRole(entities.RoleAdmin)
+
+	m.group.POST("", admin, m.createCategory)
+	m.group.GET("", middlewares.RequireRole(entities.RoleViewer), m.listCategories)
+	m.group.GET("/:id", middlewares.RequireRole(entities.RoleViewer), m.getCategoryByID)
+	m.group.PUT("/:id", admin, m.updateCategory)
+	m.group.DELETE("/:id", admin, m.deleteCategory)
 }
 
 // createCategory creates a new category
@@ -69,6 +75,10 @@ func (m *Module) createCategory(c *gin.Context) {
 		return
 	}
 
+	event := middlewares.NewAuditEvent(c, entities.AuditCategoryCreate, entities.AuditTargetCategory, created.ID)
+	event.Detail = created.Name
+	m.auditService.Record(c.Request.Context(), event)
+
 	c.JSON(http.StatusCreated, m.toResponse(created))
 }
 
@@ -171,6 +181,10 @@ func (m *Module) updateCategory(c *gin.Context) {
 		return
 	}
 
+	event := middlewares.NewAuditEvent(c, entities.AuditCategoryUpdate, entities.AuditTargetCategory, id)
+	event.Detail = result.Name
+	m.auditService.Record(c.Request.Context(), event)
+
 	c.JSON(http.StatusOK, m.toResponse(result))
 }
 
@@ -191,6 +205,8 @@ func (m *Module) deleteCategory(c *gin.Context) {
 		return
 	}
 
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditCategoryDelete, entities.AuditTargetCategory, id))
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -22,7 +22,7 @@ func setupTestDB(t *testing.T) *database.Database {
 	}
 
 	// Run migrations
-	if err := db.AutoMigrate(&models.Category{}, &models.User{}, &models.Session{}); err != nil {
+	if err := db.AutoMigrate(&models.Category{}, &models.User{}, &models.Session{}, &models.AuditEvent{}); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
@@ -39,7 +39,7 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *database.Database) {
 	v1 := router.Group("/api/v1")
 
 	// Register category routes without middleware for testing
-	module := NewModule(v1, db)
+	module := NewModule(v1, db, nil)
 	categoriesGroup := v1.Group("/categories")
 
 	categoriesGroup.POST("", module.createCategory)
@@ -0,0 +1,135 @@
+package enrollment
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/enrollment"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// Module holds the agent enrollment routes
+type Module struct {
+	group        *gin.RouterGroup
+	service      *enrollment.Service
+	auditService *audit.Service
+	limits       *ratelimit.Limiters
+	auth         *middlewares.Auth
+}
+
+// NewModule creates a new agent enrollment module
+func NewModule(router *gin.RouterGroup, db *database.Database, c *crypto.CryptoService, auth *middlewares.Auth, limits *ratelimit.Limiters) *Module {
+	return &Module{
+		group:        router.Group("/enrollment"),
+		service:      enrollment.NewService(db, c),
+		auditService: audit.NewService(db),
+		limits:       limits,
+		auth:         auth,
+	}
+}
+
+// Register registers the agent enrollment routes. Admins issue the tokens,
+// agents enroll with them without any other credential.
+func (m *Module) Register() {
+	m.group.POST("/", middlewares.Throttle(m.limits.Register, middlewares.ClientKey), m.enroll)
+
+	tokens := m.group.Group("/tokens")
+	tokens.Use(m.auth.AuthMiddleware(), middlewares.Authorize(entities.RoleAdmin, entities.ScopeAgentsAdmin))
+	tokens.GET("", m.listTokens)
+	tokens.POST("", m.createToken)
+	tokens.DELETE("/:id", m.revokeToken)
+}
+
+// enroll registers the agent presenting an enrollment token and answers the
+// secret generated for it
+func (m *Module) enroll(c *gin.Context) {
+	var body schemas.AgentEnrollRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.Enroll(c.Request.Context(), &body)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditAgentEnroll, entities.AuditTargetAgent, result.Agent.UUID.String())
+	event.Detail = result.Agent.Name + " with enrollment token " + result.Token.ID.String()
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, models.AgentEnrollResponse{
+		AgentUUID: result.Agent.UUID.String(),
+		Name:      result.Agent.Name,
+		Secret:    result.Secret,
+	})
+}
+
+// listTokens returns the enrollment tokens
+func (m *Module) listTokens(c *gin.Context) {
+	tokens, err := m.service.ListTokens(c.Request.Context())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	response := make([]models.AgentEnrollmentTokenResponse, len(tokens))
+	for i, item := range tokens {
+		response[i] = mappers.ToEnrollmentTokenResponse(item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// createToken issues an enrollment token, the token is only returned in this response
+func (m *Module) createToken(c *gin.Context) {
+	currentUser, ok := middlewares.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var body schemas.EnrollmentTokenCreateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	ttl := time.Duration(body.ExpiresInMinutes) * time.Minute
+	token, err := m.service.CreateToken(c.Request.Context(), currentUser.UUID, body.Name, ttl)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditEnrollmentCreate, entities.AuditTargetEnrollmentToken, token.ID.String())
+	event.Detail = token.Name
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, mappers.ToEnrollmentTokenResponse(token))
+}
+
+// revokeToken deletes an enrollment token not used yet
+func (m *Module) revokeToken(c *gin.Context) {
+	if err := m.service.RevokeToken(c.Request.Context(), c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.auditService.Record(c.Request.Context(), middlewares.NewAuditEvent(c, entities.AuditEnrollmentRevoke, entities.AuditTargetEnrollmentToken, c.Param("id")))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Enrollment token revoked"})
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/infra/events"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/services/eventhub"
+	"github.com/gardarr/gardarr/internal/services/session"
+	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// accessCheckInterval is how often an open stream checks that its session is
+// still valid and reloads the role and grants of its user
+const accessCheckInterval = 30 * time.Second
+
+// Module holds live events routes configuration
+type Module struct {
+	group         *gin.RouterGroup
+	hub           *eventhub.Hub
+	users         *user.Service
+	sessions      *session.Service
+	auth          *middlewares.Auth
+	checkInterval time.Duration
+}
+
+// NewModule creates a new live events module
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth, hub *eventhub.Hub) *Module {
+	return &Module{
+		group:         router.Group("/events"),
+		hub:           hub,
+		users:         user.NewService(db),
+		sessions:      session.NewService(db),
+		auth:          auth,
+		checkInterval: accessCheckInterval,
+	}
+}
+
+// Register registers the live events stream
+func (m *Module) Register() {
+	m.group.Use(m.auth.SessionMiddleware())
+
+	m.group.GET("", middlewares.RequireRole(entities.RoleViewer), m.stream)
+}
+
+// stream sends a snapshot per connected agent, followed by the task and instance
+// changes of every agent as Server-Sent Events. Users only receive the events
+// of the agents they were granted. The access is checked again on each
+// checkInterval: the stream ends once the session is revoked or expired, or
+// the user disabled or demoted, and follows the changes of the grants.
+func (m *Module) stream(c *gin.Context) {
+	currentUser, _ := middlewares.CurrentUser(c)
+	currentSession, _ := middlewares.CurrentSession(c)
+	ctx := c.Request.Context()
+
+	allowed, err := m.users.AgentFilter(ctx, currentUser)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	initial, sub := m.hub.Subscribe()
+	defer sub.Close()
+
+	visible := make([]*entities.Event, 0, len(initial))
+	for _, event := range initial {
+		if allowed(event.AgentUUID) {
+			visible = append(visible, event)
+		}
+	}
+
+	filtered := make(chan *entities.Event)
+	go func() {
+		defer close(filtered)
+
+		check := time.NewTicker(m.checkInterval)
+		defer check.Stop()
+
+		done := ctx.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-check.C:
+				if allowed, err = m.access(ctx, currentSession); err != nil {
+					return
+				}
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if !allowed(event.AgentUUID) {
+					continue
+				}
+
+				select {
+				case filtered <- event:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	events.Stream(c, visible, filtered)
+}
+
+// access reloads the user of a session still valid and returns the agents they
+// may see, or an error when they lost access to the stream
+func (m *Module) access(ctx context.Context, currentSession *entities.Session) (func(uuid.UUID) bool, error) {
+	if currentSession == nil {
+		return nil, errors.ErrSessionNotFound
+	}
+
+	currentUser, err := m.sessions.CheckSession(ctx, currentSession.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !currentUser.HasRole(entities.RoleViewer) {
+		return nil, errors.ErrForbidden
+	}
+
+	return m.users.AgentFilter(ctx, currentUser)
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/eventhub"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestStream serves the events stream to the user of a new session,
+// without the session middleware, and checks the access every few milliseconds
+func setupTestStream(t *testing.T) (*database.Database, *Module, *entities.Session, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.Agent{}, &models.AgentPermission{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	testDB := &database.Database{DB: db}
+
+	testUser := &models.User{Email: "viewer@example.com", PasswordHash: "hash", Salt: "salt", Role: entities.RoleViewer}
+	if err := db.Create(testUser).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	router := gin.New()
+	module := NewModule(router.Group("/api/v1"), testDB, nil, eventhub.NewHub(nil, eventhub.DefaultConfig()))
+	module.checkInterval = 10 * time.Millisecond
+
+	currentSession, err := module.sessions.CreateSession(context.Background(), testUser.UUID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	router.GET("/api/v1/events", func(c *gin.Context) {
+		c.Set(middlewares.UserContextKey, &entities.User{UUID: testUser.UUID, Email: testUser.Email, Role: testUser.Role})
+		c.Set(middlewares.SessionContextKey, currentSession)
+		c.Next()
+	}, module.stream)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	// Runs first: a stream left open would keep Close waiting
+	t.Cleanup(server.CloseClientConnections)
+
+	return testDB, module, currentSession, server
+}
+
+// openStream connects to the events stream and returns a channel closed once
+// the server ends it
+func openStream(t *testing.T, server *httptest.Server) <-chan struct{} {
+	resp, err := http.Get(server.URL + "/api/v1/events")
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+	}()
+
+	select {
+	case <-ended:
+		t.Fatal("Expected the stream to stay open while the session is valid")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	return ended
+}
+
+func TestRoutes_Stream_EndsWhenSessionRevoked(t *testing.T) {
+	_, module, currentSession, server := setupTestStream(t)
+	ended := openStream(t, server)
+
+	if err := module.sessions.RevokeSession(context.Background(), currentSession.UserUUID, currentSession.ID.String()); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	select {
+	case <-ended:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the stream to end after the session was revoked")
+	}
+}
+
+func TestRoutes_Stream_EndsWhenUserLosesAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		value  any
+	}{
+		{"disabled", "disabled", true},
+		{"demoted", "role", "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _, currentSession, server := setupTestStream(t)
+			ended := openStream(t, server)
+
+			if err := db.DB.Model(&models.User{}).Where("uuid = ?", currentSession.UserUUID).Update(tt.column, tt.value).Error; err != nil {
+				t.Fatalf("Failed to update user: %v", err)
+			}
+
+			select {
+			case <-ended:
+			case <-time.After(2 * time.Second):
+				t.Errorf("Expected the stream to end once the user is %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimits
+
+import (
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/ratelimit"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// Module holds the rate limiting administration routes
+type Module struct {
+	group        *gin.RouterGroup
+	limits       *ratelimit.Limiters
+	auditService *audit.Service
+	auth         *middlewares.Auth
+}
+
+// NewModule creates a new rate limiting administration module
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth, limits *ratelimit.Limiters) *Module {
+	return &Module{
+		group:        router.Group("/ratelimits"),
+		limits:       limits,
+		auditService: audit.NewService(db),
+		auth:         auth,
+	}
+}
+
+// Register registers the rate limiting administration routes, reserved to admins
+func (m *Module) Register() {
+	m.group.Use(m.auth.SessionMiddleware(), middlewares.RequireRole(entities.RoleAdmin))
+
+	m.group.GET("", m.listPolicies)
+	m.group.POST("/:policy/unblock", m.unblock)
+}
+
+// listPolicies returns the statistics and the blocked identifiers of every policy
+func (m *Module) listPolicies(c *gin.Context) {
+	services := m.limits.All()
+	response := make([]models.RateLimitPolicyResponse, 0, len(services))
+
+	for _, service := range services {
+		blocked, err := service.Blocked(c.Request.Context())
+		if err != nil {
+			errors.HandleError(c, err)
+			return
+		}
+
+		response = append(response, mappers.ToRateLimitPolicyResponse(service.Policy(), service.GetStats(), blocked))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// unblock lifts the block of an identifier under a policy
+func (m *Module) unblock(c *gin.Context) {
+	service, ok := m.limits.Get(c.Param("policy"))
+	if !ok {
+		respErr := errors.NewNotFoundError("Rate limit policy not found", nil)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	var body schemas.RateLimitUnblockRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	if err := service.Unblock(body.Identifier); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditRateLimitUnblock, "", "")
+	event.Detail = body.Identifier + " under the " + service.Policy().Name + " policy"
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identifier unblocked"})
+}
@@ -0,0 +1,201 @@
+package rules
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/rule"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultExecutionLimit = 100
+	maxExecutionLimit     = 1000
+)
+
+// Module holds automation rules routes configuration
+type Module struct {
+	group   *gin.RouterGroup
+	service *rule.Service
+	auth    *middlewares.Auth
+}
+
+// NewModule creates a new automation rules module
+func NewModule(router *gin.RouterGroup, auth *middlewares.Auth, svc *rule.Service) *Module {
+	return &Module{
+		group:   router.Group("/rules"),
+		service: svc,
+		auth:    auth,
+	}
+}
+
+// Register registers all automation rules routes
+func (m *Module) Register() {
+	// Rules act on the tasks of every agent, they are managed by admins
+	m.group.Use(m.auth.SessionMiddleware(), middlewares.RequireRole(entities.RoleAdmin))
+
+	m.group.GET("", m.listRules)
+	m.group.POST("", m.createRule)
+	m.group.POST("/preview", m.previewRule)
+	m.group.GET("/executions", m.listExecutions)
+	m.group.GET("/:id", m.getRule)
+	m.group.PUT("/:id", m.updateRule)
+	m.group.DELETE("/:id", m.deleteRule)
+	m.group.POST("/:id/dry-run", m.dryRunRule)
+	m.group.POST("/:id/run", m.runRule)
+	m.group.GET("/:id/executions", m.listRuleExecutions)
+}
+
+func (m *Module) listRules(c *gin.Context) {
+	result, err := m.service.ListRules(c.Request.Context())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := make([]models.RuleResponse, len(result))
+	for i, item := range result {
+		resp[i] = mappers.ToRuleResponse(item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (m *Module) createRule(c *gin.Context) {
+	var payload schemas.RuleCreateSchema
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		badRequest(c, "Invalid request body", err)
+		return
+	}
+
+	result, err := m.service.CreateRule(c.Request.Context(), payload)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, mappers.ToRuleResponse(result))
+}
+
+func (m *Module) previewRule(c *gin.Context) {
+	var payload schemas.RuleCreateSchema
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		badRequest(c, "Invalid request body", err)
+		return
+	}
+
+	result, err := m.service.Preview(c.Request.Context(), payload)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toExecutionsResponse(result))
+}
+
+func (m *Module) getRule(c *gin.Context) {
+	result, err := m.service.GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToRuleResponse(result))
+}
+
+func (m *Module) updateRule(c *gin.Context) {
+	var payload schemas.RuleUpdateSchema
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		badRequest(c, "Invalid request body", err)
+		return
+	}
+
+	result, err := m.service.UpdateRule(c.Request.Context(), c.Param("id"), payload)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToRuleResponse(result))
+}
+
+func (m *Module) deleteRule(c *gin.Context) {
+	if err := m.service.DeleteRule(c.Request.Context(), c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (m *Module) dryRunRule(c *gin.Context) {
+	result, err := m.service.DryRun(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toExecutionsResponse(result))
+}
+
+func (m *Module) runRule(c *gin.Context) {
+	result, err := m.service.RunRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toExecutionsResponse(result))
+}
+
+func (m *Module) listExecutions(c *gin.Context) {
+	m.executions(c, c.Query("rule"))
+}
+
+func (m *Module) listRuleExecutions(c *gin.Context) {
+	if _, err := m.service.GetRule(c.Request.Context(), c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.executions(c, c.Param("id"))
+}
+
+func (m *Module) executions(c *gin.Context, ruleID string) {
+	limit := defaultExecutionLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxExecutionLimit {
+			badRequest(c, "Invalid 'limit', expected a number between 1 and 1000", err)
+			return
+		}
+		limit = parsed
+	}
+
+	result, err := m.service.ListExecutions(c.Request.Context(), ruleID, c.Query("agent"), limit)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toExecutionsResponse(result))
+}
+
+func toExecutionsResponse(items []*entities.RuleExecution) []models.RuleExecutionResponse {
+	resp := make([]models.RuleExecutionResponse, len(items))
+	for i, item := range items {
+		resp[i] = mappers.ToRuleExecutionResponse(item)
+	}
+	return resp
+}
+
+func badRequest(c *gin.Context, message string, err error) {
+	respErr := errors.NewBadRequestError(message, err)
+	c.JSON(respErr.StatusCode, respErr)
+}
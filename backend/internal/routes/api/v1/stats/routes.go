@@ -0,0 +1,170 @@
+package stats
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/stats"
+	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPoints bounds the size of a series response
+const maxPoints = 5000
+
+// Module holds transfer statistics routes configuration
+type Module struct {
+	group   *gin.RouterGroup
+	service *stats.Service
+	users   *user.Service
+	auth    *middlewares.Auth
+	db      *database.Database
+}
+
+// NewModule creates a new transfer statistics module
+func NewModule(router *gin.RouterGroup, db *database.Database, auth *middlewares.Auth, svc *stats.Service) *Module {
+	return &Module{
+		group:   router.Group("/stats"),
+		service: svc,
+		users:   user.NewService(db),
+		auth:    auth,
+		db:      db,
+	}
+}
+
+// Register registers all transfer statistics routes
+func (m *Module) Register() {
+	m.group.Use(m.auth.SessionMiddleware(), middlewares.RequireRole(entities.RoleViewer))
+
+	agentAccess := middlewares.RequireAgentAccess(m.db, "id")
+
+	m.group.GET("/agents/:id", agentAccess, m.getAgentSeries)
+	m.group.GET("/agents/:id/tasks/:task_id", agentAccess, m.getTaskSeries)
+	m.group.GET("/categories", m.listCategorySeries)
+}
+
+func (m *Module) getAgentSeries(c *gin.Context) {
+	from, to, step, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	result, err := m.service.AgentSeries(c.Request.Context(), c.Param("id"), from, to, step)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTransferSeriesResponse(result))
+}
+
+func (m *Module) getTaskSeries(c *gin.Context) {
+	from, to, step, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	result, err := m.service.TaskSeries(c.Request.Context(), c.Param("id"), c.Param("task_id"), from, to, step)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTransferSeriesResponse(result))
+}
+
+func (m *Module) listCategorySeries(c *gin.Context) {
+	from, to, step, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	// The series of every agent are only available to admins
+	agentID := c.Query("agent")
+	currentUser, _ := middlewares.CurrentUser(c)
+	if agentID == "" && !currentUser.IsAdmin() {
+		errors.HandleError(c, errors.ErrForbidden)
+		return
+	}
+
+	allowed, err := m.users.CanAccessAgent(c.Request.Context(), currentUser, agentID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+	if !allowed {
+		errors.HandleError(c, errors.ErrForbidden)
+		return
+	}
+
+	result, err := m.service.CategorySeries(c.Request.Context(), agentID, from, to, step)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := make([]models.TransferSeriesResponse, len(result))
+	for i, item := range result {
+		resp[i] = mappers.ToTransferSeriesResponse(item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseRange reads the from, to (RFC 3339) and step query parameters. The range
+// defaults to the last 24 hours. It writes the error response and returns false
+// when a parameter is invalid.
+func parseRange(c *gin.Context) (time.Time, time.Time, time.Duration, bool) {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			badRequest(c, "Invalid 'to', expected an RFC 3339 date", err)
+			return time.Time{}, time.Time{}, 0, false
+		}
+		to = parsed.UTC()
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			badRequest(c, "Invalid 'from', expected an RFC 3339 date", err)
+			return time.Time{}, time.Time{}, 0, false
+		}
+		from = parsed.UTC()
+	}
+
+	if !from.Before(to) {
+		badRequest(c, "'from' must be before 'to'", nil)
+		return time.Time{}, time.Time{}, 0, false
+	}
+
+	step := stats.DefaultStep(from, to)
+	if value := c.Query("step"); value != "" {
+		parsed, err := stats.ParseStep(value)
+		if err != nil {
+			badRequest(c, "Invalid 'step'", err)
+			return time.Time{}, time.Time{}, 0, false
+		}
+		step = parsed
+	}
+
+	if to.Sub(from)/step > maxPoints {
+		badRequest(c, "Range too large for the requested step", nil)
+		return time.Time{}, time.Time{}, 0, false
+	}
+
+	return from, to, step, true
+}
+
+func badRequest(c *gin.Context, message string, err error) {
+	respErr := errors.NewBadRequestError(message, err)
+	c.JSON(respErr.StatusCode, respErr)
+}
@@ -0,0 +1,79 @@
+package torrents
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gardarr/gardarr/pkg/torrent"
+	"github.com/gin-gonic/gin"
+)
+
+// Module holds the routes reading .torrent files, without any agent
+type Module struct {
+	group *gin.RouterGroup
+	auth  *middlewares.Auth
+}
+
+// NewModule creates a new torrents module
+func NewModule(router *gin.RouterGroup, auth *middlewares.Auth) *Module {
+	return &Module{
+		group: router.Group("/torrents"),
+		auth:  auth,
+	}
+}
+
+// Register registers the torrents routes, open to the users able to read tasks
+func (m *Module) Register() {
+	m.group.Use(m.auth.AuthMiddleware(), middlewares.Authorize(entities.RoleViewer, entities.ScopeTasksRead))
+
+	m.group.POST("/inspect", m.inspectTorrent)
+}
+
+// inspectTorrent decodes a .torrent file and describes its metainfo
+func (m *Module) inspectTorrent(c *gin.Context) {
+	body, err := bindTorrentInspect(c)
+	if err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	meta, err := torrent.ParseMetaInfo(body.Torrent)
+	if err != nil {
+		respErr := errors.NewBadRequestError("Invalid torrent file", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToTorrentInspectResponse(meta))
+}
+
+// bindTorrentInspect binds a JSON body, or a multipart form whose torrent part
+// is the .torrent file
+func bindTorrentInspect(c *gin.Context) (schemas.TorrentInspectSchema, error) {
+	var body schemas.TorrentInspectSchema
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, err := c.FormFile("torrent")
+		if err != nil {
+			return body, fmt.Errorf("torrent is required")
+		}
+		return body, body.ReadTorrent(file)
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return body, err
+	}
+	if len(body.Torrent) == 0 {
+		return body, fmt.Errorf("torrent is required")
+	}
+	if len(body.Torrent) > schemas.MaxTorrentSize {
+		return body, fmt.Errorf("torrent file larger than %d bytes", schemas.MaxTorrentSize)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,105 @@
+package torrents
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const testTorrent = "d8:announce30:http://tracker.example.org/ann7:comment4:test4:infod6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaa7:privatei1eee"
+
+// setupTestRouter creates a test router with the torrents routes, without
+// the authentication middleware
+func setupTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	module := NewModule(router.Group("/api/v1"), nil)
+	router.POST("/api/v1/torrents/inspect", module.inspectTorrent)
+
+	return router
+}
+
+func TestRoutes_InspectTorrent_JSON(t *testing.T) {
+	router := setupTestRouter()
+
+	payload, _ := json.Marshal(map[string][]byte{"torrent": []byte(testTorrent)})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/torrents/inspect", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.TorrentInspectResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Name != "file.iso" || response.Version != "v1" || response.Size != 1024 || response.PieceCount != 1 {
+		t.Errorf("Expected a v1 torrent of one piece, got %+v", response)
+	}
+	if !response.Private || response.Comment != "test" || len(response.InfoHashV1) != 40 {
+		t.Errorf("Expected a private torrent with its comment and infohash, got %+v", response)
+	}
+	if len(response.Trackers) != 1 || response.Trackers[0][0] != "http://tracker.example.org/ann" {
+		t.Errorf("Expected the announce as the single tier, got %v", response.Trackers)
+	}
+	if response.MagnetURI != "magnet:?xt=urn:btih:"+response.InfoHashV1+"&dn=file.iso&xl=1024&tr=http%3A%2F%2Ftracker.example.org%2Fann" {
+		t.Errorf("Unexpected magnet link %s", response.MagnetURI)
+	}
+}
+
+func TestRoutes_InspectTorrent_Multipart(t *testing.T) {
+	router := setupTestRouter()
+
+	var payload bytes.Buffer
+	writer := multipart.NewWriter(&payload)
+	part, _ := writer.CreateFormFile("torrent", "file.torrent")
+	_, _ = part.Write([]byte(testTorrent))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/torrents/inspect", &payload)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRoutes_InspectTorrent_Invalid(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing torrent", `{}`},
+		{"invalid JSON", `{"torrent":`},
+		{"not bencode", `{"torrent": "bm90IGEgdG9ycmVudA=="}`},
+		{"no info dictionary", `{"torrent": "ZDQ6bmFtZTE6YWU="}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/torrents/inspect", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
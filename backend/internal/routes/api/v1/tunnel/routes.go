@@ -0,0 +1,59 @@
+package tunnel
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
+	"github.com/gardarr/gardarr/internal/services/agentmanager"
+	"github.com/gin-gonic/gin"
+)
+
+// Module holds the route the agents open their tunnel with
+type Module struct {
+	group        *gin.RouterGroup
+	agentService *agentmanager.Service
+}
+
+// NewModule creates a new agent tunnel module
+func NewModule(router *gin.RouterGroup, a *agentmanager.Service) *Module {
+	return &Module{
+		group:        router.Group("/tunnel"),
+		agentService: a,
+	}
+}
+
+// Register registers the agent tunnel route. The agents authenticate with a
+// request signed with their secret, no session is involved.
+func (m *Module) Register() {
+	m.group.GET("", m.connect)
+}
+
+// connect upgrades the request of an agent into its tunnel, the manager
+// sending its requests to the agent over it from then on
+func (m *Module) connect(c *gin.Context) {
+	agent, err := m.agentService.AuthenticateTunnel(c.Request)
+	if err != nil {
+		log.Printf("agent tunnel: refused tunnel from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid agent credentials"})
+		return
+	}
+
+	conn, err := agenttunnel.Upgrade(c.Writer, c.Request)
+	if errors.Is(err, agenttunnel.ErrUpgradeRequired) {
+		c.JSON(http.StatusUpgradeRequired, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("agent tunnel: failed to upgrade the tunnel of agent %s: %v", agent.Name, err)
+		return
+	}
+
+	if err := m.agentService.Tunnels().Open(agent.UUID, conn); err != nil {
+		log.Printf("agent tunnel: failed to open the tunnel of agent %s: %v", agent.Name, err)
+		return
+	}
+
+	log.Printf("agent tunnel: agent %s connected from %s", agent.Name, c.ClientIP())
+}
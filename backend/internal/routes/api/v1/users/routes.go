@@ -0,0 +1,213 @@
+package users
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/mappers"
+	"github.com/gardarr/gardarr/internal/middlewares"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/audit"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/twofactor"
+	"github.com/gardarr/gardarr/internal/services/user"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// Module holds user management routes configuration
+type Module struct {
+	group        *gin.RouterGroup
+	service      *user.Service
+	twoFactor    *twofactor.Service
+	auditService *audit.Service
+	auth         *middlewares.Auth
+}
+
+// NewModule creates a new user management module
+func NewModule(router *gin.RouterGroup, db *database.Database, c *crypto.CryptoService, auth *middlewares.Auth) *Module {
+	return &Module{
+		group:        router.Group("/users"),
+		service:      user.NewService(db),
+		twoFactor:    twofactor.NewService(db, c),
+		auditService: audit.NewService(db),
+		auth:         auth,
+	}
+}
+
+// Register registers all user management routes, reserved to admins
+func (m *Module) Register() {
+	m.group.Use(m.auth.SessionMiddleware(), middlewares.RequireRole(entities.RoleAdmin))
+
+	m.group.GET("", m.listUsers)
+	m.group.POST("/invites", m.inviteUser)
+	m.group.PUT("/:id/role", m.setUserRole)
+	m.group.POST("/:id/disable", m.disableUser)
+	m.group.POST("/:id/enable", m.enableUser)
+	m.group.GET("/:id/agents", m.getUserAgents)
+	m.group.PUT("/:id/agents", m.setUserAgents)
+	m.group.DELETE("/:id/2fa", m.resetUserTwoFactor)
+	m.group.POST("/:id/password-reset", m.issuePasswordReset)
+}
+
+func (m *Module) listUsers(c *gin.Context) {
+	result, err := m.service.ListUsers(c.Request.Context())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := make([]models.UserResponse, len(result))
+	for i, item := range result {
+		resp[i] = mappers.ToUserResponse(item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (m *Module) inviteUser(c *gin.Context) {
+	var body schemas.UserInviteRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	currentUser, _ := middlewares.CurrentUser(c)
+	result, err := m.service.Invite(c.Request.Context(), currentUser, body.Email, body.Role)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	event := middlewares.NewAuditEvent(c, entities.AuditUserInvite, "", "")
+	event.Detail = result.Email + " as " + result.Role
+	m.auditService.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, mappers.ToUserInviteResponse(result))
+}
+
+func (m *Module) setUserRole(c *gin.Context) {
+	var body schemas.UserRoleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.SetRole(c.Request.Context(), c.Param("id"), body.Role)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserRoleChange, result.UUID.String(), result.Email+" is now "+result.Role)
+
+	c.JSON(http.StatusOK, mappers.ToUserResponse(result))
+}
+
+func (m *Module) disableUser(c *gin.Context) {
+	id := c.Param("id")
+
+	// An admin disabling itself would lock itself out
+	if currentUser, _ := middlewares.CurrentUser(c); currentUser.UUID.String() == id {
+		respErr := errors.NewBadRequestError("You cannot disable your own account", nil)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.SetDisabled(c.Request.Context(), id, true)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserDisable, id, result.Email)
+
+	c.JSON(http.StatusOK, mappers.ToUserResponse(result))
+}
+
+func (m *Module) enableUser(c *gin.Context) {
+	result, err := m.service.SetDisabled(c.Request.Context(), c.Param("id"), false)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserEnable, c.Param("id"), result.Email)
+
+	c.JSON(http.StatusOK, mappers.ToUserResponse(result))
+}
+
+func (m *Module) getUserAgents(c *gin.Context) {
+	result, err := m.service.AgentGrants(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappers.ToUserAgentsResponse(result))
+}
+
+func (m *Module) setUserAgents(c *gin.Context) {
+	var body schemas.UserAgentsRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respErr := errors.NewBadRequestError("Invalid request body", err)
+		c.JSON(respErr.StatusCode, respErr)
+		return
+	}
+
+	result, err := m.service.SetAgentGrants(c.Request.Context(), c.Param("id"), body.Agents)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserAgents, c.Param("id"), strings.Join(body.Agents, ", "))
+
+	c.JSON(http.StatusOK, mappers.ToUserAgentsResponse(result))
+}
+
+// resetUserTwoFactor removes the second factor of a user who lost it
+func (m *Module) resetUserTwoFactor(c *gin.Context) {
+	target, err := m.service.GetUserByUUID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := m.twoFactor.Reset(c.Request.Context(), target.UUID); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserTwoFactor, target.UUID.String(), target.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// issuePasswordReset creates a password reset token for an admin to hand to a user
+func (m *Module) issuePasswordReset(c *gin.Context) {
+	result, err := m.service.IssuePasswordReset(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	m.record(c, entities.AuditUserPasswordReset, c.Param("id"), "")
+
+	c.JSON(http.StatusCreated, models.PasswordResetResponse{
+		Token:     result.Token,
+		ExpiresAt: result.ExpiresAt,
+	})
+}
+
+// record audits an administrative action on a user
+func (m *Module) record(c *gin.Context, action, userID, detail string) {
+	event := middlewares.NewAuditEvent(c, action, entities.AuditTargetUser, userID)
+	event.Detail = detail
+	m.auditService.Record(c.Request.Context(), event)
+}
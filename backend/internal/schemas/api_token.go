@@ -0,0 +1,8 @@
+package schemas
+
+// APITokenCreateRequest represents the request body for creating an API token
+type APITokenCreateRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=tasks:read tasks:write agents:admin"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"`
+}
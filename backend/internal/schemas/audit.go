@@ -0,0 +1,16 @@
+package schemas
+
+import "time"
+
+// AuditEventQuery holds the filters and the page of an audit log listing
+type AuditEventQuery struct {
+	Action     string    `form:"action"` // comma separated actions
+	Actor      string    `form:"actor"`  // UUID of the user behind the events
+	IPAddress  string    `form:"ip"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	Since      time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until      time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int       `form:"limit"  binding:"omitempty,min=1,max=1000"`
+	Offset     int       `form:"offset" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,20 @@
+package schemas
+
+// EnrollmentTokenCreateRequest represents the request body for issuing an
+// agent enrollment token
+type EnrollmentTokenCreateRequest struct {
+	// Name given to the agent, else the one it sends
+	Name             string `json:"name" binding:"omitempty,max=100"`
+	ExpiresInMinutes int    `json:"expires_in_minutes" binding:"omitempty,min=1"`
+}
+
+// AgentEnrollRequest represents the request body of an agent registering
+// itself with an enrollment token
+type AgentEnrollRequest struct {
+	Token   string `json:"token"   binding:"required"`
+	Name    string `json:"name"    binding:"required,max=100"`
+	Type    string `json:"type"    binding:"required,instancetype"`
+	Address string `json:"address" binding:"required,max=600"`
+	// CertificateFingerprint pins the TLS certificate the agent serves
+	CertificateFingerprint string `json:"certificate_fingerprint" binding:"omitempty"`
+}
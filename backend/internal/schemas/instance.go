@@ -14,6 +14,8 @@ type AgentCreateSchema struct {
 	Token   string `json:"token"    binding:"required"`
 	Icon    string `json:"icon"     binding:"omitempty,max=100"`
 	Color   string `json:"color"    binding:"omitempty,max=50"`
+	// CertificateFingerprint pins the agent certificate, SHA-256 in hex
+	CertificateFingerprint string `json:"certificate_fingerprint" binding:"omitempty"`
 }
 
 // AgentUpdateSchema represents the request body for updating an agent
@@ -23,10 +25,15 @@ type AgentUpdateSchema struct {
 	Token   string `json:"token"    binding:"omitempty"`
 	Icon    string `json:"icon"     binding:"omitempty,max=100"`
 	Color   string `json:"color"    binding:"omitempty,max=50"`
+	// CertificateFingerprint replaces the pinned agent certificate, an empty
+	// string removes the pin
+	CertificateFingerprint *string `json:"certificate_fingerprint" binding:"omitempty"`
 }
 
 var validInstanceTypes = []string{
 	"qbittorrent",
+	"transmission",
+	"deluge",
 }
 
 // validateInstanceType is a custom validator function for instance types
@@ -0,0 +1,6 @@
+package schemas
+
+// RateLimitUnblockRequest represents the request body for unblocking an identifier
+type RateLimitUnblockRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
@@ -0,0 +1,41 @@
+package schemas
+
+// RuleConditionSchema is a test over a task field
+type RuleConditionSchema struct {
+	Field    string   `json:"field" binding:"required"`
+	Operator string   `json:"operator" binding:"required"`
+	Value    string   `json:"value"`
+	Values   []string `json:"values"`
+}
+
+// RuleActionSchema is an action applied to the matching tasks. Only the fields of its type are used.
+type RuleActionSchema struct {
+	Type             string   `json:"type" binding:"required"`
+	DeleteFiles      bool     `json:"delete_files"`
+	Location         string   `json:"location"`
+	Tags             []string `json:"tags"`
+	Category         string   `json:"category"`
+	RatioLimit       float64  `json:"ratio_limit" binding:"min=0"`
+	SeedingTimeLimit int      `json:"seeding_time_limit" binding:"min=0"`
+	Limit            int      `json:"limit" binding:"min=0"`
+}
+
+// RuleCreateSchema represents the request body for creating a rule
+type RuleCreateSchema struct {
+	Name       string                `json:"name" binding:"required,min=1,max=100"`
+	Enabled    *bool                 `json:"enabled"`
+	DryRun     bool                  `json:"dry_run"`
+	Interval   string                `json:"interval"`
+	Conditions []RuleConditionSchema `json:"conditions" binding:"required,min=1,dive"`
+	Actions    []RuleActionSchema    `json:"actions" binding:"required,min=1,dive"`
+}
+
+// RuleUpdateSchema represents the request body for updating a rule, omitted fields are kept
+type RuleUpdateSchema struct {
+	Name       *string               `json:"name" binding:"omitempty,min=1,max=100"`
+	Enabled    *bool                 `json:"enabled"`
+	DryRun     *bool                 `json:"dry_run"`
+	Interval   *string               `json:"interval"`
+	Conditions []RuleConditionSchema `json:"conditions" binding:"omitempty,min=1,dive"`
+	Actions    []RuleActionSchema    `json:"actions" binding:"omitempty,min=1,dive"`
+}
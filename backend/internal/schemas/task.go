@@ -1,10 +1,70 @@
 package schemas
 
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// TaskCreateSchema adds a task from exactly one source: a magnet link, the
+// http(s) URL of a .torrent file, or the .torrent file itself, uploaded as the
+// torrent part of a multipart form or base64 encoded in JSON
 type TaskCreateSchema struct {
-	MagnetURI string   `json:"magnet_uri" binding:"required"`
-	Category  string   `json:"category" binding:"required"`
-	Directory string   `json:"directory"`
-	Tags      []string `json:"tags" binding:"required"`
+	MagnetURI  string   `json:"magnet_uri,omitempty" form:"magnet_uri"`
+	TorrentURL string   `json:"torrent_url,omitempty" form:"torrent_url" binding:"omitempty,url"`
+	Torrent    []byte   `json:"torrent,omitempty" form:"-"`
+	Category   string   `json:"category" form:"category" binding:"required"`
+	Directory  string   `json:"directory" form:"directory"`
+	Tags       []string `json:"tags" form:"tags"`
+
+	// Paused adds the task without starting it
+	Paused bool `json:"paused,omitempty" form:"paused"`
+	// SkipChecking trusts the data already in the directory without checking it
+	SkipChecking       bool `json:"skip_checking,omitempty" form:"skip_checking"`
+	SequentialDownload bool `json:"sequential_download,omitempty" form:"sequential_download"`
+	// ContentLayout is how the files are laid out in the directory, the torrent
+	// client default when empty
+	ContentLayout string `json:"content_layout,omitempty" form:"content_layout" binding:"omitempty,oneof=Original Subfolder NoSubfolder"`
+	// FilePriorities are the initial priorities of the files of a .torrent, in
+	// order, on the qBittorrent scale: 0 skipped, 1 normal, 6 high, 7 maximal
+	FilePriorities []int `json:"file_priorities,omitempty" form:"file_priorities" binding:"omitempty,dive,oneof=0 1 6 7"`
+}
+
+// MaxTorrentSize bounds the size of an uploaded or fetched .torrent file
+const MaxTorrentSize = 10 << 20
+
+// ReadTorrent reads an uploaded .torrent file into the schema
+func (s *TaskCreateSchema) ReadTorrent(header *multipart.FileHeader) error {
+	data, err := readTorrent(header)
+	if err != nil {
+		return err
+	}
+
+	s.Torrent = data
+	return nil
+}
+
+// readTorrent reads an uploaded .torrent file, bounded by MaxTorrentSize
+func readTorrent(header *multipart.FileHeader) ([]byte, error) {
+	if header.Size > MaxTorrentSize {
+		return nil, fmt.Errorf("torrent file larger than %d bytes", MaxTorrentSize)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxTorrentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxTorrentSize {
+		return nil, fmt.Errorf("torrent file larger than %d bytes", MaxTorrentSize)
+	}
+
+	return data, nil
 }
 
 type TaskDeleteSchema struct {
@@ -16,7 +76,7 @@ type TaskDeleteOptionsSchema struct {
 }
 
 type TaskSetShareLimitSchema struct {
-	Hash             string  `json:"hash" binding:"required"`
+	Hash             string  `json:"hash"`
 	RatioLimit       float64 `json:"ratio_limit" binding:"required,min=0"`
 	SeedingTimeLimit int     `json:"seeding_time_limit" binding:"required,min=0"`
 }
@@ -40,3 +100,13 @@ type TaskSetDownloadLimitSchema struct {
 type TaskSetUploadLimitSchema struct {
 	Limit int `json:"limit" binding:"required,min=0"`
 }
+
+// TaskSetCategorySchema moves a task to a category, an empty one removes it from its category
+type TaskSetCategorySchema struct {
+	Category string `json:"category" binding:"max=100"`
+}
+
+// TaskSetTagsSchema replaces every tag of a task
+type TaskSetTagsSchema struct {
+	Tags []string `json:"tags"`
+}
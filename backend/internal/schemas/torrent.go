@@ -0,0 +1,20 @@
+package schemas
+
+import "mime/multipart"
+
+// TorrentInspectSchema holds a .torrent file to inspect, uploaded as the
+// torrent part of a multipart form or base64 encoded in JSON
+type TorrentInspectSchema struct {
+	Torrent []byte `json:"torrent" form:"-"`
+}
+
+// ReadTorrent reads an uploaded .torrent file into the schema
+func (s *TorrentInspectSchema) ReadTorrent(header *multipart.FileHeader) error {
+	data, err := readTorrent(header)
+	if err != nil {
+		return err
+	}
+
+	s.Torrent = data
+	return nil
+}
@@ -2,8 +2,9 @@ package schemas
 
 // UserRegisterRequest represents the request body for user registration
 type UserRegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required,min=8"`
+	InviteToken string `json:"invite_token" binding:"omitempty"`
 }
 
 // UserLoginRequest represents the request body for user login
@@ -11,3 +12,45 @@ type UserLoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
+
+// UserInviteRequest represents the request body for inviting a user
+type UserInviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=admin operator viewer"`
+}
+
+// UserRoleRequest represents the request body for changing the role of a user
+type UserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin operator viewer"`
+}
+
+// UserAgentsRequest represents the request body for granting agents to a user
+type UserAgentsRequest struct {
+	Agents []string `json:"agents" binding:"dive,uuid"`
+}
+
+// TwoFactorCodeRequest represents a request confirmed by a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// LoginTwoFactorRequest represents the request body answering a login challenge
+// with either a TOTP code or a recovery code
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// PasswordChangeRequest represents the request body for changing the password
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+// PasswordResetRequest represents the request body for setting a new password
+// with a reset token
+type PasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
@@ -10,8 +10,13 @@ type Service struct {
 	repository *agent.Repository
 }
 
-func NewService(db *database.Database, c *crypto.CryptoService) *Service {
-	return &Service{
-		repository: agent.NewRepository(db, c),
+func NewService(db *database.Database, c *crypto.CryptoService) (*Service, error) {
+	repository, err := agent.NewRepository(db, c)
+	if err != nil {
+		return nil, err
 	}
+
+	return &Service{
+		repository: repository,
+	}, nil
 }
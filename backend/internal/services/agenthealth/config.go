@@ -0,0 +1,37 @@
+package agenthealth
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the health monitor settings
+type Config struct {
+	Interval time.Duration // time between two probe rounds
+	Timeout  time.Duration // maximum duration of a single probe
+
+	// FailureThreshold is the number of consecutive failed probes before an
+	// ACTIVE agent is marked ERRORED
+	FailureThreshold int
+	// RecoveryThreshold is the number of consecutive successful probes before
+	// an ERRORED or INACTIVE agent is marked ACTIVE again
+	RecoveryThreshold int
+	// InactiveThreshold is the number of consecutive failed probes before an
+	// agent is marked INACTIVE
+	InactiveThreshold int
+
+	HistoryRetention time.Duration
+}
+
+// LoadConfigFromEnv loads the health monitor configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		Interval:          env.Get("AGENT_HEALTH_INTERVAL").Default("30s").ValuePositiveDuration(),
+		Timeout:           env.Get("AGENT_HEALTH_TIMEOUT").Default("10s").ValuePositiveDuration(),
+		FailureThreshold:  env.Get("AGENT_HEALTH_FAILURE_THRESHOLD").Default("3").ValueInt(),
+		RecoveryThreshold: env.Get("AGENT_HEALTH_RECOVERY_THRESHOLD").Default("2").ValueInt(),
+		InactiveThreshold: env.Get("AGENT_HEALTH_INACTIVE_THRESHOLD").Default("20").ValueInt(),
+		HistoryRetention:  env.Get("AGENT_HEALTH_HISTORY_RETENTION").Default("720h").ValueDuration(),
+	}
+}
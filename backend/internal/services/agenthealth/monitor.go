@@ -0,0 +1,251 @@
+package agenthealth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/google/uuid"
+)
+
+// Repository is the storage and transport used by the monitor
+type Repository interface {
+	ListAgents() ([]*entities.Agent, error)
+	GetInstance(ctx context.Context, agent *entities.Agent) (*entities.Instance, error)
+	GetAgentHealth(ctx context.Context, uid uuid.UUID) (*entities.AgentHealth, error)
+	SaveAgentHealth(ctx context.Context, health *entities.AgentHealth) error
+	CreateAgentHealthEvent(ctx context.Context, event *entities.AgentHealthEvent) error
+	DeleteAgentHealthEventsBefore(ctx context.Context, before time.Time) error
+}
+
+// Monitor periodically probes every agent and persists its health state, so
+// listing agents never has to wait on a slow or unreachable agent
+type Monitor struct {
+	repository Repository
+	config     Config
+
+	mu sync.RWMutex
+	// instances keeps the instance data returned by the last successful probe
+	instances map[uuid.UUID]*entities.Instance
+}
+
+// NewMonitor creates a health monitor
+func NewMonitor(repository Repository, config Config) *Monitor {
+	return &Monitor{
+		repository: repository,
+		config:     config,
+		instances:  make(map[uuid.UUID]*entities.Instance),
+	}
+}
+
+// Run probes all agents immediately and then on every interval until ctx is canceled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll probes every registered agent concurrently
+func (m *Monitor) CheckAll(ctx context.Context) {
+	agents, err := m.repository.ListAgents()
+	if err != nil {
+		log.Printf("agent health: failed to list agents: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, agent := range agents {
+		wg.Add(1)
+		go func(a *entities.Agent) {
+			defer wg.Done()
+			if _, err := m.Check(ctx, a); err != nil {
+				log.Printf("agent health: failed to check agent %s: %v", a.Name, err)
+			}
+		}(agent)
+	}
+	wg.Wait()
+
+	m.forget(agents)
+
+	if m.config.HistoryRetention > 0 {
+		if err := m.repository.DeleteAgentHealthEventsBefore(ctx, time.Now().Add(-m.config.HistoryRetention)); err != nil {
+			log.Printf("agent health: failed to clean history: %v", err)
+		}
+	}
+}
+
+// Check probes a single agent and records the result
+func (m *Monitor) Check(ctx context.Context, agent *entities.Agent) (*entities.AgentHealth, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	instance, err := m.repository.GetInstance(probeCtx, agent)
+	latency := time.Since(start)
+
+	// the probe was interrupted by shutdown, its result says nothing about the agent
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return m.Record(ctx, agent.UUID, instance, latency, err)
+}
+
+// Connected probes an agent that just opened its tunnel. The connection tells
+// it is back, so a successful probe marks it ACTIVE without waiting for the
+// recovery threshold.
+func (m *Monitor) Connected(ctx context.Context, agent *entities.Agent) (*entities.AgentHealth, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	instance, err := m.repository.GetInstance(probeCtx, agent)
+	latency := time.Since(start)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	status := ""
+	if err == nil {
+		status = entities.AgentStatusActive
+	}
+	return m.record(ctx, agent.UUID, instance, latency, err, status)
+}
+
+// Disconnected marks INACTIVE an agent only reachable through its tunnel, once
+// the tunnel closed: no probe can reach it until it connects again
+func (m *Monitor) Disconnected(ctx context.Context, uid uuid.UUID, reason error) (*entities.AgentHealth, error) {
+	return m.record(ctx, uid, nil, 0, reason, entities.AgentStatusInactive)
+}
+
+// Record applies the result of a probe made elsewhere (e.g. when an agent is created
+// or updated) to the health state of the agent
+func (m *Monitor) Record(ctx context.Context, uid uuid.UUID, instance *entities.Instance, latency time.Duration, probeErr error) (*entities.AgentHealth, error) {
+	return m.record(ctx, uid, instance, latency, probeErr, "")
+}
+
+// record applies the result of a probe, status replacing the computed one when not empty
+func (m *Monitor) record(ctx context.Context, uid uuid.UUID, instance *entities.Instance, latency time.Duration, probeErr error, status string) (*entities.AgentHealth, error) {
+	current, err := m.repository.GetAgentHealth(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	next := m.next(current, probeErr == nil)
+	if status != "" {
+		next.Status = status
+	}
+	next.AgentUUID = uid
+	next.LastCheckAt = now
+	next.Latency = latency
+
+	if probeErr != nil {
+		next.LastError = probeErr.Error()
+	} else {
+		next.LastError = ""
+		next.LastSeenAt = &now
+	}
+
+	if err := m.repository.SaveAgentHealth(ctx, next); err != nil {
+		return nil, err
+	}
+
+	if current == nil || current.Status != next.Status {
+		event := &entities.AgentHealthEvent{
+			AgentUUID: uid,
+			Status:    next.Status,
+			Latency:   latency,
+			Error:     next.LastError,
+			CreatedAt: now,
+		}
+		if current != nil {
+			event.PreviousStatus = current.Status
+		}
+
+		if err := m.repository.CreateAgentHealthEvent(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	if probeErr == nil && instance != nil {
+		m.instances[uid] = instance
+	}
+	m.mu.Unlock()
+
+	return next, nil
+}
+
+// Instance returns the instance data of the last successful probe of an agent
+func (m *Monitor) Instance(uid uuid.UUID) *entities.Instance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.instances[uid]
+}
+
+// next computes the new health state from the current one and the probe result.
+// Status changes are delayed by the configured thresholds so a single slow
+// answer does not make an agent flap.
+func (m *Monitor) next(current *entities.AgentHealth, success bool) *entities.AgentHealth {
+	next := &entities.AgentHealth{}
+	if current != nil {
+		*next = *current
+	}
+
+	if success {
+		next.ConsecutiveSuccesses++
+		next.ConsecutiveFailures = 0
+
+		// first probe ever: trust it
+		if current == nil || next.ConsecutiveSuccesses >= m.config.RecoveryThreshold {
+			next.Status = entities.AgentStatusActive
+		}
+
+		return next
+	}
+
+	next.ConsecutiveFailures++
+	next.ConsecutiveSuccesses = 0
+
+	switch {
+	case next.ConsecutiveFailures >= m.config.InactiveThreshold:
+		next.Status = entities.AgentStatusInactive
+	case current == nil:
+		next.Status = entities.AgentStatusErrored
+	case next.Status == entities.AgentStatusActive && next.ConsecutiveFailures >= m.config.FailureThreshold:
+		next.Status = entities.AgentStatusErrored
+	}
+
+	return next
+}
+
+// forget drops the cached instances of agents that no longer exist
+func (m *Monitor) forget(agents []*entities.Agent) {
+	known := make(map[uuid.UUID]struct{}, len(agents))
+	for _, agent := range agents {
+		known[agent.UUID] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for uid := range m.instances {
+		if _, ok := known[uid]; !ok {
+			delete(m.instances, uid)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package agenthealth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/agent"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// probeRepository replaces the network probe of the agent repository with scripted results
+type probeRepository struct {
+	*agent.Repository
+
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+func (r *probeRepository) setFailing(name string, failing bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failing[name] = failing
+}
+
+func (r *probeRepository) GetInstance(ctx context.Context, a *entities.Agent) (*entities.Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failing[a.Name] {
+		return nil, errors.New("connection refused")
+	}
+
+	return &entities.Instance{Application: entities.InstanceApplication{Version: "v5.0.0"}}, nil
+}
+
+func setupMonitor(t *testing.T) (*Monitor, *probeRepository, *entities.Agent) {
+	t.Helper()
+
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.Agent{}, &models.AgentHealth{}, &models.AgentHealthEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	repository, err := agent.NewRepository(&database.Database{DB: db}, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	repo := &probeRepository{
+		Repository: repository,
+		failing:    map[string]bool{},
+	}
+
+	created, err := repo.CreateAgent(context.Background(), entities.Agent{Name: "seedbox", Address: "http://seedbox:8080", Token: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	monitor := NewMonitor(repo, Config{
+		Interval:          time.Minute,
+		Timeout:           time.Second,
+		FailureThreshold:  2,
+		RecoveryThreshold: 2,
+		InactiveThreshold: 4,
+	})
+
+	return monitor, repo, created
+}
+
+func TestMonitor_Hysteresis(t *testing.T) {
+	monitor, repo, a := setupMonitor(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		failing bool
+		want    string
+	}{
+		{false, entities.AgentStatusActive},  // first probe is trusted
+		{true, entities.AgentStatusActive},   // 1 failure, below threshold
+		{true, entities.AgentStatusErrored},  // 2 failures
+		{false, entities.AgentStatusErrored}, // 1 success, below recovery threshold
+		{false, entities.AgentStatusActive},  // 2 successes
+		{true, entities.AgentStatusActive},
+		{true, entities.AgentStatusErrored},
+		{true, entities.AgentStatusErrored},
+		{true, entities.AgentStatusInactive}, // 4 failures
+		{false, entities.AgentStatusInactive},
+		{false, entities.AgentStatusActive},
+	}
+
+	for i, step := range steps {
+		repo.setFailing(a.Name, step.failing)
+
+		health, err := monitor.Check(ctx, a)
+		if err != nil {
+			t.Fatalf("Step %d: expected no error, got %v", i, err)
+		}
+		if health.Status != step.want {
+			t.Errorf("Step %d: expected status %s, got %s", i, step.want, health.Status)
+		}
+	}
+
+	events, err := repo.ListAgentHealthEvents(ctx, a.UUID, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// ACTIVE, ERRORED, ACTIVE, ERRORED, INACTIVE, ACTIVE
+	if len(events) != 6 {
+		t.Fatalf("Expected 6 transitions, got %d", len(events))
+	}
+	if events[0].PreviousStatus != entities.AgentStatusInactive || events[0].Status != entities.AgentStatusActive {
+		t.Errorf("Expected latest transition INACTIVE -> ACTIVE, got %s -> %s", events[0].PreviousStatus, events[0].Status)
+	}
+	if events[5].PreviousStatus != "" {
+		t.Errorf("Expected first transition without previous status, got %s", events[5].PreviousStatus)
+	}
+}
+
+func TestMonitor_PersistsProbeDetails(t *testing.T) {
+	monitor, repo, a := setupMonitor(t)
+	ctx := context.Background()
+
+	monitor.CheckAll(ctx)
+
+	health, err := repo.GetAgentHealth(ctx, a.UUID)
+	if err != nil || health == nil {
+		t.Fatalf("Expected stored health, got %v (err: %v)", health, err)
+	}
+	if health.LastSeenAt == nil {
+		t.Error("Expected last seen time to be set")
+	}
+	if monitor.Instance(a.UUID) == nil {
+		t.Error("Expected instance of last successful probe to be cached")
+	}
+
+	lastSeen := *health.LastSeenAt
+	repo.setFailing(a.Name, true)
+	monitor.CheckAll(ctx)
+
+	health, _ = repo.GetAgentHealth(ctx, a.UUID)
+	if health.ConsecutiveFailures != 1 {
+		t.Errorf("Expected 1 consecutive failure, got %d", health.ConsecutiveFailures)
+	}
+	if health.LastError != "connection refused" {
+		t.Errorf("Expected last error 'connection refused', got '%s'", health.LastError)
+	}
+	if !health.LastSeenAt.Equal(lastSeen) {
+		t.Errorf("Expected last seen time to be kept on failure, got %v", health.LastSeenAt)
+	}
+}
+
+func TestMonitor_UnknownAgentStartsErrored(t *testing.T) {
+	monitor, repo, a := setupMonitor(t)
+
+	repo.setFailing(a.Name, true)
+
+	health, err := monitor.Check(context.Background(), a)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if health.Status != entities.AgentStatusErrored {
+		t.Errorf("Expected status %s, got %s", entities.AgentStatusErrored, health.Status)
+	}
+}
+
+func TestMonitor_TunnelConnection(t *testing.T) {
+	monitor, repo, a := setupMonitor(t)
+	ctx := context.Background()
+
+	health, err := monitor.Disconnected(ctx, a.UUID, errors.New("agent not connected through its tunnel"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if health.Status != entities.AgentStatusInactive {
+		t.Errorf("Expected status %s once disconnected, got %s", entities.AgentStatusInactive, health.Status)
+	}
+
+	// Reconnecting skips the recovery threshold
+	health, err = monitor.Connected(ctx, a)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if health.Status != entities.AgentStatusActive {
+		t.Errorf("Expected status %s once connected, got %s", entities.AgentStatusActive, health.Status)
+	}
+
+	// A connected agent failing its probe follows the thresholds
+	repo.setFailing(a.Name, true)
+	health, _ = monitor.Connected(ctx, a)
+	if health.Status != entities.AgentStatusActive {
+		t.Errorf("Expected status %s after a single failure, got %s", entities.AgentStatusActive, health.Status)
+	}
+}
@@ -0,0 +1,102 @@
+package agentmanager
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// SyncClients reads the torrent clients managed by an agent and keeps one
+// child agent per client but the default one, which the agent stands for.
+// It returns the child agents.
+func (s *Service) SyncClients(ctx context.Context, agent *entities.Agent) ([]*entities.Agent, error) {
+	clients, err := s.repository.ListAgentClients(ctx, agent)
+	if err != nil {
+		// agents released before the multi-client support manage a single one
+		var agentErr *errors.AgentError
+		if !errors.As(err, &agentErr) || agentErr.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+		clients = nil
+	}
+
+	return s.repository.SyncAgentClients(ctx, agent, clients)
+}
+
+// DiscoverClients syncs the torrent clients of every agent, skipping the ones
+// it cannot reach
+func (s *Service) DiscoverClients(ctx context.Context) {
+	agents, err := s.repository.ListAgents()
+	if err != nil {
+		log.Printf("agent clients: failed to list agents: %v", err)
+		return
+	}
+
+	for _, agent := range agents {
+		if agent.Parent != nil {
+			continue
+		}
+		if _, err := s.SyncClients(ctx, agent); err != nil {
+			log.Printf("agent clients: failed to sync clients of agent %s: %v", agent.Name, err)
+		}
+	}
+}
+
+// RunClientDiscovery syncs the torrent clients of the agents on every
+// interval, until ctx is canceled. A zero interval disables it.
+func (s *Service) RunClientDiscovery(ctx context.Context) {
+	if s.clientsInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.clientsInterval)
+	defer ticker.Stop()
+
+	for {
+		s.DiscoverClients(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncClients syncs the torrent clients of an agent just reached and probes
+// its child agents, the agent itself being usable whatever the outcome
+func (s *Service) syncClients(ctx context.Context, agent *entities.Agent) {
+	children, err := s.SyncClients(ctx, agent)
+	if err != nil {
+		log.Printf("agent clients: failed to sync clients of agent %s: %v", agent.Name, err)
+		return
+	}
+
+	for _, child := range children {
+		if _, err := s.health.Check(ctx, child); err != nil {
+			log.Printf("agent health: failed to check agent %s: %v", child.Name, err)
+		}
+	}
+}
+
+// children returns the child agents of an agent
+func (s *Service) children(uid uuid.UUID) ([]*entities.Agent, error) {
+	agents, err := s.repository.ListAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	var children []*entities.Agent
+	for _, agent := range agents {
+		if agent.Parent != nil && agent.Parent.UUID == uid {
+			children = append(children, agent)
+		}
+	}
+
+	return children, nil
+}
@@ -3,41 +3,179 @@ package agentmanager
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/agenttunnel"
 	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/repository/agent"
 	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/agenthealth"
 	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/eventhub"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/gardarr/gardarr/pkg/errors"
 	"github.com/google/uuid"
 )
 
 type Service struct {
 	repository *agent.Repository
+	health     *agenthealth.Monitor
+	events     *eventhub.Hub
+	tunnels    *agenttunnel.Registry
+	// clientsInterval is the period of the torrent clients discovery, see RunClientDiscovery
+	clientsInterval time.Duration
 }
 
-func NewService(db *database.Database, c *crypto.CryptoService) *Service {
-	return &Service{
-		repository: agent.NewRepository(db, c),
+func NewService(db *database.Database, c *crypto.CryptoService) (*Service, error) {
+	repository, err := agent.NewRepository(db, c)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		repository: repository,
+		health:     agenthealth.NewMonitor(repository, agenthealth.LoadConfigFromEnv()),
+		events:     eventhub.NewHub(repository, eventhub.DefaultConfig()),
+		tunnels:    agenttunnel.Shared(),
+
+		clientsInterval: env.Get("AGENT_CLIENTS_DISCOVERY_INTERVAL").Default("5m").ValuePositiveDuration(),
+	}
+	s.tunnels.Notify(s.tunnelChanged)
+
+	return s, nil
+}
+
+// Repository returns the agent repository, whose client pool the other
+// services reaching the agents share
+func (s *Service) Repository() *agent.Repository {
+	return s.repository
+}
+
+// HealthMonitor returns the background monitor that keeps the agents status up to date
+func (s *Service) HealthMonitor() *agenthealth.Monitor {
+	return s.health
+}
+
+// EventHub returns the hub that fans in the live events of every agent
+func (s *Service) EventHub() *eventhub.Hub {
+	return s.events
+}
+
+// Tunnels returns the registry of the tunnels opened by the agents
+func (s *Service) Tunnels() *agenttunnel.Registry {
+	return s.tunnels
+}
+
+// AuthenticateTunnel returns the agent opening a tunnel, once the request is
+// checked to be signed with its secret
+func (s *Service) AuthenticateTunnel(r *http.Request) (*entities.Agent, error) {
+	uid, err := agenttunnel.AgentID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// a child agent has no secret, its parent opens the tunnel
+	agent, err := s.repository.GetAgentByUUID(uid)
+	if err != nil || agent.Parent != nil {
+		return nil, agenttunnel.ErrUnknownAgent
+	}
+
+	secret, err := s.repository.AgentSecret(agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt agent token: %w", err)
+	}
+
+	if err := s.tunnels.Verify(r, secret); err != nil {
+		return nil, err
+	}
+
+	return agent, nil
+}
+
+// tunnelChanged feeds the tunnel connections into the health of the agents:
+// a connected agent is probed through its tunnel, and one only reachable
+// through it is INACTIVE once it closed, as its child agents
+func (s *Service) tunnelChanged(uid uuid.UUID, connected bool) {
+	ctx := context.Background()
+
+	agent, err := s.repository.GetAgentByUUID(uid)
+	if err != nil {
+		return
+	}
+
+	if connected {
+		if _, err := s.health.Connected(ctx, agent); err != nil {
+			log.Printf("agent health: failed to record tunnel of agent %s: %v", agent.Name, err)
+		}
+		s.syncClients(ctx, agent)
+		return
+	}
+
+	children, err := s.children(uid)
+	if err != nil {
+		log.Printf("agent health: failed to list child agents of agent %s: %v", agent.Name, err)
+	}
+
+	for _, a := range append([]*entities.Agent{agent}, children...) {
+		if agenttunnel.IsTunnelAddress(a.Address) {
+			_, err = s.health.Disconnected(ctx, a.UUID, agenttunnel.ErrNotConnected)
+		} else {
+			// still reachable at its address
+			_, err = s.health.Check(ctx, a)
+		}
+		if err != nil {
+			log.Printf("agent health: failed to record tunnel of agent %s: %v", a.Name, err)
+		}
 	}
 }
 
 func (s *Service) CreateAgent(ctx context.Context, schema *schemas.AgentCreateSchema) (*entities.Agent, error) {
 	input := entities.Agent{
 		Name:    schema.Name,
+		Type:    schema.Type,
 		Address: schema.Address,
 		Token:   schema.Token,
 		Icon:    schema.Icon,
 		Color:   schema.Color,
 	}
 
+	if schema.CertificateFingerprint != "" {
+		fingerprint, err := agentauth.NormalizeFingerprint(schema.CertificateFingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		input.CertificateFingerprint = fingerprint
+	}
+
+	// An agent behind a tunnel cannot connect before it is registered
+	if agenttunnel.IsTunnelAddress(input.Address) {
+		agent, err := s.repository.CreateAgent(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		agent.Status = entities.AgentStatusInactive
+		return agent, nil
+	}
+
 	// Validate instance connectivity BEFORE persisting to database
-	instance, err := s.repository.GetInstance(&input)
+	start := time.Now()
+	instance, err := s.repository.ProbeInstance(ctx, &input)
 	if err != nil {
 		return nil, fmt.Errorf("não foi possível conectar com a instância: %s", err.Error())
 	}
+	latency := time.Since(start)
+
+	// Agents report their torrent client since the Transmission support, older ones run qBittorrent
+	if client := instance.Application.Client; client != "" && client != input.Type {
+		return nil, fmt.Errorf("%w: the agent drives %s, not %s", errors.ErrInvalidInput, client, input.Type)
+	}
 
 	// If connection is successful, create the agent
 	agent, err := s.repository.CreateAgent(ctx, input)
@@ -48,54 +186,34 @@ func (s *Service) CreateAgent(ctx context.Context, schema *schemas.AgentCreateSc
 	// Set status and instance data
 	agent.Status = entities.AgentStatusActive
 	agent.Instance = instance
+	agent.Health = s.recordHealth(ctx, agent, instance, latency)
+
+	s.syncClients(ctx, agent)
 
 	return agent, nil
 }
 
-func (s *Service) ListAgents() ([]*entities.Agent, error) {
+// ListAgents returns the registered agents with the status last observed by the
+// health monitor, without contacting them
+func (s *Service) ListAgents(ctx context.Context) ([]*entities.Agent, error) {
 	agents, err := s.repository.ListAgents()
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a channel to receive processed agents
-	agentChan := make(chan *entities.Agent, len(agents))
-
-	// Process each agent concurrently
-	for _, agent := range agents {
-		go func(a *entities.Agent) {
-			// Set default status to ACTIVE
-			a.Status = entities.AgentStatusActive
-
-			// Try to get instance, if it fails, set status to ERRORED
-			instance, err := s.repository.GetInstance(a)
-			if err != nil {
-				a.Status = entities.AgentStatusErrored
-				a.Error = err.Error()
-				a.Instance = nil
-			} else {
-				a.Instance = instance
-			}
-
-			// Send the processed agent to the channel
-			agentChan <- a
-		}(agent)
+	health, err := s.repository.ListAgentHealth(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Collect all processed agents from the channel
-	result := make([]*entities.Agent, 0, len(agents))
-	for i := 0; i < len(agents); i++ {
-		processedAgent := <-agentChan
-		result = append(result, processedAgent)
+	for _, a := range agents {
+		s.applyHealth(a, health[a.UUID])
 	}
 
-	// Close the channel
-	close(agentChan)
-
-	return result, nil
+	return agents, nil
 }
 
-func (s *Service) ListTasks(agents []*entities.Agent) ([]*entities.Task, error) {
+func (s *Service) ListTasks(ctx context.Context, agents []*entities.Agent) ([]*entities.Task, error) {
 	if len(agents) == 0 {
 		return []*entities.Task{}, nil
 	}
@@ -107,7 +225,7 @@ func (s *Service) ListTasks(agents []*entities.Agent) ([]*entities.Task, error)
 	// Process each agent concurrently
 	for _, agent := range agents {
 		go func(a *entities.Agent) {
-			tasks, err := s.repository.ListAgentTasks(a)
+			tasks, err := s.repository.ListAgentTasks(ctx, a)
 			if err != nil {
 				errorChan <- err
 				taskChan <- nil
@@ -154,6 +272,7 @@ func (s *Service) ListTasks(agents []*entities.Agent) ([]*entities.Task, error)
 	return result, nil
 }
 
+// Get returns an agent with the status of its last health probe, as ListAgents
 func (s *Service) Get(ctx context.Context, id string) (*entities.Agent, error) {
 	parsedID, err := uuid.Parse(id)
 	if err != nil {
@@ -165,44 +284,11 @@ func (s *Service) Get(ctx context.Context, id string) (*entities.Agent, error) {
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
-	// Set default status to ACTIVE
-	agent.Status = entities.AgentStatusActive
-
-	// Try to get instance, if it fails, set status to ERRORED
-	instance, err := s.repository.GetInstance(agent)
-	if err != nil {
-		agent.Status = entities.AgentStatusErrored
-		agent.Instance = nil
-	} else {
-		agent.Instance = instance
-	}
-
-	return agent, nil
-}
-
-func (s *Service) GetAgent(ctx context.Context, id string) (*entities.Agent, error) {
-	parsedID, err := uuid.Parse(id)
+	health, err := s.repository.GetAgentHealth(ctx, agent.UUID)
 	if err != nil {
 		return nil, err
 	}
-
-	agent, err := s.repository.GetAgentByUUID(parsedID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set default status to ACTIVE
-	agent.Status = entities.AgentStatusActive
-
-	// Try to get instance, if it fails, set status to ERRORED
-	instance, err := s.repository.GetInstance(agent)
-	if err != nil {
-		agent.Status = entities.AgentStatusErrored
-		agent.Error = err.Error()
-		agent.Instance = nil
-	} else {
-		agent.Instance = instance
-	}
+	s.applyHealth(agent, health)
 
 	return agent, nil
 }
@@ -219,6 +305,11 @@ func (s *Service) UpdateAgent(ctx context.Context, id string, schema *schemas.Ag
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
+	// The torrent client of a child agent is reached through its parent
+	if currentAgent.Parent != nil && (schema.Address != "" || schema.Token != "" || schema.CertificateFingerprint != nil) {
+		return nil, fmt.Errorf("%w: the agent of client %s is reached through agent %s", errors.ErrInvalidInput, currentAgent.Client, currentAgent.Parent.Name)
+	}
+
 	// Convert schema to map for updates
 	updates := make(map[string]interface{})
 	if schema.Name != "" {
@@ -236,6 +327,15 @@ func (s *Service) UpdateAgent(ctx context.Context, id string, schema *schemas.Ag
 	if schema.Color != "" {
 		updates["color"] = schema.Color
 	}
+	if schema.CertificateFingerprint != nil {
+		fingerprint := *schema.CertificateFingerprint
+		if fingerprint != "" {
+			if fingerprint, err = agentauth.NormalizeFingerprint(fingerprint); err != nil {
+				return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+			}
+		}
+		updates["certificate_fingerprint"] = fingerprint
+	}
 
 	// Create a test agent with updated values to validate connectivity
 	testAgent := *currentAgent
@@ -247,6 +347,11 @@ func (s *Service) UpdateAgent(ctx context.Context, id string, schema *schemas.Ag
 	}
 	if schema.Token != "" {
 		testAgent.Token = schema.Token
+	} else if currentAgent.Parent == nil {
+		// the agent is probed with the plaintext token, as before it is saved
+		if testAgent.Token, err = s.repository.AgentSecret(currentAgent); err != nil {
+			return nil, fmt.Errorf("failed to decrypt agent token: %w", err)
+		}
 	}
 	if schema.Icon != "" {
 		testAgent.Icon = schema.Icon
@@ -254,12 +359,38 @@ func (s *Service) UpdateAgent(ctx context.Context, id string, schema *schemas.Ag
 	if schema.Color != "" {
 		testAgent.Color = schema.Color
 	}
+	if fingerprint, ok := updates["certificate_fingerprint"].(string); ok {
+		testAgent.CertificateFingerprint = fingerprint
+	}
+
+	// A new token must be presented again by the agent behind a tunnel
+	if schema.Token != "" {
+		s.tunnels.Disconnect(parsedID)
+	}
+
+	// Until the agent connects, there is nothing to validate
+	if agenttunnel.IsTunnelAddress(testAgent.Address) && !s.tunnels.Connected(testAgent.Host().UUID) {
+		agent, err := s.repository.UpdateAgent(ctx, parsedID, updates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update agent: %w", err)
+		}
+
+		agent.Status = entities.AgentStatusInactive
+		return agent, nil
+	}
 
 	// Validate instance connectivity BEFORE updating the database
-	instance, err := s.repository.GetInstance(&testAgent)
+	start := time.Now()
+	instance, err := s.repository.ProbeInstance(ctx, &testAgent)
 	if err != nil {
 		return nil, fmt.Errorf("não foi possível conectar com a instância: %s", err.Error())
 	}
+	latency := time.Since(start)
+
+	// The address may now lead to an agent driving another torrent client
+	if client := instance.Application.Client; client != "" {
+		updates["type"] = client
+	}
 
 	// If connection is successful, update the agent in the database
 	agent, err := s.repository.UpdateAgent(ctx, parsedID, updates)
@@ -270,6 +401,11 @@ func (s *Service) UpdateAgent(ctx context.Context, id string, schema *schemas.Ag
 	// Set status and instance data
 	agent.Status = entities.AgentStatusActive
 	agent.Instance = instance
+	agent.Health = s.recordHealth(ctx, agent, instance, latency)
+
+	if agent.Parent == nil {
+		s.syncClients(ctx, agent)
+	}
 
 	return agent, nil
 }
@@ -280,7 +416,55 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 
-	return s.repository.DeleteAgent(parsedID)
+	if err := s.repository.DeleteAgent(parsedID); err != nil {
+		return err
+	}
+
+	s.tunnels.Disconnect(parsedID)
+	return nil
+}
+
+// GetAgentHealthHistory returns the most recent status transitions of an agent, newest first
+func (s *Service) GetAgentHealthHistory(ctx context.Context, id string, limit int) ([]*entities.AgentHealthEvent, error) {
+	agent, err := s.resolveAgent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.repository.ListAgentHealthEvents(ctx, agent.UUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list health history: %w", err)
+	}
+
+	return events, nil
+}
+
+// applyHealth sets the status, error and last known instance of an agent from its health state.
+// Agents that were never checked are reported as INACTIVE.
+func (s *Service) applyHealth(agent *entities.Agent, health *entities.AgentHealth) {
+	agent.Health = health
+	agent.Instance = s.health.Instance(agent.UUID)
+	agent.Tunnel = s.tunnels.Connected(agent.Host().UUID)
+
+	if health == nil {
+		agent.Status = entities.AgentStatusInactive
+		return
+	}
+
+	agent.Status = health.Status
+	agent.Error = health.LastError
+}
+
+// recordHealth feeds a successful connectivity check into the health monitor so a
+// new or updated agent does not wait for the next probe round to be reported
+func (s *Service) recordHealth(ctx context.Context, agent *entities.Agent, instance *entities.Instance, latency time.Duration) *entities.AgentHealth {
+	health, err := s.health.Record(ctx, agent.UUID, instance, latency, nil)
+	if err != nil {
+		log.Printf("agent health: failed to record health of agent %s: %v", agent.Name, err)
+		return nil
+	}
+
+	return health
 }
 
 func (s *Service) CreateAgentTask(ctx context.Context, id string, schema schemas.TaskCreateSchema) (*entities.Task, error) {
@@ -294,7 +478,11 @@ func (s *Service) CreateAgentTask(ctx context.Context, id string, schema schemas
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
-	task, err := s.repository.CreateAgentTask(agent, schema)
+	if err := checkAddOptions(agent, schema); err != nil {
+		return nil, err
+	}
+
+	task, err := s.repository.CreateAgentTask(ctx, agent, schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
@@ -302,8 +490,38 @@ func (s *Service) CreateAgentTask(ctx context.Context, id string, schema schemas
 	return task, nil
 }
 
+// PreviewAgentTask reads the torrent a task would be added from on an agent,
+// telling whether the agent already has it
+func (s *Service) PreviewAgentTask(ctx context.Context, id string, schema schemas.TaskCreateSchema) (*entities.TaskPreview, error) {
+	agent, err := s.resolveAgent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.PreviewAgentTask(ctx, agent, schema)
+}
+
+// checkAddOptions rejects the add options the torrent client of an agent does not support
+func checkAddOptions(agent *entities.Agent, schema schemas.TaskCreateSchema) error {
+	capabilities := entities.CapabilitiesOf(agent.Type)
+
+	var option string
+	switch {
+	case schema.ContentLayout != "" && !capabilities.ContentLayout:
+		option = "content_layout"
+	case schema.SkipChecking && !capabilities.SkipChecking:
+		option = "skip_checking"
+	case schema.SequentialDownload && !capabilities.SequentialDownload:
+		option = "sequential_download"
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("agent %s runs %s, %s: %w", agent.Name, agent.Type, option, errors.ErrNotSupported)
+}
+
 func (s *Service) GetPreferences(ctx context.Context, agent *entities.Agent) (*entities.InstancePreferences, error) {
-	preferences, err := s.repository.GetAgentPreferences(agent)
+	preferences, err := s.repository.GetAgentPreferences(ctx, agent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get preferences: %w", err)
 	}
@@ -322,7 +540,7 @@ func (s *Service) ListAgentTasks(ctx context.Context, id string) ([]*entities.Ta
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
-	tasks, err := s.repository.ListAgentTasks(agent)
+	tasks, err := s.repository.ListAgentTasks(ctx, agent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
@@ -330,55 +548,186 @@ func (s *Service) ListAgentTasks(ctx context.Context, id string) ([]*entities.Ta
 	return tasks, nil
 }
 
-func (s *Service) ListAgentsTasks() ([]*entities.Task, error) {
+func (s *Service) ListAgentsTasks(ctx context.Context) ([]*entities.Task, error) {
 	agents, err := s.repository.ListAgents()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list agents: %w", err)
 	}
 
-	return s.ListTasks(agents)
+	return s.ListTasks(ctx, agents)
 }
 
-func (s *Service) PauseAgentTask(ctx context.Context, agentID, taskID string) error {
-	uid, err := uuid.Parse(agentID)
+func (s *Service) GetAgentTask(ctx context.Context, agentID, taskID string) (*entities.Task, error) {
+	agent, err := s.resolveAgent(agentID)
 	if err != nil {
-		return fmt.Errorf("invalid agent UUID format: %w", err)
+		return nil, err
 	}
 
-	agent, err := s.repository.GetAgentByUUID(uid)
+	return s.repository.GetAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) DeleteAgentTask(ctx context.Context, agentID, taskID string, purge bool) error {
+	agent, err := s.resolveAgent(agentID)
 	if err != nil {
-		return fmt.Errorf("agent not found: %w", err)
+		return err
 	}
 
-	return s.repository.PauseAgentTask(agent, taskID)
+	return s.repository.DeleteAgentTask(ctx, agent, taskID, purge)
 }
 
-func (s *Service) ResumeAgentTask(ctx context.Context, agentID, taskID string) error {
-	uid, err := uuid.Parse(agentID)
+func (s *Service) StopAgentTask(ctx context.Context, agentID, taskID string) error {
+	agent, err := s.resolveAgent(agentID)
 	if err != nil {
-		return fmt.Errorf("invalid agent UUID format: %w", err)
+		return err
 	}
 
-	agent, err := s.repository.GetAgentByUUID(uid)
+	return s.repository.StopAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) StartAgentTask(ctx context.Context, agentID, taskID string) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.StartAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) ForceResumeAgentTask(ctx context.Context, agentID, taskID string) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.ForceStart })
+	if err != nil {
+		return err
+	}
+
+	return s.repository.ForceResumeAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) SetAgentTaskShareLimit(ctx context.Context, agentID, taskID string, schema schemas.TaskSetShareLimitSchema) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.ShareLimits })
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskShareLimit(ctx, agent, taskID, schema)
+}
+
+func (s *Service) SetAgentTaskLocation(ctx context.Context, agentID, taskID string, schema schemas.TaskSetLocationSchema) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskLocation(ctx, agent, taskID, schema)
+}
+
+func (s *Service) RenameAgentTask(ctx context.Context, agentID, taskID string, schema schemas.TaskRenameSchema) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.Rename })
 	if err != nil {
-		return fmt.Errorf("agent not found: %w", err)
+		return err
+	}
+
+	return s.repository.RenameAgentTask(ctx, agent, taskID, schema)
+}
+
+func (s *Service) SetAgentTaskSuperSeeding(ctx context.Context, agentID, taskID string, schema schemas.TaskSuperSeedingSchema) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.SuperSeeding })
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskSuperSeeding(ctx, agent, taskID, schema)
+}
+
+func (s *Service) ForceRecheckAgentTask(ctx context.Context, agentID, taskID string) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.ForceRecheckAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) ForceReannounceAgentTask(ctx context.Context, agentID, taskID string) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.ForceReannounceAgentTask(ctx, agent, taskID)
+}
+
+func (s *Service) SetAgentTaskDownloadLimit(ctx context.Context, agentID, taskID string, schema schemas.TaskSetDownloadLimitSchema) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskDownloadLimit(ctx, agent, taskID, schema)
+}
+
+func (s *Service) SetAgentTaskUploadLimit(ctx context.Context, agentID, taskID string, schema schemas.TaskSetUploadLimitSchema) error {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return err
 	}
 
-	return s.repository.ResumeAgentTask(agent, taskID)
+	return s.repository.SetAgentTaskUploadLimit(ctx, agent, taskID, schema)
 }
 
-func (s *Service) ForceDownloadAgentTask(ctx context.Context, agentID, taskID string) error {
+func (s *Service) SetAgentTaskCategory(ctx context.Context, agentID, taskID string, schema schemas.TaskSetCategorySchema) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.Categories })
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskCategory(ctx, agent, taskID, schema)
+}
+
+func (s *Service) SetAgentTaskTags(ctx context.Context, agentID, taskID string, schema schemas.TaskSetTagsSchema) error {
+	agent, err := s.resolveCapableAgent(agentID, func(c entities.ClientCapabilities) bool { return c.Tags })
+	if err != nil {
+		return err
+	}
+
+	return s.repository.SetAgentTaskTags(ctx, agent, taskID, schema)
+}
+
+func (s *Service) ListAgentTaskFiles(ctx context.Context, agentID, taskID string) ([]*entities.TaskFile, error) {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.ListAgentTaskFiles(ctx, agent, taskID)
+}
+
+// resolveAgent parses the agent UUID and loads it from the database
+func (s *Service) resolveAgent(agentID string) (*entities.Agent, error) {
 	uid, err := uuid.Parse(agentID)
 	if err != nil {
-		return fmt.Errorf("invalid agent UUID format: %w", err)
+		return nil, fmt.Errorf("invalid UUID format: %w", err)
 	}
 
 	agent, err := s.repository.GetAgentByUUID(uid)
 	if err != nil {
-		return fmt.Errorf("agent not found: %w", err)
+		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
-	return s.repository.ForceDownloadAgentTask(agent, taskID)
+	return agent, nil
+}
+
+// resolveCapableAgent resolves an agent whose torrent client supports an action
+func (s *Service) resolveCapableAgent(agentID string, supported func(entities.ClientCapabilities) bool) (*entities.Agent, error) {
+	agent, err := s.resolveAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !supported(entities.CapabilitiesOf(agent.Type)) {
+		return nil, fmt.Errorf("agent %s runs %s: %w", agent.Name, agent.Type, errors.ErrNotSupported)
+	}
+
+	return agent, nil
 }
 
 func ToResponse(item *entities.Agent) models.AgentResponse {
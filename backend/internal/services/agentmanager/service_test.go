@@ -1,14 +1,27 @@
 package agentmanager
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/repository/agent"
+	"github.com/gardarr/gardarr/internal/schemas"
 	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 // TestRepository is a test implementation that simulates network delays
@@ -17,14 +30,21 @@ type TestRepository struct {
 	delay time.Duration
 }
 
-func NewTestRepository(db *database.Database, crypto *crypto.CryptoService, delay time.Duration) *TestRepository {
+func NewTestRepository(t *testing.T, db *database.Database, crypto *crypto.CryptoService, delay time.Duration) *TestRepository {
+	t.Helper()
+
+	repository, err := agent.NewRepository(db, crypto)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
 	return &TestRepository{
-		Repository: agent.NewRepository(db, crypto),
+		Repository: repository,
 		delay:      delay,
 	}
 }
 
-func (r *TestRepository) ListAgentTasks(agent *entities.Agent) ([]*entities.Task, error) {
+func (r *TestRepository) ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error) {
 	// Simulate network delay
 	time.Sleep(r.delay)
 
@@ -47,7 +67,7 @@ func TestService_ListTasks_EmptyAgents(t *testing.T) {
 	// Create a minimal service for testing
 	service := &Service{}
 
-	tasks, err := service.ListTasks([]*entities.Agent{})
+	tasks, err := service.ListTasks(context.Background(), []*entities.Agent{})
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -69,7 +89,7 @@ func TestService_ListTasks_ParallelExecution(t *testing.T) {
 	// but we can test the basic functionality and error handling
 
 	// Test with empty agents
-	tasks, err := service.ListTasks([]*entities.Agent{})
+	tasks, err := service.ListTasks(context.Background(), []*entities.Agent{})
 	if err != nil {
 		t.Errorf("Expected no error for empty agents, got %v", err)
 	}
@@ -86,7 +106,7 @@ func TestService_ListTasks_ErrorHandling(t *testing.T) {
 	service := &Service{}
 
 	// Test with nil agents slice
-	tasks, err := service.ListTasks(nil)
+	tasks, err := service.ListTasks(context.Background(), nil)
 	if err != nil {
 		t.Errorf("Expected no error for nil agents, got %v", err)
 	}
@@ -95,6 +115,288 @@ func TestService_ListTasks_ErrorHandling(t *testing.T) {
 	}
 }
 
+func TestService_RejectsActionsUnsupportedByTheClient(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Agent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// the address is never reached, the action is rejected before
+	item := &models.Agent{Name: "seedbox", Type: entities.ClientTransmission, Address: "http://127.0.0.1:1", EncrypetedToken: "token"}
+	if err := db.Create(item).Error; err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	service, err := NewService(&database.Database{DB: db}, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	ctx := context.Background()
+	agentID := item.UUID.String()
+
+	if err := service.SetAgentTaskSuperSeeding(ctx, agentID, "hash", schemas.TaskSuperSeedingSchema{Enabled: true}); !errors.Is(err, pkgerrors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for super seeding, got %v", err)
+	}
+	if err := service.SetAgentTaskCategory(ctx, agentID, "hash", schemas.TaskSetCategorySchema{Category: "tv"}); !errors.Is(err, pkgerrors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for categories, got %v", err)
+	}
+	if _, err := service.CreateAgentTask(ctx, agentID, schemas.TaskCreateSchema{MagnetURI: "magnet:?xt=urn:btih:hash", ContentLayout: "NoSubfolder"}); !errors.Is(err, pkgerrors.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for a content layout, got %v", err)
+	}
+	if err := service.StopAgentTask(ctx, agentID, "hash"); errors.Is(err, pkgerrors.ErrNotSupported) {
+		t.Errorf("Expected stop to be forwarded to the agent, got %v", err)
+	}
+}
+
+func TestService_SyncClients(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Agent{}, &models.AgentHealth{}, &models.AgentHealthEvent{}, &models.AgentPermission{}, &models.TransferSample{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// the agent lists its clients, or answers 404 as the agents managing a single one
+	var listed atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clients, _ := listed.Load().(string)
+		if r.URL.Path != "/v1/clients/" || clients == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(clients))
+	}))
+	defer server.Close()
+
+	uid := uuid.New()
+	token, err := cryptoSvc.EncryptWithAD("secret", crypto.AssociatedData(uid))
+	if err != nil {
+		t.Fatalf("Failed to encrypt token: %v", err)
+	}
+	if err := db.Create(&models.Agent{UUID: uid, Name: "box", Type: entities.ClientQBittorrent, Address: server.URL, EncrypetedToken: token}).Error; err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	service, err := NewService(&database.Database{DB: db}, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	ctx := context.Background()
+
+	parent, err := service.repository.GetAgentByUUID(uid)
+	if err != nil {
+		t.Fatalf("Failed to get agent: %v", err)
+	}
+
+	listed.Store(`[{"name":"default","type":"qbittorrent","default":true,"status":"ACTIVE"},` +
+		`{"name":"movies","type":"transmission","status":"ACTIVE"},{"name":"tv","type":"qbittorrent","status":"ACTIVE"}]`)
+	children, err := service.SyncClients(ctx, parent)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(children) != 2 || children[0].Name != "box/movies" || children[0].Type != entities.ClientTransmission || children[1].Client != "tv" {
+		t.Fatalf("Expected the movies and tv child agents, got %+v", children)
+	}
+
+	agents, err := service.ListAgents(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, a := range agents {
+		if a.UUID != uid && (a.Parent == nil || a.Parent.UUID != uid || a.Address != server.URL) {
+			t.Errorf("Expected agent %s to be reached through its parent, got %+v", a.Name, a.Parent)
+		}
+	}
+
+	// a client removed from the agent configuration removes its child agent
+	listed.Store(`[{"name":"default","type":"qbittorrent","default":true},{"name":"movies","type":"transmission"}]`)
+	children, err = service.SyncClients(ctx, parent)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(children) != 1 || children[0].Client != "movies" {
+		t.Errorf("Expected the movies child agent to be kept, got %+v", children)
+	}
+
+	// an update of the child agent must not redirect it
+	if _, err := service.UpdateAgent(ctx, children[0].UUID.String(), &schemas.AgentUpdateSchema{Address: "http://elsewhere"}); !errors.Is(err, pkgerrors.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for the address of a child agent, got %v", err)
+	}
+
+	// deleting the parent deletes its children and their statistics
+	for _, owner := range []uuid.UUID{uid, children[0].UUID} {
+		sample := &models.TransferSample{AgentUUID: owner, Scope: entities.TransferScopeInstance, Resolution: entities.TransferResolutionRaw, Timestamp: time.Now()}
+		if err := db.Create(sample).Error; err != nil {
+			t.Fatalf("Failed to create transfer sample: %v", err)
+		}
+	}
+	if err := service.Delete(ctx, uid.String()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var count int64
+	db.Model(&models.Agent{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no agent left, got %d", count)
+	}
+	db.Model(&models.TransferSample{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no transfer sample left, got %d", count)
+	}
+
+	// an agent managing a single client has no children
+	if err := db.Create(&models.Agent{UUID: uid, Name: "box", Address: server.URL, EncrypetedToken: token}).Error; err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	listed.Store("")
+	children, err = service.SyncClients(ctx, parent)
+	if err != nil {
+		t.Fatalf("Expected a 404 to mean a single client, got %v", err)
+	}
+	if len(children) != 0 {
+		t.Errorf("Expected no child agent, got %d", len(children))
+	}
+}
+
+func TestService_Get(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Agent{}, &models.AgentHealth{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// the address is never reached, the status comes from the health probes
+	item := &models.Agent{Name: "seedbox", Type: entities.ClientQBittorrent, Address: "http://127.0.0.1:1", EncrypetedToken: "token"}
+	if err := db.Create(item).Error; err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	service, err := NewService(&database.Database{DB: db}, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	ctx := context.Background()
+
+	agent, err := service.Get(ctx, item.UUID.String())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if agent.Status != entities.AgentStatusInactive {
+		t.Errorf("Expected an agent not probed yet to be inactive, got %s", agent.Status)
+	}
+
+	if err := service.repository.SaveAgentHealth(ctx, &entities.AgentHealth{AgentUUID: item.UUID, Status: entities.AgentStatusActive, LastCheckAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to save agent health: %v", err)
+	}
+	agent, err = service.Get(ctx, item.UUID.String())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if agent.Status != entities.AgentStatusActive || agent.Health == nil {
+		t.Errorf("Expected the status of the last probe, got %s", agent.Status)
+	}
+}
+
+func TestService_CreateAgent(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("Failed to create crypto service: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Agent{}, &models.AgentHealth{}, &models.AgentHealthEvent{}, &models.AgentPermission{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/instance/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"application":{"client":"qbittorrent","version":"v5.0.0"}}`))
+	}))
+	defer server.Close()
+
+	service, err := NewService(&database.Database{DB: db}, cryptoSvc)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	ctx := context.Background()
+	fingerprint := agentauth.Fingerprint(server.Certificate())
+
+	// the agent is probed before it is saved, through the pinned certificate
+	if _, err := service.CreateAgent(ctx, &schemas.AgentCreateSchema{Name: "box", Type: entities.ClientQBittorrent, Address: server.URL, Token: "secret", CertificateFingerprint: strings.Repeat("ab", 32)}); err == nil {
+		t.Error("Expected an error for another certificate, got nil")
+	}
+	if requests.Load() != 0 {
+		t.Errorf("Expected no request to reach an agent with another certificate, got %d", requests.Load())
+	}
+	if _, err := service.CreateAgent(ctx, &schemas.AgentCreateSchema{Name: "box", Type: entities.ClientTransmission, Address: server.URL, Token: "secret", CertificateFingerprint: fingerprint}); !errors.Is(err, pkgerrors.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for another torrent client, got %v", err)
+	}
+
+	created, err := service.CreateAgent(ctx, &schemas.AgentCreateSchema{Name: "box", Type: entities.ClientQBittorrent, Address: server.URL, Token: "secret", CertificateFingerprint: fingerprint})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Status != entities.AgentStatusActive || created.Instance == nil || created.Instance.Application.Version != "v5.0.0" {
+		t.Errorf("Expected an active agent with its instance, got %+v", created)
+	}
+
+	saved, err := service.repository.GetAgentByUUID(created.UUID)
+	if err != nil {
+		t.Fatalf("Failed to get agent: %v", err)
+	}
+	if secret, err := service.repository.AgentSecret(saved); err != nil || secret != "secret" {
+		t.Errorf("Expected the token to be saved encrypted, got '%s' (%v)", secret, err)
+	}
+
+	// an update is probed with the new token, or the saved one decrypted
+	if _, err := service.UpdateAgent(ctx, created.UUID.String(), &schemas.AgentUpdateSchema{Token: "rotated"}); err != nil {
+		t.Fatalf("Expected no error updating the token, got %v", err)
+	}
+	if _, err := service.UpdateAgent(ctx, created.UUID.String(), &schemas.AgentUpdateSchema{Name: "renamed"}); err != nil {
+		t.Fatalf("Expected no error updating the name, got %v", err)
+	}
+	saved, _ = service.repository.GetAgentByUUID(created.UUID)
+	if secret, _ := service.repository.AgentSecret(saved); secret != "rotated" || saved.Name != "renamed" {
+		t.Errorf("Expected the rotated token and the new name, got '%s' and '%s'", secret, saved.Name)
+	}
+}
+
 // Note: Benchmark test removed as it requires a properly initialized repository
 // The parallel implementation can be verified through integration tests
 
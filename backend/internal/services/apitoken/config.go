@@ -0,0 +1,21 @@
+package apitoken
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the API token settings
+type Config struct {
+	DefaultTTL time.Duration // lifetime of a token created without one
+	MaxTTL     time.Duration // longest lifetime a token can be created with
+}
+
+// LoadConfigFromEnv loads the API token configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		DefaultTTL: env.Get("AUTH_API_TOKEN_TTL").Default("2160h").ValueDuration(),
+		MaxTTL:     env.Get("AUTH_API_TOKEN_MAX_TTL").Default("8760h").ValueDuration(),
+	}
+}
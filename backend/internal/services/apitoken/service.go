@@ -0,0 +1,149 @@
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/apitoken"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	tokenPrefix = "gdr_" // makes the tokens easy to spot, in a leaked file for instance
+	tokenLength = 32     // 32 bytes = 256 bits
+	prefixShown = 12     // characters of the token kept to recognize it
+
+	// touchInterval bounds how often the last use of a token is written
+	touchInterval = time.Minute
+)
+
+type Service struct {
+	repository *apitoken.Repository
+	config     Config
+	now        func() time.Time
+}
+
+func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with the default and maximal lifetimes of the API tokens
+func NewWithConfig(db *database.Database, config Config) *Service {
+	return &Service{
+		repository: apitoken.NewRepository(db),
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// CreateToken creates an API token for a user. A zero ttl gives the default
+// lifetime. The returned token holds the token itself, which is not stored.
+func (s *Service) CreateToken(ctx context.Context, user *entities.User, name string, scopes []string, ttl time.Duration) (*entities.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: a name is required", pkgerrors.ErrInvalidInput)
+	}
+
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(entities.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", pkgerrors.ErrInvalidInput, scope)
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", pkgerrors.ErrInvalidInput)
+	}
+
+	if ttl == 0 {
+		ttl = s.config.DefaultTTL
+	}
+	if ttl < 0 || ttl > s.config.MaxTTL {
+		return nil, fmt.Errorf("%w: the lifetime must be at most %s", pkgerrors.ErrInvalidInput, s.config.MaxTTL)
+	}
+
+	token, err := crypto.GenerateToken(tokenPrefix, tokenLength)
+	if err != nil {
+		return nil, errors.New("failed to generate api token")
+	}
+
+	result := &entities.APIToken{
+		UserUUID:  user.UUID,
+		Name:      name,
+		Prefix:    token[:prefixShown],
+		Token:     token,
+		Scopes:    granted,
+		ExpiresAt: s.now().Add(ttl),
+	}
+	if err := s.repository.CreateToken(ctx, result, crypto.HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListTokens returns the API tokens of a user
+func (s *Service) ListTokens(ctx context.Context, userUUID uuid.UUID) ([]*entities.APIToken, error) {
+	return s.repository.ListUserTokens(ctx, userUUID)
+}
+
+// RevokeToken deletes an API token of a user
+func (s *Service) RevokeToken(ctx context.Context, userUUID uuid.UUID, id string) error {
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		return pkgerrors.ErrInvalidUUID
+	}
+
+	return s.repository.DeleteToken(ctx, userUUID, tokenID)
+}
+
+// Authenticate validates an API token and returns its user
+func (s *Service) Authenticate(ctx context.Context, token string) (*entities.User, *entities.APIToken, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, nil, errors.New("invalid api token")
+	}
+
+	model, err := s.repository.GetTokenByHash(ctx, crypto.HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+	if now.After(model.ExpiresAt) {
+		return nil, nil, errors.New("api token expired")
+	}
+
+	// A disabled user cannot use its tokens
+	if model.User.Disabled {
+		return nil, nil, errors.New("user disabled")
+	}
+
+	// Recording every request would write to the database on each call
+	if model.LastUsedAt == nil || now.Sub(*model.LastUsedAt) >= touchInterval {
+		if err := s.repository.TouchToken(ctx, model.ID, now); err == nil {
+			model.LastUsedAt = &now
+		}
+	}
+
+	user := &entities.User{
+		UUID:      model.User.UUID,
+		Username:  model.User.Username,
+		Email:     model.User.Email,
+		Role:      model.User.Role,
+		Disabled:  model.User.Disabled,
+		CreatedAt: model.User.CreatedAt,
+		UpdatedAt: model.User.UpdatedAt,
+	}
+
+	return user, apitoken.ToEntity(*model), nil
+}
@@ -0,0 +1,157 @@
+package apitoken
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *database.Database {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.APIToken{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	return &database.Database{DB: db}
+}
+
+func createUser(t *testing.T, db *database.Database, email, role string) *entities.User {
+	model := &models.User{Username: email, Email: email, PasswordHash: "hash", Salt: "salt", Role: role}
+	if err := db.DB.Create(model).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return &entities.User{UUID: model.UUID, Email: model.Email, Role: model.Role}
+}
+
+func TestCreateToken(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour})
+	user := createUser(t, db, "ops@example.com", entities.RoleOperator)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		scopes []string
+		ttl    time.Duration
+	}{
+		{name: "No scope", scopes: nil},
+		{name: "Unknown scope", scopes: []string{"users:admin"}},
+		{name: "Lifetime above the maximum", scopes: []string{entities.ScopeTasksRead}, ttl: 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateToken(ctx, user, "script", tt.scopes, tt.ttl); !errors.Is(err, errors.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+
+	token, err := service.CreateToken(ctx, user, "script", []string{entities.ScopeTasksRead, entities.ScopeTasksRead}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token.Token, tokenPrefix) || !strings.HasPrefix(token.Token, token.Prefix) {
+		t.Errorf("Expected a prefixed token, got %q (prefix %q)", token.Token, token.Prefix)
+	}
+	if len(token.Scopes) != 1 {
+		t.Errorf("Expected duplicated scopes to be merged, got %v", token.Scopes)
+	}
+	if time.Until(token.ExpiresAt) > time.Hour {
+		t.Errorf("Expected the default lifetime, got expiry %v", token.ExpiresAt)
+	}
+
+	var stored models.APIToken
+	if err := db.DB.First(&stored, "id = ?", token.ID).Error; err != nil {
+		t.Fatalf("failed to read the stored token: %v", err)
+	}
+	if stored.TokenHash == token.Token || stored.TokenHash != crypto.HashToken(token.Token) {
+		t.Error("Expected the token to be stored hashed")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour})
+	user := createUser(t, db, "ops@example.com", entities.RoleOperator)
+	ctx := context.Background()
+
+	token, err := service.CreateToken(ctx, user, "script", []string{entities.ScopeTasksWrite}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	authenticated, apiToken, err := service.Authenticate(ctx, token.Token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authenticated.UUID != user.UUID || authenticated.Role != entities.RoleOperator {
+		t.Errorf("Expected the token user, got %+v", authenticated)
+	}
+	if !apiToken.HasScope(entities.ScopeTasksWrite) || apiToken.HasScope(entities.ScopeAgentsAdmin) {
+		t.Errorf("Expected the token scopes, got %v", apiToken.Scopes)
+	}
+	if apiToken.LastUsedAt == nil {
+		t.Error("Expected the last use to be recorded")
+	}
+
+	if _, _, err := service.Authenticate(ctx, tokenPrefix+"unknown"); err == nil {
+		t.Error("Expected an unknown token to be rejected")
+	}
+
+	service.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := service.Authenticate(ctx, token.Token); err == nil {
+		t.Error("Expected an expired token to be rejected")
+	}
+	service.now = time.Now
+
+	if err := db.DB.Model(&models.User{}).Where("uuid = ?", user.UUID).Update("disabled", true).Error; err != nil {
+		t.Fatalf("failed to disable test user: %v", err)
+	}
+	if _, _, err := service.Authenticate(ctx, token.Token); err == nil {
+		t.Error("Expected the token of a disabled user to be rejected")
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour})
+	owner := createUser(t, db, "owner@example.com", entities.RoleOperator)
+	other := createUser(t, db, "other@example.com", entities.RoleOperator)
+	ctx := context.Background()
+
+	token, err := service.CreateToken(ctx, owner, "script", []string{entities.ScopeTasksRead}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.RevokeToken(ctx, other.UUID, token.ID.String()); !errors.Is(err, errors.ErrAPITokenNotFound) {
+		t.Errorf("Expected another user to be unable to revoke the token, got %v", err)
+	}
+	if err := service.RevokeToken(ctx, owner.UUID, token.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := service.Authenticate(ctx, token.Token); err == nil {
+		t.Error("Expected a revoked token to be rejected")
+	}
+
+	tokens, err := service.ListTokens(ctx, owner.UUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("Expected no token left, got %d", len(tokens))
+	}
+}
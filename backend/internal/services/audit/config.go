@@ -0,0 +1,21 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the audit log settings
+type Config struct {
+	Retention       time.Duration // zero keeps the audit log forever
+	CleanupInterval time.Duration // time between two removals of the events past the retention
+}
+
+// LoadConfigFromEnv loads the audit log configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		Retention:       env.Get("AUDIT_RETENTION").Default("8760h").ValueDuration(),
+		CleanupInterval: env.Get("AUDIT_CLEANUP_INTERVAL").Default("1h").ValuePositiveDuration(),
+	}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/audit"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/google/uuid"
+)
+
+// Service keeps the security audit log: logins, lockouts, sessions, tokens and
+// the administrative changes
+type Service struct {
+	repository *audit.Repository
+	config     Config
+	now        func() time.Time
+}
+
+func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with the retention of the events and the cleanup period
+func NewWithConfig(db *database.Database, config Config) *Service {
+	return &Service{
+		repository: audit.NewRepository(db),
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Record appends an event to the audit log. Failures are logged, they never
+// fail the audited action. The event is written even if ctx, usually the one
+// of the request, is canceled.
+func (s *Service) Record(ctx context.Context, event *entities.AuditEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = s.now()
+	}
+
+	if err := s.repository.CreateEvent(context.WithoutCancel(ctx), event); err != nil {
+		log.Printf("audit: failed to record %s event: %v", event.Action, err)
+	}
+}
+
+// ListEvents retrieves a page of the audit log, newest first, and the number
+// of events matching the query
+func (s *Service) ListEvents(ctx context.Context, query schemas.AuditEventQuery) ([]*entities.AuditEvent, int64, error) {
+	filter := audit.Filter{
+		IPAddress:  query.IPAddress,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+		Since:      query.Since,
+		Until:      query.Until,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	}
+
+	for _, action := range strings.Split(query.Action, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			filter.Actions = append(filter.Actions, action)
+		}
+	}
+
+	if query.Actor != "" {
+		uid, err := uuid.Parse(query.Actor)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid UUID format: %w", err)
+		}
+		filter.ActorUUID = uid
+	}
+
+	return s.repository.ListEvents(ctx, filter)
+}
+
+// Cleanup removes the events older than the retention and returns how many
+// were removed
+func (s *Service) Cleanup(ctx context.Context) (int64, error) {
+	if s.config.Retention <= 0 {
+		return 0, nil
+	}
+	return s.repository.DeleteEventsBefore(ctx, s.now().Add(-s.config.Retention))
+}
+
+// Run removes the events past the retention on each cleanup interval until ctx is canceled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.Cleanup(ctx)
+			if err != nil {
+				log.Printf("audit: failed to prune audit log: %v", err)
+			} else if removed > 0 {
+				log.Printf("audit: removed %d events past the retention", removed)
+			}
+		}
+	}
+}
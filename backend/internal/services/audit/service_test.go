@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *database.Database {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.AuditEvent{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	return &database.Database{DB: db}
+}
+
+func TestListEvents(t *testing.T) {
+	service := NewWithConfig(setupTestDB(t), Config{Retention: 24 * time.Hour, CleanupInterval: time.Hour})
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	admin := uuid.New()
+
+	events := []*entities.AuditEvent{
+		{Action: entities.AuditLoginFailed, ActorEmail: "admin@example.com", IPAddress: "203.0.113.7"},
+		{Action: entities.AuditLockout, IPAddress: "203.0.113.7"},
+		{Action: entities.AuditLogin, ActorUUID: admin, IPAddress: "10.0.0.1"},
+		{Action: entities.AuditAgentDelete, ActorUUID: admin, IPAddress: "10.0.0.1", TargetType: entities.AuditTargetAgent, TargetID: "agent-1"},
+	}
+	for i, event := range events {
+		event.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		service.Record(ctx, event)
+		if event.ID == uuid.Nil {
+			t.Fatalf("expected event %d to be recorded", i)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    schemas.AuditEventQuery
+		expected []string
+		total    int64
+	}{
+		{"all, newest first", schemas.AuditEventQuery{}, []string{entities.AuditAgentDelete, entities.AuditLogin, entities.AuditLockout, entities.AuditLoginFailed}, 4},
+		{"actions", schemas.AuditEventQuery{Action: "auth.lockout, auth.login_failed"}, []string{entities.AuditLockout, entities.AuditLoginFailed}, 2},
+		{"actor", schemas.AuditEventQuery{Actor: admin.String()}, []string{entities.AuditAgentDelete, entities.AuditLogin}, 2},
+		{"client address", schemas.AuditEventQuery{IPAddress: "203.0.113.7"}, []string{entities.AuditLockout, entities.AuditLoginFailed}, 2},
+		{"target", schemas.AuditEventQuery{TargetType: entities.AuditTargetAgent, TargetID: "agent-1"}, []string{entities.AuditAgentDelete}, 1},
+		{"time range", schemas.AuditEventQuery{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []string{entities.AuditLogin, entities.AuditLockout}, 2},
+		{"page", schemas.AuditEventQuery{Limit: 2, Offset: 1}, []string{entities.AuditLogin, entities.AuditLockout}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, total, err := service.ListEvents(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if total != tt.total {
+				t.Errorf("Expected %d matching events, got %d", tt.total, total)
+			}
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d events, got %d", len(tt.expected), len(result))
+			}
+			for i, action := range tt.expected {
+				if result[i].Action != action {
+					t.Errorf("Expected event %d to be '%s', got '%s'", i, action, result[i].Action)
+				}
+			}
+		})
+	}
+
+	if _, _, err := service.ListEvents(ctx, schemas.AuditEventQuery{Actor: "not-a-uuid"}); err == nil {
+		t.Error("expected an invalid actor to be rejected")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	service := NewWithConfig(setupTestDB(t), Config{Retention: 24 * time.Hour, CleanupInterval: time.Hour})
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	service.Record(ctx, &entities.AuditEvent{Action: entities.AuditLogin, CreatedAt: now.Add(-48 * time.Hour)})
+	service.Record(ctx, &entities.AuditEvent{Action: entities.AuditLogout})
+
+	removed, err := service.Cleanup(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 event past the retention to be removed, got %d", removed)
+	}
+
+	result, _, _ := service.ListEvents(ctx, schemas.AuditEventQuery{})
+	if len(result) != 1 || result[0].Action != entities.AuditLogout {
+		t.Errorf("Expected the recent event to be kept, got %v", result)
+	}
+
+	// A zero retention keeps the audit log forever
+	service.config.Retention = 0
+	service.now = func() time.Time { return now.Add(1000 * time.Hour) }
+	if removed, _ := service.Cleanup(ctx); removed != 0 {
+		t.Errorf("Expected no event to be removed, got %d", removed)
+	}
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RotationResult counts the secrets of a rotation
+type RotationResult struct {
+	AgentTokens   int
+	TOTPSecrets   int
+	AlreadyActive int
+}
+
+// storedSecret is a secret stored in the database and the record owning it
+type storedSecret struct {
+	agent      bool // an agent token, else a TOTP secret
+	owner      uuid.UUID
+	ciphertext string
+}
+
+func (s storedSecret) String() string {
+	if s.agent {
+		return "agent " + s.owner.String()
+	}
+	return "totp secret of user " + s.owner.String()
+}
+
+// update stores the ciphertext encrypted again
+func (s storedSecret) update(tx *gorm.DB, ciphertext string) error {
+	if s.agent {
+		return tx.Model(&models.Agent{}).Where("uuid = ?", s.owner).Update("encrypeted_token", ciphertext).Error
+	}
+	return tx.Model(&models.UserTOTP{}).Where("user_uuid = ?", s.owner).Update("encrypted_secret", ciphertext).Error
+}
+
+// count adds the secret encrypted again to the result
+func (s storedSecret) count(result *RotationResult) {
+	if s.agent {
+		result.AgentTokens++
+	} else {
+		result.TOTPSecrets++
+	}
+}
+
+// storedSecrets lists the agent tokens and TOTP secrets
+func storedSecrets(tx *gorm.DB) ([]storedSecret, error) {
+	// child agents have no token, they use the one of their parent
+	var agents []models.Agent
+	if err := tx.Where("encrypeted_token <> ''").Find(&agents).Error; err != nil {
+		return nil, err
+	}
+
+	var totps []models.UserTOTP
+	if err := tx.Find(&totps).Error; err != nil {
+		return nil, err
+	}
+
+	secrets := make([]storedSecret, 0, len(agents)+len(totps))
+	for _, agent := range agents {
+		secrets = append(secrets, storedSecret{agent: true, owner: agent.UUID, ciphertext: agent.EncrypetedToken})
+	}
+	for _, totp := range totps {
+		secrets = append(secrets, storedSecret{owner: totp.UserUUID, ciphertext: totp.EncryptedSecret})
+	}
+	return secrets, nil
+}
+
+// Rotate encrypts again with the active key the secrets stored in the
+// database, binding them to their owner. The secrets are rotated in a single
+// transaction: a secret that cannot be decrypted with the keyring rolls back
+// every change, so the previous keys can be removed once it succeeds.
+func (cs *CryptoService) Rotate(ctx context.Context, db *database.Database) (*RotationResult, error) {
+	result := &RotationResult{}
+
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		secrets, err := storedSecrets(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, secret := range secrets {
+			encrypted, changed, err := cs.Reencrypt(secret.ciphertext, AssociatedData(secret.owner))
+			if err != nil {
+				return fmt.Errorf("%s: %w", secret, err)
+			}
+			if !changed {
+				result.AlreadyActive++
+				continue
+			}
+
+			if err := secret.update(tx, encrypted); err != nil {
+				return err
+			}
+			secret.count(result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpgradeLegacy binds the secrets predating the keyring to their owner, which
+// DecryptWithAD requires, by encrypting them again with the active key. It
+// runs at startup so an upgrade needs no rotation. The secrets no key
+// decrypts are logged and left as they are, the others being kept.
+func (cs *CryptoService) UpgradeLegacy(ctx context.Context, db *database.Database) (*RotationResult, error) {
+	result := &RotationResult{}
+
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		secrets, err := storedSecrets(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, secret := range secrets {
+			if strings.HasPrefix(secret.ciphertext, versionPrefix) {
+				continue
+			}
+
+			encrypted, _, err := cs.Reencrypt(secret.ciphertext, AssociatedData(secret.owner))
+			if err != nil {
+				log.Printf("crypto: cannot upgrade the %s: %v", secret, err)
+				continue
+			}
+
+			if err := secret.update(tx, encrypted); err != nil {
+				return err
+			}
+			secret.count(result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
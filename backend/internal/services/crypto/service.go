@@ -4,39 +4,95 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/google/uuid"
 )
 
-// CryptoService handles encryption and decryption of sensitive data
+const (
+	// keySize is the size of an AES-256 key
+	keySize = 32
+
+	// versionPrefix starts the ciphertexts naming their key, followed by the
+	// key ID: "v1:<key id>:<base64 nonce and ciphertext>". Ciphertexts without
+	// it predate the keyring and have no associated data.
+	versionPrefix = "v1:"
+)
+
+var (
+	ErrUnknownKey = errors.New("ciphertext encrypted with an unknown key")
+	ErrDecrypt    = errors.New("failed to decrypt")
+)
+
+// CryptoService handles encryption and decryption of sensitive data with
+// AES-256-GCM. It holds a keyring: the active key encrypts, the previous ones
+// are kept to decrypt what they encrypted until it is rotated.
 type CryptoService struct {
+	active *key
+	keys   map[string]*key // by ID, the active key included
+	order  []*key          // active key first, for the unversioned ciphertexts
+}
+
+type key struct {
+	id  string
 	gcm cipher.AEAD
 }
 
-// NewCryptoService creates a new crypto service with AES-256-GCM
+// NewCryptoService creates the crypto service from ENCRYPTION_KEY, the
+// active key, and ENCRYPTION_PREVIOUS_KEYS, the comma separated keys it replaced
 func NewCryptoService() (*CryptoService, error) {
 	// Get encryption key from environment variable (32 bytes for AES-256)
-	keyStr := env.Get("ENCRYPTION_KEY").Value()
-	if keyStr == "" {
+	active := env.Get("ENCRYPTION_KEY").Value()
+	if active == "" {
 		return nil, errors.New("ENCRYPTION_KEY environment variable is required")
 	}
 
+	return NewKeyring(active, env.Get("ENCRYPTION_PREVIOUS_KEYS").ValueList()...)
+}
+
+// NewKeyring creates a crypto service encrypting with the active key, base64
+// encoded, and decrypting with it and the previous keys
+func NewKeyring(active string, previous ...string) (*CryptoService, error) {
+	cs := &CryptoService{keys: make(map[string]*key)}
+
+	for i, encoded := range append([]string{active}, previous...) {
+		k, err := newKey(encoded)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("invalid ENCRYPTION_KEY: %w", err)
+			}
+			return nil, fmt.Errorf("invalid previous encryption key #%d: %w", i, err)
+		}
+
+		if _, exists := cs.keys[k.id]; exists {
+			continue
+		}
+		cs.keys[k.id] = k
+		cs.order = append(cs.order, k)
+	}
+	cs.active = cs.order[0]
+
+	return cs, nil
+}
+
+func newKey(encoded string) (*key, error) {
 	// Decode base64 key
-	key, err := base64.StdEncoding.DecodeString(keyStr)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, err
 	}
-
-	fmt.Println(key)
-	if len(key) != 32 {
+	if len(raw) != keySize {
 		return nil, errors.New("encryption key must be 32 bytes (AES-256)")
 	}
 
-	block, err := aes.NewCipher(key)
+	block, err := aes.NewCipher(raw)
 	if err != nil {
 		return nil, err
 	}
@@ -46,36 +102,125 @@ func NewCryptoService() (*CryptoService, error) {
 		return nil, err
 	}
 
-	return &CryptoService{gcm: gcm}, nil
+	return &key{id: KeyID(raw), gcm: gcm}, nil
+}
+
+// KeyID identifies a key in the ciphertexts without revealing it: the first
+// 4 bytes of its SHA-256, hex encoded
+func KeyID(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:4])
+}
+
+// AssociatedData binds a secret to the record owning it, by its UUID. Records
+// not persisted yet have none.
+func AssociatedData(owner uuid.UUID) []byte {
+	if owner == uuid.Nil {
+		return nil
+	}
+	return []byte(owner.String())
+}
+
+// ActiveKeyID returns the ID of the key new ciphertexts are encrypted with
+func (cs *CryptoService) ActiveKeyID() string {
+	return cs.active.id
 }
 
 // Encrypt encrypts plaintext using AES-256-GCM
 func (cs *CryptoService) Encrypt(plaintext string) (string, error) {
-	nonce := make([]byte, cs.gcm.NonceSize())
+	return cs.EncryptWithAD(plaintext, nil)
+}
+
+// EncryptWithAD encrypts plaintext with the active key, binding it to the
+// associated data (e.g. the UUID of its owner): decrypting it requires the
+// same data, so a ciphertext cannot be moved to another record
+func (cs *CryptoService) EncryptWithAD(plaintext string, associatedData []byte) (string, error) {
+	nonce := make([]byte, cs.active.gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := cs.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	ciphertext := cs.active.gcm.Seal(nonce, nonce, []byte(plaintext), associatedData)
+	return versionPrefix + cs.active.id + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
 // Decrypt decrypts ciphertext using AES-256-GCM
 func (cs *CryptoService) Decrypt(ciphertext string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	return cs.DecryptWithAD(ciphertext, nil)
+}
+
+// DecryptWithAD decrypts a ciphertext with the key it names and the
+// associated data it was encrypted with. Ciphertexts predating the keyring
+// have none, so they are refused when associated data is given: anyone able
+// to write one to a record would get round the binding. UpgradeLegacy binds them.
+func (cs *CryptoService) DecryptWithAD(ciphertext string, associatedData []byte) (string, error) {
+	return cs.decrypt(ciphertext, associatedData, associatedData == nil)
+}
+
+// decrypt decrypts a ciphertext, the ones predating the keyring only when
+// legacy is set, trying every key without associated data
+func (cs *CryptoService) decrypt(ciphertext string, associatedData []byte, legacy bool) (string, error) {
+	if !strings.HasPrefix(ciphertext, versionPrefix) {
+		if !legacy {
+			return "", ErrDecrypt
+		}
+		for _, k := range cs.order {
+			if plaintext, err := open(k, ciphertext, nil); err == nil {
+				return plaintext, nil
+			}
+		}
+		return "", ErrDecrypt
+	}
+
+	id, data, ok := strings.Cut(strings.TrimPrefix(ciphertext, versionPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed ciphertext")
+	}
+
+	k, ok := cs.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, id)
+	}
+
+	return open(k, data, associatedData)
+}
+
+// Reencrypt encrypts again with the active key a ciphertext of a previous key
+// or predating the keyring, binding the latter to the associated data. It
+// tells whether the ciphertext changed.
+func (cs *CryptoService) Reencrypt(ciphertext string, associatedData []byte) (string, bool, error) {
+	if strings.HasPrefix(ciphertext, versionPrefix+cs.active.id+":") {
+		return ciphertext, false, nil
+	}
+
+	plaintext, err := cs.decrypt(ciphertext, associatedData, true)
+	if err != nil {
+		return "", false, err
+	}
+
+	reencrypted, err := cs.EncryptWithAD(plaintext, associatedData)
+	if err != nil {
+		return "", false, err
+	}
+
+	return reencrypted, true, nil
+}
+
+func open(k *key, encoded string, associatedData []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", err
 	}
 
-	nonceSize := cs.gcm.NonceSize()
+	nonceSize := k.gcm.NonceSize()
 	if len(data) < nonceSize {
 		return "", errors.New("ciphertext too short")
 	}
 
-	nonce, ciphertext_bytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := cs.gcm.Open(nil, nonce, ciphertext_bytes, nil)
+	nonce, sealed := data[:nonceSize], data[nonceSize:]
+	plaintext, err := k.gcm.Open(nil, nonce, sealed, associatedData)
 	if err != nil {
-		return "", err
+		return "", ErrDecrypt
 	}
 
 	return string(plaintext), nil
@@ -0,0 +1,336 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestKey(t *testing.T) string {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// legacyEncrypt encrypts as before the keyring: no version prefix nor associated data
+func legacyEncrypt(t *testing.T, encodedKey, plaintext string) string {
+	raw, _ := base64.StdEncoding.DecodeString(encodedKey)
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("failed to create gcm: %v", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		t.Fatalf("failed to generate nonce: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func TestNewCryptoService(t *testing.T) {
+	active, previous := newTestKey(t), newTestKey(t)
+	t.Setenv("ENCRYPTION_KEY", active)
+	t.Setenv("ENCRYPTION_PREVIOUS_KEYS", " "+previous+", ")
+
+	cs, err := NewCryptoService()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cs.order) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(cs.order))
+	}
+
+	t.Setenv("ENCRYPTION_PREVIOUS_KEYS", "not-a-key")
+	if _, err := NewCryptoService(); err == nil {
+		t.Error("Expected error for an invalid previous key, got nil")
+	}
+
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	t.Setenv("ENCRYPTION_PREVIOUS_KEYS", "")
+	if _, err := NewCryptoService(); err == nil {
+		t.Error("Expected error for a short key, got nil")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	cs, err := NewKeyring(newTestKey(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ciphertext, err := cs.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, "v1:"+cs.ActiveKeyID()+":") {
+		t.Errorf("Expected the ciphertext to name key %s, got %s", cs.ActiveKeyID(), ciphertext)
+	}
+
+	plaintext, err := cs.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plaintext != "secret" {
+		t.Errorf("Expected secret, got %s", plaintext)
+	}
+}
+
+func TestDecryptWithAD(t *testing.T) {
+	cs, err := NewKeyring(newTestKey(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	owner, other := uuid.New(), uuid.New()
+	ciphertext, err := cs.EncryptWithAD("secret", AssociatedData(owner))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if plaintext, err := cs.DecryptWithAD(ciphertext, AssociatedData(owner)); err != nil || plaintext != "secret" {
+		t.Errorf("Expected secret, got %q (%v)", plaintext, err)
+	}
+
+	// A ciphertext moved to another record cannot be decrypted
+	if _, err := cs.DecryptWithAD(ciphertext, AssociatedData(other)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+	if _, err := cs.Decrypt(ciphertext); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+}
+
+func TestDecryptWithAD_RefusesLegacyCiphertexts(t *testing.T) {
+	encoded := newTestKey(t)
+	cs, err := NewKeyring(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	legacy := legacyEncrypt(t, encoded, "secret")
+
+	// A ciphertext without associated data written to a record does not
+	// decrypt as its secret
+	if _, err := cs.DecryptWithAD(legacy, AssociatedData(uuid.New())); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+	if plaintext, err := cs.Decrypt(legacy); err != nil || plaintext != "secret" {
+		t.Errorf("Expected secret without associated data, got %q (%v)", plaintext, err)
+	}
+}
+
+func TestDecrypt_Keyring(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+
+	old, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ciphertext, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rotated, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plaintext, err := rotated.Decrypt(ciphertext); err != nil || plaintext != "secret" {
+		t.Errorf("Expected the previous key to decrypt, got %q (%v)", plaintext, err)
+	}
+	if plaintext, err := rotated.Decrypt(legacyEncrypt(t, oldKey, "legacy")); err != nil || plaintext != "legacy" {
+		t.Errorf("Expected the unversioned ciphertext to decrypt, got %q (%v)", plaintext, err)
+	}
+
+	// Once the previous key is removed
+	current, err := NewKeyring(newKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := current.Decrypt(ciphertext); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	oldKey := newTestKey(t)
+	cs, err := NewKeyring(newTestKey(t), oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	owner := AssociatedData(uuid.New())
+
+	// Ciphertexts of the keyring era keep their associated data, the older
+	// ones get it
+	ciphertext, changed, err := cs.Reencrypt(legacyEncrypt(t, oldKey, "secret"), owner)
+	if err != nil || !changed {
+		t.Fatalf("Expected the ciphertext to change, got %v (%v)", changed, err)
+	}
+	if plaintext, err := cs.DecryptWithAD(ciphertext, owner); err != nil || plaintext != "secret" {
+		t.Errorf("Expected secret, got %q (%v)", plaintext, err)
+	}
+
+	again, changed, err := cs.Reencrypt(ciphertext, owner)
+	if err != nil || changed || again != ciphertext {
+		t.Errorf("Expected the active key ciphertext to be kept, got %v (%v)", changed, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.Agent{}, &models.UserTOTP{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	old, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	agentUUID, userUUID := uuid.New(), uuid.New()
+	token, _ := old.EncryptWithAD("token", AssociatedData(agentUUID))
+	gdb.Create(&models.Agent{UUID: agentUUID, Name: "agent", Address: "http://agent:8080", EncrypetedToken: token})
+	gdb.Create(&models.UserTOTP{UserUUID: userUUID, EncryptedSecret: legacyEncrypt(t, oldKey, "totp")})
+
+	cs, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := cs.Rotate(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AgentTokens != 1 || result.TOTPSecrets != 1 || result.AlreadyActive != 0 {
+		t.Errorf("Expected 1 agent token and 1 TOTP secret, got %+v", result)
+	}
+
+	// The previous key is no longer needed
+	current, _ := NewKeyring(newKey)
+
+	var agent models.Agent
+	gdb.First(&agent, "uuid = ?", agentUUID)
+	if plaintext, err := current.DecryptWithAD(agent.EncrypetedToken, AssociatedData(agentUUID)); err != nil || plaintext != "token" {
+		t.Errorf("Expected token, got %q (%v)", plaintext, err)
+	}
+
+	var secret models.UserTOTP
+	gdb.First(&secret, "user_uuid = ?", userUUID)
+	if plaintext, err := current.DecryptWithAD(secret.EncryptedSecret, AssociatedData(userUUID)); err != nil || plaintext != "totp" {
+		t.Errorf("Expected totp, got %q (%v)", plaintext, err)
+	}
+
+	result, err = cs.Rotate(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AgentTokens != 0 || result.TOTPSecrets != 0 || result.AlreadyActive != 2 {
+		t.Errorf("Expected the secrets to be kept, got %+v", result)
+	}
+}
+
+func TestRotate_RollsBack(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.Agent{}, &models.UserTOTP{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	oldKey, lostKey := newTestKey(t), newTestKey(t)
+	old, _ := NewKeyring(oldKey)
+	lost, _ := NewKeyring(lostKey)
+
+	agentUUID, userUUID := uuid.New(), uuid.New()
+	token, _ := old.EncryptWithAD("token", AssociatedData(agentUUID))
+	secret, _ := lost.EncryptWithAD("totp", AssociatedData(userUUID))
+	gdb.Create(&models.Agent{UUID: agentUUID, Name: "agent", Address: "http://agent:8080", EncrypetedToken: token})
+	gdb.Create(&models.UserTOTP{UserUUID: userUUID, EncryptedSecret: secret})
+
+	cs, _ := NewKeyring(newTestKey(t), oldKey)
+	if _, err := cs.Rotate(context.Background(), db); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected ErrUnknownKey, got %v", err)
+	}
+
+	var agent models.Agent
+	gdb.First(&agent, "uuid = ?", agentUUID)
+	if agent.EncrypetedToken != token {
+		t.Error("Expected the agent token to be left unchanged")
+	}
+}
+
+func TestUpgradeLegacy(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.Agent{}, &models.UserTOTP{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	cs, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A legacy agent token, a versioned one of the previous key and a secret of a lost key
+	agentUUID, currentUUID, userUUID := uuid.New(), uuid.New(), uuid.New()
+	old, _ := NewKeyring(oldKey)
+	current, _ := old.EncryptWithAD("current", AssociatedData(currentUUID))
+	lost := legacyEncrypt(t, newTestKey(t), "totp")
+	gdb.Create(&models.Agent{UUID: agentUUID, Name: "legacy", Address: "http://legacy:8080", EncrypetedToken: legacyEncrypt(t, oldKey, "token")})
+	gdb.Create(&models.Agent{UUID: currentUUID, Name: "current", Address: "http://current:8080", EncrypetedToken: current})
+	gdb.Create(&models.UserTOTP{UserUUID: userUUID, EncryptedSecret: lost})
+
+	result, err := cs.UpgradeLegacy(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AgentTokens != 1 || result.TOTPSecrets != 0 {
+		t.Errorf("Expected only the legacy agent token upgraded, got %+v", result)
+	}
+
+	var agent models.Agent
+	gdb.First(&agent, "uuid = ?", agentUUID)
+	if plaintext, err := cs.DecryptWithAD(agent.EncrypetedToken, AssociatedData(agentUUID)); err != nil || plaintext != "token" {
+		t.Errorf("Expected token, got %q (%v)", plaintext, err)
+	}
+
+	// The versioned secrets are left to Rotate, the undecryptable ones as they are
+	var versioned models.Agent
+	gdb.First(&versioned, "uuid = ?", currentUUID)
+	if versioned.EncrypetedToken != current {
+		t.Error("Expected the versioned token to be left unchanged")
+	}
+	var secret models.UserTOTP
+	gdb.First(&secret, "user_uuid = ?", userUUID)
+	if secret.EncryptedSecret != lost {
+		t.Error("Expected the undecryptable secret to be left unchanged")
+	}
+}
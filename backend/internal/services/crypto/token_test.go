@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestGenerateToken(t *testing.T) {
+	first, err := GenerateToken("gdr_", 32)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := GenerateToken("gdr_", 32)
+
+	if !strings.HasPrefix(first, "gdr_") || first == second {
+		t.Errorf("Expected distinct prefixed tokens, got '%s' and '%s'", first, second)
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(first, "gdr_"))
+	if err != nil || len(decoded) != 32 {
+		t.Errorf("Expected 32 random bytes, got %d (%v)", len(decoded), err)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	// SHA-256 of "abc", FIPS 180-2
+	if hash := HashToken("abc"); hash != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("Unexpected hash %s", hash)
+	}
+}
@@ -0,0 +1,21 @@
+package enrollment
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the agent enrollment settings
+type Config struct {
+	DefaultTTL time.Duration // lifetime of a token issued without one
+	MaxTTL     time.Duration // longest lifetime a token can be issued with
+}
+
+// LoadConfigFromEnv loads the agent enrollment configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		DefaultTTL: env.Get("AGENT_ENROLLMENT_TOKEN_TTL").Default("1h").ValueDuration(),
+		MaxTTL:     env.Get("AGENT_ENROLLMENT_TOKEN_MAX_TTL").Default("24h").ValueDuration(),
+	}
+}
@@ -0,0 +1,148 @@
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentauth"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/enrollment"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	tokenPrefix  = "gde_" // makes the tokens easy to spot, in a shell history for instance
+	tokenLength  = 32     // 32 bytes = 256 bits
+	secretLength = 32
+)
+
+// Service issues the enrollment tokens agents register themselves with. An
+// enrolled agent gets a secret of its own, so no secret is copied by hand.
+type Service struct {
+	repository *enrollment.Repository
+	crypto     *crypto.CryptoService
+	config     Config
+	now        func() time.Time
+}
+
+func NewService(db *database.Database, c *crypto.CryptoService) *Service {
+	return NewWithConfig(db, c, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with how long the enrollment tokens stay usable
+func NewWithConfig(db *database.Database, c *crypto.CryptoService, config Config) *Service {
+	return &Service{
+		repository: enrollment.NewRepository(db),
+		crypto:     c,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// CreateToken issues a single use enrollment token. A zero ttl gives the
+// default lifetime and createdBy is unset for the tokens issued from the
+// shell. The returned token holds the token itself, which is not stored.
+func (s *Service) CreateToken(ctx context.Context, createdBy uuid.UUID, name string, ttl time.Duration) (*entities.EnrollmentToken, error) {
+	if ttl == 0 {
+		ttl = s.config.DefaultTTL
+	}
+	if ttl < 0 || ttl > s.config.MaxTTL {
+		return nil, fmt.Errorf("%w: the lifetime must be at most %s", pkgerrors.ErrInvalidInput, s.config.MaxTTL)
+	}
+
+	token, err := crypto.GenerateToken(tokenPrefix, tokenLength)
+	if err != nil {
+		return nil, errors.New("failed to generate enrollment token")
+	}
+
+	result := &entities.EnrollmentToken{
+		Name:      strings.TrimSpace(name),
+		Token:     token,
+		CreatedBy: createdBy,
+		ExpiresAt: s.now().Add(ttl),
+	}
+	if err := s.repository.CreateToken(ctx, result, crypto.HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListTokens returns the enrollment tokens
+func (s *Service) ListTokens(ctx context.Context) ([]*entities.EnrollmentToken, error) {
+	return s.repository.ListTokens(ctx)
+}
+
+// RevokeToken deletes an enrollment token not used yet
+func (s *Service) RevokeToken(ctx context.Context, id string) error {
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		return pkgerrors.ErrInvalidUUID
+	}
+
+	return s.repository.DeleteToken(ctx, tokenID)
+}
+
+// Enroll registers the agent presenting an enrollment token and generates its
+// secret. The manager does not contact the agent, which may not be reachable
+// yet: the health monitor reports it once it is.
+func (s *Service) Enroll(ctx context.Context, request *schemas.AgentEnrollRequest) (*entities.Enrollment, error) {
+	if !strings.HasPrefix(request.Token, tokenPrefix) {
+		return nil, pkgerrors.ErrEnrollmentTokenInvalid
+	}
+
+	var fingerprint string
+	if request.CertificateFingerprint != "" {
+		var err error
+		if fingerprint, err = agentauth.NormalizeFingerprint(request.CertificateFingerprint); err != nil {
+			return nil, fmt.Errorf("%w: %v", pkgerrors.ErrInvalidInput, err)
+		}
+	}
+
+	secret, err := crypto.GenerateToken("", secretLength)
+	if err != nil {
+		return nil, errors.New("failed to generate agent secret")
+	}
+
+	// The UUID is assigned first, the secret being bound to it
+	uid := uuid.New()
+	encrypted, err := s.crypto.EncryptWithAD(secret, crypto.AssociatedData(uid))
+	if err != nil {
+		return nil, err
+	}
+
+	model := &models.Agent{
+		UUID:                   uid,
+		Name:                   strings.TrimSpace(request.Name),
+		Type:                   request.Type,
+		Address:                strings.TrimSpace(request.Address),
+		EncrypetedToken:        encrypted,
+		CertificateFingerprint: fingerprint,
+	}
+
+	token, err := s.repository.Enroll(ctx, crypto.HashToken(request.Token), s.now(), model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.Enrollment{
+		Agent: &entities.Agent{
+			UUID:                   model.UUID,
+			Name:                   model.Name,
+			Type:                   model.Type,
+			Address:                model.Address,
+			Token:                  model.EncrypetedToken,
+			CertificateFingerprint: model.CertificateFingerprint,
+		},
+		Secret: secret,
+		Token:  token,
+	}, nil
+}
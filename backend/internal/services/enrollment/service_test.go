@@ -0,0 +1,227 @@
+package enrollment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *crypto.CryptoService, *database.Database) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	if err := gdb.AutoMigrate(&models.Agent{}, &models.AgentEnrollmentToken{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	cs, err := crypto.NewKeyring(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+
+	db := &database.Database{DB: gdb}
+	return NewWithConfig(db, cs, Config{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}), cs, db
+}
+
+func enrollRequest(token, name string) *schemas.AgentEnrollRequest {
+	return &schemas.AgentEnrollRequest{
+		Token:   token,
+		Name:    name,
+		Type:    entities.ClientQBittorrent,
+		Address: "https://" + name + ":3100",
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	service, _, db := setupTestService(t)
+	ctx := context.Background()
+
+	token, err := service.CreateToken(ctx, uuid.New(), " seedbox-1 ", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(token.Token, tokenPrefix) {
+		t.Errorf("Expected token prefixed with %s, got %s", tokenPrefix, token.Token)
+	}
+	if token.Name != "seedbox-1" {
+		t.Errorf("Expected name seedbox-1, got %q", token.Name)
+	}
+	if lifetime := time.Until(token.ExpiresAt); lifetime < 59*time.Minute || lifetime > time.Hour {
+		t.Errorf("Expected the default lifetime, got %s", lifetime)
+	}
+
+	var stored models.AgentEnrollmentToken
+	if err := db.DB.First(&stored, "id = ?", token.ID).Error; err != nil {
+		t.Fatalf("Expected the token to be stored, got %v", err)
+	}
+	if stored.TokenHash != crypto.HashToken(token.Token) {
+		t.Errorf("Expected the token to be stored hashed")
+	}
+
+	for _, ttl := range []time.Duration{-time.Minute, 48 * time.Hour} {
+		if _, err := service.CreateToken(ctx, uuid.Nil, "", ttl); !errors.Is(err, errors.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for lifetime %s, got %v", ttl, err)
+		}
+	}
+}
+
+func TestEnroll(t *testing.T) {
+	service, cs, db := setupTestService(t)
+	ctx := context.Background()
+
+	token, err := service.CreateToken(ctx, uuid.Nil, "", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	request := enrollRequest(token.Token, "seedbox-1")
+	request.CertificateFingerprint = strings.ToUpper(strings.Repeat("ab:", 31) + "ab")
+
+	result, err := service.Enroll(ctx, request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Secret == "" {
+		t.Fatal("Expected a generated secret")
+	}
+	if result.Agent.CertificateFingerprint != strings.Repeat("ab", 32) {
+		t.Errorf("Expected normalized fingerprint, got %s", result.Agent.CertificateFingerprint)
+	}
+	if result.Token.UsedAt == nil || result.Token.AgentUUID == nil || *result.Token.AgentUUID != result.Agent.UUID {
+		t.Errorf("Expected the token to be marked as used by the agent, got %+v", result.Token)
+	}
+
+	var agent models.Agent
+	if err := db.DB.First(&agent, "uuid = ?", result.Agent.UUID).Error; err != nil {
+		t.Fatalf("Expected the agent to be stored, got %v", err)
+	}
+	secret, err := cs.DecryptWithAD(agent.EncrypetedToken, crypto.AssociatedData(agent.UUID))
+	if err != nil {
+		t.Fatalf("Expected the secret to be bound to the agent, got %v", err)
+	}
+	if secret != result.Secret {
+		t.Errorf("Expected the stored secret to be the one returned")
+	}
+
+	// Single use
+	if _, err := service.Enroll(ctx, enrollRequest(token.Token, "seedbox-2")); !errors.Is(err, errors.ErrEnrollmentTokenInvalid) {
+		t.Errorf("Expected ErrEnrollmentTokenInvalid when reused, got %v", err)
+	}
+}
+
+func TestEnroll_InvalidTokens(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	expired, err := service.CreateToken(ctx, uuid.Nil, "", time.Minute)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	service.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "Expired", token: expired.Token},
+		{name: "Unknown", token: tokenPrefix + "unknown"},
+		{name: "Other prefix", token: "gdt_" + strings.TrimPrefix(expired.Token, tokenPrefix)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Enroll(ctx, enrollRequest(tt.token, "seedbox-1")); !errors.Is(err, errors.ErrEnrollmentTokenInvalid) {
+				t.Errorf("Expected ErrEnrollmentTokenInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEnroll_TokenName(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	token, err := service.CreateToken(ctx, uuid.Nil, "paris-1", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, err := service.Enroll(ctx, enrollRequest(token.Token, "localhost"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Agent.Name != "paris-1" {
+		t.Errorf("Expected the name of the token, got %s", result.Agent.Name)
+	}
+}
+
+func TestEnroll_DuplicateNameKeepsToken(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	first, _ := service.CreateToken(ctx, uuid.Nil, "", 0)
+	if _, err := service.Enroll(ctx, enrollRequest(first.Token, "seedbox-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	second, _ := service.CreateToken(ctx, uuid.Nil, "", 0)
+	if _, err := service.Enroll(ctx, enrollRequest(second.Token, "seedbox-1")); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Fatalf("Expected ErrInvalidInput for a taken name, got %v", err)
+	}
+
+	// The failed enrollment did not use the token
+	if _, err := service.Enroll(ctx, enrollRequest(second.Token, "seedbox-2")); err != nil {
+		t.Errorf("Expected the token to stay usable, got %v", err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	unused, _ := service.CreateToken(ctx, uuid.Nil, "", 0)
+	used, _ := service.CreateToken(ctx, uuid.Nil, "", 0)
+	if _, err := service.Enroll(ctx, enrollRequest(used.Token, "seedbox-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := service.RevokeToken(ctx, unused.ID.String()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := service.Enroll(ctx, enrollRequest(unused.Token, "seedbox-2")); !errors.Is(err, errors.ErrEnrollmentTokenInvalid) {
+		t.Errorf("Expected a revoked token to be refused, got %v", err)
+	}
+
+	if err := service.RevokeToken(ctx, used.ID.String()); !errors.Is(err, errors.ErrEnrollmentTokenNotFound) {
+		t.Errorf("Expected ErrEnrollmentTokenNotFound for a used token, got %v", err)
+	}
+	if err := service.RevokeToken(ctx, "not-a-uuid"); !errors.Is(err, errors.ErrInvalidUUID) {
+		t.Errorf("Expected ErrInvalidUUID, got %v", err)
+	}
+
+	tokens, err := service.ListTokens(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != used.ID {
+		t.Errorf("Expected only the used token to be listed, got %d tokens", len(tokens))
+	}
+}
@@ -0,0 +1,239 @@
+package eventhub
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/events"
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is the number of events a slow SSE client may lag behind
+const subscriberBuffer = 512
+
+// Repository lists the agents and follows their event streams
+type Repository interface {
+	ListAgents() ([]*entities.Agent, error)
+	StreamAgentEvents(ctx context.Context, agent *entities.Agent, fn func(event *entities.Event) error) error
+}
+
+// Config holds the hub settings
+type Config struct {
+	Refresh    time.Duration // how often the agent list is reloaded
+	MinBackoff time.Duration // first delay before reconnecting to an agent
+	MaxBackoff time.Duration
+}
+
+// DefaultConfig returns the hub settings used by the service
+func DefaultConfig() Config {
+	return Config{
+		Refresh:    30 * time.Second,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// Hub follows the event stream of every agent, keeps the last known tasks and
+// instance of each one and re-publishes their events tagged with the agent
+type Hub struct {
+	repository Repository
+	config     Config
+	broker     *events.Broker
+
+	mu        sync.Mutex
+	streams   map[uuid.UUID]*stream
+	tasks     map[uuid.UUID]map[string]*entities.Task
+	instances map[uuid.UUID]*entities.Instance
+}
+
+// stream is a running follower of an agent event stream
+type stream struct {
+	address string
+	token   string
+	cancel  context.CancelFunc
+}
+
+// NewHub creates an event hub. Unset durations take their default, the refresh
+// ticker panicking on a zero one.
+func NewHub(repository Repository, config Config) *Hub {
+	defaults := DefaultConfig()
+	if config.Refresh <= 0 {
+		config.Refresh = defaults.Refresh
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaults.MinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = max(defaults.MaxBackoff, config.MinBackoff)
+	}
+
+	return &Hub{
+		repository: repository,
+		config:     config,
+		broker:     events.NewBroker(subscriberBuffer),
+		streams:    make(map[uuid.UUID]*stream),
+		tasks:      make(map[uuid.UUID]map[string]*entities.Task),
+		instances:  make(map[uuid.UUID]*entities.Instance),
+	}
+}
+
+// Run follows the agents until ctx is canceled, picking up added, updated and removed agents
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.config.Refresh)
+	defer ticker.Stop()
+
+	for {
+		h.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Subscribe returns a snapshot event per connected agent and the subscription to the following events
+func (h *Hub) Subscribe() ([]*entities.Event, *events.Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	initial := make([]*entities.Event, 0, len(h.tasks))
+	for uid, tasks := range h.tasks {
+		snapshot := &entities.Event{
+			Type:      entities.EventSnapshot,
+			AgentUUID: uid,
+			Tasks:     make([]*entities.Task, 0, len(tasks)),
+			Instance:  h.instances[uid],
+		}
+		for _, task := range tasks {
+			snapshot.Tasks = append(snapshot.Tasks, task)
+		}
+		initial = append(initial, snapshot)
+	}
+
+	return initial, h.broker.Subscribe()
+}
+
+func (h *Hub) refresh(ctx context.Context) {
+	agents, err := h.repository.ListAgents()
+	if err != nil {
+		log.Printf("events: failed to list agents: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	known := make(map[uuid.UUID]struct{}, len(agents))
+	for _, agent := range agents {
+		known[agent.UUID] = struct{}{}
+
+		if current, ok := h.streams[agent.UUID]; ok {
+			if current.address == agent.Address && current.token == agent.Token {
+				continue
+			}
+			// the agent was updated, reconnect with the new settings
+			current.cancel()
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		h.streams[agent.UUID] = &stream{address: agent.Address, token: agent.Token, cancel: cancel}
+		go h.follow(streamCtx, agent)
+	}
+
+	for uid, current := range h.streams {
+		if _, ok := known[uid]; !ok {
+			current.cancel()
+			delete(h.streams, uid)
+			h.forget(uid)
+		}
+	}
+}
+
+// follow streams the events of an agent, reconnecting with exponential backoff
+func (h *Hub) follow(ctx context.Context, agent *entities.Agent) {
+	backoff := h.config.MinBackoff
+
+	for {
+		err := h.repository.StreamAgentEvents(ctx, agent, func(event *entities.Event) error {
+			backoff = h.config.MinBackoff
+			h.apply(ctx, agent, event)
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("events: lost event stream of agent %s: %v", agent.Name, err)
+
+		h.mu.Lock()
+		h.forget(agent.UUID)
+		h.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > h.config.MaxBackoff {
+			backoff = h.config.MaxBackoff
+		}
+	}
+}
+
+// apply records an agent event in the state tables and publishes it
+func (h *Hub) apply(ctx context.Context, agent *entities.Agent, event *entities.Event) {
+	event.AgentUUID = agent.UUID
+	if event.Task != nil {
+		event.Task.Agent = agent
+	}
+	for _, task := range event.Tasks {
+		task.Agent = agent
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the stream was stopped (agent removed or updated) while the event was decoded
+	if ctx.Err() != nil {
+		return
+	}
+
+	switch event.Type {
+	case entities.EventSnapshot:
+		tasks := make(map[string]*entities.Task, len(event.Tasks))
+		for _, task := range event.Tasks {
+			tasks[task.ID] = task
+		}
+		h.tasks[agent.UUID] = tasks
+		h.instances[agent.UUID] = event.Instance
+	case entities.EventTaskAdded, entities.EventTaskUpdated:
+		if tasks, ok := h.tasks[agent.UUID]; ok && event.Task != nil {
+			tasks[event.Task.ID] = event.Task
+		}
+	case entities.EventTaskRemoved:
+		delete(h.tasks[agent.UUID], event.TaskID)
+	case entities.EventInstanceUpdated:
+		h.instances[agent.UUID] = event.Instance
+	}
+
+	h.broker.Publish(event)
+}
+
+// forget drops the state of a disconnected agent. The caller must hold the lock.
+func (h *Hub) forget(uid uuid.UUID) {
+	if _, ok := h.tasks[uid]; !ok {
+		return
+	}
+
+	delete(h.tasks, uid)
+	delete(h.instances, uid)
+
+	h.broker.Publish(&entities.Event{Type: entities.EventAgentDisconnected, AgentUUID: uid})
+}
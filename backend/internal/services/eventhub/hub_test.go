@@ -0,0 +1,123 @@
+package eventhub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/google/uuid"
+)
+
+// fakeRepository feeds each agent stream from a channel of scripted events
+type fakeRepository struct {
+	mu      sync.Mutex
+	agents  []*entities.Agent
+	streams map[uuid.UUID]chan *entities.Event
+}
+
+func (r *fakeRepository) ListAgents() ([]*entities.Agent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.agents, nil
+}
+
+func (r *fakeRepository) StreamAgentEvents(ctx context.Context, agent *entities.Agent, fn func(event *entities.Event) error) error {
+	r.mu.Lock()
+	events := r.streams[agent.UUID]
+	r.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("connection reset")
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func receive(t *testing.T, c <-chan *entities.Event) *entities.Event {
+	t.Helper()
+
+	select {
+	case event := <-c:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event")
+		return nil
+	}
+}
+
+func TestHub_TracksAgentState(t *testing.T) {
+	agent := &entities.Agent{UUID: uuid.New(), Name: "seedbox", Address: "http://seedbox:8080", Token: "secret"}
+	stream := make(chan *entities.Event)
+
+	repo := &fakeRepository{
+		agents:  []*entities.Agent{agent},
+		streams: map[uuid.UUID]chan *entities.Event{agent.UUID: stream},
+	}
+
+	hub := NewHub(repo, Config{Refresh: time.Minute, MinBackoff: time.Minute, MaxBackoff: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, sub := hub.Subscribe()
+	defer sub.Close()
+
+	go hub.Run(ctx)
+
+	stream <- &entities.Event{
+		Type:     entities.EventSnapshot,
+		Tasks:    []*entities.Task{{ID: "a", Name: "Movie"}, {ID: "b", Name: "Show"}},
+		Instance: &entities.Instance{},
+	}
+	if event := receive(t, sub.C); event.AgentUUID != agent.UUID || event.Tasks[0].Agent != agent {
+		t.Errorf("Expected snapshot tagged with agent %s, got %+v", agent.UUID, event)
+	}
+
+	stream <- &entities.Event{Type: entities.EventTaskUpdated, Task: &entities.Task{ID: "a", Name: "Movie (2024)"}}
+	receive(t, sub.C)
+
+	stream <- &entities.Event{Type: entities.EventTaskRemoved, TaskID: "b"}
+	receive(t, sub.C)
+
+	initial, late := hub.Subscribe()
+	late.Close()
+
+	if len(initial) != 1 || len(initial[0].Tasks) != 1 {
+		t.Fatalf("Expected snapshot of 1 agent with 1 task, got %+v", initial)
+	}
+	if task := initial[0].Tasks[0]; task.ID != "a" || task.Name != "Movie (2024)" {
+		t.Errorf("Expected updated task a, got %+v", task)
+	}
+
+	close(stream)
+
+	if event := receive(t, sub.C); event.Type != entities.EventAgentDisconnected || event.AgentUUID != agent.UUID {
+		t.Errorf("Expected %s event, got %+v", entities.EventAgentDisconnected, event)
+	}
+
+	initial, late = hub.Subscribe()
+	late.Close()
+
+	if len(initial) != 0 {
+		t.Errorf("Expected no snapshot after disconnect, got %d", len(initial))
+	}
+}
+
+func TestNewHub_DefaultsUnsetDurations(t *testing.T) {
+	hub := NewHub(nil, Config{})
+
+	if hub.config != DefaultConfig() {
+		t.Errorf("Expected the default config, got %+v", hub.config)
+	}
+}
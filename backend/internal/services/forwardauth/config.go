@@ -0,0 +1,54 @@
+package forwardauth
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/provisioning"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the forward authentication settings, Gardarr being behind an
+// authenticating proxy (Authelia, Authentik, oauth2-proxy)
+type Config struct {
+	Enabled bool
+	// TrustedProxies lists the addresses and CIDRs allowed to send the user
+	// headers. The headers of any other client are ignored.
+	TrustedProxies []string
+
+	UserHeader      string // user name at the proxy, the subject the user is linked to
+	EmailHeader     string
+	GroupsHeader    string
+	GroupsSeparator string
+
+	// RoleMapping maps the groups to roles. When set, the role is synced on
+	// each request: the highest role mapped, or else DefaultRole.
+	RoleMapping map[string]string // group -> role
+	// DefaultRole is given to the users no group maps. Empty refuses them.
+	DefaultRole string
+
+	AutoProvision bool // creates the users unknown to Gardarr
+	LinkByEmail   bool // links a first login to the local user with the same verified email
+	// EmailVerified tells that the proxy only sends emails its users proved
+	// they own. Otherwise the emails are unverified and never linked.
+	EmailVerified bool
+	// LogoutURL is where the browser is sent on logout, ending the session at
+	// the proxy. Otherwise the proxy logs the user in again at once.
+	LogoutURL string
+}
+
+// LoadConfigFromEnv loads the forward authentication configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		Enabled:         env.Get("AUTH_FORWARD_ENABLED").Default("false").ValueBool(),
+		TrustedProxies:  env.Get("AUTH_FORWARD_TRUSTED_PROXIES").ValueList(),
+		UserHeader:      env.Get("AUTH_FORWARD_USER_HEADER").Default("Remote-User").Value(),
+		EmailHeader:     env.Get("AUTH_FORWARD_EMAIL_HEADER").Default("Remote-Email").Value(),
+		GroupsHeader:    env.Get("AUTH_FORWARD_GROUPS_HEADER").Default("Remote-Groups").Value(),
+		GroupsSeparator: env.Get("AUTH_FORWARD_GROUPS_SEPARATOR").Default(",").Value(),
+		RoleMapping:     provisioning.ParseRoleMapping(env.Get("AUTH_FORWARD_ROLE_MAPPING").ValueList()),
+		DefaultRole:     env.Get("AUTH_FORWARD_DEFAULT_ROLE").Default(entities.RoleViewer).Value(),
+		AutoProvision:   env.Get("AUTH_FORWARD_AUTO_PROVISION").Default("true").ValueBool(),
+		LinkByEmail:     env.Get("AUTH_FORWARD_LINK_BY_EMAIL").Default("false").ValueBool(),
+		EmailVerified:   env.Get("AUTH_FORWARD_EMAIL_VERIFIED").Default("false").ValueBool(),
+		LogoutURL:       env.Get("AUTH_FORWARD_LOGOUT_URL").Value(),
+	}
+}
@@ -0,0 +1,131 @@
+package forwardauth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/services/provisioning"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gardarr/gardarr/pkg/netaddr"
+)
+
+// Issuer identifies the forward authentication proxy in the user identities
+const Issuer = "forward-auth"
+
+// Service authenticates the requests by the user headers of a trusted
+// authenticating proxy. Users are provisioned on their first request and
+// linked to their user name at the proxy, see provisioning.Service.
+type Service struct {
+	config       Config
+	proxies      []netip.Prefix
+	provisioning *provisioning.Service
+}
+
+func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service trusting the user headers of the configured proxies only
+func NewWithConfig(db *database.Database, config Config) *Service {
+	s := &Service{
+		config:       config,
+		proxies:      parsePrefixes(config.TrustedProxies),
+		provisioning: provisioning.NewService(db),
+	}
+
+	if config.Enabled && len(s.proxies) == 0 {
+		log.Println("forwardauth: AUTH_FORWARD_ENABLED ignored, AUTH_FORWARD_TRUSTED_PROXIES is empty")
+	}
+
+	return s
+}
+
+// Enabled tells whether forward authentication is configured
+func (s *Service) Enabled() bool {
+	return s.config.Enabled && len(s.proxies) > 0
+}
+
+// LogoutURL returns where the browser is sent to log out from the proxy
+func (s *Service) LogoutURL() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return s.config.LogoutURL
+}
+
+// Trusted tells whether a request comes straight from a trusted proxy and
+// names a user. The connection address is checked, the forwarded ones being
+// chosen by the client.
+func (s *Service) Trusted(r *http.Request) bool {
+	if !s.Enabled() || s.subject(r.Header) == "" {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	return netaddr.Contains(s.proxies, addr)
+}
+
+// Authenticate returns the user named by the headers of a trusted request
+func (s *Service) Authenticate(ctx context.Context, header http.Header) (*entities.User, error) {
+	subject := s.subject(header)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no %s header", pkgerrors.ErrSSOFailed, s.config.UserHeader)
+	}
+
+	email := strings.TrimSpace(header.Get(s.config.EmailHeader))
+	if email == "" && strings.Contains(subject, "@") {
+		// oauth2-proxy may only send the email as user name
+		email = subject
+	}
+
+	var groups []string
+	if s.config.GroupsHeader != "" {
+		for _, group := range strings.Split(header.Get(s.config.GroupsHeader), s.config.GroupsSeparator) {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return s.provisioning.Resolve(ctx, provisioning.Identity{
+		Issuer:  Issuer,
+		Subject: subject,
+		Email:   email,
+		// A header email is only trusted when the operator vouches for the proxy
+		EmailVerified: s.config.EmailVerified,
+		Role:          provisioning.MapRole(groups, s.config.RoleMapping, s.config.DefaultRole),
+		ManagedRole:   len(s.config.RoleMapping) > 0,
+	}, provisioning.Policy{AutoProvision: s.config.AutoProvision, LinkByEmail: s.config.LinkByEmail})
+}
+
+// subject returns the user name sent by the proxy, or else its email
+func (s *Service) subject(header http.Header) string {
+	if subject := strings.TrimSpace(header.Get(s.config.UserHeader)); subject != "" {
+		return subject
+	}
+	return strings.TrimSpace(header.Get(s.config.EmailHeader))
+}
+
+// parsePrefixes parses addresses and CIDRs, logging and skipping the invalid ones
+func parsePrefixes(values []string) []netip.Prefix {
+	prefixes, invalid := netaddr.ParsePrefixes(values)
+	for _, value := range invalid {
+		log.Printf("forwardauth: ignoring invalid trusted proxy %q", value)
+	}
+	return prefixes
+}
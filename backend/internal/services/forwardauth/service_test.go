@@ -0,0 +1,229 @@
+package forwardauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T, configure func(*Config)) (*Service, *database.Database) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.User{}, &models.UserIdentity{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	// The first user becomes admin, the tests provision the next ones
+	admin := &models.User{Username: "admin@example.com", Email: "admin@example.com", PasswordHash: "hash", Salt: "salt", Role: entities.RoleAdmin}
+	if err := gdb.Create(admin).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	config := Config{
+		Enabled:         true,
+		TrustedProxies:  []string{"10.0.0.0/8", "192.0.2.1"},
+		UserHeader:      "Remote-User",
+		EmailHeader:     "Remote-Email",
+		GroupsHeader:    "Remote-Groups",
+		GroupsSeparator: ",",
+		DefaultRole:     entities.RoleViewer,
+		AutoProvision:   true,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	return NewWithConfig(db, config), db
+}
+
+func TestTrusted(t *testing.T) {
+	service, _ := setupTestService(t, nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		user       string
+		forwarded  string
+		expected   bool
+	}{
+		{"proxy in a trusted network", "10.1.2.3:4567", "alice", "", true},
+		{"trusted proxy address", "192.0.2.1:4567", "alice", "", true},
+		{"untrusted client", "192.0.2.2:4567", "alice", "", false},
+		{"untrusted client spoofing the proxy", "192.0.2.2:4567", "alice", "10.1.2.3", false},
+		{"trusted proxy without user", "10.1.2.3:4567", "", "", false},
+		{"IPv4-mapped proxy address", "[::ffff:10.1.2.3]:4567", "alice", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.user != "" {
+				req.Header.Set("Remote-User", tt.user)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if got := service.Trusted(req); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrusted_Disabled(t *testing.T) {
+	service, _ := setupTestService(t, func(c *Config) { c.Enabled = false })
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("Remote-User", "alice")
+
+	if service.Trusted(req) {
+		t.Error("Expected the headers to be ignored when forward authentication is disabled")
+	}
+}
+
+func TestAuthenticate_ProvisionsUser(t *testing.T) {
+	service, db := setupTestService(t, nil)
+	ctx := context.Background()
+
+	header := http.Header{}
+	header.Set("Remote-User", "alice")
+	header.Set("Remote-Email", "alice@example.com")
+
+	u, err := service.Authenticate(ctx, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Email != "alice@example.com" || u.Role != entities.RoleViewer {
+		t.Errorf("Expected viewer alice@example.com, got %s %s", u.Role, u.Email)
+	}
+
+	// The user is found by its name at the proxy, whatever its email
+	header.Set("Remote-Email", "alice@new.example.com")
+	again, err := service.Authenticate(ctx, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.UUID != u.UUID {
+		t.Errorf("Expected user %s, got %s", u.UUID, again.UUID)
+	}
+
+	var identity models.UserIdentity
+	if err := db.DB.Where("issuer = ? AND subject = ?", Issuer, "alice").First(&identity).Error; err != nil {
+		t.Fatalf("Expected the identity to be stored: %v", err)
+	}
+}
+
+func TestAuthenticate_EmailAsUser(t *testing.T) {
+	service, _ := setupTestService(t, func(c *Config) { c.EmailHeader = "X-Forwarded-Email" })
+
+	header := http.Header{}
+	header.Set("Remote-User", "bob@example.com")
+
+	u, err := service.Authenticate(context.Background(), header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Email != "bob@example.com" {
+		t.Errorf("Expected email bob@example.com, got %s", u.Email)
+	}
+}
+
+func TestAuthenticate_MapsGroups(t *testing.T) {
+	service, _ := setupTestService(t, func(c *Config) {
+		c.GroupsSeparator = "|"
+		c.RoleMapping = map[string]string{"gardarr-admins": entities.RoleAdmin, "gardarr-ops": entities.RoleOperator}
+		c.DefaultRole = ""
+	})
+	ctx := context.Background()
+
+	header := http.Header{}
+	header.Set("Remote-User", "carol")
+	header.Set("Remote-Email", "carol@example.com")
+	header.Set("Remote-Groups", "staff| gardarr-ops")
+
+	u, err := service.Authenticate(ctx, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleOperator {
+		t.Errorf("Expected role %s, got %s", entities.RoleOperator, u.Role)
+	}
+
+	// Leaving the mapped groups removes the access
+	header.Set("Remote-Groups", "staff")
+	if _, err := service.Authenticate(ctx, header); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+}
+
+func TestAuthenticate_AutoProvisionOff(t *testing.T) {
+	service, _ := setupTestService(t, func(c *Config) {
+		c.AutoProvision = false
+		c.LinkByEmail = true
+		c.EmailVerified = true
+	})
+	ctx := context.Background()
+
+	header := http.Header{}
+	header.Set("Remote-User", "dave")
+	header.Set("Remote-Email", "dave@example.com")
+	if _, err := service.Authenticate(ctx, header); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+
+	// Existing users are still linked by their verified email
+	header.Set("Remote-User", "admin")
+	header.Set("Remote-Email", "admin@example.com")
+	u, err := service.Authenticate(ctx, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleAdmin {
+		t.Errorf("Expected role %s, got %s", entities.RoleAdmin, u.Role)
+	}
+}
+
+func TestAuthenticate_DoesNotLinkByEmailByDefault(t *testing.T) {
+	defaults := LoadConfigFromEnv()
+	service, db := setupTestService(t, func(c *Config) {
+		c.LinkByEmail = defaults.LinkByEmail
+		c.EmailVerified = defaults.EmailVerified
+	})
+	ctx := context.Background()
+
+	// Anyone the proxy lets set their email could claim the admin account
+	header := http.Header{}
+	header.Set("Remote-User", "mallory")
+	header.Set("Remote-Email", "admin@example.com")
+	if _, err := service.Authenticate(ctx, header); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+
+	// Linking still needs the operator to vouch for the emails of the proxy
+	service.config.LinkByEmail = true
+	if _, err := service.Authenticate(ctx, header); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied for an unverified email, got %v", err)
+	}
+
+	var count int64
+	if err := db.DB.Model(&models.UserIdentity{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count identities: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no identity linked, got %d", count)
+	}
+}
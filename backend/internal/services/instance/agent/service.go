@@ -14,9 +14,14 @@ func New() (interfaces.InstanceService, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewWithRepository(r), nil
+}
+
+// NewWithRepository creates the instance service on top of an existing repository
+func NewWithRepository(r repository.RepositoryInterface) interfaces.InstanceService {
 	return &service{
 		repository: r,
-	}, nil
+	}
 }
 
 type service struct {
@@ -0,0 +1,54 @@
+package oidc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/services/provisioning"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the OpenID Connect single sign-on settings
+type Config struct {
+	Enabled      bool
+	ProviderName string // shown on the login button
+	IssuerURL    string // discovery is read from IssuerURL + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string // empty for a public client, PKCE still protects the code
+	RedirectURL  string // external URL of the callback, ending with /v1/auth/oidc/callback
+	Scopes       []string
+	EmailClaim   string
+
+	// RoleClaim names the claim holding the groups or roles of the user, a
+	// string or a list. When set the role is synced on each login: the highest
+	// role mapped by RoleMapping, or else DefaultRole.
+	RoleClaim   string
+	RoleMapping map[string]string // claim value -> role
+	// DefaultRole is given to the users no claim value maps. Empty refuses them.
+	DefaultRole string
+
+	AutoProvision bool // creates the users logging in for the first time
+	LinkByEmail   bool // links a first login to the local user with the same verified email
+	StateTTL      time.Duration
+}
+
+// LoadConfigFromEnv loads the single sign-on configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		Enabled:       env.Get("OIDC_ENABLED").Default("false").ValueBool(),
+		ProviderName:  env.Get("OIDC_PROVIDER_NAME").Default("SSO").Value(),
+		IssuerURL:     strings.TrimSuffix(env.Get("OIDC_ISSUER_URL").Value(), "/"),
+		ClientID:      env.Get("OIDC_CLIENT_ID").Value(),
+		ClientSecret:  env.Get("OIDC_CLIENT_SECRET").Value(),
+		RedirectURL:   env.Get("OIDC_REDIRECT_URL").Value(),
+		Scopes:        env.Get("OIDC_SCOPES").Default("openid,email,profile").ValueList(),
+		EmailClaim:    env.Get("OIDC_EMAIL_CLAIM").Default("email").Value(),
+		RoleClaim:     env.Get("OIDC_ROLE_CLAIM").Value(),
+		RoleMapping:   provisioning.ParseRoleMapping(env.Get("OIDC_ROLE_MAPPING").ValueList()),
+		DefaultRole:   env.Get("OIDC_DEFAULT_ROLE").Default(entities.RoleViewer).Value(),
+		AutoProvision: env.Get("OIDC_AUTO_PROVISION").Default("true").ValueBool(),
+		LinkByEmail:   env.Get("OIDC_LINK_BY_EMAIL").Default("true").ValueBool(),
+		StateTTL:      env.Get("OIDC_STATE_TTL").Default("10m").ValueDuration(),
+	}
+}
@@ -0,0 +1,215 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/oidc"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/internal/services/provisioning"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+)
+
+// randomLength is the size of the state, nonce and PKCE verifier
+const randomLength = 32 // 32 bytes = 256 bits
+
+// Service logs users in through an OpenID Connect identity provider with the
+// authorization code flow and PKCE. Users are provisioned on their first login
+// and linked to their subject at the provider, see provisioning.Service.
+type Service struct {
+	repository   *oidc.Repository
+	provisioning *provisioning.Service
+	provider     *provider
+	config       Config
+	now          func() time.Time
+}
+
+func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service for the provider, client and role mapping of config
+func NewWithConfig(db *database.Database, config Config) *Service {
+	s := &Service{
+		repository:   oidc.NewRepository(db),
+		provisioning: provisioning.NewService(db),
+		config:       config,
+		now:          time.Now,
+	}
+	s.provider = newProvider(config.IssuerURL, func() time.Time { return s.now() })
+	return s
+}
+
+// Enabled tells whether single sign-on is configured
+func (s *Service) Enabled() bool {
+	return s.config.Enabled && s.config.IssuerURL != "" && s.config.ClientID != "" && s.config.RedirectURL != ""
+}
+
+// ProviderName returns the name of the identity provider shown to users
+func (s *Service) ProviderName() string {
+	return s.config.ProviderName
+}
+
+// StateTTL returns the time left to a user to log in at the identity provider
+func (s *Service) StateTTL() time.Duration {
+	return s.config.StateTTL
+}
+
+// Begin starts a single sign-on. The browser is to be sent to the returned
+// URL, redirectPath being where it lands once logged in.
+func (s *Service) Begin(ctx context.Context, redirectPath string) (*entities.OIDCLogin, error) {
+	if !s.Enabled() {
+		return nil, pkgerrors.ErrSSODisabled
+	}
+
+	metadata, err := s.provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := crypto.GenerateToken("", randomLength)
+	if err != nil {
+		return nil, errors.New("failed to generate state")
+	}
+	nonce, err := crypto.GenerateToken("", randomLength)
+	if err != nil {
+		return nil, errors.New("failed to generate nonce")
+	}
+	verifier, err := crypto.GenerateToken("", randomLength)
+	if err != nil {
+		return nil, errors.New("failed to generate code verifier")
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.config.StateTTL)
+
+	// Flows never completed are dropped as new ones start
+	if err := s.repository.DeleteExpiredStates(ctx, now); err != nil {
+		log.Printf("oidc: failed to remove expired login states: %v", err)
+	}
+
+	if err := s.repository.CreateState(ctx, &models.OIDCLoginState{
+		StateHash:    crypto.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectPath: SafeRedirectPath(redirectPath),
+		ExpiresAt:    expiresAt,
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.config.ClientID},
+		"redirect_uri":          {s.config.RedirectURL},
+		"scope":                 {strings.Join(s.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authURL := metadata.AuthorizationEndpoint
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + query.Encode()
+	} else {
+		authURL += "?" + query.Encode()
+	}
+
+	return &entities.OIDCLogin{URL: authURL, State: state, ExpiresAt: expiresAt}, nil
+}
+
+// Complete ends a single sign-on with the code the identity provider sent the
+// browser back with. It returns the user to create a session for and the path
+// to send the browser to.
+func (s *Service) Complete(ctx context.Context, state, code string) (*entities.User, string, error) {
+	if !s.Enabled() {
+		return nil, "", pkgerrors.ErrSSODisabled
+	}
+
+	login, err := s.repository.TakeState(ctx, crypto.HashToken(state))
+	if err != nil {
+		return nil, "", err
+	}
+	if login == nil || s.now().After(login.ExpiresAt) {
+		return nil, "", fmt.Errorf("%w: unknown or expired login state", pkgerrors.ErrSSOFailed)
+	}
+
+	tokens, err := s.provider.exchange(ctx, s.config, code, login.CodeVerifier)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", pkgerrors.ErrSSOFailed, err)
+	}
+
+	token, err := s.verifyIDToken(ctx, tokens.IDToken, login.Nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", pkgerrors.ErrSSOFailed, err)
+	}
+
+	role, managed := s.mapRole(token.Claims)
+	u, err := s.provisioning.Resolve(ctx, provisioning.Identity{
+		Issuer:        token.Issuer,
+		Subject:       token.Subject,
+		Email:         token.Email,
+		EmailVerified: token.EmailVerified,
+		Role:          role,
+		ManagedRole:   managed,
+	}, provisioning.Policy{AutoProvision: s.config.AutoProvision, LinkByEmail: s.config.LinkByEmail})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return u, login.RedirectPath, nil
+}
+
+// mapRole returns the role the claims map to, or else the default role. It
+// tells whether roles are managed by the identity provider, a role claim
+// being configured.
+func (s *Service) mapRole(claims map[string]any) (string, bool) {
+	if s.config.RoleClaim == "" {
+		return s.config.DefaultRole, false
+	}
+
+	var values []string
+	switch value := claims[s.config.RoleClaim].(type) {
+	case string:
+		values = []string{value}
+	case []any:
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+	}
+
+	return provisioning.MapRole(values, s.config.RoleMapping, s.config.DefaultRole), true
+}
+
+// scopes returns the requested scopes, openid being required
+func (s *Service) scopes() []string {
+	if slices.Contains(s.config.Scopes, "openid") {
+		return s.config.Scopes
+	}
+	return append([]string{"openid"}, s.config.Scopes...)
+}
+
+// SafeRedirectPath keeps a redirect within the manager: a path relative to its
+// root, "/" otherwise
+func SafeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsAny(path, "\\\r\n") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,525 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	testClientID     = "gardarr"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "https://gardarr.example.com/v1/auth/oidc/callback"
+)
+
+// mockProvider is an in-process OpenID Connect identity provider. The user
+// "logs in" through authorize, which returns the code the browser would be
+// sent back with.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+
+	// Token alterations, to check the verification
+	alg    string          // signing algorithm written in the header
+	kid    string          // key ID written in the header
+	signer *rsa.PrivateKey // signs instead of key when set
+}
+
+type pendingCode struct {
+	challenge string
+	claims    map[string]any
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m := &mockProvider{t: t, key: key, kid: "key-1", alg: "RS256", codes: make(map[string]pendingCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize checks the authorization request and returns a code for a user
+// with the given claims
+func (m *mockProvider) authorize(authURL string, claims map[string]any) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+
+	if query.Get("client_id") != testClientID || query.Get("redirect_uri") != testRedirectURL {
+		m.t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		m.t.Fatalf("expected the code flow with PKCE, got %s", authURL)
+	}
+	if !strings.Contains(query.Get("scope"), "openid") {
+		m.t.Fatalf("expected the openid scope, got %q", query.Get("scope"))
+	}
+
+	all := map[string]any{
+		"iss":   m.server.URL,
+		"aud":   testClientID,
+		"nonce": query.Get("nonce"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	code := rand.Text()
+	m.mu.Lock()
+	m.codes[code] = pendingCode{challenge: query.Get("code_challenge"), claims: all}
+	m.mu.Unlock()
+
+	return code
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if id, secret, ok := r.BasicAuth(); !ok || id != testClientID || secret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	pending, ok := m.codes[r.Form.Get("code")]
+	delete(m.codes, r.Form.Get("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     m.sign(pending.claims),
+	})
+}
+
+// sign creates an ID token
+func (m *mockProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": m.alg, "kid": m.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signer := m.key
+	if m.signer != nil {
+		signer = m.signer
+	}
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, digest[:])
+	if err != nil {
+		m.t.Fatalf("failed to sign: %v", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func setupTestService(t *testing.T, configure func(*Config)) (*Service, *mockProvider, *database.Database) {
+	mock := newMockProvider(t)
+
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.User{}, &models.OIDCLoginState{}, &models.UserIdentity{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	config := Config{
+		Enabled:       true,
+		ProviderName:  "Mock",
+		IssuerURL:     mock.server.URL,
+		ClientID:      testClientID,
+		ClientSecret:  testClientSecret,
+		RedirectURL:   testRedirectURL,
+		Scopes:        []string{"openid", "email"},
+		EmailClaim:    "email",
+		DefaultRole:   entities.RoleViewer,
+		AutoProvision: true,
+		LinkByEmail:   true,
+		StateTTL:      10 * time.Minute,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	return NewWithConfig(db, config), mock, db
+}
+
+// login runs a whole single sign-on of a user with the given claims
+func login(t *testing.T, service *Service, mock *mockProvider, claims map[string]any) (*entities.User, string, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	begun, err := service.Begin(ctx, "/tasks")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	code := mock.authorize(begun.URL, claims)
+	return service.Complete(ctx, begun.State, code)
+}
+
+func createLocalUser(t *testing.T, db *database.Database, email, role string) *models.User {
+	model := &models.User{Username: email, Email: email, PasswordHash: "hash", Salt: "salt", Role: role}
+	if err := db.DB.Create(model).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return model
+}
+
+func TestComplete_ProvisionsUser(t *testing.T) {
+	service, mock, db := setupTestService(t, nil)
+	createLocalUser(t, db, "admin@example.com", entities.RoleAdmin)
+
+	u, redirectPath, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "Alice@Example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Email != "alice@example.com" {
+		t.Errorf("Expected email alice@example.com, got %s", u.Email)
+	}
+	if u.Role != entities.RoleViewer {
+		t.Errorf("Expected role %s, got %s", entities.RoleViewer, u.Role)
+	}
+	if redirectPath != "/tasks" {
+		t.Errorf("Expected redirect path /tasks, got %s", redirectPath)
+	}
+
+	// The next login finds the user by its subject, whatever its email
+	again, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@new.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.UUID != u.UUID {
+		t.Errorf("Expected user %s, got %s", u.UUID, again.UUID)
+	}
+
+	var count int64
+	db.DB.Model(&models.UserIdentity{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 identity, got %d", count)
+	}
+}
+
+func TestComplete_FirstUserBecomesAdmin(t *testing.T) {
+	service, mock, _ := setupTestService(t, nil)
+
+	u, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleAdmin {
+		t.Errorf("Expected role %s, got %s", entities.RoleAdmin, u.Role)
+	}
+}
+
+func TestComplete_AutoProvisionOff(t *testing.T) {
+	service, mock, _ := setupTestService(t, func(c *Config) { c.AutoProvision = false })
+
+	_, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com"})
+	if !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+}
+
+func TestComplete_LinksByVerifiedEmail(t *testing.T) {
+	service, mock, db := setupTestService(t, nil)
+	local := createLocalUser(t, db, "bob@example.com", entities.RoleOperator)
+
+	// An unverified email could be claimed by anyone at the provider
+	if _, _, err := login(t, service, mock, map[string]any{"sub": "bob", "email": "bob@example.com", "email_verified": false}); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+
+	u, _, err := login(t, service, mock, map[string]any{"sub": "bob", "email": "bob@example.com", "email_verified": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.UUID != local.UUID {
+		t.Errorf("Expected user %s, got %s", local.UUID, u.UUID)
+	}
+	if u.Role != entities.RoleOperator {
+		t.Errorf("Expected role %s to be kept, got %s", entities.RoleOperator, u.Role)
+	}
+}
+
+func TestComplete_LinkByEmailOff(t *testing.T) {
+	service, mock, db := setupTestService(t, func(c *Config) { c.LinkByEmail = false })
+	createLocalUser(t, db, "bob@example.com", entities.RoleOperator)
+
+	_, _, err := login(t, service, mock, map[string]any{"sub": "bob", "email": "bob@example.com", "email_verified": true})
+	if !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+}
+
+func TestComplete_DisabledUser(t *testing.T) {
+	service, mock, db := setupTestService(t, nil)
+	createLocalUser(t, db, "admin@example.com", entities.RoleAdmin)
+
+	u, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.DB.Model(&models.User{}).Where("uuid = ?", u.UUID).Update("disabled", true)
+
+	if _, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com"}); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+}
+
+func TestComplete_RoleMapping(t *testing.T) {
+	service, mock, db := setupTestService(t, func(c *Config) {
+		c.RoleClaim = "groups"
+		c.RoleMapping = map[string]string{"gardarr-admins": entities.RoleAdmin, "gardarr-ops": entities.RoleOperator}
+		c.DefaultRole = ""
+	})
+	createLocalUser(t, db, "admin@example.com", entities.RoleAdmin)
+
+	// The highest mapped role wins
+	u, _, err := login(t, service, mock, map[string]any{"sub": "carol", "email": "carol@example.com", "groups": []string{"staff", "gardarr-ops"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleOperator {
+		t.Errorf("Expected role %s, got %s", entities.RoleOperator, u.Role)
+	}
+
+	// Roles follow the claims on each login
+	u, _, err = login(t, service, mock, map[string]any{"sub": "carol", "email": "carol@example.com", "groups": []string{"gardarr-ops", "gardarr-admins"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleAdmin {
+		t.Errorf("Expected role %s, got %s", entities.RoleAdmin, u.Role)
+	}
+
+	var model models.User
+	db.DB.Where("uuid = ?", u.UUID).First(&model)
+	if model.Role != entities.RoleAdmin {
+		t.Errorf("Expected stored role %s, got %s", entities.RoleAdmin, model.Role)
+	}
+
+	// No mapped role and no default role: no access
+	if _, _, err := login(t, service, mock, map[string]any{"sub": "dave", "email": "dave@example.com", "groups": "staff"}); !errors.Is(err, errors.ErrSSODenied) {
+		t.Errorf("Expected ErrSSODenied, got %v", err)
+	}
+}
+
+func TestComplete_KeepsLastAdmin(t *testing.T) {
+	service, mock, _ := setupTestService(t, func(c *Config) {
+		c.RoleClaim = "groups"
+		c.RoleMapping = map[string]string{"gardarr-admins": entities.RoleAdmin}
+	})
+
+	u, _, err := login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com", "groups": []string{"gardarr-admins"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, _, err = login(t, service, mock, map[string]any{"sub": "alice", "email": "alice@example.com", "groups": []string{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Role != entities.RoleAdmin {
+		t.Errorf("Expected the last admin to keep role %s, got %s", entities.RoleAdmin, u.Role)
+	}
+}
+
+func TestComplete_RejectsInvalidTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		alter  func(m *mockProvider)
+	}{
+		{name: "wrong nonce", claims: map[string]any{"nonce": "replayed"}},
+		{name: "wrong audience", claims: map[string]any{"aud": "another-client"}},
+		{name: "wrong authorized party", claims: map[string]any{"aud": []string{testClientID, "another-client"}, "azp": "another-client"}},
+		{name: "wrong issuer", claims: map[string]any{"iss": "https://evil.example.com"}},
+		{name: "expired", claims: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "not valid yet", claims: map[string]any{"nbf": time.Now().Add(time.Hour).Unix()}},
+		{name: "no subject", claims: map[string]any{"sub": ""}},
+		{name: "bad signature", alter: func(m *mockProvider) { m.signer = otherKey }},
+		{name: "unknown key", alter: func(m *mockProvider) { m.kid = "key-2" }},
+		{name: "symmetric algorithm", alter: func(m *mockProvider) { m.alg = "HS256" }},
+		{name: "no algorithm", alter: func(m *mockProvider) { m.alg = "none" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock, _ := setupTestService(t, nil)
+			ctx := context.Background()
+
+			begun, err := service.Begin(ctx, "/")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			claims := map[string]any{"sub": "mallory", "email": "mallory@example.com"}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			code := mock.authorize(begun.URL, claims)
+			if tt.alter != nil {
+				tt.alter(mock)
+			}
+
+			if _, _, err := service.Complete(ctx, begun.State, code); !errors.Is(err, errors.ErrSSOFailed) {
+				t.Errorf("Expected ErrSSOFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestComplete_StateUsedOnce(t *testing.T) {
+	service, mock, _ := setupTestService(t, nil)
+	ctx := context.Background()
+
+	begun, err := service.Begin(ctx, "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims := map[string]any{"sub": "alice", "email": "alice@example.com"}
+
+	if _, _, err := service.Complete(ctx, begun.State, mock.authorize(begun.URL, claims)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := service.Complete(ctx, begun.State, mock.authorize(begun.URL, claims)); !errors.Is(err, errors.ErrSSOFailed) {
+		t.Errorf("Expected ErrSSOFailed on a reused state, got %v", err)
+	}
+}
+
+func TestComplete_ExpiredState(t *testing.T) {
+	service, mock, _ := setupTestService(t, nil)
+	ctx := context.Background()
+
+	begun, err := service.Begin(ctx, "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service.now = func() time.Time { return time.Now().Add(time.Hour) }
+	code := mock.authorize(begun.URL, map[string]any{"sub": "alice", "email": "alice@example.com"})
+	if _, _, err := service.Complete(ctx, begun.State, code); !errors.Is(err, errors.ErrSSOFailed) {
+		t.Errorf("Expected ErrSSOFailed, got %v", err)
+	}
+}
+
+func TestComplete_WrongVerifier(t *testing.T) {
+	service, mock, _ := setupTestService(t, nil)
+	ctx := context.Background()
+
+	first, err := service.Begin(ctx, "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := service.Begin(ctx, "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A code issued to one login cannot complete another, its verifier differing
+	code := mock.authorize(first.URL, map[string]any{"sub": "alice", "email": "alice@example.com"})
+	if _, _, err := service.Complete(ctx, second.State, code); !errors.Is(err, errors.ErrSSOFailed) {
+		t.Errorf("Expected ErrSSOFailed, got %v", err)
+	}
+}
+
+func TestBegin_Disabled(t *testing.T) {
+	service, _, _ := setupTestService(t, func(c *Config) { c.Enabled = false })
+
+	if _, err := service.Begin(context.Background(), "/"); !errors.Is(err, errors.ErrSSODisabled) {
+		t.Errorf("Expected ErrSSODisabled, got %v", err)
+	}
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	tests := map[string]string{
+		"":                       "/",
+		"/":                      "/",
+		"/tasks?state=active":    "/tasks?state=active",
+		"https://evil.example":   "/",
+		"//evil.example":         "/",
+		"/\\evil.example":        "/",
+		"tasks":                  "/",
+		"/tasks\r\nSet-Cookie:x": "/",
+	}
+
+	for path, expected := range tests {
+		if got := SafeRedirectPath(path); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, path, got)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is the clock difference tolerated with the identity provider
+const clockSkew = time.Minute
+
+// ecCurveBits is the curve size each ECDSA algorithm signs with
+var ecCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// idTokenHeader is the JOSE header of an ID token
+type idTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// idToken holds the claims of a verified ID token
+type idToken struct {
+	Issuer        string
+	Subject       string
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Claims        map[string]any
+}
+
+// audience accepts the "aud" claim as a string or a list (RFC 7519 4.1.3)
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// verifyIDToken checks the signature and the claims of an ID token (OpenID
+// Connect Core 3.1.3.7) and returns its claims
+func (s *Service) verifyIDToken(ctx context.Context, raw, nonce string) (*idToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+
+	var header idTokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed ID token signature")
+	}
+
+	key, err := s.provider.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Issuer    string   `json:"iss"`
+		Subject   string   `json:"sub"`
+		Audience  audience `json:"aud"`
+		AZP       string   `json:"azp"`
+		Expiry    float64  `json:"exp"`
+		IssuedAt  float64  `json:"iat"`
+		NotBefore float64  `json:"nbf"`
+		Nonce     string   `json:"nonce"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+
+	var all map[string]any
+	if err := decodeSegment(parts[1], &all); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+
+	now := s.now()
+	switch {
+	case claims.Issuer != s.config.IssuerURL:
+		return nil, fmt.Errorf("ID token issued by %q", claims.Issuer)
+	case claims.Subject == "":
+		return nil, errors.New("ID token lacks a subject")
+	case !slices.Contains(claims.Audience, s.config.ClientID):
+		return nil, errors.New("ID token issued to another client")
+	case len(claims.Audience) > 1 && claims.AZP != "" && claims.AZP != s.config.ClientID:
+		return nil, errors.New("ID token authorized for another client")
+	case claims.Expiry == 0 || now.After(unixTime(claims.Expiry).Add(clockSkew)):
+		return nil, errors.New("ID token expired")
+	case claims.NotBefore != 0 && now.Add(clockSkew).Before(unixTime(claims.NotBefore)):
+		return nil, errors.New("ID token not valid yet")
+	case claims.IssuedAt != 0 && now.Add(clockSkew).Before(unixTime(claims.IssuedAt)):
+		return nil, errors.New("ID token issued in the future")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	token := &idToken{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Nonce:   claims.Nonce,
+		Claims:  all,
+	}
+	token.Email, _ = all[s.config.EmailClaim].(string)
+	switch verified := all["email_verified"].(type) {
+	case bool:
+		token.EmailVerified = verified
+	case string:
+		// Some providers send the flag as a string
+		token.EmailVerified = verified == "true"
+	}
+
+	return token, nil
+}
+
+// verifySignature checks a JWS signature. Only asymmetric algorithms are
+// accepted, "none" and the HMAC ones would let anyone knowing the client
+// secret, or no one, sign tokens.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("signing key does not match the algorithm")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature); err != nil {
+			return errors.New("invalid ID token signature")
+		}
+	case strings.HasPrefix(alg, "PS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("signing key does not match the algorithm")
+		}
+		if err := rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+			return errors.New("invalid ID token signature")
+		}
+	case strings.HasPrefix(alg, "ES"):
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecCurveBits[alg] != ecKey.Curve.Params().BitSize {
+			return errors.New("signing key does not match the algorithm")
+		}
+		// The signature is the concatenation of r and s (RFC 7518 3.4)
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid ID token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		sig := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, sig) {
+			return errors.New("invalid ID token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,231 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/oidc"
+	"github.com/gardarr/gardarr/internal/repository/user"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+)
+
+// Identity is a user authenticated by an external identity provider, an OIDC
+// provider or a forward authentication proxy
+type Identity struct {
+	Issuer        string // identifies the provider
+	Subject       string // identifies the user at the provider
+	Email         string
+	EmailVerified bool
+
+	// Role is given to the users created on their first login. When
+	// ManagedRole is set, the provider manages the roles and the user gets Role
+	// on each login. An empty Role refuses the user.
+	Role        string
+	ManagedRole bool
+}
+
+// Policy tells how the first login of a subject is handled
+type Policy struct {
+	AutoProvision bool // creates the users unknown to Gardarr
+	LinkByEmail   bool // links the subject to the user with the same verified email
+}
+
+// Service finds the local user of an external identity, linking or creating
+// it on its first login. The links are kept in the user identities.
+type Service struct {
+	identities *oidc.Repository
+	users      *user.Repository
+	now        func() time.Time
+}
+
+func NewService(db *database.Database) *Service {
+	return &Service{
+		identities: oidc.NewRepository(db),
+		users:      user.NewRepository(db),
+		now:        time.Now,
+	}
+}
+
+// Resolve returns the user linked to an identity, linking or provisioning it
+// on its first login, and syncs its role
+func (s *Service) Resolve(ctx context.Context, identity Identity, policy Policy) (*entities.User, error) {
+	email := strings.TrimSpace(strings.ToLower(identity.Email))
+
+	link, err := s.identities.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var u *entities.User
+	if link != nil {
+		u, err = s.users.GetUserByUUID(ctx, link.UserUUID.String())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identities.TouchIdentity(ctx, identity.Issuer, identity.Subject, email, s.now()); err != nil {
+			log.Printf("provisioning: failed to record login of %s: %v", u.Email, err)
+		}
+	} else {
+		u, err = s.linkOrProvision(ctx, identity, email, policy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Disabled {
+		return nil, fmt.Errorf("%w: account disabled", pkgerrors.ErrSSODenied)
+	}
+
+	if identity.ManagedRole && u.Role != identity.Role {
+		if err := s.syncRole(ctx, u, identity.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// linkOrProvision links the first login of a subject to the local user with
+// the same verified email, or else creates a user
+func (s *Service) linkOrProvision(ctx context.Context, identity Identity, email string, policy Policy) (*entities.User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("%w: the identity provider sent no email", pkgerrors.ErrSSOFailed)
+	}
+
+	existing, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, pkgerrors.ErrUserNotFound) {
+		return nil, err
+	}
+
+	var u *entities.User
+	switch {
+	case existing != nil:
+		if !policy.LinkByEmail || !identity.EmailVerified {
+			return nil, fmt.Errorf("%w: a local account uses %s", pkgerrors.ErrSSODenied, email)
+		}
+		u, err = s.users.GetUserByUUID(ctx, existing.UUID.String())
+		if err != nil {
+			return nil, err
+		}
+	case !policy.AutoProvision:
+		return nil, fmt.Errorf("%w: no account for %s", pkgerrors.ErrSSODenied, email)
+	default:
+		u, err = s.provision(ctx, identity, email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := s.now()
+	if err := s.identities.CreateIdentity(ctx, &models.UserIdentity{
+		Issuer:      identity.Issuer,
+		Subject:     identity.Subject,
+		UserUUID:    u.UUID,
+		Email:       email,
+		LastLoginAt: now,
+		CreatedAt:   now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// provision creates the user of a first login. The first user becomes admin,
+// as with the registration: the count holds until the user is created, so
+// concurrent first logins cannot both be first.
+func (s *Service) provision(ctx context.Context, identity Identity, email string) (*entities.User, error) {
+	var created *entities.User
+	err := s.users.Transaction(ctx, func(repo *user.Repository) error {
+		if err := repo.LockUsers(ctx); err != nil {
+			return err
+		}
+
+		count, err := repo.CountUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		role := identity.Role
+		if count == 0 {
+			role = entities.RoleAdmin
+		}
+		if role == "" {
+			return fmt.Errorf("%w: no role for %s", pkgerrors.ErrSSODenied, email)
+		}
+
+		// Provisioned users have no password, they can only log in through the provider
+		created, err = repo.CreateUserWithRole(ctx, email, "", "", role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// syncRole gives a user the role mapped by its provider. The last active admin
+// keeps its role, as when demoted by an admin.
+func (s *Service) syncRole(ctx context.Context, u *entities.User, role string) error {
+	if role == "" {
+		return fmt.Errorf("%w: no role for %s", pkgerrors.ErrSSODenied, u.Email)
+	}
+
+	if u.IsAdmin() && role != entities.RoleAdmin {
+		count, err := s.users.CountActiveAdmins(ctx)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			log.Printf("provisioning: keeping the admin role of %s, the last active admin", u.Email)
+			return nil
+		}
+	}
+
+	if err := s.users.UpdateRole(ctx, u.UUID, role); err != nil {
+		return err
+	}
+	u.Role = role
+	return nil
+}
+
+// MapRole returns the role the values (groups, claims) map to, the highest
+// when several do, or else defaultRole
+func MapRole(values []string, mapping map[string]string, defaultRole string) string {
+	best := -1
+	for _, value := range values {
+		if role, ok := mapping[value]; ok {
+			best = max(best, slices.Index(entities.Roles, role))
+		}
+	}
+
+	if best < 0 {
+		return defaultRole
+	}
+	return entities.Roles[best]
+}
+
+// ParseRoleMapping reads "value=role" pairs, e.g. the list
+// "gardarr-admins=admin,gardarr-ops=operator". Invalid pairs are logged and skipped.
+func ParseRoleMapping(pairs []string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range pairs {
+		claim, role, ok := strings.Cut(pair, "=")
+		claim, role = strings.TrimSpace(claim), strings.TrimSpace(role)
+		if !ok || claim == "" || !slices.Contains(entities.Roles, role) {
+			log.Printf("provisioning: ignoring invalid role mapping %q", pair)
+			continue
+		}
+		mapping[claim] = role
+	}
+	return mapping
+}
@@ -0,0 +1,38 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/gardarr/gardarr/internal/entities"
+)
+
+func TestMapRole(t *testing.T) {
+	mapping := map[string]string{"gardarr-admins": entities.RoleAdmin, "gardarr-ops": entities.RoleOperator}
+
+	tests := []struct {
+		values   []string
+		expected string
+	}{
+		{values: nil, expected: entities.RoleViewer},
+		{values: []string{"staff"}, expected: entities.RoleViewer},
+		{values: []string{"staff", "gardarr-ops"}, expected: entities.RoleOperator},
+		{values: []string{"gardarr-admins", "gardarr-ops"}, expected: entities.RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		if got := MapRole(tt.values, mapping, entities.RoleViewer); got != tt.expected {
+			t.Errorf("Expected role %s for %v, got %s", tt.expected, tt.values, got)
+		}
+	}
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping := ParseRoleMapping([]string{"gardarr-admins = admin", "ops=operator", "broken", "unknown=root"})
+
+	if len(mapping) != 2 {
+		t.Fatalf("Expected 2 mappings, got %d: %v", len(mapping), mapping)
+	}
+	if mapping["gardarr-admins"] != entities.RoleAdmin || mapping["ops"] != entities.RoleOperator {
+		t.Errorf("Unexpected mapping %v", mapping)
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"log"
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+const (
+	PolicyLoginIP      = "login_ip"
+	PolicyLoginAccount = "login_account"
+	PolicyRegister     = "register"
+	PolicySession      = "session"
+	PolicyAPIToken     = "api_token"
+	PolicyAgents       = "agents"
+	PolicyAPI          = "api"
+)
+
+// Config holds the rate limiting store and the policy of each kind of request
+type Config struct {
+	Store           string        // StoreMemory or StoreDatabase
+	CleanupInterval time.Duration // time between two removals of the expired entries
+
+	LoginIP      Policy // failed logins, password resets and second factors by client address
+	LoginAccount Policy // failed logins by account
+	Register     Policy // registrations by client address
+	Session      Policy // invalid session cookies by client address
+	APIToken     Policy // invalid API tokens by client address
+	Agents       Policy // agent calls by user
+	API          Policy // every API request by client address
+}
+
+// LoadConfigFromEnv loads the rate limiting configuration from environment
+// variables. An invalid policy or cleanup interval is logged and replaced by
+// its default.
+func LoadConfigFromEnv() Config {
+	return Config{
+		Store:           env.Get("RATELIMIT_STORE").Default(StoreMemory).Value(),
+		CleanupInterval: env.Get("RATELIMIT_CLEANUP_INTERVAL").Default("1m").ValuePositiveDuration(),
+		LoginIP:         loadPolicy(PolicyLoginIP, "RATELIMIT_LOGIN_IP", "5/5m/15m"),
+		LoginAccount:    loadPolicy(PolicyLoginAccount, "RATELIMIT_LOGIN_ACCOUNT", "10/15m/30m"),
+		Register:        loadPolicy(PolicyRegister, "RATELIMIT_REGISTER", "5/1h"),
+		Session:         loadPolicy(PolicySession, "RATELIMIT_SESSION", "5/5m/15m"),
+		APIToken:        loadPolicy(PolicyAPIToken, "RATELIMIT_API_TOKEN", "5/5m/15m"),
+		Agents:          loadPolicy(PolicyAgents, "RATELIMIT_AGENTS", "300/1m"),
+		API:             loadPolicy(PolicyAPI, "RATELIMIT_API", "1200/1m"),
+	}
+}
+
+// loadPolicy parses the policy set in key, falling back to fallback
+func loadPolicy(name, key, fallback string) Policy {
+	policy, err := ParsePolicy(name, env.Get(key).Default(fallback).Value())
+	if err != nil {
+		log.Printf("ratelimit: %v, using %s", err, fallback)
+		policy, _ = ParsePolicy(name, fallback)
+	}
+	return policy
+}
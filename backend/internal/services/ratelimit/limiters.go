@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/infra/database"
+)
+
+// Limiters holds a service for each policy, sharing the configured store
+type Limiters struct {
+	LoginIP      *Service
+	LoginAccount *Service
+	Register     *Service
+	Session      *Service
+	APIToken     *Service
+	Agents       *Service
+	API          *Service
+
+	store  Store
+	config Config
+}
+
+// NewLimiters creates the limiters configured by the environment
+func NewLimiters(db *database.Database) *Limiters {
+	return NewLimitersWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewLimitersWithConfig creates the limiters with the given settings
+func NewLimitersWithConfig(db *database.Database, config Config) *Limiters {
+	store := NewStore(db, config.Store)
+
+	return &Limiters{
+		LoginIP:      NewWithStore(store, config.LoginIP),
+		LoginAccount: NewWithStore(store, config.LoginAccount),
+		Register:     NewWithStore(store, config.Register),
+		Session:      NewWithStore(store, config.Session),
+		APIToken:     NewWithStore(store, config.APIToken),
+		Agents:       NewWithStore(store, config.Agents),
+		API:          NewWithStore(store, config.API),
+		store:        store,
+		config:       config,
+	}
+}
+
+// All returns the services of every policy
+func (l *Limiters) All() []*Service {
+	return []*Service{l.LoginIP, l.LoginAccount, l.Register, l.Session, l.APIToken, l.Agents, l.API}
+}
+
+// Get returns the service of the policy with the given name
+func (l *Limiters) Get(name string) (*Service, bool) {
+	for _, service := range l.All() {
+		if service.Policy().Name == name {
+			return service, true
+		}
+	}
+	return nil, false
+}
+
+// Run removes the expired entries of the store on each cleanup interval until ctx is canceled
+func (l *Limiters) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := l.store.DeleteExpired(ctx, now); err != nil {
+				log.Printf("ratelimit: failed to remove expired entries: %v", err)
+			}
+		}
+	}
+}
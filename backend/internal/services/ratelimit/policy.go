@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ModeLockout blocks an identifier once it failed too many times
+	ModeLockout = "lockout"
+	// ModeTokenBucket throttles every request of an identifier
+	ModeTokenBucket = "token_bucket"
+)
+
+// Policy is the limit applied to one kind of request
+type Policy struct {
+	Name string
+	Mode string // ModeLockout or ModeTokenBucket, empty when disabled
+
+	// Lockout: MaxAttempts failures within Window block the identifier for Block
+	MaxAttempts int
+	Window      time.Duration
+	Block       time.Duration
+
+	// Token bucket: Burst requests at once, refilled at Burst requests per Period
+	Burst  int
+	Period time.Duration
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	switch p.Mode {
+	case ModeLockout:
+		return p.MaxAttempts > 0 && p.Window > 0 && p.Block > 0
+	case ModeTokenBucket:
+		return p.Burst > 0 && p.Period > 0
+	default:
+		return false
+	}
+}
+
+// ParsePolicy reads a policy setting: "attempts/window/block" for a lockout,
+// such as "5/5m/15m", "requests/period" for a token bucket, such as "60/1m",
+// and "off" to disable it
+func ParsePolicy(name, spec string) (Policy, error) {
+	policy := Policy{Name: name}
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "off") {
+		return policy, nil
+	}
+
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		return policy, fmt.Errorf("invalid rate limit policy %q for %s", spec, name)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return policy, fmt.Errorf("invalid rate limit count %q for %s", parts[0], name)
+	}
+
+	durations := make([]time.Duration, len(parts)-1)
+	for i, part := range parts[1:] {
+		if durations[i], err = time.ParseDuration(strings.TrimSpace(part)); err != nil || durations[i] <= 0 {
+			return policy, fmt.Errorf("invalid rate limit duration %q for %s", part, name)
+		}
+	}
+
+	if len(parts) == 3 {
+		policy.Mode = ModeLockout
+		policy.MaxAttempts = count
+		policy.Window = durations[0]
+		policy.Block = durations[1]
+	} else {
+		policy.Mode = ModeTokenBucket
+		policy.Burst = count
+		policy.Period = durations[0]
+	}
+
+	return policy, nil
+}
+
+// String formats the policy the way ParsePolicy reads it
+func (p Policy) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	if p.Mode == ModeLockout {
+		return fmt.Sprintf("%d/%s/%s", p.MaxAttempts, p.Window, p.Block)
+	}
+	return fmt.Sprintf("%d/%s", p.Burst, p.Period)
+}
@@ -1,43 +1,42 @@
 package ratelimit
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"log"
+	"math"
+	"strings"
 	"time"
-)
 
-// Attempt tracks failed authentication attempts
-type Attempt struct {
-	Count     int
-	FirstTry  time.Time
-	BlockedAt time.Time
-	IsBlocked bool
-}
+	"github.com/gardarr/gardarr/internal/entities"
+)
 
-// Service provides rate limiting functionality
+// Service applies one policy to the identifiers, its state being kept in a store.
+// Store failures are logged and let the requests through.
 type Service struct {
-	attempts map[string]*Attempt
-	mu       sync.RWMutex
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
 
-	// Configuration
-	maxAttempts    int
-	windowDuration time.Duration
-	blockDuration  time.Duration
+// Decision is the outcome of a rate limit check, reported in the RateLimit-* headers
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the limit is fully restored
+	RetryAfter time.Duration // until a request is allowed again, when refused
 }
 
-// NewService creates a new rate limiting service
+// NewService creates a lockout service with its own memory store
 func NewService(maxAttempts int, windowDuration, blockDuration time.Duration) *Service {
-	s := &Service{
-		attempts:       make(map[string]*Attempt),
-		maxAttempts:    maxAttempts,
-		windowDuration: windowDuration,
-		blockDuration:  blockDuration,
-	}
-
-	// Start cleanup goroutine
-	go s.cleanup()
-
-	return s
+	return NewWithStore(NewMemoryStore(), Policy{
+		Name:        "default",
+		Mode:        ModeLockout,
+		MaxAttempts: maxAttempts,
+		Window:      windowDuration,
+		Block:       blockDuration,
+	})
 }
 
 // NewDefaultService creates a service with default settings
@@ -46,117 +45,204 @@ func NewDefaultService() *Service {
 	return NewService(5, 5*time.Minute, 15*time.Minute)
 }
 
-// RecordAttempt records a failed authentication attempt
-func (s *Service) RecordAttempt(identifier string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// NewWithStore creates a service applying policy with its state in store
+func NewWithStore(store Store, policy Policy) *Service {
+	return &Service{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
 
-	now := time.Now()
-	attempt, exists := s.attempts[identifier]
+// Policy returns the policy applied by the service
+func (s *Service) Policy() Policy {
+	return s.policy
+}
 
-	if !exists {
-		// First attempt
-		s.attempts[identifier] = &Attempt{
-			Count:    1,
-			FirstTry: now,
-		}
+// key returns the store key of an identifier, scoped to the policy
+func (s *Service) key(identifier string) string {
+	return s.policy.Name + ":" + identifier
+}
+
+// RecordAttempt records a failed authentication attempt
+func (s *Service) RecordAttempt(identifier string) {
+	if s.policy.Mode != ModeLockout || !s.policy.Enabled() {
 		return
 	}
 
-	// Check if window has expired
-	if now.Sub(attempt.FirstTry) > s.windowDuration {
-		// Reset window
-		s.attempts[identifier] = &Attempt{
-			Count:    1,
-			FirstTry: now,
+	now := s.now()
+	err := s.store.Update(context.Background(), s.key(identifier), func(entry *entities.RateLimitEntry) *entities.RateLimitEntry {
+		// Check if window has expired, a running block is kept
+		if entry == nil || now.Sub(entry.WindowStart) > s.policy.Window {
+			blockedUntil := time.Time{}
+			if entry != nil && entry.BlockedUntil.After(now) {
+				blockedUntil = entry.BlockedUntil
+			}
+			entry = &entities.RateLimitEntry{WindowStart: now, BlockedUntil: blockedUntil}
 		}
-		return
-	}
 
-	// Increment attempt count
-	attempt.Count++
+		// Increment attempt count
+		entry.Count++
+
+		// Check if should be blocked
+		if entry.Count >= s.policy.MaxAttempts && !entry.BlockedUntil.After(now) {
+			entry.BlockedUntil = now.Add(s.policy.Block)
+		}
 
-	// Check if should be blocked
-	if attempt.Count >= s.maxAttempts && !attempt.IsBlocked {
-		attempt.IsBlocked = true
-		attempt.BlockedAt = now
+		entry.ExpiresAt = entry.WindowStart.Add(s.policy.Window)
+		if entry.BlockedUntil.After(entry.ExpiresAt) {
+			entry.ExpiresAt = entry.BlockedUntil
+		}
+		return entry
+	})
+	if err != nil {
+		log.Printf("ratelimit: failed to record attempt for %s: %v", s.policy.Name, err)
 	}
 }
 
 // IsBlocked checks if an identifier is currently blocked
 func (s *Service) IsBlocked(identifier string) (bool, time.Duration) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	attempt, exists := s.attempts[identifier]
-	if !exists {
+	if s.policy.Mode != ModeLockout || !s.policy.Enabled() {
 		return false, 0
 	}
 
-	if !attempt.IsBlocked {
+	entry := s.get(identifier)
+	if entry == nil {
 		return false, 0
 	}
 
-	// Check if block has expired
-	elapsed := time.Since(attempt.BlockedAt)
-	if elapsed >= s.blockDuration {
-		// Block expired, but we'll clean it up in the cleanup goroutine
+	// Check if block has expired, the entry is removed by the cleanup job
+	remaining := entry.BlockedUntil.Sub(s.now())
+	if remaining <= 0 {
 		return false, 0
 	}
 
-	remaining := s.blockDuration - elapsed
 	return true, remaining
 }
 
 // Reset removes all attempts for an identifier (e.g., after successful auth)
 func (s *Service) Reset(identifier string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.attempts, identifier)
+	if err := s.Unblock(identifier); err != nil {
+		log.Printf("ratelimit: failed to reset %s: %v", s.policy.Name, err)
+	}
+}
+
+// Unblock removes the state of an identifier, lifting its block
+func (s *Service) Unblock(identifier string) error {
+	return s.store.Delete(context.Background(), s.key(identifier))
 }
 
 // GetAttemptCount returns the current attempt count for an identifier
 func (s *Service) GetAttemptCount(identifier string) int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	attempt, exists := s.attempts[identifier]
-	if !exists {
+	entry := s.get(identifier)
+	if entry == nil {
 		return 0
 	}
 
 	// If window expired, return 0
-	if time.Since(attempt.FirstTry) > s.windowDuration {
+	if s.now().Sub(entry.WindowStart) > s.policy.Window {
 		return 0
 	}
 
-	return attempt.Count
+	return entry.Count
 }
 
-// cleanup periodically removes expired entries
-func (s *Service) cleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+// Status returns the lockout state of an identifier without recording anything
+func (s *Service) Status(identifier string) Decision {
+	decision := Decision{Allowed: true, Limit: s.policy.MaxAttempts, Remaining: s.policy.MaxAttempts}
+	if s.policy.Mode != ModeLockout || !s.policy.Enabled() {
+		return decision
+	}
 
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
+	entry := s.get(identifier)
+	if entry == nil {
+		return decision
+	}
 
-		for key, attempt := range s.attempts {
-			// Remove if window expired and not blocked
-			if !attempt.IsBlocked && now.Sub(attempt.FirstTry) > s.windowDuration {
-				delete(s.attempts, key)
-				continue
-			}
+	now := s.now()
+	if blockedFor := entry.BlockedUntil.Sub(now); blockedFor > 0 {
+		decision.Allowed = false
+		decision.Remaining = 0
+		decision.Reset = blockedFor
+		decision.RetryAfter = blockedFor
+		return decision
+	}
 
-			// Remove if block expired
-			if attempt.IsBlocked && now.Sub(attempt.BlockedAt) > s.blockDuration {
-				delete(s.attempts, key)
-			}
+	if now.Sub(entry.WindowStart) <= s.policy.Window {
+		decision.Remaining = max(s.policy.MaxAttempts-entry.Count, 0)
+		decision.Reset = entry.WindowStart.Add(s.policy.Window).Sub(now)
+	}
+
+	return decision
+}
+
+// Allow takes a request from the bucket of an identifier (token bucket)
+func (s *Service) Allow(identifier string) Decision {
+	if s.policy.Mode != ModeTokenBucket || !s.policy.Enabled() {
+		return Decision{Allowed: true}
+	}
+
+	burst := float64(s.policy.Burst)
+	rate := burst / s.policy.Period.Seconds() // tokens per second
+	now := s.now()
+	decision := Decision{Allowed: true, Limit: s.policy.Burst}
+
+	err := s.store.Update(context.Background(), s.key(identifier), func(entry *entities.RateLimitEntry) *entities.RateLimitEntry {
+		if entry == nil {
+			entry = &entities.RateLimitEntry{Tokens: burst, WindowStart: now}
+		} else {
+			elapsed := now.Sub(entry.WindowStart).Seconds()
+			entry.Tokens = math.Min(burst, entry.Tokens+math.Max(elapsed, 0)*rate)
+			entry.WindowStart = now
+		}
+
+		if entry.Tokens >= 1 {
+			entry.Tokens--
+		} else {
+			decision.Allowed = false
+			decision.RetryAfter = time.Duration((1 - entry.Tokens) / rate * float64(time.Second))
+		}
+
+		decision.Remaining = int(entry.Tokens)
+		decision.Reset = time.Duration((burst - entry.Tokens) / rate * float64(time.Second))
+		entry.ExpiresAt = now.Add(decision.Reset)
+		return entry
+	})
+	if err != nil {
+		log.Printf("ratelimit: failed to check %s: %v", s.policy.Name, err)
+		return Decision{Allowed: true, Limit: s.policy.Burst, Remaining: s.policy.Burst}
+	}
+
+	return decision
+}
+
+// Blocked returns the entries of the identifiers currently locked out
+func (s *Service) Blocked(ctx context.Context) ([]*entities.RateLimitEntry, error) {
+	entries, err := s.store.List(ctx, s.key(""))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	blocked := make([]*entities.RateLimitEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.BlockedUntil.After(now) {
+			entry.Key = strings.TrimPrefix(entry.Key, s.key(""))
+			blocked = append(blocked, entry)
 		}
+	}
+
+	return blocked, nil
+}
 
-		s.mu.Unlock()
+// get returns the entry of an identifier, nil when missing or unreadable
+func (s *Service) get(identifier string) *entities.RateLimitEntry {
+	entry, err := s.store.Get(context.Background(), s.key(identifier))
+	if err != nil {
+		log.Printf("ratelimit: failed to read %s: %v", s.policy.Name, err)
+		return nil
 	}
+	return entry
 }
 
 // GetIdentifier creates a unique identifier for rate limiting
@@ -167,24 +253,36 @@ func GetIdentifier(ip, userAgent string) string {
 
 // GetStats returns current rate limiting statistics
 func (s *Service) GetStats() map[string]interface{} {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	entries, err := s.store.List(context.Background(), s.key(""))
+	if err != nil {
+		log.Printf("ratelimit: failed to list %s: %v", s.policy.Name, err)
+	}
 
+	now := s.now()
 	totalBlocked := 0
 	totalAttempts := 0
 
-	for _, attempt := range s.attempts {
+	for _, entry := range entries {
 		totalAttempts++
-		if attempt.IsBlocked {
+		if entry.BlockedUntil.After(now) {
 			totalBlocked++
 		}
 	}
 
-	return map[string]interface{}{
-		"total_tracked":  totalAttempts,
-		"total_blocked":  totalBlocked,
-		"max_attempts":   s.maxAttempts,
-		"window_minutes": s.windowDuration.Minutes(),
-		"block_minutes":  s.blockDuration.Minutes(),
+	stats := map[string]interface{}{
+		"policy":        s.policy.String(),
+		"total_tracked": totalAttempts,
+		"total_blocked": totalBlocked,
 	}
+
+	if s.policy.Mode == ModeTokenBucket {
+		stats["burst"] = s.policy.Burst
+		stats["period_seconds"] = s.policy.Period.Seconds()
+	} else {
+		stats["max_attempts"] = s.policy.MaxAttempts
+		stats["window_minutes"] = s.policy.Window.Minutes()
+		stats["block_minutes"] = s.policy.Block.Minutes()
+	}
+
+	return stats
 }
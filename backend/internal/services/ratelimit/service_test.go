@@ -171,3 +171,121 @@ func TestConcurrentAccess(t *testing.T) {
 		t.Errorf("expected 50 attempts but got %d", count)
 	}
 }
+
+func TestParsePolicy(t *testing.T) {
+	lockout, err := ParsePolicy("login_ip", "5/5m/15m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lockout.Mode != ModeLockout || lockout.MaxAttempts != 5 || lockout.Window != 5*time.Minute || lockout.Block != 15*time.Minute {
+		t.Errorf("unexpected lockout policy %+v", lockout)
+	}
+
+	bucket, err := ParsePolicy("api", "60/1m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bucket.Mode != ModeTokenBucket || bucket.Burst != 60 || bucket.Period != time.Minute {
+		t.Errorf("unexpected token bucket policy %+v", bucket)
+	}
+
+	if off, err := ParsePolicy("api", "off"); err != nil || off.Enabled() {
+		t.Errorf("expected a disabled policy, got %+v (error %v)", off, err)
+	}
+
+	for _, spec := range []string{"5", "x/1m", "0/1m", "5/never", "5/1m/-1m", "1/2/3/4"} {
+		if _, err := ParsePolicy("api", spec); err == nil {
+			t.Errorf("expected '%s' to be rejected", spec)
+		}
+	}
+}
+
+func TestAllow_TokenBucket(t *testing.T) {
+	service := NewWithStore(NewMemoryStore(), Policy{Name: "api", Mode: ModeTokenBucket, Burst: 3, Period: 3 * time.Second})
+	start := time.Now()
+	service.now = func() time.Time { return start }
+
+	for i := 0; i < 3; i++ {
+		decision := service.Allow("10.0.0.1")
+		if !decision.Allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+		if decision.Remaining != 2-i {
+			t.Errorf("expected %d requests left but got %d", 2-i, decision.Remaining)
+		}
+	}
+
+	decision := service.Allow("10.0.0.1")
+	if decision.Allowed {
+		t.Fatal("expected the request to be refused once the bucket is empty")
+	}
+	if decision.RetryAfter != time.Second {
+		t.Errorf("expected to retry after 1s but got %v", decision.RetryAfter)
+	}
+
+	// Other identifiers have their own bucket
+	if !service.Allow("10.0.0.2").Allowed {
+		t.Error("expected another identifier to be allowed")
+	}
+
+	// The bucket refills over time
+	service.now = func() time.Time { return start.Add(time.Second) }
+	if !service.Allow("10.0.0.1").Allowed {
+		t.Error("expected a request to be allowed after a refill")
+	}
+	if service.Allow("10.0.0.1").Allowed {
+		t.Error("expected a single request to be refilled after one second")
+	}
+}
+
+func TestRecordAttempt_BlockOutlivesWindow(t *testing.T) {
+	service := NewService(2, time.Minute, time.Hour)
+	start := time.Now()
+	service.now = func() time.Time { return start }
+
+	service.RecordAttempt("10.0.0.1")
+	service.RecordAttempt("10.0.0.1")
+
+	// A new failure after the window starts a new count but keeps the block
+	service.now = func() time.Time { return start.Add(2 * time.Minute) }
+	service.RecordAttempt("10.0.0.1")
+
+	blocked, remaining := service.IsBlocked("10.0.0.1")
+	if !blocked || remaining != 58*time.Minute {
+		t.Errorf("expected the block to last 58 more minutes, got %v (%v)", blocked, remaining)
+	}
+
+	status := service.Status("10.0.0.1")
+	if status.Allowed || status.RetryAfter != 58*time.Minute {
+		t.Errorf("expected a refused status, got %+v", status)
+	}
+}
+
+func TestDisabledPolicy(t *testing.T) {
+	service := NewWithStore(NewMemoryStore(), Policy{Name: "api"})
+
+	for i := 0; i < 10; i++ {
+		service.RecordAttempt("10.0.0.1")
+		if !service.Allow("10.0.0.1").Allowed {
+			t.Fatal("expected a disabled policy to allow every request")
+		}
+	}
+
+	if blocked, _ := service.IsBlocked("10.0.0.1"); blocked {
+		t.Error("expected a disabled policy to never block")
+	}
+}
+
+func TestLoadConfigFromEnv_CleanupInterval(t *testing.T) {
+	for _, interval := range []string{"0", "-1m", "soon"} {
+		t.Setenv("RATELIMIT_CLEANUP_INTERVAL", interval)
+		if config := LoadConfigFromEnv(); config.CleanupInterval != time.Minute {
+			t.Errorf("Expected the default interval for %q, got %v", interval, config.CleanupInterval)
+		}
+	}
+
+	t.Setenv("RATELIMIT_CLEANUP_INTERVAL", "30s")
+	if config := LoadConfigFromEnv(); config.CleanupInterval != 30*time.Second {
+		t.Errorf("Expected 30s, got %v", config.CleanupInterval)
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/ratelimit"
+)
+
+const (
+	StoreMemory   = "memory"
+	StoreDatabase = "database"
+)
+
+// Store keeps the rate limiting state of the identifiers
+type Store interface {
+	// Get returns the entry of a key, nil when missing
+	Get(ctx context.Context, key string) (*entities.RateLimitEntry, error)
+	// Update stores the entry fn returns for the entry of a key, atomically.
+	// fn receives nil when the key is missing and removes it by returning nil.
+	Update(ctx context.Context, key string, fn func(entry *entities.RateLimitEntry) *entities.RateLimitEntry) error
+	// Delete removes the entry of a key
+	Delete(ctx context.Context, key string) error
+	// List returns the entries whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]*entities.RateLimitEntry, error)
+	// DeleteExpired removes the entries expired before now
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// NewStore returns the store of the given kind, a new memory one when unknown
+func NewStore(db *database.Database, kind string) Store {
+	if kind == StoreDatabase {
+		return ratelimit.NewRepository(db)
+	}
+	return NewMemoryStore()
+}
+
+// MemoryStore keeps the rate limiting state in memory. It is lost on restart
+// and not shared between manager replicas.
+type MemoryStore struct {
+	entries map[string]entities.RateLimitEntry
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]entities.RateLimitEntry),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*entities.RateLimitEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.entries[key]
+	if !exists {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, key string, fn func(entry *entities.RateLimitEntry) *entities.RateLimitEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current *entities.RateLimitEntry
+	if entry, exists := s.entries[key]; exists {
+		current = &entry
+	}
+
+	next := fn(current)
+	if next == nil {
+		delete(s.entries, key)
+		return nil
+	}
+
+	next.Key = key
+	s.entries[key] = *next
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) List(_ context.Context, prefix string) ([]*entities.RateLimitEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*entities.RateLimitEntry
+	for key, entry := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, &entry)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	for key, entry := range s.entries {
+		if entry.ExpiresAt.Before(now) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
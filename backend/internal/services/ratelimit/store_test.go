@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *database.Database {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.RateLimit{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	return &database.Database{DB: db}
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		StoreMemory:   func(t *testing.T) Store { return NewMemoryStore() },
+		StoreDatabase: func(t *testing.T) Store { return NewStore(setupTestDB(t), StoreDatabase) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Now()
+
+			increment := func(entry *entities.RateLimitEntry) *entities.RateLimitEntry {
+				if entry == nil {
+					entry = &entities.RateLimitEntry{WindowStart: now}
+				}
+				entry.Count++
+				entry.ExpiresAt = now.Add(time.Minute)
+				return entry
+			}
+
+			for i := 0; i < 3; i++ {
+				if err := store.Update(ctx, "login_ip:10.0.0.1", increment); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := store.Update(ctx, "login_ip:10%", increment); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := store.Update(ctx, "api:10.0.0.1", func(*entities.RateLimitEntry) *entities.RateLimitEntry {
+				return &entities.RateLimitEntry{ExpiresAt: now.Add(-time.Minute)}
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entry, err := store.Get(ctx, "login_ip:10.0.0.1")
+			if err != nil || entry == nil {
+				t.Fatalf("expected the entry to exist, got %v (error %v)", entry, err)
+			}
+			if entry.Count != 3 {
+				t.Errorf("expected 3 attempts but got %d", entry.Count)
+			}
+
+			entries, err := store.List(ctx, "login_ip:")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != 2 {
+				t.Errorf("expected 2 entries under the policy but got %d", len(entries))
+			}
+
+			if entries, _ := store.List(ctx, "login_ip:10%"); len(entries) != 1 {
+				t.Errorf("expected the prefix to be matched literally, got %d entries", len(entries))
+			}
+
+			removed, err := store.DeleteExpired(ctx, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if removed != 1 {
+				t.Errorf("expected 1 expired entry to be removed but got %d", removed)
+			}
+
+			if err := store.Update(ctx, "login_ip:10.0.0.1", func(*entities.RateLimitEntry) *entities.RateLimitEntry { return nil }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry, _ := store.Get(ctx, "login_ip:10.0.0.1"); entry != nil {
+				t.Error("expected the entry to be removed")
+			}
+		})
+	}
+}
+
+func TestLimiters_DatabaseStoreIsShared(t *testing.T) {
+	db := setupTestDB(t)
+	config := LoadConfigFromEnv()
+	config.Store = StoreDatabase
+
+	// Two managers sharing the database see the same lockouts
+	first := NewLimitersWithConfig(db, config)
+	second := NewLimitersWithConfig(db, config)
+
+	for i := 0; i < config.LoginIP.MaxAttempts; i++ {
+		first.LoginIP.RecordAttempt("203.0.113.7")
+	}
+
+	if blocked, _ := second.LoginIP.IsBlocked("203.0.113.7"); !blocked {
+		t.Error("expected the lockout to be seen by the other limiters")
+	}
+
+	if blocked, _ := second.LoginAccount.IsBlocked("203.0.113.7"); blocked {
+		t.Error("expected the policies to be kept apart")
+	}
+
+	if err := second.LoginIP.Unblock("203.0.113.7"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if blocked, _ := first.LoginIP.IsBlocked("203.0.113.7"); blocked {
+		t.Error("expected the identifier to be unblocked")
+	}
+}
+
+func TestNewStore_MemoryStoresAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	first, second := NewStore(nil, StoreMemory), NewStore(nil, StoreMemory)
+
+	if err := first.Update(ctx, "login_ip:10.0.0.1", func(*entities.RateLimitEntry) *entities.RateLimitEntry {
+		return &entities.RateLimitEntry{Count: 1, ExpiresAt: time.Now().Add(time.Minute)}
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The limiters only share the state of the store they are given
+	if entry, err := second.Get(ctx, "login_ip:10.0.0.1"); err != nil || entry != nil {
+		t.Errorf("Expected no entry in another memory store, got %+v (%v)", entry, err)
+	}
+}
@@ -0,0 +1,26 @@
+package rule
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// minInterval is the shortest time allowed between two evaluations of a rule
+const minInterval = time.Minute
+
+// Config holds the rule engine settings
+type Config struct {
+	CheckInterval   time.Duration // time between two looks for rules due to run
+	DefaultInterval time.Duration // evaluation interval of rules created without one
+	AuditRetention  time.Duration // zero keeps the audit log forever
+}
+
+// LoadConfigFromEnv loads the rule engine configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		CheckInterval:   env.Get("RULES_CHECK_INTERVAL").Default("1m").ValuePositiveDuration(),
+		DefaultInterval: env.Get("RULES_DEFAULT_INTERVAL").Default("15m").ValuePositiveDuration(),
+		AuditRetention:  env.Get("RULES_AUDIT_RETENTION").Default("2160h").ValueDuration(),
+	}
+}
@@ -0,0 +1,413 @@
+package rule
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/pkg/errors"
+)
+
+type fieldKind int
+
+const (
+	kindNumber fieldKind = iota
+	kindText
+	kindList
+)
+
+var fieldKinds = map[string]fieldKind{
+	entities.RuleFieldName:         kindText,
+	entities.RuleFieldRatio:        kindNumber,
+	entities.RuleFieldSeedingTime:  kindNumber,
+	entities.RuleFieldState:        kindText,
+	entities.RuleFieldCategory:     kindText,
+	entities.RuleFieldTags:         kindList,
+	entities.RuleFieldSize:         kindNumber,
+	entities.RuleFieldProgress:     kindNumber,
+	entities.RuleFieldTracker:      kindText,
+	entities.RuleFieldSwarmSeeders: kindNumber,
+	entities.RuleFieldAgent:        kindText,
+}
+
+var kindOperators = map[fieldKind][]string{
+	kindNumber: {
+		entities.RuleOperatorEqual, entities.RuleOperatorNotEqual,
+		entities.RuleOperatorGreater, entities.RuleOperatorGreaterOrEq,
+		entities.RuleOperatorLess, entities.RuleOperatorLessOrEq,
+	},
+	kindText: {
+		entities.RuleOperatorEqual, entities.RuleOperatorNotEqual,
+		entities.RuleOperatorContains, entities.RuleOperatorNotContains,
+		entities.RuleOperatorIn, entities.RuleOperatorNotIn,
+		entities.RuleOperatorMatches,
+	},
+	kindList: {
+		entities.RuleOperatorContains, entities.RuleOperatorNotContains,
+		entities.RuleOperatorIn, entities.RuleOperatorNotIn,
+	},
+}
+
+// stoppedStates are the task states a stop action has nothing to do on
+var stoppedStates = []string{"STOPPED_UPLOAD", "STOPPED_DOWNLOAD", "PAUSED_UPLOAD", "PAUSED_DOWNLOAD"}
+
+// condition is a validated rule condition with its value parsed
+type condition struct {
+	field    string
+	kind     fieldKind
+	operator string
+	number   float64
+	text     string
+	values   []string
+	pattern  *regexp.Regexp
+}
+
+// compile validates the conditions and actions of a rule
+func compile(rule *entities.Rule) ([]condition, error) {
+	if len(rule.Conditions) == 0 {
+		return nil, invalid("a rule needs at least one condition")
+	}
+	if len(rule.Actions) == 0 {
+		return nil, invalid("a rule needs at least one action")
+	}
+
+	conditions := make([]condition, len(rule.Conditions))
+	for i, item := range rule.Conditions {
+		c, err := compileCondition(item)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		conditions[i] = c
+	}
+
+	for i, action := range rule.Actions {
+		if err := validateAction(action); err != nil {
+			return nil, fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if action.Type == entities.RuleActionDelete && i != len(rule.Actions)-1 {
+			return nil, fmt.Errorf("action %d: %w", i+1, invalid("delete must be the last action"))
+		}
+	}
+
+	return conditions, nil
+}
+
+func compileCondition(item entities.RuleCondition) (condition, error) {
+	kind, ok := fieldKinds[item.Field]
+	if !ok {
+		return condition{}, invalid("unknown field %q", item.Field)
+	}
+	if !slices.Contains(kindOperators[kind], item.Operator) {
+		return condition{}, invalid("operator %q does not apply to field %q", item.Operator, item.Field)
+	}
+
+	c := condition{field: item.Field, kind: kind, operator: item.Operator}
+
+	if item.Operator == entities.RuleOperatorIn || item.Operator == entities.RuleOperatorNotIn {
+		if len(item.Values) == 0 {
+			return condition{}, invalid("operator %q needs values", item.Operator)
+		}
+		c.values = item.Values
+		return c, nil
+	}
+
+	if strings.TrimSpace(item.Value) == "" {
+		return condition{}, invalid("operator %q needs a value", item.Operator)
+	}
+
+	switch {
+	case kind == kindNumber:
+		number, err := parseNumber(item.Field, item.Value)
+		if err != nil {
+			return condition{}, err
+		}
+		c.number = number
+	case item.Operator == entities.RuleOperatorMatches:
+		pattern, err := regexp.Compile(item.Value)
+		if err != nil {
+			return condition{}, invalid("invalid pattern: %v", err)
+		}
+		c.pattern = pattern
+	default:
+		c.text = item.Value
+	}
+
+	return c, nil
+}
+
+// matches tells whether a task satisfies every condition
+func matches(conditions []condition, task *entities.Task) bool {
+	for _, c := range conditions {
+		if !c.match(task) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) match(task *entities.Task) bool {
+	switch c.kind {
+	case kindNumber:
+		return c.compare(numberValue(task, c.field))
+	case kindList:
+		return c.matchList(task.Tags)
+	default:
+		return c.matchText(textValues(task, c.field))
+	}
+}
+
+func (c condition) compare(value float64) bool {
+	switch c.operator {
+	case entities.RuleOperatorEqual:
+		return value == c.number
+	case entities.RuleOperatorNotEqual:
+		return value != c.number
+	case entities.RuleOperatorGreater:
+		return value > c.number
+	case entities.RuleOperatorGreaterOrEq:
+		return value >= c.number
+	case entities.RuleOperatorLess:
+		return value < c.number
+	case entities.RuleOperatorLessOrEq:
+		return value <= c.number
+	}
+	return false
+}
+
+// matchText tests the values of a text field, any of them matching is enough.
+// Negated operators hold when none of them matches.
+func (c condition) matchText(values []string) bool {
+	anyOf := func(fn func(value string) bool) bool {
+		return slices.ContainsFunc(values, fn)
+	}
+
+	switch c.operator {
+	case entities.RuleOperatorEqual:
+		return anyOf(func(value string) bool { return strings.EqualFold(value, c.text) })
+	case entities.RuleOperatorNotEqual:
+		return !anyOf(func(value string) bool { return strings.EqualFold(value, c.text) })
+	case entities.RuleOperatorContains:
+		return anyOf(func(value string) bool { return containsFold(value, c.text) })
+	case entities.RuleOperatorNotContains:
+		return !anyOf(func(value string) bool { return containsFold(value, c.text) })
+	case entities.RuleOperatorIn:
+		return anyOf(func(value string) bool { return inFold(c.values, value) })
+	case entities.RuleOperatorNotIn:
+		return !anyOf(func(value string) bool { return inFold(c.values, value) })
+	case entities.RuleOperatorMatches:
+		return anyOf(c.pattern.MatchString)
+	}
+	return false
+}
+
+// matchList tests the tags of a task: contains looks for one tag, in for any of several
+func (c condition) matchList(tags []string) bool {
+	switch c.operator {
+	case entities.RuleOperatorContains:
+		return inFold(tags, c.text)
+	case entities.RuleOperatorNotContains:
+		return !inFold(tags, c.text)
+	case entities.RuleOperatorIn:
+		return slices.ContainsFunc(c.values, func(value string) bool { return inFold(tags, value) })
+	case entities.RuleOperatorNotIn:
+		return !slices.ContainsFunc(c.values, func(value string) bool { return inFold(tags, value) })
+	}
+	return false
+}
+
+func numberValue(task *entities.Task, field string) float64 {
+	switch field {
+	case entities.RuleFieldRatio:
+		return task.Ratio
+	case entities.RuleFieldSeedingTime:
+		return float64(task.SeedingTime)
+	case entities.RuleFieldSize:
+		return float64(task.Size)
+	case entities.RuleFieldProgress:
+		return task.Progress
+	case entities.RuleFieldSwarmSeeders:
+		return float64(task.Pairs.SwarmSeeders)
+	}
+	return 0
+}
+
+func textValues(task *entities.Task, field string) []string {
+	switch field {
+	case entities.RuleFieldName:
+		return []string{task.Name}
+	case entities.RuleFieldState:
+		return []string{task.State}
+	case entities.RuleFieldCategory:
+		return []string{task.Category}
+	case entities.RuleFieldTracker:
+		// the current tracker first, then the ones of the magnet link
+		var values []string
+		if task.Tracker != "" {
+			values = append(values, task.Tracker)
+		}
+		return append(values, task.MagnetLink.Trackers...)
+	case entities.RuleFieldAgent:
+		if task.Agent == nil {
+			return nil
+		}
+		return []string{task.Agent.UUID.String(), task.Agent.Name}
+	}
+	return nil
+}
+
+// parseNumber reads a condition value: seeding times accept durations such as
+// "36h" or "7d" and sizes units such as "1.5GB", both default to seconds and bytes
+func parseNumber(field, value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	switch field {
+	case entities.RuleFieldSeedingTime:
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return seconds, nil
+		}
+		duration, err := parseDuration(value)
+		if err != nil {
+			return 0, invalid("invalid seeding time %q", value)
+		}
+		return duration.Seconds(), nil
+	case entities.RuleFieldSize:
+		size, err := parseSize(value)
+		if err != nil {
+			return 0, invalid("invalid size %q", value)
+		}
+		return size, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, invalid("invalid number %q", value)
+	}
+	return number, nil
+}
+
+// parseDuration parses a Go duration, or a number of days such as "7d"
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	// longest suffixes first, units are binary as in the qBittorrent UI
+	{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"B", 1},
+}
+
+func parseSize(value string) (float64, error) {
+	upper := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+
+	factor := 1.0
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			upper, factor = number, unit.factor
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(upper, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return number * factor, nil
+}
+
+func validateAction(action entities.RuleAction) error {
+	switch action.Type {
+	case entities.RuleActionStop, entities.RuleActionDelete, entities.RuleActionReannounce,
+		entities.RuleActionRetag, entities.RuleActionRecategorize:
+		return nil
+	case entities.RuleActionMove:
+		if strings.TrimSpace(action.Location) == "" {
+			return invalid("move needs a location")
+		}
+		return nil
+	case entities.RuleActionSetShareLimit:
+		if action.RatioLimit < 0 || action.SeedingTimeLimit < 0 {
+			return invalid("share limits cannot be negative")
+		}
+		return nil
+	case entities.RuleActionSetDownloadLimit, entities.RuleActionSetUploadLimit:
+		if action.Limit < 0 {
+			return invalid("limit cannot be negative")
+		}
+		return nil
+	}
+	return invalid("unknown action %q", action.Type)
+}
+
+// describe explains what an action would do to a task. The action is skipped
+// when the task is already in the state it leads to.
+func describe(action entities.RuleAction, task *entities.Task) (detail string, skip bool) {
+	switch action.Type {
+	case entities.RuleActionStop:
+		return "", slices.Contains(stoppedStates, task.State)
+	case entities.RuleActionDelete:
+		if action.DeleteFiles {
+			return "with files", false
+		}
+		return "keeping files", false
+	case entities.RuleActionMove:
+		if path.Clean(task.Path) == path.Clean(action.Location) {
+			return "", true
+		}
+		return fmt.Sprintf("from %s to %s", task.Path, action.Location), false
+	case entities.RuleActionRetag:
+		if sameTags(task.Tags, action.Tags) {
+			return "", true
+		}
+		return fmt.Sprintf("from [%s] to [%s]", strings.Join(task.Tags, ", "), strings.Join(action.Tags, ", ")), false
+	case entities.RuleActionRecategorize:
+		if task.Category == action.Category {
+			return "", true
+		}
+		return fmt.Sprintf("from %q to %q", task.Category, action.Category), false
+	case entities.RuleActionSetShareLimit:
+		return fmt.Sprintf("ratio %g, seeding time %d minutes", action.RatioLimit, action.SeedingTimeLimit), false
+	case entities.RuleActionSetDownloadLimit, entities.RuleActionSetUploadLimit:
+		return fmt.Sprintf("%d B/s", action.Limit), false
+	}
+	return "", false
+}
+
+func sameTags(current, wanted []string) bool {
+	if len(current) != len(wanted) {
+		return false
+	}
+	for _, tag := range wanted {
+		if !inFold(current, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func inFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(item string) bool { return strings.EqualFold(item, value) })
+}
+
+func containsFold(value, substr string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errors.ErrInvalidInput, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,159 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
+)
+
+func newRule(conditions []entities.RuleCondition, actions ...entities.RuleAction) *entities.Rule {
+	if len(actions) == 0 {
+		actions = []entities.RuleAction{{Type: entities.RuleActionStop}}
+	}
+	return &entities.Rule{Name: "test", Conditions: conditions, Actions: actions}
+}
+
+func TestCompile_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *entities.Rule
+	}{
+		{"no conditions", newRule(nil)},
+		{"no actions", &entities.Rule{Conditions: []entities.RuleCondition{{Field: "ratio", Operator: "gt", Value: "1"}}}},
+		{"unknown field", newRule([]entities.RuleCondition{{Field: "color", Operator: "eq", Value: "red"}})},
+		{"operator of another kind", newRule([]entities.RuleCondition{{Field: "ratio", Operator: "contains", Value: "1"}})},
+		{"invalid number", newRule([]entities.RuleCondition{{Field: "ratio", Operator: "gt", Value: "high"}})},
+		{"invalid size", newRule([]entities.RuleCondition{{Field: "size", Operator: "gt", Value: "10XB"}})},
+		{"invalid regexp", newRule([]entities.RuleCondition{{Field: "name", Operator: "matches", Value: "("}})},
+		{"in without values", newRule([]entities.RuleCondition{{Field: "state", Operator: "in"}})},
+		{"move without location", newRule(
+			[]entities.RuleCondition{{Field: "ratio", Operator: "gt", Value: "1"}},
+			entities.RuleAction{Type: entities.RuleActionMove},
+		)},
+		{"action after delete", newRule(
+			[]entities.RuleCondition{{Field: "ratio", Operator: "gt", Value: "1"}},
+			entities.RuleAction{Type: entities.RuleActionDelete},
+			entities.RuleAction{Type: entities.RuleActionStop},
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.rule)
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if !errors.Is(err, errors.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		field    string
+		value    string
+		expected float64
+	}{
+		{entities.RuleFieldRatio, "1.5", 1.5},
+		{entities.RuleFieldSeedingTime, "3600", 3600},
+		{entities.RuleFieldSeedingTime, "36h", 36 * 3600},
+		{entities.RuleFieldSeedingTime, "7d", 7 * 24 * 3600},
+		{entities.RuleFieldSize, "512", 512},
+		{entities.RuleFieldSize, "1KB", 1024},
+		{entities.RuleFieldSize, "1.5 GiB", 1.5 * 1024 * 1024 * 1024},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNumber(tt.field, tt.value)
+		if err != nil {
+			t.Errorf("parseNumber(%q, %q) returned error: %v", tt.field, tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseNumber(%q, %q): expected %v, got %v", tt.field, tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	agent := &entities.Agent{UUID: uuid.New(), Name: "seedbox"}
+	task := &entities.Task{
+		ID:          "abc",
+		Agent:       agent,
+		Name:        "Some.Linux.ISO",
+		State:       "STOPPED_UPLOAD",
+		Category:    "Linux",
+		Size:        2 * 1024 * 1024 * 1024,
+		Ratio:       2.5,
+		Tags:        []string{"seeded", "iso"},
+		SeedingTime: int((10 * 24 * time.Hour).Seconds()),
+		Tracker:     "https://tracker.example.org/announce",
+		Pairs:       entities.TaskPairs{SwarmSeeders: 40},
+	}
+
+	tests := []struct {
+		name      string
+		condition entities.RuleCondition
+		expected  bool
+	}{
+		{"ratio gte", entities.RuleCondition{Field: "ratio", Operator: "gte", Value: "2.5"}, true},
+		{"ratio lt", entities.RuleCondition{Field: "ratio", Operator: "lt", Value: "2"}, false},
+		{"seeding time in days", entities.RuleCondition{Field: "seeding_time", Operator: "gt", Value: "7d"}, true},
+		{"size with unit", entities.RuleCondition{Field: "size", Operator: "gt", Value: "1GB"}, true},
+		{"swarm seeders", entities.RuleCondition{Field: "swarm_seeders", Operator: "lte", Value: "10"}, false},
+		{"category ignores case", entities.RuleCondition{Field: "category", Operator: "eq", Value: "linux"}, true},
+		{"state in", entities.RuleCondition{Field: "state", Operator: "in", Values: []string{"UPLOADING", "STOPPED_UPLOAD"}}, true},
+		{"state not in", entities.RuleCondition{Field: "state", Operator: "not_in", Values: []string{"STOPPED_UPLOAD"}}, false},
+		{"tracker contains", entities.RuleCondition{Field: "tracker", Operator: "contains", Value: "example.org"}, true},
+		{"tags contains", entities.RuleCondition{Field: "tags", Operator: "contains", Value: "iso"}, true},
+		{"tags not contains", entities.RuleCondition{Field: "tags", Operator: "not_contains", Value: "keep"}, true},
+		{"name matches", entities.RuleCondition{Field: "name", Operator: "matches", Value: `(?i)linux\.iso$`}, true},
+		{"agent by name", entities.RuleCondition{Field: "agent", Operator: "eq", Value: "seedbox"}, true},
+		{"agent by uuid", entities.RuleCondition{Field: "agent", Operator: "neq", Value: agent.UUID.String()}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, err := compile(newRule([]entities.RuleCondition{tt.condition}))
+			if err != nil {
+				t.Fatalf("Failed to compile rule: %v", err)
+			}
+			if got := matches(conditions, task); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDescribe_SkipsNoOps(t *testing.T) {
+	task := &entities.Task{
+		State:    "STOPPED_UPLOAD",
+		Path:     "/data/done/",
+		Category: "tv",
+		Tags:     []string{"a", "b"},
+	}
+
+	tests := []struct {
+		action   entities.RuleAction
+		expected bool
+	}{
+		{entities.RuleAction{Type: entities.RuleActionStop}, true},
+		{entities.RuleAction{Type: entities.RuleActionMove, Location: "/data/done"}, true},
+		{entities.RuleAction{Type: entities.RuleActionMove, Location: "/data/archive"}, false},
+		{entities.RuleAction{Type: entities.RuleActionRetag, Tags: []string{"b", "a"}}, true},
+		{entities.RuleAction{Type: entities.RuleActionRetag, Tags: []string{"a"}}, false},
+		{entities.RuleAction{Type: entities.RuleActionRecategorize, Category: "tv"}, true},
+		{entities.RuleAction{Type: entities.RuleActionDelete}, false},
+	}
+
+	for _, tt := range tests {
+		if _, skip := describe(tt.action, task); skip != tt.expected {
+			t.Errorf("describe(%+v): expected skip %v, got %v", tt.action, tt.expected, skip)
+		}
+	}
+}
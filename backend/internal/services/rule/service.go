@@ -0,0 +1,485 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/agent"
+	"github.com/gardarr/gardarr/internal/repository/rule"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/google/uuid"
+)
+
+// Executor lists the agent tasks and applies the rule actions to them
+type Executor interface {
+	ListAgents() ([]*entities.Agent, error)
+	ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error)
+	StopAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error
+	DeleteAgentTask(ctx context.Context, agent *entities.Agent, taskID string, purge bool) error
+	SetAgentTaskLocation(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetLocationSchema) error
+	SetAgentTaskTags(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetTagsSchema) error
+	SetAgentTaskCategory(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetCategorySchema) error
+	SetAgentTaskShareLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetShareLimitSchema) error
+	SetAgentTaskDownloadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetDownloadLimitSchema) error
+	SetAgentTaskUploadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetUploadLimitSchema) error
+	ForceReannounceAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error
+}
+
+// Service stores the automation rules, evaluates them on schedule against the
+// tasks of every agent and keeps an audit log of the actions applied
+type Service struct {
+	repository *rule.Repository
+	executor   Executor
+	config     Config
+
+	// mu serializes evaluations so two runs never act on the same task at once
+	mu sync.Mutex
+	// applied remembers the limits already set, the client does not report them
+	applied map[appliedKey]time.Time
+}
+
+// appliedKey identifies an action of a rule on a task
+type appliedKey struct {
+	rule   uuid.UUID
+	agent  uuid.UUID
+	task   string
+	action int
+}
+
+// agentTasks are the tasks of an agent at evaluation time
+type agentTasks struct {
+	agent *entities.Agent
+	tasks []*entities.Task
+}
+
+// NewService creates a service acting on the tasks through the agent
+// repository of the manager, and so through the pooled agent clients
+func NewService(db *database.Database, agents *agent.Repository) *Service {
+	return NewWithExecutor(rule.NewRepository(db), agents, LoadConfigFromEnv())
+}
+
+// NewWithExecutor creates a service applying the actions through the given executor
+func NewWithExecutor(repository *rule.Repository, executor Executor, config Config) *Service {
+	return &Service{
+		repository: repository,
+		executor:   executor,
+		config:     config,
+		applied:    make(map[appliedKey]time.Time),
+	}
+}
+
+// CreateRule validates and stores a new rule
+func (s *Service) CreateRule(ctx context.Context, schema schemas.RuleCreateSchema) (*entities.Rule, error) {
+	r, err := s.fromSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.CreateRule(ctx, *r)
+}
+
+// ListRules retrieves every rule
+func (s *Service) ListRules(ctx context.Context) ([]*entities.Rule, error) {
+	return s.repository.ListRules(ctx)
+}
+
+// GetRule retrieves a rule by its UUID
+func (s *Service) GetRule(ctx context.Context, ruleID string) (*entities.Rule, error) {
+	uid, err := parseUUID(ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetRule(ctx, uid)
+}
+
+// UpdateRule changes the provided fields of a rule
+func (s *Service) UpdateRule(ctx context.Context, ruleID string, schema schemas.RuleUpdateSchema) (*entities.Rule, error) {
+	r, err := s.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema.Name != nil {
+		r.Name = *schema.Name
+	}
+	if schema.Enabled != nil {
+		r.Enabled = *schema.Enabled
+	}
+	if schema.DryRun != nil {
+		r.DryRun = *schema.DryRun
+	}
+	if schema.Interval != nil {
+		if r.Interval, err = s.parseInterval(*schema.Interval); err != nil {
+			return nil, err
+		}
+	}
+	if schema.Conditions != nil {
+		r.Conditions = toConditions(schema.Conditions)
+	}
+	if schema.Actions != nil {
+		r.Actions = toActions(schema.Actions)
+	}
+
+	if _, err := compile(r); err != nil {
+		return nil, err
+	}
+
+	return s.repository.UpdateRule(ctx, *r)
+}
+
+// DeleteRule removes a rule, its audit entries are kept
+func (s *Service) DeleteRule(ctx context.Context, ruleID string) error {
+	uid, err := parseUUID(ruleID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.DeleteRule(ctx, uid)
+}
+
+// ListExecutions retrieves the audit log, optionally for one rule and one agent
+func (s *Service) ListExecutions(ctx context.Context, ruleID, agentID string, limit int) ([]*entities.RuleExecution, error) {
+	filter := rule.ExecutionFilter{Limit: limit}
+
+	if ruleID != "" {
+		uid, err := parseUUID(ruleID)
+		if err != nil {
+			return nil, err
+		}
+		filter.RuleUUID = uid
+	}
+	if agentID != "" {
+		uid, err := parseUUID(agentID)
+		if err != nil {
+			return nil, err
+		}
+		filter.AgentUUID = uid
+	}
+
+	return s.repository.ListExecutions(ctx, filter)
+}
+
+// Preview reports what an unsaved rule would do to the current tasks
+func (s *Service) Preview(ctx context.Context, schema schemas.RuleCreateSchema) ([]*entities.RuleExecution, error) {
+	r, err := s.fromSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.dryRun(ctx, r)
+}
+
+// DryRun reports what a rule would do to the current tasks, without recording it
+func (s *Service) DryRun(ctx context.Context, ruleID string) ([]*entities.RuleExecution, error) {
+	r, err := s.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.dryRun(ctx, r)
+}
+
+func (s *Service) dryRun(ctx context.Context, r *entities.Rule) ([]*entities.RuleExecution, error) {
+	conditions, err := compile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.evaluate(ctx, r, conditions, s.listTasks(ctx), time.Now(), false, nil), nil
+}
+
+// RunRule evaluates a rule now, whatever its schedule. A rule in dry-run mode only records its plan.
+func (s *Service) RunRule(ctx context.Context, ruleID string) ([]*entities.RuleExecution, error) {
+	r, err := s.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := compile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	executions := s.evaluate(ctx, r, conditions, s.listTasks(ctx), now, !r.DryRun, nil)
+	if err := s.record(ctx, r, executions, now); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
+
+// Run evaluates the rules due on each check interval until ctx is canceled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Evaluate(ctx, now)
+
+			if s.config.AuditRetention > 0 {
+				if err := s.repository.DeleteExecutionsBefore(ctx, now.Add(-s.config.AuditRetention)); err != nil {
+					log.Printf("rules: failed to prune audit log: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Evaluate runs every enabled rule whose interval elapsed since its last run
+func (s *Service) Evaluate(ctx context.Context, now time.Time) {
+	rules, err := s.repository.ListRules(ctx)
+	if err != nil {
+		log.Printf("rules: failed to list rules: %v", err)
+		return
+	}
+
+	var due []*entities.Rule
+	for _, r := range rules {
+		if r.Enabled && (r.LastRunAt == nil || !now.Before(r.LastRunAt.Add(r.Interval))) {
+			due = append(due, r)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := s.listTasks(ctx)
+	// tasks deleted by a rule are not offered to the following ones
+	deleted := make(map[string]bool)
+
+	for _, r := range due {
+		conditions, err := compile(r)
+		if err != nil {
+			log.Printf("rules: skipping invalid rule %s: %v", r.Name, err)
+			continue
+		}
+
+		executions := s.evaluate(ctx, r, conditions, tasks, now, !r.DryRun, deleted)
+		if err := s.record(ctx, r, executions, now); err != nil {
+			log.Printf("rules: failed to record run of rule %s: %v", r.Name, err)
+		}
+	}
+}
+
+// evaluate applies, or only plans when execute is false, the actions of a rule
+// to the matching tasks. The caller must hold the lock.
+func (s *Service) evaluate(ctx context.Context, r *entities.Rule, conditions []condition, tasks []agentTasks, now time.Time, execute bool, deleted map[string]bool) []*entities.RuleExecution {
+	var executions []*entities.RuleExecution
+
+	for _, group := range tasks {
+		for _, task := range group.tasks {
+			key := group.agent.UUID.String() + ":" + task.ID
+			if deleted[key] || !matches(conditions, task) {
+				continue
+			}
+
+			for i, action := range r.Actions {
+				detail, skip := describe(action, task)
+				if skip || s.alreadyApplied(r, group.agent, task, i) {
+					continue
+				}
+
+				execution := &entities.RuleExecution{
+					RuleUUID:  r.UUID,
+					RuleName:  r.Name,
+					AgentUUID: group.agent.UUID,
+					AgentName: group.agent.Name,
+					TaskHash:  task.ID,
+					TaskName:  task.Name,
+					Action:    action.Type,
+					Detail:    detail,
+					DryRun:    !execute,
+					CreatedAt: now,
+				}
+				executions = append(executions, execution)
+
+				if !execute {
+					continue
+				}
+
+				if err := s.apply(ctx, group.agent, task.ID, action); err != nil {
+					// the next actions may depend on this one
+					execution.Error = err.Error()
+					break
+				}
+
+				switch action.Type {
+				case entities.RuleActionDelete:
+					if deleted != nil {
+						deleted[key] = true
+					}
+				case entities.RuleActionSetShareLimit, entities.RuleActionSetDownloadLimit, entities.RuleActionSetUploadLimit:
+					s.applied[appliedKey{r.UUID, group.agent.UUID, task.ID, i}] = now
+				}
+			}
+		}
+	}
+
+	return executions
+}
+
+// alreadyApplied tells whether a limit was already set on the task by the current version of the rule
+func (s *Service) alreadyApplied(r *entities.Rule, a *entities.Agent, task *entities.Task, action int) bool {
+	at, ok := s.applied[appliedKey{r.UUID, a.UUID, task.ID, action}]
+	return ok && at.After(r.UpdatedAt)
+}
+
+func (s *Service) apply(ctx context.Context, a *entities.Agent, taskID string, action entities.RuleAction) error {
+	switch action.Type {
+	case entities.RuleActionStop:
+		return s.executor.StopAgentTask(ctx, a, taskID)
+	case entities.RuleActionDelete:
+		return s.executor.DeleteAgentTask(ctx, a, taskID, action.DeleteFiles)
+	case entities.RuleActionMove:
+		return s.executor.SetAgentTaskLocation(ctx, a, taskID, schemas.TaskSetLocationSchema{Location: action.Location})
+	case entities.RuleActionRetag:
+		return s.executor.SetAgentTaskTags(ctx, a, taskID, schemas.TaskSetTagsSchema{Tags: action.Tags})
+	case entities.RuleActionRecategorize:
+		return s.executor.SetAgentTaskCategory(ctx, a, taskID, schemas.TaskSetCategorySchema{Category: action.Category})
+	case entities.RuleActionSetShareLimit:
+		return s.executor.SetAgentTaskShareLimit(ctx, a, taskID, schemas.TaskSetShareLimitSchema{
+			RatioLimit:       action.RatioLimit,
+			SeedingTimeLimit: action.SeedingTimeLimit,
+		})
+	case entities.RuleActionSetDownloadLimit:
+		return s.executor.SetAgentTaskDownloadLimit(ctx, a, taskID, schemas.TaskSetDownloadLimitSchema{Limit: action.Limit})
+	case entities.RuleActionSetUploadLimit:
+		return s.executor.SetAgentTaskUploadLimit(ctx, a, taskID, schemas.TaskSetUploadLimitSchema{Limit: action.Limit})
+	case entities.RuleActionReannounce:
+		return s.executor.ForceReannounceAgentTask(ctx, a, taskID)
+	}
+	return fmt.Errorf("unknown action %q", action.Type)
+}
+
+// record stores the executions of a run in the audit log and marks the rule as run
+func (s *Service) record(ctx context.Context, r *entities.Rule, executions []*entities.RuleExecution, now time.Time) error {
+	if err := s.repository.CreateExecutions(ctx, executions); err != nil {
+		return err
+	}
+
+	return s.repository.SetRuleLastRun(ctx, r.UUID, now)
+}
+
+// listTasks loads the tasks of every agent, agents that cannot be reached are skipped
+func (s *Service) listTasks(ctx context.Context) []agentTasks {
+	agents, err := s.executor.ListAgents()
+	if err != nil {
+		log.Printf("rules: failed to list agents: %v", err)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	result := make([]agentTasks, len(agents))
+	for i, a := range agents {
+		wg.Add(1)
+		go func(i int, a *entities.Agent) {
+			defer wg.Done()
+
+			tasks, err := s.executor.ListAgentTasks(ctx, a)
+			if err != nil {
+				log.Printf("rules: failed to list tasks of agent %s: %v", a.Name, err)
+			}
+			result[i] = agentTasks{agent: a, tasks: tasks}
+		}(i, a)
+	}
+	wg.Wait()
+
+	return result
+}
+
+func (s *Service) fromSchema(schema schemas.RuleCreateSchema) (*entities.Rule, error) {
+	interval, err := s.parseInterval(schema.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &entities.Rule{
+		Name:       strings.TrimSpace(schema.Name),
+		Enabled:    schema.Enabled == nil || *schema.Enabled,
+		DryRun:     schema.DryRun,
+		Interval:   interval,
+		Conditions: toConditions(schema.Conditions),
+		Actions:    toActions(schema.Actions),
+	}
+
+	if _, err := compile(r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (s *Service) parseInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return s.config.DefaultInterval, nil
+	}
+
+	interval, err := parseDuration(value)
+	if err != nil {
+		return 0, invalid("invalid interval %q", value)
+	}
+	if interval < minInterval {
+		return 0, invalid("interval must be at least %s", minInterval)
+	}
+
+	return interval, nil
+}
+
+func toConditions(items []schemas.RuleConditionSchema) []entities.RuleCondition {
+	result := make([]entities.RuleCondition, len(items))
+	for i, item := range items {
+		result[i] = entities.RuleCondition{
+			Field:    item.Field,
+			Operator: item.Operator,
+			Value:    item.Value,
+			Values:   item.Values,
+		}
+	}
+	return result
+}
+
+func toActions(items []schemas.RuleActionSchema) []entities.RuleAction {
+	result := make([]entities.RuleAction, len(items))
+	for i, item := range items {
+		result[i] = entities.RuleAction{
+			Type:             item.Type,
+			DeleteFiles:      item.DeleteFiles,
+			Location:         item.Location,
+			Tags:             item.Tags,
+			Category:         item.Category,
+			RatioLimit:       item.RatioLimit,
+			SeedingTimeLimit: item.SeedingTimeLimit,
+			Limit:            item.Limit,
+		}
+	}
+	return result
+}
+
+func parseUUID(value string) (uuid.UUID, error) {
+	uid, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid UUID format: %w", err)
+	}
+	return uid, nil
+}
@@ -0,0 +1,303 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/rule"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeExecutor serves the tasks of a single agent and records the actions applied
+type fakeExecutor struct {
+	mu    sync.Mutex
+	agent *entities.Agent
+	tasks []*entities.Task
+	calls []string
+	fail  map[string]error
+}
+
+func (f *fakeExecutor) ListAgents() ([]*entities.Agent, error) {
+	return []*entities.Agent{f.agent}, nil
+}
+
+func (f *fakeExecutor) ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*entities.Task(nil), f.tasks...), nil
+}
+
+func (f *fakeExecutor) record(action, taskID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, action+":"+taskID)
+	return f.fail[action]
+}
+
+func (f *fakeExecutor) StopAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	return f.record("stop", taskID)
+}
+
+func (f *fakeExecutor) DeleteAgentTask(ctx context.Context, agent *entities.Agent, taskID string, purge bool) error {
+	if err := f.record("delete", taskID); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, task := range f.tasks {
+		if task.ID == taskID {
+			f.tasks = append(f.tasks[:i], f.tasks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeExecutor) SetAgentTaskLocation(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetLocationSchema) error {
+	return f.record("move", taskID)
+}
+
+func (f *fakeExecutor) SetAgentTaskTags(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetTagsSchema) error {
+	return f.record("retag", taskID)
+}
+
+func (f *fakeExecutor) SetAgentTaskCategory(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetCategorySchema) error {
+	return f.record("recategorize", taskID)
+}
+
+func (f *fakeExecutor) SetAgentTaskShareLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetShareLimitSchema) error {
+	return f.record("set_share_limit", taskID)
+}
+
+func (f *fakeExecutor) SetAgentTaskDownloadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetDownloadLimitSchema) error {
+	return f.record("set_download_limit", taskID)
+}
+
+func (f *fakeExecutor) SetAgentTaskUploadLimit(ctx context.Context, agent *entities.Agent, taskID string, schema schemas.TaskSetUploadLimitSchema) error {
+	return f.record("set_upload_limit", taskID)
+}
+
+func (f *fakeExecutor) ForceReannounceAgentTask(ctx context.Context, agent *entities.Agent, taskID string) error {
+	return f.record("reannounce", taskID)
+}
+
+func setupService(t *testing.T) (*Service, *fakeExecutor) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.Rule{}, &models.RuleExecution{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	agent := &entities.Agent{UUID: uuid.New(), Name: "seedbox"}
+	executor := &fakeExecutor{
+		agent: agent,
+		tasks: []*entities.Task{
+			{ID: "done", Agent: agent, Name: "Done", State: "UPLOADING", Ratio: 3, Category: "tv"},
+			{ID: "young", Agent: agent, Name: "Young", State: "UPLOADING", Ratio: 0.5, Category: "tv"},
+		},
+	}
+
+	svc := NewWithExecutor(rule.NewRepository(&database.Database{DB: db}), executor, Config{
+		CheckInterval:   time.Minute,
+		DefaultInterval: 15 * time.Minute,
+	})
+
+	return svc, executor
+}
+
+func ratioRule(name string, dryRun bool, actions ...schemas.RuleActionSchema) schemas.RuleCreateSchema {
+	return schemas.RuleCreateSchema{
+		Name:       name,
+		DryRun:     dryRun,
+		Conditions: []schemas.RuleConditionSchema{{Field: "ratio", Operator: "gte", Value: "2"}},
+		Actions:    actions,
+	}
+}
+
+func TestService_CreateRuleDefaults(t *testing.T) {
+	svc, _ := setupService(t)
+
+	created, err := svc.CreateRule(context.Background(), ratioRule("seeded", false, schemas.RuleActionSchema{Type: "stop"}))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	if !created.Enabled {
+		t.Error("Expected rule to be enabled by default")
+	}
+	if created.Interval != 15*time.Minute {
+		t.Errorf("Expected default interval 15m, got %s", created.Interval)
+	}
+
+	if _, err := svc.CreateRule(context.Background(), ratioRule("seeded", false, schemas.RuleActionSchema{Type: "stop"})); err == nil {
+		t.Error("Expected an error for a duplicate name, got nil")
+	}
+
+	schema := ratioRule("too often", false, schemas.RuleActionSchema{Type: "stop"})
+	schema.Interval = "10s"
+	if _, err := svc.CreateRule(context.Background(), schema); err == nil {
+		t.Error("Expected an error for an interval below the minimum, got nil")
+	}
+}
+
+func TestService_DryRunDoesNotExecute(t *testing.T) {
+	svc, executor := setupService(t)
+	ctx := context.Background()
+
+	created, err := svc.CreateRule(ctx, ratioRule("seeded", false, schemas.RuleActionSchema{Type: "delete", DeleteFiles: true}))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	result, err := svc.DryRun(ctx, created.UUID.String())
+	if err != nil {
+		t.Fatalf("Failed to dry-run rule: %v", err)
+	}
+
+	if len(result) != 1 || result[0].TaskHash != "done" || !result[0].DryRun {
+		t.Errorf("Expected a dry-run delete of task done, got %+v", result)
+	}
+	if len(executor.calls) != 0 {
+		t.Errorf("Expected no action applied, got %v", executor.calls)
+	}
+
+	logged, err := svc.ListExecutions(ctx, created.UUID.String(), "", 0)
+	if err != nil {
+		t.Fatalf("Failed to list executions: %v", err)
+	}
+	if len(logged) != 0 {
+		t.Errorf("Expected dry-run not to be recorded, got %d entries", len(logged))
+	}
+}
+
+func TestService_RunRuleRecordsExecutions(t *testing.T) {
+	svc, executor := setupService(t)
+	ctx := context.Background()
+
+	created, err := svc.CreateRule(ctx, ratioRule("archive", false,
+		schemas.RuleActionSchema{Type: "recategorize", Category: "tv"},
+		schemas.RuleActionSchema{Type: "move", Location: "/archive"},
+		schemas.RuleActionSchema{Type: "stop"},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	if _, err := svc.RunRule(ctx, created.UUID.String()); err != nil {
+		t.Fatalf("Failed to run rule: %v", err)
+	}
+
+	// recategorize is a no-op as the task is already in tv
+	expected := []string{"move:done", "stop:done"}
+	if len(executor.calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, executor.calls)
+	}
+	for i := range expected {
+		if executor.calls[i] != expected[i] {
+			t.Errorf("Expected call %d to be %s, got %s", i, expected[i], executor.calls[i])
+		}
+	}
+
+	logged, err := svc.ListExecutions(ctx, "", executor.agent.UUID.String(), 0)
+	if err != nil {
+		t.Fatalf("Failed to list executions: %v", err)
+	}
+	if len(logged) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(logged))
+	}
+	for _, item := range logged {
+		if item.RuleUUID != created.UUID || item.DryRun || item.ID == uuid.Nil {
+			t.Errorf("Unexpected audit entry %+v", item)
+		}
+	}
+
+	stored, err := svc.GetRule(ctx, created.UUID.String())
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if stored.LastRunAt == nil {
+		t.Error("Expected last run time to be set")
+	}
+}
+
+func TestService_FailedActionStopsTheNextOnes(t *testing.T) {
+	svc, executor := setupService(t)
+	ctx := context.Background()
+	executor.fail = map[string]error{"move": errors.New("disk full")}
+
+	created, err := svc.CreateRule(ctx, ratioRule("archive", false,
+		schemas.RuleActionSchema{Type: "move", Location: "/archive"},
+		schemas.RuleActionSchema{Type: "delete"},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	result, err := svc.RunRule(ctx, created.UUID.String())
+	if err != nil {
+		t.Fatalf("Failed to run rule: %v", err)
+	}
+
+	if len(result) != 1 || result[0].Error != "disk full" {
+		t.Errorf("Expected a single failed move, got %+v", result)
+	}
+	if len(executor.calls) != 1 {
+		t.Errorf("Expected delete not to be applied, got %v", executor.calls)
+	}
+}
+
+func TestService_EvaluateSchedule(t *testing.T) {
+	svc, executor := setupService(t)
+	ctx := context.Background()
+
+	if _, err := svc.CreateRule(ctx, ratioRule("delete", false, schemas.RuleActionSchema{Type: "delete"})); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	// runs after the delete rule, it must not see the deleted task
+	limit := ratioRule("limit", false, schemas.RuleActionSchema{Type: "set_upload_limit", Limit: 1024})
+	limit.Conditions = []schemas.RuleConditionSchema{{Field: "category", Operator: "eq", Value: "tv"}}
+	if _, err := svc.CreateRule(ctx, limit); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	disabled := false
+	paused := ratioRule("paused", false, schemas.RuleActionSchema{Type: "reannounce"})
+	paused.Enabled = &disabled
+	if _, err := svc.CreateRule(ctx, paused); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	now := time.Now()
+	svc.Evaluate(ctx, now)
+
+	expected := []string{"delete:done", "set_upload_limit:young"}
+	if len(executor.calls) != len(expected) || executor.calls[0] != expected[0] || executor.calls[1] != expected[1] {
+		t.Fatalf("Expected calls %v, got %v", expected, executor.calls)
+	}
+
+	// not due yet
+	svc.Evaluate(ctx, now.Add(5*time.Minute))
+	if len(executor.calls) != 2 {
+		t.Errorf("Expected no new action before the interval elapsed, got %v", executor.calls)
+	}
+
+	// due again: the limit already set is not applied twice
+	svc.Evaluate(ctx, now.Add(15*time.Minute))
+	if len(executor.calls) != 2 {
+		t.Errorf("Expected the upload limit to be applied once, got %v", executor.calls)
+	}
+}
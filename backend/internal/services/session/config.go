@@ -0,0 +1,25 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the session lifetime settings
+type Config struct {
+	// IdleTTL is how long a session lasts without being used. Each use moves the
+	// expiry, up to MaxTTL after the login.
+	IdleTTL         time.Duration
+	MaxTTL          time.Duration // longest lifetime of a session, whatever its use
+	CleanupInterval time.Duration // time between two removals of the expired sessions
+}
+
+// LoadConfigFromEnv loads the session configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		IdleTTL:         env.Get("AUTH_SESSION_IDLE_TTL").Default("168h").ValueDuration(),
+		MaxTTL:          env.Get("AUTH_SESSION_MAX_TTL").Default("720h").ValueDuration(),
+		CleanupInterval: env.Get("AUTH_SESSION_CLEANUP_INTERVAL").Default("1h").ValuePositiveDuration(),
+	}
+}
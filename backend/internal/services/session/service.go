@@ -5,30 +5,51 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
 	"github.com/gardarr/gardarr/internal/repository/session"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
 	"github.com/google/uuid"
 )
 
 const (
-	sessionTokenLength = 32                 // 32 bytes = 256 bits
-	sessionDuration    = 7 * 24 * time.Hour // 7 days
+	sessionTokenLength = 32 // 32 bytes = 256 bits
+
+	// touchInterval bounds how often the last use of a session is written
+	touchInterval = time.Minute
 )
 
 type Service struct {
 	repository *session.Repository
+	config     Config
+	now        func() time.Time
 }
 
 func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with the idle and maximal lifetimes of the sessions
+func NewWithConfig(db *database.Database, config Config) *Service {
 	return &Service{
 		repository: session.NewRepository(db),
+		config:     config,
+		now:        time.Now,
 	}
 }
 
-// CreateSession creates a new session for a user
+// MaxAge returns the longest lifetime of a session, which session cookies are given
+func (s *Service) MaxAge() time.Duration {
+	return s.config.MaxTTL
+}
+
+// CreateSession creates a new session for a user. The returned session holds
+// the token itself, only its hash is stored.
 func (s *Service) CreateSession(ctx context.Context, userUUID uuid.UUID, userAgent, ipAddress string) (*entities.Session, error) {
 	// Generate secure random token
 	token, err := generateSessionToken()
@@ -36,51 +57,92 @@ func (s *Service) CreateSession(ctx context.Context, userUUID uuid.UUID, userAge
 		return nil, errors.New("failed to generate session token")
 	}
 
-	expiresAt := time.Now().Add(sessionDuration)
+	now := s.now()
+	sessionEntity, err := s.repository.CreateSession(ctx, userUUID, crypto.HashToken(token), userAgent, ipAddress, now, s.expiry(now, now))
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repository.CreateSession(ctx, userUUID, token, userAgent, ipAddress, expiresAt)
+	sessionEntity.Token = token
+	return sessionEntity, nil
 }
 
-// ValidateSession validates a session token and returns the session if valid
-func (s *Service) ValidateSession(ctx context.Context, token string) (*entities.User, *entities.Session, error) {
-	sessionModel, err := s.repository.GetSessionByToken(ctx, token)
+// ValidateSession validates a session token and returns the session if valid.
+// Using a session moves its expiry and records the address it was used from,
+// written at most once per touchInterval.
+func (s *Service) ValidateSession(ctx context.Context, token, ipAddress string) (*entities.User, *entities.Session, error) {
+	sessionModel, err := s.repository.GetSessionByTokenHash(ctx, crypto.HashToken(token))
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Check if session is expired
-	if sessionModel.IsExpired() {
+	now := s.now()
+	if s.expired(sessionModel, now) {
 		// Clean up expired session
-		_ = s.repository.DeleteSession(ctx, token)
+		_ = s.repository.DeleteSession(ctx, sessionModel.TokenHash)
 		return nil, nil, errors.New("session expired")
 	}
 
+	// Disabled users keep their sessions in the database but cannot use them
+	if sessionModel.User.Disabled {
+		return nil, nil, errors.New("user disabled")
+	}
+
+	if now.Sub(sessionModel.LastSeenAt) >= touchInterval {
+		expiresAt := s.expiry(sessionModel.CreatedAt, now)
+		if err := s.repository.TouchSession(ctx, sessionModel.ID, now, ipAddress, expiresAt); err == nil {
+			sessionModel.LastSeenAt = now
+			sessionModel.IPAddress = ipAddress
+			sessionModel.ExpiresAt = expiresAt
+		}
+	}
+
 	// Convert session to entity
 	sessionEntity := &entities.Session{
-		ID:        sessionModel.ID,
-		UserUUID:  sessionModel.UserUUID,
-		Token:     sessionModel.Token,
-		UserAgent: sessionModel.UserAgent,
-		IPAddress: sessionModel.IPAddress,
-		ExpiresAt: sessionModel.ExpiresAt,
-		CreatedAt: sessionModel.CreatedAt,
+		ID:         sessionModel.ID,
+		UserUUID:   sessionModel.UserUUID,
+		UserAgent:  sessionModel.UserAgent,
+		IPAddress:  sessionModel.IPAddress,
+		LastSeenAt: sessionModel.LastSeenAt,
+		ExpiresAt:  sessionModel.ExpiresAt,
+		CreatedAt:  sessionModel.CreatedAt,
+	}
+
+	return toUserEntity(sessionModel.User), sessionEntity, nil
+}
+
+// CheckSession returns the user of a session that is still valid, without
+// using it. Long-lived requests call it to notice a revoked session.
+func (s *Service) CheckSession(ctx context.Context, id uuid.UUID) (*entities.User, error) {
+	sessionModel, err := s.repository.GetSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert user to entity
-	userEntity := &entities.User{
-		UUID:      sessionModel.User.UUID,
-		Username:  sessionModel.User.Username,
-		Email:     sessionModel.User.Email,
-		CreatedAt: sessionModel.User.CreatedAt,
-		UpdatedAt: sessionModel.User.UpdatedAt,
+	if s.expired(sessionModel, s.now()) {
+		return nil, errors.New("session expired")
+	}
+	if sessionModel.User.Disabled {
+		return nil, errors.New("user disabled")
 	}
 
-	return userEntity, sessionEntity, nil
+	return toUserEntity(sessionModel.User), nil
 }
 
 // DeleteSession invalidates a session (logout)
 func (s *Service) DeleteSession(ctx context.Context, token string) error {
-	return s.repository.DeleteSession(ctx, token)
+	return s.repository.DeleteSession(ctx, crypto.HashToken(token))
+}
+
+// RevokeSession invalidates a session of a user by its ID
+func (s *Service) RevokeSession(ctx context.Context, userUUID uuid.UUID, id string) error {
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return pkgerrors.ErrInvalidUUID
+	}
+
+	return s.repository.DeleteSessionByID(ctx, userUUID, sessionID)
 }
 
 // DeleteUserSessions invalidates all sessions for a user (logout from all devices)
@@ -90,12 +152,61 @@ func (s *Service) DeleteUserSessions(ctx context.Context, userUUID uuid.UUID) er
 
 // GetUserSessions retrieves all active sessions for a user
 func (s *Service) GetUserSessions(ctx context.Context, userUUID uuid.UUID) ([]*entities.Session, error) {
-	return s.repository.GetUserSessions(ctx, userUUID)
+	return s.repository.GetUserSessions(ctx, userUUID, s.now())
+}
+
+// CleanupExpiredSessions removes all expired sessions and returns how many
+// were removed. Run calls it periodically.
+func (s *Service) CleanupExpiredSessions(ctx context.Context) (int64, error) {
+	return s.repository.DeleteExpiredSessions(ctx, s.now())
+}
+
+// Run removes the expired sessions on each cleanup interval until ctx is canceled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.CleanupExpiredSessions(ctx)
+			if err != nil {
+				log.Printf("sessions: failed to remove expired sessions: %v", err)
+			} else if removed > 0 {
+				log.Printf("sessions: removed %d expired sessions", removed)
+			}
+		}
+	}
+}
+
+// expiry returns when a session created at createdAt and last used at now
+// expires: IdleTTL after its last use, but no later than MaxTTL after its creation
+func (s *Service) expiry(createdAt, now time.Time) time.Time {
+	expiresAt := now.Add(s.config.IdleTTL)
+	if limit := createdAt.Add(s.config.MaxTTL); expiresAt.After(limit) {
+		return limit
+	}
+	return expiresAt
+}
+
+// expired tells whether a session is past its idle expiry or its maximum lifetime
+func (s *Service) expired(sessionModel *models.Session, now time.Time) bool {
+	return now.After(sessionModel.ExpiresAt) || now.After(sessionModel.CreatedAt.Add(s.config.MaxTTL))
 }
 
-// CleanupExpiredSessions removes all expired sessions (should be called periodically)
-func (s *Service) CleanupExpiredSessions(ctx context.Context) error {
-	return s.repository.DeleteExpiredSessions(ctx)
+// toUserEntity converts the user loaded with a session
+func toUserEntity(model models.User) *entities.User {
+	return &entities.User{
+		UUID:      model.UUID,
+		Username:  model.Username,
+		Email:     model.Email,
+		Role:      model.Role,
+		Disabled:  model.Disabled,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
 }
 
 // generateSessionToken generates a cryptographically secure random token
@@ -2,10 +2,15 @@ package session
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -86,7 +91,7 @@ func TestValidateSession(t *testing.T) {
 	}
 
 	// Validate session
-	user, sessionEntity, err := service.ValidateSession(ctx, session.Token)
+	user, sessionEntity, err := service.ValidateSession(ctx, session.Token, "192.168.1.1")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -104,7 +109,7 @@ func TestValidateSession(t *testing.T) {
 	}
 
 	// Test invalid token
-	_, _, err = service.ValidateSession(ctx, "invalid_token")
+	_, _, err = service.ValidateSession(ctx, "invalid_token", "192.168.1.1")
 	if err == nil {
 		t.Error("expected error for invalid token but got none")
 	}
@@ -138,7 +143,7 @@ func TestDeleteSession(t *testing.T) {
 	}
 
 	// Try to validate deleted session
-	_, _, err = service.ValidateSession(ctx, session.Token)
+	_, _, err = service.ValidateSession(ctx, session.Token, "192.168.1.1")
 	if err == nil {
 		t.Error("expected error for deleted session but got none")
 	}
@@ -170,8 +175,8 @@ func TestDeleteUserSessions(t *testing.T) {
 	}
 
 	// Validate both sessions should fail
-	_, _, err1 := service.ValidateSession(ctx, session1.Token)
-	_, _, err2 := service.ValidateSession(ctx, session2.Token)
+	_, _, err1 := service.ValidateSession(ctx, session1.Token, "192.168.1.1")
+	_, _, err2 := service.ValidateSession(ctx, session2.Token, "192.168.1.2")
 
 	if err1 == nil {
 		t.Error("expected error for deleted session 1 but got none")
@@ -231,3 +236,222 @@ func TestGenerateSessionToken(t *testing.T) {
 		t.Error("expected non-empty token")
 	}
 }
+
+func TestValidateSession_DisabledUser(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+
+	testUser := &models.User{
+		Email:        "test@example.com",
+		PasswordHash: "hash",
+		Salt:         "salt",
+		Role:         "operator",
+	}
+	if err := db.DB.Create(testUser).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	session, err := service.CreateSession(ctx, testUser.UUID, "Mozilla/5.0", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := db.DB.Model(testUser).Update("disabled", true).Error; err != nil {
+		t.Fatalf("failed to disable test user: %v", err)
+	}
+
+	if _, _, err := service.ValidateSession(ctx, session.Token, "192.168.1.1"); err == nil {
+		t.Error("expected the session of a disabled user to be rejected")
+	}
+}
+
+func createTestUser(t *testing.T, db *database.Database) *models.User {
+	testUser := &models.User{
+		Email:        "test@example.com",
+		PasswordHash: "hash",
+		Salt:         "salt",
+	}
+	if err := db.DB.Create(testUser).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return testUser
+}
+
+func TestCreateSession_StoresTokenHash(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	session, err := service.CreateSession(ctx, testUser.UUID, "Mozilla/5.0", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	var stored models.Session
+	if err := db.DB.First(&stored, "id = ?", session.ID).Error; err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+
+	if stored.TokenHash == session.Token || stored.TokenHash != crypto.HashToken(session.Token) {
+		t.Errorf("expected the SHA-256 of the token to be stored, got '%s'", stored.TokenHash)
+	}
+}
+
+func TestValidateSession_SlidingExpiry(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{IdleTTL: time.Hour, MaxTTL: 3 * time.Hour})
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	start := time.Now()
+	service.now = func() time.Time { return start }
+	session, err := service.CreateSession(ctx, testUser.UUID, "Mozilla/5.0", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	// Used again within the throttle interval, nothing is written
+	service.now = func() time.Time { return start.Add(30 * time.Second) }
+	_, sessionEntity, err := service.ValidateSession(ctx, session.Token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sessionEntity.LastSeenAt.Equal(session.LastSeenAt) || sessionEntity.IPAddress != "192.168.1.1" {
+		t.Errorf("expected no update within a minute, got last seen %v from %s", sessionEntity.LastSeenAt, sessionEntity.IPAddress)
+	}
+
+	// Each use moves the expiry one idle period ahead
+	for _, elapsed := range []time.Duration{50 * time.Minute, 100 * time.Minute, 150 * time.Minute} {
+		now := start.Add(elapsed)
+		service.now = func() time.Time { return now }
+		_, sessionEntity, err = service.ValidateSession(ctx, session.Token, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error after %v: %v", elapsed, err)
+		}
+	}
+	if sessionEntity.IPAddress != "10.0.0.1" {
+		t.Errorf("expected the last address to be recorded, got %s", sessionEntity.IPAddress)
+	}
+
+	// The absolute maximum caps the expiry
+	if !sessionEntity.ExpiresAt.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("expected the session to expire at the absolute maximum, got %v", sessionEntity.ExpiresAt)
+	}
+
+	service.now = func() time.Time { return start.Add(3*time.Hour + time.Second) }
+	if _, _, err := service.ValidateSession(ctx, session.Token, "10.0.0.1"); err == nil {
+		t.Error("expected the session to be expired after the absolute maximum")
+	}
+}
+
+func TestValidateSession_IdleExpiry(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{IdleTTL: time.Hour, MaxTTL: 3 * time.Hour})
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	start := time.Now()
+	service.now = func() time.Time { return start }
+	session, err := service.CreateSession(ctx, testUser.UUID, "Mozilla/5.0", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	service.now = func() time.Time { return start.Add(61 * time.Minute) }
+	if _, _, err := service.ValidateSession(ctx, session.Token, "192.168.1.1"); err == nil {
+		t.Error("expected an idle session to be expired")
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	session1, _ := service.CreateSession(ctx, testUser.UUID, "Chrome", "192.168.1.1")
+	session2, _ := service.CreateSession(ctx, testUser.UUID, "Firefox", "192.168.1.2")
+
+	if err := service.RevokeSession(ctx, uuid.New(), session1.ID.String()); !errors.Is(err, pkgerrors.ErrSessionNotFound) {
+		t.Errorf("expected the session of another user to be not found, got %v", err)
+	}
+
+	if err := service.RevokeSession(ctx, testUser.UUID, "invalid"); !errors.Is(err, pkgerrors.ErrInvalidUUID) {
+		t.Errorf("expected an invalid ID to be rejected, got %v", err)
+	}
+
+	if err := service.RevokeSession(ctx, testUser.UUID, session1.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, err := service.ValidateSession(ctx, session1.Token, "192.168.1.1"); err == nil {
+		t.Error("expected error for revoked session but got none")
+	}
+
+	if _, _, err := service.ValidateSession(ctx, session2.Token, "192.168.1.2"); err != nil {
+		t.Errorf("expected the other session to stay valid, got %v", err)
+	}
+}
+
+func TestCheckSession(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	session, _ := service.CreateSession(ctx, testUser.UUID, "Chrome", "192.168.1.1")
+
+	user, err := service.CheckSession(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.UUID != testUser.UUID {
+		t.Errorf("expected the user of the session, got %s", user.UUID)
+	}
+
+	if err := db.DB.Model(testUser).Update("disabled", true).Error; err != nil {
+		t.Fatalf("failed to disable test user: %v", err)
+	}
+	if _, err := service.CheckSession(ctx, session.ID); err == nil {
+		t.Error("expected the session of a disabled user to be rejected")
+	}
+
+	if err := service.RevokeSession(ctx, testUser.UUID, session.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.CheckSession(ctx, session.ID); !errors.Is(err, pkgerrors.ErrSessionNotFound) {
+		t.Errorf("expected a revoked session to be not found, got %v", err)
+	}
+}
+
+func TestCleanupExpiredSessions(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{IdleTTL: time.Hour, MaxTTL: 3 * time.Hour})
+	ctx := context.Background()
+	testUser := createTestUser(t, db)
+
+	start := time.Now()
+	service.now = func() time.Time { return start }
+	_, _ = service.CreateSession(ctx, testUser.UUID, "Chrome", "192.168.1.1")
+
+	service.now = func() time.Time { return start.Add(30 * time.Minute) }
+	_, _ = service.CreateSession(ctx, testUser.UUID, "Firefox", "192.168.1.2")
+
+	service.now = func() time.Time { return start.Add(75 * time.Minute) }
+	removed, err := service.CleanupExpiredSessions(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if removed != 1 {
+		t.Errorf("expected 1 expired session to be removed but got %d", removed)
+	}
+
+	var remaining int64
+	db.DB.Model(&models.Session{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("expected 1 session left but got %d", remaining)
+	}
+}
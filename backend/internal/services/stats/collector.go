@@ -0,0 +1,263 @@
+package stats
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/google/uuid"
+)
+
+// counters are the cumulative values of a series at its last sample
+type counters struct {
+	downloaded int64
+	uploaded   int64
+}
+
+// Run samples every agent on each interval and downsamples the stored series,
+// until ctx is canceled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Collect(ctx, now)
+
+			if err := s.Compact(ctx, now); err != nil {
+				log.Printf("stats: failed to downsample transfer samples: %v", err)
+			}
+		}
+	}
+}
+
+// Collect stores a raw sample of the instance and task counters of every agent
+func (s *Service) Collect(ctx context.Context, now time.Time) {
+	agents, err := s.source.ListAgents()
+	if err != nil {
+		log.Printf("stats: failed to list agents: %v", err)
+		return
+	}
+
+	timestamp := now.UTC().Truncate(time.Second)
+
+	var wg sync.WaitGroup
+	for _, agent := range agents {
+		wg.Add(1)
+		go func(a *entities.Agent) {
+			defer wg.Done()
+			if err := s.collectAgent(ctx, a, timestamp); err != nil {
+				log.Printf("stats: failed to sample agent %s: %v", a.Name, err)
+			}
+		}(agent)
+	}
+	wg.Wait()
+}
+
+func (s *Service) collectAgent(ctx context.Context, agent *entities.Agent, timestamp time.Time) error {
+	previous, err := s.previousCounters(ctx, agent.UUID)
+	if err != nil {
+		return err
+	}
+
+	instance, err := s.source.GetInstance(ctx, agent)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]counters)
+	samples := []*entities.TransferSample{
+		newSample(agent.UUID, entities.TransferScopeInstance, "", "", timestamp,
+			int64(instance.Transfer.AllTimeDownloaded), int64(instance.Transfer.AllTimeUploaded), instance.Transfer.GlobalRatio,
+			previous, current),
+	}
+
+	tasks, tasksErr := s.source.ListAgentTasks(ctx, agent)
+	if tasksErr != nil {
+		// keep the instance sample, task series resume on the next round
+		log.Printf("stats: failed to list tasks of agent %s: %v", agent.Name, tasksErr)
+
+		for key, value := range previous {
+			if _, ok := current[key]; !ok {
+				current[key] = value
+			}
+		}
+	}
+
+	for _, task := range tasks {
+		samples = append(samples, newSample(agent.UUID, entities.TransferScopeTask, task.Hash, task.Category, timestamp,
+			int64(task.Network.Download.Amount), int64(task.Network.Upload.Amount), task.Ratio,
+			previous, current))
+	}
+
+	if err := s.repository.CreateSamples(ctx, samples); err != nil {
+		return err
+	}
+
+	// tasks missing from this round were removed from the client and are forgotten
+	s.mu.Lock()
+	s.last[agent.UUID] = current
+	s.mu.Unlock()
+
+	return nil
+}
+
+// previousCounters returns the counters of the last round of an agent, loading
+// them from the database after a restart
+func (s *Service) previousCounters(ctx context.Context, agentUUID uuid.UUID) (map[string]counters, error) {
+	s.mu.Lock()
+	previous, ok := s.last[agentUUID]
+	s.mu.Unlock()
+	if ok {
+		return previous, nil
+	}
+
+	samples, err := s.repository.ListLatestSamples(ctx, agentUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous = make(map[string]counters, len(samples))
+	for _, sample := range samples {
+		previous[seriesKey(sample.Scope, sample.TaskHash)] = counters{
+			downloaded: sample.Downloaded,
+			uploaded:   sample.Uploaded,
+		}
+	}
+
+	return previous, nil
+}
+
+// newSample builds a raw sample and its deltas against the previous counters of the series
+func newSample(agentUUID uuid.UUID, scope, taskHash, category string, timestamp time.Time, downloaded, uploaded int64, ratio float64, previous, current map[string]counters) *entities.TransferSample {
+	key := seriesKey(scope, taskHash)
+	current[key] = counters{downloaded: downloaded, uploaded: uploaded}
+
+	sample := &entities.TransferSample{
+		AgentUUID:  agentUUID,
+		Scope:      scope,
+		TaskHash:   taskHash,
+		Category:   category,
+		Resolution: entities.TransferResolutionRaw,
+		Timestamp:  timestamp,
+		Downloaded: downloaded,
+		Uploaded:   uploaded,
+		Ratio:      ratio,
+	}
+
+	// the first sample of a series has no reference to compute a volume from
+	if last, ok := previous[key]; ok {
+		sample.DownloadedDelta = delta(last.downloaded, downloaded)
+		sample.UploadedDelta = delta(last.uploaded, uploaded)
+	}
+
+	return sample
+}
+
+// delta returns the volume between two readings of a counter. A counter going
+// backwards was reset (client restarted or task re-added), so it counts from zero.
+func delta(previous, current int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func seriesKey(scope, taskHash string) string {
+	return scope + ":" + taskHash
+}
+
+// Compact rolls the samples of each tier up into the next coarser one, for every
+// bucket already completed, and drops the samples past the tier retention
+func (s *Service) Compact(ctx context.Context, now time.Time) error {
+	tiers := s.config.tiers()
+	now = now.UTC()
+
+	for i := 1; i < len(tiers); i++ {
+		source, target := tiers[i-1], tiers[i]
+
+		end := now.Truncate(target.size)
+
+		start := time.Time{}
+		last, ok, err := s.repository.LastTimestamp(ctx, target.resolution)
+		if err != nil {
+			return err
+		}
+		if ok {
+			start = last.Add(target.size)
+		}
+
+		if start.Before(end) {
+			samples, err := s.repository.ListSamples(ctx, sampleFilter(source.resolution, start, end))
+			if err != nil {
+				return err
+			}
+
+			if err := s.repository.CreateSamples(ctx, rollup(samples, target)); err != nil {
+				return err
+			}
+		}
+
+		// only samples already rolled up may be removed
+		if source.retention > 0 {
+			before := now.Add(-source.retention)
+			if end.Before(before) {
+				before = end
+			}
+
+			if err := s.repository.DeleteSamplesBefore(ctx, source.resolution, before); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollup aggregates samples into the buckets of a tier. Samples must be sorted by timestamp.
+func rollup(samples []*entities.TransferSample, target tier) []*entities.TransferSample {
+	type bucketKey struct {
+		agentUUID uuid.UUID
+		series    string
+		timestamp time.Time
+	}
+
+	buckets := make(map[bucketKey]*entities.TransferSample)
+	var result []*entities.TransferSample
+
+	for _, sample := range samples {
+		key := bucketKey{
+			agentUUID: sample.AgentUUID,
+			series:    seriesKey(sample.Scope, sample.TaskHash),
+			timestamp: sample.Timestamp.Truncate(target.size),
+		}
+
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &entities.TransferSample{
+				AgentUUID:  sample.AgentUUID,
+				Scope:      sample.Scope,
+				TaskHash:   sample.TaskHash,
+				Resolution: target.resolution,
+				Timestamp:  key.timestamp,
+			}
+			buckets[key] = bucket
+			result = append(result, bucket)
+		}
+
+		// counters and category are those of the last sample of the bucket
+		bucket.Category = sample.Category
+		bucket.Downloaded = sample.Downloaded
+		bucket.Uploaded = sample.Uploaded
+		bucket.Ratio = sample.Ratio
+		bucket.DownloadedDelta += sample.DownloadedDelta
+		bucket.UploadedDelta += sample.UploadedDelta
+	}
+
+	return result
+}
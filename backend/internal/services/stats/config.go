@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the transfer statistics settings. A zero retention keeps the tier forever.
+type Config struct {
+	SampleInterval time.Duration
+
+	RawRetention    time.Duration
+	MinuteRetention time.Duration
+	HourRetention   time.Duration
+	DayRetention    time.Duration
+}
+
+// LoadConfigFromEnv loads the transfer statistics configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		SampleInterval:  env.Get("STATS_SAMPLE_INTERVAL").Default("15s").ValuePositiveDuration(),
+		RawRetention:    env.Get("STATS_RAW_RETENTION").Default("6h").ValueDuration(),
+		MinuteRetention: env.Get("STATS_MINUTE_RETENTION").Default("168h").ValueDuration(),
+		HourRetention:   env.Get("STATS_HOUR_RETENTION").Default("2160h").ValueDuration(),
+		DayRetention:    env.Get("STATS_DAY_RETENTION").Default("0s").ValueDuration(),
+	}
+}
+
+// tier is a retention level of the transfer series
+type tier struct {
+	resolution string
+	size       time.Duration // bucket size, zero for raw samples
+	retention  time.Duration
+}
+
+// tiers returns the retention tiers from the finest to the coarsest
+func (c Config) tiers() []tier {
+	return []tier{
+		{resolution: entities.TransferResolutionRaw, size: 0, retention: c.RawRetention},
+		{resolution: entities.TransferResolutionMinute, size: time.Minute, retention: c.MinuteRetention},
+		{resolution: entities.TransferResolutionHour, size: time.Hour, retention: c.HourRetention},
+		{resolution: entities.TransferResolutionDay, size: 24 * time.Hour, retention: c.DayRetention},
+	}
+}
@@ -0,0 +1,280 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/agent"
+	"github.com/gardarr/gardarr/internal/repository/stats"
+	"github.com/google/uuid"
+)
+
+// Source is where the transfer counters are read from
+type Source interface {
+	ListAgents() ([]*entities.Agent, error)
+	GetAgentByUUID(uid uuid.UUID) (*entities.Agent, error)
+	GetInstance(ctx context.Context, agent *entities.Agent) (*entities.Instance, error)
+	ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error)
+}
+
+// Service collects transfer counters from the agents into downsampled series and
+// answers range queries over them
+type Service struct {
+	repository *stats.Repository
+	source     Source
+	config     Config
+
+	mu sync.Mutex
+	// last holds the counters of the last round, per agent and series
+	last map[uuid.UUID]map[string]counters
+}
+
+// NewService creates a service reading the counters through the shared agent
+// repository
+func NewService(db *database.Database, agents *agent.Repository) *Service {
+	return NewWithSource(stats.NewRepository(db), agents, LoadConfigFromEnv())
+}
+
+// NewWithSource creates a service reading counters from the given source
+func NewWithSource(repository *stats.Repository, source Source, config Config) *Service {
+	return &Service{
+		repository: repository,
+		source:     source,
+		config:     config,
+		last:       make(map[uuid.UUID]map[string]counters),
+	}
+}
+
+// AgentSeries returns the transfer volume of an agent instance over [from, to)
+func (s *Service) AgentSeries(ctx context.Context, agentID string, from, to time.Time, step time.Duration) (*entities.TransferSeries, error) {
+	a, err := s.resolveAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := s.loadRange(ctx, stats.SampleFilter{
+		AgentUUID: a.UUID,
+		Scope:     entities.TransferScopeInstance,
+	}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer samples: %w", err)
+	}
+
+	return buildSeries(samples, from, to, step), nil
+}
+
+// TaskSeries returns the transfer volume of a task over [from, to)
+func (s *Service) TaskSeries(ctx context.Context, agentID, taskID string, from, to time.Time, step time.Duration) (*entities.TransferSeries, error) {
+	a, err := s.resolveAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := s.loadRange(ctx, stats.SampleFilter{
+		AgentUUID: a.UUID,
+		Scope:     entities.TransferScopeTask,
+		TaskHash:  taskID,
+	}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer samples: %w", err)
+	}
+
+	return buildSeries(samples, from, to, step), nil
+}
+
+// CategorySeries returns the transfer volume of every task category over [from, to),
+// for one agent or, when agentID is empty, for all of them
+func (s *Service) CategorySeries(ctx context.Context, agentID string, from, to time.Time, step time.Duration) ([]*entities.TransferSeries, error) {
+	filter := stats.SampleFilter{Scope: entities.TransferScopeTask}
+
+	if agentID != "" {
+		a, err := s.resolveAgent(agentID)
+		if err != nil {
+			return nil, err
+		}
+		filter.AgentUUID = a.UUID
+	}
+
+	samples, err := s.loadRange(ctx, filter, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer samples: %w", err)
+	}
+
+	byCategory := make(map[string][]*entities.TransferSample)
+	for _, sample := range samples {
+		byCategory[sample.Category] = append(byCategory[sample.Category], sample)
+	}
+
+	result := make([]*entities.TransferSeries, 0, len(byCategory))
+	for category, items := range byCategory {
+		series := buildSeries(items, from, to, step)
+		series.Category = category
+		// a category mixes several tasks, there is no client ratio to report
+		series.CurrentRatio = 0
+		result = append(result, series)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Category < result[j].Category
+	})
+
+	return result, nil
+}
+
+// loadRange reads the samples covering [from, to), taking each period from the
+// coarsest tier that already holds it so no volume is counted twice
+func (s *Service) loadRange(ctx context.Context, filter stats.SampleFilter, from, to time.Time) ([]*entities.TransferSample, error) {
+	tiers := s.config.tiers()
+	cursor := from.UTC()
+	to = to.UTC()
+
+	var result []*entities.TransferSample
+	for i := len(tiers) - 1; i >= 0 && cursor.Before(to); i-- {
+		t := tiers[i]
+
+		end := to
+		if t.size > 0 {
+			last, ok, err := s.repository.LastTimestamp(ctx, t.resolution)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			covered := last.Add(t.size)
+			if !covered.After(cursor) {
+				continue
+			}
+			if covered.Before(end) {
+				end = covered
+			}
+		}
+
+		filter.Resolution = t.resolution
+		filter.From = cursor
+		filter.To = end
+
+		samples, err := s.repository.ListSamples(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, samples...)
+		cursor = end
+	}
+
+	return result, nil
+}
+
+// buildSeries sums the sample volumes of a range into points of step. Samples must be sorted by timestamp.
+func buildSeries(samples []*entities.TransferSample, from, to time.Time, step time.Duration) *entities.TransferSeries {
+	series := &entities.TransferSeries{
+		From:   from,
+		To:     to,
+		Step:   step,
+		Points: []entities.TransferPoint{},
+	}
+
+	index := make(map[time.Time]int)
+	for _, sample := range samples {
+		timestamp := sample.Timestamp.Truncate(step)
+
+		i, ok := index[timestamp]
+		if !ok {
+			i = len(series.Points)
+			index[timestamp] = i
+			series.Points = append(series.Points, entities.TransferPoint{Timestamp: timestamp})
+		}
+
+		series.Points[i].Downloaded += sample.DownloadedDelta
+		series.Points[i].Uploaded += sample.UploadedDelta
+
+		series.Downloaded += sample.DownloadedDelta
+		series.Uploaded += sample.UploadedDelta
+		series.CurrentRatio = sample.Ratio
+	}
+
+	sort.Slice(series.Points, func(i, j int) bool {
+		return series.Points[i].Timestamp.Before(series.Points[j].Timestamp)
+	})
+
+	for i := range series.Points {
+		series.Points[i].Ratio = ratio(series.Points[i].Uploaded, series.Points[i].Downloaded)
+	}
+	series.Ratio = ratio(series.Uploaded, series.Downloaded)
+
+	return series
+}
+
+func ratio(uploaded, downloaded int64) float64 {
+	if downloaded == 0 {
+		return 0
+	}
+	return float64(uploaded) / float64(downloaded)
+}
+
+func sampleFilter(resolution string, from, to time.Time) stats.SampleFilter {
+	return stats.SampleFilter{
+		Resolution: resolution,
+		From:       from,
+		To:         to,
+	}
+}
+
+func (s *Service) resolveAgent(agentID string) (*entities.Agent, error) {
+	uid, err := uuid.Parse(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UUID format: %w", err)
+	}
+
+	a, err := s.source.GetAgentByUUID(uid)
+	if err != nil {
+		return nil, fmt.Errorf("agent not found: %w", err)
+	}
+
+	return a, nil
+}
+
+// ParseStep parses a series step such as "15m", "1h" or "7d". Steps shorter than a minute are rejected.
+func ParseStep(value string) (time.Duration, error) {
+	var step time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid step %q", value)
+		}
+		step = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid step %q", value)
+		}
+		step = parsed
+	}
+
+	if step < time.Minute {
+		return 0, fmt.Errorf("step must be at least 1m")
+	}
+
+	return step, nil
+}
+
+// DefaultStep picks a step giving a readable number of points for the range
+func DefaultStep(from, to time.Time) time.Duration {
+	switch span := to.Sub(from); {
+	case span <= 6*time.Hour:
+		return time.Minute
+	case span <= 7*24*time.Hour:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/stats"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeSource serves scripted counters for a single agent
+type fakeSource struct {
+	agent    *entities.Agent
+	instance entities.InstanceTransfer
+	tasks    []*entities.Task
+}
+
+func (f *fakeSource) ListAgents() ([]*entities.Agent, error) {
+	return []*entities.Agent{f.agent}, nil
+}
+
+func (f *fakeSource) GetAgentByUUID(uid uuid.UUID) (*entities.Agent, error) {
+	if uid != f.agent.UUID {
+		return nil, errors.New("record not found")
+	}
+	return f.agent, nil
+}
+
+func (f *fakeSource) GetInstance(ctx context.Context, agent *entities.Agent) (*entities.Instance, error) {
+	return &entities.Instance{Transfer: f.instance}, nil
+}
+
+func (f *fakeSource) ListAgentTasks(ctx context.Context, agent *entities.Agent) ([]*entities.Task, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeSource) set(downloaded, uploaded int, tasks ...*entities.Task) {
+	f.instance = entities.InstanceTransfer{AllTimeDownloaded: downloaded, AllTimeUploaded: uploaded}
+	f.tasks = tasks
+}
+
+func task(hash, category string, downloaded, uploaded int) *entities.Task {
+	return &entities.Task{
+		Hash:     hash,
+		Category: category,
+		Network: entities.TaskNetwork{
+			Download: entities.TaskDownload{Amount: downloaded},
+			Upload:   entities.TaskUpload{Amount: uploaded},
+		},
+	}
+}
+
+func setupService(t *testing.T) (*Service, *fakeSource) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.TransferSample{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	source := &fakeSource{agent: &entities.Agent{UUID: uuid.New(), Name: "seedbox"}}
+
+	svc := NewWithSource(stats.NewRepository(&database.Database{DB: db}), source, Config{
+		SampleInterval:  30 * time.Second,
+		RawRetention:    time.Hour,
+		MinuteRetention: 24 * time.Hour,
+		HourRetention:   30 * 24 * time.Hour,
+	})
+
+	return svc, source
+}
+
+func TestService_CollectComputesDeltas(t *testing.T) {
+	svc, source := setupService(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	source.set(100, 50, task("a", "movies", 10, 5))
+	svc.Collect(ctx, t0)
+
+	source.set(150, 80, task("a", "movies", 30, 25), task("b", "tv", 7, 0))
+	svc.Collect(ctx, t0.Add(30*time.Second))
+
+	// the client restarted: instance counter went backwards
+	source.set(20, 10, task("a", "movies", 40, 45), task("b", "tv", 10, 3))
+	svc.Collect(ctx, t0.Add(70*time.Second))
+
+	series, err := svc.AgentSeries(ctx, source.agent.UUID.String(), t0.Add(-time.Hour), t0.Add(time.Hour), time.Minute)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 50 + 20 downloaded, 30 + 10 uploaded
+	if series.Downloaded != 70 || series.Uploaded != 40 {
+		t.Errorf("Expected 70/40 bytes, got %d/%d", series.Downloaded, series.Uploaded)
+	}
+	if len(series.Points) != 2 {
+		t.Errorf("Expected 2 points of 1 minute, got %d", len(series.Points))
+	}
+
+	categories, err := svc.CategorySeries(ctx, "", t0.Add(-time.Hour), t0.Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(categories) != 2 {
+		t.Fatalf("Expected 2 categories, got %d", len(categories))
+	}
+
+	// task b is new on the second round, its volume starts on the third one
+	want := map[string][2]int64{"movies": {30, 40}, "tv": {3, 3}}
+	for _, category := range categories {
+		if got := [2]int64{category.Downloaded, category.Uploaded}; got != want[category.Category] {
+			t.Errorf("Category %s: expected %v, got %v", category.Category, want[category.Category], got)
+		}
+	}
+}
+
+func TestService_CompactKeepsTotals(t *testing.T) {
+	svc, source := setupService(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 3 hours of samples every 30 seconds, 10 bytes down and 20 up each
+	var downloaded, uploaded int
+	for i := 0; i < 360; i++ {
+		source.set(downloaded, uploaded, task("a", "movies", downloaded, uploaded))
+		svc.Collect(ctx, t0.Add(time.Duration(i)*30*time.Second))
+		downloaded += 10
+		uploaded += 20
+	}
+
+	from, to := t0.Add(-time.Hour), t0.Add(4*time.Hour)
+
+	before, err := svc.AgentSeries(ctx, source.agent.UUID.String(), from, to, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := svc.Compact(ctx, t0.Add(3*time.Hour+30*time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	after, err := svc.AgentSeries(ctx, source.agent.UUID.String(), from, to, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if before.Downloaded != 3590 || before.Uploaded != 7180 {
+		t.Errorf("Expected 3590/7180 bytes before compaction, got %d/%d", before.Downloaded, before.Uploaded)
+	}
+	if after.Downloaded != before.Downloaded || after.Uploaded != before.Uploaded {
+		t.Errorf("Expected compaction to keep totals %d/%d, got %d/%d", before.Downloaded, before.Uploaded, after.Downloaded, after.Uploaded)
+	}
+	if after.Ratio != 2 {
+		t.Errorf("Expected ratio 2, got %f", after.Ratio)
+	}
+
+	raw, err := svc.repository.ListSamples(ctx, sampleFilter(entities.TransferResolutionRaw, time.Time{}, t0.Add(2*time.Hour)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(raw) != 0 {
+		t.Errorf("Expected raw samples past retention to be removed, got %d", len(raw))
+	}
+
+	hours, err := svc.repository.ListSamples(ctx, sampleFilter(entities.TransferResolutionHour, t0, to))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// one instance and one task series for each of the 3 completed hours
+	if len(hours) != 6 {
+		t.Errorf("Expected 6 hourly samples, got %d", len(hours))
+	}
+
+	// compacting again must not duplicate buckets
+	if err := svc.Compact(ctx, t0.Add(3*time.Hour+31*time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	again, _ := svc.AgentSeries(ctx, source.agent.UUID.String(), from, to, time.Hour)
+	if again.Downloaded != before.Downloaded {
+		t.Errorf("Expected %d bytes after second compaction, got %d", before.Downloaded, again.Downloaded)
+	}
+}
+
+func TestService_UnknownAgent(t *testing.T) {
+	svc, _ := setupService(t)
+
+	_, err := svc.AgentSeries(context.Background(), uuid.New().String(), time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	if err == nil {
+		t.Error("Expected error for unknown agent")
+	}
+}
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"1m", time.Minute, false},
+		{"15m", 15 * time.Minute, false},
+		{"1h", time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"30s", 0, true},
+		{"xd", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseStep(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStep(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStep(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
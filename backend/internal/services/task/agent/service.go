@@ -2,7 +2,7 @@ package task
 
 import (
 	"context"
-	"strings"
+	"net/http"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/interfaces"
@@ -12,6 +12,7 @@ import (
 
 type service struct {
 	repository repository.RepositoryInterface
+	fetch      *http.Client // downloads the .torrent files of the torrent URLs
 }
 
 func New() (interfaces.TaskService, error) {
@@ -20,9 +21,15 @@ func New() (interfaces.TaskService, error) {
 		return nil, err
 	}
 
+	return NewWithRepository(r), nil
+}
+
+// NewWithRepository creates the task service on top of an existing repository
+func NewWithRepository(r repository.RepositoryInterface) interfaces.TaskService {
 	return &service{
 		repository: r,
-	}, nil
+		fetch:      newFetchClient(allowedNetworks()),
+	}
 }
 
 func (s *service) ListTasks(ctx context.Context) ([]*entities.Task, error) {
@@ -38,23 +45,51 @@ func (s *service) CreateTask(ctx context.Context, schema schemas.TaskCreateSchem
 		schema.Directory = "/data/downloads"
 	}
 
-	uri, err := repository.ParseMagnetLink(schema.MagnetURI)
+	src, err := resolveSource(ctx, s.fetch, &schema)
 	if err != nil {
 		return nil, err
 	}
 
+	existing, err := s.find(src)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	return s.repository.Add(schema)
+}
+
+// PreviewTask reads the torrent a create request points to without adding it
+func (s *service) PreviewTask(ctx context.Context, schema schemas.TaskCreateSchema) (*entities.TaskPreview, error) {
+	src, err := resolveSource(ctx, s.fetch, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := src.preview()
+	if preview.Existing, err = s.find(src); err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// find returns the task already added from the torrent of a source, if any
+func (s *service) find(src *source) (*entities.Task, error) {
 	list, err := s.repository.List()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, item := range list {
-		if strings.EqualFold(item.MagnetLink.Hash, uri.Hash) {
+		if src.matches(item) {
 			return item, nil
 		}
 	}
 
-	return s.repository.Add(schema)
+	return nil, nil
 }
 
 func (s *service) StopTask(ctx context.Context, hash string) error {
@@ -108,3 +143,11 @@ func (s *service) SetTaskUploadLimit(ctx context.Context, hash string, schema sc
 func (s *service) ListTaskFiles(ctx context.Context, hash string) ([]*entities.TaskFile, error) {
 	return s.repository.ListFiles(hash)
 }
+
+func (s *service) SetTaskCategory(ctx context.Context, hash string, schema schemas.TaskSetCategorySchema) error {
+	return s.repository.SetCategory(hash, schema)
+}
+
+func (s *service) SetTaskTags(ctx context.Context, hash string, schema schemas.TaskSetTagsSchema) error {
+	return s.repository.ReplaceTags(hash, schema)
+}
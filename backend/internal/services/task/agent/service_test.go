@@ -2,13 +2,23 @@ package task
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/schemas"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
 )
 
+// loopback lets the torrent URLs point to the test servers
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
 // mockRepository is a mock implementation of the task repository for testing
 type mockRepository struct {
 	tasks       map[string]*entities.Task
@@ -17,6 +27,8 @@ type mockRepository struct {
 	forceError  error
 	deleteError error
 	createError error
+	// added holds the schemas given to Add
+	added []schemas.TaskCreateSchema
 }
 
 func newMockRepository() *mockRepository {
@@ -44,6 +56,7 @@ func (m *mockRepository) Add(schema schemas.TaskCreateSchema) (*entities.Task, e
 	if m.createError != nil {
 		return nil, m.createError
 	}
+	m.added = append(m.added, schema)
 
 	task := &entities.Task{
 		ID:        "test-hash",
@@ -175,6 +188,22 @@ func (m *mockRepository) SetUploadLimit(hash string, schema schemas.TaskSetUploa
 	return errors.New("task not found")
 }
 
+func (m *mockRepository) SetCategory(hash string, schema schemas.TaskSetCategorySchema) error {
+	if task, exists := m.tasks[hash]; exists {
+		task.Category = schema.Category
+		return nil
+	}
+	return errors.New("task not found")
+}
+
+func (m *mockRepository) ReplaceTags(hash string, schema schemas.TaskSetTagsSchema) error {
+	if task, exists := m.tasks[hash]; exists {
+		task.Tags = schema.Tags
+		return nil
+	}
+	return errors.New("task not found")
+}
+
 func (m *mockRepository) ListFiles(hash string) ([]*entities.TaskFile, error) {
 	if _, exists := m.tasks[hash]; exists {
 		// Return mock files for testing
@@ -409,6 +438,179 @@ func TestService_CreateTask(t *testing.T) {
 	}
 }
 
+// testTorrent is a single file .torrent and testInfo its info dictionary
+const (
+	testInfo    = "d6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	testTorrent = "d4:info" + testInfo + "e"
+)
+
+func testInfoHash() string {
+	sum := sha1.Sum([]byte(testInfo))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestService_CreateTask_TorrentFile(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	service := &service{repository: mockRepo}
+
+	schema := schemas.TaskCreateSchema{
+		Torrent:        []byte(testTorrent),
+		Category:       "linux",
+		Paused:         true,
+		FilePriorities: []int{6},
+	}
+
+	if _, err := service.CreateTask(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(mockRepo.added) != 1 || string(mockRepo.added[0].Torrent) != testTorrent || !mockRepo.added[0].Paused {
+		t.Fatalf("Expected the torrent file and options given to the repository, got %+v", mockRepo.added)
+	}
+
+	// the same torrent, known to the client by its uppercase infohash
+	existing := &entities.Task{ID: "existing", Hash: strings.ToUpper(testInfoHash())}
+	mockRepo.tasks = map[string]*entities.Task{"existing": existing}
+
+	task, err := service.CreateTask(ctx, schema)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if task != existing || len(mockRepo.added) != 1 {
+		t.Errorf("Expected the existing task without adding it again, got %+v", task)
+	}
+}
+
+func TestService_CreateTask_InvalidSource(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	service := &service{repository: mockRepo}
+
+	tests := []struct {
+		name   string
+		schema schemas.TaskCreateSchema
+	}{
+		{"no source", schemas.TaskCreateSchema{Category: "linux"}},
+		{"two sources", schemas.TaskCreateSchema{MagnetURI: "magnet:?xt=urn:btih:test-hash", Torrent: []byte(testTorrent)}},
+		{"invalid torrent", schemas.TaskCreateSchema{Torrent: []byte("not a torrent")}},
+		{"priorities of a magnet", schemas.TaskCreateSchema{MagnetURI: "magnet:?xt=urn:btih:test-hash", FilePriorities: []int{0}}},
+		{"priority count", schemas.TaskCreateSchema{Torrent: []byte(testTorrent), FilePriorities: []int{0, 1}}},
+		{"not an http URL", schemas.TaskCreateSchema{TorrentURL: "file:///etc/passwd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateTask(ctx, tt.schema); !pkgerrors.Is(err, pkgerrors.ErrInvalidInput) {
+				t.Errorf("Expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+
+	if len(mockRepo.added) != 0 {
+		t.Errorf("Expected nothing added, got %+v", mockRepo.added)
+	}
+}
+
+func TestService_CreateTask_TorrentURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file.torrent":
+			w.Write([]byte(testTorrent))
+		case "/redirect":
+			http.Redirect(w, r, "/file.torrent", http.StatusFound)
+		case "/magnet":
+			http.Redirect(w, r, "magnet:?xt=urn:btih:magnet-hash", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	service := &service{repository: mockRepo, fetch: newFetchClient(loopback)}
+
+	if _, err := service.CreateTask(ctx, schemas.TaskCreateSchema{TorrentURL: server.URL + "/redirect"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if added := mockRepo.added[0]; string(added.Torrent) != testTorrent || added.TorrentURL != "" {
+		t.Errorf("Expected the fetched torrent file given to the repository, got %+v", added)
+	}
+
+	mockRepo.tasks = map[string]*entities.Task{}
+	if _, err := service.CreateTask(ctx, schemas.TaskCreateSchema{TorrentURL: server.URL + "/magnet"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if added := mockRepo.added[1]; added.MagnetURI != "magnet:?xt=urn:btih:magnet-hash" || len(added.Torrent) != 0 {
+		t.Errorf("Expected the magnet link of the redirect given to the repository, got %+v", added)
+	}
+
+	if _, err := service.CreateTask(ctx, schemas.TaskCreateSchema{TorrentURL: server.URL + "/missing"}); !pkgerrors.Is(err, pkgerrors.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for a missing torrent, got %v", err)
+	}
+}
+
+func TestService_CreateTask_TorrentURLPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTorrent))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	service := &service{repository: mockRepo, fetch: newFetchClient(nil)}
+
+	for _, target := range []string{server.URL + "/file.torrent", "http://10.0.0.1/file.torrent", "http://169.254.169.254/latest/meta-data/"} {
+		if _, err := service.CreateTask(ctx, schemas.TaskCreateSchema{TorrentURL: target}); !pkgerrors.Is(err, pkgerrors.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %s, got %v", target, err)
+		}
+	}
+
+	if len(mockRepo.added) != 0 {
+		t.Errorf("Expected nothing added, got %+v", mockRepo.added)
+	}
+}
+
+func TestService_CreateTask_TorrentURLRedirectToPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	// The test server itself is on loopback, the redirect target is not allowed
+	service := &service{repository: mockRepo, fetch: newFetchClient(loopback)}
+
+	if _, err := service.CreateTask(ctx, schemas.TaskCreateSchema{TorrentURL: server.URL + "/file.torrent"}); !pkgerrors.Is(err, pkgerrors.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for a redirect to a private address, got %v", err)
+	}
+	if len(mockRepo.added) != 0 {
+		t.Errorf("Expected nothing added, got %+v", mockRepo.added)
+	}
+}
+
+func TestService_PreviewTask(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := newMockRepository()
+	service := &service{repository: mockRepo}
+
+	preview, err := service.PreviewTask(ctx, schemas.TaskCreateSchema{Torrent: []byte(testTorrent)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if preview.Name != "file.iso" || preview.Size != 1024 || preview.InfoHashV1 != testInfoHash() || preview.Existing != nil {
+		t.Errorf("Expected the metainfo of the torrent, got %+v", preview)
+	}
+	if len(preview.Files) != 1 || preview.Files[0].Path != "file.iso" {
+		t.Errorf("Expected the single file, got %+v", preview.Files)
+	}
+	if len(mockRepo.added) != 0 {
+		t.Errorf("Expected nothing added, got %+v", mockRepo.added)
+	}
+}
+
 func TestService_DeleteTask(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := newMockRepository()
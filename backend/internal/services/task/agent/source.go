@@ -0,0 +1,198 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	repository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	"github.com/gardarr/gardarr/internal/schemas"
+	"github.com/gardarr/gardarr/pkg/env"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gardarr/gardarr/pkg/netaddr"
+	"github.com/gardarr/gardarr/pkg/torrent"
+)
+
+// newFetchClient returns the client downloading the .torrent files of the
+// torrent URLs. Indexers may answer a redirect to a magnet link, which is not
+// followed. Only public addresses are dialed, plus the allowed networks: the
+// check runs on the resolved address of every connection, redirects included,
+// and no proxy is used, which would dial the address in place of the agent.
+func newFetchClient(allowed []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !netaddr.IsPublic(addr) && !netaddr.Contains(allowed, addr) {
+				return fmt.Errorf("%w: torrent_url leads to %s, which is not a public address", errors.ErrInvalidInput, addr)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			ForceAttemptHTTP2:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme == "magnet" {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return nil
+		},
+	}
+}
+
+// allowedNetworks returns the private networks torrent URLs may point to, an
+// indexer on the local network for instance
+func allowedNetworks() []netip.Prefix {
+	prefixes, invalid := netaddr.ParsePrefixes(env.Get(constants.AgentTorrentURLAllowedNetworksEnv).ValueList())
+	for _, value := range invalid {
+		log.Printf("task: ignoring invalid torrent URL network %q", value)
+	}
+	return prefixes
+}
+
+// source is the torrent a create request points to, known by its magnet link
+// or by the metainfo of its .torrent file
+type source struct {
+	magnet *entities.TaskMagnetLink
+	meta   *torrent.MetaInfo
+}
+
+// matches tells whether a task was added from the same torrent
+func (s *source) matches(task *entities.Task) bool {
+	if s.meta != nil {
+		return s.meta.InfoHash.Matches(task.Hash) || s.meta.InfoHash.Matches(task.MagnetLink.Hash)
+	}
+	return strings.EqualFold(task.MagnetLink.Hash, s.magnet.Hash)
+}
+
+func (s *source) preview() *entities.TaskPreview {
+	if s.meta == nil {
+		return &entities.TaskPreview{
+			Name:       s.magnet.DisplayName,
+			InfoHashV1: strings.ToLower(s.magnet.Hash),
+			Files:      []entities.TaskPreviewFile{},
+		}
+	}
+
+	preview := &entities.TaskPreview{
+		Name:       s.meta.Name,
+		Size:       s.meta.Length,
+		InfoHashV1: s.meta.InfoHash.V1,
+		InfoHashV2: s.meta.InfoHash.V2,
+		Files:      make([]entities.TaskPreviewFile, len(s.meta.Files)),
+	}
+	for i, file := range s.meta.Files {
+		preview.Files[i] = entities.TaskPreviewFile{Path: file.Path, Size: file.Length}
+	}
+
+	return preview
+}
+
+// resolveSource checks that the schema has a single source and reads the
+// torrent it points to. The .torrent file of a torrent URL is fetched into the
+// schema, so that the repositories only get magnet links and .torrent files.
+func resolveSource(ctx context.Context, client *http.Client, schema *schemas.TaskCreateSchema) (*source, error) {
+	sources := 0
+	for _, set := range []bool{schema.MagnetURI != "", schema.TorrentURL != "", len(schema.Torrent) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.Wrap(errors.ErrInvalidInput, "exactly one of magnet_uri, torrent_url and torrent must be set")
+	}
+
+	if schema.TorrentURL != "" {
+		if err := fetchTorrent(ctx, client, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if schema.MagnetURI != "" {
+		if len(schema.FilePriorities) > 0 {
+			return nil, errors.Wrap(errors.ErrInvalidInput, "file priorities need a .torrent file")
+		}
+
+		link, err := repository.ParseMagnetLink(schema.MagnetURI)
+		if err != nil {
+			return nil, err
+		}
+		return &source{magnet: link}, nil
+	}
+
+	meta, err := torrent.ParseMetaInfo(schema.Torrent)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidInput, fmt.Sprintf("invalid torrent file: %v", err))
+	}
+	if len(schema.FilePriorities) > 0 && len(schema.FilePriorities) != len(meta.Files) {
+		return nil, errors.Wrap(errors.ErrInvalidInput, fmt.Sprintf("expected %d file priorities, got %d", len(meta.Files), len(schema.FilePriorities)))
+	}
+
+	return &source{meta: meta}, nil
+}
+
+// fetchTorrent downloads the .torrent file of the torrent URL of the schema,
+// or takes the magnet link the URL redirects to
+func fetchTorrent(ctx context.Context, client *http.Client, schema *schemas.TaskCreateSchema) error {
+	target, err := url.Parse(schema.TorrentURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return errors.Wrap(errors.ErrInvalidInput, "torrent_url must be an http(s) URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch torrent")
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 && strings.HasPrefix(strings.ToLower(location), "magnet:") {
+		schema.MagnetURI, schema.TorrentURL = location, ""
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrap(errors.ErrInvalidInput, fmt.Sprintf("torrent_url answered status %d", resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, schemas.MaxTorrentSize+1))
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch torrent")
+	}
+	if len(data) > schemas.MaxTorrentSize {
+		return errors.Wrap(errors.ErrInvalidInput, fmt.Sprintf("torrent file larger than %d bytes", schemas.MaxTorrentSize))
+	}
+
+	schema.Torrent, schema.TorrentURL = data, ""
+	return nil
+}
@@ -0,0 +1,106 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	"github.com/gardarr/gardarr/internal/infra/qbtsync"
+)
+
+// DelugeSyncer translates the Deluge torrents into the sync/maindata answers the
+// tracker consumes. Deluge has no deltas, every answer is a full update the
+// tracker compares with its table.
+type DelugeSyncer struct {
+	client *deluge.Client
+
+	mu  sync.Mutex
+	rid int64
+}
+
+// NewDelugeSyncer creates a syncer reading the given Deluge daemon
+func NewDelugeSyncer(client *deluge.Client) *DelugeSyncer {
+	return &DelugeSyncer{
+		client: client,
+	}
+}
+
+// MainData returns every torrent and the daemon totals
+func (s *DelugeSyncer) MainData(ctx context.Context, rid int64) (*qbtsync.MainData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	torrents, err := s.client.TorrentsStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &qbtsync.MainData{
+		FullUpdate: true,
+		Torrents:   make(map[string]map[string]json.RawMessage, len(torrents)),
+	}
+	for hash, item := range torrents {
+		item.Hash = hash
+		data.Torrents[hash] = delugeFields(item)
+	}
+
+	if data.ServerState, err = s.serverState(ctx); err != nil {
+		return nil, err
+	}
+
+	s.rid = rid + 1
+	data.Rid = s.rid
+
+	return data, nil
+}
+
+func (s *DelugeSyncer) serverState(ctx context.Context) (map[string]json.RawMessage, error) {
+	status, err := s.client.GetSessionStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	freeSpace, err := s.client.FreeSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ratio float64
+	if status.TotalPayloadDownload > 0 {
+		ratio = float64(status.TotalPayloadUpload) / float64(status.TotalPayloadDownload)
+	}
+
+	return toFields(serverState{
+		AllTimeDownloaded: status.TotalPayloadDownload,
+		AllTimeUploaded:   status.TotalPayloadUpload,
+		GlobalRatio:       strconv.FormatFloat(ratio, 'f', 2, 64),
+		FreeSpaceOnDisk:   freeSpace,
+	}), nil
+}
+
+// delugeFields writes a Deluge torrent with the sync/maindata field names
+func delugeFields(item deluge.Torrent) map[string]json.RawMessage {
+	return toFields(torrent{
+		Name:          item.Name,
+		State:         item.State(),
+		Category:      item.Label,
+		SavePath:      item.DownloadLocation,
+		Size:          item.TotalWanted,
+		Priority:      item.Queue,
+		Ratio:         max(item.Ratio, 0),
+		Progress:      item.Progress / 100,
+		MagnetURI:     item.MagnetURI(),
+		NumComplete:   item.TotalSeeds,
+		NumIncomplete: item.TotalPeers,
+		NumSeeds:      item.NumSeeds,
+		NumLeechs:     item.NumPeers,
+		Dlspeed:       item.DownloadPayloadRate,
+		Downloaded:    item.AllTimeDownload,
+		Upspeed:       item.UploadPayloadRate,
+		Uploaded:      item.TotalUploaded,
+		SeedingTime:   item.SeedingTime,
+		Tracker:       item.Tracker,
+	})
+}
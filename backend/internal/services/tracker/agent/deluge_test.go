@@ -0,0 +1,119 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	tracker "github.com/gardarr/gardarr/internal/services/tracker/agent"
+)
+
+// fakeDeluge serves a Web UI already connected to its daemon
+type fakeDeluge struct {
+	mu       sync.Mutex
+	torrents map[string]map[string]any
+	uploaded int
+}
+
+func (f *fakeDeluge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		ID     int    `json:"id"`
+		Method string `json:"method"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var result any
+	switch req.Method {
+	case "auth.login", "web.connected":
+		result = true
+	case "core.get_torrents_status":
+		result = f.torrents
+	case "core.get_session_status":
+		result = map[string]any{"total_payload_download": 1000, "total_payload_upload": f.uploaded}
+	case "core.get_free_space":
+		result = 500
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"id": req.ID, "result": result, "error": nil})
+}
+
+func TestTracker_DelugeSyncer(t *testing.T) {
+	fake := &fakeDeluge{
+		torrents: map[string]map[string]any{
+			hashA: {"name": "Movie", "state": "Seeding", "is_finished": true, "progress": 100, "total_uploaded": 100, "label": "movies"},
+			hashB: {"name": "Show", "state": "Paused", "is_finished": true, "progress": 100},
+		},
+		uploaded: 2000,
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := deluge.New(server.URL+"/json", "deluge", "")
+	tr := tracker.NewWithClient(tracker.NewDelugeSyncer(client), time.Minute)
+	ctx := context.Background()
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	initial, sub := tr.Subscribe()
+	defer sub.Close()
+
+	if len(initial) != 1 || len(initial[0].Tasks) != 2 {
+		t.Fatalf("Expected a snapshot with 2 tasks, got %+v", initial)
+	}
+	if initial[0].Instance.Transfer.GlobalRatio != 2 || initial[0].Instance.Server.FreeSpaceOnDisk != 500 {
+		t.Errorf("Expected instance from the session status, got %+v", initial[0].Instance)
+	}
+	if task, _ := tr.Task(hashA); task == nil || task.Category != "movies" || task.Progress != 100 {
+		t.Errorf("Expected seed %s with its label, got %+v", hashA, task)
+	}
+	if task, _ := tr.Task(hashB); task == nil || task.State != "PAUSED_UPLOAD" {
+		t.Errorf("Expected paused seed %s, got %+v", hashB, task)
+	}
+
+	fake.mu.Lock()
+	fake.torrents = map[string]map[string]any{
+		hashA: {"name": "Movie", "state": "Seeding", "is_finished": true, "progress": 100, "total_uploaded": 150, "label": "movies"},
+	}
+	fake.uploaded = 2050
+	fake.mu.Unlock()
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var received []*entities.Event
+	for len(received) < 3 {
+		select {
+		case event := <-sub.C:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 3 events, got %d", len(received))
+		}
+	}
+
+	byType := map[string]*entities.Event{}
+	for _, event := range received {
+		byType[event.Type] = event
+	}
+
+	if updated := byType[entities.EventTaskUpdated]; updated == nil || updated.Task.Network.Upload.Amount != 150 {
+		t.Errorf("Expected update of %s, got %+v", hashA, updated)
+	}
+	if removed := byType[entities.EventTaskRemoved]; removed == nil || removed.TaskID != hashB {
+		t.Errorf("Expected removal of %s, got %+v", hashB, removed)
+	}
+	if instance := byType[entities.EventInstanceUpdated]; instance == nil || instance.Instance.Transfer.AllTimeUploaded != 2050 {
+		t.Errorf("Expected instance update, got %+v", instance)
+	}
+}
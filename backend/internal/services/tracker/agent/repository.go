@@ -0,0 +1,38 @@
+package tracker
+
+import (
+	"github.com/gardarr/gardarr/internal/entities"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+)
+
+// cachedRepository serves task reads from the tracker table once it is synced,
+// instead of listing every torrent from qBittorrent, and delegates everything else
+type cachedRepository struct {
+	taskRepository.RepositoryInterface
+	tracker *Tracker
+}
+
+// WrapRepository returns a task repository reading tasks from the tracker
+func (t *Tracker) WrapRepository(next taskRepository.RepositoryInterface) taskRepository.RepositoryInterface {
+	return &cachedRepository{
+		RepositoryInterface: next,
+		tracker:             t,
+	}
+}
+
+func (r *cachedRepository) List() ([]*entities.Task, error) {
+	if tasks, ok := r.tracker.Tasks(); ok {
+		return tasks, nil
+	}
+
+	return r.RepositoryInterface.List()
+}
+
+func (r *cachedRepository) Get(hash string) (*entities.Task, error) {
+	if task, ok := r.tracker.Task(hash); ok {
+		return task, nil
+	}
+
+	// the task may have been added since the last sync
+	return r.RepositoryInterface.Get(hash)
+}
@@ -0,0 +1,368 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gardarr/gardarr/cmd/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/clientconfig"
+	"github.com/gardarr/gardarr/internal/infra/deluge"
+	"github.com/gardarr/gardarr/internal/infra/events"
+	"github.com/gardarr/gardarr/internal/infra/qbtsync"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	taskRepository "github.com/gardarr/gardarr/internal/repository/task/agent"
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// subscriberBuffer is the number of events a slow SSE client may lag behind
+const subscriberBuffer = 256
+
+// Syncer returns the torrent client state changes since a response id, in the
+// shape of the qBittorrent sync/maindata answers
+type Syncer interface {
+	MainData(ctx context.Context, rid int64) (*qbtsync.MainData, error)
+}
+
+// Tracker keeps an in-memory table of the qBittorrent torrents, fed by the
+// rid-based deltas of sync/maindata, and publishes every change as an event
+type Tracker struct {
+	client   Syncer
+	interval time.Duration
+	broker   *events.Broker
+
+	mu          sync.RWMutex
+	rid         int64
+	ready       bool
+	torrents    map[string]map[string]json.RawMessage
+	tasks       map[string]*entities.Task
+	serverState map[string]json.RawMessage
+	instance    *entities.Instance
+}
+
+func New() *Tracker {
+	interval := env.Get("AGENT_SYNC_INTERVAL").Default("2s").ValuePositiveDuration()
+
+	return NewWithClient(NewSyncer(clientconfig.FromEnv()), interval)
+}
+
+// NewSyncer creates the syncer of the torrent client of the definition
+func NewSyncer(definition clientconfig.Definition) Syncer {
+	switch definition.Type {
+	case entities.ClientTransmission:
+		return NewTransmissionSyncer(transmission.New(definition.URL, definition.Username, definition.Password))
+	case entities.ClientDeluge:
+		return NewDelugeSyncer(deluge.New(definition.URL, definition.Password, definition.HostID))
+	}
+
+	return qbtsync.New(definition.URL, definition.Username, definition.Password)
+}
+
+// NewWithClient creates a tracker polling the given syncer on every interval
+func NewWithClient(client Syncer, interval time.Duration) *Tracker {
+	return &Tracker{
+		client:      client,
+		interval:    interval,
+		broker:      events.NewBroker(subscriberBuffer),
+		torrents:    make(map[string]map[string]json.RawMessage),
+		tasks:       make(map[string]*entities.Task),
+		serverState: make(map[string]json.RawMessage),
+	}
+}
+
+// Run polls the torrent client until ctx is canceled
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("tracker: failed to sync with the torrent client: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches and applies the changes since the last poll
+func (t *Tracker) Poll(ctx context.Context) error {
+	t.mu.RLock()
+	rid := t.rid
+	t.mu.RUnlock()
+
+	data, err := t.client.MainData(ctx, rid)
+	if err != nil {
+		// start over from a full update, the deltas may have been lost
+		t.mu.Lock()
+		t.rid = 0
+		t.mu.Unlock()
+		return err
+	}
+
+	t.apply(data)
+	return nil
+}
+
+// Subscribe returns a snapshot of the current state and the subscription to
+// the following changes. The snapshot is empty until the first sync.
+func (t *Tracker) Subscribe() ([]*entities.Event, *events.Subscription) {
+	// the write lock keeps the snapshot and the subscription consistent with apply
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var initial []*entities.Event
+	if t.ready {
+		initial = append(initial, &entities.Event{
+			Type:     entities.EventSnapshot,
+			Tasks:    t.list(),
+			Instance: t.instance,
+		})
+	}
+
+	return initial, t.broker.Subscribe()
+}
+
+// Tasks returns the tracked tasks. The boolean is false until the first sync.
+func (t *Tracker) Tasks() ([]*entities.Task, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.ready {
+		return nil, false
+	}
+
+	return t.list(), true
+}
+
+// Task returns a tracked task. The boolean is false when the task is unknown or
+// the tracker has not synced yet.
+func (t *Tracker) Task(hash string) (*entities.Task, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	task, ok := t.tasks[hash]
+	return task, ok
+}
+
+func (t *Tracker) list() []*entities.Task {
+	result := make([]*entities.Task, 0, len(t.tasks))
+	for _, task := range t.tasks {
+		result = append(result, task)
+	}
+	return result
+}
+
+// apply merges a sync answer into the table and publishes the resulting events
+func (t *Tracker) apply(data *qbtsync.MainData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changes []*entities.Event
+
+	if data.FullUpdate {
+		previous := t.tasks
+		t.torrents = make(map[string]map[string]json.RawMessage, len(data.Torrents))
+		t.tasks = make(map[string]*entities.Task, len(data.Torrents))
+
+		for hash, fields := range data.Torrents {
+			t.torrents[hash] = fields
+			task := toTask(hash, fields)
+			t.tasks[hash] = task
+
+			old, ok := previous[hash]
+			switch {
+			case !ok:
+				changes = append(changes, &entities.Event{Type: entities.EventTaskAdded, Task: task})
+			case !reflect.DeepEqual(old, task):
+				changes = append(changes, &entities.Event{Type: entities.EventTaskUpdated, Task: task})
+			}
+		}
+
+		for hash := range previous {
+			if _, ok := t.tasks[hash]; !ok {
+				changes = append(changes, &entities.Event{Type: entities.EventTaskRemoved, TaskID: hash})
+			}
+		}
+
+		t.serverState = make(map[string]json.RawMessage, len(data.ServerState))
+	} else {
+		for hash, fields := range data.Torrents {
+			current, ok := t.torrents[hash]
+			if !ok {
+				current = make(map[string]json.RawMessage, len(fields))
+				t.torrents[hash] = current
+			}
+			for key, value := range fields {
+				current[key] = value
+			}
+
+			task := toTask(hash, current)
+			old := t.tasks[hash]
+			t.tasks[hash] = task
+
+			switch {
+			case !ok:
+				changes = append(changes, &entities.Event{Type: entities.EventTaskAdded, Task: task})
+			// fields that are not mapped to the task (e.g. last_activity) change often
+			case !reflect.DeepEqual(old, task):
+				changes = append(changes, &entities.Event{Type: entities.EventTaskUpdated, Task: task})
+			}
+		}
+
+		for _, hash := range data.TorrentsRemoved {
+			if _, ok := t.torrents[hash]; !ok {
+				continue
+			}
+			delete(t.torrents, hash)
+			delete(t.tasks, hash)
+			changes = append(changes, &entities.Event{Type: entities.EventTaskRemoved, TaskID: hash})
+		}
+	}
+
+	for key, value := range data.ServerState {
+		t.serverState[key] = value
+	}
+
+	if instance := toInstance(t.serverState); !reflect.DeepEqual(t.instance, instance) {
+		t.instance = instance
+		// the first sync is not a change, subscribers get it in the snapshot
+		if t.ready {
+			changes = append(changes, &entities.Event{Type: entities.EventInstanceUpdated, Instance: instance})
+		}
+	}
+
+	t.rid = data.Rid
+	t.ready = true
+
+	for _, event := range changes {
+		t.broker.Publish(event)
+	}
+}
+
+// torrent holds the sync/maindata torrent fields mapped to a task
+type torrent struct {
+	Name          string  `json:"name"`
+	State         string  `json:"state"`
+	Category      string  `json:"category"`
+	SavePath      string  `json:"save_path"`
+	Size          int     `json:"size"`
+	Priority      int     `json:"priority"`
+	Ratio         float64 `json:"ratio"`
+	Progress      float64 `json:"progress"`
+	Popularity    float64 `json:"popularity"`
+	MagnetURI     string  `json:"magnet_uri"`
+	NumComplete   int     `json:"num_complete"`
+	NumIncomplete int     `json:"num_incomplete"`
+	NumSeeds      int     `json:"num_seeds"`
+	NumLeechs     int     `json:"num_leechs"`
+	Tags          string  `json:"tags"`
+	Dlspeed       int     `json:"dlspeed"`
+	Downloaded    int     `json:"downloaded"`
+	Upspeed       int     `json:"upspeed"`
+	Uploaded      int     `json:"uploaded"`
+	SeedingTime   int     `json:"seeding_time"`
+	Tracker       string  `json:"tracker"`
+}
+
+func toTask(hash string, fields map[string]json.RawMessage) *entities.Task {
+	var item torrent
+	if raw, err := json.Marshal(fields); err == nil {
+		// fields with an unexpected type keep their zero value
+		_ = json.Unmarshal(raw, &item)
+	}
+
+	status := entities.TaskStatuses[constants.UnknownStatus]
+	if value, ok := entities.TaskStatuses[item.State]; ok {
+		status = value
+	}
+
+	var magnetLink entities.TaskMagnetLink
+	if link, err := taskRepository.ParseMagnetLink(item.MagnetURI); err == nil {
+		magnetLink = *link
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(item.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return &entities.Task{
+		ID:         hash,
+		Name:       item.Name,
+		Hash:       hash,
+		Category:   item.Category,
+		Path:       item.SavePath,
+		State:      status,
+		Size:       item.Size,
+		Priority:   item.Priority,
+		Ratio:      item.Ratio,
+		Progress:   item.Progress * 100,
+		Popularity: item.Popularity,
+		MagnetURI:  item.MagnetURI,
+		MagnetLink: magnetLink,
+		Pairs: entities.TaskPairs{
+			SwarmSeeders:  item.NumComplete,
+			SwarmLeechers: item.NumIncomplete,
+			Seeders:       item.NumSeeds,
+			Leechers:      item.NumLeechs,
+		},
+		NumSeeds:    item.NumSeeds,
+		Tags:        tags,
+		SeedingTime: item.SeedingTime,
+		Tracker:     item.Tracker,
+		Network: entities.TaskNetwork{
+			Download: entities.TaskDownload{
+				Speed:  item.Dlspeed,
+				Amount: item.Downloaded,
+			},
+			Upload: entities.TaskUpload{
+				Speed:  item.Upspeed,
+				Amount: item.Uploaded,
+			},
+		},
+	}
+}
+
+// serverState holds the sync/maindata server_state fields mapped to an instance
+type serverState struct {
+	AllTimeDownloaded     int    `json:"alltime_dl"`
+	AllTimeUploaded       int    `json:"alltime_ul"`
+	GlobalRatio           string `json:"global_ratio"`
+	FreeSpaceOnDisk       int    `json:"free_space_on_disk"`
+	LastExternalAddressV4 string `json:"last_external_address_v4"`
+	LastExternalAddressV6 string `json:"last_external_address_v6"`
+}
+
+func toInstance(fields map[string]json.RawMessage) *entities.Instance {
+	var state serverState
+	if raw, err := json.Marshal(fields); err == nil {
+		_ = json.Unmarshal(raw, &state)
+	}
+
+	ratio, _ := strconv.ParseFloat(state.GlobalRatio, 64)
+
+	return &entities.Instance{
+		Server: entities.InstanceServer{
+			FreeSpaceOnDisk: state.FreeSpaceOnDisk,
+		},
+		Transfer: entities.InstanceTransfer{
+			AllTimeDownloaded:     state.AllTimeDownloaded,
+			AllTimeUploaded:       state.AllTimeUploaded,
+			GlobalRatio:           ratio,
+			LastExternalAddressV4: state.LastExternalAddressV4,
+			LastExternalAddressV6: state.LastExternalAddressV6,
+		},
+	}
+}
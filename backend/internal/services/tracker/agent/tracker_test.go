@@ -0,0 +1,235 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/constants"
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/agentclient"
+	"github.com/gardarr/gardarr/internal/infra/qbtsync"
+	"github.com/gardarr/gardarr/internal/routes/agent/v1/events"
+	tracker "github.com/gardarr/gardarr/internal/services/tracker/agent"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	hashB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// fakeQBittorrent serves scripted sync/maindata answers, one per rid
+type fakeQBittorrent struct {
+	mu        sync.Mutex
+	responses map[int64]string
+	logins    int
+	rids      []int64
+}
+
+func (f *fakeQBittorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v2/auth/login":
+		f.logins++
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+		w.Write([]byte("Ok."))
+	case "/api/v2/sync/maindata":
+		if cookie, err := r.Cookie("SID"); err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		rid, _ := strconv.ParseInt(r.URL.Query().Get("rid"), 10, 64)
+		f.rids = append(f.rids, rid)
+		w.Write([]byte(f.responses[rid]))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func setupTracker(t *testing.T) (*tracker.Tracker, *fakeQBittorrent) {
+	t.Helper()
+
+	fake := &fakeQBittorrent{responses: map[int64]string{
+		0: `{"rid":1,"full_update":true,
+			"torrents":{
+				"` + hashA + `":{"name":"Movie","state":"uploading","category":"movies","progress":1,"uploaded":100,"tags":"hd, x265"},
+				"` + hashB + `":{"name":"Show","state":"downloading","category":"tv","progress":0.5}
+			},
+			"server_state":{"alltime_dl":1000,"alltime_ul":2000,"global_ratio":"2.00","free_space_on_disk":500}}`,
+		1: `{"rid":2,
+			"torrents":{"` + hashA + `":{"uploaded":150,"upspeed":10}},
+			"torrents_removed":["` + hashB + `"],
+			"server_state":{"alltime_ul":2050}}`,
+		2: `{"rid":3}`,
+	}}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return tracker.NewWithClient(qbtsync.New(server.URL, "admin", "secret"), time.Minute), fake
+}
+
+func TestTracker_AppliesDeltas(t *testing.T) {
+	tr, fake := setupTracker(t)
+	ctx := context.Background()
+
+	if _, ok := tr.Tasks(); ok {
+		t.Fatal("Expected tracker not to be ready before the first sync")
+	}
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tasks, ok := tr.Tasks()
+	if !ok || len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks after full update, got %d", len(tasks))
+	}
+
+	initial, sub := tr.Subscribe()
+	defer sub.Close()
+
+	if len(initial) != 1 || initial[0].Type != entities.EventSnapshot || len(initial[0].Tasks) != 2 {
+		t.Fatalf("Expected a snapshot with 2 tasks, got %+v", initial)
+	}
+	if initial[0].Instance.Transfer.GlobalRatio != 2 {
+		t.Errorf("Expected global ratio 2, got %f", initial[0].Instance.Transfer.GlobalRatio)
+	}
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var received []*entities.Event
+	for len(received) < 3 {
+		select {
+		case event := <-sub.C:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 3 events, got %d", len(received))
+		}
+	}
+
+	byType := map[string]*entities.Event{}
+	for _, event := range received {
+		byType[event.Type] = event
+	}
+
+	updated := byType[entities.EventTaskUpdated]
+	if updated == nil || updated.Task.Network.Upload.Amount != 150 || updated.Task.Name != "Movie" {
+		t.Errorf("Expected merged update of %s, got %+v", hashA, updated)
+	}
+	if updated != nil && len(updated.Task.Tags) != 2 {
+		t.Errorf("Expected 2 tags, got %v", updated.Task.Tags)
+	}
+	if removed := byType[entities.EventTaskRemoved]; removed == nil || removed.TaskID != hashB {
+		t.Errorf("Expected removal of %s, got %+v", hashB, removed)
+	}
+	if instance := byType[entities.EventInstanceUpdated]; instance == nil || instance.Instance.Transfer.AllTimeUploaded != 2050 {
+		t.Errorf("Expected instance update, got %+v", instance)
+	}
+
+	// an empty delta publishes nothing
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case event := <-sub.C:
+		t.Errorf("Expected no event, got %s", event.Type)
+	default:
+	}
+
+	if got := fake.rids; len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("Expected rids [0 1 2], got %v", got)
+	}
+	if fake.logins != 1 {
+		t.Errorf("Expected a single login, got %d", fake.logins)
+	}
+}
+
+func TestTracker_StreamsToManagerClient(t *testing.T) {
+	t.Setenv(constants.AgentSecretEnv, "agent-secret")
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	tr, _ := setupTracker(t)
+	ctx := context.Background()
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	events.NewModule(router.Group("/v1"), tr).Register()
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// the stream must end before the server can close
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	received := make(chan *entities.Event, 10)
+	go agentclient.New(server.URL, "agent-secret", agentclient.DefaultConfig()).StreamEvents(streamCtx, func(event *entities.Event) error {
+		received <- event
+		return nil
+	})
+
+	next := func() *entities.Event {
+		select {
+		case event := <-received:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected an event from the agent stream")
+			return nil
+		}
+	}
+
+	snapshot := next()
+	if snapshot.Type != entities.EventSnapshot || len(snapshot.Tasks) != 2 {
+		t.Fatalf("Expected snapshot with 2 tasks, got %+v", snapshot)
+	}
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	types := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		types[next().Type] = true
+	}
+	for _, want := range []string{entities.EventTaskUpdated, entities.EventTaskRemoved, entities.EventInstanceUpdated} {
+		if !types[want] {
+			t.Errorf("Expected %s event, got %v", want, types)
+		}
+	}
+}
+
+func TestTracker_StreamRejectsInvalidToken(t *testing.T) {
+	t.Setenv(constants.AgentSecretEnv, "agent-secret")
+
+	tr, _ := setupTracker(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	events.NewModule(router.Group("/v1"), tr).Register()
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := agentclient.New(server.URL, "wrong", agentclient.DefaultConfig()).StreamEvents(context.Background(), func(event *entities.Event) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected stream to be rejected")
+	}
+}
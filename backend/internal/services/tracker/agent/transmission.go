@@ -0,0 +1,148 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gardarr/gardarr/internal/infra/qbtsync"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+)
+
+// fullSyncEvery is the number of deltas after which the whole torrent list is
+// fetched again, catching the changes Transmission does not flag as activity
+const fullSyncEvery = 30
+
+// TransmissionSyncer translates the Transmission torrents into the sync/maindata
+// answers the tracker consumes. The recently active torrents and the recently
+// removed ones stand for the rid deltas.
+type TransmissionSyncer struct {
+	client *transmission.Client
+
+	mu          sync.Mutex
+	rid         int64
+	hashes      map[int]string // removed torrents are reported by id
+	downloadDir string
+}
+
+// NewTransmissionSyncer creates a syncer reading the given Transmission daemon
+func NewTransmissionSyncer(client *transmission.Client) *TransmissionSyncer {
+	return &TransmissionSyncer{
+		client: client,
+		hashes: make(map[int]string),
+	}
+}
+
+// MainData returns the torrents changed since rid, or all of them when rid is 0
+// or does not follow the previous answer
+func (s *TransmissionSyncer) MainData(ctx context.Context, rid int64) (*qbtsync.MainData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	full := rid == 0 || rid != s.rid || rid%fullSyncEvery == 0
+	data := &qbtsync.MainData{
+		FullUpdate: full,
+		Torrents:   make(map[string]map[string]json.RawMessage),
+	}
+
+	var (
+		torrents []transmission.Torrent
+		removed  []int
+		err      error
+	)
+	if full {
+		session, err := s.client.SessionGet(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.downloadDir = session.DownloadDir
+
+		if torrents, err = s.client.TorrentGet(ctx); err != nil {
+			return nil, err
+		}
+		s.hashes = make(map[int]string, len(torrents))
+	} else if torrents, removed, err = s.client.RecentlyActive(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, item := range torrents {
+		s.hashes[item.ID] = item.HashString
+		data.Torrents[item.HashString] = torrentFields(item)
+	}
+	for _, id := range removed {
+		if hash, ok := s.hashes[id]; ok {
+			delete(s.hashes, id)
+			data.TorrentsRemoved = append(data.TorrentsRemoved, hash)
+		}
+	}
+
+	if data.ServerState, err = s.serverState(ctx); err != nil {
+		return nil, err
+	}
+
+	s.rid = rid + 1
+	data.Rid = s.rid
+
+	return data, nil
+}
+
+func (s *TransmissionSyncer) serverState(ctx context.Context) (map[string]json.RawMessage, error) {
+	stats, err := s.client.SessionStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	freeSpace, err := s.client.FreeSpace(ctx, s.downloadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ratio float64
+	if stats.DownloadedBytes > 0 {
+		ratio = float64(stats.UploadedBytes) / float64(stats.DownloadedBytes)
+	}
+
+	return toFields(serverState{
+		AllTimeDownloaded: stats.DownloadedBytes,
+		AllTimeUploaded:   stats.UploadedBytes,
+		GlobalRatio:       strconv.FormatFloat(ratio, 'f', 2, 64),
+		FreeSpaceOnDisk:   freeSpace,
+	}), nil
+}
+
+// torrentFields writes a Transmission torrent with the sync/maindata field names
+func torrentFields(item transmission.Torrent) map[string]json.RawMessage {
+	seeders, leechers := item.Swarm()
+
+	return toFields(torrent{
+		Name:          item.Name,
+		State:         item.State(),
+		SavePath:      item.DownloadDir,
+		Size:          item.SizeWhenDone,
+		Priority:      item.QueuePosition,
+		Ratio:         max(item.UploadRatio, 0),
+		Progress:      item.PercentDone,
+		MagnetURI:     item.MagnetLink,
+		NumComplete:   seeders,
+		NumIncomplete: leechers,
+		NumSeeds:      item.PeersSendingToUs,
+		NumLeechs:     item.PeersGettingFromUs,
+		Tags:          strings.Join(item.Labels, ","),
+		Dlspeed:       item.RateDownload,
+		Downloaded:    item.DownloadedEver,
+		Upspeed:       item.RateUpload,
+		Uploaded:      item.UploadedEver,
+		SeedingTime:   item.SecondsSeeding,
+		Tracker:       item.Tracker(),
+	})
+}
+
+func toFields(value any) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if raw, err := json.Marshal(value); err == nil {
+		_ = json.Unmarshal(raw, &fields)
+	}
+	return fields
+}
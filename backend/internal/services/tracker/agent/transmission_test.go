@@ -0,0 +1,142 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/transmission"
+	tracker "github.com/gardarr/gardarr/internal/services/tracker/agent"
+)
+
+// fakeTransmission serves every torrent on full listings and the scripted
+// recently active torrents and removals otherwise
+type fakeTransmission struct {
+	mu       sync.Mutex
+	torrents []map[string]any
+	active   []map[string]any
+	removed  []int
+	uploaded int
+}
+
+func (f *fakeTransmission) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Transmission-Session-Id") != "session" {
+		w.Header().Set("X-Transmission-Session-Id", "session")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var req struct {
+		Method    string         `json:"method"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var args map[string]any
+	switch req.Method {
+	case "session-get":
+		args = map[string]any{"version": "4.0.5", "download-dir": "/downloads"}
+	case "session-stats":
+		args = map[string]any{"cumulative-stats": map[string]any{"downloadedBytes": 1000, "uploadedBytes": f.uploaded}}
+	case "free-space":
+		args = map[string]any{"path": "/downloads", "size-bytes": 500}
+	case "torrent-get":
+		if req.Arguments["ids"] == "recently-active" {
+			args = map[string]any{"torrents": f.active, "removed": f.removed}
+		} else {
+			args = map[string]any{"torrents": f.torrents}
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"result": "success", "arguments": args})
+}
+
+func torrent(id int, hash, name string, status int, uploaded int) map[string]any {
+	return map[string]any{
+		"id":                      id,
+		"hashString":              hash,
+		"name":                    name,
+		"status":                  status,
+		"metadataPercentComplete": 1,
+		"percentDone":             1,
+		"uploadedEver":            uploaded,
+		"peersGettingFromUs":      1,
+		"labels":                  []string{"hd"},
+	}
+}
+
+func TestTracker_TransmissionSyncer(t *testing.T) {
+	fake := &fakeTransmission{
+		torrents: []map[string]any{
+			torrent(1, hashA, "Movie", transmission.StatusSeed, 100),
+			torrent(2, hashB, "Show", transmission.StatusStopped, 0),
+		},
+		uploaded: 2000,
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := transmission.New(server.URL+"/transmission/rpc", "", "")
+	tr := tracker.NewWithClient(tracker.NewTransmissionSyncer(client), time.Minute)
+	ctx := context.Background()
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	initial, sub := tr.Subscribe()
+	defer sub.Close()
+
+	if len(initial) != 1 || len(initial[0].Tasks) != 2 {
+		t.Fatalf("Expected a snapshot with 2 tasks, got %+v", initial)
+	}
+	if initial[0].Instance.Transfer.GlobalRatio != 2 || initial[0].Instance.Server.FreeSpaceOnDisk != 500 {
+		t.Errorf("Expected instance from the session stats, got %+v", initial[0].Instance)
+	}
+	if task, _ := tr.Task(hashB); task == nil || task.State != "STOPPED_UPLOAD" || len(task.Tags) != 1 {
+		t.Errorf("Expected stopped seed %s with its labels, got %+v", hashB, task)
+	}
+
+	fake.mu.Lock()
+	fake.active = []map[string]any{torrent(1, hashA, "Movie", transmission.StatusSeed, 150)}
+	fake.removed = []int{2}
+	fake.uploaded = 2050
+	fake.mu.Unlock()
+
+	if err := tr.Poll(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var received []*entities.Event
+	for len(received) < 3 {
+		select {
+		case event := <-sub.C:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 3 events, got %d", len(received))
+		}
+	}
+
+	byType := map[string]*entities.Event{}
+	for _, event := range received {
+		byType[event.Type] = event
+	}
+
+	if updated := byType[entities.EventTaskUpdated]; updated == nil || updated.Task.Network.Upload.Amount != 150 {
+		t.Errorf("Expected update of %s, got %+v", hashA, updated)
+	}
+	if removed := byType[entities.EventTaskRemoved]; removed == nil || removed.TaskID != hashB {
+		t.Errorf("Expected removal of %s, got %+v", hashB, removed)
+	}
+	if instance := byType[entities.EventInstanceUpdated]; instance == nil || instance.Instance.Transfer.AllTimeUploaded != 2050 {
+		t.Errorf("Expected instance update, got %+v", instance)
+	}
+}
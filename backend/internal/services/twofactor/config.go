@@ -0,0 +1,21 @@
+package twofactor
+
+import (
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the two-factor authentication settings
+type Config struct {
+	Issuer       string        // name shown by authenticator apps
+	ChallengeTTL time.Duration // time left to enter the second factor after the password
+}
+
+// LoadConfigFromEnv loads the two-factor configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		Issuer:       env.Get("AUTH_TOTP_ISSUER").Default("Gardarr").Value(),
+		ChallengeTTL: env.Get("AUTH_2FA_CHALLENGE_TTL").Default("5m").ValueDuration(),
+	}
+}
@@ -0,0 +1,317 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/repository/twofactor"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gardarr/gardarr/pkg/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10 // characters, shown as two groups of five
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	challengeTokenLength = 32 // 32 bytes = 256 bits
+	maxChallengeAttempts = 5
+
+	// totpSkew accepts the codes of the previous and next time steps, for clock drift
+	totpSkew = 1
+)
+
+type Service struct {
+	repository *twofactor.Repository
+	crypto     *crypto.CryptoService
+	config     Config
+	now        func() time.Time
+}
+
+func NewService(db *database.Database, c *crypto.CryptoService) *Service {
+	return NewWithConfig(db, c, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with the TOTP issuer and the time left to enter a code
+func NewWithConfig(db *database.Database, c *crypto.CryptoService, config Config) *Service {
+	return &Service{
+		repository: twofactor.NewRepository(db),
+		crypto:     c,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Status returns the second factor of a user
+func (s *Service) Status(ctx context.Context, userUUID uuid.UUID) (*entities.TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, userUUID)
+	if err != nil || !enabled {
+		return &entities.TwoFactorStatus{}, err
+	}
+
+	left, err := s.repository.CountRecoveryCodes(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: int(left)}, nil
+}
+
+// Enabled tells whether a user logs in with a second factor
+func (s *Service) Enabled(ctx context.Context, userUUID uuid.UUID) (bool, error) {
+	secret, err := s.repository.GetTOTP(ctx, userUUID)
+	if err != nil {
+		return false, err
+	}
+
+	return secret != nil && secret.ConfirmedAt != nil, nil
+}
+
+// Enroll generates a TOTP secret for a user. The second factor is only enabled
+// once a code of the secret is confirmed, see Confirm.
+func (s *Service) Enroll(ctx context.Context, user *entities.User) (*entities.TOTPEnrollment, error) {
+	enabled, err := s.Enabled(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is already enabled", pkgerrors.ErrInvalidInput)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate totp secret")
+	}
+
+	encrypted, err := s.crypto.EncryptWithAD(secret, crypto.AssociatedData(user.UUID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.SaveTOTP(ctx, user.UUID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &entities.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables the enrolled TOTP secret of a user with a first code and
+// returns its recovery codes, which are not stored
+func (s *Service) Confirm(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error) {
+	model, err := s.repository.GetTOTP(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil || model.ConfirmedAt != nil {
+		return nil, fmt.Errorf("%w: no pending two-factor enrollment", pkgerrors.ErrInvalidInput)
+	}
+
+	secret, err := s.crypto.DecryptWithAD(model.EncryptedSecret, crypto.AssociatedData(userUUID))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	step, ok := totp.Validate(secret, code, now, totpSkew)
+	if !ok {
+		return nil, pkgerrors.ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.ConfirmTOTP(ctx, userUUID, step, now, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the second factor of a user, confirmed by a TOTP or recovery code
+func (s *Service) Disable(ctx context.Context, userUUID uuid.UUID, code string) error {
+	if err := s.verify(ctx, userUUID, code, code); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteTwoFactor(ctx, userUUID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, confirmed by a
+// TOTP code, and returns the new ones
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userUUID uuid.UUID, code string) ([]string, error) {
+	if err := s.verify(ctx, userUUID, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.ReplaceRecoveryCodes(ctx, userUUID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Reset removes the second factor of a user, for an admin to recover a user
+// who lost both its authenticator and recovery codes
+func (s *Service) Reset(ctx context.Context, userUUID uuid.UUID) error {
+	return s.repository.DeleteTwoFactor(ctx, userUUID)
+}
+
+// Challenge issues a login challenge for a user whose password was verified
+func (s *Service) Challenge(ctx context.Context, userUUID uuid.UUID) (*entities.LoginChallenge, error) {
+	token, err := crypto.GenerateToken("", challengeTokenLength)
+	if err != nil {
+		return nil, errors.New("failed to generate challenge token")
+	}
+
+	expiresAt := s.now().Add(s.config.ChallengeTTL)
+	if err := s.repository.CreateChallenge(ctx, userUUID, crypto.HashToken(token), expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &entities.LoginChallenge{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// CompleteChallenge answers a login challenge with a TOTP code or a recovery
+// code and returns the user to create a session for. A challenge is deleted
+// once answered, expired or failed too many times.
+func (s *Service) CompleteChallenge(ctx context.Context, challengeToken, code, recoveryCode string) (*entities.User, error) {
+	challenge, err := s.repository.GetChallenge(ctx, crypto.HashToken(challengeToken))
+	if err != nil {
+		return nil, pkgerrors.ErrInvalidCode
+	}
+
+	if s.now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts || challenge.User.Disabled {
+		_ = s.repository.DeleteChallenge(ctx, challenge.ID)
+		return nil, fmt.Errorf("%w: login challenge expired", pkgerrors.ErrInvalidCode)
+	}
+
+	if err := s.verify(ctx, challenge.UserUUID, code, recoveryCode); err != nil {
+		if errors.Is(err, pkgerrors.ErrInvalidCode) {
+			_ = s.repository.RecordChallengeFailure(ctx, challenge.ID)
+		}
+		return nil, err
+	}
+
+	if err := s.repository.DeleteChallenge(ctx, challenge.ID); err != nil {
+		return nil, err
+	}
+
+	return toUser(challenge.User), nil
+}
+
+// ChallengeUser returns the user a pending login challenge was issued to, so
+// that the failed answers are counted against their account
+func (s *Service) ChallengeUser(ctx context.Context, challengeToken string) (*entities.User, error) {
+	challenge, err := s.repository.GetChallenge(ctx, crypto.HashToken(challengeToken))
+	if err != nil {
+		return nil, pkgerrors.ErrInvalidCode
+	}
+
+	return toUser(challenge.User), nil
+}
+
+// toUser converts the user loaded with a login challenge
+func toUser(model models.User) *entities.User {
+	return &entities.User{
+		UUID:      model.UUID,
+		Username:  model.Username,
+		Email:     model.Email,
+		Role:      model.Role,
+		Disabled:  model.Disabled,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+// verify checks a TOTP code, or else a recovery code, of a user. Each code is
+// only accepted once.
+func (s *Service) verify(ctx context.Context, userUUID uuid.UUID, code, recoveryCode string) error {
+	model, err := s.repository.GetTOTP(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	if model == nil || model.ConfirmedAt == nil {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", pkgerrors.ErrInvalidInput)
+	}
+
+	if code != "" {
+		secret, err := s.crypto.DecryptWithAD(model.EncryptedSecret, crypto.AssociatedData(userUUID))
+		if err != nil {
+			return err
+		}
+
+		if step, ok := totp.Validate(secret, code, s.now(), totpSkew); ok {
+			used, err := s.repository.UseStep(ctx, userUUID, step)
+			if err != nil {
+				return err
+			}
+			if used {
+				return nil
+			}
+		}
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repository.UseRecoveryCode(ctx, userUUID, hashRecoveryCode(recoveryCode), s.now())
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+
+	return pkgerrors.ErrInvalidCode
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		random := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, errors.New("failed to generate recovery codes")
+		}
+
+		var code strings.Builder
+		for j, b := range random {
+			if j == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+			// The alphabet size divides 256 unevenly, the bias is negligible
+			code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+
+		codes[i] = code.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring its case and separators
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return crypto.HashToken(normalized)
+}
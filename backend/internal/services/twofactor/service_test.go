@@ -0,0 +1,188 @@
+package twofactor
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/gardarr/gardarr/internal/entities"
+	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/gardarr/gardarr/pkg/totp"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *database.Database, *entities.User) {
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	cryptoSvc, err := crypto.NewCryptoService()
+	if err != nil {
+		t.Fatalf("failed to create crypto service: %v", err)
+	}
+
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := gdb.AutoMigrate(&models.User{}, &models.UserTOTP{}, &models.RecoveryCode{}, &models.LoginChallenge{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db := &database.Database{DB: gdb}
+
+	model := &models.User{Username: "admin@example.com", Email: "admin@example.com", PasswordHash: "hash", Salt: "salt", Role: entities.RoleAdmin}
+	if err := gdb.Create(model).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	user := &entities.User{UUID: model.UUID, Email: model.Email, Role: model.Role}
+
+	return NewWithConfig(db, cryptoSvc, Config{Issuer: "Gardarr", ChallengeTTL: time.Minute}), db, user
+}
+
+// codeAt returns the TOTP code of the secret, steps time steps from now
+func codeAt(t *testing.T, secret string, steps int64) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+steps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return code
+}
+
+func TestEnrollAndConfirm(t *testing.T) {
+	service, db, user := setupTestService(t)
+	ctx := context.Background()
+
+	enrollment, err := service.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enrollment.ProvisioningURI == "" || enrollment.Secret == "" {
+		t.Fatalf("Expected a secret and its provisioning URI, got %+v", enrollment)
+	}
+
+	var stored models.UserTOTP
+	if err := db.DB.First(&stored, "user_uuid = ?", user.UUID).Error; err != nil {
+		t.Fatalf("failed to read the stored secret: %v", err)
+	}
+	if stored.EncryptedSecret == enrollment.Secret {
+		t.Error("Expected the secret to be stored encrypted")
+	}
+
+	if enabled, _ := service.Enabled(ctx, user.UUID); enabled {
+		t.Error("Expected the second factor to wait for confirmation")
+	}
+	if _, err := service.Confirm(ctx, user.UUID, codeAt(t, enrollment.Secret, 10)); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+
+	codes, err := service.Confirm(ctx, user.UUID, codeAt(t, enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	status, err := service.Status(ctx, user.UUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount {
+		t.Errorf("Expected an enabled second factor with every recovery code, got %+v", status)
+	}
+
+	if _, err := service.Enroll(ctx, user); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected a second enrollment to be rejected, got %v", err)
+	}
+}
+
+func TestCompleteChallenge(t *testing.T) {
+	service, _, user := setupTestService(t)
+	ctx := context.Background()
+
+	enrollment, err := service.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	codes, err := service.Confirm(ctx, user.UUID, codeAt(t, enrollment.Secret, -1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	challenge, err := service.Challenge(ctx, user.UUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	challengeUser, err := service.ChallengeUser(ctx, challenge.Token)
+	if err != nil || challengeUser.UUID != user.UUID {
+		t.Fatalf("Expected the user of the challenge, got %v (%v)", challengeUser, err)
+	}
+	if _, err := service.ChallengeUser(ctx, "unknown"); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected an unknown challenge to be rejected, got %v", err)
+	}
+
+	// The code confirming the enrollment cannot be replayed
+	if _, err := service.CompleteChallenge(ctx, challenge.Token, codeAt(t, enrollment.Secret, -1), ""); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+
+	authenticated, err := service.CompleteChallenge(ctx, challenge.Token, codeAt(t, enrollment.Secret, 0), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authenticated.UUID != user.UUID {
+		t.Errorf("Expected user %s, got %s", user.UUID, authenticated.UUID)
+	}
+
+	// A challenge is answered once
+	if _, err := service.CompleteChallenge(ctx, challenge.Token, "", codes[0]); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected an answered challenge to be rejected, got %v", err)
+	}
+
+	// Recovery codes are single use
+	challenge, _ = service.Challenge(ctx, user.UUID)
+	if _, err := service.CompleteChallenge(ctx, challenge.Token, "", codes[0]); err != nil {
+		t.Fatalf("Expected the recovery code to be accepted, got %v", err)
+	}
+	challenge, _ = service.Challenge(ctx, user.UUID)
+	if _, err := service.CompleteChallenge(ctx, challenge.Token, "", codes[0]); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+
+	// Too many wrong answers end the challenge
+	for i := 0; i < maxChallengeAttempts; i++ {
+		service.CompleteChallenge(ctx, challenge.Token, "", "wrong-code")
+	}
+	if _, err := service.CompleteChallenge(ctx, challenge.Token, "", codes[1]); !errors.Is(err, errors.ErrInvalidCode) {
+		t.Errorf("Expected the challenge to be over, got %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	service, _, user := setupTestService(t)
+	ctx := context.Background()
+
+	enrollment, err := service.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.Confirm(ctx, user.UUID, codeAt(t, enrollment.Secret, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.Reset(ctx, user.UUID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status, err := service.Status(ctx, user.UUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Enabled || status.RecoveryCodesLeft != 0 {
+		t.Errorf("Expected the second factor to be removed, got %+v", status)
+	}
+}
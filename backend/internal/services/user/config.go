@@ -0,0 +1,57 @@
+package user
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gardarr/gardarr/pkg/env"
+)
+
+// Config holds the registration and password settings
+type Config struct {
+	// RegistrationEnabled lets anyone register once the first user, who becomes
+	// admin, exists. Otherwise registering requires an invite.
+	RegistrationEnabled bool
+	RegistrationRole    string        // role of the users registering without an invite
+	InviteTTL           time.Duration // lifetime of an invite
+	ResetTTL            time.Duration // lifetime of a password reset token
+	HashParams          HashParams    // argon2id parameters of new password hashes, the defaults when zero
+	// PasswordLoginEnabled lets users log in with a password. Turning it off
+	// leaves single sign-on as the only way in, it is ignored without it.
+	PasswordLoginEnabled bool
+}
+
+// LoadConfigFromEnv loads the registration and password configuration from environment variables
+func LoadConfigFromEnv() Config {
+	return Config{
+		RegistrationEnabled:  env.Get("AUTH_REGISTRATION_ENABLED").Default("false").ValueBool(),
+		RegistrationRole:     env.Get("AUTH_REGISTRATION_ROLE").Default("viewer").Value(),
+		InviteTTL:            env.Get("AUTH_INVITE_TTL").Default("72h").ValueDuration(),
+		ResetTTL:             env.Get("AUTH_PASSWORD_RESET_TTL").Default("24h").ValueDuration(),
+		PasswordLoginEnabled: env.Get("AUTH_PASSWORD_LOGIN_ENABLED").Default("true").ValueBool(),
+		HashParams:           loadHashParams(),
+	}
+}
+
+// loadHashParams reads the argon2id parameters, falling back to the defaults
+// when they are out of range: argon2 panics on zero time or threads, and a
+// negative memory would wrap to a huge one
+func loadHashParams() HashParams {
+	iterations := env.Get("AUTH_ARGON2_TIME").Default(strconv.Itoa(argon2Time)).ValueInt()
+	memory := env.Get("AUTH_ARGON2_MEMORY").Default(strconv.Itoa(argon2Memory)).ValueInt()
+	threads := env.Get("AUTH_ARGON2_THREADS").Default(strconv.Itoa(argon2Threads)).ValueInt()
+
+	if iterations < 1 || iterations > math.MaxUint32 || memory < 1 || memory > math.MaxUint32 || threads < 1 || threads > math.MaxUint8 {
+		log.Printf("user: invalid argon2 parameters t=%d m=%d p=%d, using the defaults", iterations, memory, threads)
+		return DefaultHashParams()
+	}
+
+	params := HashParams{Time: uint32(iterations), Memory: uint32(memory), Threads: uint8(threads)}
+	if !params.valid() {
+		log.Printf("user: argon2 memory %d KiB below 8 KiB per thread, using the defaults", memory)
+		return DefaultHashParams()
+	}
+	return params
+}
@@ -0,0 +1,120 @@
+package user
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// phcPrefix starts the PHC string of an argon2id hash. Hashes without it were
+// stored before the parameters were encoded, as a bare hash next to its salt.
+const phcPrefix = "$argon2id$"
+
+// HashParams are the argon2id parameters new password hashes are created with.
+// Raising them rehashes the passwords of the users as they log in.
+type HashParams struct {
+	Time    uint32 // iterations
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// DefaultHashParams returns the parameters used when none are configured
+func DefaultHashParams() HashParams {
+	return HashParams{Time: argon2Time, Memory: argon2Memory, Threads: argon2Threads}
+}
+
+// valid tells whether argon2 accepts the parameters: at least one iteration and
+// one thread, and 8 KiB of memory per thread
+func (p HashParams) valid() bool {
+	return p.Time >= 1 && p.Threads >= 1 && p.Memory >= 8*uint32(p.Threads)
+}
+
+// encodePassword hashes a password with a new salt and returns its PHC string:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func encodePassword(password string, params HashParams) (string, error) {
+	salt, err := generateSalt()
+	if err != nil {
+		return "", errors.New("failed to generate salt")
+	}
+	saltBytes, _ := base64.StdEncoding.DecodeString(salt)
+
+	hash := argon2.IDKey([]byte(password), saltBytes, params.Time, params.Memory, params.Threads, argon2KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		phcPrefix, argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(saltBytes),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// decodePassword parses the PHC string of an argon2id hash
+func decodePassword(encoded string) (HashParams, []byte, []byte, error) {
+	var params HashParams
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("invalid password hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if !params.valid() {
+		return params, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid hash: %w", err)
+	}
+
+	return params, salt, hash, nil
+}
+
+// checkPassword compares a password with its stored hash in constant time. The
+// salt is only used by the hashes stored before the PHC format. It also tells
+// whether the hash should be replaced, being legacy or using other parameters.
+func checkPassword(password, stored, legacySalt string, current HashParams) (bool, bool) {
+	if !strings.HasPrefix(stored, phcPrefix) {
+		provided := hashPassword(password, legacySalt)
+		return subtle.ConstantTimeCompare([]byte(provided), []byte(stored)) == 1, true
+	}
+
+	params, salt, hash, err := decodePassword(stored)
+	if err != nil {
+		return false, false
+	}
+
+	provided := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(provided, hash) != 1 {
+		return false, false
+	}
+
+	return true, params != current || len(hash) != argon2KeyLen
+}
+
+// validatePassword checks a new password against the password policy
+func validatePassword(password string) error {
+	if password == "" {
+		return errors.New("password is required")
+	}
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	return nil
+}
@@ -5,13 +5,21 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"log"
+	"slices"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 
 	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
+	"github.com/gardarr/gardarr/internal/repository/session"
 	"github.com/gardarr/gardarr/internal/repository/user"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	pkgerrors "github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
 )
 
 const (
@@ -23,43 +31,142 @@ const (
 	argon2Threads = 4
 	argon2KeyLen  = 32
 	saltLength    = 16
+
+	inviteTokenLength = 32
+	resetTokenLength  = 32
 )
 
 type Service struct {
 	repository *user.Repository
+	sessions   *session.Repository
+	config     Config
+	now        func() time.Time
 }
 
 func NewService(db *database.Database) *Service {
+	return NewWithConfig(db, LoadConfigFromEnv())
+}
+
+// NewWithConfig creates the service with the given registration settings
+func NewWithConfig(db *database.Database, config Config) *Service {
+	if config.HashParams == (HashParams{}) {
+		config.HashParams = DefaultHashParams()
+	}
+
 	return &Service{
 		repository: user.NewRepository(db),
+		sessions:   session.NewRepository(db),
+		config:     config,
+		now:        time.Now,
 	}
 }
 
-// CreateUser creates a new user with email and password
+// PasswordLoginEnabled tells whether users may log in with a password
+func (s *Service) PasswordLoginEnabled() bool {
+	return s.config.PasswordLoginEnabled
+}
+
+// Register creates a user through the public registration. The first user
+// becomes admin. Afterwards a valid invite is required, unless registration is
+// enabled by config.
+func (s *Service) Register(ctx context.Context, email, password, inviteToken string) (*entities.User, error) {
+	// Without an invite nor users, closed registration is refused before hashing
+	if inviteToken == "" && !s.config.RegistrationEnabled {
+		count, err := s.repository.CountUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, pkgerrors.ErrRegistrationOff
+		}
+	}
+
+	email, passwordHash, err := s.prepareUser(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The invite is only used along with the creation of the user, and the
+	// count holds until then, so concurrent registrations cannot both be first
+	var created *entities.User
+	err = s.repository.Transaction(ctx, func(repo *user.Repository) error {
+		if err := repo.LockUsers(ctx); err != nil {
+			return err
+		}
+
+		count, err := repo.CountUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		role := s.config.RegistrationRole
+		switch {
+		case count == 0:
+			role = entities.RoleAdmin
+		case inviteToken != "":
+			invite, err := repo.UseInvite(ctx, crypto.HashToken(inviteToken), s.now())
+			if err != nil {
+				return fmt.Errorf("%w: %v", pkgerrors.ErrRegistrationOff, err)
+			}
+			if !strings.EqualFold(invite.Email, email) {
+				return fmt.Errorf("%w: the invite was sent to another email", pkgerrors.ErrRegistrationOff)
+			}
+			role = invite.Role
+		case !s.config.RegistrationEnabled:
+			return pkgerrors.ErrRegistrationOff
+		}
+
+		if !slices.Contains(entities.Roles, role) {
+			return fmt.Errorf("%w: unknown role %q", pkgerrors.ErrInvalidInput, role)
+		}
+
+		created, err = repo.CreateUserWithRole(ctx, email, passwordHash, "", role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// CreateUser creates a new viewer with email and password
 func (s *Service) CreateUser(ctx context.Context, email, password string) (*entities.User, error) {
-	// Validate input
+	return s.createUser(ctx, email, password, entities.RoleViewer)
+}
+
+func (s *Service) createUser(ctx context.Context, email, password, role string) (*entities.User, error) {
+	if !slices.Contains(entities.Roles, role) {
+		return nil, fmt.Errorf("%w: unknown role %q", pkgerrors.ErrInvalidInput, role)
+	}
+
+	email, passwordHash, err := s.prepareUser(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create user in repository
+	return s.repository.CreateUserWithRole(ctx, email, passwordHash, "", role)
+}
+
+// prepareUser validates the email and password of a new user, returning the
+// normalized email and the password hash
+func (s *Service) prepareUser(email, password string) (string, string, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
 	if email == "" {
-		return nil, errors.New("email is required")
-	}
-	if password == "" {
-		return nil, errors.New("password is required")
+		return "", "", errors.New("email is required")
 	}
-	if len(password) < 8 {
-		return nil, errors.New("password must be at least 8 characters")
+	if err := validatePassword(password); err != nil {
+		return "", "", err
 	}
 
-	// Generate salt
-	salt, err := generateSalt()
+	// Hash password with argon2, the salt is part of the encoded hash
+	passwordHash, err := encodePassword(password, s.config.HashParams)
 	if err != nil {
-		return nil, errors.New("failed to generate salt")
+		return "", "", err
 	}
 
-	// Hash password with argon2
-	passwordHash := hashPassword(password, salt)
-
-	// Create user in repository
-	return s.repository.CreateUser(ctx, email, passwordHash, salt)
+	return email, passwordHash, nil
 }
 
 // VerifyPassword verifies if the provided password matches the user's password
@@ -72,29 +179,304 @@ func (s *Service) VerifyPassword(ctx context.Context, email, password string) (*
 		return nil, err
 	}
 
-	// Hash the provided password with the stored salt
-	providedHash := hashPassword(password, userModel.Salt)
-
-	// Compare hashes
-	if providedHash != userModel.PasswordHash {
+	ok, rehash := checkPassword(password, userModel.PasswordHash, userModel.Salt, s.config.HashParams)
+	if !ok {
 		return nil, errors.New("invalid credentials")
 	}
 
+	if userModel.Disabled {
+		return nil, errors.New("user disabled")
+	}
+
+	// Legacy hashes and hashes with outdated parameters are replaced while the
+	// password is at hand, a failure only delays it to the next login
+	if rehash {
+		if passwordHash, err := encodePassword(password, s.config.HashParams); err == nil {
+			if err := s.repository.UpdatePassword(ctx, userModel.UUID, passwordHash); err != nil {
+				log.Printf("user: failed to rehash the password of %s: %v", userModel.UUID, err)
+			}
+		}
+	}
+
 	// Convert to entity
 	return &entities.User{
 		UUID:      userModel.UUID,
 		Username:  userModel.Username,
 		Email:     userModel.Email,
+		Role:      userModel.Role,
+		Disabled:  userModel.Disabled,
 		CreatedAt: userModel.CreatedAt,
 		UpdatedAt: userModel.UpdatedAt,
 	}, nil
 }
 
+// ChangePassword replaces the password of a user after checking the current
+// one. Every session of the user is ended, the caller starts a new one.
+func (s *Service) ChangePassword(ctx context.Context, userUUID uuid.UUID, currentPassword, newPassword string) error {
+	userModel, err := s.repository.GetUserModel(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := checkPassword(currentPassword, userModel.PasswordHash, userModel.Salt, s.config.HashParams); !ok {
+		return fmt.Errorf("%w: the current password is wrong", pkgerrors.ErrInvalidInput)
+	}
+
+	return s.setPassword(ctx, userUUID, newPassword)
+}
+
+// IssuePasswordReset creates a single use token to reset the password of a
+// user, replacing its previous ones
+func (s *Service) IssuePasswordReset(ctx context.Context, id string) (*entities.PasswordReset, error) {
+	target, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issuePasswordReset(ctx, target.UUID)
+}
+
+// IssuePasswordResetByEmail creates a password reset token for the user with
+// the given email
+func (s *Service) IssuePasswordResetByEmail(ctx context.Context, email string) (*entities.PasswordReset, error) {
+	userModel, err := s.repository.GetUserByEmail(ctx, strings.TrimSpace(strings.ToLower(email)))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issuePasswordReset(ctx, userModel.UUID)
+}
+
+func (s *Service) issuePasswordReset(ctx context.Context, userUUID uuid.UUID) (*entities.PasswordReset, error) {
+	token, err := crypto.GenerateToken("", resetTokenLength)
+	if err != nil {
+		return nil, errors.New("failed to generate reset token")
+	}
+
+	reset := &entities.PasswordReset{
+		UserUUID:  userUUID,
+		Token:     token,
+		ExpiresAt: s.now().Add(s.config.ResetTTL),
+	}
+	if err := s.repository.CreatePasswordReset(ctx, userUUID, crypto.HashToken(token), reset.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return reset, nil
+}
+
+// ResetPassword sets a new password with a reset token. Every session of the
+// user is ended.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", pkgerrors.ErrInvalidInput, err)
+	}
+
+	userUUID, err := s.repository.UsePasswordReset(ctx, crypto.HashToken(token), s.now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", pkgerrors.ErrInvalidInput, err)
+	}
+
+	return s.setPassword(ctx, userUUID, newPassword)
+}
+
+func (s *Service) setPassword(ctx context.Context, userUUID uuid.UUID, password string) error {
+	if err := validatePassword(password); err != nil {
+		return fmt.Errorf("%w: %v", pkgerrors.ErrInvalidInput, err)
+	}
+
+	passwordHash, err := encodePassword(password, s.config.HashParams)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.UpdatePassword(ctx, userUUID, passwordHash); err != nil {
+		return err
+	}
+
+	return s.sessions.DeleteUserSessions(ctx, userUUID)
+}
+
 // GetUserByUUID retrieves a user by UUID
 func (s *Service) GetUserByUUID(ctx context.Context, uuid string) (*entities.User, error) {
 	return s.repository.GetUserByUUID(ctx, uuid)
 }
 
+// ListUsers returns every user
+func (s *Service) ListUsers(ctx context.Context) ([]*entities.User, error) {
+	return s.repository.ListUsers(ctx)
+}
+
+// Invite creates an invite to register with the given role. The returned
+// invite holds the token, which is not stored.
+func (s *Service) Invite(ctx context.Context, inviter *entities.User, email, role string) (*entities.UserInvite, error) {
+	if !slices.Contains(entities.Roles, role) {
+		return nil, fmt.Errorf("%w: unknown role %q", pkgerrors.ErrInvalidInput, role)
+	}
+
+	token, err := crypto.GenerateToken("", inviteTokenLength)
+	if err != nil {
+		return nil, errors.New("failed to generate invite token")
+	}
+
+	invite := &entities.UserInvite{
+		Email:     strings.TrimSpace(strings.ToLower(email)),
+		Role:      role,
+		Token:     token,
+		InvitedBy: inviter.UUID,
+		ExpiresAt: s.now().Add(s.config.InviteTTL),
+	}
+	if err := s.repository.CreateInvite(ctx, invite, crypto.HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// SetRole changes the role of a user. The last active admin cannot be demoted.
+func (s *Service) SetRole(ctx context.Context, id, role string) (*entities.User, error) {
+	if !slices.Contains(entities.Roles, role) {
+		return nil, fmt.Errorf("%w: unknown role %q", pkgerrors.ErrInvalidInput, role)
+	}
+
+	target, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if role != entities.RoleAdmin && target.IsAdmin() {
+		if err := s.ensureAnotherAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repository.UpdateRole(ctx, target.UUID, role); err != nil {
+		return nil, err
+	}
+
+	target.Role = role
+	return target, nil
+}
+
+// SetDisabled disables or enables a user. Disabling a user ends its sessions,
+// the last active admin cannot be disabled.
+func (s *Service) SetDisabled(ctx context.Context, id string, disabled bool) (*entities.User, error) {
+	target, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled && target.IsAdmin() {
+		if err := s.ensureAnotherAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repository.SetDisabled(ctx, target.UUID, disabled); err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := s.sessions.DeleteUserSessions(ctx, target.UUID); err != nil {
+			return nil, err
+		}
+	}
+
+	target.Disabled = disabled
+	return target, nil
+}
+
+func (s *Service) ensureAnotherAdmin(ctx context.Context) error {
+	admins, err := s.repository.CountActiveAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return fmt.Errorf("%w: at least one active admin is required", pkgerrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// AgentGrants returns the agents a user was granted
+func (s *Service) AgentGrants(ctx context.Context, id string) ([]uuid.UUID, error) {
+	target, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetAgentGrants(ctx, target.UUID)
+}
+
+// SetAgentGrants replaces the agents a user was granted
+func (s *Service) SetAgentGrants(ctx context.Context, id string, agentIDs []string) ([]uuid.UUID, error) {
+	target, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	agentUUIDs := make([]uuid.UUID, 0, len(agentIDs))
+	for _, agentID := range agentIDs {
+		agentUUID, err := uuid.Parse(agentID)
+		if err != nil {
+			return nil, pkgerrors.ErrInvalidUUID
+		}
+		if !slices.Contains(agentUUIDs, agentUUID) {
+			agentUUIDs = append(agentUUIDs, agentUUID)
+		}
+	}
+
+	if err := s.repository.SetAgentGrants(ctx, target.UUID, agentUUIDs); err != nil {
+		return nil, err
+	}
+
+	return agentUUIDs, nil
+}
+
+// CanAccessAgent tells whether a user may see and act on an agent
+func (s *Service) CanAccessAgent(ctx context.Context, u *entities.User, agentID string) (bool, error) {
+	if u.IsAdmin() {
+		return true, nil
+	}
+	if u == nil || u.Disabled {
+		return false, nil
+	}
+
+	agentUUID, err := uuid.Parse(agentID)
+	if err != nil {
+		return false, pkgerrors.ErrInvalidUUID
+	}
+
+	return s.repository.HasAgentGrant(ctx, u.UUID, agentUUID)
+}
+
+// AgentFilter returns a function telling whether a user may see an agent,
+// loading the grants of the user once
+func (s *Service) AgentFilter(ctx context.Context, u *entities.User) (func(uuid.UUID) bool, error) {
+	if u.IsAdmin() {
+		return func(uuid.UUID) bool { return true }, nil
+	}
+	if u == nil || u.Disabled {
+		return func(uuid.UUID) bool { return false }, nil
+	}
+
+	grants, err := s.repository.GetAccessibleAgents(ctx, u.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(agentUUID uuid.UUID) bool {
+		return slices.Contains(grants, agentUUID)
+	}, nil
+}
+
+func (s *Service) get(ctx context.Context, id string) (*entities.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, pkgerrors.ErrInvalidUUID
+	}
+
+	return s.repository.GetUserByUUID(ctx, id)
+}
+
 // generateSalt generates a random salt for password hashing
 func generateSalt() (string, error) {
 	salt := make([]byte, saltLength)
@@ -104,7 +486,9 @@ func generateSalt() (string, error) {
 	return base64.StdEncoding.EncodeToString(salt), nil
 }
 
-// hashPassword hashes a password using argon2id with the provided salt
+// hashPassword hashes a password using argon2id with the provided salt and the
+// default parameters, the format passwords were stored in before the PHC strings
+// of encodePassword. It is only used to check these passwords.
 func hashPassword(password, salt string) string {
 	saltBytes, _ := base64.StdEncoding.DecodeString(salt)
 	hash := argon2.IDKey([]byte(password), saltBytes, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
@@ -2,10 +2,16 @@ package user
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gardarr/gardarr/internal/entities"
 	"github.com/gardarr/gardarr/internal/infra/database"
 	"github.com/gardarr/gardarr/internal/models"
+	"github.com/gardarr/gardarr/internal/services/crypto"
+	"github.com/gardarr/gardarr/pkg/errors"
+	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -17,7 +23,7 @@ func setupTestDB(t *testing.T) *database.Database {
 	}
 
 	// Auto migrate models
-	if err := db.AutoMigrate(&models.User{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.Agent{}, &models.AgentPermission{}, &models.UserInvite{}, &models.PasswordReset{}); err != nil {
 		t.Fatalf("failed to migrate test database: %v", err)
 	}
 
@@ -187,6 +193,33 @@ func TestPasswordHashing(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFromEnv_HashParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected HashParams
+	}{
+		{"custom", map[string]string{"AUTH_ARGON2_TIME": "2", "AUTH_ARGON2_MEMORY": "32768", "AUTH_ARGON2_THREADS": "4"}, HashParams{Time: 2, Memory: 32768, Threads: 4}},
+		{"zero time", map[string]string{"AUTH_ARGON2_TIME": "0"}, DefaultHashParams()},
+		{"unparsable memory", map[string]string{"AUTH_ARGON2_MEMORY": "64MB"}, DefaultHashParams()},
+		{"negative memory", map[string]string{"AUTH_ARGON2_MEMORY": "-1"}, DefaultHashParams()},
+		{"threads overflowing", map[string]string{"AUTH_ARGON2_THREADS": "256"}, DefaultHashParams()},
+		{"memory below 8 KiB per thread", map[string]string{"AUTH_ARGON2_MEMORY": "16", "AUTH_ARGON2_THREADS": "4"}, DefaultHashParams()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if params := LoadConfigFromEnv().HashParams; params != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, params)
+			}
+		})
+	}
+}
+
 func TestGetUserByUUID(t *testing.T) {
 	db := setupTestDB(t)
 	service := NewService(db)
@@ -221,3 +254,327 @@ func TestGetUserByUUID(t *testing.T) {
 		t.Errorf("expected error for non-existent user but got none")
 	}
 }
+
+func TestRegister_FirstUserBecomesAdmin(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{RegistrationRole: entities.RoleViewer, InviteTTL: time.Hour})
+	ctx := context.Background()
+
+	first, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Role != entities.RoleAdmin {
+		t.Errorf("Expected first user to be admin, got %s", first.Role)
+	}
+
+	// Registration is closed afterwards
+	if _, err := service.Register(ctx, "other@example.com", "SecurePass123", ""); !errors.Is(err, errors.ErrRegistrationOff) {
+		t.Errorf("Expected ErrRegistrationOff, got %v", err)
+	}
+
+	open := NewWithConfig(db, Config{RegistrationEnabled: true, RegistrationRole: entities.RoleViewer})
+	second, err := open.Register(ctx, "other@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Role != entities.RoleViewer {
+		t.Errorf("Expected viewer, got %s", second.Role)
+	}
+}
+
+func TestRegister_WithInvite(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{RegistrationRole: entities.RoleViewer, InviteTTL: time.Hour})
+	ctx := context.Background()
+
+	admin, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invite, err := service.Invite(ctx, admin, "Operator@example.com", entities.RoleOperator)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if invite.Token == "" {
+		t.Fatal("Expected the invite token to be returned")
+	}
+
+	if _, err := service.Register(ctx, "someone@example.com", "SecurePass123", invite.Token); !errors.Is(err, errors.ErrRegistrationOff) {
+		t.Errorf("Expected an invite sent to another email to be rejected, got %v", err)
+	}
+
+	// A failed registration leaves the invite unused
+	if _, err := service.Register(ctx, "operator@example.com", "weak", invite.Token); err == nil {
+		t.Error("Expected a weak password to be rejected, got nil")
+	}
+	if _, err := service.CreateUser(ctx, "taken@example.com", "SecurePass123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	taken, err := service.Invite(ctx, admin, "taken@example.com", entities.RoleOperator)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.Register(ctx, "taken@example.com", "SecurePass123", taken.Token); err == nil {
+		t.Error("Expected an email already registered to be rejected, got nil")
+	}
+	if _, err := service.repository.UseInvite(ctx, crypto.HashToken(taken.Token), time.Now()); err != nil {
+		t.Errorf("Expected the invite to be left unused by a failed insert, got %v", err)
+	}
+
+	operator, err := service.Register(ctx, "operator@example.com", "SecurePass123", invite.Token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if operator.Role != entities.RoleOperator {
+		t.Errorf("Expected operator, got %s", operator.Role)
+	}
+
+	// An invite is single use
+	if _, err := service.Register(ctx, "operator2@example.com", "SecurePass123", invite.Token); !errors.Is(err, errors.ErrRegistrationOff) {
+		t.Errorf("Expected a used invite to be rejected, got %v", err)
+	}
+
+	// Expired invites are rejected
+	invite, err = service.Invite(ctx, admin, "late@example.com", entities.RoleViewer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := service.Register(ctx, "late@example.com", "SecurePass123", invite.Token); !errors.Is(err, errors.ErrRegistrationOff) {
+		t.Errorf("Expected an expired invite to be rejected, got %v", err)
+	}
+}
+
+func TestSetRoleAndDisabled_KeepAnAdmin(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{RegistrationEnabled: true, RegistrationRole: entities.RoleViewer})
+	ctx := context.Background()
+
+	admin, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	viewer, err := service.Register(ctx, "viewer@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := service.SetRole(ctx, admin.UUID.String(), entities.RoleViewer); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected the last admin demotion to fail, got %v", err)
+	}
+	if _, err := service.SetDisabled(ctx, admin.UUID.String(), true); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected disabling the last admin to fail, got %v", err)
+	}
+	if _, err := service.SetRole(ctx, viewer.UUID.String(), "owner"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected an unknown role to be rejected, got %v", err)
+	}
+
+	disabled, err := service.SetDisabled(ctx, viewer.UUID.String(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !disabled.Disabled || disabled.HasRole(entities.RoleViewer) {
+		t.Errorf("Expected a disabled user without any role, got %+v", disabled)
+	}
+	if _, err := service.VerifyPassword(ctx, "viewer@example.com", "SecurePass123"); err == nil {
+		t.Error("Expected a disabled user to be unable to log in")
+	}
+
+	if _, err := service.SetRole(ctx, viewer.UUID.String(), entities.RoleAdmin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.SetDisabled(ctx, viewer.UUID.String(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	demoted, err := service.SetRole(ctx, admin.UUID.String(), entities.RoleOperator)
+	if err != nil {
+		t.Fatalf("Expected demotion with another admin, got %v", err)
+	}
+	if demoted.Role != entities.RoleOperator {
+		t.Errorf("Expected operator, got %s", demoted.Role)
+	}
+}
+
+func TestAgentGrants(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{RegistrationEnabled: true, RegistrationRole: entities.RoleOperator})
+	ctx := context.Background()
+
+	admin, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	operator, err := service.Register(ctx, "operator@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	granted, other := uuid.New(), uuid.New()
+	if _, err := service.SetAgentGrants(ctx, operator.UUID.String(), []string{"invalid"}); !errors.Is(err, errors.ErrInvalidUUID) {
+		t.Errorf("Expected ErrInvalidUUID, got %v", err)
+	}
+	if _, err := service.SetAgentGrants(ctx, operator.UUID.String(), []string{granted.String(), granted.String()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	grants, err := service.AgentGrants(ctx, operator.UUID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(grants) != 1 || grants[0] != granted {
+		t.Errorf("Expected a single grant on %s, got %v", granted, grants)
+	}
+
+	if ok, _ := service.CanAccessAgent(ctx, operator, granted.String()); !ok {
+		t.Error("Expected access to the granted agent")
+	}
+	if ok, _ := service.CanAccessAgent(ctx, operator, other.String()); ok {
+		t.Error("Expected no access to another agent")
+	}
+	if ok, _ := service.CanAccessAgent(ctx, admin, other.String()); !ok {
+		t.Error("Expected admins to access every agent")
+	}
+
+	allowed, err := service.AgentFilter(ctx, operator)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allowed(granted) || allowed(other) {
+		t.Error("Expected the filter to only allow the granted agent")
+	}
+
+	// the grant of an agent covers the torrent clients it manages
+	child := &models.Agent{Name: "box/movies", ParentUUID: &granted, Client: "movies"}
+	if err := db.DB.Create(child).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok, _ := service.CanAccessAgent(ctx, operator, child.UUID.String()); !ok {
+		t.Error("Expected access to the child agent of the granted agent")
+	}
+	if allowed, _ = service.AgentFilter(ctx, operator); !allowed(child.UUID) {
+		t.Error("Expected the filter to allow the child agent of the granted agent")
+	}
+	if grants, _ = service.AgentGrants(ctx, operator.UUID.String()); len(grants) != 1 {
+		t.Errorf("Expected the grants to be left unchanged, got %v", grants)
+	}
+}
+
+// fastHash keeps the password tests quick, the parameters are encoded in the hash
+var fastHash = HashParams{Time: 1, Memory: 1024, Threads: 1}
+
+func TestVerifyPassword_RehashesLegacyAndOutdatedHashes(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	// A user stored before the PHC format, as a bare hash next to its salt
+	salt, err := generateSalt()
+	if err != nil {
+		t.Fatalf("failed to generate salt: %v", err)
+	}
+	legacy := &models.User{Username: "legacy@example.com", Email: "legacy@example.com", PasswordHash: hashPassword("SecurePass123", salt), Salt: salt}
+	if err := db.DB.Create(legacy).Error; err != nil {
+		t.Fatalf("failed to create legacy user: %v", err)
+	}
+
+	service := NewWithConfig(db, Config{HashParams: fastHash})
+	if _, err := service.VerifyPassword(ctx, "legacy@example.com", "WrongPass123"); err == nil {
+		t.Error("Expected a wrong password to be rejected")
+	}
+	if _, err := service.VerifyPassword(ctx, "legacy@example.com", "SecurePass123"); err != nil {
+		t.Fatalf("Expected the legacy password to be accepted, got %v", err)
+	}
+
+	var stored models.User
+	db.DB.First(&stored, "uuid = ?", legacy.UUID)
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$v=19$m=1024,t=1,p=1$") || stored.Salt != "" {
+		t.Errorf("Expected the legacy hash to be replaced by a PHC string, got %q (salt %q)", stored.PasswordHash, stored.Salt)
+	}
+
+	// Raising the parameters rehashes on the next login
+	stronger := NewWithConfig(db, Config{HashParams: HashParams{Time: 2, Memory: 2048, Threads: 1}})
+	if _, err := stronger.VerifyPassword(ctx, "legacy@example.com", "SecurePass123"); err != nil {
+		t.Fatalf("Expected the password to be accepted, got %v", err)
+	}
+	db.DB.First(&stored, "uuid = ?", legacy.UUID)
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$v=19$m=2048,t=2,p=1$") {
+		t.Errorf("Expected a rehash with the new parameters, got %q", stored.PasswordHash)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{RegistrationRole: entities.RoleViewer, HashParams: fastHash})
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.DB.Create(&models.Session{UserUUID: user.UUID, TokenHash: "token", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := service.ChangePassword(ctx, user.UUID, "WrongPass123", "NewSecurePass456"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected a wrong current password to be rejected, got %v", err)
+	}
+	if err := service.ChangePassword(ctx, user.UUID, "SecurePass123", "short"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected a short password to be rejected, got %v", err)
+	}
+	if err := service.ChangePassword(ctx, user.UUID, "SecurePass123", "NewSecurePass456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := service.VerifyPassword(ctx, "admin@example.com", "NewSecurePass456"); err != nil {
+		t.Errorf("Expected the new password to be accepted, got %v", err)
+	}
+
+	var sessions int64
+	db.DB.Model(&models.Session{}).Where("user_uuid = ?", user.UUID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected the sessions to be ended, got %d", sessions)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewWithConfig(db, Config{ResetTTL: time.Hour, HashParams: fastHash})
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, "admin@example.com", "SecurePass123", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, err := service.IssuePasswordReset(ctx, user.UUID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reset, err := service.IssuePasswordResetByEmail(ctx, "Admin@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.ResetPassword(ctx, first.Token, "NewSecurePass456"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected a replaced token to be rejected, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, reset.Token, "NewSecurePass456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ResetPassword(ctx, reset.Token, "OtherSecurePass789"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected a used token to be rejected, got %v", err)
+	}
+	if _, err := service.VerifyPassword(ctx, "admin@example.com", "NewSecurePass456"); err != nil {
+		t.Errorf("Expected the new password to be accepted, got %v", err)
+	}
+
+	expired, err := service.IssuePasswordReset(ctx, user.UUID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if err := service.ResetPassword(ctx, expired.Token, "OtherSecurePass789"); !errors.Is(err, errors.ErrInvalidInput) {
+		t.Errorf("Expected an expired token to be rejected, got %v", err)
+	}
+}
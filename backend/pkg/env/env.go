@@ -2,6 +2,7 @@ package env
 
 import (
 	"errors"
+	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -28,11 +29,14 @@ type Env interface {
 	ValueBool() bool
 	ValueTime() time.Time
 	ValueDuration() time.Duration
+	ValuePositiveDuration() time.Duration
+	ValueList() []string
 }
 
 type value struct {
-	key   string
-	value any
+	key      string
+	value    any
+	fallback any // the default, kept when a value is set
 }
 
 // Get retrieves environment variable value, supporting Docker secrets with _FILE suffix
@@ -90,6 +94,7 @@ func readSecretFile(filePath string) (string, error) {
 }
 
 func (v *value) Default(value any) Env {
+	v.fallback = value
 	if v.Value() != "" {
 		return v
 	}
@@ -192,3 +197,28 @@ func (v *value) ValueDuration() time.Duration {
 	// Return zero duration if parsing fails
 	return 0
 }
+
+// ValuePositiveDuration returns the environment value as a duration, or the
+// default when the value is not a positive duration, which is logged. Tickers
+// and other intervals panic on zero or negative durations.
+func (v *value) ValuePositiveDuration() time.Duration {
+	if duration := v.ValueDuration(); duration > 0 {
+		return duration
+	}
+
+	fallback := &value{key: v.key, value: v.fallback}
+	log.Printf("env: %s=%q is not a positive duration, using %v", v.key, v.Value(), v.fallback)
+	return fallback.ValueDuration()
+}
+
+// ValueList returns the environment value as a comma-separated list, the items
+// being trimmed and the empty ones dropped
+func (v *value) ValueList() []string {
+	var items []string
+	for _, item := range strings.Split(v.Value(), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
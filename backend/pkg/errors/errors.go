@@ -23,6 +23,10 @@ func Is(err error, target error) bool {
 	return errors.Is(err, target)
 }
 
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
+
 // HandleError sends an appropriate HTTP error response based on the error type
 func HandleError(c *gin.Context, err error) {
 	if err == nil {
@@ -0,0 +1,28 @@
+package errors
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DecodeMessage extracts the error message from an error response body:
+// {"error": "..."}, {"message": "..."}, a bare JSON string or plain text
+func DecodeMessage(payload []byte) string {
+	var object struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &object); err == nil {
+		if object.Error != "" {
+			return object.Error
+		}
+		return object.Message
+	}
+
+	var message string
+	if err := json.Unmarshal(payload, &message); err == nil {
+		return message
+	}
+
+	return strings.TrimSpace(string(payload))
+}
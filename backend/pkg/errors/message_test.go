@@ -0,0 +1,21 @@
+package errors
+
+import "testing"
+
+func TestDecodeMessage(t *testing.T) {
+	tests := []struct {
+		payload  string
+		expected string
+	}{
+		{`{"error":"task not found"}`, "task not found"},
+		{`{"status_code":401,"message":"Invalid or expired enrollment token"}`, "Invalid or expired enrollment token"},
+		{`"agent busy"`, "agent busy"},
+		{"plain text\n", "plain text"},
+	}
+
+	for _, tt := range tests {
+		if message := DecodeMessage([]byte(tt.payload)); message != tt.expected {
+			t.Errorf("Expected message '%s', got '%s'", tt.expected, message)
+		}
+	}
+}
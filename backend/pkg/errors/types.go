@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 )
 
@@ -11,8 +12,40 @@ var (
 	ErrInvalidUUID      = errors.New("invalid UUID format")
 	ErrInvalidInput     = errors.New("invalid input data")
 	ErrAgentUnavailable = errors.New("agent unavailable")
+	ErrRuleNotFound     = errors.New("rule not found")
+	ErrRuleExists       = errors.New("rule already exists")
+	ErrNotSupported     = errors.New("action not supported by the torrent client")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrForbidden        = errors.New("access denied")
+	ErrRegistrationOff  = errors.New("registration is closed")
+	ErrAPITokenNotFound = errors.New("api token not found")
+	ErrInvalidCode      = errors.New("invalid two-factor code")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrPasswordLoginOff = errors.New("password login is disabled")
+	ErrSSODisabled      = errors.New("single sign-on is not configured")
+	ErrSSOFailed        = errors.New("single sign-on failed")
+	ErrSSODenied        = errors.New("single sign-on denied")
+
+	ErrEnrollmentTokenNotFound = errors.New("enrollment token not found")
+	ErrEnrollmentTokenInvalid  = errors.New("invalid or expired enrollment token")
 )
 
+// AgentError represents an error response returned by an agent API.
+// StatusCode is the status code answered by the agent and Message the error
+// reported in its response body.
+type AgentError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AgentError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("agent responded with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("agent responded with status %d: %s", e.StatusCode, e.Message)
+}
+
 // ResponseError represents an HTTP error response with status code and message
 type ResponseError struct {
 	StatusCode int    `json:"status_code"`
@@ -61,6 +94,12 @@ func ToResponseError(err error) *ResponseError {
 
 	errMsg := err.Error()
 
+	// Errors answered by an agent keep their client/server semantics
+	var agentErr *AgentError
+	if errors.As(err, &agentErr) {
+		return fromAgentError(agentErr, err)
+	}
+
 	// Check for predefined error types
 	switch {
 	case errors.Is(err, ErrTaskNotFound):
@@ -73,6 +112,36 @@ func ToResponseError(err error) *ResponseError {
 		return NewBadRequestError("Invalid request", err)
 	case errors.Is(err, ErrAgentUnavailable):
 		return NewServiceUnavailableError("Agent is unavailable", err)
+	case errors.Is(err, ErrRuleNotFound):
+		return NewNotFoundError("Rule not found", err)
+	case errors.Is(err, ErrRuleExists):
+		return NewResponseError(http.StatusConflict, "Rule already exists", err)
+	case errors.Is(err, ErrNotSupported):
+		return NewResponseError(http.StatusNotImplemented, "Action not supported by the torrent client", err)
+	case errors.Is(err, ErrUserNotFound):
+		return NewNotFoundError("User not found", err)
+	case errors.Is(err, ErrForbidden):
+		return NewResponseError(http.StatusForbidden, "Access denied", err)
+	case errors.Is(err, ErrRegistrationOff):
+		return NewResponseError(http.StatusForbidden, "Registration is closed", err)
+	case errors.Is(err, ErrAPITokenNotFound):
+		return NewNotFoundError("API token not found", err)
+	case errors.Is(err, ErrInvalidCode):
+		return NewResponseError(http.StatusUnauthorized, "Invalid two-factor code", err)
+	case errors.Is(err, ErrSessionNotFound):
+		return NewNotFoundError("Session not found", err)
+	case errors.Is(err, ErrPasswordLoginOff):
+		return NewResponseError(http.StatusForbidden, "Password login is disabled", err)
+	case errors.Is(err, ErrSSODisabled):
+		return NewNotFoundError("Single sign-on is not configured", err)
+	case errors.Is(err, ErrSSOFailed):
+		return NewResponseError(http.StatusUnauthorized, "Single sign-on failed", err)
+	case errors.Is(err, ErrSSODenied):
+		return NewResponseError(http.StatusForbidden, "Single sign-on denied", err)
+	case errors.Is(err, ErrEnrollmentTokenNotFound):
+		return NewNotFoundError("Enrollment token not found", err)
+	case errors.Is(err, ErrEnrollmentTokenInvalid):
+		return NewResponseError(http.StatusUnauthorized, "Invalid or expired enrollment token", err)
 	}
 
 	// Check error message patterns for wrapped errors
@@ -92,6 +161,30 @@ func ToResponseError(err error) *ResponseError {
 	}
 }
 
+// fromAgentError maps an agent error status to the manager response. Client errors
+// are forwarded, while authentication and server failures on the agent side become
+// gateway errors since they are not caused by the manager's caller.
+func fromAgentError(agentErr *AgentError, err error) *ResponseError {
+	switch {
+	case agentErr.StatusCode == http.StatusNotFound:
+		return NewNotFoundError("Resource not found on agent", err)
+	case agentErr.StatusCode == http.StatusConflict:
+		return NewResponseError(http.StatusConflict, "Agent reported a conflict", err)
+	case agentErr.StatusCode == http.StatusTooManyRequests:
+		return NewResponseError(http.StatusTooManyRequests, "Agent is rate limiting requests", err)
+	case agentErr.StatusCode == http.StatusUnauthorized, agentErr.StatusCode == http.StatusForbidden:
+		return NewResponseError(http.StatusBadGateway, "Agent rejected the manager credentials", err)
+	case agentErr.StatusCode >= 400 && agentErr.StatusCode < 500:
+		return NewBadRequestError("Agent rejected the request", err)
+	case agentErr.StatusCode == http.StatusServiceUnavailable:
+		return NewServiceUnavailableError("Agent is unavailable", err)
+	case agentErr.StatusCode == http.StatusNotImplemented:
+		return NewResponseError(http.StatusNotImplemented, "Action not supported by the torrent client", err)
+	default:
+		return NewResponseError(http.StatusBadGateway, "Agent failed to process the request", err)
+	}
+}
+
 // contains is a helper function to check if a string contains a substring (case-insensitive)
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) &&
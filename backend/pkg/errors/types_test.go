@@ -2,7 +2,9 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
 )
 
@@ -155,6 +157,32 @@ func TestToResponseError_AgentUnavailable(t *testing.T) {
 	}
 }
 
+func TestToResponseError_AccessErrors(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{ErrUserNotFound, http.StatusNotFound},
+		{fmt.Errorf("%w: agent not granted", ErrForbidden), http.StatusForbidden},
+		{ErrRegistrationOff, http.StatusForbidden},
+		{ErrAPITokenNotFound, http.StatusNotFound},
+		{ErrInvalidCode, http.StatusUnauthorized},
+		{ErrSessionNotFound, http.StatusNotFound},
+		{ErrPasswordLoginOff, http.StatusForbidden},
+		{ErrSSODisabled, http.StatusNotFound},
+		{ErrSSOFailed, http.StatusUnauthorized},
+		{ErrSSODenied, http.StatusForbidden},
+		{ErrEnrollmentTokenNotFound, http.StatusNotFound},
+		{ErrEnrollmentTokenInvalid, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if respErr := ToResponseError(tt.err); respErr.StatusCode != tt.expectedStatus {
+			t.Errorf("Expected status code %d for %v, got %d", tt.expectedStatus, tt.err, respErr.StatusCode)
+		}
+	}
+}
+
 func TestToResponseError_UnknownError(t *testing.T) {
 	unknownErr := errors.New("unknown error")
 	respErr := ToResponseError(unknownErr)
@@ -244,3 +272,37 @@ func TestToResponseError_ErrorsFetchingTasks(t *testing.T) {
 		t.Errorf("Expected message 'Unable to fetch tasks from agents', got '%s'", respErr.Message)
 	}
 }
+
+func TestToResponseError_AgentError(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantStatus int
+	}{
+		{http.StatusBadRequest, http.StatusBadRequest},
+		{http.StatusUnprocessableEntity, http.StatusBadRequest},
+		{http.StatusNotFound, http.StatusNotFound},
+		{http.StatusConflict, http.StatusConflict},
+		{http.StatusTooManyRequests, http.StatusTooManyRequests},
+		{http.StatusUnauthorized, http.StatusBadGateway},
+		{http.StatusForbidden, http.StatusBadGateway},
+		{http.StatusInternalServerError, http.StatusBadGateway},
+		{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{http.StatusNotImplemented, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// Wrapped like the repository does, with a message that would otherwise match a pattern
+			err := Wrap(&AgentError{StatusCode: tt.status, Message: "boom"}, "failed to create task")
+			respErr := ToResponseError(err)
+
+			if respErr.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, respErr.StatusCode)
+			}
+
+			if respErr.Error != "failed to create task: agent responded with status "+strconv.Itoa(tt.status)+": boom" {
+				t.Errorf("Unexpected error message '%s'", respErr.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package netaddr classifies IP addresses and parses the address lists of the
+// settings.
+package netaddr
+
+import "net/netip"
+
+// reserved lists the special-purpose ranges netip does not classify: this
+// network, shared address space (CGNAT), IETF protocol assignments,
+// benchmarking, documentation, reserved, NAT64 and 6to4, the last two
+// embedding IPv4 addresses.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// IsPublic tells whether an address is reachable on the internet: neither
+// loopback, private (RFC 1918, unique local), link-local, which includes the
+// cloud metadata endpoint 169.254.169.254, multicast, unspecified nor reserved
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains tells whether an address is in one of the prefixes
+func Contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrefixes parses addresses and CIDRs, a single address being a prefix
+// of its own. The values that are neither are returned apart.
+func ParsePrefixes(values []string) (prefixes []netip.Prefix, invalid []string) {
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		invalid = append(invalid, value)
+	}
+	return prefixes, invalid
+}
@@ -0,0 +1,51 @@
+package netaddr
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		if public := IsPublic(netip.MustParseAddr(tt.addr)); public != tt.expected {
+			t.Errorf("Expected IsPublic(%s) to be %v, got %v", tt.addr, tt.expected, public)
+		}
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, invalid := ParsePrefixes([]string{"10.0.0.0/8", "192.168.1.10", "::ffff:172.16.0.1", "not-an-address"})
+
+	if len(prefixes) != 3 || len(invalid) != 1 || invalid[0] != "not-an-address" {
+		t.Fatalf("Expected 3 prefixes and 1 invalid value, got %v and %v", prefixes, invalid)
+	}
+	if !Contains(prefixes, netip.MustParseAddr("10.20.30.40")) || !Contains(prefixes, netip.MustParseAddr("172.16.0.1")) {
+		t.Errorf("Expected the parsed networks to contain their addresses, got %v", prefixes)
+	}
+	if Contains(prefixes, netip.MustParseAddr("192.168.1.11")) {
+		t.Errorf("Expected a single address to match only itself, got %v", prefixes)
+	}
+}
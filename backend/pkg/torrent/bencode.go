@@ -0,0 +1,221 @@
+// Package torrent reads and writes the bencode encoding, and reads the
+// metainfo of .torrent files, BEP 3 for the v1 torrents and BEP 52 for the v2
+// and hybrid ones.
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds the nesting of lists and dictionaries, the input being untrusted
+const maxDepth = 64
+
+var (
+	ErrSyntax   = errors.New("bencode: syntax error")
+	ErrTooDeep  = errors.New("bencode: nesting too deep")
+	ErrTrailing = errors.New("bencode: data after the value")
+)
+
+// Decode parses data holding a single bencoded value. Integers are decoded as
+// int64, strings as []byte, lists as []any and dictionaries as map[string]any.
+func Decode(data []byte) (any, error) {
+	d := &decoder{data: data}
+
+	value, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w at offset %d", ErrTrailing, d.pos)
+	}
+
+	return value, nil
+}
+
+// decodeRawDict parses data holding a dictionary and returns the encoded
+// value of each key, as hashes are computed over the encoded info dictionary
+func decodeRawDict(data []byte) (map[string][]byte, error) {
+	d := &decoder{data: data}
+
+	if !d.consume('d') {
+		return nil, d.syntaxError("expected a dictionary")
+	}
+
+	result := make(map[string][]byte)
+	for !d.consume('e') {
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+
+		start := d.pos
+		if _, err := d.value(); err != nil {
+			return nil, err
+		}
+		result[string(key)] = data[start:d.pos]
+	}
+
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w at offset %d", ErrTrailing, d.pos)
+	}
+
+	return result, nil
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (d *decoder) syntaxError(message string) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, d.pos, message)
+}
+
+// consume skips the next byte when it is c
+func (d *decoder) consume(c byte) bool {
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *decoder) value() (any, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.syntaxError("unexpected end of data")
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.integer()
+	case c >= '0' && c <= '9':
+		return d.string()
+	case c == 'l':
+		return d.list()
+	case c == 'd':
+		return d.dict()
+	default:
+		return nil, d.syntaxError(fmt.Sprintf("unexpected %q", c))
+	}
+}
+
+// integer reads i<digits>e, rejecting the leading zeros and -0 as BEP 3 does
+func (d *decoder) integer() (int64, error) {
+	d.pos++ // i
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, d.syntaxError("unterminated integer")
+	}
+
+	digits := d.data[d.pos : d.pos+end]
+	unsigned := bytes.TrimPrefix(digits, []byte("-"))
+	if !isDigits(unsigned) || (unsigned[0] == '0' && len(digits) > 1) {
+		return 0, d.syntaxError(fmt.Sprintf("invalid integer %q", digits))
+	}
+
+	value, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		return 0, d.syntaxError(fmt.Sprintf("invalid integer %q", digits))
+	}
+
+	d.pos += end + 1
+	return value, nil
+}
+
+// string reads <length>:<bytes>
+func (d *decoder) string() ([]byte, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return nil, d.syntaxError("expected a string")
+	}
+
+	digits := d.data[d.pos : d.pos+colon]
+	if !isDigits(digits) || (digits[0] == '0' && len(digits) > 1) {
+		return nil, d.syntaxError(fmt.Sprintf("invalid string length %q", digits))
+	}
+
+	length, err := strconv.Atoi(string(digits))
+	start := d.pos + colon + 1
+	if err != nil || length > len(d.data)-start {
+		return nil, d.syntaxError("string longer than the data")
+	}
+
+	d.pos = start + length
+	return d.data[start:d.pos], nil
+}
+
+func (d *decoder) list() ([]any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	result := []any{}
+	for !d.consume('e') {
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+func (d *decoder) dict() (map[string]any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	result := make(map[string]any)
+	for !d.consume('e') {
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("unterminated dictionary")
+		}
+
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		result[string(key)] = value
+	}
+
+	return result, nil
+}
+
+// enter skips the l or d opening a list or dictionary
+func (d *decoder) enter() error {
+	d.depth++
+	if d.depth > maxDepth {
+		return ErrTooDeep
+	}
+	d.pos++
+	return nil
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// isDigits tells whether b is a non-empty run of decimal digits
+func isDigits(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
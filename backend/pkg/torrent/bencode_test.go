@@ -0,0 +1,66 @@
+package torrent
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{"i42e", int64(42)},
+		{"i-7e", int64(-7)},
+		{"i0e", int64(0)},
+		{"4:spam", []byte("spam")},
+		{"0:", []byte("")},
+		{"l4:spami3ee", []any{[]byte("spam"), int64(3)}},
+		{"le", []any{}},
+		{"d3:cow3:moo4:spaml1:a1:bee", map[string]any{
+			"cow":  []byte("moo"),
+			"spam": []any{[]byte("a"), []byte("b")},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := Decode([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Decode(%q): expected no error, got %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%q): expected %#v, got %#v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"", ErrSyntax},
+		{"i-0e", ErrSyntax},
+		{"i03e", ErrSyntax},
+		{"i+3e", ErrSyntax},
+		{"ie", ErrSyntax},
+		{"i42", ErrSyntax},
+		{"5:spam", ErrSyntax},
+		{"04:spam", ErrSyntax},
+		{"l4:spam", ErrSyntax},
+		{"d3:cowe", ErrSyntax},
+		{"di1e3:cowe", ErrSyntax},
+		{"x", ErrSyntax},
+		{"i1ei2e", ErrTrailing},
+		{strings.Repeat("l", maxDepth+1) + strings.Repeat("e", maxDepth+1), ErrTooDeep},
+	}
+
+	for _, tt := range tests {
+		if _, err := Decode([]byte(tt.input)); !errors.Is(err, tt.want) {
+			t.Errorf("Decode(%q): expected %v, got %v", tt.input, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package torrent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{42, "i42e"},
+		{int64(-7), "i-7e"},
+		{uint8(3), "i3e"},
+		{"spam", "4:spam"},
+		{[]byte{}, "0:"},
+		{[20]byte{}, "20:" + string(make([]byte, 20))},
+		{[]string{"a", "b"}, "l1:a1:be"},
+		{[]any{"spam", 3, []any{}}, "l4:spami3elee"},
+		{map[string]any{"spam": "eggs", "cow": "moo", "": 0}, "d0:i0e3:cow3:moo4:spam4:eggse"},
+		{map[string][]int{"n": {1, 2}}, "d1:nli1ei2eee"},
+	}
+
+	for _, tt := range tests {
+		got, err := Encode(tt.value)
+		if err != nil {
+			t.Errorf("Encode(%#v): expected no error, got %v", tt.value, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Encode(%#v): expected %q, got %q", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestEncode_Unsupported(t *testing.T) {
+	loop := map[string]any{}
+	loop["self"] = loop
+
+	tests := []struct {
+		value any
+		want  error
+	}{
+		{nil, ErrUnsupportedType},
+		{1.5, ErrUnsupportedType},
+		{true, ErrUnsupportedType},
+		{map[int]string{1: "a"}, ErrUnsupportedType},
+		{[]any{nil}, ErrUnsupportedType},
+		{loop, ErrTooDeep},
+	}
+
+	for _, tt := range tests {
+		if _, err := Encode(tt.value); !errors.Is(err, tt.want) {
+			t.Errorf("Encode(%T): expected %v, got %v", tt.value, tt.want, err)
+		}
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	value := map[string]any{
+		"announce": []byte("http://tracker/announce"),
+		"info": map[string]any{
+			"length": int64(1024),
+			"name":   []byte("file.iso"),
+			"files":  []any{},
+		},
+	}
+
+	encoded, err := Encode(value)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("Expected %#v, got %#v", value, decoded)
+	}
+}
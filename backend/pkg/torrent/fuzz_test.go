@@ -0,0 +1,64 @@
+package torrent
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range []string{"i42e", "i-0e", "4:spam", "l4:spami3ee", "d3:cow3:moo4:spaml1:a1:bee", "d1:ad1:bl1:cee", "l"} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, err := Decode(data)
+		if err != nil {
+			return
+		}
+
+		// what is decoded encodes back to the same value
+		encoded, err := Encode(value)
+		if err != nil {
+			t.Fatalf("Failed to encode %#v: %v", value, err)
+		}
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", encoded, err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Fatalf("Expected %#v, got %#v", value, decoded)
+		}
+	})
+}
+
+func FuzzParseMetaInfo(f *testing.F) {
+	f.Add(metainfo("d6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"))
+	f.Add(metainfo("d5:filesld6:lengthi10e4:pathl3:dir5:a.txteed4:attr1:p6:lengthi6e4:pathl4:.pad1:6eee4:name6:folder12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae"))
+	f.Add(metainfo("d9:file treed5:a.txtd0:d6:lengthi10eeee12:meta versioni2e4:name6:folder12:piece lengthi16384ee"))
+	f.Add([]byte("d8:announce3:url13:announce-listll3:urlee8:url-listl2:wse4:infod6:lengthi1e4:name1:a12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaa7:privatei1eee"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		meta, err := ParseMetaInfo(data)
+		if err != nil {
+			return
+		}
+
+		if meta.InfoHash.ID() == "" || meta.Length < 0 || meta.PieceCount < 0 {
+			t.Fatalf("Unexpected metainfo %+v", meta)
+		}
+		for _, file := range meta.Files {
+			for _, element := range strings.Split(file.Path, "/") {
+				if !validElement(element) {
+					t.Fatalf("Unexpected file path %q", file.Path)
+				}
+			}
+		}
+
+		uri, err := url.Parse(meta.MagnetURI())
+		if err != nil || uri.Scheme != "magnet" || uri.Query().Get("dn") != meta.Name {
+			t.Fatalf("Unexpected magnet link %s: %v", meta.MagnetURI(), err)
+		}
+	})
+}
@@ -0,0 +1,384 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMetaInfo = errors.New("torrent: invalid metainfo")
+
+// Versions of the metainfo format
+const (
+	V1     = "v1"
+	V2     = "v2"
+	Hybrid = "hybrid"
+)
+
+// MetaInfo is the content of a .torrent file
+type MetaInfo struct {
+	Name        string
+	PieceLength int64
+	PieceCount  int
+	// Length is the total size of the files
+	Length   int64
+	Files    []File
+	InfoHash InfoHash
+	// Private torrents are only shared through their trackers, BEP 27
+	Private bool
+
+	// Announce is the tracker of BEP 3, superseded by the tiers of trackers of
+	// AnnounceList, BEP 12, when set
+	Announce     string
+	AnnounceList [][]string
+	// WebSeeds are the url-list of BEP 19
+	WebSeeds     []string
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time
+}
+
+// Version returns V1, V2 or Hybrid after the infohashes of the torrent
+func (m *MetaInfo) Version() string {
+	switch {
+	case m.InfoHash.V1 != "" && m.InfoHash.V2 != "":
+		return Hybrid
+	case m.InfoHash.V2 != "":
+		return V2
+	default:
+		return V1
+	}
+}
+
+// Trackers returns the trackers of the torrent, tier after tier, without duplicates
+func (m *MetaInfo) Trackers() []string {
+	tiers := m.AnnounceList
+	if len(tiers) == 0 && m.Announce != "" {
+		tiers = [][]string{{m.Announce}}
+	}
+
+	seen := make(map[string]bool)
+	var trackers []string
+	for _, tier := range tiers {
+		for _, tracker := range tier {
+			if !seen[tracker] {
+				seen[tracker] = true
+				trackers = append(trackers, tracker)
+			}
+		}
+	}
+
+	return trackers
+}
+
+// MagnetURI builds the magnet link of the torrent, BEP 9, with the v2
+// infohash as a multihash for the v2 and hybrid torrents
+func (m *MetaInfo) MagnetURI() string {
+	var params []string
+	if m.InfoHash.V1 != "" {
+		params = append(params, "xt=urn:btih:"+m.InfoHash.V1)
+	}
+	if m.InfoHash.V2 != "" {
+		// 0x12 is SHA-256 and 0x20 its length
+		params = append(params, "xt=urn:btmh:1220"+m.InfoHash.V2)
+	}
+	params = append(params, "dn="+url.QueryEscape(m.Name), "xl="+strconv.FormatInt(m.Length, 10))
+	for _, tracker := range m.Trackers() {
+		params = append(params, "tr="+url.QueryEscape(tracker))
+	}
+	for _, seed := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(seed))
+	}
+
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// File is a file of a torrent, its path being relative to the torrent root
+type File struct {
+	Path   string
+	Length int64
+}
+
+// InfoHash holds the lowercase hex infohashes of a torrent, V1 being empty for
+// the v2 only torrents and V2 for the v1 ones
+type InfoHash struct {
+	V1 string
+	V2 string
+}
+
+// ID returns the hash torrent clients identify the torrent with: the v1
+// infohash, or the v2 one truncated to 40 characters for the v2 only torrents
+func (h InfoHash) ID() string {
+	if h.V1 != "" {
+		return h.V1
+	}
+	if len(h.V2) > 40 {
+		return h.V2[:40]
+	}
+	return h.V2
+}
+
+// Matches tells whether hash, as reported by a torrent client or a magnet
+// link, is one of the infohashes. The case is ignored.
+func (h InfoHash) Matches(hash string) bool {
+	if hash == "" {
+		return false
+	}
+	if strings.EqualFold(hash, h.V1) || strings.EqualFold(hash, h.V2) {
+		return true
+	}
+	return len(h.V2) > 40 && strings.EqualFold(hash, h.V2[:40])
+}
+
+// ParseMetaInfo decodes a .torrent file and computes its infohashes
+func ParseMetaInfo(data []byte) (*MetaInfo, error) {
+	raw, err := decodeRawDict(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rawInfo, ok := raw["info"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing info dictionary", ErrInvalidMetaInfo)
+	}
+
+	decoded, err := Decode(rawInfo)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: info is not a dictionary", ErrInvalidMetaInfo)
+	}
+
+	meta := &MetaInfo{}
+	if meta.Name, ok = stringField(info, "name"); !ok || !validElement(meta.Name) {
+		return nil, fmt.Errorf("%w: invalid name", ErrInvalidMetaInfo)
+	}
+	if meta.PieceLength, ok = intField(info, "piece length"); !ok || meta.PieceLength <= 0 {
+		return nil, fmt.Errorf("%w: invalid piece length", ErrInvalidMetaInfo)
+	}
+
+	pieces, v1 := info["pieces"].([]byte)
+	version, _ := intField(info, "meta version")
+	v2 := version == 2
+	if v1 && len(pieces)%sha1.Size != 0 {
+		return nil, fmt.Errorf("%w: pieces not made of SHA-1 hashes", ErrInvalidMetaInfo)
+	}
+
+	switch {
+	case v1:
+		sum := sha1.Sum(rawInfo)
+		meta.InfoHash.V1 = hex.EncodeToString(sum[:])
+		if v2 {
+			sum := sha256.Sum256(rawInfo)
+			meta.InfoHash.V2 = hex.EncodeToString(sum[:])
+		}
+		meta.Files, err = filesV1(info, meta.Name)
+	case v2:
+		sum := sha256.Sum256(rawInfo)
+		meta.InfoHash.V2 = hex.EncodeToString(sum[:])
+		meta.Files, err = filesV2(info)
+	default:
+		return nil, fmt.Errorf("%w: neither pieces nor a v2 file tree", ErrInvalidMetaInfo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range meta.Files {
+		if file.Length > math.MaxInt64-meta.Length {
+			return nil, fmt.Errorf("%w: total length overflows", ErrInvalidMetaInfo)
+		}
+		meta.Length += file.Length
+
+		// v2 pieces are aligned on the files
+		if !v1 {
+			meta.PieceCount += int(file.Length / meta.PieceLength)
+			if file.Length%meta.PieceLength != 0 {
+				meta.PieceCount++
+			}
+		}
+	}
+	if v1 {
+		meta.PieceCount = len(pieces) / sha1.Size
+	}
+
+	private, _ := intField(info, "private")
+	meta.Private = private == 1
+	readOptional(meta, raw)
+
+	return meta, nil
+}
+
+// readOptional reads the fields outside the info dictionary, ignoring the
+// ones of an unexpected type as clients do
+func readOptional(meta *MetaInfo, raw map[string][]byte) {
+	top := make(map[string]any)
+	for _, key := range []string{"announce", "announce-list", "url-list", "comment", "created by", "creation date"} {
+		if value, ok := raw[key]; ok {
+			// the raw values were validated with the dictionary
+			top[key], _ = Decode(value)
+		}
+	}
+
+	meta.Announce, _ = stringField(top, "announce")
+	meta.Comment, _ = stringField(top, "comment")
+	meta.CreatedBy, _ = stringField(top, "created by")
+	if date, ok := intField(top, "creation date"); ok && date > 0 {
+		meta.CreationDate = time.Unix(date, 0).UTC()
+	}
+
+	tiers, _ := top["announce-list"].([]any)
+	for _, tier := range tiers {
+		if trackers := stringList(tier); len(trackers) > 0 {
+			meta.AnnounceList = append(meta.AnnounceList, trackers)
+		}
+	}
+
+	// url-list is a single URL or a list of them
+	if seed, ok := stringField(top, "url-list"); ok && seed != "" {
+		meta.WebSeeds = []string{seed}
+	} else {
+		meta.WebSeeds = stringList(top["url-list"])
+	}
+}
+
+// filesV1 reads the length of a single file torrent or the files of a
+// multi-file one, leaving out the pad files of the hybrid torrents
+func filesV1(info map[string]any, name string) ([]File, error) {
+	if length, ok := intField(info, "length"); ok {
+		if length < 0 {
+			return nil, fmt.Errorf("%w: negative length", ErrInvalidMetaInfo)
+		}
+		return []File{{Path: name, Length: length}}, nil
+	}
+
+	entries, ok := info["files"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: neither length nor files", ErrInvalidMetaInfo)
+	}
+
+	files := make([]File, 0, len(entries))
+	for _, entry := range entries {
+		file, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: file is not a dictionary", ErrInvalidMetaInfo)
+		}
+
+		length, ok := intField(file, "length")
+		if !ok || length < 0 {
+			return nil, fmt.Errorf("%w: invalid file length", ErrInvalidMetaInfo)
+		}
+
+		elements, ok := file["path"].([]any)
+		if !ok || len(elements) == 0 {
+			return nil, fmt.Errorf("%w: invalid file path", ErrInvalidMetaInfo)
+		}
+		parts := make([]string, len(elements))
+		for i, element := range elements {
+			part, ok := element.([]byte)
+			if !ok || !validElement(string(part)) {
+				return nil, fmt.Errorf("%w: invalid file path", ErrInvalidMetaInfo)
+			}
+			parts[i] = string(part)
+		}
+
+		if attr, _ := stringField(file, "attr"); strings.Contains(attr, "p") {
+			continue
+		}
+		files = append(files, File{Path: path.Join(parts...), Length: length})
+	}
+
+	return files, nil
+}
+
+// filesV2 walks the file tree of a v2 torrent, where a file is a dictionary
+// holding its properties under the empty key. A single file torrent has its
+// file at the root of the tree.
+func filesV2(info map[string]any) ([]File, error) {
+	tree, ok := info["file tree"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing file tree", ErrInvalidMetaInfo)
+	}
+
+	var files []File
+	if err := walkTree(tree, "", &files); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: empty file tree", ErrInvalidMetaInfo)
+	}
+
+	return files, nil
+}
+
+func walkTree(node map[string]any, dir string, files *[]File) error {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child, ok := node[key].(map[string]any)
+		if !ok || !validElement(key) {
+			return fmt.Errorf("%w: invalid file tree", ErrInvalidMetaInfo)
+		}
+
+		name := path.Join(dir, key)
+		if leaf, ok := child[""].(map[string]any); ok {
+			length, ok := intField(leaf, "length")
+			if !ok || length < 0 {
+				return fmt.Errorf("%w: invalid file length", ErrInvalidMetaInfo)
+			}
+			*files = append(*files, File{Path: name, Length: length})
+			continue
+		}
+
+		if err := walkTree(child, name, files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validElement tells whether s can be a file or directory name, without
+// escaping the download directory
+func validElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, "/\\\x00")
+}
+
+func stringField(m map[string]any, key string) (string, bool) {
+	value, ok := m[key].([]byte)
+	return string(value), ok
+}
+
+func intField(m map[string]any, key string) (int64, bool) {
+	value, ok := m[key].(int64)
+	return value, ok
+}
+
+// stringList returns the non-empty strings of a list
+func stringList(value any) []string {
+	items, _ := value.([]any)
+
+	var result []string
+	for _, item := range items {
+		if s, ok := item.([]byte); ok && len(s) > 0 {
+			result = append(result, string(s))
+		}
+	}
+
+	return result
+}
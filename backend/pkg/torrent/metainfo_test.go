@@ -0,0 +1,234 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// metainfo wraps an encoded info dictionary into a .torrent file
+func metainfo(info string) []byte {
+	return []byte("d8:announce18:http://tracker/ann4:info" + info + "e")
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseMetaInfo_SingleFile(t *testing.T) {
+	info := "d6:lengthi1024e4:name8:file.iso12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+
+	meta, err := ParseMetaInfo(metainfo(info))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if meta.Name != "file.iso" || meta.Length != 1024 || meta.PieceLength != 16384 {
+		t.Errorf("Expected file.iso of 1024 bytes, got %+v", meta)
+	}
+	if !reflect.DeepEqual(meta.Files, []File{{Path: "file.iso", Length: 1024}}) {
+		t.Errorf("Expected the single file, got %+v", meta.Files)
+	}
+	if meta.InfoHash.V1 != sha1Hex(info) || meta.InfoHash.V2 != "" {
+		t.Errorf("Expected the SHA-1 of the info dictionary, got %+v", meta.InfoHash)
+	}
+	if meta.InfoHash.ID() != meta.InfoHash.V1 {
+		t.Errorf("Expected the v1 infohash as ID, got '%s'", meta.InfoHash.ID())
+	}
+}
+
+func TestParseMetaInfo_MultiFileSkipsPadFiles(t *testing.T) {
+	info := "d5:filesl" +
+		"d6:lengthi10e4:pathl3:dir5:a.txteed" +
+		"4:attr1:p6:lengthi6e4:pathl4:.pad1:6eed" +
+		"6:lengthi20e4:pathl5:b.txtee" +
+		"e4:name6:folder12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+
+	meta, err := ParseMetaInfo(metainfo(info))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := []File{{Path: "dir/a.txt", Length: 10}, {Path: "b.txt", Length: 20}}
+	if !reflect.DeepEqual(meta.Files, want) {
+		t.Errorf("Expected %+v, got %+v", want, meta.Files)
+	}
+	if meta.Length != 30 {
+		t.Errorf("Expected a length of 30, got %d", meta.Length)
+	}
+}
+
+func TestParseMetaInfo_V2AndHybrid(t *testing.T) {
+	tree := "9:file tree" +
+		"d3:dird5:b.txtd0:d6:lengthi20e11:pieces root32:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbeee" +
+		"5:a.txtd0:d6:lengthi10eeee"
+
+	v2 := "d" + tree + "12:meta versioni2e4:name6:folder12:piece lengthi16384ee"
+	meta, err := ParseMetaInfo(metainfo(v2))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if meta.InfoHash.V1 != "" || meta.InfoHash.V2 != sha256Hex(v2) {
+		t.Errorf("Expected the SHA-256 of the info dictionary only, got %+v", meta.InfoHash)
+	}
+	if id := meta.InfoHash.ID(); id != sha256Hex(v2)[:40] {
+		t.Errorf("Expected the truncated v2 infohash as ID, got '%s'", id)
+	}
+	want := []File{{Path: "a.txt", Length: 10}, {Path: "dir/b.txt", Length: 20}}
+	if !reflect.DeepEqual(meta.Files, want) {
+		t.Errorf("Expected %+v, got %+v", want, meta.Files)
+	}
+
+	hybrid := "d" + tree + "5:filesld6:lengthi10e4:pathl5:a.txteed6:lengthi20e4:pathl3:dir5:b.txteee" +
+		"12:meta versioni2e4:name6:folder12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	meta, err = ParseMetaInfo(metainfo(hybrid))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if meta.InfoHash.V1 != sha1Hex(hybrid) || meta.InfoHash.V2 != sha256Hex(hybrid) {
+		t.Errorf("Expected both infohashes, got %+v", meta.InfoHash)
+	}
+}
+
+func TestParseMetaInfo_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a dictionary", []byte("l4:infoe")},
+		{"no info", []byte("d8:announce3:urle")},
+		{"no name", metainfo("d6:lengthi1e12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae")},
+		{"zero piece length", metainfo("d6:lengthi1e4:name1:a12:piece lengthi0e6:pieces20:aaaaaaaaaaaaaaaaaaaae")},
+		{"neither v1 nor v2", metainfo("d6:lengthi1e4:name1:a12:piece lengthi16ee")},
+		{"path escaping the directory", metainfo("d5:filesld6:lengthi1e4:pathl2:..6:passwdeee4:name1:a12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae")},
+		{"name with a separator", metainfo("d6:lengthi1e4:name4:../a12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae")},
+		{"negative length", metainfo("d6:lengthi-1e4:name1:a12:piece lengthi16e6:pieces20:aaaaaaaaaaaaaaaaaaaae")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMetaInfo(tt.data); err == nil {
+				t.Error("Expected an error, got nil")
+			} else if !errors.Is(err, ErrInvalidMetaInfo) && !errors.Is(err, ErrSyntax) {
+				t.Errorf("Expected an invalid metainfo or syntax error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInfoHash_Matches(t *testing.T) {
+	v2 := strings.Repeat("ab", 32)
+	hash := InfoHash{V1: strings.Repeat("cd", 20), V2: v2}
+
+	for _, candidate := range []string{strings.Repeat("CD", 20), v2, strings.ToUpper(v2[:40])} {
+		if !hash.Matches(candidate) {
+			t.Errorf("Expected %s to match", candidate)
+		}
+	}
+	for _, candidate := range []string{"", strings.Repeat("ef", 20), v2[:20]} {
+		if hash.Matches(candidate) {
+			t.Errorf("Expected %q not to match", candidate)
+		}
+	}
+}
+
+func TestParseMetaInfo_OptionalFields(t *testing.T) {
+	data, err := Encode(map[string]any{
+		"announce": "http://tracker.example.org/announce",
+		"announce-list": []any{
+			[]any{"http://tracker.example.org/announce", "udp://backup.example.org:6969"},
+			[]any{},
+			[]any{"http://tier2.example.org/announce", 42},
+		},
+		"url-list":      "https://seed.example.org/files/",
+		"comment":       "Some comment",
+		"created by":    "gardarr",
+		"creation date": 1700000000,
+		"info": map[string]any{
+			"length":       40000,
+			"name":         "file name.iso",
+			"piece length": 16384,
+			"pieces":       make([]byte, 3*20),
+			"private":      1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode torrent: %v", err)
+	}
+
+	meta, err := ParseMetaInfo(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	wantTrackers := []string{"http://tracker.example.org/announce", "udp://backup.example.org:6969", "http://tier2.example.org/announce"}
+	if !reflect.DeepEqual(meta.Trackers(), wantTrackers) {
+		t.Errorf("Expected trackers %v, got %v", wantTrackers, meta.Trackers())
+	}
+	if len(meta.AnnounceList) != 2 {
+		t.Errorf("Expected the empty tier left out, got %v", meta.AnnounceList)
+	}
+	if !reflect.DeepEqual(meta.WebSeeds, []string{"https://seed.example.org/files/"}) {
+		t.Errorf("Expected the single web seed, got %v", meta.WebSeeds)
+	}
+	if !meta.Private || meta.PieceCount != 3 || meta.Version() != V1 {
+		t.Errorf("Expected a private v1 torrent of 3 pieces, got %+v", meta)
+	}
+	if meta.Comment != "Some comment" || meta.CreatedBy != "gardarr" || meta.CreationDate.Unix() != 1700000000 {
+		t.Errorf("Expected the comment, creator and creation date, got %+v", meta)
+	}
+
+	want := "magnet:?xt=urn:btih:" + meta.InfoHash.V1 + "&dn=file+name.iso&xl=40000" +
+		"&tr=http%3A%2F%2Ftracker.example.org%2Fannounce&tr=udp%3A%2F%2Fbackup.example.org%3A6969" +
+		"&tr=http%3A%2F%2Ftier2.example.org%2Fannounce&ws=https%3A%2F%2Fseed.example.org%2Ffiles%2F"
+	if uri := meta.MagnetURI(); uri != want {
+		t.Errorf("Expected magnet %s, got %s", want, uri)
+	}
+}
+
+func TestParseMetaInfo_V2Pieces(t *testing.T) {
+	data, err := Encode(map[string]any{
+		"announce": "http://tracker.example.org/announce",
+		"info": map[string]any{
+			"meta version": 2,
+			"name":         "folder",
+			"piece length": 16384,
+			"file tree": map[string]any{
+				"a.bin": map[string]any{"": map[string]any{"length": 16385}},
+				"b.bin": map[string]any{"": map[string]any{"length": 100}},
+				"empty": map[string]any{"": map[string]any{"length": 0}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode torrent: %v", err)
+	}
+
+	meta, err := ParseMetaInfo(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// pieces are aligned on the files: 2 for a.bin and 1 for b.bin
+	if meta.PieceCount != 3 || meta.Version() != V2 {
+		t.Errorf("Expected a v2 torrent of 3 pieces, got %+v", meta)
+	}
+	if uri := meta.MagnetURI(); !strings.HasPrefix(uri, "magnet:?xt=urn:btmh:1220"+meta.InfoHash.V2+"&dn=folder") {
+		t.Errorf("Expected the multihash magnet link, got %s", uri)
+	}
+	if meta.WebSeeds != nil || meta.Private {
+		t.Errorf("Expected no web seed and a public torrent, got %+v", meta)
+	}
+}
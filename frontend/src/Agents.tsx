@@ -65,14 +65,16 @@ function Agents() {
     address: "",
     token: "",
     icon: "Server",
-    color: "#3b82f6"
+    color: "#3b82f6",
+    certificate_fingerprint: ""
   });
   const [editForm, setEditForm] = useState<UpdateAgentRequest>({
     name: "",
     address: "",
     token: "",
     icon: "Server",
-    color: "#3b82f6"
+    color: "#3b82f6",
+    certificate_fingerprint: ""
   });
   
   const { toasts, showSuccess, showError, removeToast } = useToast();
@@ -144,7 +146,8 @@ function Agents() {
           address: "", 
           token: "", 
           icon: "Server", 
-          color: "#3b82f6" 
+          color: "#3b82f6",
+          certificate_fingerprint: ""
         });
         setShowCreateForm(false);
       }
@@ -165,7 +168,8 @@ function Agents() {
       address: agent.address,
       token: "", // Don't pre-fill token for security
       icon: agent.icon || "Server",
-      color: agent.color || "#3b82f6"
+      color: agent.color || "#3b82f6",
+      certificate_fingerprint: agent.certificate_fingerprint || ""
     });
     setShowEditModal(true);
   };
@@ -190,6 +194,9 @@ function Agents() {
     if (editForm.color && editForm.color !== (agentToEdit.color || "#3b82f6")) {
       updateData.color = editForm.color;
     }
+    if (editForm.certificate_fingerprint !== (agentToEdit.certificate_fingerprint || "")) {
+      updateData.certificate_fingerprint = editForm.certificate_fingerprint;
+    }
 
     // If no changes, show message and return
     if (Object.keys(updateData).length === 0) {
@@ -334,14 +341,16 @@ function Agents() {
               </div>
               <div className="space-y-1.5">
                 <Label htmlFor="type" className="text-sm">Type *</Label>
-                <Input
+                <select
                   id="type"
-                  placeholder="qbittorrent"
                   value={createForm.type}
                   onChange={(e) => setCreateForm({ ...createForm, type: e.target.value })}
-                  className="h-9"
-                  disabled
-                />
+                  className="w-full h-9 px-3 bg-background border border-input rounded-md focus:outline-none focus:ring-2 focus:ring-ring focus:ring-offset-2 text-sm"
+                >
+                  <option value="qbittorrent">qBittorrent</option>
+                  <option value="transmission">Transmission</option>
+                  <option value="deluge">Deluge</option>
+                </select>
               </div>
             </div>
 
@@ -369,6 +378,17 @@ function Agents() {
               </div>
             </div>
 
+            <div className="space-y-1.5">
+              <Label htmlFor="certificate-fingerprint" className="text-sm">Certificate fingerprint (SHA-256, optional)</Label>
+              <Input
+                id="certificate-fingerprint"
+                placeholder="Pins the TLS certificate of an https:// agent"
+                value={createForm.certificate_fingerprint}
+                onChange={(e) => setCreateForm({ ...createForm, certificate_fingerprint: e.target.value })}
+                className="h-9 font-mono"
+              />
+            </div>
+
             <div className="space-y-1.5">
               <Label htmlFor="color" className="text-sm">Color</Label>
               <div className="flex gap-1.5 flex-wrap">
@@ -544,6 +564,12 @@ function Agents() {
                         <span className={`text-xs font-medium ${getStatusTextColor(agent.status)}`}>
                           {agent.status}
                         </span>
+                        {agent.tunnel && (
+                          <span className="text-xs text-muted-foreground">via tunnel</span>
+                        )}
+                        {agent.client && (
+                          <span className="text-xs text-muted-foreground">client {agent.client}</span>
+                        )}
                       </div>
 
                       {agent.error && (
@@ -784,6 +810,7 @@ function Agents() {
                         placeholder="http://localhost:8080"
                         value={editForm.address}
                         onChange={(e) => setEditForm({ ...editForm, address: e.target.value })}
+                        disabled={!!agentToEdit?.parent_uuid}
                         className="h-9"
                       />
                     </div>
@@ -797,10 +824,22 @@ function Agents() {
                       placeholder="New authentication token"
                       value={editForm.token}
                       onChange={(e) => setEditForm({ ...editForm, token: e.target.value })}
+                      disabled={!!agentToEdit?.parent_uuid}
                       className="h-9"
                     />
                   </div>
 
+                  <div className="space-y-1.5">
+                    <Label htmlFor="edit-certificate-fingerprint" className="text-sm">Certificate fingerprint (empty to remove the pin)</Label>
+                    <Input
+                      id="edit-certificate-fingerprint"
+                      placeholder="SHA-256 of the agent TLS certificate"
+                      value={editForm.certificate_fingerprint}
+                      onChange={(e) => setEditForm({ ...editForm, certificate_fingerprint: e.target.value })}
+                      className="h-9 font-mono"
+                    />
+                  </div>
+
                   <div className="space-y-1.5">
                     <Label htmlFor="edit-color" className="text-sm">Color</Label>
                     <div className="flex gap-1.5 flex-wrap">
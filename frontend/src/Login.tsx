@@ -1,22 +1,50 @@
 import { useState, useEffect } from "react";
-import { Link, useNavigate } from "react-router-dom";
+import { Link, useNavigate, useSearchParams } from "react-router-dom";
 import { useTranslation } from "react-i18next";
 import { useAuth } from "@/contexts/AuthContext";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { Card, CardContent, CardFooter, CardHeader, CardTitle } from "@/components/ui/card";
-import { LogIn } from "lucide-react";
+import { KeyRound, LogIn } from "lucide-react";
+import { authService } from "@/services/auth";
+import type { AuthProviders } from "@/types/auth";
 import logoImage from "@/assets/img/logo/logo_128x128.png";
 
 export default function LoginPage() {
   const { t } = useTranslation();
   const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
   const { login, user } = useAuth();
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
   const [isLoading, setIsLoading] = useState(false);
-  const [error, setError] = useState("");
+  const [error, setError] = useState(() => {
+    // Set by the backend when a single sign-on fails
+    switch (searchParams.get("error")) {
+      case "sso":
+        return t("auth.login.ssoFailed");
+      case "sso_denied":
+        return t("auth.login.ssoDenied");
+      default:
+        return "";
+    }
+  });
+  const [providers, setProviders] = useState<AuthProviders>({ password_login: true, forward_auth: false });
+
+  useEffect(() => {
+    authService.getProviders().then((result) => {
+      if (result.providers) {
+        setProviders(result.providers);
+      }
+    });
+  }, []);
+
+  const handleSSO = () => {
+    if (providers.oidc) {
+      window.location.href = `${providers.oidc.login_url}?redirect=${encodeURIComponent("/dashboard")}`;
+    }
+  };
 
   // Set dark theme as default on mount
   useEffect(() => {
@@ -78,69 +106,94 @@ export default function LoginPage() {
               <CardTitle className="text-xl">{t("auth.login.title")}</CardTitle>
             </CardHeader>
             <CardContent className="space-y-4">
-              <div className="space-y-2">
-                <Label htmlFor="email">{t("auth.login.email")}</Label>
-                <Input
-                  id="email"
-                  type="email"
-                  placeholder={t("auth.login.emailPlaceholder")}
-                  value={email}
-                  onChange={(e) => setEmail(e.target.value)}
-                  required
-                  autoComplete="email"
+              {providers.oidc && (
+                <Button
+                  type="button"
+                  variant="outline"
+                  className="w-full"
+                  onClick={handleSSO}
                   disabled={isLoading}
-                />
-              </div>
-              <div className="space-y-2">
-                <div className="flex items-center justify-between">
-                  <Label htmlFor="password">{t("auth.login.password")}</Label>
-                  <Link
-                    to="/forgot-password"
-                    className="text-sm text-primary hover:underline"
-                  >
-                    {t("auth.login.forgotPassword")}
-                  </Link>
+                >
+                  <KeyRound className="h-4 w-4" />
+                  {t("auth.login.sso", { name: providers.oidc.name })}
+                </Button>
+              )}
+              {providers.oidc && providers.password_login && (
+                <div className="flex items-center gap-3 text-xs uppercase text-muted-foreground">
+                  <div className="h-px flex-1 bg-border" />
+                  {t("auth.login.or")}
+                  <div className="h-px flex-1 bg-border" />
                 </div>
-                <Input
-                  id="password"
-                  type="password"
-                  placeholder={t("auth.login.passwordPlaceholder")}
-                  value={password}
-                  onChange={(e) => setPassword(e.target.value)}
-                  required
-                  autoComplete="current-password"
-                  disabled={isLoading}
-                />
-              </div>
+              )}
+              {providers.password_login && (
+                <>
+                  <div className="space-y-2">
+                    <Label htmlFor="email">{t("auth.login.email")}</Label>
+                    <Input
+                      id="email"
+                      type="email"
+                      placeholder={t("auth.login.emailPlaceholder")}
+                      value={email}
+                      onChange={(e) => setEmail(e.target.value)}
+                      required
+                      autoComplete="email"
+                      disabled={isLoading}
+                    />
+                  </div>
+                  <div className="space-y-2">
+                    <div className="flex items-center justify-between">
+                      <Label htmlFor="password">{t("auth.login.password")}</Label>
+                      <Link
+                        to="/forgot-password"
+                        className="text-sm text-primary hover:underline"
+                      >
+                        {t("auth.login.forgotPassword")}
+                      </Link>
+                    </div>
+                    <Input
+                      id="password"
+                      type="password"
+                      placeholder={t("auth.login.passwordPlaceholder")}
+                      value={password}
+                      onChange={(e) => setPassword(e.target.value)}
+                      required
+                      autoComplete="current-password"
+                      disabled={isLoading}
+                    />
+                  </div>
+                </>
+              )}
               {error && (
                 <p className="text-sm text-destructive">{error}</p>
               )}
             </CardContent>
-            <CardFooter className="flex-col space-y-3 pt-2">
-              <Button
-                type="submit"
-                className="w-full"
-                disabled={isLoading}
-              >
-                {isLoading ? (
-                  <>{t("common.loading")}</>
-                ) : (
-                  <>
-                    <LogIn className="h-4 w-4" />
-                    {t("auth.login.submit")}
-                  </>
-                )}
-              </Button>
-              <p className="text-sm text-center text-muted-foreground">
-                {t("auth.login.noAccount")}{" "}
-                <Link
-                  to="/signup"
-                  className="text-primary font-medium hover:underline"
+            {providers.password_login && (
+              <CardFooter className="flex-col space-y-3 pt-2">
+                <Button
+                  type="submit"
+                  className="w-full"
+                  disabled={isLoading}
                 >
-                  {t("auth.login.signupLink")}
-                </Link>
-              </p>
-            </CardFooter>
+                  {isLoading ? (
+                    <>{t("common.loading")}</>
+                  ) : (
+                    <>
+                      <LogIn className="h-4 w-4" />
+                      {t("auth.login.submit")}
+                    </>
+                  )}
+                </Button>
+                <p className="text-sm text-center text-muted-foreground">
+                  {t("auth.login.noAccount")}{" "}
+                  <Link
+                    to="/signup"
+                    className="text-primary font-medium hover:underline"
+                  >
+                    {t("auth.login.signupLink")}
+                  </Link>
+                </p>
+              </CardFooter>
+            )}
           </form>
         </Card>
       </div>
@@ -11,6 +11,8 @@ interface Session {
   id: string;
   user_agent: string;
   ip_address: string;
+  current: boolean;
+  last_seen_at: string;
   created_at: string;
   expires_at: string;
 }
@@ -7,6 +7,8 @@ import { useEffect, useRef, useState, useMemo } from "react";
 import { useTranslation } from "react-i18next";
 import { torrentService } from "./services/torrents";
 import { agentService } from "./services/agents";
+import { subscribeEvents } from "./services/events";
+import type { TaskEvent } from "./services/events";
 import type { Task, CreateTaskRequest } from "./types/torrent";
 import type { Agent, AgentStatus } from "./types/agent";
 import AgentFilter from "@/components/ui/AgentFilter";
@@ -114,6 +116,32 @@ function mapTaskToTorrent(task: Task): Torrent {
   };
 }
 
+// Aplica um evento do stream /v1/events na lista de tasks
+function applyTaskEvent(tasks: Task[], event: TaskEvent): Task[] {
+  const ofAgent = (task: Task) => task.agent?.uuid === event.agent;
+
+  switch (event.type) {
+    case "snapshot":
+      return [...tasks.filter((task) => !ofAgent(task)), ...(event.tasks || [])];
+    case "task.added":
+    case "task.updated": {
+      if (!event.task) return tasks;
+      const updated = event.task;
+      const index = tasks.findIndex((task) => ofAgent(task) && task.id === updated.id);
+      if (index < 0) return [...tasks, updated];
+      const next = [...tasks];
+      next[index] = updated;
+      return next;
+    }
+    case "task.removed":
+      return tasks.filter((task) => !(ofAgent(task) && task.id === event.task_id));
+    case "agent.disconnected":
+      return tasks.filter((task) => !ofAgent(task));
+    default:
+      return tasks;
+  }
+}
+
 function formatBytes(bytes: number): string {
   if (bytes === 0) return "0 B";
   const k = 1024;
@@ -756,9 +784,11 @@ export default function TorrentsPage() {
   const [selectedCategories, setSelectedCategories] = useState<Set<string>>(new Set());
   const [selectedTags, setSelectedTags] = useState<Set<string>>(new Set());
   const [refreshIntervalSec, setRefreshIntervalSec] = useState<number>(5);
+  const [liveUpdates, setLiveUpdates] = useState(false);
   const [selectedTorrent, setSelectedTorrent] = useState<Task | null>(null);
   const [isModalOpen, setIsModalOpen] = useState(false);
   const [originalTasks, setOriginalTasks] = useState<Task[]>([]);
+  const tasksRef = useRef<Task[]>([]);
   const [isAddModalOpen, setIsAddModalOpen] = useState(false);
   const [isFilterSidebarOpen, setIsFilterSidebarOpen] = useState(false);
   const { toasts, showSuccess, showError, removeToast } = useToast();
@@ -985,7 +1015,13 @@ export default function TorrentsPage() {
 
   const handleDeleteTorrent = async (torrentId: string) => {
     try {
-      const response = await torrentService.deleteTask(torrentId, false);
+      const task = originalTasks.find(t => t.id === torrentId);
+      if (!task || !task.agent?.uuid) {
+        showError('Agent ID não encontrado para este torrent');
+        return;
+      }
+
+      const response = await torrentService.deleteTask(task.agent.uuid, torrentId, false);
       
       if (response.error) {
         showError(response.error);
@@ -1032,14 +1068,32 @@ export default function TorrentsPage() {
     loadAgents();
   }, []);
 
+  useEffect(() => {
+    tasksRef.current = originalTasks;
+  }, [originalTasks]);
+
+  // Atualizações em tempo real; o polling só é usado sem o stream
+  useEffect(() => {
+    return subscribeEvents({
+      onEvent: (event) => {
+        if (event.type === "instance.updated") return;
+        const next = applyTaskEvent(tasksRef.current, event);
+        tasksRef.current = next;
+        setOriginalTasks(next);
+        setTorrents(next.map(mapTaskToTorrent));
+      },
+      onStatusChange: setLiveUpdates,
+    });
+  }, []);
+
   // Intervalo de atualização automática
   useEffect(() => {
-    if (refreshIntervalSec <= 0) return;
+    if (liveUpdates || refreshIntervalSec <= 0) return;
     const id = setInterval(() => {
       refreshTorrentsSilently();
     }, refreshIntervalSec * 1000);
     return () => clearInterval(id);
-  }, [refreshIntervalSec]);
+  }, [refreshIntervalSec, liveUpdates]);
 
   // Filtrar e ordenar torrents
   const filteredTorrents = useMemo(() => {
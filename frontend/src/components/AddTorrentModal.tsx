@@ -19,6 +19,16 @@ function formatBytes(bytes: number): string {
   return `${value.toFixed(value >= 100 ? 0 : value >= 10 ? 1 : 2)} ${sizes[i]}`;
 }
 
+// readBase64 reads a file as base64, without the data URL prefix
+function readBase64(file: File): Promise<string> {
+  return new Promise((resolve, reject) => {
+    const reader = new FileReader();
+    reader.onload = () => resolve(String(reader.result).split(",", 2)[1] || "");
+    reader.onerror = () => reject(reader.error);
+    reader.readAsDataURL(file);
+  });
+}
+
 interface AddTorrentModalProps {
   isOpen: boolean;
   onClose: () => void;
@@ -30,6 +40,8 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [selectedAgentId, setSelectedAgentId] = useState<string>("");
   const [magnetUri, setMagnetUri] = useState("");
+  const [torrentFile, setTorrentFile] = useState<File | null>(null);
+  const [paused, setPaused] = useState(false);
   const [selectedCategoryId, setSelectedCategoryId] = useState<string>("");
   const [category, setCategory] = useState("");
   const [directory, setDirectory] = useState("");
@@ -70,6 +82,8 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
       setSelectedAgentId(activeAgents.length > 0 ? activeAgents[0].uuid : "");
       setSelectedCategoryId("");
       setMagnetUri("");
+      setTorrentFile(null);
+      setPaused(false);
       setCategory("");
       setDirectory("");
       setTagInput("");
@@ -178,10 +192,15 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
       newErrors.agent = "Selecione um agente";
     }
 
-    if (!magnetUri.trim()) {
-      newErrors.magnetUri = "Magnet URI é obrigatório";
-    } else if (!magnetUri.startsWith("magnet:")) {
-      newErrors.magnetUri = "Magnet URI deve começar com 'magnet:'";
+    const source = magnetUri.trim();
+    if (torrentFile) {
+      if (source) {
+        newErrors.magnetUri = "Informe um magnet/URL ou um arquivo .torrent, não ambos";
+      }
+    } else if (!source) {
+      newErrors.magnetUri = "Magnet URI, URL ou arquivo .torrent é obrigatório";
+    } else if (!/^(magnet:|https?:\/\/)/i.test(source)) {
+      newErrors.magnetUri = "Informe um link 'magnet:' ou uma URL http(s) de um .torrent";
     }
 
     if (!selectedCategoryId) {
@@ -206,12 +225,20 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
     setIsSubmitting(true);
 
     try {
+      const source = magnetUri.trim();
       const taskData: CreateTaskRequest = {
-        magnet_uri: magnetUri.trim(),
         category: category.trim(),
         tags: tags,
         ...(directory.trim() && { directory: directory.trim() }),
+        ...(paused && { paused: true }),
       };
+      if (torrentFile) {
+        taskData.torrent = await readBase64(torrentFile);
+      } else if (source.toLowerCase().startsWith("magnet:")) {
+        taskData.magnet_uri = source;
+      } else {
+        taskData.torrent_url = source;
+      }
 
       await onSubmit(selectedAgentId, taskData);
       // Fechar modal apenas se sucesso (onSubmit irá fechar em caso de erro)
@@ -324,15 +351,16 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
             )}
           </div>
 
-          {/* Magnet URI */}
+          {/* Magnet URI, torrent URL or .torrent file */}
           <div className="space-y-2">
             <Label htmlFor="magnetUri">
-              Magnet URI <span className="text-destructive">*</span>
+              Magnet URI, URL ou arquivo .torrent <span className="text-destructive">*</span>
             </Label>
             <textarea
               id="magnetUri"
               rows={5}
-              placeholder="magnet:?xt=urn:btih:..."
+              placeholder="magnet:?xt=urn:btih:... ou https://.../arquivo.torrent"
+              disabled={!!torrentFile}
               value={magnetUri}
               onChange={(e) => {
                 setMagnetUri(e.target.value);
@@ -342,9 +370,26 @@ export function AddTorrentModal({ isOpen, onClose, onSubmit, agents }: AddTorren
                 errors.magnetUri ? "border-destructive" : ""
               }`}
             />
+            <Input
+              id="torrentFile"
+              type="file"
+              accept=".torrent,application/x-bittorrent"
+              onChange={(e) => {
+                setTorrentFile(e.target.files?.[0] || null);
+                setErrors({ ...errors, magnetUri: "" });
+              }}
+            />
             {errors.magnetUri && (
               <p className="text-sm text-destructive">{errors.magnetUri}</p>
             )}
+            <label className="flex items-center gap-2 text-sm">
+              <input
+                type="checkbox"
+                checked={paused}
+                onChange={(e) => setPaused(e.target.checked)}
+              />
+              Adicionar pausado
+            </label>
           </div>
 
           {/* Category Selection */}
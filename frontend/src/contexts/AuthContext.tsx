@@ -66,8 +66,13 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   };
 
   const logout = async () => {
-    await authService.logout();
+    const { logoutUrl } = await authService.logout();
     setUser(null);
+
+    // Behind an authenticating proxy, the user must also log out from it
+    if (logoutUrl) {
+      window.location.href = logoutUrl;
+    }
   };
 
   return (
@@ -2,7 +2,20 @@
 import type { ResponseError } from '../types/errors';
 import { isResponseError, getErrorMessage } from '../types/errors';
 
-const API_BASE_URL = '/v1';
+// BASE_URL é o caminho base do build (APP_BASE_PATH), '/' por padrão
+const API_BASE_URL = `${import.meta.env.BASE_URL}v1`;
+
+const CSRF_COOKIE_NAME = 'csrf_token';
+const CSRF_HEADER_NAME = 'X-CSRF-Token';
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+// Lê o token CSRF que o backend define junto com o cookie de sessão
+function getCSRFToken(): string | undefined {
+  return document.cookie
+    .split('; ')
+    .find((cookie) => cookie.startsWith(`${CSRF_COOKIE_NAME}=`))
+    ?.slice(CSRF_COOKIE_NAME.length + 1);
+}
 
 export interface ApiResponse<T> {
   data?: T;
@@ -22,10 +35,15 @@ class ApiClient {
     options: RequestInit = {}
   ): Promise<ApiResponse<T>> {
     const url = `${this.baseURL}${endpoint}`;
-    
+
+    // Requisições que alteram estado repetem o token CSRF no header (double-submit)
+    const method = (options.method || 'GET').toUpperCase();
+    const csrfToken = SAFE_METHODS.includes(method) ? undefined : getCSRFToken();
+
     const defaultOptions: RequestInit = {
       headers: {
         'Content-Type': 'application/json',
+        ...(csrfToken ? { [CSRF_HEADER_NAME]: csrfToken } : {}),
         ...options.headers,
       },
       credentials: 'include', // Include cookies in requests
@@ -1,5 +1,5 @@
 import { api } from "@/lib/api";
-import type { AuthResponse, LoginRequest, RegisterRequest, User } from "@/types/auth";
+import type { AuthProviders, AuthResponse, LoginRequest, RegisterRequest, User } from "@/types/auth";
 
 class AuthService {
   /**
@@ -28,17 +28,30 @@ class AuthService {
     return { user: response.data?.user };
   }
 
+  /**
+   * Gets the ways users can log in: password and/or single sign-on
+   */
+  async getProviders(): Promise<{ providers?: AuthProviders; error?: string }> {
+    const response = await api.get<AuthProviders>("/auth/providers");
+
+    if (response.error) {
+      return { error: response.error };
+    }
+
+    return { providers: response.data };
+  }
+
   /**
    * Logs out the current user
    */
-  async logout(): Promise<{ error?: string }> {
-    const response = await api.post("/auth/logout");
+  async logout(): Promise<{ logoutUrl?: string; error?: string }> {
+    const response = await api.post<{ logout_url?: string }>("/auth/logout");
     
     if (response.error) {
       return { error: response.error };
     }
     
-    return {};
+    return { logoutUrl: response.data?.logout_url };
   }
 
   /**
@@ -0,0 +1,60 @@
+import type { Task } from '../types/torrent';
+
+const EVENTS_URL = `${import.meta.env.BASE_URL}v1/events`;
+
+export type TaskEventType =
+  | 'snapshot'
+  | 'task.added'
+  | 'task.updated'
+  | 'task.removed'
+  | 'instance.updated'
+  | 'agent.disconnected';
+
+export interface TaskEvent {
+  type: TaskEventType;
+  agent?: string;
+  task?: Task;
+  task_id?: string;
+  tasks?: Task[];
+}
+
+export interface EventHandlers {
+  onEvent: (event: TaskEvent) => void;
+  onStatusChange?: (connected: boolean) => void;
+}
+
+const EVENT_TYPES: TaskEventType[] = [
+  'snapshot',
+  'task.added',
+  'task.updated',
+  'task.removed',
+  'instance.updated',
+  'agent.disconnected',
+];
+
+/**
+ * Abre o stream SSE de eventos das tasks de todos os agentes.
+ * O navegador reconecta sozinho; retorna a função que fecha o stream.
+ */
+export function subscribeEvents({ onEvent, onStatusChange }: EventHandlers): () => void {
+  const source = new EventSource(EVENTS_URL, { withCredentials: true });
+
+  source.onopen = () => onStatusChange?.(true);
+  source.onerror = () => onStatusChange?.(false);
+
+  EVENT_TYPES.forEach((type) => {
+    source.addEventListener(type, (message) => {
+      try {
+        const data = JSON.parse((message as MessageEvent<string>).data);
+        onEvent({ ...data, type });
+      } catch {
+        // evento malformado, ignorado
+      }
+    });
+  });
+
+  return () => {
+    source.close();
+    onStatusChange?.(false);
+  };
+}
@@ -2,7 +2,9 @@ import { api } from '../lib/api';
 import type { ApiResponse } from '../lib/api';
 import type {
   Task,
-  CreateTaskRequest
+  CreateTaskRequest,
+  TaskPreview,
+  TorrentInspection
 } from '../types/torrent';
 
 /**
@@ -25,11 +27,25 @@ export class TorrentService {
     return api.post<null>(`/agent/${agentId}/task`, taskData);
   }
 
+  /**
+   * Lê o torrent de uma task sem adicioná-la
+   */
+  async previewTask(agentId: string, taskData: CreateTaskRequest): Promise<ApiResponse<TaskPreview>> {
+    return api.post<TaskPreview>(`/agent/${agentId}/task/preview`, taskData);
+  }
+
+  /**
+   * Lê os metadados de um arquivo .torrent, codificado em base64
+   */
+  async inspectTorrent(torrent: string): Promise<ApiResponse<TorrentInspection>> {
+    return api.post<TorrentInspection>('/torrents/inspect', { torrent });
+  }
+
   /**
    * Remove uma task/torrent
    */
-  async deleteTask(taskId: string, purge: boolean = false): Promise<ApiResponse<null>> {
-    const endpoint = `${this.baseEndpoint}/${taskId}${purge ? '?purge=true' : ''}`;
+  async deleteTask(agentId: string, taskId: string, purge: boolean = false): Promise<ApiResponse<null>> {
+    const endpoint = `/agent/${agentId}/tasks/${taskId}${purge ? '?purge=true' : ''}`;
     return api.delete<null>(endpoint);
   }
 
@@ -51,7 +67,7 @@ export class TorrentService {
    * Força download de uma task/torrent
    */
   async forceDownloadTask(agentId: string, taskId: string): Promise<ApiResponse<null>> {
-    return api.post<null>(`/agent/${agentId}/tasks/${taskId}/force_download`);
+    return api.post<null>(`/agent/${agentId}/tasks/${taskId}/force-download`);
   }
 }
 
@@ -6,13 +6,35 @@ export interface Agent {
   address: string;
   status: AgentStatus;
   error?: string;
+  type?: string;
+  capabilities?: ClientCapabilities;
   instance: Instance;
   icon?: string;
   color?: string;
+  // SHA-256 of the pinned TLS certificate of the agent
+  certificate_fingerprint?: string;
+  // Whether the agent is connected through a tunnel it opened
+  tunnel?: boolean;
+  // Agent managing the torrent client of a child agent, and the name of the client on it
+  parent_uuid?: string;
+  client?: string;
 }
 
 export type AgentStatus = 'ACTIVE' | 'ERRORED' | 'INACTIVE';
 
+// Actions the torrent client of the agent supports
+export interface ClientCapabilities {
+  categories: boolean;
+  tags: boolean;
+  super_seeding: boolean;
+  force_start: boolean;
+  rename: boolean;
+  share_limits: boolean;
+  content_layout: boolean;
+  skip_checking: boolean;
+  sequential_download: boolean;
+}
+
 export interface Instance {
   application: InstanceApplication;
   server: InstanceServer;
@@ -20,6 +42,7 @@ export interface Instance {
 }
 
 export interface InstanceApplication {
+  client?: string;
   version: string;
   api_version: string;
 }
@@ -43,6 +66,7 @@ export interface CreateAgentRequest {
   token: string;
   icon?: string;
   color?: string;
+  certificate_fingerprint?: string;
 }
 
 export interface UpdateAgentRequest {
@@ -51,6 +75,8 @@ export interface UpdateAgentRequest {
   token?: string;
   icon?: string;
   color?: string;
+  // An empty string removes the pin
+  certificate_fingerprint?: string;
 }
 
 export interface AgentListResponse {
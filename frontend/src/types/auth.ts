@@ -1,6 +1,10 @@
+export type Role = 'admin' | 'operator' | 'viewer';
+
 export interface User {
   uuid: string;
   email: string;
+  role: Role;
+  disabled: boolean;
   created_at: string;
 }
 
@@ -16,6 +20,7 @@ export interface LoginRequest {
 export interface RegisterRequest {
   email: string;
   password: string;
+  invite_token?: string;
 }
 
 export interface AuthError {
@@ -24,3 +29,72 @@ export interface AuthError {
   retry_after_seconds?: number;
 }
 
+
+export type TokenScope = 'tasks:read' | 'tasks:write' | 'agents:admin';
+
+export interface APIToken {
+  id: string;
+  name: string;
+  prefix: string;
+  token?: string;
+  scopes: TokenScope[];
+  expires_at: string;
+  last_used_at: string | null;
+  created_at: string;
+}
+
+export interface CreateAPITokenRequest {
+  name: string;
+  scopes: TokenScope[];
+  expires_in_days?: number;
+}
+
+export interface LoginChallengeResponse {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+}
+
+export interface LoginTwoFactorRequest {
+  challenge_token: string;
+  code?: string;
+  recovery_code?: string;
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  recovery_codes_left: number;
+}
+
+export interface TOTPEnrollment {
+  secret: string;
+  provisioning_uri: string;
+}
+
+export interface RecoveryCodesResponse {
+  recovery_codes: string[];
+}
+
+export interface PasswordChangeRequest {
+  current_password: string;
+  new_password: string;
+}
+
+export interface PasswordResetRequest {
+  token: string;
+  new_password: string;
+}
+
+export interface PasswordResetResponse {
+  token: string;
+  expires_at: string;
+}
+
+export interface AuthProviders {
+  password_login: boolean;
+  forward_auth: boolean;
+  oidc?: {
+    name: string;
+    login_url: string;
+  };
+}
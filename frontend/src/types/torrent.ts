@@ -51,11 +51,55 @@ export interface TaskMagnetLink {
   exact_source: string;
 }
 
+// A task is added from exactly one of magnet_uri, torrent_url and torrent
 export interface CreateTaskRequest {
-  magnet_uri: string;
+  magnet_uri?: string;
+  torrent_url?: string;
+  // .torrent file, base64 encoded
+  torrent?: string;
   category: string;
   directory?: string;
   tags: string[];
+  paused?: boolean;
+  skip_checking?: boolean;
+  sequential_download?: boolean;
+  content_layout?: 'Original' | 'Subfolder' | 'NoSubfolder';
+  // one per file of the .torrent: 0 skipped, 1 normal, 6 high, 7 maximal
+  file_priorities?: number[];
+}
+
+export interface TaskPreviewFile {
+  path: string;
+  size: number;
+}
+
+// Torrent a create request points to, read without adding it
+export interface TaskPreview {
+  name: string;
+  size: number;
+  info_hash_v1?: string;
+  info_hash_v2?: string;
+  files: TaskPreviewFile[];
+  existing?: Task;
+}
+
+// Metainfo of a .torrent file, read by the manager
+export interface TorrentInspection {
+  name: string;
+  version: 'v1' | 'v2' | 'hybrid';
+  info_hash_v1?: string;
+  info_hash_v2?: string;
+  size: number;
+  piece_length: number;
+  piece_count: number;
+  private: boolean;
+  trackers: string[][];
+  web_seeds: string[];
+  comment?: string;
+  created_by?: string;
+  creation_date?: string;
+  files: TaskPreviewFile[];
+  magnet_uri: string;
 }
 
 export interface DeleteTaskRequest {
@@ -3,6 +3,9 @@ import react from '@vitejs/plugin-react'
 import path from "path"
 import tailwindcss from "@tailwindcss/vite"
 
+// Path prefix when served behind a reverse proxy, e.g. APP_BASE_PATH=/gardarr
+const basePath = `/${(process.env.APP_BASE_PATH || '').replace(/^\/+|\/+$/g, '')}/`.replace('//', '/')
+
 // https://vite.dev/config/
 export default defineConfig({
   plugins: [react(), tailwindcss()],
@@ -14,7 +17,7 @@ export default defineConfig({
   server: {
     port: 5173,
     proxy: {
-      '/v1': {
+      [`${basePath}v1`]: {
         target: 'http://localhost:3000',
         changeOrigin: true,
         secure: false,
@@ -35,5 +38,5 @@ export default defineConfig({
       },
     },
   },
-  base: '/',
+  base: basePath,
 })